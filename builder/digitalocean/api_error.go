@@ -0,0 +1,71 @@
+package digitalocean
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/digitalocean/godo"
+)
+
+// explainCreateError turns the errors most often returned when creating a
+// droplet into a message saying what to change, keeping the API message and
+// request ID for support. Other errors are returned unchanged.
+func explainCreateError(err error, req *godo.DropletCreateRequest) error {
+	apiErr, ok := err.(*godo.ErrorResponse)
+	if !ok || apiErr.Response == nil {
+		return err
+	}
+
+	message := strings.ToLower(apiErr.Message)
+	var hint string
+	switch status := apiErr.Response.StatusCode; {
+	case status == http.StatusUnauthorized:
+		hint = "the API token was rejected, check api_token or DIGITALOCEAN_API_TOKEN"
+	case status == http.StatusForbidden:
+		hint = "the API token is read-only, use a token with write scope"
+	case status == http.StatusTooManyRequests:
+		hint = "the API rate limit was reached, retry later or run fewer builds with this token at once"
+	case strings.Contains(message, "droplet limit"):
+		hint = "the account droplet limit was reached, destroy unused droplets or ask DigitalOcean support to raise the limit"
+	case strings.Contains(message, "image") && strings.Contains(message, "not available"):
+		hint = fmt.Sprintf("the image is not available in region %s, transfer it there first or build in one of its regions", req.Region)
+	case strings.Contains(message, "invalid size"):
+		hint = fmt.Sprintf("size %s does not exist, list the available ones with `doctl compute size list`", req.Size)
+	case strings.Contains(message, "invalid region"):
+		hint = fmt.Sprintf("region %s does not exist, list the available ones with `doctl compute region list`", req.Region)
+	case strings.Contains(message, "unavailable") || strings.Contains(message, "not available"):
+		hint = fmt.Sprintf("region %s has no capacity for size %s right now, try another size or region, or set region to \"auto\"", req.Region, req.Size)
+	default:
+		return err
+	}
+
+	details := fmt.Sprintf("%d %s", apiErr.Response.StatusCode, apiErr.Message)
+	if apiErr.RequestID != "" {
+		details += fmt.Sprintf(", request ID %s", apiErr.RequestID)
+	}
+	return fmt.Errorf("%s (%s)", hint, details)
+}
+
+// isSSHKeyNotFound returns whether err is the API rejecting an SSH key of a
+// create call as unknown.
+func isSSHKeyNotFound(err error) bool {
+	apiErr, ok := err.(*godo.ErrorResponse)
+	if !ok || apiErr.Response == nil || apiErr.Response.StatusCode != http.StatusUnprocessableEntity {
+		return false
+	}
+	message := strings.ToLower(apiErr.Message)
+	return (strings.Contains(message, "ssh key") || strings.Contains(message, "ssh_key")) &&
+		(strings.Contains(message, "found") || strings.Contains(message, "exist"))
+}
+
+// IsAlreadyAvailable returns whether err is the API rejecting the transfer of
+// an image to a region it is already available in, which a transfer retried
+// after the first one went through runs into.
+func IsAlreadyAvailable(err error) bool {
+	apiErr, ok := err.(*godo.ErrorResponse)
+	if !ok || apiErr.Response == nil || apiErr.Response.StatusCode != http.StatusUnprocessableEntity {
+		return false
+	}
+	return strings.Contains(strings.ToLower(apiErr.Message), "already")
+}
@@ -0,0 +1,45 @@
+package digitalocean
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-digitalocean/internal/simulator"
+)
+
+func TestExplainCreateError(t *testing.T) {
+	tt := []struct {
+		name     string
+		status   int
+		message  string
+		expected string
+	}{
+		{"limit", http.StatusUnprocessableEntity, "Creating this droplet will exceed your droplet limit.", "the account droplet limit was reached"},
+		{"capacity", http.StatusUnprocessableEntity, "Region is currently unavailable for the selected size.", "region nyc3 has no capacity for size s-1vcpu-1gb"},
+		{"image", http.StatusUnprocessableEntity, "The image you specified is not available in the selected region.", "the image is not available in region nyc3"},
+		{"read-only", http.StatusForbidden, "You do not have access for the attempted action.", "the API token is read-only"},
+		{"other", http.StatusUnprocessableEntity, "Name is invalid.", "POST "},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			sim, client := testSimulator(t)
+			sim.Inject(simulator.Fault{Method: http.MethodPost, Path: "/v2/droplets", Status: tc.status, ID: "unprocessable_entity", Message: tc.message})
+
+			req := &godo.DropletCreateRequest{Name: "packer-test", Region: "nyc3", Size: "s-1vcpu-1gb", Image: godo.DropletCreateImage{Slug: "ubuntu-20-04-x64"}}
+			_, _, err := client.Droplets.Create(context.TODO(), req)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			err = explainCreateError(err, req)
+			if !strings.HasPrefix(err.Error(), tc.expected) {
+				t.Errorf("got %q, expected it to start with %q", err, tc.expected)
+			}
+			if !strings.Contains(err.Error(), tc.message) {
+				t.Errorf("got %q, expected it to contain the API message", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package digitalocean
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// apiUsage is an http.RoundTripper keeping track of how much of the API a
+// build used, to size how many builds a token can run at once.
+type apiUsage struct {
+	base http.RoundTripper
+
+	mu          sync.Mutex
+	calls       int
+	retries     int
+	failures    int
+	rateLimited int
+	requestTime time.Duration
+	waitTime    time.Duration
+	remaining   int
+
+	lastKey    string
+	lastFailed bool
+	lastTime   time.Time
+}
+
+// newAPIUsage tracks the calls sent through base, which defaults to
+// http.DefaultTransport.
+func newAPIUsage(base http.RoundTripper) *apiUsage {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &apiUsage{base: base, remaining: -1}
+}
+
+func (u *apiUsage) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := u.base.RoundTrip(req)
+	end := time.Now()
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	key := req.Method + " " + req.URL.String()
+	u.calls++
+	u.requestTime += end.Sub(start)
+	if key == u.lastKey {
+		if u.lastFailed {
+			u.retries++
+		} else if req.Method == http.MethodGet {
+			// Fetching the same resource again is polling it for a state
+			u.waitTime += start.Sub(u.lastTime)
+		}
+	}
+
+	failed := err != nil || resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	if failed {
+		u.failures++
+	}
+	if resp != nil {
+		if resp.StatusCode == http.StatusTooManyRequests {
+			u.rateLimited++
+		}
+		if remaining, err := strconv.Atoi(resp.Header.Get("Ratelimit-Remaining")); err == nil {
+			u.remaining = remaining
+		}
+	}
+	u.lastKey, u.lastFailed, u.lastTime = key, failed, end
+
+	return resp, err
+}
+
+// StateData returns the usage in a form suitable for artifact state.
+func (u *apiUsage) StateData() map[string]interface{} {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	data := map[string]interface{}{
+		"calls":        u.calls,
+		"retries":      u.retries,
+		"failures":     u.failures,
+		"rate_limited": u.rateLimited,
+		"request_time": u.requestTime.String(),
+		"wait_time":    u.waitTime.String(),
+	}
+	if u.remaining >= 0 {
+		data["rate_limit_remaining"] = u.remaining
+	}
+	return data
+}
+
+func (u *apiUsage) String() string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	s := fmt.Sprintf("%d calls (%d retries, %d failed, %d rate limited), %s in requests, %s waiting for resources",
+		u.calls, u.retries, u.failures, u.rateLimited,
+		u.requestTime.Round(time.Millisecond), u.waitTime.Round(time.Second))
+	if u.remaining >= 0 {
+		s += fmt.Sprintf(", %d requests left in the rate limit", u.remaining)
+	}
+	return s
+}
@@ -0,0 +1,45 @@
+package digitalocean
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+)
+
+func TestAPIUsage(t *testing.T) {
+	sim, _ := testSimulator(t)
+	droplet := sim.AddDroplet(godo.Droplet{Name: "packer-test", Status: "active"})
+
+	usage := newAPIUsage(nil)
+	client, err := newClient("token", sim.URL(), "", usage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sim.RateLimit(1)
+	if _, _, err := client.Account.Get(context.TODO()); err == nil {
+		t.Fatal("expected the first call to be rate limited")
+	}
+	if _, _, err := client.Account.Get(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, _, err := client.Droplets.Get(context.TODO(), droplet.ID); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	data := usage.StateData()
+	expected := map[string]interface{}{"calls": 5, "retries": 1, "failures": 1, "rate_limited": 1}
+	for key, value := range expected {
+		if data[key] != value {
+			t.Errorf("got %s = %v, expected %v", key, data[key], value)
+		}
+	}
+	if usage.waitTime < 10*time.Millisecond {
+		t.Errorf("got wait time %s, expected at least 10ms", usage.waitTime)
+	}
+}
@@ -3,7 +3,8 @@ package digitalocean
 import (
 	"context"
 	"fmt"
-	"log"
+	"os"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -27,6 +28,9 @@ type Artifact struct {
 	// StateData should store data such as GeneratedData
 	// to be shared with post-processors
 	StateData map[string]interface{}
+
+	// Local files written along with the snapshot
+	FilePaths []string
 }
 
 var _ packersdk.Artifact = new(Artifact)
@@ -35,17 +39,64 @@ func (*Artifact) BuilderId() string {
 	return BuilderId
 }
 
-func (*Artifact) Files() []string {
-	// No files with DigitalOcean
-	return nil
+func (a *Artifact) Files() []string {
+	return a.FilePaths
 }
 
 func (a *Artifact) Id() string {
 	return fmt.Sprintf("%s:%s", strings.Join(a.RegionNames[:], ","), strconv.FormatUint(uint64(a.SnapshotId), 10))
 }
 
+// ParseArtifactId splits the ID of a DigitalOcean artifact, as returned by
+// Id, into its region names and snapshot ID. Post-processors receive
+// artifacts over RPC and can't type assert them, so they use this instead.
+func ParseArtifactId(id string) ([]string, int, error) {
+	idx := strings.LastIndex(id, ":")
+	if idx < 0 {
+		return nil, 0, fmt.Errorf("malformed artifact ID: %q", id)
+	}
+
+	snapshotId, err := strconv.Atoi(id[idx+1:])
+	if err != nil {
+		return nil, 0, fmt.Errorf("malformed artifact ID: %q", id)
+	}
+
+	var regions []string
+	if id[:idx] != "" {
+		regions = strings.Split(id[:idx], ",")
+	}
+
+	return regions, snapshotId, nil
+}
+
 func (a *Artifact) String() string {
-	return fmt.Sprintf("A snapshot was created: '%v' (ID: %v) in regions '%v'", a.SnapshotName, a.SnapshotId, strings.Join(a.RegionNames[:], ","))
+	s := fmt.Sprintf("A snapshot was created: '%v' (ID: %v) in regions '%v'", a.SnapshotName, a.SnapshotId, strings.Join(a.RegionNames[:], ","))
+	snapshots := a.volumeSnapshots()
+	if len(snapshots) == 0 {
+		return s
+	}
+	names := make([]string, 0, len(snapshots))
+	for name := range snapshots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		names[i] = fmt.Sprintf("'%s' (ID: %s)", name, snapshots[name])
+	}
+	return s + ", along with snapshots of volumes " + strings.Join(names, ", ")
+}
+
+// volumeSnapshots returns the IDs of the volume snapshots taken along with
+// the image, by volume name.
+func (a *Artifact) volumeSnapshots() map[string]string {
+	raw, _ := a.StateData["volume_snapshots"].(map[string]interface{})
+	snapshots := make(map[string]string, len(raw))
+	for name, id := range raw {
+		if id, ok := id.(string); ok {
+			snapshots[name] = id
+		}
+	}
+	return snapshots
 }
 
 func (a *Artifact) State(name string) interface{} {
@@ -53,7 +104,20 @@ func (a *Artifact) State(name string) interface{} {
 }
 
 func (a *Artifact) Destroy() error {
-	log.Printf("Destroying image: %d (%s)", a.SnapshotId, a.SnapshotName)
-	_, err := a.Client.Images.Delete(context.TODO(), a.SnapshotId)
-	return err
+	logf(levelInfo, []interface{}{"image_id", a.SnapshotId}, "Destroying image %s", a.SnapshotName)
+	if _, err := a.Client.Images.Delete(context.TODO(), a.SnapshotId); err != nil {
+		return err
+	}
+	for _, path := range a.FilePaths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	for name, id := range a.volumeSnapshots() {
+		logf(levelInfo, []interface{}{"snapshot_id", id}, "Destroying snapshot of volume %s", name)
+		if _, err := a.Client.Storage.DeleteSnapshot(context.TODO(), id); err != nil {
+			return err
+		}
+	}
+	return nil
 }
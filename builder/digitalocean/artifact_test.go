@@ -9,7 +9,7 @@ func generatedData() map[string]interface{} {
 }
 
 func TestArtifactId(t *testing.T) {
-	a := &Artifact{"packer-foobar", 42, []string{"sfo", "tor1"}, nil, generatedData()}
+	a := &Artifact{"packer-foobar", 42, []string{"sfo", "tor1"}, nil, generatedData(), nil}
 	expected := "sfo,tor1:42"
 
 	if a.Id() != expected {
@@ -18,7 +18,7 @@ func TestArtifactId(t *testing.T) {
 }
 
 func TestArtifactIdWithoutMultipleRegions(t *testing.T) {
-	a := &Artifact{"packer-foobar", 42, []string{"sfo"}, nil, generatedData()}
+	a := &Artifact{"packer-foobar", 42, []string{"sfo"}, nil, generatedData(), nil}
 	expected := "sfo:42"
 
 	if a.Id() != expected {
@@ -27,7 +27,7 @@ func TestArtifactIdWithoutMultipleRegions(t *testing.T) {
 }
 
 func TestArtifactString(t *testing.T) {
-	a := &Artifact{"packer-foobar", 42, []string{"sfo", "tor1"}, nil, generatedData()}
+	a := &Artifact{"packer-foobar", 42, []string{"sfo", "tor1"}, nil, generatedData(), nil}
 	expected := "A snapshot was created: 'packer-foobar' (ID: 42) in regions 'sfo,tor1'"
 
 	if a.String() != expected {
@@ -36,7 +36,7 @@ func TestArtifactString(t *testing.T) {
 }
 
 func TestArtifactStringWithoutMultipleRegions(t *testing.T) {
-	a := &Artifact{"packer-foobar", 42, []string{"sfo"}, nil, generatedData()}
+	a := &Artifact{"packer-foobar", 42, []string{"sfo"}, nil, generatedData(), nil}
 	expected := "A snapshot was created: 'packer-foobar' (ID: 42) in regions 'sfo'"
 
 	if a.String() != expected {
@@ -69,3 +69,24 @@ func TestArtifactState_StateData(t *testing.T) {
 		t.Fatalf("Bad: State should be nil for nil StateData")
 	}
 }
+
+func TestParseArtifactId(t *testing.T) {
+	a := &Artifact{"packer-foobar", 42, []string{"sfo", "tor1"}, nil, generatedData(), nil}
+
+	regions, id, err := ParseArtifactId(a.Id())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if id != 42 {
+		t.Fatalf("snapshot ID should be 42, got %d", id)
+	}
+	if len(regions) != 2 || regions[0] != "sfo" || regions[1] != "tor1" {
+		t.Fatalf("bad regions: %v", regions)
+	}
+
+	for _, bad := range []string{"", "sfo", "sfo:abc"} {
+		if _, _, err := ParseArtifactId(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
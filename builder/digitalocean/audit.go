@@ -0,0 +1,160 @@
+package digitalocean
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// auditLog is an http.RoundTripper appending a record of every mutating API
+// call to a file, one JSON object per line.
+type auditLog struct {
+	base      http.RoundTripper
+	buildName string
+	buildUUID string
+	runUUID   string
+
+	mu   sync.Mutex
+	path string
+}
+
+type auditRecord struct {
+	Time       string `json:"time"`
+	BuildName  string `json:"build_name,omitempty"`
+	BuildUUID  string `json:"build_uuid,omitempty"`
+	RunUUID    string `json:"run_uuid,omitempty"`
+	Method     string `json:"method"`
+	Endpoint   string `json:"endpoint"`
+	ResourceID string `json:"resource_id,omitempty"`
+	Status     int    `json:"status,omitempty"`
+	Error      string `json:"error,omitempty"`
+	RequestID  string `json:"request_id,omitempty"`
+}
+
+// openAuditLog checks that records of the calls sent through base, which
+// defaults to http.DefaultTransport, can be appended to path. The file is
+// opened for each record, so that the client keeps auditing calls once the
+// build is over, such as the artifact being destroyed. The records name the
+// build, by its name and UUID, and the Packer run it is part of.
+func openAuditLog(path string, base http.RoundTripper, buildName string, buildUUID string) (*auditLog, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &auditLog{
+		base:      base,
+		buildName: buildName,
+		buildUUID: buildUUID,
+		runUUID:   os.Getenv("PACKER_RUN_UUID"),
+		path:      path,
+	}, nil
+}
+
+func (a *auditLog) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := a.base.RoundTrip(req)
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		return resp, err
+	}
+
+	record := auditRecord{
+		Time:       time.Now().UTC().Format(time.RFC3339Nano),
+		BuildName:  a.buildName,
+		BuildUUID:  a.buildUUID,
+		RunUUID:    a.runUUID,
+		Method:     req.Method,
+		Endpoint:   req.URL.Path,
+		ResourceID: pathResourceID(req.URL.Path),
+	}
+	if err != nil {
+		record.Error = err.Error()
+	} else {
+		record.Status = resp.StatusCode
+		body, readErr := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if readErr != nil {
+			return resp, readErr
+		}
+		if resp.StatusCode >= 400 {
+			var e struct {
+				Message string `json:"message"`
+			}
+			if json.Unmarshal(body, &e) == nil {
+				record.Error = e.Message
+			}
+			record.RequestID = resp.Header.Get("X-Request-Id")
+		} else if record.ResourceID == "" {
+			record.ResourceID = bodyResourceID(body)
+		}
+	}
+
+	line, _ := json.Marshal(record)
+	if werr := a.write(append(line, '\n')); werr != nil && err == nil {
+		// Calls that can't be audited must not go unnoticed
+		return resp, werr
+	}
+	return resp, err
+}
+
+func (a *auditLog) write(line []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	f, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// pathResourceID returns the ID of the resource a request path refers to,
+// such as 123 for /v2/droplets/123/actions.
+func pathResourceID(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i, part := range parts {
+		if part == "v2" {
+			if len(parts) > i+2 {
+				return parts[i+2]
+			}
+			break
+		}
+	}
+	return ""
+}
+
+// bodyResourceID returns the ID of the resource a create call returned,
+// such as 123 for {"droplet": {"id": 123, ...}}.
+func bodyResourceID(body []byte) string {
+	var objects map[string]json.RawMessage
+	if json.Unmarshal(body, &objects) != nil {
+		return ""
+	}
+	for _, raw := range objects {
+		var object struct {
+			ID   json.RawMessage `json:"id"`
+			Name string          `json:"name"`
+		}
+		if json.Unmarshal(raw, &object) != nil {
+			continue
+		}
+		if id := strings.Trim(string(object.ID), `"`); id != "" {
+			return id
+		}
+		if object.Name != "" {
+			return object.Name
+		}
+	}
+	return ""
+}
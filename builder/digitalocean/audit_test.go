@@ -0,0 +1,97 @@
+package digitalocean
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/digitalocean/godo"
+)
+
+func TestAuditLog(t *testing.T) {
+	sim, _ := testSimulator(t)
+	t.Setenv("PACKER_RUN_UUID", "8c3a3994-09b1-4f1e-b6b5-5c0a4b1ebd4f")
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	audit, err := openAuditLog(path, nil, "digitalocean.base", "2f1d7c0e-5b8a-4a52-9d1e-3c6f0b7a9e21")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := newClient("token", sim.URL(), "", audit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	droplet, _, err := client.Droplets.Create(context.TODO(), &godo.DropletCreateRequest{
+		Name:   "packer-test",
+		Region: "nyc3",
+		Size:   "s-1vcpu-1gb",
+		Image:  godo.DropletCreateImage{Slug: "ubuntu-20-04-x64"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := client.Droplets.Get(context.TODO(), droplet.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := client.DropletActions.PowerOff(context.TODO(), droplet.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Droplets.Delete(context.TODO(), 42); err == nil {
+		t.Fatal("expected deleting an unknown droplet to fail")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var records []auditRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record auditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("invalid record %q: %s", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+
+	id := strconv.Itoa(droplet.ID)
+	expected := []auditRecord{
+		{Method: "POST", Endpoint: "/v2/droplets", ResourceID: id, Status: 202},
+		{Method: "POST", Endpoint: "/v2/droplets/" + id + "/actions", ResourceID: id, Status: 201},
+		{Method: "DELETE", Endpoint: "/v2/droplets/42", ResourceID: "42", Status: 404,
+			Error: "The resource you were accessing could not be found.", RequestID: "sim-404"},
+	}
+	if len(records) != len(expected) {
+		t.Fatalf("got %d records, expected %d: %#v", len(records), len(expected), records)
+	}
+	for i, record := range records {
+		if record.Time == "" || record.BuildName != "digitalocean.base" ||
+			record.BuildUUID != "2f1d7c0e-5b8a-4a52-9d1e-3c6f0b7a9e21" || record.RunUUID != "8c3a3994-09b1-4f1e-b6b5-5c0a4b1ebd4f" {
+			t.Errorf("record %d is missing build details: %#v", i, record)
+		}
+		record.Time, record.BuildName, record.BuildUUID, record.RunUUID = "", "", "", ""
+		if record != expected[i] {
+			t.Errorf("record %d: got %#v, expected %#v", i, record, expected[i])
+		}
+	}
+}
+
+func TestBodyResourceID(t *testing.T) {
+	tt := map[string]string{
+		`{"droplet": {"id": 123, "name": "packer-test"}}`:         "123",
+		`{"vpc": {"id": "5a4981aa-9653-4bd1-bef5-d6bff52042e4"}}`: "5a4981aa-9653-4bd1-bef5-d6bff52042e4",
+		`{"tag": {"name": "packer"}}`:                             "packer",
+		``:                                                        "",
+	}
+	for body, expected := range tt {
+		if id := bodyResourceID([]byte(body)); id != expected {
+			t.Errorf("bodyResourceID(%q) = %q, expected %q", body, id, expected)
+		}
+	}
+}
@@ -6,8 +6,10 @@ package digitalocean
 import (
 	"context"
 	"fmt"
-	"log"
-	"net/url"
+	"net/http"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/digitalocean/godo"
 	"github.com/hashicorp/hcl/v2/hcldec"
@@ -15,7 +17,7 @@ import (
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	"github.com/hashicorp/packer-plugin-sdk/multistep/commonsteps"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
-	"golang.org/x/oauth2"
+	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
 )
 
 // The unique id for the builder
@@ -41,27 +43,108 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, []string, error) {
 		return nil, warnings, errs
 	}
 
-	return nil, nil, nil
+	var generatedData []string
+	if b.config.SnapshotVersionPrefix != "" {
+		generatedData = append(generatedData, "SnapshotVersion")
+	}
+	if b.config.PrivateNetworking {
+		generatedData = append(generatedData, "VPCUUID")
+	}
+	return generatedData, warnings, nil
 }
 
 func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook) (packersdk.Artifact, error) {
-	client := godo.NewClient(oauth2.NewClient(context.TODO(), &apiTokenSource{
-		AccessToken: b.config.APIToken,
-	}))
-	if b.config.APIURL != "" {
-		u, err := url.Parse(b.config.APIURL)
+	if holdErr := holdRunDir(b.config.buildUUID); holdErr != nil {
+		logf(levelWarn, nil, "Unable to hold the directory of the run: %s", holdErr)
+	}
+	defer func() {
+		if releaseErr := releaseRunDir(b.config.buildUUID); releaseErr != nil {
+			logf(levelWarn, nil, "Unable to release the directory of the run: %s", releaseErr)
+		}
+	}()
+
+	artifact, err := b.run(ctx, ui, hook)
+
+	// For the builds of the run using this one as their source_build
+	result := new(buildResult)
+	switch a := artifact.(type) {
+	case *Artifact:
+		result.SnapshotID = a.SnapshotId
+		result.SnapshotName = a.SnapshotName
+		result.Regions = a.RegionNames
+	default:
+		result.Error = "the build didn't create a snapshot"
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	if writeErr := writeBuildResult(b.config.PackerBuildName, result); writeErr != nil {
+		logf(levelWarn, nil, "Unable to write the result of the build: %s", writeErr)
+	}
+
+	return artifact, err
+}
+
+func (b *Builder) run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook) (packersdk.Artifact, error) {
+	started := time.Now()
+
+	var transport http.RoundTripper
+	if b.config.AuditLog != "" {
+		audit, err := openAuditLog(b.config.AuditLog, nil, b.config.PackerBuildName, b.config.buildUUID)
 		if err != nil {
-			return nil, fmt.Errorf("DigitalOcean: Invalid API URL, %s.", err)
+			return nil, fmt.Errorf("DigitalOcean: Unable to open audit_log, %s", err)
 		}
-		client.BaseURL = u
+		transport = audit
 	}
 
-	if len(b.config.SnapshotRegions) > 0 {
-		opt := &godo.ListOptions{
-			Page:    1,
-			PerPage: 200,
+	usage := newAPIUsage(transport)
+	transport = usage
+	if tokens := b.config.apiTokens(); len(tokens) > 1 {
+		transport = newTokenPool(tokens, usage)
+	}
+
+	client, err := newClient(b.config.APIToken, b.config.APIURL, b.config.buildUUID, transport)
+	if err != nil {
+		return nil, fmt.Errorf("DigitalOcean: Invalid API URL, %s.", err)
+	}
+
+	if err := checkToken(client); err != nil {
+		return nil, fmt.Errorf("DigitalOcean: %s", err)
+	}
+
+	if b.config.Size == "" {
+		size, err := findSizeByRequirements(client, &b.config)
+		if err != nil {
+			return nil, fmt.Errorf("DigitalOcean: Unable to select a size, %s", err)
+		}
+		ui.Say(fmt.Sprintf("Selected size %s", size.Slug))
+		b.config.Size = size.Slug
+		if b.config.VerifySize == "" {
+			b.config.VerifySize = size.Slug
+		}
+	}
+
+	if len(b.config.SSHKeyNames) > 0 {
+		ids, err := findKeysByName(client, b.config.SSHKeyNames)
+		if err != nil {
+			return nil, fmt.Errorf("DigitalOcean: Unable to resolve ssh_key_names, %s", err)
+		}
+		b.config.SSHKeyIDs = append(b.config.SSHKeyIDs, ids...)
+	}
+
+	var regionCandidates []string
+	if b.config.Region == "auto" {
+		regions, err := selectRegions(client, &b.config)
+		if err != nil {
+			return nil, fmt.Errorf("DigitalOcean: Unable to select a region, %s", err)
 		}
-		regions, _, err := client.Regions.List(context.TODO(), opt)
+		ui.Say(fmt.Sprintf("Selected region %s", regions[0]))
+		b.config.Region = regions[0]
+		regionCandidates = regions[1:]
+	}
+
+	if len(b.config.SnapshotRegions) > 0 {
+		regions, err := listRegions(client)
 		if err != nil {
 			return nil, fmt.Errorf("DigitalOcean: Unable to get regions, %s", err)
 		}
@@ -71,6 +154,12 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 			validRegions[val.Slug] = struct{}{}
 		}
 
+		for _, region := range b.config.SnapshotRegions {
+			if _, ok := validRegions[strings.TrimPrefix(region, "!")]; !ok && region != "all" {
+				return nil, fmt.Errorf("DigitalOcean: Invalid region, %s", region)
+			}
+		}
+		b.config.SnapshotRegions = expandSnapshotRegions(b.config.SnapshotRegions, regions)
 		for _, region := range append(b.config.SnapshotRegions, b.config.Region) {
 			if _, ok := validRegions[region]; !ok {
 				return nil, fmt.Errorf("DigitalOcean: Invalid region, %s", region)
@@ -78,55 +167,253 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 		}
 	}
 
+	var snapshotVersion, previousSnapshot string
+	if b.config.SnapshotVersionPrefix != "" {
+		version, err := nextSnapshotVersion(client, b.config.SnapshotVersionPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("DigitalOcean: Unable to get snapshot versions, %s", err)
+		}
+		snapshotVersion = fmt.Sprintf("v%d", version)
+		if version > 1 {
+			previousSnapshot = fmt.Sprintf("%sv%d", b.config.SnapshotVersionPrefix, version-1)
+		}
+		b.config.SnapshotName = b.config.SnapshotVersionPrefix + snapshotVersion
+		ui.Say(fmt.Sprintf("Naming snapshot %s", b.config.SnapshotName))
+	}
+
+	if b.config.sshUsernameInferred {
+		image := b.config.Image
+		if b.config.Distribution != "" {
+			image = b.config.Distribution
+		} else if b.config.SourceBuild != "" {
+			image = "of build " + b.config.SourceBuild
+		}
+		ui.Say(fmt.Sprintf("No ssh_username set, using %q for image %s", b.config.Comm.SSHUsername, image))
+	}
+
 	// Set up the state
 	state := new(multistep.BasicStateBag)
 	state.Put("config", &b.config)
 	state.Put("client", client)
 	state.Put("hook", hook)
 	state.Put("ui", ui)
+	state.Put("region_candidates", regionCandidates)
+	if snapshotVersion != "" {
+		generatedData := &packerbuilderdata.GeneratedData{State: state}
+		generatedData.Put("SnapshotVersion", snapshotVersion)
+	}
+	if previousSnapshot != "" {
+		state.Put("previous_snapshot_name", previousSnapshot)
+	}
+
+	if b.config.ValidateOnly {
+		steps := []multistep.Step{
+			new(stepSourceImage),
+			new(stepValidate),
+		}
+		b.runner = commonsteps.NewRunner(steps, b.config.PackerConfig, ui)
+		b.runner.Run(ctx, state)
+
+		if rawErr, ok := state.GetOk("error"); ok {
+			return nil, rawErr.(error)
+		}
+		return nil, nil
+	}
+
+	if b.config.SkipUnchanged {
+		steps := []multistep.Step{
+			multistep.If(b.config.SourceBuild != "", new(stepSourceBuild)),
+			new(stepSourceImage),
+			new(stepSkipUnchanged),
+		}
+		b.runner = commonsteps.NewRunner(steps, b.config.PackerConfig, ui)
+		b.runner.Run(ctx, state)
+
+		if rawErr, ok := state.GetOk("error"); ok {
+			return nil, rawErr.(error)
+		}
+		if image, ok := state.GetOk("unchanged_snapshot"); ok {
+			return unchangedArtifact(client, image.(*godo.Image), state), nil
+		}
+	}
+
+	resumed := false
+	if b.config.CheckpointFile != "" {
+		cp, err := loadCheckpoint(b.config.CheckpointFile)
+		if err != nil {
+			return nil, fmt.Errorf("DigitalOcean: %s", err)
+		}
+		if cp != nil {
+			resumed, err = resumeCheckpoint(client, cp, b.config.CheckpointFile, b.config.StateTimeout)
+			if err != nil {
+				return nil, fmt.Errorf("DigitalOcean: %s", err)
+			}
+			if resumed {
+				state.Put("resumed_checkpoint", cp)
+			} else {
+				ui.Say(fmt.Sprintf("Discarded checkpoint of droplet %d, which wasn't provisioned", cp.DropletID))
+			}
+		}
+	}
+
+	resources := new(resourceLedger)
+	state.Put("resources", resources)
 
 	// Build the steps
 	steps := []multistep.Step{
-		&communicator.StepSSHKeyGen{
+		// Already run to look for an unchanged snapshot
+		multistep.If(!b.config.SkipUnchanged && b.config.SourceBuild != "", new(stepSourceBuild)),
+		multistep.If(!b.config.SkipUnchanged, new(stepSourceImage)),
+		multistep.If(b.config.MaxHourlyPrice > 0, new(stepCheckBudget)),
+		multistep.If(!resumed && b.config.Comm.Type == "ssh", &communicator.StepSSHKeyGen{
 			CommConf:            &b.config.Comm,
 			SSHTemporaryKeyPair: b.config.Comm.SSH.SSHTemporaryKeyPair,
-		},
-		multistep.If(b.config.PackerDebug && b.config.Comm.SSHPrivateKeyFile == "",
+		}),
+		multistep.If(!resumed && b.config.Comm.Type == "ssh" && b.config.PackerDebug && b.config.Comm.SSHPrivateKeyFile == "",
 			&communicator.StepDumpSSHKey{
 				Path: fmt.Sprintf("do_%s.pem", b.config.PackerBuildName),
 				SSH:  &b.config.Comm.SSH,
 			},
 		),
-		&stepCreateSSHKey{},
+		multistep.If(b.config.Comm.Type == "ssh", &stepCreateSSHKey{}),
+		multistep.If(len(b.config.Volumes) > 0, new(stepCreateVolumes)),
 		new(stepCreateDroplet),
-		new(stepDropletInfo),
-		&communicator.StepConnect{
+		multistep.If(b.config.CheckpointFile != "", new(stepCheckpoint)),
+		// A resumed build continues from the snapshot, everything up to it
+		// was done by the previous run
+		multistep.If(!resumed, new(stepDropletInfo)),
+		multistep.If(!resumed && b.config.ReservedIP != "", new(stepReservedIP)),
+		multistep.If(!resumed && b.config.TemporaryBastion, new(stepCreateBastion)),
+		multistep.If(!resumed && len(b.config.Webhooks) > 0, &stepWebhooks{event: webhookDropletCreated}),
+		multistep.If(!resumed && b.config.CloudInitLogDir != "", new(stepCloudInitLogs)),
+		multistep.If(!resumed && len(b.config.Hooks.PostCreate) > 0,
+			&stepHooks{hook: "post_create", commands: b.config.Hooks.PostCreate}),
+		multistep.If(!resumed, &communicator.StepConnect{
 			Config:    &b.config.Comm,
 			Host:      communicator.CommHost(b.config.Comm.Host(), "droplet_ip"),
-			SSHConfig: b.config.Comm.SSHConfigFunc(),
-		},
-		new(commonsteps.StepProvision),
-		&commonsteps.StepCleanupTempKeys{
+			SSHConfig: pinnedSSHConfigFunc(b.config.Comm.SSHConfigFunc()),
+		}),
+		multistep.If(!resumed && b.config.DebugBundleDir != "", new(stepDebugBundle)),
+		multistep.If(!resumed && len(b.config.Volumes) > 0 && b.config.Comm.Type == "ssh",
+			new(stepWaitForMounts)),
+		multistep.If(!resumed && b.config.WaitForDropletAgent, new(stepWaitForDropletAgent)),
+		multistep.If(!resumed && b.config.UpdatePackages, new(stepUpdatePackages)),
+		multistep.If(!resumed && !b.config.IntermediateSnapshots, new(commonsteps.StepProvision)),
+		multistep.If(!resumed && b.config.IntermediateSnapshots,
+			&stepIntermediateSnapshots{provision: new(commonsteps.StepProvision)}),
+		// Before the temporary key is removed, which the reconnection needs
+		multistep.If(!resumed && b.config.RebootBeforeSnapshot, new(stepReboot)),
+		multistep.If(!resumed && len(b.config.Webhooks) > 0, &stepWebhooks{event: webhookProvisioningFinished}),
+		multistep.If(b.config.MaxEstimatedCost > 0, &stepCheckBudget{accrued: true}),
+		multistep.If(!resumed, &commonsteps.StepCleanupTempKeys{
 			Comm: &b.config.Comm,
-		},
+		}),
+		multistep.If(!resumed && b.config.Monitoring && b.config.Comm.Type == "ssh",
+			new(stepMonitoringAgent)),
+		multistep.If(!resumed && b.config.Generalize, new(stepGeneralize)),
+		multistep.If(!resumed && b.config.TrimDisk, new(stepTrimDisk)),
+		multistep.If(!resumed && b.config.PackageInventory, new(stepPackageInventory)),
+		multistep.If(!resumed && b.config.FilesystemDigest, new(stepFilesystemDigest)),
+		multistep.If(b.config.CheckpointFile != "", &stepCheckpoint{snapshotReady: true}),
+		multistep.If(len(b.config.Hooks.PreSnapshot) > 0,
+			&stepHooks{hook: "pre_snapshot", commands: b.config.Hooks.PreSnapshot}),
 		new(stepShutdown),
 		new(stepPowerOff),
 		&stepSnapshot{
 			snapshotTimeout: b.config.SnapshotTimeout,
 		},
+		multistep.If(b.config.SnapshotVolumes, new(stepSnapshotVolumes)),
+		multistep.If(len(b.config.Webhooks) > 0, &stepWebhooks{event: webhookTransfersComplete}),
+		multistep.If(len(b.config.Hooks.PostSnapshot) > 0,
+			&stepHooks{hook: "post_snapshot", commands: b.config.Hooks.PostSnapshot}),
+		multistep.If(len(b.config.VerifyCommands) > 0, new(stepVerify)),
+		multistep.If(b.config.SnapshotDiffFile != "", new(stepSnapshotDiff)),
+		new(stepEstimateCost),
+	}
+
+	var root *span
+	if b.config.OTLPEndpoint != "" {
+		trace := newTracer(b.config.OTLPEndpoint, b.config.OTLPHeaders, os.Getenv("TRACEPARENT"), traceResource(&b.config))
+		root = trace.start("build", nil)
+		steps = traceSteps(steps, root)
+		state.Put("trace_span", root)
+	}
+
+	var progress *progressLog
+	if b.config.ProgressFile != "" {
+		progress, err = openProgressLog(b.config.ProgressFile, b.config.PackerBuildName)
+		if err != nil {
+			return nil, fmt.Errorf("DigitalOcean: Unable to open progress_file, %s", err)
+		}
+		defer progress.Close()
+		state.Put("progress_log", progress)
+		steps = progressSteps(steps)
+		progress.emit(progressEvent{Event: progressBuildStart, Percent: percent(0, 1)})
+	}
+
+	if b.config.MaxBuildDuration > 0 || b.config.MaxEstimatedCost > 0 {
+		var stop func()
+		ctx, stop = startWatchdog(ctx, state, b.config.MaxBuildDuration, b.config.MaxEstimatedCost)
+		defer stop()
 	}
 
 	// Run the steps
 	b.runner = commonsteps.NewRunner(steps, b.config.PackerConfig, ui)
 	b.runner.Run(ctx, state)
 
+	ui.Say(fmt.Sprintf("API usage: %s", usage))
+
+	if report, leaked := resources.report(state); leaked {
+		ui.Error(fmt.Sprintf("Resources of the build, some of which failed to delete:\n%s", report))
+	} else {
+		ui.Say(fmt.Sprintf("Resources of the build:\n%s", report))
+	}
+
+	if progress != nil {
+		e := progressEvent{
+			Event:     progressBuildFinish,
+			Percent:   percent(1, 1),
+			Duration:  time.Since(started).Seconds(),
+			Result:    "success",
+			Resources: progressResources(state),
+		}
+		if rawErr, ok := state.GetOk("error"); ok {
+			e.Result = "failure"
+			e.Error = rawErr.(error).Error()
+		} else if _, ok := state.GetOk(multistep.StateCancelled); ok {
+			e.Result = "cancelled"
+		}
+		progress.emit(e)
+	}
+
+	if root != nil {
+		setBuildAttrs(root, state)
+		var err error
+		if rawErr, ok := state.GetOk("error"); ok {
+			err = rawErr.(error)
+		}
+		root.finish(err)
+		if err := root.tracer.export(); err != nil {
+			ui.Error(fmt.Sprintf("Error exporting the trace to otlp_endpoint: %s", err))
+		}
+	}
+
+	if b.config.SummaryFile != "" {
+		if err := newBuildSummary(state, started, usage).write(b.config.SummaryFile); err != nil {
+			ui.Error(fmt.Sprintf("Error writing summary_file: %s", err))
+		}
+	}
+
 	// If there was an error, return that
 	if rawErr, ok := state.GetOk("error"); ok {
+		if len(b.config.Webhooks) > 0 {
+			sendWebhooks(newStepUi(state, "webhooks"), b.config.Webhooks, newWebhookPayload(state, webhookBuildFailed))
+		}
 		return nil, rawErr.(error)
 	}
 
 	if _, ok := state.GetOk("snapshot_name"); !ok {
-		log.Println("Failed to find snapshot_name in state. Bug?")
+		logf(levelWarn, nil, "Failed to find snapshot_name in state. Bug?")
 		return nil, nil
 	}
 
@@ -137,6 +424,78 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 		Client:       client,
 		StateData:    map[string]interface{}{"generated_data": state.Get("generated_data")},
 	}
+	artifact.StateData["api_usage"] = usage.StateData()
+	artifact.StateData["resources"] = resources.list(state)
+	if availability, ok := state.GetOk("region_availability"); ok {
+		artifact.StateData["region_availability"] = availability
+	}
+	if estimate, ok := state.GetOk("estimated_cost"); ok {
+		artifact.StateData["estimated_cost"] = estimate
+	}
+	if packages, ok := state.GetOk("packages"); ok {
+		artifact.StateData["packages"] = packages
+	}
+	if b.config.PackageInventoryFile != "" {
+		artifact.FilePaths = []string{b.config.PackageInventoryFile}
+	}
+	if diff, ok := state.GetOk("snapshot_diff"); ok {
+		artifact.StateData["snapshot_diff"] = diff
+		artifact.FilePaths = append(artifact.FilePaths, b.config.SnapshotDiffFile)
+	}
+	if digest, ok := state.GetOk("filesystem_digest"); ok {
+		artifact.StateData["filesystem_digest"] = digest
+	}
+	if snapshots, ok := state.GetOk("volume_snapshots"); ok {
+		artifact.StateData["volume_snapshots"] = snapshots
+	}
+	if snapshots, ok := state.GetOk("intermediate_snapshots"); ok {
+		artifact.StateData["intermediate_snapshots"] = snapshots
+	}
+	if id, ok := state.GetOk("ssh_key_id"); ok && b.config.SkipKeyCleanup {
+		artifact.StateData["ssh_key_id"] = id
+	}
 
 	return artifact, nil
 }
+
+// expandSnapshotRegions replaces "all" with every available region and
+// removes the regions excluded with a leading "!", such as "!nyc1".
+func expandSnapshotRegions(snapshotRegions []string, regions []godo.Region) []string {
+	excluded := make(map[string]struct{})
+	all := false
+	for _, region := range snapshotRegions {
+		if strings.HasPrefix(region, "!") {
+			excluded[strings.TrimPrefix(region, "!")] = struct{}{}
+		} else if region == "all" {
+			all = true
+		}
+	}
+
+	var candidates []string
+	if all {
+		for _, region := range regions {
+			if region.Available {
+				candidates = append(candidates, region.Slug)
+			}
+		}
+	}
+	for _, region := range snapshotRegions {
+		if region != "all" && !strings.HasPrefix(region, "!") {
+			candidates = append(candidates, region)
+		}
+	}
+
+	seen := make(map[string]struct{})
+	expanded := []string{}
+	for _, region := range candidates {
+		if _, ok := excluded[region]; ok {
+			continue
+		}
+		if _, ok := seen[region]; ok {
+			continue
+		}
+		seen[region] = struct{}{}
+		expanded = append(expanded, region)
+	}
+	return expanded
+}
@@ -1,9 +1,15 @@
 package digitalocean
 
 import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/digitalocean/godo"
 )
 
 func testConfig() map[string]interface{} {
@@ -108,6 +114,31 @@ func TestBuilderPrepare_Size(t *testing.T) {
 	if b.config.Size != expected {
 		t.Errorf("found %s, expected %s", b.config.Size, expected)
 	}
+
+	// Test combined with requirements
+	config["min_vcpus"] = 2
+	b = Builder{}
+	_, _, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test requirements
+	delete(config, "size")
+	config["size_class"] = "cpu-optimized"
+	b = Builder{}
+	_, _, err = b.Prepare(config)
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	// Test invalid class
+	config["size_class"] = "gpu"
+	b = Builder{}
+	_, _, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
 }
 
 func TestBuilderPrepare_Image(t *testing.T) {
@@ -242,7 +273,7 @@ func TestBuilderPrepare_PrivateNetworking(t *testing.T) {
 	// Test set
 	config["private_networking"] = true
 	b = Builder{}
-	_, warnings, err = b.Prepare(config)
+	generated, warnings, err := b.Prepare(config)
 	if len(warnings) > 0 {
 		t.Fatalf("bad: %#v", warnings)
 	}
@@ -253,6 +284,109 @@ func TestBuilderPrepare_PrivateNetworking(t *testing.T) {
 	if b.config.PrivateNetworking != true {
 		t.Errorf("invalid: %t", b.config.PrivateNetworking)
 	}
+	if !reflect.DeepEqual(generated, []string{"VPCUUID"}) {
+		t.Errorf("expected VPCUUID in the generated data, got %#v", generated)
+	}
+}
+
+func TestBuilderPrepare_UserDataCommand(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["user_data_command"] = "cloud-init-gen --role web"
+	if _, _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	config["user_data"] = "#cloud-config"
+	b = Builder{}
+	if _, _, err := b.Prepare(config); err == nil || !strings.Contains(err.Error(), "only one of user_data") {
+		t.Fatalf("expected an error with user_data, got %v", err)
+	}
+}
+
+func TestBuilderPrepare_SkipKeyCleanup(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["skip_key_cleanup"] = true
+	if _, _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	config["shared_temporary_key"] = true
+	b = Builder{}
+	if _, _, err := b.Prepare(config); err == nil || !strings.Contains(err.Error(), "skip_key_cleanup") {
+		t.Fatalf("expected an error with shared_temporary_key, got %v", err)
+	}
+}
+
+func TestBuilderPrepare_FreezeMountPoints(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["intermediate_snapshots"] = true
+	config["freeze_mount_points"] = []string{"/data"}
+	if _, _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		extra map[string]interface{}
+	}{
+		{"no intermediate snapshots", map[string]interface{}{"freeze_mount_points": []string{"/data"}}},
+		{"root", map[string]interface{}{"intermediate_snapshots": true, "freeze_mount_points": []string{"/"}}},
+		{"relative", map[string]interface{}{"intermediate_snapshots": true, "freeze_mount_points": []string{"data"}}},
+		{"quote", map[string]interface{}{"intermediate_snapshots": true, "freeze_mount_points": []string{"/data'; reboot"}}},
+	} {
+		config := testConfig()
+		for k, v := range tc.extra {
+			config[k] = v
+		}
+		b = Builder{}
+		if _, _, err := b.Prepare(config); err == nil || !strings.Contains(err.Error(), "freeze_mount_points") {
+			t.Errorf("%s: expected a freeze_mount_points error, got %v", tc.name, err)
+		}
+	}
+}
+
+func TestBuilderPrepare_ReservedIP(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["reserved_ip"] = "203.0.113.10"
+	if _, _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		extra map[string]interface{}
+	}{
+		{"not an address", map[string]interface{}{"reserved_ip": "web"}},
+		{"ipv6", map[string]interface{}{"reserved_ip": "2001:db8::10"}},
+		{"auto region", map[string]interface{}{"reserved_ip": "203.0.113.10", "region": "auto"}},
+	} {
+		config := testConfig()
+		for k, v := range tc.extra {
+			config[k] = v
+		}
+		b = Builder{}
+		if _, _, err := b.Prepare(config); err == nil || !strings.Contains(err.Error(), "reserved_ip") {
+			t.Errorf("%s: expected a reserved_ip error, got %v", tc.name, err)
+		}
+	}
+}
+
+func TestBuilderPrepare_SnapshotRegionsBuildRegion(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["snapshot_regions"] = []string{"nyc2", "sfo3"}
+
+	_, warnings, err := b.Prepare(config)
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "snapshot_regions includes nyc2") {
+		t.Fatalf("expected a warning about the build region, got %#v", warnings)
+	}
 }
 
 func TestBuilderPrepare_SnapshotName(t *testing.T) {
@@ -299,6 +433,28 @@ func TestBuilderPrepare_SnapshotName(t *testing.T) {
 		t.Fatalf("failed to parse int in template: %s", err)
 	}
 
+	// Test names the API rejects
+	for _, name := range []string{"ubuntu/20.04", " ubuntu", strings.Repeat("a", 256)} {
+		config["snapshot_name"] = name
+		b = Builder{}
+		if _, _, err = b.Prepare(config); err == nil {
+			t.Fatalf("should have error for %q", name)
+		}
+	}
+}
+
+func TestCheckImageName(t *testing.T) {
+	if err := checkImageName("ubuntu-20.04_base 2021-06-01T10:00:00Z"); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	err := checkImageName("ubuntu/20.04")
+	if err == nil || err.Error() != `contains '/', only letters, digits, spaces, '.', '_', '-' and ':' are allowed` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = checkImageName(strings.Repeat("a", 300))
+	if err == nil || err.Error() != "is 300 characters long, the maximum is 255" {
+		t.Fatalf("unexpected error: %v", err)
+	}
 }
 
 func TestBuilderPrepare_DropletName(t *testing.T) {
@@ -351,6 +507,29 @@ func TestBuilderPrepare_DropletName(t *testing.T) {
 		t.Fatal("should have error")
 	}
 
+	// Fully qualified
+	config["droplet_name"] = "web-1.example.com"
+	b = Builder{}
+	if _, _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	// Not a host name
+	for _, name := range []string{
+		"web_1",
+		"web 1",
+		"-web",
+		"web.",
+		"web..example",
+		strings.Repeat("a", 64),
+		strings.Repeat("a.", 128) + "a",
+	} {
+		config["droplet_name"] = name
+		b = Builder{}
+		if _, _, err := b.Prepare(config); err == nil || !strings.Contains(err.Error(), "droplet_name") {
+			t.Errorf("droplet_name %q: expected an error, got %v", name, err)
+		}
+	}
 }
 
 func TestBuilderPrepare_VPCUUID(t *testing.T) {
@@ -404,3 +583,1074 @@ func TestBuilderPrepare_ConnectWithPrivateIP(t *testing.T) {
 		t.Fatal("should not have error")
 	}
 }
+
+func TestBuilderPrepare_Distribution(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test with both image and distribution
+	config["distribution"] = "Ubuntu"
+	config["version"] = "22.04"
+	if _, _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error: only one of image, source_image_filter, distribution or source_build can be set")
+	}
+
+	delete(config, "image")
+	b = Builder{}
+	_, warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	// Test with a version but no distribution
+	delete(config, "distribution")
+	config["image"] = "foo"
+	b = Builder{}
+	if _, _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error: version requires distribution")
+	}
+}
+
+func TestBuilderPrepare_UserDataURL(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	config["user_data_file"] = "https://artifacts.example.com/cloud-init.yaml"
+	config["user_data_checksum"] = "sha256:" + strings.Repeat("ab", 32)
+	_, warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	config["user_data_file"] = "http://artifacts.example.com/cloud-init.yaml"
+	b = Builder{}
+	if _, _, err = b.Prepare(config); err == nil {
+		t.Fatal("should have error: user_data_file can only be downloaded over https")
+	}
+
+	config["user_data_file"] = "https://artifacts.example.com/cloud-init.yaml"
+	config["user_data_checksum"] = "md5:d41d8cd98f00b204e9800998ecf8427e"
+	b = Builder{}
+	if _, _, err = b.Prepare(config); err == nil {
+		t.Fatal("should have error: unsupported hash type")
+	}
+}
+
+func TestBuilderPrepare_UserDataVars(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	config["user_data"] = "password: @{password}"
+	config["user_data_vars"] = map[string]string{"password": "hunter2"}
+	config["user_data_sensitive_vars"] = []string{"password"}
+	_, warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	// Test with a placeholder without a value
+	config["user_data"] = "password: @{password}\ntoken: @{token}"
+	b = Builder{}
+	if _, _, err = b.Prepare(config); err == nil {
+		t.Fatal("should have error: no user_data_vars value for token")
+	}
+
+	// Test with a sensitive name that isn't a variable
+	config["user_data"] = "password: @{password}"
+	config["user_data_sensitive_vars"] = []string{"token"}
+	b = Builder{}
+	if _, _, err = b.Prepare(config); err == nil {
+		t.Fatal("should have error: token isn't in user_data_vars")
+	}
+}
+
+func TestBuilderPrepare_BudgetAction(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test default
+	_, warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.BudgetAction != "fail" {
+		t.Errorf("found %s, expected fail", b.config.BudgetAction)
+	}
+
+	// Test invalid
+	config["budget_action"] = "ignore"
+	b = Builder{}
+	_, _, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test negative limit
+	config["budget_action"] = "warn"
+	config["max_hourly_price"] = -1
+	b = Builder{}
+	_, _, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_PinSSHHostKey(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	config["pin_ssh_host_key"] = true
+	_, warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	config["communicator"] = "none"
+	b = Builder{}
+	_, _, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_SSHUsername(t *testing.T) {
+	tt := []struct {
+		image    string
+		username string
+	}{
+		{"ubuntu-20-04-x64", "root"},
+		{"fedora-coreos-34-x64", "core"},
+		{"rancheros", "rancher"},
+		{"123456", "root"},
+	}
+
+	for _, tc := range tt {
+		var b Builder
+		config := testConfig()
+		delete(config, "ssh_username")
+		config["image"] = tc.image
+
+		_, warnings, err := b.Prepare(config)
+		if len(warnings) > 0 {
+			t.Fatalf("bad: %#v", warnings)
+		}
+		if err != nil {
+			t.Fatalf("should not have error: %s", err)
+		}
+		if b.config.Comm.SSHUsername != tc.username {
+			t.Errorf("image %s: found %s, expected %s", tc.image, b.config.Comm.SSHUsername, tc.username)
+		}
+	}
+
+	// An explicit username is kept
+	var b Builder
+	config := testConfig()
+	config["image"] = "fedora-coreos-34-x64"
+	if _, _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.Comm.SSHUsername != "root" {
+		t.Errorf("found %s, expected root", b.config.Comm.SSHUsername)
+	}
+}
+
+func TestBuilderPrepare_SourceImageFilter(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test both set
+	config["source_image_filter"] = map[string]interface{}{
+		"name":        "^base-",
+		"most_recent": true,
+	}
+	_, _, err := b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test only the filter
+	delete(config, "image")
+	b = Builder{}
+	_, warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	// Test invalid regex
+	config["source_image_filter"] = map[string]interface{}{"name": "base-("}
+	b = Builder{}
+	_, _, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestExpandSnapshotRegions(t *testing.T) {
+	regions := []godo.Region{
+		{Slug: "nyc1", Available: true},
+		{Slug: "nyc2", Available: false},
+		{Slug: "nyc3", Available: true},
+		{Slug: "ams3", Available: true},
+	}
+
+	tt := []struct {
+		in  []string
+		out []string
+	}{
+		{[]string{"nyc3", "ams3"}, []string{"nyc3", "ams3"}},
+		{[]string{"all"}, []string{"nyc1", "nyc3", "ams3"}},
+		{[]string{"all", "!nyc1"}, []string{"nyc3", "ams3"}},
+		{[]string{"!ams3", "all", "nyc3"}, []string{"nyc1", "nyc3"}},
+		{[]string{"nyc3", "!nyc3"}, []string{}},
+	}
+
+	for _, tc := range tt {
+		out := expandSnapshotRegions(tc.in, regions)
+		if !reflect.DeepEqual(out, tc.out) {
+			t.Errorf("expandSnapshotRegions(%v) = %v, expected %v", tc.in, out, tc.out)
+		}
+	}
+}
+
+func TestBuilderPrepare_RegionStrategy(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test default
+	config["region"] = "auto"
+	_, warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.RegionStrategy != "first-available" {
+		t.Errorf("found %s, expected first-available", b.config.RegionStrategy)
+	}
+
+	// Test default with preferences
+	config["region_preference"] = []string{"ams3", "fra1"}
+	b = Builder{}
+	_, _, err = b.Prepare(config)
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.RegionStrategy != "preference" {
+		t.Errorf("found %s, expected preference", b.config.RegionStrategy)
+	}
+
+	// Test invalid
+	config["region_strategy"] = "cheapest"
+	b = Builder{}
+	_, _, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test preference without preferences
+	delete(config, "region_preference")
+	config["region_strategy"] = "preference"
+	b = Builder{}
+	_, _, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test with a VPC
+	config["region_strategy"] = "latency"
+	config["vpc_uuid"] = "3004fd30-6a50-4b5c-8be2-0d0ce181ffc2"
+	b = Builder{}
+	_, _, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_Tags(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	config["tags"] = []string{"packer", "build-date:{{isotime \"2006-01-02\"}}"}
+	config["snapshot_tags"] = []string{"golden", "build-id:{{uuid}}"}
+	_, warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	expected := "build-date:" + time.Now().UTC().Format("2006-01-02")
+	if b.config.Tags[1] != expected {
+		t.Errorf("found %s, expected %s", b.config.Tags[1], expected)
+	}
+	if b.config.SnapshotTags[1] == "build-id:{{uuid}}" || len(b.config.SnapshotTags[1]) != len("build-id:")+36 {
+		t.Errorf("snapshot tag not interpolated: %s", b.config.SnapshotTags[1])
+	}
+
+	// Test invalid
+	config["snapshot_tags"] = []string{"golden image"}
+	b = Builder{}
+	_, _, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_RequiredTags(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	config["required_tags"] = []string{"owner", "cost-center"}
+	config["tags"] = []string{"owner:ops", "cost-center:1234"}
+	config["snapshot_tags"] = []string{"owner:ops", "cost-center"}
+	_, warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	// Test missing on the snapshot
+	config["snapshot_tags"] = []string{"owner:ops", "cost-centre:1234"}
+	b = Builder{}
+	_, _, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test policy from the environment
+	delete(config, "required_tags")
+	t.Setenv("DIGITALOCEAN_REQUIRED_TAGS", "owner, team")
+	b = Builder{}
+	_, _, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+	if !reflect.DeepEqual(b.config.RequiredTags, []string{"owner", "team"}) {
+		t.Errorf("found %v, expected [owner team]", b.config.RequiredTags)
+	}
+}
+
+func TestBuilderPrepare_LogLevel(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test default
+	_, _, err := b.Prepare(config)
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.LogLevel != "info" {
+		t.Errorf("found %s, expected info", b.config.LogLevel)
+	}
+
+	// Test invalid
+	config["log_level"] = "trace"
+	b = Builder{}
+	_, _, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_Blocks(t *testing.T) {
+	var b Builder
+	config := map[string]interface{}{
+		"api_token":    "bar",
+		"ssh_username": "root",
+		"droplet": map[string]interface{}{
+			"region":             "nyc3",
+			"size":               "s-1vcpu-1gb",
+			"image":              "ubuntu-20-04-x64",
+			"name":               "packer-base",
+			"private_networking": true,
+			"state_timeout":      "10m",
+		},
+		"snapshot": map[string]interface{}{
+			"name":    "base",
+			"timeout": "30m",
+		},
+		"connection": map[string]interface{}{
+			"private_ip": true,
+		},
+	}
+	_, warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if b.config.Region != "nyc3" || b.config.Size != "s-1vcpu-1gb" || b.config.Image != "ubuntu-20-04-x64" {
+		t.Errorf("droplet block not applied: %s, %s, %s", b.config.Region, b.config.Size, b.config.Image)
+	}
+	if b.config.DropletName != "packer-base" || !b.config.PrivateNetworking || b.config.StateTimeout != 10*time.Minute {
+		t.Errorf("droplet block not applied: %s, %t, %s", b.config.DropletName, b.config.PrivateNetworking, b.config.StateTimeout)
+	}
+	if b.config.SnapshotName != "base" || b.config.SnapshotTimeout != 30*time.Minute {
+		t.Errorf("snapshot block not applied: %s, %s", b.config.SnapshotName, b.config.SnapshotTimeout)
+	}
+	if !b.config.ConnectWithPrivateIP {
+		t.Error("connection block not applied")
+	}
+
+	// Test conflicting with the deprecated option
+	config["region"] = "ams3"
+	b = Builder{}
+	_, _, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_BlocksDeprecation(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["state_timeout"] = "5m"
+	config["snapshot_timeout"] = "15m"
+	config["droplet"] = map[string]interface{}{
+		"private_networking": true,
+	}
+	_, warnings, err := b.Prepare(config)
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	// Only for the top-level options of blocks the template uses
+	expected := []string{
+		"region is deprecated, set droplet.region instead",
+		"size is deprecated, set droplet.size instead",
+		"image is deprecated, set droplet.image instead",
+		"state_timeout is deprecated, set droplet.state_timeout instead",
+	}
+	if !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("got warnings %q, expected %q", warnings, expected)
+	}
+	if b.config.StateTimeout != 5*time.Minute || b.config.SnapshotTimeout != 15*time.Minute || !b.config.PrivateNetworking {
+		t.Errorf("unexpected config: %s, %s, %t", b.config.StateTimeout, b.config.SnapshotTimeout, b.config.PrivateNetworking)
+	}
+}
+
+func TestBuilderPrepare_TemporaryBastion(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["temporary_bastion"] = true
+	if _, _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error: 'private networking should be enabled to use temporary_bastion'")
+	}
+
+	config["droplet"] = map[string]interface{}{"private_networking": true}
+	b = Builder{}
+	if _, _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if !b.config.ConnectWithPrivateIP || b.config.TemporaryBastionSize != "s-1vcpu-1gb" ||
+		b.config.TemporaryBastionImage != "ubuntu-22-04-x64" {
+		t.Errorf("unexpected config: %t, %s, %s", b.config.ConnectWithPrivateIP, b.config.TemporaryBastionSize,
+			b.config.TemporaryBastionImage)
+	}
+
+	config["ssh_bastion_host"] = "bastion.example.com"
+	b = Builder{}
+	if _, _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error: 'temporary_bastion and ssh_bastion_host can't both be set'")
+	}
+}
+
+func TestBuilderPrepare_DropletAgent(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	if _, _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.DropletAgent.ToBoolPointer() != nil {
+		t.Errorf("expected the API to choose, got %s", b.config.DropletAgent.ToString())
+	}
+
+	config["droplet_agent"] = false
+	config["wait_for_droplet_agent"] = true
+	b = Builder{}
+	if _, _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	config["droplet_agent"] = true
+	b = Builder{}
+	if _, _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if !b.config.DropletAgent.True() || !b.config.WaitForDropletAgent {
+		t.Errorf("unexpected config: %s, %t", b.config.DropletAgent.ToString(), b.config.WaitForDropletAgent)
+	}
+}
+
+func TestBuilderPrepare_EnvDefaults(t *testing.T) {
+	t.Setenv("DIGITALOCEAN_REGION", "ams3")
+	t.Setenv("DIGITALOCEAN_SIZE", "s-2vcpu-2gb")
+	t.Setenv("DIGITALOCEAN_PRIVATE_NETWORKING", "true")
+	t.Setenv("DIGITALOCEAN_VPC_UUID", "3004fd30-6a50-4b5c-8be2-0d0ce181ffc2")
+	t.Setenv("DIGITALOCEAN_SNAPSHOT_REGIONS", "nyc3, sfo3")
+	t.Setenv("DIGITALOCEAN_STATE_TIMEOUT", "10m")
+
+	var b Builder
+	config := testConfig()
+	delete(config, "region")
+	_, warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if b.config.Region != "ams3" {
+		t.Errorf("found region %s, expected ams3", b.config.Region)
+	}
+	// Set in the template
+	if b.config.Size != "512mb" {
+		t.Errorf("found size %s, expected 512mb", b.config.Size)
+	}
+	if !b.config.PrivateNetworking || b.config.VPCUUID != "3004fd30-6a50-4b5c-8be2-0d0ce181ffc2" {
+		t.Errorf("found private_networking %t and vpc_uuid %s", b.config.PrivateNetworking, b.config.VPCUUID)
+	}
+	if !reflect.DeepEqual(b.config.SnapshotRegions, []string{"nyc3", "sfo3"}) {
+		t.Errorf("found snapshot_regions %v", b.config.SnapshotRegions)
+	}
+	if b.config.StateTimeout != 10*time.Minute {
+		t.Errorf("found state_timeout %s", b.config.StateTimeout)
+	}
+
+	// Test set in a block
+	config["droplet"] = map[string]interface{}{"private_networking": false}
+	delete(config, "vpc_uuid")
+	t.Setenv("DIGITALOCEAN_VPC_UUID", "")
+	b = Builder{}
+	_, _, err = b.Prepare(config)
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.PrivateNetworking {
+		t.Error("expected private_networking from the droplet block")
+	}
+
+	// Test invalid
+	t.Setenv("DIGITALOCEAN_STATE_TIMEOUT", "ten minutes")
+	b = Builder{}
+	_, _, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_EnvDefaultsExclusive(t *testing.T) {
+	t.Setenv("DIGITALOCEAN_SIZE", "s-2vcpu-2gb")
+	t.Setenv("DIGITALOCEAN_IMAGE", "ubuntu-22-04-x64")
+
+	// Ignored for the options they can't be combined with
+	var b Builder
+	config := testConfig()
+	delete(config, "size")
+	delete(config, "image")
+	config["min_vcpus"] = 2
+	config["distribution"] = "Ubuntu"
+	config["version"] = "22.04"
+	if _, _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.Size != "" || b.config.Image != "" {
+		t.Errorf("found size %q and image %q, expected neither", b.config.Size, b.config.Image)
+	}
+
+	// Options outside of the list are never taken from the environment
+	t.Setenv("DIGITALOCEAN_VALIDATE_ONLY", "true")
+	t.Setenv("DIGITALOCEAN_MAX_BUILD_DURATION", "1m")
+	b = Builder{}
+	if _, _, err := b.Prepare(testConfig()); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.ValidateOnly || b.config.MaxBuildDuration != 0 {
+		t.Errorf("found validate_only %t and max_build_duration %s", b.config.ValidateOnly, b.config.MaxBuildDuration)
+	}
+}
+
+func TestBuilderPrepare_DefaultsFile(t *testing.T) {
+	dir := t.TempDir()
+	hclPath := filepath.Join(dir, "defaults.pkrvars.hcl")
+	err := ioutil.WriteFile(hclPath, []byte(`
+snapshot_regions = ["nyc3", "sfo3"]
+tags             = ["team:platform"]
+state_timeout    = "10m"
+size             = "s-2vcpu-2gb"
+droplet = {
+  private_networking = true
+  vpc_uuid           = "3004fd30-6a50-4b5c-8be2-0d0ce181ffc2"
+}
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b Builder
+	config := testConfig()
+	config["defaults_file"] = hclPath
+	_, warnings, err := b.Prepare(config)
+	// The file uses the droplet block, along with its top-level options
+	for _, warning := range warnings {
+		if !strings.Contains(warning, "is deprecated, set droplet.") {
+			t.Fatalf("bad: %#v", warnings)
+		}
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if !reflect.DeepEqual(b.config.SnapshotRegions, []string{"nyc3", "sfo3"}) {
+		t.Errorf("found snapshot_regions %v", b.config.SnapshotRegions)
+	}
+	if !reflect.DeepEqual(b.config.Tags, []string{"team:platform"}) {
+		t.Errorf("found tags %v", b.config.Tags)
+	}
+	if b.config.StateTimeout != 10*time.Minute {
+		t.Errorf("found state_timeout %s", b.config.StateTimeout)
+	}
+	if b.config.VPCUUID != "3004fd30-6a50-4b5c-8be2-0d0ce181ffc2" {
+		t.Errorf("found vpc_uuid %s", b.config.VPCUUID)
+	}
+	// Set in the template
+	if b.config.Size != "512mb" {
+		t.Errorf("found size %s, expected 512mb", b.config.Size)
+	}
+
+	// Test JSON
+	jsonPath := filepath.Join(dir, "defaults.json")
+	err = ioutil.WriteFile(jsonPath, []byte(`{"region": "ams3", "tags": ["team:platform"]}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config = testConfig()
+	delete(config, "region")
+	config["defaults_file"] = jsonPath
+	b = Builder{}
+	_, _, err = b.Prepare(config)
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.Region != "ams3" {
+		t.Errorf("found region %s, expected ams3", b.config.Region)
+	}
+
+	// Test missing
+	config["defaults_file"] = filepath.Join(dir, "missing.json")
+	b = Builder{}
+	_, _, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_SharedTemporaryKey(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test outside of a Packer run
+	t.Setenv("PACKER_RUN_UUID", "")
+	config["shared_temporary_key"] = true
+	if _, _, err := b.Prepare(config); err == nil || !strings.Contains(err.Error(), "PACKER_RUN_UUID") {
+		t.Fatalf("expected an error without PACKER_RUN_UUID, got %v", err)
+	}
+
+	t.Setenv("PACKER_RUN_UUID", "7c1d2e3f-4a5b-6c7d-8e9f-0a1b2c3d4e5f")
+	b = Builder{}
+	_, warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if !b.config.SharedTemporaryKey {
+		t.Error("expected shared_temporary_key to be set")
+	}
+
+	// Test with checkpoint_file
+	config["checkpoint_file"] = "checkpoint.json"
+	b = Builder{}
+	_, _, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_SnapshotVersionPrefix(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	config["snapshot_version_prefix"] = "myimage-"
+	generated, warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if !reflect.DeepEqual(generated, []string{"SnapshotVersion"}) {
+		t.Errorf("found generated data %v", generated)
+	}
+	if b.config.SnapshotName != "" {
+		t.Errorf("found snapshot_name %s, expected none before the build", b.config.SnapshotName)
+	}
+
+	// Test with snapshot_name
+	config["snapshot_name"] = "myimage"
+	b = Builder{}
+	_, _, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_Webhooks(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["webhooks"] = []string{"https://bot.example.com/packer?token=abc", "http://10.0.0.5:8080/hook"}
+	if _, _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	for _, webhook := range []string{"bot.example.com/packer", "ftp://bot.example.com", "https://", "://x"} {
+		config := testConfig()
+		config["webhooks"] = []string{webhook}
+		b = Builder{}
+		if _, _, err := b.Prepare(config); err == nil {
+			t.Errorf("should have error for webhook %q", webhook)
+		}
+	}
+}
+
+func TestBuilderPrepare_AutoTagCI(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("GITHUB_SHA", "6fe3c9a1b2d4e5f60718293a4b5c6d7e8f901234")
+	t.Setenv("GITHUB_REF_NAME", "main")
+	t.Setenv("GITHUB_RUN_ID", "9120391")
+	t.Setenv("GITHUB_JOB", "images")
+
+	var b Builder
+	config := testConfig()
+	config["auto_tag_ci"] = true
+	config["snapshot_tags"] = []string{"git-sha:pinned"}
+	if _, _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	expected := []string{"ci:github", "git-sha:6fe3c9a1b2d4e5f60718293a4b5c6d7e8f901234", "git-branch:main",
+		"ci-pipeline:9120391", "ci-job:images"}
+	if !reflect.DeepEqual(b.config.Tags, expected) {
+		t.Errorf("got tags %q, expected %q", b.config.Tags, expected)
+	}
+	expected = []string{"git-sha:pinned", "ci:github", "git-branch:main", "ci-pipeline:9120391", "ci-job:images"}
+	if !reflect.DeepEqual(b.config.SnapshotTags, expected) {
+		t.Errorf("got snapshot tags %q, expected %q", b.config.SnapshotTags, expected)
+	}
+}
+
+func TestBuilderPrepare_SnapshotDescription(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["snapshot_description"] = "Built from {{ .SourceImage }}"
+	if _, _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.SnapshotDescription != "Built from {{ .SourceImage }}" {
+		t.Errorf("expected the description to be rendered later, got %q", b.config.SnapshotDescription)
+	}
+
+	config["snapshot_description"] = "{{ .SourceImage"
+	b = Builder{}
+	if _, _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_APITokens(t *testing.T) {
+	t.Setenv("DIGITALOCEAN_API_TOKEN", "")
+	var b Builder
+	config := testConfig()
+	delete(config, "api_token")
+	config["api_tokens"] = []string{"first", "second", "first"}
+	if _, _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.APIToken != "first" {
+		t.Errorf("expected api_token to default to the first token, got %q", b.config.APIToken)
+	}
+	if tokens := b.config.apiTokens(); !reflect.DeepEqual(tokens, []string{"first", "second"}) {
+		t.Errorf("unexpected tokens %q", tokens)
+	}
+}
+
+func TestBuilderPrepare_OTLPEndpoint(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "Authorization=Bearer abc")
+	var b Builder
+	if _, _, err := b.Prepare(testConfig()); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.OTLPEndpoint != "http://localhost:4318" || b.config.OTLPHeaders["Authorization"] != "Bearer abc" {
+		t.Errorf("expected the environment defaults, got %q %v", b.config.OTLPEndpoint, b.config.OTLPHeaders)
+	}
+
+	config := testConfig()
+	config["otlp_endpoint"] = "localhost:4318"
+	b = Builder{}
+	if _, _, err := b.Prepare(config); err == nil {
+		t.Error("should have error")
+	}
+}
+
+func TestBuilderPrepare_Volumes(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["droplet_name"] = "Web.Example"
+	config["volume"] = []map[string]interface{}{
+		{"size": 10},
+		{"name": "data", "size": 100, "filesystem_type": "xfs", "mount_path": "/srv/data"},
+	}
+	_, warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if name := b.config.Volumes[0].Name; name != "web-example-0" {
+		t.Errorf("expected default volume name web-example-0, got %s", name)
+	}
+
+	invalid := []map[string]interface{}{
+		{"size": 0},
+		{"name": "Data", "size": 10},
+		{"size": 10, "filesystem_type": "btrfs"},
+		{"size": 10, "mount_path": "/srv/data"},
+		{"size": 10, "filesystem_type": "ext4", "mount_path": "srv/data"},
+		{"size": 10, "filesystem_type": "ext4", "mount_path": "/srv/my data"},
+	}
+	for _, volume := range invalid {
+		config := testConfig()
+		config["volume"] = []map[string]interface{}{volume}
+		b = Builder{}
+		if _, _, err := b.Prepare(config); err == nil {
+			t.Errorf("should have error for volume %v", volume)
+		}
+	}
+
+	config = testConfig()
+	config["volume"] = []map[string]interface{}{
+		{"size": 10, "filesystem_type": "ext4", "mount_path": "/srv"},
+		{"size": 10, "filesystem_type": "ext4", "mount_path": "/srv"},
+	}
+	b = Builder{}
+	if _, _, err := b.Prepare(config); err == nil {
+		t.Error("should have error for a mount_path used twice")
+	}
+
+	config = testConfig()
+	config["region"] = "auto"
+	config["volume"] = []map[string]interface{}{{"size": 10}}
+	b = Builder{}
+	if _, _, err := b.Prepare(config); err == nil {
+		t.Error("should have error for volumes with region auto")
+	}
+}
+
+func TestDefaultVolumeName(t *testing.T) {
+	cases := map[string]string{
+		"packer-6110a3c2":        "packer-6110a3c2-1",
+		"Web_Server.example.com": "web-server-example-com-1",
+		"1st":                    "packer-1st-1",
+		"...":                    "packer-1",
+		strings.Repeat("a", 80):  strings.Repeat("a", 62) + "-1",
+		strings.Repeat("a-", 40): strings.Repeat("a-", 30) + "a-1",
+	}
+	for droplet, expected := range cases {
+		if name := defaultVolumeName(droplet, 1); name != expected {
+			t.Errorf("defaultVolumeName(%q) = %q, expected %q", droplet, name, expected)
+		}
+		if !volumeNameRe.MatchString(defaultVolumeName(droplet, 1)) {
+			t.Errorf("defaultVolumeName(%q) is not a valid volume name", droplet)
+		}
+	}
+}
+
+func TestBuilderPrepare_SnapshotVolumes(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["snapshot_volumes"] = true
+	if _, _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error without a volume")
+	}
+
+	config["volume"] = []map[string]interface{}{{"name": "data", "size": 100}}
+	b = Builder{}
+	if _, _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	delete(config, "snapshot_volumes")
+	config["snapshot"] = map[string]interface{}{"volumes": true}
+	b = Builder{}
+	if _, _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if !b.config.SnapshotVolumes {
+		t.Error("expected snapshot.volumes to set snapshot_volumes")
+	}
+}
+
+func TestBuilderPrepare_SourceBuild(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test with both image and source_build
+	config["source_build"] = "digitalocean.base"
+	if _, _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error: only one of image, source_image_filter, distribution or source_build can be set")
+	}
+
+	delete(config, "image")
+	b = Builder{}
+	_, warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.SourceBuildTimeout != time.Hour {
+		t.Errorf("expected a source_build_timeout of 1h, got %s", b.config.SourceBuildTimeout)
+	}
+
+	// Test with the build itself
+	config["packer_build_name"] = "digitalocean.base"
+	b = Builder{}
+	if _, _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error: source_build can't be the build itself")
+	}
+}
+
+func TestBuilderPrepare_SkipUnchanged(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test input_files without skip_unchanged
+	config["input_files"] = []string{"builder_test.go"}
+	if _, _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error: input_files requires skip_unchanged")
+	}
+
+	config["skip_unchanged"] = true
+	b = Builder{}
+	_, warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	// Test a missing file
+	config["input_files"] = []string{"missing.sh"}
+	b = Builder{}
+	if _, _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error: missing input file")
+	}
+}
+
+func TestBuilderPrepare_SnapshotDiff(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test without an image to compare to
+	config["snapshot_diff_file"] = "diff.txt"
+	if _, _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error: snapshot_diff_file requires snapshot_diff_image or snapshot_version_prefix")
+	}
+
+	config["snapshot_version_prefix"] = "web-"
+	b = Builder{}
+	_, warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	// Test snapshot_diff_image without snapshot_diff_file
+	delete(config, "snapshot_diff_file")
+	config["snapshot_diff_image"] = "web-v1"
+	b = Builder{}
+	if _, _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error: snapshot_diff_image requires snapshot_diff_file")
+	}
+}
+
+func TestBuilderPrepare_WinRM(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test skip_winrm_setup with SSH
+	config["skip_winrm_setup"] = true
+	if _, _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error: skip_winrm_setup requires the winrm communicator")
+	}
+
+	delete(config, "skip_winrm_setup")
+	config["communicator"] = "winrm"
+	b = Builder{}
+	_, warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.Comm.WinRMUser != "Administrator" {
+		t.Errorf("expected winrm_username Administrator, got %q", b.config.Comm.WinRMUser)
+	}
+	if len(b.config.Comm.WinRMPassword) < 20 {
+		t.Errorf("expected a generated winrm_password, got %q", b.config.Comm.WinRMPassword)
+	}
+	if b.config.Comm.WinRMPort != 5985 {
+		t.Errorf("expected winrm_port 5985, got %d", b.config.Comm.WinRMPort)
+	}
+
+	// Test an image enabling WinRM itself
+	config["skip_winrm_setup"] = true
+	b = Builder{}
+	if _, _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.Comm.WinRMPassword != "" {
+		t.Errorf("unexpected winrm_password %q", b.config.Comm.WinRMPassword)
+	}
+}
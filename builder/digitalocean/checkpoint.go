@@ -0,0 +1,153 @@
+package digitalocean
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+// checkpoint is what checkpoint_file records about a build, so that a
+// build interrupted once the droplet is ready to be snapshotted can resume
+// from there.
+type checkpoint struct {
+	DropletID     int  `json:"droplet_id"`
+	SSHKeyID      int  `json:"ssh_key_id,omitempty"`
+	SnapshotReady bool `json:"snapshot_ready"`
+}
+
+// loadCheckpoint reads the checkpoint at path, returning nil when there is
+// none.
+func loadCheckpoint(path string) (*checkpoint, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cp := new(checkpoint)
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %s: %s", path, err)
+	}
+	return cp, nil
+}
+
+func (cp *checkpoint) save(path string) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	// Written to a temporary file first, so that a crash never leaves a
+	// truncated checkpoint behind
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// resumeCheckpoint decides what to do with the checkpoint left by a previous
+// run. A droplet that was ready to be snapshotted is adopted, and true is
+// returned. Otherwise whatever the previous run left behind is deleted, so
+// that the build starts over without leaking resources.
+func resumeCheckpoint(client *godo.Client, cp *checkpoint, path string, timeout time.Duration) (bool, error) {
+	droplet, _, err := client.Droplets.Get(context.TODO(), cp.DropletID)
+	if err != nil && !isNotFound(err) {
+		return false, fmt.Errorf("Error looking up droplet %d from checkpoint: %s", cp.DropletID, err)
+	}
+	if err == nil && cp.SnapshotReady && droplet.Status != "archive" {
+		return true, nil
+	}
+
+	if err == nil {
+		if err := DestroyDroplet(client, cp.DropletID, timeout); err != nil {
+			return false, fmt.Errorf("Error destroying droplet %d from checkpoint: %s", cp.DropletID, err)
+		}
+	}
+	if cp.SSHKeyID != 0 {
+		if _, err := client.Keys.DeleteByID(context.TODO(), cp.SSHKeyID); err != nil && !isNotFound(err) {
+			return false, fmt.Errorf("Error deleting SSH key %d from checkpoint: %s", cp.SSHKeyID, err)
+		}
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	return false, nil
+}
+
+// resumedCheckpoint returns the checkpoint the build resumes from, if any.
+func resumedCheckpoint(state multistep.StateBag) (*checkpoint, bool) {
+	cp, ok := state.GetOk("resumed_checkpoint")
+	if !ok {
+		return nil, false
+	}
+	return cp.(*checkpoint), true
+}
+
+// stepCheckpoint records the build droplet in checkpoint_file. The step
+// marking the droplet as ready to be snapshotted keeps the droplet around
+// when a later step fails, and the first one removes the checkpoint once
+// the droplet is gone.
+type stepCheckpoint struct {
+	snapshotReady bool
+	saved         bool
+}
+
+func (s *stepCheckpoint) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := newStepUi(state, "checkpoint")
+	c := state.Get("config").(*Config)
+
+	cp := &checkpoint{
+		DropletID:     state.Get("droplet_id").(int),
+		SnapshotReady: s.snapshotReady,
+	}
+	if sshKeyId, ok := state.GetOk("ssh_key_id"); ok {
+		cp.SSHKeyID = sshKeyId.(int)
+	}
+	if _, ok := resumedCheckpoint(state); ok {
+		cp.SnapshotReady = true
+	}
+
+	if err := cp.save(c.CheckpointFile); err != nil {
+		err := fmt.Errorf("Error writing checkpoint: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	s.saved = true
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCheckpoint) Cleanup(state multistep.StateBag) {
+	if !s.saved {
+		return
+	}
+
+	c := state.Get("config").(*Config)
+	ui := newStepUi(state, "checkpoint")
+
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if s.snapshotReady {
+		if halted && !cancelled {
+			ui.Say(fmt.Sprintf("Keeping droplet %d to resume the build from %s, run it again to continue",
+				state.Get("droplet_id").(int), c.CheckpointFile))
+			state.Put("keep_droplet", true)
+		}
+		return
+	}
+
+	if _, ok := state.GetOk("keep_droplet"); ok {
+		return
+	}
+	if err := os.Remove(c.CheckpointFile); err != nil && !os.IsNotExist(err) {
+		ui.Error(fmt.Sprintf("Error removing checkpoint: %s", err))
+	}
+}
@@ -0,0 +1,108 @@
+package digitalocean
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestResumeCheckpoint(t *testing.T) {
+	for _, ready := range []bool{true, false} {
+		sim, client := testSimulator(t)
+		droplet := sim.AddDroplet(godo.Droplet{Name: "packer-test", Status: "active"})
+		key := sim.AddKey(godo.Key{Name: "packer-test"})
+
+		path := filepath.Join(t.TempDir(), "checkpoint.json")
+		cp := &checkpoint{DropletID: droplet.ID, SSHKeyID: key.ID, SnapshotReady: ready}
+		if err := cp.save(path); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := loadCheckpoint(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *loaded != *cp {
+			t.Fatalf("got %#v, expected %#v", loaded, cp)
+		}
+
+		resumed, err := resumeCheckpoint(client, loaded, path, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resumed != ready {
+			t.Fatalf("snapshot_ready = %t, but resumed = %t", ready, resumed)
+		}
+		if _, ok := sim.Droplet(droplet.ID); ok != ready {
+			t.Errorf("snapshot_ready = %t, but droplet exists = %t", ready, ok)
+		}
+		if len(sim.Keys()) == 0 == ready {
+			t.Errorf("snapshot_ready = %t, but keys are %#v", ready, sim.Keys())
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) == ready {
+			t.Errorf("snapshot_ready = %t, but checkpoint exists = %t", ready, err == nil)
+		}
+	}
+
+	if cp, err := loadCheckpoint(filepath.Join(t.TempDir(), "missing.json")); cp != nil || err != nil {
+		t.Errorf("expected no checkpoint, got %#v, %v", cp, err)
+	}
+}
+
+func TestStepCheckpoint(t *testing.T) {
+	tt := []struct {
+		name      string
+		halted    bool
+		cancelled bool
+		kept      bool
+	}{
+		{name: "success"},
+		{name: "failed", halted: true, kept: true},
+		{name: "cancelled", halted: true, cancelled: true},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "checkpoint.json")
+			state := new(multistep.BasicStateBag)
+			state.Put("ui", packersdk.TestUi(t))
+			state.Put("config", &Config{CheckpointFile: path})
+			state.Put("droplet_id", 1002)
+			state.Put("ssh_key_id", 1001)
+
+			created, ready := new(stepCheckpoint), &stepCheckpoint{snapshotReady: true}
+			for _, step := range []*stepCheckpoint{created, ready} {
+				if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+					t.Fatalf("expected action continue, got %#v: %s", action, state.Get("error"))
+				}
+			}
+			cp, err := loadCheckpoint(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *cp != (checkpoint{DropletID: 1002, SSHKeyID: 1001, SnapshotReady: true}) {
+				t.Fatalf("got checkpoint %#v", cp)
+			}
+
+			if tc.halted {
+				state.Put(multistep.StateHalted, true)
+			}
+			if tc.cancelled {
+				state.Put(multistep.StateCancelled, true)
+			}
+			ready.Cleanup(state)
+			created.Cleanup(state)
+
+			if _, ok := state.GetOk("keep_droplet"); ok != tc.kept {
+				t.Errorf("expected keep_droplet = %t", tc.kept)
+			}
+			if _, err := os.Stat(path); (err == nil) != tc.kept {
+				t.Errorf("expected checkpoint exists = %t", tc.kept)
+			}
+		})
+	}
+}
@@ -1,14 +1,20 @@
 //go:generate packer-sdc struct-markdown
-//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,ImageFilter,DropletConfig,SnapshotConfig,ConnectionConfig,Hooks,Volume
 
 package digitalocean
 
 import (
 	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"reflect"
 	"regexp"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/hashicorp/packer-plugin-sdk/common"
 	"github.com/hashicorp/packer-plugin-sdk/communicator"
@@ -19,13 +25,178 @@ import (
 	"github.com/mitchellh/mapstructure"
 )
 
+// ImageFilter selects an image by its attributes.
+type ImageFilter struct {
+	// A regular expression the image name must match.
+	Name string `mapstructure:"name" required:"false"`
+	// The image type, such as `snapshot`, `backup`, `custom` or `base`.
+	Type string `mapstructure:"type" required:"false"`
+	// A tag the image must have.
+	Tag string `mapstructure:"tag" required:"false"`
+	// Use the most recently created image when several match.
+	MostRecent bool `mapstructure:"most_recent" required:"false"`
+}
+
+// Empty reports whether no filter was set.
+func (f *ImageFilter) Empty() bool {
+	return f.Name == "" && f.Type == "" && f.Tag == ""
+}
+
+// DropletConfig groups the options of the build droplet. Each of them
+// replaces the deprecated top-level option of the same name, except for
+// `name`, which replaces `droplet_name`.
+type DropletConfig struct {
+	// See `region`.
+	Region string `mapstructure:"region" required:"false"`
+	// See `region_strategy`.
+	RegionStrategy string `mapstructure:"region_strategy" required:"false"`
+	// See `region_preference`.
+	RegionPreference []string `mapstructure:"region_preference" required:"false"`
+	// See `size`.
+	Size string `mapstructure:"size" required:"false"`
+	// See `min_vcpus`.
+	MinVCPUs int `mapstructure:"min_vcpus" required:"false"`
+	// See `min_memory_gb`.
+	MinMemoryGB int `mapstructure:"min_memory_gb" required:"false"`
+	// See `size_class`.
+	SizeClass string `mapstructure:"size_class" required:"false"`
+	// See `image`.
+	Image string `mapstructure:"image" required:"false"`
+	// See `distribution`.
+	Distribution string `mapstructure:"distribution" required:"false"`
+	// See `version`.
+	Version string `mapstructure:"version" required:"false"`
+	// See `droplet_name`.
+	Name string `mapstructure:"name" required:"false"`
+	// See `private_networking`.
+	PrivateNetworking bool `mapstructure:"private_networking" required:"false"`
+	// See `monitoring`.
+	Monitoring bool `mapstructure:"monitoring" required:"false"`
+	// See `ipv6`.
+	IPv6 bool `mapstructure:"ipv6" required:"false"`
+	// See `vpc_uuid`.
+	VPCUUID string `mapstructure:"vpc_uuid" required:"false"`
+	// See `reserved_ip`.
+	ReservedIP string `mapstructure:"reserved_ip" required:"false"`
+	// See `user_data`.
+	UserData string `mapstructure:"user_data" required:"false"`
+	// See `user_data_file`.
+	UserDataFile string `mapstructure:"user_data_file" required:"false"`
+	// See `user_data_checksum`.
+	UserDataChecksum string `mapstructure:"user_data_checksum" required:"false"`
+	// See `user_data_command`.
+	UserDataCommand string `mapstructure:"user_data_command" required:"false"`
+	// See `user_data_vars`.
+	UserDataVars map[string]string `mapstructure:"user_data_vars" required:"false"`
+	// See `user_data_sensitive_vars`.
+	UserDataSensitiveVars []string `mapstructure:"user_data_sensitive_vars" required:"false"`
+	// See `tags`.
+	Tags []string `mapstructure:"tags" required:"false"`
+	// See `state_timeout`.
+	StateTimeout time.Duration `mapstructure:"state_timeout" required:"false"`
+	// See `active_timeout`.
+	ActiveTimeout time.Duration `mapstructure:"active_timeout" required:"false"`
+}
+
+// SnapshotConfig groups the options of the resulting snapshot. They replace
+// the deprecated top-level options prefixed with `snapshot_`, and
+// `cleanup_on_error` replaces `cleanup_snapshot_on_error`.
+type SnapshotConfig struct {
+	// See `snapshot_name`.
+	Name string `mapstructure:"name" required:"false"`
+	// See `snapshot_regions`.
+	Regions []string `mapstructure:"regions" required:"false"`
+	// See `snapshot_tags`.
+	Tags []string `mapstructure:"tags" required:"false"`
+	// See `snapshot_version_prefix`.
+	VersionPrefix string `mapstructure:"version_prefix" required:"false"`
+	// See `snapshot_timeout`.
+	Timeout time.Duration `mapstructure:"timeout" required:"false"`
+	// See `cleanup_snapshot_on_error`.
+	CleanupOnError bool `mapstructure:"cleanup_on_error" required:"false"`
+	// See `snapshot_allow_failed_transfers`.
+	AllowFailedTransfers bool `mapstructure:"allow_failed_transfers" required:"false"`
+	// See `snapshot_volumes`.
+	Volumes bool `mapstructure:"volumes" required:"false"`
+}
+
+// ConnectionConfig groups the DigitalOcean specific options of the
+// connection to the droplet. They replace the deprecated top-level options
+// of the same name, and `private_ip` replaces `connect_with_private_ip`.
+type ConnectionConfig struct {
+	// See `connect_with_private_ip`.
+	PrivateIP bool `mapstructure:"private_ip" required:"false"`
+	// See `ssh_key_id`.
+	SSHKeyID int `mapstructure:"ssh_key_id" required:"false"`
+	// See `ssh_key_ids`.
+	SSHKeyIDs []int `mapstructure:"ssh_key_ids" required:"false"`
+	// See `ssh_key_names`.
+	SSHKeyNames []string `mapstructure:"ssh_key_names" required:"false"`
+	// See `pin_ssh_host_key`.
+	PinSSHHostKey bool `mapstructure:"pin_ssh_host_key" required:"false"`
+}
+
+// Hooks are local commands run at defined points of the build. They run
+// with a shell, `/bin/sh` or `cmd` on Windows, and with details of the build
+// in their environment: `PACKER_BUILD_NAME`, `DIGITALOCEAN_REGION`,
+// `DIGITALOCEAN_SIZE`, `DIGITALOCEAN_IMAGE`, `DIGITALOCEAN_DROPLET_NAME`,
+// `DIGITALOCEAN_DROPLET_ID` and `DIGITALOCEAN_DROPLET_IP` once the droplet
+// exists, and `DIGITALOCEAN_SNAPSHOT_ID` and `DIGITALOCEAN_SNAPSHOT_NAME`
+// once the snapshot does. The build fails if a command exits with a
+// non-zero status.
+type Hooks struct {
+	// Commands run once the droplet is active, before connecting to it.
+	PostCreate []string `mapstructure:"post_create" required:"false"`
+	// Commands run once provisioning is done, before the droplet is shut
+	// down to be snapshotted.
+	PreSnapshot []string `mapstructure:"pre_snapshot" required:"false"`
+	// Commands run once the snapshot has been created.
+	PostSnapshot []string `mapstructure:"post_snapshot" required:"false"`
+}
+
+// Volume is a block storage volume created in the droplet's region for the
+// build and attached to the droplet when it is created. It is deleted along
+// with the droplet.
+type Volume struct {
+	// The name of the volume, lowercase letters, digits and `-`, starting
+	// with a letter. Defaults to the droplet name, made valid, followed by
+	// `-` and the position of the volume in the list, such as
+	// `packer-6110a3c2-0`.
+	Name string `mapstructure:"name" required:"false"`
+	// The size of the volume in GiB.
+	Size int64 `mapstructure:"size" required:"true"`
+	// The filesystem to format the volume with, `ext4` or `xfs`, when it
+	// has none yet. Required with `mount_path`. Images need `mkfs.xfs`,
+	// from the xfsprogs package, for `xfs`.
+	FilesystemType string `mapstructure:"filesystem_type" required:"false"`
+	// The path to mount the volume at. When set, the user data of the
+	// droplet gets a script that waits for udev to create the device of the
+	// volume, formats it and mounts it, adding it to `/etc/fstab`, and the
+	// provisioners don't run before it is mounted. The image must run
+	// cloud-init.
+	MountPath string `mapstructure:"mount_path" required:"false"`
+}
+
 type Config struct {
 	common.PackerConfig `mapstructure:",squash"`
 	Comm                communicator.Config `mapstructure:",squash"`
+	// The options of the build droplet, grouped in a block. See
+	// [Droplet, Snapshot and Connection Blocks](#droplet-snapshot-and-connection-blocks).
+	Droplet DropletConfig `mapstructure:"droplet" required:"false"`
+	// The options of the resulting snapshot, grouped in a block.
+	Snapshot SnapshotConfig `mapstructure:"snapshot" required:"false"`
+	// The DigitalOcean specific options of the connection to the droplet,
+	// grouped in a block.
+	Connection ConnectionConfig `mapstructure:"connection" required:"false"`
 	// The client TOKEN to use to access your account. It
 	// can also be specified via environment variable DIGITALOCEAN_API_TOKEN, if
 	// set.
 	APIToken string `mapstructure:"api_token" required:"true"`
+	// More API tokens of the same team to fail over to: when the API rate
+	// limits the token in use, or stops accepting it, the request is sent
+	// again with the next token, which the rest of the build then uses.
+	// `api_token` defaults to the first of them.
+	APITokens []string `mapstructure:"api_tokens" required:"false"`
 	// Non standard api endpoint URL. Set this if you are
 	// using a DigitalOcean API compatible service. It can also be specified via
 	// environment variable DIGITALOCEAN_API_URL.
@@ -35,36 +206,148 @@ type Config struct {
 	// See
 	// https://developers.digitalocean.com/documentation/v2/#list-all-regions
 	// for the accepted region names/slugs.
+	// Set to `auto` to have Packer pick a region that offers `size`
+	// according to `region_strategy`.
 	Region string `mapstructure:"region" required:"true"`
+	// How a region is picked when `region` is `auto`: `first-available`,
+	// the first region offering `size`; `latency`, the one closest to the
+	// machine running Packer; or `preference`, the first of
+	// `region_preference` offering `size`. When creating the droplet fails in
+	// the picked region, the next best one is tried. Defaults to
+	// `preference` when `region_preference` is set, and `first-available`
+	// otherwise.
+	RegionStrategy string `mapstructure:"region_strategy" required:"false"`
+	// The regions to pick from, most preferred first, when `region` is
+	// `auto`.
+	RegionPreference []string `mapstructure:"region_preference" required:"false"`
 	// The name (or slug) of the droplet size to use. See
 	// https://developers.digitalocean.com/documentation/v2/#list-all-sizes
 	// for the accepted size names/slugs.
+	// Either this or at least one of `min_vcpus`, `min_memory_gb` and
+	// `size_class` must be set.
 	Size string `mapstructure:"size" required:"true"`
+	// The minimum number of vCPUs of the droplet. When set instead of
+	// `size`, the cheapest available size meeting every requirement is used.
+	MinVCPUs int `mapstructure:"min_vcpus" required:"false"`
+	// The minimum memory of the droplet, in gigabytes.
+	MinMemoryGB int `mapstructure:"min_memory_gb" required:"false"`
+	// The class of droplet sizes to pick from: `basic`, `general-purpose`,
+	// `cpu-optimized`, `memory-optimized` or `storage-optimized`.
+	SizeClass string `mapstructure:"size_class" required:"false"`
 	// The name (or slug) of the base image to use. This is the
 	// image that will be used to launch a new droplet and provision it. See
 	// https://developers.digitalocean.com/documentation/v2/#list-all-images
 	// for details on how to get a list of the accepted image names/slugs.
+	// Snapshots and custom images can also be referred to by their name, as
+	// long as no other image has the same one. Either this,
+	// `source_image_filter` or `distribution` must be set.
 	Image string `mapstructure:"image" required:"true"`
+	// Filters used to look up the base image when the build starts, instead
+	// of setting `image`. For example, to build on top of the most recent
+	// snapshot tagged `golden-base`:
+	//
+	// ```hcl
+	// source_image_filter {
+	//   name        = "^base-"
+	//   type        = "snapshot"
+	//   tag         = "golden-base"
+	//   most_recent = true
+	// }
+	// ```
+	//
+	// The build fails when no image matches, or when several do and
+	// `most_recent` isn't set.
+	SourceImageFilter ImageFilter `mapstructure:"source_image_filter" required:"false"`
+	// The distribution of the DigitalOcean image to create the droplet from,
+	// as the API names it, such as `Ubuntu` or `Debian`, regardless of case.
+	// The image is resolved to the current one of `version` when the build
+	// starts, so that templates keep working when DigitalOcean replaces the
+	// image of a release. Can't be used with `image` or
+	// `source_image_filter`.
+	Distribution string `mapstructure:"distribution" required:"false"`
+	// The release of `distribution`, such as `22.04`, which the image name
+	// starts with. `lts` stands for the newest long term support release.
+	// Defaults to the newest release.
+	Version string `mapstructure:"version" required:"false"`
+	// The name of another build of the same `packer build` run, such as
+	// `digitalocean.base`, to create the droplet from the snapshot of, so
+	// that images built upon one another, such as base, hardened and app,
+	// are built in a single run. The build waits for the other one to
+	// finish, which requires the builds to run in parallel, and fails when
+	// it does or when its snapshot isn't available in `region`. Can't be
+	// used with `image`, `source_image_filter` or `distribution`.
+	SourceBuild string `mapstructure:"source_build" required:"false"`
+	// The time to wait for the build of `source_build` to finish, such as
+	// `90m`. Defaults to `1h`.
+	SourceBuildTimeout time.Duration `mapstructure:"source_build_timeout" required:"false"`
 	// Set to true to enable private networking
 	// for the droplet being created. This defaults to false, or not enabled.
+	// Without `vpc_uuid`, the droplet is created in the default VPC of the
+	// region, which is looked up and available to provisioners and
+	// post-processors as `VPCUUID` in the generated data. Leaving
+	// `vpc_uuid` out is deprecated.
 	PrivateNetworking bool `mapstructure:"private_networking" required:"false"`
 	// Set to true to enable monitoring for the droplet
-	// being created. This defaults to false, or not enabled.
+	// being created. This defaults to false, or not enabled. DigitalOcean
+	// images come with the monitoring agent, on custom images it is
+	// installed before the snapshot, and the build waits for the droplet to
+	// report metrics.
 	Monitoring bool `mapstructure:"monitoring" required:"false"`
+	// Whether to install the droplet agent, which the Droplet Console of the
+	// control panel connects through. Left out, the API installs it on the
+	// images supporting it.
+	DropletAgent config.Trilean `mapstructure:"droplet_agent" required:"false"`
+	// Wait for the droplet agent to be running before the provisioners run,
+	// for the ones configuring features that depend on it. The agent is
+	// installed while the droplet boots, after SSH is up. Requires the ssh
+	// communicator. Defaults to false.
+	WaitForDropletAgent bool `mapstructure:"wait_for_droplet_agent" required:"false"`
 	// Set to true to enable ipv6 for the droplet being
 	// created. This defaults to false, or not enabled.
 	IPv6 bool `mapstructure:"ipv6" required:"false"`
+	// Set to true to create a small droplet with a public IP in the VPC of
+	// the build droplet, and to connect to the private IP of the build
+	// droplet through it, as with `ssh_bastion_host`. The bastion gets the
+	// SSH key used to connect to the build droplet, and is destroyed once the
+	// build is done. Before using this, private_networking should be enabled.
+	// Defaults to false.
+	TemporaryBastion bool `mapstructure:"temporary_bastion" required:"false"`
+	// The size of the `temporary_bastion` droplet. Defaults to
+	// `s-1vcpu-1gb`.
+	TemporaryBastionSize string `mapstructure:"temporary_bastion_size" required:"false"`
+	// The image of the `temporary_bastion` droplet, which must run an SSH
+	// server letting root in with an SSH key. Defaults to
+	// `ubuntu-22-04-x64`.
+	TemporaryBastionImage string `mapstructure:"temporary_bastion_image" required:"false"`
 	// The name of the resulting snapshot that will
 	// appear in your account. Defaults to `packer-{{timestamp}}` (see
-	// configuration templates for more info).
+	// configuration templates for more info). The rendered name must be at
+	// most 255 characters long, of letters, digits, spaces, `.`, `_`, `-`
+	// and `:`.
 	SnapshotName string `mapstructure:"snapshot_name" required:"false"`
+	// Name the snapshot after this prefix and the next version of the
+	// image: when snapshots named `myimage-v12` and `myimage-v11` exist, a
+	// prefix of `myimage-` names the new snapshot `myimage-v13`. The first
+	// version is `v1`. The version, such as `v13`, is available to
+	// provisioners and post-processors as the `SnapshotVersion` generated
+	// data. Can't be used with `snapshot_name`.
+	SnapshotVersionPrefix string `mapstructure:"snapshot_version_prefix" required:"false"`
 	// The regions of the resulting
-	// snapshot that will appear in your account.
+	// snapshot that will appear in your account. `all` stands for every
+	// available region, and a region prefixed with `!`, such as `!nyc1`, is
+	// left out, so `["all", "!nyc1"]` copies the snapshot everywhere but
+	// nyc1. Both are expanded against the live region list at build time.
+	// The region the snapshot is created in is skipped.
 	SnapshotRegions []string `mapstructure:"snapshot_regions" required:"false"`
 	// The time to wait, as a duration string, for a
 	// droplet to enter a desired state (such as "active") before timing out. The
-	// default state timeout is "6m".
+	// default state timeout is "6m". Droplets created from a custom image
+	// take longer to become active the larger the image is, so they are
+	// given an extra minute per gigabyte of the image.
 	StateTimeout time.Duration `mapstructure:"state_timeout" required:"false"`
+	// The time to wait for the droplet to become active, overriding
+	// `state_timeout` and the extra time given to custom images.
+	ActiveTimeout time.Duration `mapstructure:"active_timeout" required:"false"`
 	// How long to wait for an image to be published to the shared image
 	// gallery before timing out. If your Packer build is failing on the
 	// Publishing to Shared Image Gallery step with the error `Original Error:
@@ -73,21 +356,104 @@ type Config struct {
 	// its default of "60m" (valid time units include `s` for seconds, `m` for
 	// minutes, and `h` for hours.)
 	SnapshotTimeout time.Duration `mapstructure:"snapshot_timeout" required:"false"`
+	// The maximum time, as a duration string, the whole build may take. When
+	// it is exceeded the build is cancelled and the droplet is powered off and
+	// destroyed, even if a provisioner is hung. Disabled by default.
+	MaxBuildDuration time.Duration `mapstructure:"max_build_duration" required:"false"`
+	// Delete the snapshot when a step after it fails, such as transferring
+	// it to `snapshot_regions`, instead of leaving a half-finished image
+	// behind. Defaults to false.
+	CleanupSnapshotOnError bool `mapstructure:"cleanup_snapshot_on_error" required:"false"`
+	// Keep the build going when the snapshot fails to transfer to some of
+	// the `snapshot_regions`, instead of failing it. The artifact then only
+	// lists the regions the snapshot is available in, and its
+	// `region_availability` state maps each requested region to
+	// `available`, `failed`, or `pending` for a transfer still running when
+	// it timed out. Defaults to false.
+	SnapshotAllowFailedTransfers bool `mapstructure:"snapshot_allow_failed_transfers" required:"false"`
+	// Snapshot each `volume` along with the droplet, naming the snapshot of
+	// a volume `snapshot_name` followed by `-` and the volume name. The
+	// artifact lists the volume snapshot IDs, by volume name, in its
+	// `volume_snapshots` state, and destroying it deletes them too. Volume
+	// snapshots stay in the region of the droplet, whatever the
+	// `snapshot_regions`, and get the `snapshot_tags`. Defaults to false.
+	SnapshotVolumes bool `mapstructure:"snapshot_volumes" required:"false"`
 	// The name assigned to the droplet. DigitalOcean
-	// sets the hostname of the machine to this value.
+	// sets the hostname of the machine to this value, so it must be a valid
+	// host name: letters, digits and '-', in parts of up to 63 characters
+	// separated by '.'. The build warns when a droplet of the account
+	// already has the name.
 	DropletName string `mapstructure:"droplet_name" required:"false"`
 	// User data to launch with the Droplet. Packer will
 	// not automatically wait for a user script to finish before shutting down the
 	// instance this must be handled in a provisioner.
 	UserData string `mapstructure:"user_data" required:"false"`
 	// Path to a file that will be used for the user
-	// data when launching the Droplet.
+	// data when launching the Droplet, or an `https://` URL it is downloaded
+	// from when the droplet is created.
 	UserDataFile string `mapstructure:"user_data_file" required:"false"`
-	// Tags to apply to the droplet when it is created
+	// The checksum the contents of `user_data_file` must have, as the hash
+	// type and value, such as `sha256:` followed by the hex encoded SHA-256
+	// hash. `sha256` and `sha512` are supported.
+	UserDataChecksum string `mapstructure:"user_data_checksum" required:"false"`
+	// A local command whose standard output is the user data, run with the
+	// shell when the droplet is created, with the environment of the hooks.
+	// Generated user data, which often holds secrets, is then never written
+	// to disk, and it is left out of the logs. The build fails if the
+	// command exits with a non-zero status.
+	UserDataCommand string `mapstructure:"user_data_command" required:"false"`
+	// Values substituted for the `@{name}` placeholders of `user_data`, or
+	// of the contents of `user_data_file`, when the droplet is created, so
+	// that secrets can be kept out of cloud-init files checked into version
+	// control. Every placeholder must have a value.
+	UserDataVars map[string]string `mapstructure:"user_data_vars" required:"false"`
+	// Names of the `user_data_vars` entries holding secrets, whose values
+	// are kept out of the logs.
+	UserDataSensitiveVars []string `mapstructure:"user_data_sensitive_vars" required:"false"`
+	// Tags to apply to the droplet when it is created. Tags are
+	// interpolated, so they can record build metadata such as
+	// `build-date:{{isotime "2006-01-02"}}`. The droplet is also tagged
+	// `packer-build:` followed by the UUID of the build, so that a create
+	// call retried after its response was lost finds the droplet it
+	// created. That tag is deleted along with the droplet.
 	Tags []string `mapstructure:"tags" required:"false"`
+	// Tags to apply to the snapshot once it has been created. Like `tags`,
+	// these are interpolated.
+	SnapshotTags []string `mapstructure:"snapshot_tags" required:"false"`
+	// Description to set on the snapshot once it has been created, such as
+	// the commit and pipeline it was built from. It is interpolated when the
+	// snapshot exists, so along with the usual functions it can use
+	// `{{ .SnapshotName }}`, `{{ .SnapshotID }}`, `{{ .SourceImage }}`,
+	// `{{ .Region }}`, `{{ .DropletID }}` and the generated data, such as
+	// `{{ .SnapshotVersion }}`.
+	SnapshotDescription string `mapstructure:"snapshot_description" required:"false"`
+	// Tag keys that must be present in both `tags` and `snapshot_tags`. A
+	// key is present when a tag is either the key itself or starts with the
+	// key followed by a colon, such as `owner:ops` for `owner`. This may
+	// also be set as a comma separated list using the
+	// `DIGITALOCEAN_REQUIRED_TAGS` environmental variable.
+	RequiredTags []string `mapstructure:"required_tags" required:"false"`
+	// Tag the droplet and the snapshot with the commit and pipeline of the
+	// CI run building them, read from the environment variables of GitHub
+	// Actions, GitLab CI, CircleCI, Buildkite, Azure Pipelines or Jenkins:
+	// `ci:` followed by the CI system, `git-sha:`, `git-branch:`,
+	// `ci-pipeline:` and `ci-job:`, such as `git-sha:6fe3c9a1...`. Tag keys
+	// already in `tags` or `snapshot_tags` are left as they are. Outside of
+	// CI, nothing is added. Defaults to false.
+	AutoTagCI bool `mapstructure:"auto_tag_ci" required:"false"`
 	// UUID of the VPC which the droplet will be created in. Before using this,
 	// private_networking should be enabled.
 	VPCUUID string `mapstructure:"vpc_uuid" required:"false"`
+	// The address of an existing reserved IP of the account, such as
+	// `203.0.113.10`, to assign to the droplet once it is active, so that
+	// the droplet can be reached at that address during the build. It must
+	// be in the region of the droplet and not assigned to another droplet.
+	// The reserved IP is unassigned when the build is done, and never
+	// deleted. A reserved IP only takes inbound traffic: the connections the
+	// droplet opens, such as to artifact mirrors, still leave from its own
+	// public IP, so firewalls must allow that address for them. The
+	// communicators also connect to the droplet's own address.
+	ReservedIP string `mapstructure:"reserved_ip" required:"false"`
 	// Wheter the communicators should use private IP or not (public IP in that case).
 	// If the droplet is or going to be accessible only from the local network because
 	// it is at behind a firewall, then communicators should use the private IP
@@ -96,15 +462,267 @@ type Config struct {
 	// The ID of an existing SSH key on the DigitalOcean account. This should be
 	// used in conjunction with `ssh_private_key_file`.
 	SSHKeyID int `mapstructure:"ssh_key_id" required:"false"`
+	// The IDs of existing SSH keys on the DigitalOcean account to add to the
+	// droplet alongside the key used to connect, such as break-glass keys of
+	// a team. Unlike `ssh_key_id`, they are not used to connect.
+	SSHKeyIDs []int `mapstructure:"ssh_key_ids" required:"false"`
+	// The names of existing SSH keys on the DigitalOcean account to add to
+	// the droplet in the same way as `ssh_key_ids`. The names are resolved
+	// to IDs before anything is created, and the build fails if a name
+	// matches no key, or several.
+	SSHKeyNames []string `mapstructure:"ssh_key_names" required:"false"`
+	// Share one temporary SSH key between the builds of a template that set
+	// this, rather than creating a key for each of them. The first build
+	// creates the key, and the last one to finish deletes it. The key pair
+	// is kept in the temporary directory for the duration of the run. Builds
+	// using other accounts, by `api_token` or `api_url`, share a key of their
+	// own. Only works when Packer runs the builds, which sets
+	// `PACKER_RUN_UUID`. Can't be used with `checkpoint_file`.
+	SharedTemporaryKey bool `mapstructure:"shared_temporary_key" required:"false"`
+	// Keep the temporary SSH key in the account when the build is done,
+	// rather than deleting it, so that a droplet left behind, with
+	// `-on-error=abort` or when destroying it failed, can still be logged
+	// in to. The private key is saved with `-debug`. The ID of the key is
+	// in the `ssh_key_id` state of the artifact, and in the UI messages.
+	// Can't be used with `shared_temporary_key`. Defaults to false.
+	SkipKeyCleanup bool `mapstructure:"skip_key_cleanup" required:"false"`
 	// The path to an SSH private key file. When excluded, an SSH key  will be
 	// automatically generated and used to build the image.
 	SSHPrivateKeyFile string `mapstructure:"ssh_private_key_file" required:"false"`
+	// The maximum hourly price, in USD, of the droplet size. The price of
+	// `size` is looked up before any resource is created, and the build
+	// refuses to start when it is more expensive than this.
+	MaxHourlyPrice float64 `mapstructure:"max_hourly_price" required:"false"`
+	// The maximum estimated cost, in USD, of the build droplet. The cost
+	// accrued since the droplet was created is checked every minute while
+	// the build runs, which is then cancelled and the droplet destroyed, as
+	// with `max_build_duration`, and once more when provisioning is done,
+	// before the snapshot is taken.
+	MaxEstimatedCost float64 `mapstructure:"max_estimated_cost" required:"false"`
+	// What to do when `max_hourly_price` or `max_estimated_cost` is exceeded:
+	// `fail` the build or only `warn` about it. Defaults to `fail`.
+	BudgetAction string `mapstructure:"budget_action" required:"false"`
+	// Only check that the API token, image, size, region, VPC and SSH key
+	// are valid, using read-only API calls, then exit without creating
+	// anything. Useful for validating templates in CI. Defaults to false.
+	ValidateOnly bool `mapstructure:"validate_only" required:"false"`
+	// Generate the droplet's SSH host key locally and install it through
+	// cloud-init user data, then only accept that key when connecting,
+	// instead of trusting whichever key the droplet presents first. The base
+	// image must run cloud-init. Defaults to false.
+	PinSSHHostKey bool `mapstructure:"pin_ssh_host_key" required:"false"`
+	// With `communicator = "winrm"`, a PowerShell script is added to the
+	// user data of the droplet, for cloudbase-init to run, which sets the
+	// password of `winrm_username`, creating the account if needed, and
+	// enables WinRM on `winrm_port`, over HTTPS with a self-signed
+	// certificate with `winrm_use_ssl`, opening the port in the Windows
+	// firewall. `winrm_username` defaults to `Administrator`, and
+	// `winrm_password` to a random password. Set to true for images that
+	// enable WinRM themselves. Defaults to false.
+	SkipWinRMSetup bool `mapstructure:"skip_winrm_setup" required:"false"`
+	// Commands to run over SSH on a droplet booted from the new snapshot,
+	// once it has been created. The build fails if any of them exits with a
+	// non-zero status, and the droplet is destroyed afterwards either way.
+	VerifyCommands []string `mapstructure:"verify_commands" required:"false"`
+	// The size of the droplet booted to run `verify_commands`. Defaults to
+	// `size`, as the snapshot can't be booted on a smaller disk.
+	VerifySize string `mapstructure:"verify_size" required:"false"`
+	// Update the droplet's OS packages with apt, dnf or zypper, whichever
+	// the image has, before the provisioners run, and reboot it when the
+	// update requires it. Defaults to false.
+	UpdatePackages bool `mapstructure:"update_packages" required:"false"`
+	// Reboot the droplet after provisioning and wait for SSH to be back
+	// before the snapshot is taken, failing the build when systemd units
+	// failed to start. Defaults to false.
+	RebootBeforeSnapshot bool `mapstructure:"reboot_before_snapshot" required:"false"`
+	// How long to wait for the droplet to come back from a reboot, such as
+	// the one of `reboot_before_snapshot` or of `update_packages`, and to
+	// finish booting. Defaults to `state_timeout`.
+	RebootTimeout time.Duration `mapstructure:"reboot_timeout" required:"false"`
+	// Let provisioners take snapshots of the droplet while they run. A
+	// provisioner requests one by creating a file in
+	// `/tmp/packer-checkpoints`, named after the checkpoint; the snapshot
+	// is named `snapshot_name` followed by `-` and the checkpoint name, and
+	// its image ID, or an error starting with `error:`, is written to the
+	// file of the same name with a `.done` extension once it is taken. For
+	// example:
+	//
+	// ```shell
+	// sync; touch /tmp/packer-checkpoints/base
+	// while [ ! -f /tmp/packer-checkpoints/base.done ]; do sleep 5; done
+	// ```
+	//
+	// A failed intermediate snapshot doesn't fail the build. Defaults to
+	// false.
+	IntermediateSnapshots bool `mapstructure:"intermediate_snapshots" required:"false"`
+	// Mount points frozen with `fsfreeze` while an intermediate snapshot is
+	// started, and thawed as soon as it is, so that what is written to them
+	// is on disk and they are quiesced in the snapshot, whereas the droplet
+	// keeps running. They are thawed after a minute in any case. The root
+	// filesystem can't be frozen, as thawing it again over SSH would hang.
+	// Requires `intermediate_snapshots`.
+	FreezeMountPoints []string `mapstructure:"freeze_mount_points" required:"false"`
+	// Generalize the droplet before it is shut down, so that droplets
+	// created from the snapshot don't share its identity: the temporary SSH
+	// key, SSH host keys, machine-id, cloud-init state, logs and shell
+	// history are removed. Defaults to false.
+	Generalize bool `mapstructure:"generalize" required:"false"`
+	// Discard unused blocks with `fstrim` before the snapshot is taken, or
+	// zero out free space where the filesystem doesn't support it, so that
+	// the snapshot only stores what is actually used. Defaults to false.
+	TrimDisk bool `mapstructure:"trim_disk" required:"false"`
+	// Compute a SHA256 digest of the root filesystem as it is snapshotted,
+	// to attest what the image holds. The digest, such as `sha256:9f86d0…`,
+	// is the `sha256sum` of the `sha256sum` list of every regular file,
+	// sorted by path, leaving out `/tmp`, `/var/tmp` and `/var/log` and
+	// other filesystems. It is put in the `filesystem_digest` state of the
+	// artifact and added to the snapshot tags. The API can't boot a droplet
+	// into recovery mode, so the digest is computed on the droplet once
+	// provisioning, `generalize` and `trim_disk` are done, right before it
+	// is shut down. Defaults to false.
+	FilesystemDigest bool `mapstructure:"filesystem_digest" required:"false"`
+	// Set to true to skip the build when nothing it is made from changed
+	// since the last one. The source image, resolved to its ID, the user
+	// data, the options changing what the droplet holds and the content of
+	// `input_files` are hashed, and the snapshot tagged with the hash, such
+	// as `inputs-sha256:9f86d0…`. When a snapshot with the same hash is
+	// available in `region` and `snapshot_regions`, the newest one is
+	// returned as the artifact, without creating a droplet, and the
+	// `unchanged` state of the artifact is set. Running `packer build
+	// -force` builds anyway. Defaults to false.
+	SkipUnchanged bool `mapstructure:"skip_unchanged" required:"false"`
+	// The files and directories hashed by `skip_unchanged`, such as the
+	// scripts and files of the provisioners, which the builder knows
+	// nothing about otherwise.
+	InputFiles []string `mapstructure:"input_files" required:"false"`
+	// List the packages installed on the droplet, with dpkg or rpm,
+	// whichever the image has, before it is shut down. The artifact maps
+	// each package name to its version in its `packages` state, the name
+	// being followed by `:` and the architecture for packages installed for
+	// several. Defaults to false.
+	PackageInventory bool `mapstructure:"package_inventory" required:"false"`
+	// Path of a CycloneDX JSON file to write the package inventory to,
+	// which is then one of the files of the artifact. Setting it enables
+	// `package_inventory`.
+	PackageInventoryFile string `mapstructure:"package_inventory_file" required:"false"`
+	// Path of a file to write the differences between the new snapshot and
+	// the previous image to, for release notes: the packages added, removed
+	// or upgraded, and the files added, removed or changed, leaving out
+	// `/tmp`, `/var/tmp` and `/var/log`. A droplet is booted from each of
+	// the two images once the snapshot is created, and destroyed once they
+	// are listed. The file is one of the files of the artifact, and the
+	// differences are put in its `snapshot_diff` state.
+	SnapshotDiffFile string `mapstructure:"snapshot_diff_file" required:"false"`
+	// The image to compare the new snapshot to with `snapshot_diff_file`,
+	// by name or ID. Defaults to the previous version with
+	// `snapshot_version_prefix`, the diff being left out for the first one.
+	SnapshotDiffImage string `mapstructure:"snapshot_diff_image" required:"false"`
+
+	// Local commands to run at defined points of the build. See
+	// [Hooks](#hooks).
+	Hooks Hooks `mapstructure:"hooks" required:"false"`
+	// URLs to POST a JSON payload describing the build to when it reaches
+	// one of its milestones: the droplet is created, provisioning is
+	// finished, the snapshot is available, the snapshot transfers are
+	// complete, or the build failed. See [Webhooks](#webhooks).
+	Webhooks []string `mapstructure:"webhooks" required:"false"`
+	// URL of an OTLP/HTTP collector to export a trace of the build to once
+	// it is over, such as `http://localhost:4318`, to which `/v1/traces` is
+	// appended. Every step is a span, with the droplet and image as
+	// attributes. Defaults to the `OTEL_EXPORTER_OTLP_ENDPOINT` environment
+	// variable. The trace continues the one of the `TRACEPARENT` environment
+	// variable when it is set. See [Tracing](#tracing).
+	OTLPEndpoint string `mapstructure:"otlp_endpoint" required:"false"`
+	// Headers to send to the collector along with the trace, such as
+	// credentials. Defaults to the `OTEL_EXPORTER_OTLP_HEADERS` environment
+	// variable.
+	OTLPHeaders map[string]string `mapstructure:"otlp_headers" required:"false"`
+	// Block storage volumes to create and attach to the droplet, one block
+	// per volume, which also lets them be formatted and mounted before the
+	// provisioners run. See [Volumes](#volumes).
+	Volumes []Volume `mapstructure:"volume" required:"false"`
+
+	// Path of a file to append a record of every API call creating,
+	// changing or deleting a resource to, one JSON object per line. A record
+	// holds the time, method, endpoint, ID of the resource, response status
+	// and error, the request ID of failed calls, the build name and UUID,
+	// and the Packer run UUID.
+	AuditLog string `mapstructure:"audit_log" required:"false"`
+
+	// Directory to save `/var/log/cloud-init.log` and
+	// `/var/log/cloud-init-output.log` of the droplet to when the build
+	// fails, before the droplet is destroyed. The logs are fetched over SSH
+	// when the droplet accepts connections, which it often does even when
+	// the build timed out waiting for SSH because cloud-init failed. Use
+	// `{{build_name}}` to keep the logs of several builds apart.
+	CloudInitLogDir string `mapstructure:"cloud_init_log_dir" required:"false"`
+
+	// Directory to save a diagnostics bundle of the droplet to when a step
+	// fails once SSH is up, before the droplet is cleaned up. The bundle,
+	// named after the droplet, is a gzipped tarball of the output of
+	// `dmesg`, `journalctl -b`, the network configuration and
+	// `cloud-init status --long`.
+	DebugBundleDir string `mapstructure:"debug_bundle_dir" required:"false"`
+
+	// Don't check the [DigitalOcean status page](https://status.digitalocean.com)
+	// when creating the droplet, or an action such as the snapshot, fails
+	// or times out. By default, the active incidents affecting the region,
+	// the API, or the service that failed are added to the error, to tell
+	// an outage apart from a problem of the build.
+	DisableStatusPage bool `mapstructure:"disable_status_page" required:"false"`
+
+	// Path of a file to write the progress of the build to as it goes, one
+	// JSON object per line: the start and end of each step with the share
+	// of the steps done, the status of the snapshot and transfer actions,
+	// and the IDs of the resources created. The UI output is unchanged. A
+	// file descriptor Packer was started with can be used, such as
+	// `/dev/fd/3`. See [Progress File](#progress-file).
+	ProgressFile string `mapstructure:"progress_file" required:"false"`
+
+	// Path of a JSON file to write a summary of the build to once it is
+	// over, failed or not: the droplet with the size and region it actually
+	// got, the source image, the snapshot and the regions it is available
+	// in, how long the build, droplet and snapshot took, the API usage
+	// including retries, and the cost estimate.
+	SummaryFile string `mapstructure:"summary_file" required:"false"`
+
+	// Path of a file recording the build droplet, so that a build
+	// interrupted once the droplet is provisioned can be resumed. When a
+	// step after provisioning fails, the droplet is kept, and running the
+	// build again adopts it and continues from the snapshot. A checkpoint of
+	// a droplet that wasn't provisioned yet makes the next run destroy that
+	// droplet and start over. The file is removed once the droplet is gone.
+	CheckpointFile string `mapstructure:"checkpoint_file" required:"false"`
+	// The minimum level of the messages this builder shows while building:
+	// `debug`, `info`, `warn` or `error`. Every message is written to the
+	// Packer log regardless, tagged with the build, step and droplet it
+	// relates to. Defaults to `info`.
+	LogLevel string `mapstructure:"log_level" required:"false"`
+	// Path of a file of builder options shared by several templates, such
+	// as regions, tags, timeouts or a VPC. Options set in the template take
+	// precedence over the ones in the file. See
+	// [Defaults File](#defaults-file).
+	DefaultsFile string `mapstructure:"defaults_file" required:"false"`
 
 	ctx interpolate.Context
+	// Set when ssh_username was inferred from the image
+	sshUsernameInferred bool
+	// Identifies the build among the concurrent ones of the account, such
+	// as in the name of the temporary SSH key
+	buildUUID string
 }
 
 func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 
+	if path := defaultsFilePath(raws); path != "" {
+		defaults, err := loadDefaultsFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading defaults_file: %s", err)
+		}
+		// Decoded first, so that the template overrides it
+		raws = append([]interface{}{defaults}, raws...)
+	}
+
 	var md mapstructure.Metadata
 	err := config.Decode(c, &config.DecodeOpts{
 		Metadata:           &md,
@@ -113,6 +731,7 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 		InterpolateFilter: &interpolate.RenderFilter{
 			Exclude: []string{
 				"run_command",
+				"snapshot_description",
 			},
 		},
 	}, raws...)
@@ -120,6 +739,14 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 		return nil, err
 	}
 
+	blockKeys, warnings, err := c.applyBlocks(md.Keys)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.applyEnvDefaults(append(md.Keys, blockKeys...)); err != nil {
+		return nil, err
+	}
+
 	// Defaults
 	if c.APIToken == "" {
 		// Default to environment variable for api_token, if it exists
@@ -128,7 +755,15 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 	if c.APIURL == "" {
 		c.APIURL = os.Getenv("DIGITALOCEAN_API_URL")
 	}
-	if c.SnapshotName == "" {
+	if c.OTLPEndpoint == "" {
+		c.OTLPEndpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+	if len(c.OTLPHeaders) == 0 {
+		c.OTLPHeaders = otlpHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"))
+	}
+	// With snapshot_version_prefix, the name is set once the existing
+	// versions are known
+	if c.SnapshotName == "" && c.SnapshotVersionPrefix == "" {
 		def, err := interpolate.Render("packer-{{timestamp}}", nil)
 		if err != nil {
 			panic(err)
@@ -138,27 +773,86 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 		c.SnapshotName = def
 	}
 
+	c.buildUUID = uuid.TimeOrderedUUID()
 	if c.DropletName == "" {
 		// Default to packer-[time-ordered-uuid]
 		c.DropletName = fmt.Sprintf("packer-%s", uuid.TimeOrderedUUID())
 	}
 
+	for i := range c.Volumes {
+		if c.Volumes[i].Name == "" {
+			c.Volumes[i].Name = defaultVolumeName(c.DropletName, i)
+		}
+	}
+
 	if c.StateTimeout == 0 {
 		// Default to 6 minute timeouts waiting for
 		// desired state. i.e waiting for droplet to become active
 		c.StateTimeout = 6 * time.Minute
 	}
+	if c.RebootTimeout == 0 {
+		c.RebootTimeout = c.StateTimeout
+	}
+	if c.SourceBuild != "" && c.SourceBuildTimeout == 0 {
+		c.SourceBuildTimeout = time.Hour
+	}
 
 	if c.SnapshotTimeout == 0 {
 		// Default to 60 minutes timeout, waiting for snapshot action to finish
 		c.SnapshotTimeout = 60 * time.Minute
 	}
 
+	if c.VerifySize == "" {
+		c.VerifySize = c.Size
+	}
+
+	if c.RegionStrategy == "" {
+		c.RegionStrategy = "first-available"
+		if len(c.RegionPreference) > 0 {
+			c.RegionStrategy = "preference"
+		}
+	}
+
+	if c.LogLevel == "" {
+		c.LogLevel = "info"
+	}
+
+	if c.BudgetAction == "" {
+		c.BudgetAction = "fail"
+	}
+
+	if (c.Comm.Type == "" || c.Comm.Type == "ssh") && c.Comm.SSHUsername == "" {
+		image := c.Image
+		if c.Distribution != "" {
+			// Such as fedora-coreos for Fedora CoreOS
+			image = strings.ReplaceAll(strings.ToLower(c.Distribution), " ", "-")
+		}
+		c.Comm.SSHUsername = defaultSSHUsername(image)
+		c.sshUsernameInferred = true
+	}
+
+	if c.Comm.Type == "winrm" {
+		if c.Comm.WinRMUser == "" {
+			c.Comm.WinRMUser = "Administrator"
+		}
+		if c.Comm.WinRMPassword == "" && !c.SkipWinRMSetup {
+			password, err := randomWinRMPassword()
+			if err != nil {
+				return nil, fmt.Errorf("Error generating winrm_password: %s", err)
+			}
+			c.Comm.WinRMPassword = password
+		}
+		packersdk.LogSecretFilter.Set(c.Comm.WinRMPassword)
+	}
+
 	var errs *packersdk.MultiError
 
 	if es := c.Comm.Prepare(&c.ctx); len(es) > 0 {
 		errs = packersdk.MultiErrorAppend(errs, es...)
 	}
+	if c.APIToken == "" && len(c.APITokens) > 0 {
+		c.APIToken = c.APITokens[0]
+	}
 	if c.APIToken == "" {
 		// Required configurations that will display errors if not set
 		errs = packersdk.MultiErrorAppend(
@@ -170,34 +864,162 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 			errs, errors.New("region is required"))
 	}
 
-	if c.Size == "" {
+	sizeRequirements := c.MinVCPUs != 0 || c.MinMemoryGB != 0 || c.SizeClass != ""
+	if c.Size == "" && !sizeRequirements {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("size or one of min_vcpus, min_memory_gb and size_class is required"))
+	}
+	if c.Size != "" && sizeRequirements {
 		errs = packersdk.MultiErrorAppend(
-			errs, errors.New("size is required"))
+			errs, errors.New("size can't be combined with min_vcpus, min_memory_gb or size_class"))
+	}
+	if c.MinVCPUs < 0 || c.MinMemoryGB < 0 {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("min_vcpus and min_memory_gb can't be negative"))
+	}
+	if _, ok := sizeClasses[c.SizeClass]; c.SizeClass != "" && !ok {
+		errs = packersdk.MultiErrorAppend(errs,
+			fmt.Errorf("size_class must be one of basic, general-purpose, cpu-optimized, memory-optimized or storage-optimized, got %q", c.SizeClass))
 	}
 
-	if c.Image == "" {
+	sources := 0
+	for _, set := range []bool{c.Image != "", !c.SourceImageFilter.Empty(), c.Distribution != "", c.SourceBuild != ""} {
+		if set {
+			sources++
+		}
+	}
+	if sources == 0 {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("image, source_image_filter, distribution or source_build is required"))
+	}
+	if sources > 1 {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("only one of image, source_image_filter, distribution or source_build can be set"))
+	}
+	if c.SourceBuild != "" && c.SourceBuild == c.PackerBuildName {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("source_build can't be the build itself"))
+	}
+	if c.Version != "" && c.Distribution == "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("version requires distribution"))
+	}
+	if _, err := regexp.Compile(c.SourceImageFilter.Name); err != nil {
 		errs = packersdk.MultiErrorAppend(
-			errs, errors.New("image is required"))
+			errs, fmt.Errorf("source_image_filter name is not a valid regular expression: %s", err))
 	}
 
-	if c.UserData != "" && c.UserDataFile != "" {
+	userDataSources := 0
+	for _, source := range []string{c.UserData, c.UserDataFile, c.UserDataCommand} {
+		if source != "" {
+			userDataSources++
+		}
+	}
+	if userDataSources > 1 {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("only one of user_data, user_data_file or user_data_command can be specified"))
+	} else if strings.HasPrefix(c.UserDataFile, "http://") {
 		errs = packersdk.MultiErrorAppend(
-			errs, errors.New("only one of user_data or user_data_file can be specified"))
-	} else if c.UserDataFile != "" {
+			errs, errors.New("user_data_file can only be downloaded over https"))
+	} else if c.UserDataFile != "" && !strings.HasPrefix(c.UserDataFile, "https://") {
 		if _, err := os.Stat(c.UserDataFile); err != nil {
 			errs = packersdk.MultiErrorAppend(
 				errs, fmt.Errorf("user_data_file not found: %s", c.UserDataFile))
 		}
 	}
+	if c.UserDataChecksum != "" {
+		if c.UserDataFile == "" {
+			errs = packersdk.MultiErrorAppend(
+				errs, errors.New("user_data_checksum requires user_data_file"))
+		}
+		if _, _, err := parseChecksum(c.UserDataChecksum); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("user_data_checksum: %s", err))
+		}
+	}
+
+	for _, name := range c.UserDataSensitiveVars {
+		value, ok := c.UserDataVars[name]
+		if !ok {
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("user_data_sensitive_vars: %s isn't in user_data_vars", name))
+			continue
+		}
+		packersdk.LogSecretFilter.Set(value)
+	}
+	if c.UserData != "" && len(c.UserDataVars) > 0 {
+		if _, err := substituteUserDataVars(c.UserData, c.UserDataVars); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("user_data: %s", err))
+		}
+	}
 
 	if c.Tags == nil {
 		c.Tags = make([]string, 0)
 	}
-	tagRe := regexp.MustCompile("^[[:alnum:]:_-]{1,255}$")
-
 	for _, t := range c.Tags {
-		if !tagRe.MatchString(t) {
-			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("invalid tag: %s", t))
+		if err := ValidateTag(t); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("invalid value in tags: %s", err))
+		}
+	}
+	if c.SnapshotVersionPrefix != "" && c.SnapshotName != "" {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("snapshot_version_prefix can't be used with snapshot_name"))
+	}
+	if err := checkDropletName(c.DropletName); err != nil {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("droplet_name %q %s", c.DropletName, err))
+	}
+	if c.SnapshotName != "" {
+		if err := checkImageName(c.SnapshotName); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("snapshot_name %q %s", c.SnapshotName, err))
+		}
+	}
+	if c.SnapshotVersionPrefix != "" {
+		// Leaving room for the version
+		if err := checkImageName(c.SnapshotVersionPrefix + "v1000"); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("snapshot_version_prefix %q %s", c.SnapshotVersionPrefix, err))
+		}
+	}
+	if c.SkipKeyCleanup && c.SharedTemporaryKey {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("skip_key_cleanup can't be used with shared_temporary_key"))
+	}
+	if c.SkipKeyCleanup && c.Comm.Type != "ssh" {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("skip_key_cleanup requires the ssh communicator"))
+	}
+	if c.SharedTemporaryKey && c.CheckpointFile != "" {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("shared_temporary_key can't be used with checkpoint_file"))
+	}
+	// Without a run, the key would be kept under a predictable path
+	if c.SharedTemporaryKey && os.Getenv("PACKER_RUN_UUID") == "" {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("shared_temporary_key requires PACKER_RUN_UUID, which Packer sets for the builds it runs"))
+	}
+	for _, name := range c.SSHKeyNames {
+		if name == "" {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("ssh_key_names must not contain empty names"))
+		}
+	}
+	if c.AutoTagCI {
+		for _, tag := range ciTags(os.Getenv) {
+			key := strings.SplitN(tag, ":", 2)[0]
+			if !hasTagKey(c.Tags, key) {
+				c.Tags = append(c.Tags, tag)
+			}
+			if !hasTagKey(c.SnapshotTags, key) {
+				c.SnapshotTags = append(c.SnapshotTags, tag)
+			}
+		}
+	}
+	if err := interpolate.Validate(c.SnapshotDescription, &c.ctx); err != nil {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("invalid snapshot_description template: %s", err))
+	}
+	for _, t := range c.SnapshotTags {
+		if err := ValidateTag(t); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("invalid value in snapshot_tags: %s", err))
+		}
+	}
+	for _, key := range c.RequiredTags {
+		if !hasTagKey(c.Tags, key) {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("tags must contain the required tag %s", key))
+		}
+		if !hasTagKey(c.SnapshotTags, key) {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("snapshot_tags must contain the required tag %s", key))
 		}
 	}
 
@@ -208,6 +1030,26 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 		}
 	}
 
+	// The bastion is in the VPC, so the private IP is the one it reaches
+	if c.TemporaryBastion {
+		if !c.PrivateNetworking {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("private networking should be enabled to use temporary_bastion"))
+		}
+		if c.Comm.Type != "ssh" {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("temporary_bastion requires the ssh communicator"))
+		}
+		if c.Comm.SSHBastionHost != "" {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("temporary_bastion and ssh_bastion_host can't both be set"))
+		}
+		if c.TemporaryBastionSize == "" {
+			c.TemporaryBastionSize = "s-1vcpu-1gb"
+		}
+		if c.TemporaryBastionImage == "" {
+			c.TemporaryBastionImage = "ubuntu-22-04-x64"
+		}
+		c.ConnectWithPrivateIP = true
+	}
+
 	// Check if the PrivateNetworking is enabled by user before use ConnectWithPrivateIP
 	if c.ConnectWithPrivateIP {
 		if !c.PrivateNetworking {
@@ -215,10 +1057,510 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 		}
 	}
 
+	if c.Generalize && c.Comm.Type != "ssh" {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("generalize requires the ssh communicator"))
+	}
+	if c.UpdatePackages && c.Comm.Type != "ssh" {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("update_packages requires the ssh communicator"))
+	}
+	if c.RebootBeforeSnapshot && c.Comm.Type != "ssh" {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("reboot_before_snapshot requires the ssh communicator"))
+	}
+	if c.IntermediateSnapshots && c.Comm.Type != "ssh" {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("intermediate_snapshots requires the ssh communicator"))
+	}
+	if len(c.FreezeMountPoints) > 0 && !c.IntermediateSnapshots {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("freeze_mount_points requires intermediate_snapshots"))
+	}
+	for _, mount := range c.FreezeMountPoints {
+		if mount == "/" {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("freeze_mount_points can't include the root filesystem"))
+		} else if !mountPathRe.MatchString(mount) {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf(
+				"freeze_mount_points %q must be an absolute path of letters, digits, '.', '_', '-' and '/'", mount))
+		}
+	}
+	if c.CloudInitLogDir != "" && c.Comm.Type != "ssh" {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("cloud_init_log_dir requires the ssh communicator"))
+	}
+	if c.DebugBundleDir != "" && c.Comm.Type != "ssh" {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("debug_bundle_dir requires the ssh communicator"))
+	}
+	if c.PackageInventoryFile != "" {
+		c.PackageInventory = true
+	}
+	if c.PackageInventory && c.Comm.Type != "ssh" {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("package_inventory requires the ssh communicator"))
+	}
+	if c.FilesystemDigest && c.Comm.Type != "ssh" {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("filesystem_digest requires the ssh communicator"))
+	}
+	if c.TrimDisk && c.Comm.Type != "ssh" {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("trim_disk requires the ssh communicator"))
+	}
+	if c.SnapshotDiffFile != "" && c.Comm.Type != "ssh" {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("snapshot_diff_file requires the ssh communicator"))
+	}
+	if c.SnapshotDiffFile != "" && c.SnapshotDiffImage == "" && c.SnapshotVersionPrefix == "" {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("snapshot_diff_file requires snapshot_diff_image or snapshot_version_prefix"))
+	}
+	if c.SnapshotDiffImage != "" && c.SnapshotDiffFile == "" {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("snapshot_diff_image requires snapshot_diff_file"))
+	}
+	if len(c.VerifyCommands) > 0 && c.Comm.Type != "ssh" {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("verify_commands requires the ssh communicator"))
+	}
+	if c.WaitForDropletAgent {
+		if c.Comm.Type != "ssh" {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("wait_for_droplet_agent requires the ssh communicator"))
+		}
+		if c.DropletAgent.False() {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("wait_for_droplet_agent can't be set with droplet_agent disabled"))
+		}
+	}
+	if c.SkipWinRMSetup && c.Comm.Type != "winrm" {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("skip_winrm_setup requires the winrm communicator"))
+	}
+	if c.Comm.Type == "winrm" {
+		for _, v := range c.Volumes {
+			if v.MountPath != "" {
+				errs = packersdk.MultiErrorAppend(errs, errors.New("volume mount_path requires the ssh communicator"))
+				break
+			}
+		}
+	}
+	if c.PinSSHHostKey && c.Comm.Type != "ssh" {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("pin_ssh_host_key requires the ssh communicator"))
+	}
+	switch c.RegionStrategy {
+	case "first-available", "latency":
+	case "preference":
+		if len(c.RegionPreference) == 0 {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("region_preference is required with region_strategy preference"))
+		}
+	default:
+		errs = packersdk.MultiErrorAppend(errs,
+			fmt.Errorf("region_strategy must be one of first-available, latency or preference, got %q", c.RegionStrategy))
+	}
+	if c.OTLPEndpoint != "" {
+		if u, err := url.Parse(c.OTLPEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("otlp_endpoint: %q must be an http or https URL", c.OTLPEndpoint))
+		}
+	}
+	for _, webhook := range c.Webhooks {
+		if u, err := url.Parse(webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("webhooks: %q must be an http or https URL", webhook))
+		}
+	}
+	mountPaths := make(map[string]bool)
+	for i, v := range c.Volumes {
+		if !volumeNameRe.MatchString(v.Name) {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf(
+				"volume %d: name %q must be lowercase letters, digits and '-', starting with a letter, at most 64 characters", i, v.Name))
+		}
+		if v.Size <= 0 {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("volume %d: size must be set", i))
+		}
+		switch v.FilesystemType {
+		case "", "ext4", "xfs":
+		default:
+			errs = packersdk.MultiErrorAppend(errs,
+				fmt.Errorf("volume %d: filesystem_type must be ext4 or xfs, got %q", i, v.FilesystemType))
+		}
+		if v.MountPath == "" {
+			continue
+		}
+		if v.FilesystemType == "" {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("volume %d: mount_path requires filesystem_type", i))
+		}
+		if !mountPathRe.MatchString(v.MountPath) || v.MountPath == "/" {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf(
+				"volume %d: mount_path %q must be an absolute path of letters, digits, '.', '_', '-' and '/'", i, v.MountPath))
+		}
+		if mountPaths[v.MountPath] {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("volume %d: mount_path %s is already used", i, v.MountPath))
+		}
+		mountPaths[v.MountPath] = true
+	}
+	if c.SnapshotVolumes && len(c.Volumes) == 0 {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("snapshot_volumes requires a volume"))
+	}
+	if c.SnapshotVolumes {
+		for i, v := range c.Volumes {
+			if err := checkImageName(c.SnapshotName + "-" + v.Name); err != nil {
+				errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("volume %d: snapshot name %q %s", i, c.SnapshotName+"-"+v.Name, err))
+			}
+		}
+	}
+	if len(c.Volumes) > 0 && c.Region == "auto" {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("region auto can't be used with volume, as volumes belong to a region"))
+	}
+	if len(c.InputFiles) > 0 && !c.SkipUnchanged {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("input_files requires skip_unchanged"))
+	}
+	for _, path := range c.InputFiles {
+		if _, err := os.Stat(path); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("input_files: %s", err))
+		}
+	}
+	if len(c.Volumes) > 0 && c.CheckpointFile != "" {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("volume can't be used with checkpoint_file"))
+	}
+	if c.Region == "auto" && c.VPCUUID != "" {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("region auto can't be used with vpc_uuid, as VPCs belong to a region"))
+	}
+	if c.ReservedIP != "" {
+		if ip := net.ParseIP(c.ReservedIP); ip == nil || ip.To4() == nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("reserved_ip must be an IPv4 address, got %q", c.ReservedIP))
+		}
+		if c.Region == "auto" {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("region auto can't be used with reserved_ip, as reserved IPs belong to a region"))
+		}
+	}
+	if _, ok := parseLogLevel(c.LogLevel); !ok {
+		errs = packersdk.MultiErrorAppend(errs,
+			fmt.Errorf("log_level must be one of debug, info, warn or error, got %q", c.LogLevel))
+	}
+	if c.MaxBuildDuration < 0 {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("max_build_duration must not be negative"))
+	}
+	if c.MaxHourlyPrice < 0 {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("max_hourly_price must not be negative"))
+	}
+	if c.MaxEstimatedCost < 0 {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("max_estimated_cost must not be negative"))
+	}
+	if c.BudgetAction != "fail" && c.BudgetAction != "warn" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("budget_action must be one of fail or warn, got %q", c.BudgetAction))
+	}
+	if containsString(c.SnapshotRegions, c.Region) {
+		// Generated region lists often do, so it is only skipped
+		warnings = append(warnings, fmt.Sprintf(
+			"snapshot_regions includes %s, which the snapshot is created in, it isn't transferred there", c.Region))
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return warnings, errs
+	}
+
+	packersdk.LogSecretFilter.Set(c.apiTokens()...)
+	return warnings, nil
+}
+
+// maxImageNameLength is the longest image name the API takes.
+const maxImageNameLength = 255
+
+// maxDropletNameLength and maxLabelLength are the limits of RFC 1123 host
+// names, which droplet names must be.
+const (
+	maxDropletNameLength = 255
+	maxLabelLength       = 63
+)
+
+// checkDropletName returns the constraint on host names that name violates,
+// if any, worded to follow the name, as checkImageName does.
+func checkDropletName(name string) error {
+	if len(name) > maxDropletNameLength {
+		return fmt.Errorf("is %d characters long, the maximum is %d", len(name), maxDropletNameLength)
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" {
+			return errors.New("must not start or end with '.' or have two in a row")
+		}
+		if len(label) > maxLabelLength {
+			return fmt.Errorf("has a part %q that is %d characters long, the maximum between dots is %d",
+				label, len(label), maxLabelLength)
+		}
+		if strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return fmt.Errorf("has a part %q that starts or ends with '-'", label)
+		}
+		for _, r := range label {
+			switch {
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-':
+			default:
+				return fmt.Errorf("contains %q, only letters, digits, '.' and '-' are allowed", r)
+			}
+		}
+	}
+	return nil
+}
+
+// checkImageName returns the constraint on image names that name violates,
+// if any, worded to follow the name. The API only rejects a snapshot name
+// once the droplet is snapshotted, at the end of the build.
+func checkImageName(name string) error {
+	if n := utf8.RuneCountInString(name); n > maxImageNameLength {
+		return fmt.Errorf("is %d characters long, the maximum is %d", n, maxImageNameLength)
+	}
+	if strings.TrimSpace(name) != name {
+		return errors.New("must not start or end with a space")
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune(" ._-:", r):
+		default:
+			return fmt.Errorf("contains %q, only letters, digits, spaces, '.', '_', '-' and ':' are allowed", r)
+		}
+	}
+	return nil
+}
+
+var (
+	volumeNameRe = regexp.MustCompile("^[a-z][a-z0-9-]{0,63}$")
+	mountPathRe  = regexp.MustCompile("^/[A-Za-z0-9._/-]*$")
+)
+
+// defaultVolumeName returns the name of the i-th volume of the droplet,
+// lowercasing its name and replacing what volume names don't allow.
+func defaultVolumeName(dropletName string, i int) string {
+	suffix := "-" + strconv.Itoa(i)
+	name := []rune(strings.ToLower(dropletName))
+	for j, r := range name {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			name[j] = '-'
+		}
+	}
+	base := strings.Trim(string(name), "-")
+	switch {
+	case base == "":
+		base = "packer"
+	case base[0] < 'a' || base[0] > 'z':
+		base = "packer-" + base
+	}
+	if max := 64 - len(suffix); len(base) > max {
+		base = strings.TrimRight(base[:max], "-")
+	}
+	return base + suffix
+}
+
+// defaultSSHUsername returns the user that can log in to droplets created
+// from the given image. DigitalOcean images use root, except for the few
+// distributions that don't allow it.
+func defaultSSHUsername(image string) string {
+	switch {
+	case strings.HasPrefix(image, "fedora-coreos"), strings.HasPrefix(image, "coreos"):
+		return "core"
+	case strings.HasPrefix(image, "rancheros"):
+		return "rancher"
+	default:
+		return "root"
+	}
+}
+
+// applyBlocks copies the options set in the droplet, snapshot and connection
+// blocks to the deprecated top-level options, which the rest of the builder
+// uses, and returns the keys of the options it set. keys are the decoded
+// keys, nested ones being joined with a dot. A template using a block gets a
+// warning for each of the block's deprecated top-level options it still
+// sets, so that it can be migrated one option at a time.
+func (c *Config) applyBlocks(keys []string) ([]string, []string, error) {
+	aliases := []struct {
+		block, flat string
+		from, to    interface{}
+	}{
+		{"droplet.region", "region", &c.Droplet.Region, &c.Region},
+		{"droplet.region_strategy", "region_strategy", &c.Droplet.RegionStrategy, &c.RegionStrategy},
+		{"droplet.region_preference", "region_preference", &c.Droplet.RegionPreference, &c.RegionPreference},
+		{"droplet.size", "size", &c.Droplet.Size, &c.Size},
+		{"droplet.min_vcpus", "min_vcpus", &c.Droplet.MinVCPUs, &c.MinVCPUs},
+		{"droplet.min_memory_gb", "min_memory_gb", &c.Droplet.MinMemoryGB, &c.MinMemoryGB},
+		{"droplet.size_class", "size_class", &c.Droplet.SizeClass, &c.SizeClass},
+		{"droplet.image", "image", &c.Droplet.Image, &c.Image},
+		{"droplet.distribution", "distribution", &c.Droplet.Distribution, &c.Distribution},
+		{"droplet.version", "version", &c.Droplet.Version, &c.Version},
+		{"droplet.name", "droplet_name", &c.Droplet.Name, &c.DropletName},
+		{"droplet.private_networking", "private_networking", &c.Droplet.PrivateNetworking, &c.PrivateNetworking},
+		{"droplet.monitoring", "monitoring", &c.Droplet.Monitoring, &c.Monitoring},
+		{"droplet.ipv6", "ipv6", &c.Droplet.IPv6, &c.IPv6},
+		{"droplet.vpc_uuid", "vpc_uuid", &c.Droplet.VPCUUID, &c.VPCUUID},
+		{"droplet.reserved_ip", "reserved_ip", &c.Droplet.ReservedIP, &c.ReservedIP},
+		{"droplet.user_data", "user_data", &c.Droplet.UserData, &c.UserData},
+		{"droplet.user_data_file", "user_data_file", &c.Droplet.UserDataFile, &c.UserDataFile},
+		{"droplet.user_data_checksum", "user_data_checksum", &c.Droplet.UserDataChecksum, &c.UserDataChecksum},
+		{"droplet.user_data_command", "user_data_command", &c.Droplet.UserDataCommand, &c.UserDataCommand},
+		{"droplet.user_data_vars", "user_data_vars", &c.Droplet.UserDataVars, &c.UserDataVars},
+		{"droplet.user_data_sensitive_vars", "user_data_sensitive_vars", &c.Droplet.UserDataSensitiveVars, &c.UserDataSensitiveVars},
+		{"droplet.tags", "tags", &c.Droplet.Tags, &c.Tags},
+		{"droplet.state_timeout", "state_timeout", &c.Droplet.StateTimeout, &c.StateTimeout},
+		{"droplet.active_timeout", "active_timeout", &c.Droplet.ActiveTimeout, &c.ActiveTimeout},
+		{"snapshot.name", "snapshot_name", &c.Snapshot.Name, &c.SnapshotName},
+		{"snapshot.regions", "snapshot_regions", &c.Snapshot.Regions, &c.SnapshotRegions},
+		{"snapshot.tags", "snapshot_tags", &c.Snapshot.Tags, &c.SnapshotTags},
+		{"snapshot.version_prefix", "snapshot_version_prefix", &c.Snapshot.VersionPrefix, &c.SnapshotVersionPrefix},
+		{"snapshot.timeout", "snapshot_timeout", &c.Snapshot.Timeout, &c.SnapshotTimeout},
+		{"snapshot.cleanup_on_error", "cleanup_snapshot_on_error", &c.Snapshot.CleanupOnError, &c.CleanupSnapshotOnError},
+		{"snapshot.allow_failed_transfers", "snapshot_allow_failed_transfers", &c.Snapshot.AllowFailedTransfers, &c.SnapshotAllowFailedTransfers},
+		{"snapshot.volumes", "snapshot_volumes", &c.Snapshot.Volumes, &c.SnapshotVolumes},
+		{"connection.private_ip", "connect_with_private_ip", &c.Connection.PrivateIP, &c.ConnectWithPrivateIP},
+		{"connection.ssh_key_id", "ssh_key_id", &c.Connection.SSHKeyID, &c.SSHKeyID},
+		{"connection.ssh_key_ids", "ssh_key_ids", &c.Connection.SSHKeyIDs, &c.SSHKeyIDs},
+		{"connection.ssh_key_names", "ssh_key_names", &c.Connection.SSHKeyNames, &c.SSHKeyNames},
+		{"connection.pin_ssh_host_key", "pin_ssh_host_key", &c.Connection.PinSSHHostKey, &c.PinSSHHostKey},
+	}
+
+	set := make(map[string]bool, len(keys))
+	blocks := make(map[string]bool)
+	for _, key := range keys {
+		set[key] = true
+		if parts := strings.SplitN(key, ".", 2); len(parts) == 2 {
+			blocks[parts[0]] = true
+		}
+	}
+
+	var errs *packersdk.MultiError
+	var applied, warnings []string
+	for _, alias := range aliases {
+		block := strings.SplitN(alias.block, ".", 2)[0]
+		if !set[alias.block] {
+			if set[alias.flat] && blocks[block] {
+				warnings = append(warnings, fmt.Sprintf("%s is deprecated, set %s instead", alias.flat, alias.block))
+			}
+			continue
+		}
+		if set[alias.flat] {
+			errs = packersdk.MultiErrorAppend(errs,
+				fmt.Errorf("%s and %s can't both be set, %s is deprecated", alias.block, alias.flat, alias.flat))
+			continue
+		}
+		reflect.ValueOf(alias.to).Elem().Set(reflect.ValueOf(alias.from).Elem())
+		applied = append(applied, alias.flat)
+	}
+	if errs != nil && len(errs.Errors) > 0 {
+		return nil, nil, errs
+	}
+	return applied, warnings, nil
+}
+
+// envDefaults are the options applyEnvDefaults takes from the environment,
+// along with the options they can't be combined with. The environment
+// variable is ignored when one of those is set.
+var envDefaults = []struct {
+	option    string
+	exclusive []string
+}{
+	{"region", nil},
+	{"size", []string{"min_vcpus", "min_memory_gb", "size_class"}},
+	{"image", []string{"source_image_filter", "distribution", "source_build"}},
+	{"vpc_uuid", nil},
+	{"private_networking", nil},
+	{"snapshot_regions", nil},
+	{"state_timeout", nil},
+	{"snapshot_timeout", nil},
+	{"tags", nil},
+	{"required_tags", nil},
+}
+
+// applyEnvDefaults sets the options of envDefaults missing from the
+// template from the DIGITALOCEAN_ environment variable named after them,
+// such as DIGITALOCEAN_REGION for `region`. Lists are comma separated. keys
+// are the options set in the template.
+func (c *Config) applyEnvDefaults(keys []string) error {
+	set := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		set[key] = true
+	}
+
+	fields := make(map[string]reflect.Value)
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if name := strings.Split(t.Field(i).Tag.Get("mapstructure"), ",")[0]; name != "" {
+			fields[name] = v.Field(i)
+		}
+	}
+
+	var errs *packersdk.MultiError
+	for _, d := range envDefaults {
+		field := fields[d.option]
+		if set[d.option] || !field.IsZero() {
+			continue
+		}
+		env, ok := os.LookupEnv("DIGITALOCEAN_" + strings.ToUpper(d.option))
+		if !ok || env == "" {
+			continue
+		}
+		exclusive := false
+		for _, other := range d.exclusive {
+			if set[other] || !fields[other].IsZero() {
+				exclusive = true
+			}
+		}
+		if exclusive {
+			continue
+		}
+		if err := setFromEnv(field, env); err != nil {
+			errs = packersdk.MultiErrorAppend(errs,
+				fmt.Errorf("invalid DIGITALOCEAN_%s: %s", strings.ToUpper(d.option), err))
+		}
+	}
 	if errs != nil && len(errs.Errors) > 0 {
-		return nil, errs
+		return errs
 	}
+	return nil
+}
+
+func setFromEnv(field reflect.Value, env string) error {
+	switch field.Interface().(type) {
+	case string:
+		field.SetString(env)
+	case bool:
+		b, err := strconv.ParseBool(env)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case int:
+		n, err := strconv.Atoi(env)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case float64:
+		f, err := strconv.ParseFloat(env, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case time.Duration:
+		d, err := time.ParseDuration(env)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+	case []string:
+		var values []string
+		for _, value := range strings.Split(env, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+		field.Set(reflect.ValueOf(values))
+	case []int:
+		var values []int
+		for _, value := range strings.Split(env, ",") {
+			if value = strings.TrimSpace(value); value == "" {
+				continue
+			}
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return err
+			}
+			values = append(values, n)
+		}
+		field.Set(reflect.ValueOf(values))
+	}
+	return nil
+}
 
-	packersdk.LogSecretFilter.Set(c.APIToken)
-	return nil, nil
+// apiTokens returns api_token followed by the other api_tokens, the tokens
+// the builder fails over between.
+func (c *Config) apiTokens() []string {
+	tokens := []string{c.APIToken}
+	for _, token := range c.APITokens {
+		if token != "" && token != c.APIToken {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
 }
@@ -10,82 +10,156 @@ import (
 // FlatConfig is an auto-generated flat version of Config.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatConfig struct {
-	PackerBuildName           *string           `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
-	PackerBuilderType         *string           `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
-	PackerCoreVersion         *string           `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
-	PackerDebug               *bool             `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
-	PackerForce               *bool             `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
-	PackerOnError             *string           `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
-	PackerUserVars            map[string]string `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
-	PackerSensitiveVars       []string          `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
-	Type                      *string           `mapstructure:"communicator" cty:"communicator" hcl:"communicator"`
-	PauseBeforeConnect        *string           `mapstructure:"pause_before_connecting" cty:"pause_before_connecting" hcl:"pause_before_connecting"`
-	SSHHost                   *string           `mapstructure:"ssh_host" cty:"ssh_host" hcl:"ssh_host"`
-	SSHPort                   *int              `mapstructure:"ssh_port" cty:"ssh_port" hcl:"ssh_port"`
-	SSHUsername               *string           `mapstructure:"ssh_username" cty:"ssh_username" hcl:"ssh_username"`
-	SSHPassword               *string           `mapstructure:"ssh_password" cty:"ssh_password" hcl:"ssh_password"`
-	SSHKeyPairName            *string           `mapstructure:"ssh_keypair_name" undocumented:"true" cty:"ssh_keypair_name" hcl:"ssh_keypair_name"`
-	SSHTemporaryKeyPairName   *string           `mapstructure:"temporary_key_pair_name" undocumented:"true" cty:"temporary_key_pair_name" hcl:"temporary_key_pair_name"`
-	SSHTemporaryKeyPairType   *string           `mapstructure:"temporary_key_pair_type" cty:"temporary_key_pair_type" hcl:"temporary_key_pair_type"`
-	SSHTemporaryKeyPairBits   *int              `mapstructure:"temporary_key_pair_bits" cty:"temporary_key_pair_bits" hcl:"temporary_key_pair_bits"`
-	SSHCiphers                []string          `mapstructure:"ssh_ciphers" cty:"ssh_ciphers" hcl:"ssh_ciphers"`
-	SSHClearAuthorizedKeys    *bool             `mapstructure:"ssh_clear_authorized_keys" cty:"ssh_clear_authorized_keys" hcl:"ssh_clear_authorized_keys"`
-	SSHKEXAlgos               []string          `mapstructure:"ssh_key_exchange_algorithms" cty:"ssh_key_exchange_algorithms" hcl:"ssh_key_exchange_algorithms"`
-	SSHPrivateKeyFile         *string           `mapstructure:"ssh_private_key_file" undocumented:"true" cty:"ssh_private_key_file" hcl:"ssh_private_key_file"`
-	SSHCertificateFile        *string           `mapstructure:"ssh_certificate_file" cty:"ssh_certificate_file" hcl:"ssh_certificate_file"`
-	SSHPty                    *bool             `mapstructure:"ssh_pty" cty:"ssh_pty" hcl:"ssh_pty"`
-	SSHTimeout                *string           `mapstructure:"ssh_timeout" cty:"ssh_timeout" hcl:"ssh_timeout"`
-	SSHWaitTimeout            *string           `mapstructure:"ssh_wait_timeout" undocumented:"true" cty:"ssh_wait_timeout" hcl:"ssh_wait_timeout"`
-	SSHAgentAuth              *bool             `mapstructure:"ssh_agent_auth" undocumented:"true" cty:"ssh_agent_auth" hcl:"ssh_agent_auth"`
-	SSHDisableAgentForwarding *bool             `mapstructure:"ssh_disable_agent_forwarding" cty:"ssh_disable_agent_forwarding" hcl:"ssh_disable_agent_forwarding"`
-	SSHHandshakeAttempts      *int              `mapstructure:"ssh_handshake_attempts" cty:"ssh_handshake_attempts" hcl:"ssh_handshake_attempts"`
-	SSHBastionHost            *string           `mapstructure:"ssh_bastion_host" cty:"ssh_bastion_host" hcl:"ssh_bastion_host"`
-	SSHBastionPort            *int              `mapstructure:"ssh_bastion_port" cty:"ssh_bastion_port" hcl:"ssh_bastion_port"`
-	SSHBastionAgentAuth       *bool             `mapstructure:"ssh_bastion_agent_auth" cty:"ssh_bastion_agent_auth" hcl:"ssh_bastion_agent_auth"`
-	SSHBastionUsername        *string           `mapstructure:"ssh_bastion_username" cty:"ssh_bastion_username" hcl:"ssh_bastion_username"`
-	SSHBastionPassword        *string           `mapstructure:"ssh_bastion_password" cty:"ssh_bastion_password" hcl:"ssh_bastion_password"`
-	SSHBastionInteractive     *bool             `mapstructure:"ssh_bastion_interactive" cty:"ssh_bastion_interactive" hcl:"ssh_bastion_interactive"`
-	SSHBastionPrivateKeyFile  *string           `mapstructure:"ssh_bastion_private_key_file" cty:"ssh_bastion_private_key_file" hcl:"ssh_bastion_private_key_file"`
-	SSHBastionCertificateFile *string           `mapstructure:"ssh_bastion_certificate_file" cty:"ssh_bastion_certificate_file" hcl:"ssh_bastion_certificate_file"`
-	SSHFileTransferMethod     *string           `mapstructure:"ssh_file_transfer_method" cty:"ssh_file_transfer_method" hcl:"ssh_file_transfer_method"`
-	SSHProxyHost              *string           `mapstructure:"ssh_proxy_host" cty:"ssh_proxy_host" hcl:"ssh_proxy_host"`
-	SSHProxyPort              *int              `mapstructure:"ssh_proxy_port" cty:"ssh_proxy_port" hcl:"ssh_proxy_port"`
-	SSHProxyUsername          *string           `mapstructure:"ssh_proxy_username" cty:"ssh_proxy_username" hcl:"ssh_proxy_username"`
-	SSHProxyPassword          *string           `mapstructure:"ssh_proxy_password" cty:"ssh_proxy_password" hcl:"ssh_proxy_password"`
-	SSHKeepAliveInterval      *string           `mapstructure:"ssh_keep_alive_interval" cty:"ssh_keep_alive_interval" hcl:"ssh_keep_alive_interval"`
-	SSHReadWriteTimeout       *string           `mapstructure:"ssh_read_write_timeout" cty:"ssh_read_write_timeout" hcl:"ssh_read_write_timeout"`
-	SSHRemoteTunnels          []string          `mapstructure:"ssh_remote_tunnels" cty:"ssh_remote_tunnels" hcl:"ssh_remote_tunnels"`
-	SSHLocalTunnels           []string          `mapstructure:"ssh_local_tunnels" cty:"ssh_local_tunnels" hcl:"ssh_local_tunnels"`
-	SSHPublicKey              []byte            `mapstructure:"ssh_public_key" undocumented:"true" cty:"ssh_public_key" hcl:"ssh_public_key"`
-	SSHPrivateKey             []byte            `mapstructure:"ssh_private_key" undocumented:"true" cty:"ssh_private_key" hcl:"ssh_private_key"`
-	WinRMUser                 *string           `mapstructure:"winrm_username" cty:"winrm_username" hcl:"winrm_username"`
-	WinRMPassword             *string           `mapstructure:"winrm_password" cty:"winrm_password" hcl:"winrm_password"`
-	WinRMHost                 *string           `mapstructure:"winrm_host" cty:"winrm_host" hcl:"winrm_host"`
-	WinRMNoProxy              *bool             `mapstructure:"winrm_no_proxy" cty:"winrm_no_proxy" hcl:"winrm_no_proxy"`
-	WinRMPort                 *int              `mapstructure:"winrm_port" cty:"winrm_port" hcl:"winrm_port"`
-	WinRMTimeout              *string           `mapstructure:"winrm_timeout" cty:"winrm_timeout" hcl:"winrm_timeout"`
-	WinRMUseSSL               *bool             `mapstructure:"winrm_use_ssl" cty:"winrm_use_ssl" hcl:"winrm_use_ssl"`
-	WinRMInsecure             *bool             `mapstructure:"winrm_insecure" cty:"winrm_insecure" hcl:"winrm_insecure"`
-	WinRMUseNTLM              *bool             `mapstructure:"winrm_use_ntlm" cty:"winrm_use_ntlm" hcl:"winrm_use_ntlm"`
-	APIToken                  *string           `mapstructure:"api_token" required:"true" cty:"api_token" hcl:"api_token"`
-	APIURL                    *string           `mapstructure:"api_url" required:"false" cty:"api_url" hcl:"api_url"`
-	Region                    *string           `mapstructure:"region" required:"true" cty:"region" hcl:"region"`
-	Size                      *string           `mapstructure:"size" required:"true" cty:"size" hcl:"size"`
-	Image                     *string           `mapstructure:"image" required:"true" cty:"image" hcl:"image"`
-	PrivateNetworking         *bool             `mapstructure:"private_networking" required:"false" cty:"private_networking" hcl:"private_networking"`
-	Monitoring                *bool             `mapstructure:"monitoring" required:"false" cty:"monitoring" hcl:"monitoring"`
-	IPv6                      *bool             `mapstructure:"ipv6" required:"false" cty:"ipv6" hcl:"ipv6"`
-	SnapshotName              *string           `mapstructure:"snapshot_name" required:"false" cty:"snapshot_name" hcl:"snapshot_name"`
-	SnapshotRegions           []string          `mapstructure:"snapshot_regions" required:"false" cty:"snapshot_regions" hcl:"snapshot_regions"`
-	StateTimeout              *string           `mapstructure:"state_timeout" required:"false" cty:"state_timeout" hcl:"state_timeout"`
-	SnapshotTimeout           *string           `mapstructure:"snapshot_timeout" required:"false" cty:"snapshot_timeout" hcl:"snapshot_timeout"`
-	DropletName               *string           `mapstructure:"droplet_name" required:"false" cty:"droplet_name" hcl:"droplet_name"`
-	UserData                  *string           `mapstructure:"user_data" required:"false" cty:"user_data" hcl:"user_data"`
-	UserDataFile              *string           `mapstructure:"user_data_file" required:"false" cty:"user_data_file" hcl:"user_data_file"`
-	Tags                      []string          `mapstructure:"tags" required:"false" cty:"tags" hcl:"tags"`
-	VPCUUID                   *string           `mapstructure:"vpc_uuid" required:"false" cty:"vpc_uuid" hcl:"vpc_uuid"`
-	ConnectWithPrivateIP      *bool             `mapstructure:"connect_with_private_ip" required:"false" cty:"connect_with_private_ip" hcl:"connect_with_private_ip"`
-	SSHKeyID                  *int              `mapstructure:"ssh_key_id" required:"false" cty:"ssh_key_id" hcl:"ssh_key_id"`
+	PackerBuildName              *string               `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
+	PackerBuilderType            *string               `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
+	PackerCoreVersion            *string               `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
+	PackerDebug                  *bool                 `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
+	PackerForce                  *bool                 `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
+	PackerOnError                *string               `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
+	PackerUserVars               map[string]string     `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
+	PackerSensitiveVars          []string              `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
+	Type                         *string               `mapstructure:"communicator" cty:"communicator" hcl:"communicator"`
+	PauseBeforeConnect           *string               `mapstructure:"pause_before_connecting" cty:"pause_before_connecting" hcl:"pause_before_connecting"`
+	SSHHost                      *string               `mapstructure:"ssh_host" cty:"ssh_host" hcl:"ssh_host"`
+	SSHPort                      *int                  `mapstructure:"ssh_port" cty:"ssh_port" hcl:"ssh_port"`
+	SSHUsername                  *string               `mapstructure:"ssh_username" cty:"ssh_username" hcl:"ssh_username"`
+	SSHPassword                  *string               `mapstructure:"ssh_password" cty:"ssh_password" hcl:"ssh_password"`
+	SSHKeyPairName               *string               `mapstructure:"ssh_keypair_name" undocumented:"true" cty:"ssh_keypair_name" hcl:"ssh_keypair_name"`
+	SSHTemporaryKeyPairName      *string               `mapstructure:"temporary_key_pair_name" undocumented:"true" cty:"temporary_key_pair_name" hcl:"temporary_key_pair_name"`
+	SSHTemporaryKeyPairType      *string               `mapstructure:"temporary_key_pair_type" cty:"temporary_key_pair_type" hcl:"temporary_key_pair_type"`
+	SSHTemporaryKeyPairBits      *int                  `mapstructure:"temporary_key_pair_bits" cty:"temporary_key_pair_bits" hcl:"temporary_key_pair_bits"`
+	SSHCiphers                   []string              `mapstructure:"ssh_ciphers" cty:"ssh_ciphers" hcl:"ssh_ciphers"`
+	SSHClearAuthorizedKeys       *bool                 `mapstructure:"ssh_clear_authorized_keys" cty:"ssh_clear_authorized_keys" hcl:"ssh_clear_authorized_keys"`
+	SSHKEXAlgos                  []string              `mapstructure:"ssh_key_exchange_algorithms" cty:"ssh_key_exchange_algorithms" hcl:"ssh_key_exchange_algorithms"`
+	SSHPrivateKeyFile            *string               `mapstructure:"ssh_private_key_file" undocumented:"true" cty:"ssh_private_key_file" hcl:"ssh_private_key_file"`
+	SSHCertificateFile           *string               `mapstructure:"ssh_certificate_file" cty:"ssh_certificate_file" hcl:"ssh_certificate_file"`
+	SSHPty                       *bool                 `mapstructure:"ssh_pty" cty:"ssh_pty" hcl:"ssh_pty"`
+	SSHTimeout                   *string               `mapstructure:"ssh_timeout" cty:"ssh_timeout" hcl:"ssh_timeout"`
+	SSHWaitTimeout               *string               `mapstructure:"ssh_wait_timeout" undocumented:"true" cty:"ssh_wait_timeout" hcl:"ssh_wait_timeout"`
+	SSHAgentAuth                 *bool                 `mapstructure:"ssh_agent_auth" undocumented:"true" cty:"ssh_agent_auth" hcl:"ssh_agent_auth"`
+	SSHDisableAgentForwarding    *bool                 `mapstructure:"ssh_disable_agent_forwarding" cty:"ssh_disable_agent_forwarding" hcl:"ssh_disable_agent_forwarding"`
+	SSHHandshakeAttempts         *int                  `mapstructure:"ssh_handshake_attempts" cty:"ssh_handshake_attempts" hcl:"ssh_handshake_attempts"`
+	SSHBastionHost               *string               `mapstructure:"ssh_bastion_host" cty:"ssh_bastion_host" hcl:"ssh_bastion_host"`
+	SSHBastionPort               *int                  `mapstructure:"ssh_bastion_port" cty:"ssh_bastion_port" hcl:"ssh_bastion_port"`
+	SSHBastionAgentAuth          *bool                 `mapstructure:"ssh_bastion_agent_auth" cty:"ssh_bastion_agent_auth" hcl:"ssh_bastion_agent_auth"`
+	SSHBastionUsername           *string               `mapstructure:"ssh_bastion_username" cty:"ssh_bastion_username" hcl:"ssh_bastion_username"`
+	SSHBastionPassword           *string               `mapstructure:"ssh_bastion_password" cty:"ssh_bastion_password" hcl:"ssh_bastion_password"`
+	SSHBastionInteractive        *bool                 `mapstructure:"ssh_bastion_interactive" cty:"ssh_bastion_interactive" hcl:"ssh_bastion_interactive"`
+	SSHBastionPrivateKeyFile     *string               `mapstructure:"ssh_bastion_private_key_file" cty:"ssh_bastion_private_key_file" hcl:"ssh_bastion_private_key_file"`
+	SSHBastionCertificateFile    *string               `mapstructure:"ssh_bastion_certificate_file" cty:"ssh_bastion_certificate_file" hcl:"ssh_bastion_certificate_file"`
+	SSHFileTransferMethod        *string               `mapstructure:"ssh_file_transfer_method" cty:"ssh_file_transfer_method" hcl:"ssh_file_transfer_method"`
+	SSHProxyHost                 *string               `mapstructure:"ssh_proxy_host" cty:"ssh_proxy_host" hcl:"ssh_proxy_host"`
+	SSHProxyPort                 *int                  `mapstructure:"ssh_proxy_port" cty:"ssh_proxy_port" hcl:"ssh_proxy_port"`
+	SSHProxyUsername             *string               `mapstructure:"ssh_proxy_username" cty:"ssh_proxy_username" hcl:"ssh_proxy_username"`
+	SSHProxyPassword             *string               `mapstructure:"ssh_proxy_password" cty:"ssh_proxy_password" hcl:"ssh_proxy_password"`
+	SSHKeepAliveInterval         *string               `mapstructure:"ssh_keep_alive_interval" cty:"ssh_keep_alive_interval" hcl:"ssh_keep_alive_interval"`
+	SSHReadWriteTimeout          *string               `mapstructure:"ssh_read_write_timeout" cty:"ssh_read_write_timeout" hcl:"ssh_read_write_timeout"`
+	SSHRemoteTunnels             []string              `mapstructure:"ssh_remote_tunnels" cty:"ssh_remote_tunnels" hcl:"ssh_remote_tunnels"`
+	SSHLocalTunnels              []string              `mapstructure:"ssh_local_tunnels" cty:"ssh_local_tunnels" hcl:"ssh_local_tunnels"`
+	SSHPublicKey                 []byte                `mapstructure:"ssh_public_key" undocumented:"true" cty:"ssh_public_key" hcl:"ssh_public_key"`
+	SSHPrivateKey                []byte                `mapstructure:"ssh_private_key" undocumented:"true" cty:"ssh_private_key" hcl:"ssh_private_key"`
+	WinRMUser                    *string               `mapstructure:"winrm_username" cty:"winrm_username" hcl:"winrm_username"`
+	WinRMPassword                *string               `mapstructure:"winrm_password" cty:"winrm_password" hcl:"winrm_password"`
+	WinRMHost                    *string               `mapstructure:"winrm_host" cty:"winrm_host" hcl:"winrm_host"`
+	WinRMNoProxy                 *bool                 `mapstructure:"winrm_no_proxy" cty:"winrm_no_proxy" hcl:"winrm_no_proxy"`
+	WinRMPort                    *int                  `mapstructure:"winrm_port" cty:"winrm_port" hcl:"winrm_port"`
+	WinRMTimeout                 *string               `mapstructure:"winrm_timeout" cty:"winrm_timeout" hcl:"winrm_timeout"`
+	WinRMUseSSL                  *bool                 `mapstructure:"winrm_use_ssl" cty:"winrm_use_ssl" hcl:"winrm_use_ssl"`
+	WinRMInsecure                *bool                 `mapstructure:"winrm_insecure" cty:"winrm_insecure" hcl:"winrm_insecure"`
+	WinRMUseNTLM                 *bool                 `mapstructure:"winrm_use_ntlm" cty:"winrm_use_ntlm" hcl:"winrm_use_ntlm"`
+	Droplet                      *FlatDropletConfig    `mapstructure:"droplet" required:"false" cty:"droplet" hcl:"droplet"`
+	Snapshot                     *FlatSnapshotConfig   `mapstructure:"snapshot" required:"false" cty:"snapshot" hcl:"snapshot"`
+	Connection                   *FlatConnectionConfig `mapstructure:"connection" required:"false" cty:"connection" hcl:"connection"`
+	APIToken                     *string               `mapstructure:"api_token" required:"true" cty:"api_token" hcl:"api_token"`
+	APITokens                    []string              `mapstructure:"api_tokens" required:"false" cty:"api_tokens" hcl:"api_tokens"`
+	APIURL                       *string               `mapstructure:"api_url" required:"false" cty:"api_url" hcl:"api_url"`
+	Region                       *string               `mapstructure:"region" required:"true" cty:"region" hcl:"region"`
+	RegionStrategy               *string               `mapstructure:"region_strategy" required:"false" cty:"region_strategy" hcl:"region_strategy"`
+	RegionPreference             []string              `mapstructure:"region_preference" required:"false" cty:"region_preference" hcl:"region_preference"`
+	Size                         *string               `mapstructure:"size" required:"true" cty:"size" hcl:"size"`
+	MinVCPUs                     *int                  `mapstructure:"min_vcpus" required:"false" cty:"min_vcpus" hcl:"min_vcpus"`
+	MinMemoryGB                  *int                  `mapstructure:"min_memory_gb" required:"false" cty:"min_memory_gb" hcl:"min_memory_gb"`
+	SizeClass                    *string               `mapstructure:"size_class" required:"false" cty:"size_class" hcl:"size_class"`
+	Image                        *string               `mapstructure:"image" required:"true" cty:"image" hcl:"image"`
+	SourceImageFilter            *FlatImageFilter      `mapstructure:"source_image_filter" required:"false" cty:"source_image_filter" hcl:"source_image_filter"`
+	Distribution                 *string               `mapstructure:"distribution" required:"false" cty:"distribution" hcl:"distribution"`
+	Version                      *string               `mapstructure:"version" required:"false" cty:"version" hcl:"version"`
+	SourceBuild                  *string               `mapstructure:"source_build" required:"false" cty:"source_build" hcl:"source_build"`
+	SourceBuildTimeout           *string               `mapstructure:"source_build_timeout" required:"false" cty:"source_build_timeout" hcl:"source_build_timeout"`
+	PrivateNetworking            *bool                 `mapstructure:"private_networking" required:"false" cty:"private_networking" hcl:"private_networking"`
+	Monitoring                   *bool                 `mapstructure:"monitoring" required:"false" cty:"monitoring" hcl:"monitoring"`
+	DropletAgent                 *bool                 `mapstructure:"droplet_agent" required:"false" cty:"droplet_agent" hcl:"droplet_agent"`
+	WaitForDropletAgent          *bool                 `mapstructure:"wait_for_droplet_agent" required:"false" cty:"wait_for_droplet_agent" hcl:"wait_for_droplet_agent"`
+	IPv6                         *bool                 `mapstructure:"ipv6" required:"false" cty:"ipv6" hcl:"ipv6"`
+	TemporaryBastion             *bool                 `mapstructure:"temporary_bastion" required:"false" cty:"temporary_bastion" hcl:"temporary_bastion"`
+	TemporaryBastionSize         *string               `mapstructure:"temporary_bastion_size" required:"false" cty:"temporary_bastion_size" hcl:"temporary_bastion_size"`
+	TemporaryBastionImage        *string               `mapstructure:"temporary_bastion_image" required:"false" cty:"temporary_bastion_image" hcl:"temporary_bastion_image"`
+	SnapshotName                 *string               `mapstructure:"snapshot_name" required:"false" cty:"snapshot_name" hcl:"snapshot_name"`
+	SnapshotVersionPrefix        *string               `mapstructure:"snapshot_version_prefix" required:"false" cty:"snapshot_version_prefix" hcl:"snapshot_version_prefix"`
+	SnapshotRegions              []string              `mapstructure:"snapshot_regions" required:"false" cty:"snapshot_regions" hcl:"snapshot_regions"`
+	StateTimeout                 *string               `mapstructure:"state_timeout" required:"false" cty:"state_timeout" hcl:"state_timeout"`
+	ActiveTimeout                *string               `mapstructure:"active_timeout" required:"false" cty:"active_timeout" hcl:"active_timeout"`
+	SnapshotTimeout              *string               `mapstructure:"snapshot_timeout" required:"false" cty:"snapshot_timeout" hcl:"snapshot_timeout"`
+	MaxBuildDuration             *string               `mapstructure:"max_build_duration" required:"false" cty:"max_build_duration" hcl:"max_build_duration"`
+	CleanupSnapshotOnError       *bool                 `mapstructure:"cleanup_snapshot_on_error" required:"false" cty:"cleanup_snapshot_on_error" hcl:"cleanup_snapshot_on_error"`
+	SnapshotAllowFailedTransfers *bool                 `mapstructure:"snapshot_allow_failed_transfers" required:"false" cty:"snapshot_allow_failed_transfers" hcl:"snapshot_allow_failed_transfers"`
+	SnapshotVolumes              *bool                 `mapstructure:"snapshot_volumes" required:"false" cty:"snapshot_volumes" hcl:"snapshot_volumes"`
+	DropletName                  *string               `mapstructure:"droplet_name" required:"false" cty:"droplet_name" hcl:"droplet_name"`
+	UserData                     *string               `mapstructure:"user_data" required:"false" cty:"user_data" hcl:"user_data"`
+	UserDataFile                 *string               `mapstructure:"user_data_file" required:"false" cty:"user_data_file" hcl:"user_data_file"`
+	UserDataChecksum             *string               `mapstructure:"user_data_checksum" required:"false" cty:"user_data_checksum" hcl:"user_data_checksum"`
+	UserDataCommand              *string               `mapstructure:"user_data_command" required:"false" cty:"user_data_command" hcl:"user_data_command"`
+	UserDataVars                 map[string]string     `mapstructure:"user_data_vars" required:"false" cty:"user_data_vars" hcl:"user_data_vars"`
+	UserDataSensitiveVars        []string              `mapstructure:"user_data_sensitive_vars" required:"false" cty:"user_data_sensitive_vars" hcl:"user_data_sensitive_vars"`
+	Tags                         []string              `mapstructure:"tags" required:"false" cty:"tags" hcl:"tags"`
+	SnapshotTags                 []string              `mapstructure:"snapshot_tags" required:"false" cty:"snapshot_tags" hcl:"snapshot_tags"`
+	SnapshotDescription          *string               `mapstructure:"snapshot_description" required:"false" cty:"snapshot_description" hcl:"snapshot_description"`
+	RequiredTags                 []string              `mapstructure:"required_tags" required:"false" cty:"required_tags" hcl:"required_tags"`
+	AutoTagCI                    *bool                 `mapstructure:"auto_tag_ci" required:"false" cty:"auto_tag_ci" hcl:"auto_tag_ci"`
+	VPCUUID                      *string               `mapstructure:"vpc_uuid" required:"false" cty:"vpc_uuid" hcl:"vpc_uuid"`
+	ReservedIP                   *string               `mapstructure:"reserved_ip" required:"false" cty:"reserved_ip" hcl:"reserved_ip"`
+	ConnectWithPrivateIP         *bool                 `mapstructure:"connect_with_private_ip" required:"false" cty:"connect_with_private_ip" hcl:"connect_with_private_ip"`
+	SSHKeyID                     *int                  `mapstructure:"ssh_key_id" required:"false" cty:"ssh_key_id" hcl:"ssh_key_id"`
+	SSHKeyIDs                    []int                 `mapstructure:"ssh_key_ids" required:"false" cty:"ssh_key_ids" hcl:"ssh_key_ids"`
+	SSHKeyNames                  []string              `mapstructure:"ssh_key_names" required:"false" cty:"ssh_key_names" hcl:"ssh_key_names"`
+	SharedTemporaryKey           *bool                 `mapstructure:"shared_temporary_key" required:"false" cty:"shared_temporary_key" hcl:"shared_temporary_key"`
+	SkipKeyCleanup               *bool                 `mapstructure:"skip_key_cleanup" required:"false" cty:"skip_key_cleanup" hcl:"skip_key_cleanup"`
+	MaxHourlyPrice               *float64              `mapstructure:"max_hourly_price" required:"false" cty:"max_hourly_price" hcl:"max_hourly_price"`
+	MaxEstimatedCost             *float64              `mapstructure:"max_estimated_cost" required:"false" cty:"max_estimated_cost" hcl:"max_estimated_cost"`
+	BudgetAction                 *string               `mapstructure:"budget_action" required:"false" cty:"budget_action" hcl:"budget_action"`
+	ValidateOnly                 *bool                 `mapstructure:"validate_only" required:"false" cty:"validate_only" hcl:"validate_only"`
+	PinSSHHostKey                *bool                 `mapstructure:"pin_ssh_host_key" required:"false" cty:"pin_ssh_host_key" hcl:"pin_ssh_host_key"`
+	SkipWinRMSetup               *bool                 `mapstructure:"skip_winrm_setup" required:"false" cty:"skip_winrm_setup" hcl:"skip_winrm_setup"`
+	VerifyCommands               []string              `mapstructure:"verify_commands" required:"false" cty:"verify_commands" hcl:"verify_commands"`
+	VerifySize                   *string               `mapstructure:"verify_size" required:"false" cty:"verify_size" hcl:"verify_size"`
+	UpdatePackages               *bool                 `mapstructure:"update_packages" required:"false" cty:"update_packages" hcl:"update_packages"`
+	RebootBeforeSnapshot         *bool                 `mapstructure:"reboot_before_snapshot" required:"false" cty:"reboot_before_snapshot" hcl:"reboot_before_snapshot"`
+	RebootTimeout                *string               `mapstructure:"reboot_timeout" required:"false" cty:"reboot_timeout" hcl:"reboot_timeout"`
+	IntermediateSnapshots        *bool                 `mapstructure:"intermediate_snapshots" required:"false" cty:"intermediate_snapshots" hcl:"intermediate_snapshots"`
+	FreezeMountPoints            []string              `mapstructure:"freeze_mount_points" required:"false" cty:"freeze_mount_points" hcl:"freeze_mount_points"`
+	Generalize                   *bool                 `mapstructure:"generalize" required:"false" cty:"generalize" hcl:"generalize"`
+	TrimDisk                     *bool                 `mapstructure:"trim_disk" required:"false" cty:"trim_disk" hcl:"trim_disk"`
+	FilesystemDigest             *bool                 `mapstructure:"filesystem_digest" required:"false" cty:"filesystem_digest" hcl:"filesystem_digest"`
+	SkipUnchanged                *bool                 `mapstructure:"skip_unchanged" required:"false" cty:"skip_unchanged" hcl:"skip_unchanged"`
+	InputFiles                   []string              `mapstructure:"input_files" required:"false" cty:"input_files" hcl:"input_files"`
+	PackageInventory             *bool                 `mapstructure:"package_inventory" required:"false" cty:"package_inventory" hcl:"package_inventory"`
+	PackageInventoryFile         *string               `mapstructure:"package_inventory_file" required:"false" cty:"package_inventory_file" hcl:"package_inventory_file"`
+	SnapshotDiffFile             *string               `mapstructure:"snapshot_diff_file" required:"false" cty:"snapshot_diff_file" hcl:"snapshot_diff_file"`
+	SnapshotDiffImage            *string               `mapstructure:"snapshot_diff_image" required:"false" cty:"snapshot_diff_image" hcl:"snapshot_diff_image"`
+	Hooks                        *FlatHooks            `mapstructure:"hooks" required:"false" cty:"hooks" hcl:"hooks"`
+	Webhooks                     []string              `mapstructure:"webhooks" required:"false" cty:"webhooks" hcl:"webhooks"`
+	OTLPEndpoint                 *string               `mapstructure:"otlp_endpoint" required:"false" cty:"otlp_endpoint" hcl:"otlp_endpoint"`
+	OTLPHeaders                  map[string]string     `mapstructure:"otlp_headers" required:"false" cty:"otlp_headers" hcl:"otlp_headers"`
+	Volumes                      []FlatVolume          `mapstructure:"volume" required:"false" cty:"volume" hcl:"volume"`
+	AuditLog                     *string               `mapstructure:"audit_log" required:"false" cty:"audit_log" hcl:"audit_log"`
+	CloudInitLogDir              *string               `mapstructure:"cloud_init_log_dir" required:"false" cty:"cloud_init_log_dir" hcl:"cloud_init_log_dir"`
+	DebugBundleDir               *string               `mapstructure:"debug_bundle_dir" required:"false" cty:"debug_bundle_dir" hcl:"debug_bundle_dir"`
+	DisableStatusPage            *bool                 `mapstructure:"disable_status_page" required:"false" cty:"disable_status_page" hcl:"disable_status_page"`
+	ProgressFile                 *string               `mapstructure:"progress_file" required:"false" cty:"progress_file" hcl:"progress_file"`
+	SummaryFile                  *string               `mapstructure:"summary_file" required:"false" cty:"summary_file" hcl:"summary_file"`
+	CheckpointFile               *string               `mapstructure:"checkpoint_file" required:"false" cty:"checkpoint_file" hcl:"checkpoint_file"`
+	LogLevel                     *string               `mapstructure:"log_level" required:"false" cty:"log_level" hcl:"log_level"`
+	DefaultsFile                 *string               `mapstructure:"defaults_file" required:"false" cty:"defaults_file" hcl:"defaults_file"`
 }
 
 // FlatMapstructure returns a new FlatConfig.
@@ -100,82 +174,380 @@ func (*Config) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec }
 // The decoded values from this spec will then be applied to a FlatConfig.
 func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 	s := map[string]hcldec.Spec{
-		"packer_build_name":            &hcldec.AttrSpec{Name: "packer_build_name", Type: cty.String, Required: false},
-		"packer_builder_type":          &hcldec.AttrSpec{Name: "packer_builder_type", Type: cty.String, Required: false},
-		"packer_core_version":          &hcldec.AttrSpec{Name: "packer_core_version", Type: cty.String, Required: false},
-		"packer_debug":                 &hcldec.AttrSpec{Name: "packer_debug", Type: cty.Bool, Required: false},
-		"packer_force":                 &hcldec.AttrSpec{Name: "packer_force", Type: cty.Bool, Required: false},
-		"packer_on_error":              &hcldec.AttrSpec{Name: "packer_on_error", Type: cty.String, Required: false},
-		"packer_user_variables":        &hcldec.AttrSpec{Name: "packer_user_variables", Type: cty.Map(cty.String), Required: false},
-		"packer_sensitive_variables":   &hcldec.AttrSpec{Name: "packer_sensitive_variables", Type: cty.List(cty.String), Required: false},
-		"communicator":                 &hcldec.AttrSpec{Name: "communicator", Type: cty.String, Required: false},
-		"pause_before_connecting":      &hcldec.AttrSpec{Name: "pause_before_connecting", Type: cty.String, Required: false},
-		"ssh_host":                     &hcldec.AttrSpec{Name: "ssh_host", Type: cty.String, Required: false},
-		"ssh_port":                     &hcldec.AttrSpec{Name: "ssh_port", Type: cty.Number, Required: false},
-		"ssh_username":                 &hcldec.AttrSpec{Name: "ssh_username", Type: cty.String, Required: false},
-		"ssh_password":                 &hcldec.AttrSpec{Name: "ssh_password", Type: cty.String, Required: false},
-		"ssh_keypair_name":             &hcldec.AttrSpec{Name: "ssh_keypair_name", Type: cty.String, Required: false},
-		"temporary_key_pair_name":      &hcldec.AttrSpec{Name: "temporary_key_pair_name", Type: cty.String, Required: false},
-		"temporary_key_pair_type":      &hcldec.AttrSpec{Name: "temporary_key_pair_type", Type: cty.String, Required: false},
-		"temporary_key_pair_bits":      &hcldec.AttrSpec{Name: "temporary_key_pair_bits", Type: cty.Number, Required: false},
-		"ssh_ciphers":                  &hcldec.AttrSpec{Name: "ssh_ciphers", Type: cty.List(cty.String), Required: false},
-		"ssh_clear_authorized_keys":    &hcldec.AttrSpec{Name: "ssh_clear_authorized_keys", Type: cty.Bool, Required: false},
-		"ssh_key_exchange_algorithms":  &hcldec.AttrSpec{Name: "ssh_key_exchange_algorithms", Type: cty.List(cty.String), Required: false},
-		"ssh_private_key_file":         &hcldec.AttrSpec{Name: "ssh_private_key_file", Type: cty.String, Required: false},
-		"ssh_certificate_file":         &hcldec.AttrSpec{Name: "ssh_certificate_file", Type: cty.String, Required: false},
-		"ssh_pty":                      &hcldec.AttrSpec{Name: "ssh_pty", Type: cty.Bool, Required: false},
-		"ssh_timeout":                  &hcldec.AttrSpec{Name: "ssh_timeout", Type: cty.String, Required: false},
-		"ssh_wait_timeout":             &hcldec.AttrSpec{Name: "ssh_wait_timeout", Type: cty.String, Required: false},
-		"ssh_agent_auth":               &hcldec.AttrSpec{Name: "ssh_agent_auth", Type: cty.Bool, Required: false},
-		"ssh_disable_agent_forwarding": &hcldec.AttrSpec{Name: "ssh_disable_agent_forwarding", Type: cty.Bool, Required: false},
-		"ssh_handshake_attempts":       &hcldec.AttrSpec{Name: "ssh_handshake_attempts", Type: cty.Number, Required: false},
-		"ssh_bastion_host":             &hcldec.AttrSpec{Name: "ssh_bastion_host", Type: cty.String, Required: false},
-		"ssh_bastion_port":             &hcldec.AttrSpec{Name: "ssh_bastion_port", Type: cty.Number, Required: false},
-		"ssh_bastion_agent_auth":       &hcldec.AttrSpec{Name: "ssh_bastion_agent_auth", Type: cty.Bool, Required: false},
-		"ssh_bastion_username":         &hcldec.AttrSpec{Name: "ssh_bastion_username", Type: cty.String, Required: false},
-		"ssh_bastion_password":         &hcldec.AttrSpec{Name: "ssh_bastion_password", Type: cty.String, Required: false},
-		"ssh_bastion_interactive":      &hcldec.AttrSpec{Name: "ssh_bastion_interactive", Type: cty.Bool, Required: false},
-		"ssh_bastion_private_key_file": &hcldec.AttrSpec{Name: "ssh_bastion_private_key_file", Type: cty.String, Required: false},
-		"ssh_bastion_certificate_file": &hcldec.AttrSpec{Name: "ssh_bastion_certificate_file", Type: cty.String, Required: false},
-		"ssh_file_transfer_method":     &hcldec.AttrSpec{Name: "ssh_file_transfer_method", Type: cty.String, Required: false},
-		"ssh_proxy_host":               &hcldec.AttrSpec{Name: "ssh_proxy_host", Type: cty.String, Required: false},
-		"ssh_proxy_port":               &hcldec.AttrSpec{Name: "ssh_proxy_port", Type: cty.Number, Required: false},
-		"ssh_proxy_username":           &hcldec.AttrSpec{Name: "ssh_proxy_username", Type: cty.String, Required: false},
-		"ssh_proxy_password":           &hcldec.AttrSpec{Name: "ssh_proxy_password", Type: cty.String, Required: false},
-		"ssh_keep_alive_interval":      &hcldec.AttrSpec{Name: "ssh_keep_alive_interval", Type: cty.String, Required: false},
-		"ssh_read_write_timeout":       &hcldec.AttrSpec{Name: "ssh_read_write_timeout", Type: cty.String, Required: false},
-		"ssh_remote_tunnels":           &hcldec.AttrSpec{Name: "ssh_remote_tunnels", Type: cty.List(cty.String), Required: false},
-		"ssh_local_tunnels":            &hcldec.AttrSpec{Name: "ssh_local_tunnels", Type: cty.List(cty.String), Required: false},
-		"ssh_public_key":               &hcldec.AttrSpec{Name: "ssh_public_key", Type: cty.List(cty.Number), Required: false},
-		"ssh_private_key":              &hcldec.AttrSpec{Name: "ssh_private_key", Type: cty.List(cty.Number), Required: false},
-		"winrm_username":               &hcldec.AttrSpec{Name: "winrm_username", Type: cty.String, Required: false},
-		"winrm_password":               &hcldec.AttrSpec{Name: "winrm_password", Type: cty.String, Required: false},
-		"winrm_host":                   &hcldec.AttrSpec{Name: "winrm_host", Type: cty.String, Required: false},
-		"winrm_no_proxy":               &hcldec.AttrSpec{Name: "winrm_no_proxy", Type: cty.Bool, Required: false},
-		"winrm_port":                   &hcldec.AttrSpec{Name: "winrm_port", Type: cty.Number, Required: false},
-		"winrm_timeout":                &hcldec.AttrSpec{Name: "winrm_timeout", Type: cty.String, Required: false},
-		"winrm_use_ssl":                &hcldec.AttrSpec{Name: "winrm_use_ssl", Type: cty.Bool, Required: false},
-		"winrm_insecure":               &hcldec.AttrSpec{Name: "winrm_insecure", Type: cty.Bool, Required: false},
-		"winrm_use_ntlm":               &hcldec.AttrSpec{Name: "winrm_use_ntlm", Type: cty.Bool, Required: false},
-		"api_token":                    &hcldec.AttrSpec{Name: "api_token", Type: cty.String, Required: false},
-		"api_url":                      &hcldec.AttrSpec{Name: "api_url", Type: cty.String, Required: false},
-		"region":                       &hcldec.AttrSpec{Name: "region", Type: cty.String, Required: false},
-		"size":                         &hcldec.AttrSpec{Name: "size", Type: cty.String, Required: false},
-		"image":                        &hcldec.AttrSpec{Name: "image", Type: cty.String, Required: false},
-		"private_networking":           &hcldec.AttrSpec{Name: "private_networking", Type: cty.Bool, Required: false},
-		"monitoring":                   &hcldec.AttrSpec{Name: "monitoring", Type: cty.Bool, Required: false},
-		"ipv6":                         &hcldec.AttrSpec{Name: "ipv6", Type: cty.Bool, Required: false},
-		"snapshot_name":                &hcldec.AttrSpec{Name: "snapshot_name", Type: cty.String, Required: false},
-		"snapshot_regions":             &hcldec.AttrSpec{Name: "snapshot_regions", Type: cty.List(cty.String), Required: false},
-		"state_timeout":                &hcldec.AttrSpec{Name: "state_timeout", Type: cty.String, Required: false},
-		"snapshot_timeout":             &hcldec.AttrSpec{Name: "snapshot_timeout", Type: cty.String, Required: false},
-		"droplet_name":                 &hcldec.AttrSpec{Name: "droplet_name", Type: cty.String, Required: false},
-		"user_data":                    &hcldec.AttrSpec{Name: "user_data", Type: cty.String, Required: false},
-		"user_data_file":               &hcldec.AttrSpec{Name: "user_data_file", Type: cty.String, Required: false},
-		"tags":                         &hcldec.AttrSpec{Name: "tags", Type: cty.List(cty.String), Required: false},
-		"vpc_uuid":                     &hcldec.AttrSpec{Name: "vpc_uuid", Type: cty.String, Required: false},
-		"connect_with_private_ip":      &hcldec.AttrSpec{Name: "connect_with_private_ip", Type: cty.Bool, Required: false},
-		"ssh_key_id":                   &hcldec.AttrSpec{Name: "ssh_key_id", Type: cty.Number, Required: false},
+		"packer_build_name":               &hcldec.AttrSpec{Name: "packer_build_name", Type: cty.String, Required: false},
+		"packer_builder_type":             &hcldec.AttrSpec{Name: "packer_builder_type", Type: cty.String, Required: false},
+		"packer_core_version":             &hcldec.AttrSpec{Name: "packer_core_version", Type: cty.String, Required: false},
+		"packer_debug":                    &hcldec.AttrSpec{Name: "packer_debug", Type: cty.Bool, Required: false},
+		"packer_force":                    &hcldec.AttrSpec{Name: "packer_force", Type: cty.Bool, Required: false},
+		"packer_on_error":                 &hcldec.AttrSpec{Name: "packer_on_error", Type: cty.String, Required: false},
+		"packer_user_variables":           &hcldec.AttrSpec{Name: "packer_user_variables", Type: cty.Map(cty.String), Required: false},
+		"packer_sensitive_variables":      &hcldec.AttrSpec{Name: "packer_sensitive_variables", Type: cty.List(cty.String), Required: false},
+		"communicator":                    &hcldec.AttrSpec{Name: "communicator", Type: cty.String, Required: false},
+		"pause_before_connecting":         &hcldec.AttrSpec{Name: "pause_before_connecting", Type: cty.String, Required: false},
+		"ssh_host":                        &hcldec.AttrSpec{Name: "ssh_host", Type: cty.String, Required: false},
+		"ssh_port":                        &hcldec.AttrSpec{Name: "ssh_port", Type: cty.Number, Required: false},
+		"ssh_username":                    &hcldec.AttrSpec{Name: "ssh_username", Type: cty.String, Required: false},
+		"ssh_password":                    &hcldec.AttrSpec{Name: "ssh_password", Type: cty.String, Required: false},
+		"ssh_keypair_name":                &hcldec.AttrSpec{Name: "ssh_keypair_name", Type: cty.String, Required: false},
+		"temporary_key_pair_name":         &hcldec.AttrSpec{Name: "temporary_key_pair_name", Type: cty.String, Required: false},
+		"temporary_key_pair_type":         &hcldec.AttrSpec{Name: "temporary_key_pair_type", Type: cty.String, Required: false},
+		"temporary_key_pair_bits":         &hcldec.AttrSpec{Name: "temporary_key_pair_bits", Type: cty.Number, Required: false},
+		"ssh_ciphers":                     &hcldec.AttrSpec{Name: "ssh_ciphers", Type: cty.List(cty.String), Required: false},
+		"ssh_clear_authorized_keys":       &hcldec.AttrSpec{Name: "ssh_clear_authorized_keys", Type: cty.Bool, Required: false},
+		"ssh_key_exchange_algorithms":     &hcldec.AttrSpec{Name: "ssh_key_exchange_algorithms", Type: cty.List(cty.String), Required: false},
+		"ssh_private_key_file":            &hcldec.AttrSpec{Name: "ssh_private_key_file", Type: cty.String, Required: false},
+		"ssh_certificate_file":            &hcldec.AttrSpec{Name: "ssh_certificate_file", Type: cty.String, Required: false},
+		"ssh_pty":                         &hcldec.AttrSpec{Name: "ssh_pty", Type: cty.Bool, Required: false},
+		"ssh_timeout":                     &hcldec.AttrSpec{Name: "ssh_timeout", Type: cty.String, Required: false},
+		"ssh_wait_timeout":                &hcldec.AttrSpec{Name: "ssh_wait_timeout", Type: cty.String, Required: false},
+		"ssh_agent_auth":                  &hcldec.AttrSpec{Name: "ssh_agent_auth", Type: cty.Bool, Required: false},
+		"ssh_disable_agent_forwarding":    &hcldec.AttrSpec{Name: "ssh_disable_agent_forwarding", Type: cty.Bool, Required: false},
+		"ssh_handshake_attempts":          &hcldec.AttrSpec{Name: "ssh_handshake_attempts", Type: cty.Number, Required: false},
+		"ssh_bastion_host":                &hcldec.AttrSpec{Name: "ssh_bastion_host", Type: cty.String, Required: false},
+		"ssh_bastion_port":                &hcldec.AttrSpec{Name: "ssh_bastion_port", Type: cty.Number, Required: false},
+		"ssh_bastion_agent_auth":          &hcldec.AttrSpec{Name: "ssh_bastion_agent_auth", Type: cty.Bool, Required: false},
+		"ssh_bastion_username":            &hcldec.AttrSpec{Name: "ssh_bastion_username", Type: cty.String, Required: false},
+		"ssh_bastion_password":            &hcldec.AttrSpec{Name: "ssh_bastion_password", Type: cty.String, Required: false},
+		"ssh_bastion_interactive":         &hcldec.AttrSpec{Name: "ssh_bastion_interactive", Type: cty.Bool, Required: false},
+		"ssh_bastion_private_key_file":    &hcldec.AttrSpec{Name: "ssh_bastion_private_key_file", Type: cty.String, Required: false},
+		"ssh_bastion_certificate_file":    &hcldec.AttrSpec{Name: "ssh_bastion_certificate_file", Type: cty.String, Required: false},
+		"ssh_file_transfer_method":        &hcldec.AttrSpec{Name: "ssh_file_transfer_method", Type: cty.String, Required: false},
+		"ssh_proxy_host":                  &hcldec.AttrSpec{Name: "ssh_proxy_host", Type: cty.String, Required: false},
+		"ssh_proxy_port":                  &hcldec.AttrSpec{Name: "ssh_proxy_port", Type: cty.Number, Required: false},
+		"ssh_proxy_username":              &hcldec.AttrSpec{Name: "ssh_proxy_username", Type: cty.String, Required: false},
+		"ssh_proxy_password":              &hcldec.AttrSpec{Name: "ssh_proxy_password", Type: cty.String, Required: false},
+		"ssh_keep_alive_interval":         &hcldec.AttrSpec{Name: "ssh_keep_alive_interval", Type: cty.String, Required: false},
+		"ssh_read_write_timeout":          &hcldec.AttrSpec{Name: "ssh_read_write_timeout", Type: cty.String, Required: false},
+		"ssh_remote_tunnels":              &hcldec.AttrSpec{Name: "ssh_remote_tunnels", Type: cty.List(cty.String), Required: false},
+		"ssh_local_tunnels":               &hcldec.AttrSpec{Name: "ssh_local_tunnels", Type: cty.List(cty.String), Required: false},
+		"ssh_public_key":                  &hcldec.AttrSpec{Name: "ssh_public_key", Type: cty.List(cty.Number), Required: false},
+		"ssh_private_key":                 &hcldec.AttrSpec{Name: "ssh_private_key", Type: cty.List(cty.Number), Required: false},
+		"winrm_username":                  &hcldec.AttrSpec{Name: "winrm_username", Type: cty.String, Required: false},
+		"winrm_password":                  &hcldec.AttrSpec{Name: "winrm_password", Type: cty.String, Required: false},
+		"winrm_host":                      &hcldec.AttrSpec{Name: "winrm_host", Type: cty.String, Required: false},
+		"winrm_no_proxy":                  &hcldec.AttrSpec{Name: "winrm_no_proxy", Type: cty.Bool, Required: false},
+		"winrm_port":                      &hcldec.AttrSpec{Name: "winrm_port", Type: cty.Number, Required: false},
+		"winrm_timeout":                   &hcldec.AttrSpec{Name: "winrm_timeout", Type: cty.String, Required: false},
+		"winrm_use_ssl":                   &hcldec.AttrSpec{Name: "winrm_use_ssl", Type: cty.Bool, Required: false},
+		"winrm_insecure":                  &hcldec.AttrSpec{Name: "winrm_insecure", Type: cty.Bool, Required: false},
+		"winrm_use_ntlm":                  &hcldec.AttrSpec{Name: "winrm_use_ntlm", Type: cty.Bool, Required: false},
+		"droplet":                         &hcldec.BlockSpec{TypeName: "droplet", Nested: hcldec.ObjectSpec((*FlatDropletConfig)(nil).HCL2Spec())},
+		"snapshot":                        &hcldec.BlockSpec{TypeName: "snapshot", Nested: hcldec.ObjectSpec((*FlatSnapshotConfig)(nil).HCL2Spec())},
+		"connection":                      &hcldec.BlockSpec{TypeName: "connection", Nested: hcldec.ObjectSpec((*FlatConnectionConfig)(nil).HCL2Spec())},
+		"api_token":                       &hcldec.AttrSpec{Name: "api_token", Type: cty.String, Required: false},
+		"api_tokens":                      &hcldec.AttrSpec{Name: "api_tokens", Type: cty.List(cty.String), Required: false},
+		"api_url":                         &hcldec.AttrSpec{Name: "api_url", Type: cty.String, Required: false},
+		"region":                          &hcldec.AttrSpec{Name: "region", Type: cty.String, Required: false},
+		"region_strategy":                 &hcldec.AttrSpec{Name: "region_strategy", Type: cty.String, Required: false},
+		"region_preference":               &hcldec.AttrSpec{Name: "region_preference", Type: cty.List(cty.String), Required: false},
+		"size":                            &hcldec.AttrSpec{Name: "size", Type: cty.String, Required: false},
+		"min_vcpus":                       &hcldec.AttrSpec{Name: "min_vcpus", Type: cty.Number, Required: false},
+		"min_memory_gb":                   &hcldec.AttrSpec{Name: "min_memory_gb", Type: cty.Number, Required: false},
+		"size_class":                      &hcldec.AttrSpec{Name: "size_class", Type: cty.String, Required: false},
+		"image":                           &hcldec.AttrSpec{Name: "image", Type: cty.String, Required: false},
+		"source_image_filter":             &hcldec.BlockSpec{TypeName: "source_image_filter", Nested: hcldec.ObjectSpec((*FlatImageFilter)(nil).HCL2Spec())},
+		"distribution":                    &hcldec.AttrSpec{Name: "distribution", Type: cty.String, Required: false},
+		"version":                         &hcldec.AttrSpec{Name: "version", Type: cty.String, Required: false},
+		"source_build":                    &hcldec.AttrSpec{Name: "source_build", Type: cty.String, Required: false},
+		"source_build_timeout":            &hcldec.AttrSpec{Name: "source_build_timeout", Type: cty.String, Required: false},
+		"private_networking":              &hcldec.AttrSpec{Name: "private_networking", Type: cty.Bool, Required: false},
+		"monitoring":                      &hcldec.AttrSpec{Name: "monitoring", Type: cty.Bool, Required: false},
+		"droplet_agent":                   &hcldec.AttrSpec{Name: "droplet_agent", Type: cty.Bool, Required: false},
+		"wait_for_droplet_agent":          &hcldec.AttrSpec{Name: "wait_for_droplet_agent", Type: cty.Bool, Required: false},
+		"ipv6":                            &hcldec.AttrSpec{Name: "ipv6", Type: cty.Bool, Required: false},
+		"temporary_bastion":               &hcldec.AttrSpec{Name: "temporary_bastion", Type: cty.Bool, Required: false},
+		"temporary_bastion_size":          &hcldec.AttrSpec{Name: "temporary_bastion_size", Type: cty.String, Required: false},
+		"temporary_bastion_image":         &hcldec.AttrSpec{Name: "temporary_bastion_image", Type: cty.String, Required: false},
+		"snapshot_name":                   &hcldec.AttrSpec{Name: "snapshot_name", Type: cty.String, Required: false},
+		"snapshot_version_prefix":         &hcldec.AttrSpec{Name: "snapshot_version_prefix", Type: cty.String, Required: false},
+		"snapshot_regions":                &hcldec.AttrSpec{Name: "snapshot_regions", Type: cty.List(cty.String), Required: false},
+		"state_timeout":                   &hcldec.AttrSpec{Name: "state_timeout", Type: cty.String, Required: false},
+		"active_timeout":                  &hcldec.AttrSpec{Name: "active_timeout", Type: cty.String, Required: false},
+		"snapshot_timeout":                &hcldec.AttrSpec{Name: "snapshot_timeout", Type: cty.String, Required: false},
+		"max_build_duration":              &hcldec.AttrSpec{Name: "max_build_duration", Type: cty.String, Required: false},
+		"cleanup_snapshot_on_error":       &hcldec.AttrSpec{Name: "cleanup_snapshot_on_error", Type: cty.Bool, Required: false},
+		"snapshot_allow_failed_transfers": &hcldec.AttrSpec{Name: "snapshot_allow_failed_transfers", Type: cty.Bool, Required: false},
+		"snapshot_volumes":                &hcldec.AttrSpec{Name: "snapshot_volumes", Type: cty.Bool, Required: false},
+		"droplet_name":                    &hcldec.AttrSpec{Name: "droplet_name", Type: cty.String, Required: false},
+		"user_data":                       &hcldec.AttrSpec{Name: "user_data", Type: cty.String, Required: false},
+		"user_data_file":                  &hcldec.AttrSpec{Name: "user_data_file", Type: cty.String, Required: false},
+		"user_data_checksum":              &hcldec.AttrSpec{Name: "user_data_checksum", Type: cty.String, Required: false},
+		"user_data_command":               &hcldec.AttrSpec{Name: "user_data_command", Type: cty.String, Required: false},
+		"user_data_vars":                  &hcldec.AttrSpec{Name: "user_data_vars", Type: cty.Map(cty.String), Required: false},
+		"user_data_sensitive_vars":        &hcldec.AttrSpec{Name: "user_data_sensitive_vars", Type: cty.List(cty.String), Required: false},
+		"tags":                            &hcldec.AttrSpec{Name: "tags", Type: cty.List(cty.String), Required: false},
+		"snapshot_tags":                   &hcldec.AttrSpec{Name: "snapshot_tags", Type: cty.List(cty.String), Required: false},
+		"snapshot_description":            &hcldec.AttrSpec{Name: "snapshot_description", Type: cty.String, Required: false},
+		"required_tags":                   &hcldec.AttrSpec{Name: "required_tags", Type: cty.List(cty.String), Required: false},
+		"auto_tag_ci":                     &hcldec.AttrSpec{Name: "auto_tag_ci", Type: cty.Bool, Required: false},
+		"vpc_uuid":                        &hcldec.AttrSpec{Name: "vpc_uuid", Type: cty.String, Required: false},
+		"reserved_ip":                     &hcldec.AttrSpec{Name: "reserved_ip", Type: cty.String, Required: false},
+		"connect_with_private_ip":         &hcldec.AttrSpec{Name: "connect_with_private_ip", Type: cty.Bool, Required: false},
+		"ssh_key_id":                      &hcldec.AttrSpec{Name: "ssh_key_id", Type: cty.Number, Required: false},
+		"ssh_key_ids":                     &hcldec.AttrSpec{Name: "ssh_key_ids", Type: cty.List(cty.Number), Required: false},
+		"ssh_key_names":                   &hcldec.AttrSpec{Name: "ssh_key_names", Type: cty.List(cty.String), Required: false},
+		"shared_temporary_key":            &hcldec.AttrSpec{Name: "shared_temporary_key", Type: cty.Bool, Required: false},
+		"skip_key_cleanup":                &hcldec.AttrSpec{Name: "skip_key_cleanup", Type: cty.Bool, Required: false},
+		"max_hourly_price":                &hcldec.AttrSpec{Name: "max_hourly_price", Type: cty.Number, Required: false},
+		"max_estimated_cost":              &hcldec.AttrSpec{Name: "max_estimated_cost", Type: cty.Number, Required: false},
+		"budget_action":                   &hcldec.AttrSpec{Name: "budget_action", Type: cty.String, Required: false},
+		"validate_only":                   &hcldec.AttrSpec{Name: "validate_only", Type: cty.Bool, Required: false},
+		"pin_ssh_host_key":                &hcldec.AttrSpec{Name: "pin_ssh_host_key", Type: cty.Bool, Required: false},
+		"skip_winrm_setup":                &hcldec.AttrSpec{Name: "skip_winrm_setup", Type: cty.Bool, Required: false},
+		"verify_commands":                 &hcldec.AttrSpec{Name: "verify_commands", Type: cty.List(cty.String), Required: false},
+		"verify_size":                     &hcldec.AttrSpec{Name: "verify_size", Type: cty.String, Required: false},
+		"update_packages":                 &hcldec.AttrSpec{Name: "update_packages", Type: cty.Bool, Required: false},
+		"reboot_before_snapshot":          &hcldec.AttrSpec{Name: "reboot_before_snapshot", Type: cty.Bool, Required: false},
+		"reboot_timeout":                  &hcldec.AttrSpec{Name: "reboot_timeout", Type: cty.String, Required: false},
+		"intermediate_snapshots":          &hcldec.AttrSpec{Name: "intermediate_snapshots", Type: cty.Bool, Required: false},
+		"freeze_mount_points":             &hcldec.AttrSpec{Name: "freeze_mount_points", Type: cty.List(cty.String), Required: false},
+		"generalize":                      &hcldec.AttrSpec{Name: "generalize", Type: cty.Bool, Required: false},
+		"trim_disk":                       &hcldec.AttrSpec{Name: "trim_disk", Type: cty.Bool, Required: false},
+		"filesystem_digest":               &hcldec.AttrSpec{Name: "filesystem_digest", Type: cty.Bool, Required: false},
+		"skip_unchanged":                  &hcldec.AttrSpec{Name: "skip_unchanged", Type: cty.Bool, Required: false},
+		"input_files":                     &hcldec.AttrSpec{Name: "input_files", Type: cty.List(cty.String), Required: false},
+		"package_inventory":               &hcldec.AttrSpec{Name: "package_inventory", Type: cty.Bool, Required: false},
+		"package_inventory_file":          &hcldec.AttrSpec{Name: "package_inventory_file", Type: cty.String, Required: false},
+		"snapshot_diff_file":              &hcldec.AttrSpec{Name: "snapshot_diff_file", Type: cty.String, Required: false},
+		"snapshot_diff_image":             &hcldec.AttrSpec{Name: "snapshot_diff_image", Type: cty.String, Required: false},
+		"hooks":                           &hcldec.BlockSpec{TypeName: "hooks", Nested: hcldec.ObjectSpec((*FlatHooks)(nil).HCL2Spec())},
+		"webhooks":                        &hcldec.AttrSpec{Name: "webhooks", Type: cty.List(cty.String), Required: false},
+		"otlp_endpoint":                   &hcldec.AttrSpec{Name: "otlp_endpoint", Type: cty.String, Required: false},
+		"otlp_headers":                    &hcldec.AttrSpec{Name: "otlp_headers", Type: cty.Map(cty.String), Required: false},
+		"volume":                          &hcldec.BlockListSpec{TypeName: "volume", Nested: hcldec.ObjectSpec((*FlatVolume)(nil).HCL2Spec())},
+		"audit_log":                       &hcldec.AttrSpec{Name: "audit_log", Type: cty.String, Required: false},
+		"cloud_init_log_dir":              &hcldec.AttrSpec{Name: "cloud_init_log_dir", Type: cty.String, Required: false},
+		"debug_bundle_dir":                &hcldec.AttrSpec{Name: "debug_bundle_dir", Type: cty.String, Required: false},
+		"disable_status_page":             &hcldec.AttrSpec{Name: "disable_status_page", Type: cty.Bool, Required: false},
+		"progress_file":                   &hcldec.AttrSpec{Name: "progress_file", Type: cty.String, Required: false},
+		"summary_file":                    &hcldec.AttrSpec{Name: "summary_file", Type: cty.String, Required: false},
+		"checkpoint_file":                 &hcldec.AttrSpec{Name: "checkpoint_file", Type: cty.String, Required: false},
+		"log_level":                       &hcldec.AttrSpec{Name: "log_level", Type: cty.String, Required: false},
+		"defaults_file":                   &hcldec.AttrSpec{Name: "defaults_file", Type: cty.String, Required: false},
+	}
+	return s
+}
+
+// FlatConnectionConfig is an auto-generated flat version of ConnectionConfig.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatConnectionConfig struct {
+	PrivateIP     *bool    `mapstructure:"private_ip" required:"false" cty:"private_ip" hcl:"private_ip"`
+	SSHKeyID      *int     `mapstructure:"ssh_key_id" required:"false" cty:"ssh_key_id" hcl:"ssh_key_id"`
+	SSHKeyIDs     []int    `mapstructure:"ssh_key_ids" required:"false" cty:"ssh_key_ids" hcl:"ssh_key_ids"`
+	SSHKeyNames   []string `mapstructure:"ssh_key_names" required:"false" cty:"ssh_key_names" hcl:"ssh_key_names"`
+	PinSSHHostKey *bool    `mapstructure:"pin_ssh_host_key" required:"false" cty:"pin_ssh_host_key" hcl:"pin_ssh_host_key"`
+}
+
+// FlatMapstructure returns a new FlatConnectionConfig.
+// FlatConnectionConfig is an auto-generated flat version of ConnectionConfig.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*ConnectionConfig) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatConnectionConfig)
+}
+
+// HCL2Spec returns the hcl spec of a ConnectionConfig.
+// This spec is used by HCL to read the fields of ConnectionConfig.
+// The decoded values from this spec will then be applied to a FlatConnectionConfig.
+func (*FlatConnectionConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"private_ip":       &hcldec.AttrSpec{Name: "private_ip", Type: cty.Bool, Required: false},
+		"ssh_key_id":       &hcldec.AttrSpec{Name: "ssh_key_id", Type: cty.Number, Required: false},
+		"ssh_key_ids":      &hcldec.AttrSpec{Name: "ssh_key_ids", Type: cty.List(cty.Number), Required: false},
+		"ssh_key_names":    &hcldec.AttrSpec{Name: "ssh_key_names", Type: cty.List(cty.String), Required: false},
+		"pin_ssh_host_key": &hcldec.AttrSpec{Name: "pin_ssh_host_key", Type: cty.Bool, Required: false},
+	}
+	return s
+}
+
+// FlatDropletConfig is an auto-generated flat version of DropletConfig.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatDropletConfig struct {
+	Region                *string           `mapstructure:"region" required:"false" cty:"region" hcl:"region"`
+	RegionStrategy        *string           `mapstructure:"region_strategy" required:"false" cty:"region_strategy" hcl:"region_strategy"`
+	RegionPreference      []string          `mapstructure:"region_preference" required:"false" cty:"region_preference" hcl:"region_preference"`
+	Size                  *string           `mapstructure:"size" required:"false" cty:"size" hcl:"size"`
+	MinVCPUs              *int              `mapstructure:"min_vcpus" required:"false" cty:"min_vcpus" hcl:"min_vcpus"`
+	MinMemoryGB           *int              `mapstructure:"min_memory_gb" required:"false" cty:"min_memory_gb" hcl:"min_memory_gb"`
+	SizeClass             *string           `mapstructure:"size_class" required:"false" cty:"size_class" hcl:"size_class"`
+	Image                 *string           `mapstructure:"image" required:"false" cty:"image" hcl:"image"`
+	Distribution          *string           `mapstructure:"distribution" required:"false" cty:"distribution" hcl:"distribution"`
+	Version               *string           `mapstructure:"version" required:"false" cty:"version" hcl:"version"`
+	Name                  *string           `mapstructure:"name" required:"false" cty:"name" hcl:"name"`
+	PrivateNetworking     *bool             `mapstructure:"private_networking" required:"false" cty:"private_networking" hcl:"private_networking"`
+	Monitoring            *bool             `mapstructure:"monitoring" required:"false" cty:"monitoring" hcl:"monitoring"`
+	IPv6                  *bool             `mapstructure:"ipv6" required:"false" cty:"ipv6" hcl:"ipv6"`
+	VPCUUID               *string           `mapstructure:"vpc_uuid" required:"false" cty:"vpc_uuid" hcl:"vpc_uuid"`
+	ReservedIP            *string           `mapstructure:"reserved_ip" required:"false" cty:"reserved_ip" hcl:"reserved_ip"`
+	UserData              *string           `mapstructure:"user_data" required:"false" cty:"user_data" hcl:"user_data"`
+	UserDataFile          *string           `mapstructure:"user_data_file" required:"false" cty:"user_data_file" hcl:"user_data_file"`
+	UserDataChecksum      *string           `mapstructure:"user_data_checksum" required:"false" cty:"user_data_checksum" hcl:"user_data_checksum"`
+	UserDataCommand       *string           `mapstructure:"user_data_command" required:"false" cty:"user_data_command" hcl:"user_data_command"`
+	UserDataVars          map[string]string `mapstructure:"user_data_vars" required:"false" cty:"user_data_vars" hcl:"user_data_vars"`
+	UserDataSensitiveVars []string          `mapstructure:"user_data_sensitive_vars" required:"false" cty:"user_data_sensitive_vars" hcl:"user_data_sensitive_vars"`
+	Tags                  []string          `mapstructure:"tags" required:"false" cty:"tags" hcl:"tags"`
+	StateTimeout          *string           `mapstructure:"state_timeout" required:"false" cty:"state_timeout" hcl:"state_timeout"`
+	ActiveTimeout         *string           `mapstructure:"active_timeout" required:"false" cty:"active_timeout" hcl:"active_timeout"`
+}
+
+// FlatMapstructure returns a new FlatDropletConfig.
+// FlatDropletConfig is an auto-generated flat version of DropletConfig.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*DropletConfig) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatDropletConfig)
+}
+
+// HCL2Spec returns the hcl spec of a DropletConfig.
+// This spec is used by HCL to read the fields of DropletConfig.
+// The decoded values from this spec will then be applied to a FlatDropletConfig.
+func (*FlatDropletConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"region":                   &hcldec.AttrSpec{Name: "region", Type: cty.String, Required: false},
+		"region_strategy":          &hcldec.AttrSpec{Name: "region_strategy", Type: cty.String, Required: false},
+		"region_preference":        &hcldec.AttrSpec{Name: "region_preference", Type: cty.List(cty.String), Required: false},
+		"size":                     &hcldec.AttrSpec{Name: "size", Type: cty.String, Required: false},
+		"min_vcpus":                &hcldec.AttrSpec{Name: "min_vcpus", Type: cty.Number, Required: false},
+		"min_memory_gb":            &hcldec.AttrSpec{Name: "min_memory_gb", Type: cty.Number, Required: false},
+		"size_class":               &hcldec.AttrSpec{Name: "size_class", Type: cty.String, Required: false},
+		"image":                    &hcldec.AttrSpec{Name: "image", Type: cty.String, Required: false},
+		"distribution":             &hcldec.AttrSpec{Name: "distribution", Type: cty.String, Required: false},
+		"version":                  &hcldec.AttrSpec{Name: "version", Type: cty.String, Required: false},
+		"name":                     &hcldec.AttrSpec{Name: "name", Type: cty.String, Required: false},
+		"private_networking":       &hcldec.AttrSpec{Name: "private_networking", Type: cty.Bool, Required: false},
+		"monitoring":               &hcldec.AttrSpec{Name: "monitoring", Type: cty.Bool, Required: false},
+		"ipv6":                     &hcldec.AttrSpec{Name: "ipv6", Type: cty.Bool, Required: false},
+		"vpc_uuid":                 &hcldec.AttrSpec{Name: "vpc_uuid", Type: cty.String, Required: false},
+		"reserved_ip":              &hcldec.AttrSpec{Name: "reserved_ip", Type: cty.String, Required: false},
+		"user_data":                &hcldec.AttrSpec{Name: "user_data", Type: cty.String, Required: false},
+		"user_data_file":           &hcldec.AttrSpec{Name: "user_data_file", Type: cty.String, Required: false},
+		"user_data_checksum":       &hcldec.AttrSpec{Name: "user_data_checksum", Type: cty.String, Required: false},
+		"user_data_command":        &hcldec.AttrSpec{Name: "user_data_command", Type: cty.String, Required: false},
+		"user_data_vars":           &hcldec.AttrSpec{Name: "user_data_vars", Type: cty.Map(cty.String), Required: false},
+		"user_data_sensitive_vars": &hcldec.AttrSpec{Name: "user_data_sensitive_vars", Type: cty.List(cty.String), Required: false},
+		"tags":                     &hcldec.AttrSpec{Name: "tags", Type: cty.List(cty.String), Required: false},
+		"state_timeout":            &hcldec.AttrSpec{Name: "state_timeout", Type: cty.String, Required: false},
+		"active_timeout":           &hcldec.AttrSpec{Name: "active_timeout", Type: cty.String, Required: false},
+	}
+	return s
+}
+
+// FlatHooks is an auto-generated flat version of Hooks.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatHooks struct {
+	PostCreate   []string `mapstructure:"post_create" required:"false" cty:"post_create" hcl:"post_create"`
+	PreSnapshot  []string `mapstructure:"pre_snapshot" required:"false" cty:"pre_snapshot" hcl:"pre_snapshot"`
+	PostSnapshot []string `mapstructure:"post_snapshot" required:"false" cty:"post_snapshot" hcl:"post_snapshot"`
+}
+
+// FlatMapstructure returns a new FlatHooks.
+// FlatHooks is an auto-generated flat version of Hooks.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*Hooks) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatHooks)
+}
+
+// HCL2Spec returns the hcl spec of a Hooks.
+// This spec is used by HCL to read the fields of Hooks.
+// The decoded values from this spec will then be applied to a FlatHooks.
+func (*FlatHooks) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"post_create":   &hcldec.AttrSpec{Name: "post_create", Type: cty.List(cty.String), Required: false},
+		"pre_snapshot":  &hcldec.AttrSpec{Name: "pre_snapshot", Type: cty.List(cty.String), Required: false},
+		"post_snapshot": &hcldec.AttrSpec{Name: "post_snapshot", Type: cty.List(cty.String), Required: false},
+	}
+	return s
+}
+
+// FlatImageFilter is an auto-generated flat version of ImageFilter.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatImageFilter struct {
+	Name       *string `mapstructure:"name" required:"false" cty:"name" hcl:"name"`
+	Type       *string `mapstructure:"type" required:"false" cty:"type" hcl:"type"`
+	Tag        *string `mapstructure:"tag" required:"false" cty:"tag" hcl:"tag"`
+	MostRecent *bool   `mapstructure:"most_recent" required:"false" cty:"most_recent" hcl:"most_recent"`
+}
+
+// FlatMapstructure returns a new FlatImageFilter.
+// FlatImageFilter is an auto-generated flat version of ImageFilter.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*ImageFilter) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatImageFilter)
+}
+
+// HCL2Spec returns the hcl spec of a ImageFilter.
+// This spec is used by HCL to read the fields of ImageFilter.
+// The decoded values from this spec will then be applied to a FlatImageFilter.
+func (*FlatImageFilter) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"name":        &hcldec.AttrSpec{Name: "name", Type: cty.String, Required: false},
+		"type":        &hcldec.AttrSpec{Name: "type", Type: cty.String, Required: false},
+		"tag":         &hcldec.AttrSpec{Name: "tag", Type: cty.String, Required: false},
+		"most_recent": &hcldec.AttrSpec{Name: "most_recent", Type: cty.Bool, Required: false},
+	}
+	return s
+}
+
+// FlatSnapshotConfig is an auto-generated flat version of SnapshotConfig.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatSnapshotConfig struct {
+	Name                 *string  `mapstructure:"name" required:"false" cty:"name" hcl:"name"`
+	Regions              []string `mapstructure:"regions" required:"false" cty:"regions" hcl:"regions"`
+	Tags                 []string `mapstructure:"tags" required:"false" cty:"tags" hcl:"tags"`
+	VersionPrefix        *string  `mapstructure:"version_prefix" required:"false" cty:"version_prefix" hcl:"version_prefix"`
+	Timeout              *string  `mapstructure:"timeout" required:"false" cty:"timeout" hcl:"timeout"`
+	CleanupOnError       *bool    `mapstructure:"cleanup_on_error" required:"false" cty:"cleanup_on_error" hcl:"cleanup_on_error"`
+	AllowFailedTransfers *bool    `mapstructure:"allow_failed_transfers" required:"false" cty:"allow_failed_transfers" hcl:"allow_failed_transfers"`
+	Volumes              *bool    `mapstructure:"volumes" required:"false" cty:"volumes" hcl:"volumes"`
+}
+
+// FlatMapstructure returns a new FlatSnapshotConfig.
+// FlatSnapshotConfig is an auto-generated flat version of SnapshotConfig.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*SnapshotConfig) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatSnapshotConfig)
+}
+
+// HCL2Spec returns the hcl spec of a SnapshotConfig.
+// This spec is used by HCL to read the fields of SnapshotConfig.
+// The decoded values from this spec will then be applied to a FlatSnapshotConfig.
+func (*FlatSnapshotConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"name":                   &hcldec.AttrSpec{Name: "name", Type: cty.String, Required: false},
+		"regions":                &hcldec.AttrSpec{Name: "regions", Type: cty.List(cty.String), Required: false},
+		"tags":                   &hcldec.AttrSpec{Name: "tags", Type: cty.List(cty.String), Required: false},
+		"version_prefix":         &hcldec.AttrSpec{Name: "version_prefix", Type: cty.String, Required: false},
+		"timeout":                &hcldec.AttrSpec{Name: "timeout", Type: cty.String, Required: false},
+		"cleanup_on_error":       &hcldec.AttrSpec{Name: "cleanup_on_error", Type: cty.Bool, Required: false},
+		"allow_failed_transfers": &hcldec.AttrSpec{Name: "allow_failed_transfers", Type: cty.Bool, Required: false},
+		"volumes":                &hcldec.AttrSpec{Name: "volumes", Type: cty.Bool, Required: false},
+	}
+	return s
+}
+
+// FlatVolume is an auto-generated flat version of Volume.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatVolume struct {
+	Name           *string `mapstructure:"name" required:"false" cty:"name" hcl:"name"`
+	Size           *int64  `mapstructure:"size" required:"true" cty:"size" hcl:"size"`
+	FilesystemType *string `mapstructure:"filesystem_type" required:"false" cty:"filesystem_type" hcl:"filesystem_type"`
+	MountPath      *string `mapstructure:"mount_path" required:"false" cty:"mount_path" hcl:"mount_path"`
+}
+
+// FlatMapstructure returns a new FlatVolume.
+// FlatVolume is an auto-generated flat version of Volume.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*Volume) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatVolume)
+}
+
+// HCL2Spec returns the hcl spec of a Volume.
+// This spec is used by HCL to read the fields of Volume.
+// The decoded values from this spec will then be applied to a FlatVolume.
+func (*FlatVolume) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"name":            &hcldec.AttrSpec{Name: "name", Type: cty.String, Required: false},
+		"size":            &hcldec.AttrSpec{Name: "size", Type: cty.Number, Required: false},
+		"filesystem_type": &hcldec.AttrSpec{Name: "filesystem_type", Type: cty.String, Required: false},
+		"mount_path":      &hcldec.AttrSpec{Name: "mount_path", Type: cty.String, Required: false},
 	}
 	return s
 }
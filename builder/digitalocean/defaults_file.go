@@ -0,0 +1,79 @@
+package digitalocean
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// defaultsFilePath returns the defaults_file set by the template, if any.
+func defaultsFilePath(raws []interface{}) string {
+	path := ""
+	for _, raw := range raws {
+		switch raw := raw.(type) {
+		case map[string]interface{}:
+			if s, ok := raw["defaults_file"].(string); ok && s != "" {
+				path = s
+			}
+		case cty.Value:
+			if raw.IsNull() || !raw.IsKnown() || !raw.Type().IsObjectType() ||
+				!raw.Type().HasAttribute("defaults_file") {
+				continue
+			}
+			v := raw.GetAttr("defaults_file")
+			if !v.IsNull() && v.IsKnown() && v.Type() == cty.String {
+				path = v.AsString()
+			}
+		}
+	}
+	return path
+}
+
+// loadDefaultsFile reads the builder options in a defaults_file, a JSON
+// object or an HCL file of top level attributes, in the form config.Decode
+// takes them.
+func loadDefaultsFile(path string) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if filepath.Ext(path) == ".json" {
+		var defaults map[string]interface{}
+		if err := json.Unmarshal(data, &defaults); err != nil {
+			return nil, fmt.Errorf("invalid defaults_file %s: %s", path, err)
+		}
+		return defaults, nil
+	}
+
+	file, diags := hclparse.NewParser().ParseHCL(data, path)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("invalid defaults_file %s: %s", path, diags.Error())
+	}
+	attrs, diags := file.Body.JustAttributes()
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("invalid defaults_file %s: %s", path, diags.Error())
+	}
+	defaults := make(map[string]interface{}, len(attrs))
+	for name, attr := range attrs {
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("invalid defaults_file %s: %s", path, diags.Error())
+		}
+		b, err := ctyjson.SimpleJSONValue{Value: value}.MarshalJSON()
+		if err != nil {
+			return nil, fmt.Errorf("invalid defaults_file %s: %s: %s", path, name, err)
+		}
+		var v interface{}
+		if err := json.Unmarshal(b, &v); err != nil {
+			return nil, err
+		}
+		defaults[name] = v
+	}
+	return defaults, nil
+}
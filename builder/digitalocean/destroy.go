@@ -0,0 +1,76 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/digitalocean/godo"
+)
+
+// deleteAttempts is how many times DestroyDroplet tries to delete a droplet
+// before giving up.
+const deleteAttempts = 5
+
+// DestroyDroplet deletes a droplet and waits until it is gone. A droplet
+// can't be deleted while an action is pending on it, so this waits for it to
+// unlock first, powers it off if deleting it still fails, and retries with
+// backoff. A droplet that no longer exists is not an error.
+func DestroyDroplet(client *godo.Client, dropletId int, timeout time.Duration) error {
+	if err := waitForDropletUnlocked(client, dropletId, timeout); err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		logf(levelDebug, []interface{}{"droplet_id", dropletId}, "Destroying droplet while it may still be locked: %s", err)
+	}
+
+	var err error
+	poweredOff := false
+	for attempt := 0; attempt < deleteAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(pollInterval << uint(attempt))
+		}
+
+		var resp *godo.Response
+		resp, err = client.Droplets.Delete(context.TODO(), dropletId)
+		if err == nil || (resp != nil && resp.StatusCode == http.StatusNotFound) {
+			return waitForDropletDeleted(client, dropletId, timeout)
+		}
+		logf(levelDebug, []interface{}{"droplet_id", dropletId}, "Error destroying droplet (attempt: %d): %s", attempt+1, err)
+
+		if !poweredOff && resp != nil && resp.StatusCode == http.StatusUnprocessableEntity {
+			poweredOff = true
+			action, _, err := client.DropletActions.PowerOff(context.TODO(), dropletId)
+			if err != nil {
+				logf(levelDebug, []interface{}{"droplet_id", dropletId}, "Error powering off droplet: %s", err)
+				continue
+			}
+			if err := waitForActionState(godo.ActionCompleted, dropletId, action.ID, client, timeout); err != nil {
+				logf(levelDebug, []interface{}{"droplet_id", dropletId}, "Error powering off droplet: %s", err)
+			}
+		}
+	}
+
+	return err
+}
+
+// waitForDropletDeleted blocks until fetching the droplet returns a 404.
+func waitForDropletDeleted(client *godo.Client, dropletId int, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		_, _, err := client.Droplets.Get(context.TODO(), dropletId)
+		if isNotFound(err) {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("Timeout while waiting for droplet %d to be deleted", dropletId)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+func isNotFound(err error) bool {
+	errResp, ok := err.(*godo.ErrorResponse)
+	return ok && errResp.Response != nil && errResp.Response.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,54 @@
+package digitalocean
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-digitalocean/internal/simulator"
+)
+
+func TestDestroyDroplet(t *testing.T) {
+	sim, client := testSimulator(t)
+	sim.ActionPolls = 3
+	droplet := sim.AddDroplet(godo.Droplet{Name: "packer-test", Region: &godo.Region{Slug: "nyc3"}})
+
+	// Locked by a pending action
+	if _, _, err := client.DropletActions.PowerOff(context.TODO(), droplet.ID); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// And the first delete fails anyway
+	sim.Inject(simulator.Fault{Method: http.MethodDelete, Path: "/v2/droplets", Status: http.StatusInternalServerError,
+		ID: "server_error", Message: "Server was unable to give you a response.", Times: 1})
+
+	if err := DestroyDroplet(client, droplet.ID, time.Second); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, ok := sim.Droplet(droplet.ID); ok {
+		t.Fatal("droplet still exists")
+	}
+}
+
+func TestDestroyDroplet_Stuck(t *testing.T) {
+	sim, client := testSimulator(t)
+	sim.StickActions("shutdown")
+	droplet := sim.AddDroplet(godo.Droplet{Name: "packer-test", Region: &godo.Region{Slug: "nyc3"}})
+
+	if _, _, err := client.DropletActions.Shutdown(context.TODO(), droplet.ID); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := DestroyDroplet(client, droplet.ID, 20*time.Millisecond); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestDestroyDroplet_AlreadyGone(t *testing.T) {
+	_, client := testSimulator(t)
+
+	if err := DestroyDroplet(client, 42, time.Second); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
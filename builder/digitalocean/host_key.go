@@ -0,0 +1,121 @@
+package digitalocean
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// generateHostKey creates an SSH host key for the droplet. The private half
+// is passed to cloud-init through user data so that the communicator knows
+// which key to expect before it ever connects.
+func generateHostKey() (privatePEM string, public gossh.PublicKey, err error) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", nil, err
+	}
+	der, err := x509.MarshalECPrivateKey(priv)
+	if err != nil {
+		return "", nil, err
+	}
+	public, err = gossh.NewPublicKey(&priv.PublicKey)
+	if err != nil {
+		return "", nil, err
+	}
+
+	block := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+	return string(block), public, nil
+}
+
+// hostKeyUserData returns user data that installs the given host key and
+// still runs the user's own user data, if any, by combining both into a
+// multipart message.
+func hostKeyUserData(userData string, privatePEM string, public gossh.PublicKey) (string, error) {
+	return mergeUserData(hostKeyCloudConfig(privatePEM, public), userData)
+}
+
+// hostKeyCloudConfig returns the cloud-config installing the given host key.
+func hostKeyCloudConfig(privatePEM string, public gossh.PublicKey) string {
+	var cloudConfig strings.Builder
+	cloudConfig.WriteString("#cloud-config\nssh_deletekeys: true\nssh_keys:\n  ecdsa_private: |\n")
+	for _, line := range strings.Split(strings.TrimSpace(privatePEM), "\n") {
+		cloudConfig.WriteString("    " + line + "\n")
+	}
+	cloudConfig.WriteString("  ecdsa_public: " + strings.TrimSpace(string(gossh.MarshalAuthorizedKey(public))) + "\n")
+	return cloudConfig.String()
+}
+
+// mergeUserData combines the non-empty user data parts into a multipart
+// message, unless there is only one, so that cloud-init runs each of them.
+func mergeUserData(parts ...string) (string, error) {
+	var bodies []string
+	for _, part := range parts {
+		if part != "" {
+			bodies = append(bodies, part)
+		}
+	}
+	switch len(bodies) {
+	case 0:
+		return "", nil
+	case 1:
+		return bodies[0], nil
+	}
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, body := range bodies {
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Type", userDataContentType(body)+`; charset="utf-8"`)
+		part, err := w.CreatePart(h)
+		if err != nil {
+			return "", err
+		}
+		if _, err := part.Write([]byte(body)); err != nil {
+			return "", err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Content-Type: multipart/mixed; boundary=%q\nMIME-Version: 1.0\n\n%s",
+		w.Boundary(), buf.String()), nil
+}
+
+func userDataContentType(userData string) string {
+	switch {
+	case strings.HasPrefix(userData, "#!"):
+		return "text/x-shellscript"
+	case strings.HasPrefix(userData, "#cloud-config"):
+		return "text/cloud-config"
+	default:
+		// cloud-init works out the type from the content itself
+		return "text/plain"
+	}
+}
+
+// pinnedSSHConfigFunc wraps an SSH config func so that the droplet's host key
+// must match the one generated for it, when there is one.
+func pinnedSSHConfigFunc(f func(multistep.StateBag) (*gossh.ClientConfig, error)) func(multistep.StateBag) (*gossh.ClientConfig, error) {
+	return func(state multistep.StateBag) (*gossh.ClientConfig, error) {
+		conf, err := f(state)
+		if err != nil {
+			return nil, err
+		}
+		if key, ok := state.GetOk("ssh_host_key"); ok {
+			conf.HostKeyCallback = gossh.FixedHostKey(key.(gossh.PublicKey))
+			conf.HostKeyAlgorithms = []string{key.(gossh.PublicKey).Type()}
+		}
+		return conf, nil
+	}
+}
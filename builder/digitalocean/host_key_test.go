@@ -0,0 +1,108 @@
+package digitalocean
+
+import (
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+func TestHostKeyUserData(t *testing.T) {
+	privatePEM, public, err := generateHostKey()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := gossh.ParsePrivateKey([]byte(privatePEM)); err != nil {
+		t.Fatalf("private key isn't usable by sshd: %s", err)
+	}
+	authorizedKey := strings.TrimSpace(string(gossh.MarshalAuthorizedKey(public)))
+
+	// Without user data, the cloud-config is used as is
+	userData, err := hostKeyUserData("", privatePEM, public)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !strings.HasPrefix(userData, "#cloud-config\n") || !strings.Contains(userData, authorizedKey) {
+		t.Fatalf("bad user data: %s", userData)
+	}
+
+	// With user data, both are combined
+	userData, err = hostKeyUserData("#!/bin/sh\necho hello\n", privatePEM, public)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(userData))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("bad content type %q: %v", mediaType, err)
+	}
+
+	var types []string
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := r.NextPart()
+		if err != nil {
+			break
+		}
+		body, _ := ioutil.ReadAll(part)
+		types = append(types, part.Header.Get("Content-Type"))
+		if strings.HasPrefix(part.Header.Get("Content-Type"), "text/x-shellscript") && string(body) != "#!/bin/sh\necho hello\n" {
+			t.Fatalf("user data was altered: %q", body)
+		}
+	}
+	if len(types) != 2 {
+		t.Fatalf("expected 2 parts, got %#v", types)
+	}
+}
+
+func TestPinnedSSHConfigFunc(t *testing.T) {
+	_, public, err := generateHostKey()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	_, other, err := generateHostKey()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	f := pinnedSSHConfigFunc(func(multistep.StateBag) (*gossh.ClientConfig, error) {
+		return &gossh.ClientConfig{HostKeyCallback: gossh.InsecureIgnoreHostKey()}, nil
+	})
+
+	state := new(multistep.BasicStateBag)
+	state.Put("ssh_host_key", public)
+	conf, err := f(state)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := conf.HostKeyCallback("droplet:22", nil, public); err != nil {
+		t.Fatalf("expected pinned key to be accepted: %s", err)
+	}
+	if err := conf.HostKeyCallback("droplet:22", nil, other); err == nil {
+		t.Fatal("expected other key to be rejected")
+	}
+}
+
+func TestMergeUserData(t *testing.T) {
+	if userData, err := mergeUserData("", "#!/bin/sh\necho hello\n", ""); err != nil || userData != "#!/bin/sh\necho hello\n" {
+		t.Fatalf("expected a single part to be left as is, got %q: %v", userData, err)
+	}
+
+	userData, err := mergeUserData("#!/bin/sh\nmount\n", "#cloud-config\npackages: [git]\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, contentType := range []string{"text/x-shellscript", "text/cloud-config"} {
+		if !strings.Contains(userData, "Content-Type: "+contentType) {
+			t.Errorf("expected a %s part:\n%s", contentType, userData)
+		}
+	}
+}
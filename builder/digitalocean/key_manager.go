@@ -0,0 +1,184 @@
+package digitalocean
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/digitalocean/godo"
+)
+
+var (
+	keyLockTimeout = time.Minute
+	keyLockPoll    = 100 * time.Millisecond
+)
+
+// sharedKey is the temporary SSH key shared by the builds of a run, along
+// with the number of builds using it.
+type sharedKey struct {
+	ID         int    `json:"id"`
+	PrivateKey []byte `json:"private_key"`
+	PublicKey  []byte `json:"public_key"`
+	Users      int    `json:"users"`
+}
+
+// keyManager hands out the temporary SSH key of a run, creating it for
+// the first build and deleting it once the last build is done with it.
+// Packer runs every build of a template in a plugin process of its own, so
+// the key is kept in a directory named after the run, guarded by a lock
+// directory. Builds of the run using other accounts get a key, and a
+// directory, of their own.
+type keyManager struct {
+	dir string
+}
+
+// runKeyManager returns the key manager of the Packer run for the account
+// of the given API URL and token. Outside of a run, there are no builds to
+// share the key with.
+func runKeyManager(apiURL, apiToken string) (*keyManager, error) {
+	run := os.Getenv("PACKER_RUN_UUID")
+	if run == "" {
+		return nil, errors.New("PACKER_RUN_UUID isn't set, shared_temporary_key only works in a Packer run")
+	}
+	// The token is hashed, rather than written to the name of a directory
+	account := sha256.Sum256([]byte(apiURL + "\n" + apiToken))
+	return &keyManager{dir: filepath.Join(runDir(), "keys", hex.EncodeToString(account[:8]))}, nil
+}
+
+// acquire returns the shared key, creating it on the account from the given
+// key pair when no build of the run did yet.
+func (m *keyManager) acquire(client *godo.Client, name string, private, public []byte) (*sharedKey, error) {
+	if err := m.lock(); err != nil {
+		return nil, err
+	}
+	defer m.unlock()
+
+	key, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		created, _, err := client.Keys.Create(context.TODO(), &godo.KeyCreateRequest{
+			Name:      name,
+			PublicKey: string(public),
+		})
+		if err != nil {
+			return nil, err
+		}
+		key = &sharedKey{ID: created.ID, PrivateKey: private, PublicKey: public}
+	}
+	key.Users++
+	if err := m.save(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// release gives the shared key back, deleting it from the account when no
+// other build of the run uses it anymore.
+func (m *keyManager) release(client *godo.Client) error {
+	if err := m.lock(); err != nil {
+		return err
+	}
+
+	key, err := m.load()
+	if err != nil || key == nil {
+		m.unlock()
+		return err
+	}
+	key.Users--
+	if key.Users > 0 {
+		defer m.unlock()
+		return m.save(key)
+	}
+
+	if _, err := client.Keys.DeleteByID(context.TODO(), key.ID); err != nil && !isNotFound(err) {
+		m.unlock()
+		return err
+	}
+	// Only the key and the lock, the directory of the run is removed by
+	// the last build holding it
+	if err := os.Remove(filepath.Join(m.dir, "key.json")); err != nil && !os.IsNotExist(err) {
+		m.unlock()
+		return err
+	}
+	m.unlock()
+	return nil
+}
+
+func (m *keyManager) lock() error {
+	return lockDir(m.dir)
+}
+
+func (m *keyManager) unlock() {
+	unlockDir(m.dir)
+}
+
+// lockDir takes the lock guarding dir between the builds of a run, creating
+// dir if need be, and waits for up to keyLockTimeout while another build
+// holds it.
+func lockDir(dir string) error {
+	lock := filepath.Join(dir, "lock")
+	deadline := time.Now().Add(keyLockTimeout)
+	for {
+		// The last build of the run may have just removed dir
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+		err := os.Mkdir(lock, 0700)
+		if err == nil {
+			return nil
+		}
+		if !os.IsExist(err) {
+			return err
+		}
+		// A build that crashed holding the lock
+		if info, err := os.Stat(lock); err == nil && time.Since(info.ModTime()) > keyLockTimeout {
+			os.Remove(lock)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout waiting for the lock on %s", dir)
+		}
+		time.Sleep(keyLockPoll)
+	}
+}
+
+func unlockDir(dir string) {
+	os.Remove(filepath.Join(dir, "lock"))
+}
+
+func (m *keyManager) load() (*sharedKey, error) {
+	data, err := ioutil.ReadFile(filepath.Join(m.dir, "key.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	key := new(sharedKey)
+	if err := json.Unmarshal(data, key); err != nil {
+		return nil, fmt.Errorf("invalid shared SSH key in %s: %s", m.dir, err)
+	}
+	return key, nil
+}
+
+func (m *keyManager) save(key *sharedKey) error {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(m.dir, "key.json")
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
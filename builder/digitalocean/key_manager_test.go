@@ -0,0 +1,137 @@
+package digitalocean
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testKeyManager(t *testing.T) *keyManager {
+	manager, err := runKeyManager("https://api.digitalocean.com", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return manager
+}
+
+func TestKeyManager(t *testing.T) {
+	sim, client := testSimulator(t)
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv("PACKER_RUN_UUID", "5b6f7c3e-0d6c-4d3c-9b5e-1f2a3b4c5d6e")
+
+	first, err := testKeyManager(t).acquire(client, "packer-a", []byte("private-a"), []byte("ssh-ed25519 AAAAa"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := testKeyManager(t).acquire(client, "packer-b", []byte("private-b"), []byte("ssh-ed25519 AAAAb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.ID != first.ID || !bytes.Equal(second.PrivateKey, []byte("private-a")) {
+		t.Fatalf("expected the key of the first build, got %#v", second)
+	}
+	if keys := sim.Keys(); len(keys) != 1 {
+		t.Fatalf("expected a single key, got %#v", keys)
+	}
+
+	if err := testKeyManager(t).release(client); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sim.Key(first.ID); !ok {
+		t.Fatal("the key was deleted while a build still uses it")
+	}
+
+	manager := testKeyManager(t)
+	if err := manager.release(client); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sim.Key(first.ID); ok {
+		t.Fatal("expected the key to be deleted by the last build")
+	}
+	if _, err := os.Stat(filepath.Join(manager.dir, "key.json")); !os.IsNotExist(err) {
+		t.Fatalf("expected the key to be removed from %s, got %v", manager.dir, err)
+	}
+}
+
+func TestKeyManager_BuildResults(t *testing.T) {
+	_, client := testSimulator(t)
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv("PACKER_RUN_UUID", "9d2e4f61-3a7b-4c8d-b1e2-f3a4b5c6d7e8")
+
+	if _, err := testKeyManager(t).acquire(client, "packer-a", []byte("private-a"), []byte("ssh-ed25519 AAAAa")); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeBuildResult("digitalocean.base", &buildResult{SnapshotID: 42}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A build waiting for digitalocean.base still finds its result once
+	// the last build using the key released it
+	if err := testKeyManager(t).release(client); err != nil {
+		t.Fatal(err)
+	}
+	result, err := readBuildResult("digitalocean.base")
+	if err != nil || result == nil || result.SnapshotID != 42 {
+		t.Fatalf("expected the build result to be kept, got %#v, %v", result, err)
+	}
+}
+
+func TestKeyManager_StaleLock(t *testing.T) {
+	_, client := testSimulator(t)
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv("PACKER_RUN_UUID", "0f0c8a52-8d0e-4a57-a3c4-6c1f24f1b0a1")
+
+	// Left behind by a build that crashed holding it
+	manager := testKeyManager(t)
+	lock := filepath.Join(manager.dir, "lock")
+	if err := os.MkdirAll(lock, 0700); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * keyLockTimeout)
+	if err := os.Chtimes(lock, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := manager.acquire(client, "packer-a", []byte("private-a"), []byte("ssh-ed25519 AAAAa")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKeyManager_Accounts(t *testing.T) {
+	sim, client := testSimulator(t)
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv("PACKER_RUN_UUID", "2a3b4c5d-6e7f-4a8b-9c0d-1e2f3a4b5c6d")
+
+	// Builds of the same run using two accounts
+	var ids []int
+	var managers []*keyManager
+	for _, token := range []string{"token-a", "token-b"} {
+		manager, err := runKeyManager(sim.URL(), token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := manager.acquire(client, "packer-"+token, []byte("private"), []byte("ssh-ed25519 AAAA"+token))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, key.ID)
+		managers = append(managers, manager)
+	}
+	if ids[0] == ids[1] {
+		t.Fatalf("expected a key for each account, got %v", ids)
+	}
+
+	if err := managers[1].release(client); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sim.Key(ids[0]); !ok {
+		t.Fatal("the key of the other account was deleted")
+	}
+
+	t.Setenv("PACKER_RUN_UUID", "")
+	if _, err := runKeyManager(sim.URL(), "token-a"); err == nil {
+		t.Fatal("expected an error outside of a run")
+	}
+}
@@ -0,0 +1,126 @@
+package digitalocean
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// logLevels are the accepted log_level values, least severe first.
+var logLevels = []string{"debug", "info", "warn", "error"}
+
+const (
+	levelDebug = iota
+	levelInfo
+	levelWarn
+	levelError
+)
+
+func parseLogLevel(level string) (int, bool) {
+	for i, l := range logLevels {
+		if l == level {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// logf writes a message to the Packer log, prefixed with its level and with
+// key=value fields, given as key and value pairs, so that messages of parallel
+// builds can be told apart and filtered. Secrets in the message are redacted.
+func logf(level int, fields []interface{}, format string, args ...interface{}) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s]", strings.ToUpper(logLevels[level]))
+	for i := 0; i+1 < len(fields); i += 2 {
+		value := fmt.Sprint(fields[i+1])
+		if strings.ContainsAny(value, " \"=") {
+			value = fmt.Sprintf("%q", value)
+		}
+		fmt.Fprintf(&b, " %s=%s", fields[i], value)
+	}
+	fmt.Fprintf(&b, " %s", fmt.Sprintf(format, args...))
+	log.Print(redact(b.String()))
+}
+
+// stepUi is the packersdk.Ui used by steps. Every message is also written
+// to the Packer log with the build, step and droplet it relates to, and
+// only messages at or above log_level are shown.
+type stepUi struct {
+	packersdk.Ui
+
+	state  multistep.StateBag
+	level  int
+	fields []interface{}
+}
+
+// newStepUi returns the Ui of the given step.
+func newStepUi(state multistep.StateBag, step string) *stepUi {
+	u := &stepUi{
+		Ui:    state.Get("ui").(packersdk.Ui),
+		state: state,
+		level: levelInfo,
+	}
+	if c, ok := state.GetOk("config"); ok {
+		c := c.(*Config)
+		if level, ok := parseLogLevel(c.LogLevel); ok {
+			u.level = level
+		}
+		if c.PackerBuildName != "" {
+			u.fields = append(u.fields, "build", c.PackerBuildName)
+		}
+	}
+	u.fields = append(u.fields, "step", step)
+	return u
+}
+
+// With returns a copy of u adding a field to every message.
+func (u *stepUi) With(key string, value interface{}) *stepUi {
+	c := *u
+	c.fields = append(append([]interface{}{}, u.fields...), key, value)
+	return &c
+}
+
+func (u *stepUi) allFields() []interface{} {
+	fields := u.fields
+	if dropletId, ok := u.state.GetOk("droplet_id"); ok {
+		fields = append(append([]interface{}{}, fields...), "droplet_id", dropletId)
+	}
+	return fields
+}
+
+func (u *stepUi) Debugf(format string, args ...interface{}) {
+	logf(levelDebug, u.allFields(), format, args...)
+	if u.level <= levelDebug {
+		u.Ui.Message(redact(fmt.Sprintf(format, args...)))
+	}
+}
+
+func (u *stepUi) Say(message string) {
+	logf(levelInfo, u.allFields(), "%s", message)
+	if u.level <= levelInfo {
+		u.Ui.Say(message)
+	}
+}
+
+func (u *stepUi) Message(message string) {
+	logf(levelInfo, u.allFields(), "%s", message)
+	if u.level <= levelInfo {
+		u.Ui.Message(message)
+	}
+}
+
+func (u *stepUi) Warn(message string) {
+	logf(levelWarn, u.allFields(), "%s", message)
+	if u.level <= levelWarn {
+		u.Ui.Say("Warning: " + message)
+	}
+}
+
+// Error messages are always shown.
+func (u *stepUi) Error(message string) {
+	logf(levelError, u.allFields(), "%s", message)
+	u.Ui.Error(message)
+}
@@ -0,0 +1,53 @@
+package digitalocean
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestStepUi(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	var out, errOut bytes.Buffer
+	state := new(multistep.BasicStateBag)
+	state.Put("ui", &packersdk.BasicUi{Writer: &out, ErrorWriter: &errOut})
+	config := &Config{LogLevel: "warn"}
+	config.PackerBuildName = "base"
+	state.Put("config", config)
+	state.Put("droplet_id", 1002)
+
+	ui := newStepUi(state, "snapshot").With("action_id", 7)
+	ui.Debugf("Checking action status... (attempt: %d)", 1)
+	ui.Say("Creating snapshot: packer test")
+	ui.Warn("Snapshot is large")
+	ui.Error("Error creating snapshot")
+
+	for _, expected := range []string{
+		"[DEBUG] build=base step=snapshot action_id=7 droplet_id=1002 Checking action status... (attempt: 1)",
+		"[INFO] build=base step=snapshot action_id=7 droplet_id=1002 Creating snapshot: packer test",
+		"[WARN] build=base step=snapshot action_id=7 droplet_id=1002 Snapshot is large",
+		"[ERROR] build=base step=snapshot action_id=7 droplet_id=1002 Error creating snapshot",
+	} {
+		if !strings.Contains(logs.String(), expected) {
+			t.Errorf("expected the log to contain %q, got:\n%s", expected, logs.String())
+		}
+	}
+
+	if strings.Contains(out.String(), "Checking action status") || strings.Contains(out.String(), "Creating snapshot") {
+		t.Errorf("expected messages below warn to be hidden, got %q", out.String())
+	}
+	if !strings.Contains(out.String(), "Warning: Snapshot is large") {
+		t.Errorf("expected the warning to be shown, got %q", out.String())
+	}
+	if !strings.Contains(errOut.String(), "Error creating snapshot") {
+		t.Errorf("expected the error to be shown, got %q", errOut.String())
+	}
+}
@@ -0,0 +1,317 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/digitalocean/godo"
+)
+
+// findSize looks up a droplet size by its slug.
+func findSize(client *godo.Client, slug string) (*godo.Size, error) {
+	opt := &godo.ListOptions{
+		Page:    1,
+		PerPage: 200,
+	}
+	for {
+		sizes, resp, err := client.Sizes.List(context.TODO(), opt)
+		if err != nil {
+			return nil, err
+		}
+		for i := range sizes {
+			if sizes[i].Slug == slug {
+				return &sizes[i], nil
+			}
+		}
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			break
+		}
+		opt.Page++
+	}
+
+	return nil, fmt.Errorf("size %s not found", slug)
+}
+
+// nextSnapshotVersion returns the version following the highest one of the
+// snapshots named after prefix and a version, such as myimage-v12 for a
+// prefix of myimage-, or 1 when there is none.
+func nextSnapshotVersion(client *godo.Client, prefix string) (int, error) {
+	re := regexp.MustCompile("^" + regexp.QuoteMeta(prefix) + `v(\d+)$`)
+	opt := &godo.ListOptions{
+		Page:    1,
+		PerPage: 200,
+	}
+	latest := 0
+	for {
+		images, resp, err := client.Images.ListUser(context.TODO(), opt)
+		if err != nil {
+			return 0, err
+		}
+		for _, image := range images {
+			m := re.FindStringSubmatch(image.Name)
+			if m == nil {
+				continue
+			}
+			if version, err := strconv.Atoi(m[1]); err == nil && version > latest {
+				latest = version
+			}
+		}
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			break
+		}
+		opt.Page++
+	}
+	return latest + 1, nil
+}
+
+// listRegions returns every region, going through all the pages.
+func listRegions(client *godo.Client) ([]godo.Region, error) {
+	opt := &godo.ListOptions{
+		Page:    1,
+		PerPage: 200,
+	}
+	var all []godo.Region
+	for {
+		regions, resp, err := client.Regions.List(context.TODO(), opt)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, regions...)
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			break
+		}
+		opt.Page++
+	}
+	return all, nil
+}
+
+// findDropletsByName returns the droplets of the account named name. godo
+// doesn't send the name filter of the list call, so the request is built
+// here.
+func findDropletsByName(client *godo.Client, name string) ([]godo.Droplet, error) {
+	path := "v2/droplets?per_page=200&name=" + url.QueryEscape(name)
+	req, err := client.NewRequest(context.TODO(), http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	root := new(struct {
+		Droplets []godo.Droplet `json:"droplets"`
+	})
+	if _, err := client.Do(context.TODO(), req, root); err != nil {
+		return nil, err
+	}
+	return root.Droplets, nil
+}
+
+// findKeysByName resolves the names of SSH keys on the account to their
+// IDs, reporting every name that matches no key, or several.
+func findKeysByName(client *godo.Client, names []string) ([]int, error) {
+	opt := &godo.ListOptions{
+		Page:    1,
+		PerPage: 200,
+	}
+	byName := make(map[string][]int)
+	for {
+		keys, resp, err := client.Keys.List(context.TODO(), opt)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			byName[key.Name] = append(byName[key.Name], key.ID)
+		}
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			break
+		}
+		opt.Page++
+	}
+
+	var ids []int
+	var unknown, ambiguous []string
+	for _, name := range names {
+		switch len(byName[name]) {
+		case 0:
+			unknown = append(unknown, name)
+		case 1:
+			ids = append(ids, byName[name][0])
+		default:
+			ambiguous = append(ambiguous, name)
+		}
+	}
+	var problems []string
+	if len(unknown) > 0 {
+		problems = append(problems, fmt.Sprintf("no SSH key is named %s", strings.Join(unknown, ", ")))
+	}
+	if len(ambiguous) > 0 {
+		problems = append(problems, fmt.Sprintf("several SSH keys are named %s, use ssh_key_ids instead", strings.Join(ambiguous, ", ")))
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return ids, nil
+}
+
+// findDefaultVPC returns the default VPC of a region, which droplets with
+// private networking are created in unless they are given a VPC.
+func findDefaultVPC(client *godo.Client, region string) (*godo.VPC, error) {
+	opt := &godo.ListOptions{
+		Page:    1,
+		PerPage: 200,
+	}
+	for {
+		vpcs, resp, err := client.VPCs.List(context.TODO(), opt)
+		if err != nil {
+			return nil, err
+		}
+		for _, vpc := range vpcs {
+			if vpc.RegionSlug == region && vpc.Default {
+				return vpc, nil
+			}
+		}
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			break
+		}
+		opt.Page++
+	}
+	return nil, fmt.Errorf("region %s has no default VPC", region)
+}
+
+// sizeClasses maps size_class values to the slug prefix of their sizes.
+var sizeClasses = map[string]string{
+	"basic":             "s-",
+	"general-purpose":   "g-",
+	"cpu-optimized":     "c-",
+	"memory-optimized":  "m-",
+	"storage-optimized": "so-",
+}
+
+// findSizeByRequirements returns the cheapest available size meeting
+// min_vcpus, min_memory_gb and size_class. Unless the region is picked
+// automatically, the size must also be offered in the configured region.
+func findSizeByRequirements(client *godo.Client, c *Config) (*godo.Size, error) {
+	opt := &godo.ListOptions{
+		Page:    1,
+		PerPage: 200,
+	}
+	var found *godo.Size
+	for {
+		sizes, resp, err := client.Sizes.List(context.TODO(), opt)
+		if err != nil {
+			return nil, err
+		}
+		for i := range sizes {
+			size := &sizes[i]
+			if !size.Available || size.Vcpus < c.MinVCPUs || size.Memory < c.MinMemoryGB*1024 {
+				continue
+			}
+			if c.SizeClass != "" && !strings.HasPrefix(size.Slug, sizeClasses[c.SizeClass]) {
+				continue
+			}
+			if c.Region != "auto" && !containsString(size.Regions, c.Region) {
+				continue
+			}
+			if found == nil || size.PriceHourly < found.PriceHourly {
+				found = size
+			}
+		}
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			break
+		}
+		opt.Page++
+	}
+
+	if found == nil {
+		return nil, fmt.Errorf("no available size has at least %d vCPUs and %d GB of memory", c.MinVCPUs, c.MinMemoryGB)
+	}
+	return found, nil
+}
+
+// suggestImages returns the slugs of up to three images whose slug is close
+// to the given one, closest first.
+func suggestImages(client *godo.Client, slug string) ([]string, error) {
+	opt := &godo.ListOptions{
+		Page:    1,
+		PerPage: 200,
+	}
+	type match struct {
+		slug     string
+		distance int
+	}
+	var matches []match
+	maxDistance := len(slug) / 4
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+	for {
+		images, resp, err := client.Images.List(context.TODO(), opt)
+		if err != nil {
+			return nil, err
+		}
+		for _, image := range images {
+			if image.Slug == "" {
+				continue
+			}
+			if d := levenshtein(slug, image.Slug); d <= maxDistance {
+				matches = append(matches, match{image.Slug, d})
+			}
+		}
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			break
+		}
+		opt.Page++
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].distance < matches[j].distance
+	})
+	var slugs []string
+	for i := 0; i < len(matches) && i < 3; i++ {
+		slugs = append(slugs, matches[i].slug)
+	}
+	return slugs, nil
+}
+
+// imageNotFoundError builds the error for an image slug that doesn't
+// exist, listing close matches when there are any.
+func imageNotFoundError(client *godo.Client, slug string) error {
+	suggestions, err := suggestImages(client, slug)
+	if err != nil || len(suggestions) == 0 {
+		return fmt.Errorf("image %s not found", slug)
+	}
+	return fmt.Errorf("image %s not found, did you mean %s?", slug, strings.Join(suggestions, ", "))
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
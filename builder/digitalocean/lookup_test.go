@@ -0,0 +1,148 @@
+package digitalocean
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/digitalocean/godo"
+)
+
+func TestLevenshtein(t *testing.T) {
+	tt := []struct {
+		a, b     string
+		distance int
+	}{
+		{"", "", 0},
+		{"ubuntu-22-04-x64", "ubuntu-22-04-x64", 0},
+		{"ubuntu-22-04-x46", "ubuntu-22-04-x64", 2},
+		{"ubuntu-2204-x64", "ubuntu-22-04-x64", 1},
+		{"debian-11-x64", "", 13},
+	}
+
+	for _, tc := range tt {
+		if d := levenshtein(tc.a, tc.b); d != tc.distance {
+			t.Errorf("levenshtein(%q, %q) = %d, expected %d", tc.a, tc.b, d, tc.distance)
+		}
+	}
+}
+
+func TestImageNotFoundError(t *testing.T) {
+	sim, client := testSimulator(t)
+	sim.AddImage(godo.Image{Name: "22.04 (LTS) x64", Type: "base", Distribution: "Ubuntu", Slug: "ubuntu-22-04-x64", Public: true})
+	sim.AddImage(godo.Image{Name: "11 x64", Type: "base", Distribution: "Debian", Slug: "debian-11-x64", Public: true})
+
+	err := imageNotFoundError(client, "ubuntu-2204-x64")
+	expected := "image ubuntu-2204-x64 not found, did you mean ubuntu-22-04-x64, ubuntu-20-04-x64?"
+	if err.Error() != expected {
+		t.Fatalf("got %q, expected %q", err, expected)
+	}
+
+	err = imageNotFoundError(client, "freebsd-13-x64-zfs")
+	expected = "image freebsd-13-x64-zfs not found"
+	if err.Error() != expected {
+		t.Fatalf("got %q, expected %q", err, expected)
+	}
+}
+
+func TestFindSizeByRequirements(t *testing.T) {
+	sim, client := testSimulator(t)
+	sim.AddSize(godo.Size{Slug: "c-2", Memory: 4096, Vcpus: 2, Disk: 25, PriceHourly: 0.04464, Available: true, Regions: []string{"nyc3"}})
+	sim.AddSize(godo.Size{Slug: "s-2vcpu-4gb", Memory: 4096, Vcpus: 2, Disk: 80, PriceHourly: 0.02976, Available: true, Regions: []string{"ams3"}})
+	sim.AddSize(godo.Size{Slug: "s-8vcpu-16gb", Memory: 16384, Vcpus: 8, Disk: 320, PriceHourly: 0.11905, Available: false, Regions: []string{"nyc3"}})
+
+	tt := []struct {
+		name   string
+		config Config
+		slug   string
+	}{
+		{"vcpus", Config{Region: "nyc3", MinVCPUs: 2}, "s-2vcpu-2gb"},
+		{"memory", Config{Region: "nyc3", MinMemoryGB: 4}, "c-2"},
+		{"class", Config{Region: "nyc3", SizeClass: "basic", MinMemoryGB: 4}, "s-4vcpu-8gb"},
+		{"region", Config{Region: "ams3", MinMemoryGB: 4}, "s-2vcpu-4gb"},
+		{"auto", Config{Region: "auto", MinMemoryGB: 4}, "s-2vcpu-4gb"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			size, err := findSizeByRequirements(client, &tc.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if size.Slug != tc.slug {
+				t.Errorf("got %s, expected %s", size.Slug, tc.slug)
+			}
+		})
+	}
+
+	_, err := findSizeByRequirements(client, &Config{Region: "nyc3", MinVCPUs: 8})
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestFindKeysByName(t *testing.T) {
+	sim, client := testSimulator(t)
+	// More keys than fit a page
+	for i := 0; i < 250; i++ {
+		sim.AddKey(godo.Key{Name: fmt.Sprintf("key-%d", i), Fingerprint: fmt.Sprintf("fp-%d", i)})
+	}
+	breakGlass := sim.AddKey(godo.Key{Name: "break-glass", Fingerprint: "fp-break-glass"})
+	sim.AddKey(godo.Key{Name: "ops", Fingerprint: "fp-ops-1"})
+	sim.AddKey(godo.Key{Name: "ops", Fingerprint: "fp-ops-2"})
+
+	ids, err := findKeysByName(client, []string{"break-glass"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != breakGlass.ID {
+		t.Errorf("got %v, expected [%d]", ids, breakGlass.ID)
+	}
+
+	_, err = findKeysByName(client, []string{"break-glass", "missing", "ops"})
+	if err == nil {
+		t.Fatal("should have error")
+	}
+	for _, want := range []string{"no SSH key is named missing", "several SSH keys are named ops"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q doesn't mention %q", err, want)
+		}
+	}
+}
+
+func TestListRegions(t *testing.T) {
+	sim, client := testSimulator(t)
+	for i := 0; i < 250; i++ {
+		sim.AddRegion(godo.Region{Slug: fmt.Sprintf("tst%d", i), Available: true})
+	}
+
+	regions, err := listRegions(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(regions) != 255 {
+		t.Errorf("got %d regions, expected 255", len(regions))
+	}
+}
+
+func TestNextSnapshotVersion(t *testing.T) {
+	sim, client := testSimulator(t)
+
+	version, err := nextSnapshotVersion(client, "myimage-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 1 {
+		t.Errorf("got version %d, expected 1", version)
+	}
+
+	for _, name := range []string{"myimage-v9", "myimage-v12", "myimage-v11", "myimage-v13-rc", "other-v40", "myimage.v50"} {
+		sim.AddImage(godo.Image{Name: name, Type: "snapshot"})
+	}
+	version, err = nextSnapshotVersion(client, "myimage-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 13 {
+		t.Errorf("got version %d, expected 13", version)
+	}
+}
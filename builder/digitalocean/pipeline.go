@@ -0,0 +1,206 @@
+package digitalocean
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+// buildResult is the outcome of a build, written for the builds of the same
+// run using its snapshot as their source_build. Every build runs in a plugin
+// process of its own, so they share it through a file.
+type buildResult struct {
+	SnapshotID   int      `json:"snapshot_id,omitempty"`
+	SnapshotName string   `json:"snapshot_name,omitempty"`
+	Regions      []string `json:"regions,omitempty"`
+	Error        string   `json:"error,omitempty"`
+}
+
+// runDir returns the directory of the current packer run, or "" outside of
+// one. It holds the results of the builds of the run and their shared SSH
+// keys, and is removed by releaseRunDir once the last build of the run is
+// done with it.
+func runDir() string {
+	runUUID := os.Getenv("PACKER_RUN_UUID")
+	if runUUID == "" {
+		return ""
+	}
+	return filepath.Join(os.TempDir(), "packer-digitalocean-"+runUUID)
+}
+
+// holdRunDir records the build with the given UUID as using the directory
+// of the run, from its start so that the results of the builds it waits on
+// are kept until it is done.
+func holdRunDir(buildUUID string) error {
+	dir := runDir()
+	if dir == "" {
+		return nil
+	}
+	if err := lockDir(dir); err != nil {
+		return err
+	}
+	defer unlockDir(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "holders"), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, "holders", buildUUID), nil, 0600)
+}
+
+// releaseRunDir gives the directory of the run back, removing it when no
+// other build of the run holds it anymore. The directory of a build that
+// crashed is left behind, in the temporary directory.
+func releaseRunDir(buildUUID string) error {
+	dir := runDir()
+	if dir == "" {
+		return nil
+	}
+	if err := lockDir(dir); err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(dir, "holders", buildUUID)); err != nil && !os.IsNotExist(err) {
+		unlockDir(dir)
+		return err
+	}
+	holders, err := ioutil.ReadDir(filepath.Join(dir, "holders"))
+	if err != nil && !os.IsNotExist(err) {
+		unlockDir(dir)
+		return err
+	}
+	if len(holders) > 0 {
+		unlockDir(dir)
+		return nil
+	}
+	// The lock goes along with the rest
+	return os.RemoveAll(dir)
+}
+
+// buildResultPath returns the path of the result of the build of the given
+// name in the current packer run, or "" outside of one. The results have a
+// directory of their own in the one of the run, which the shared SSH key
+// also uses.
+func buildResultPath(buildName string) string {
+	dir := runDir()
+	if dir == "" || buildName == "" {
+		return ""
+	}
+	return filepath.Join(dir, "builds", url.PathEscape(buildName)+".json")
+}
+
+// writeBuildResult writes the result of the build, renaming it in place so
+// that waiting builds never read a partial one.
+func writeBuildResult(buildName string, result *buildResult) error {
+	path := buildResultPath(buildName)
+	if path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	body, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, body, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// readBuildResult returns the result of the build, or nil while it hasn't
+// finished.
+func readBuildResult(buildName string) (*buildResult, error) {
+	body, err := ioutil.ReadFile(buildResultPath(buildName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := new(buildResult)
+	if err := json.Unmarshal(body, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// stepSourceBuild waits for the build of source_build to finish, and sets
+// its snapshot as the image of the droplet.
+type stepSourceBuild struct {
+	pollInterval time.Duration
+}
+
+func (s *stepSourceBuild) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := newStepUi(state, "source_build")
+	c := state.Get("config").(*Config)
+
+	if buildResultPath(c.SourceBuild) == "" {
+		err := fmt.Errorf("source_build requires the builds to be run by packer build")
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	pollInterval := s.pollInterval
+	if pollInterval == 0 {
+		pollInterval = 5 * time.Second
+	}
+
+	ui.Say(fmt.Sprintf("Waiting for build %s to finish...", c.SourceBuild))
+	deadline := time.Now().Add(c.SourceBuildTimeout)
+	var result *buildResult
+	for {
+		var err error
+		result, err = readBuildResult(c.SourceBuild)
+		if err != nil {
+			err := fmt.Errorf("Error reading the result of build %s: %s", c.SourceBuild, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		if result != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			err := fmt.Errorf("Timeout waiting for build %s to finish, builds using source_build must run in parallel", c.SourceBuild)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		select {
+		case <-ctx.Done():
+			state.Put("error", ctx.Err())
+			return multistep.ActionHalt
+		case <-time.After(pollInterval):
+		}
+	}
+
+	if result.Error != "" {
+		err := fmt.Errorf("Build %s failed: %s", c.SourceBuild, result.Error)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	if !containsString(result.Regions, c.Region) {
+		err := fmt.Errorf("Snapshot %s of build %s isn't available in %s, add it to the snapshot_regions of the build",
+			result.SnapshotName, c.SourceBuild, c.Region)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	c.Image = strconv.Itoa(result.SnapshotID)
+	ui.Message(fmt.Sprintf("Using snapshot %s (%d) of build %s", result.SnapshotName, result.SnapshotID, c.SourceBuild))
+	return multistep.ActionContinue
+}
+
+func (s *stepSourceBuild) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
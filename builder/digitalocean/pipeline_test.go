@@ -0,0 +1,122 @@
+package digitalocean
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestStepSourceBuild(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv("PACKER_RUN_UUID", "5f8a1d4e-run")
+
+	cases := []struct {
+		name   string
+		result *buildResult
+		action multistep.StepAction
+		err    string
+	}{
+		{"snapshot", &buildResult{SnapshotID: 1234, SnapshotName: "base-1", Regions: []string{"nyc3", "ams3"}}, multistep.ActionContinue, ""},
+		{"failed", &buildResult{Error: "droplet failed"}, multistep.ActionHalt, "Build digitalocean.base failed: droplet failed"},
+		{"other region", &buildResult{SnapshotID: 1234, SnapshotName: "base-1", Regions: []string{"sfo3"}}, multistep.ActionHalt, "isn't available in nyc3"},
+		{"not finished", nil, multistep.ActionHalt, "Timeout waiting for build digitalocean.base"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PACKER_RUN_UUID", "run-"+strings.ReplaceAll(tt.name, " ", "-"))
+			if tt.result != nil {
+				if err := writeBuildResult("digitalocean.base", tt.result); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			c := &Config{SourceBuild: "digitalocean.base", SourceBuildTimeout: 20 * time.Millisecond, Region: "nyc3"}
+			state := new(multistep.BasicStateBag)
+			state.Put("ui", packersdk.TestUi(t))
+			state.Put("config", c)
+
+			step := &stepSourceBuild{pollInterval: 5 * time.Millisecond}
+			if action := step.Run(context.Background(), state); action != tt.action {
+				t.Fatalf("expected action %#v, got %#v: %s", tt.action, action, state.Get("error"))
+			}
+			if tt.err != "" {
+				if err := state.Get("error").(error); !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("unexpected error %q", err)
+				}
+				return
+			}
+			if c.Image != "1234" {
+				t.Errorf("expected image 1234, got %q", c.Image)
+			}
+		})
+	}
+}
+
+func TestStepSourceBuild_Waits(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv("PACKER_RUN_UUID", "5f8a1d4e-run")
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		writeBuildResult("digitalocean.base", &buildResult{SnapshotID: 1234, Regions: []string{"nyc3"}})
+	}()
+
+	c := &Config{SourceBuild: "digitalocean.base", SourceBuildTimeout: time.Second, Region: "nyc3"}
+	state := new(multistep.BasicStateBag)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("config", c)
+
+	step := &stepSourceBuild{pollInterval: 5 * time.Millisecond}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected action continue, got %#v: %s", action, state.Get("error"))
+	}
+	if c.Image != "1234" {
+		t.Errorf("expected image 1234, got %q", c.Image)
+	}
+}
+
+func TestBuildResultPath(t *testing.T) {
+	t.Setenv("PACKER_RUN_UUID", "")
+	if path := buildResultPath("digitalocean.base"); path != "" {
+		t.Errorf("expected no path outside of a run, got %q", path)
+	}
+
+	t.Setenv("PACKER_RUN_UUID", "5f8a1d4e-run")
+	if path := buildResultPath("app/web"); !strings.HasSuffix(path, "packer-digitalocean-5f8a1d4e-run/builds/app%2Fweb.json") {
+		t.Errorf("unexpected path %q", path)
+	}
+}
+
+func TestRunDir(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv("PACKER_RUN_UUID", "5f8a1d4e-run")
+
+	for _, build := range []string{"build-a", "build-b"} {
+		if err := holdRunDir(build); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writeBuildResult("digitalocean.base", &buildResult{SnapshotID: 1234}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The result is kept for the build still running
+	if err := releaseRunDir("build-a"); err != nil {
+		t.Fatal(err)
+	}
+	if result, err := readBuildResult("digitalocean.base"); err != nil || result == nil || result.SnapshotID != 1234 {
+		t.Fatalf("expected the result to be kept, got %#v: %v", result, err)
+	}
+
+	if err := releaseRunDir("build-b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(runDir()); !os.IsNotExist(err) {
+		t.Errorf("expected the run directory to be removed, got %v", err)
+	}
+}
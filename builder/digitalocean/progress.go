@@ -0,0 +1,192 @@
+package digitalocean
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+// The events of the progress log.
+const (
+	progressBuildStart   = "build_start"
+	progressBuildFinish  = "build_finish"
+	progressStepStart    = "step_start"
+	progressStepFinish   = "step_finish"
+	progressActionStatus = "action_status"
+)
+
+// progressLog writes events describing the progress of a build to a file,
+// one JSON object per line, for tools following builds to parse instead of
+// the messages of the UI.
+type progressLog struct {
+	buildName string
+
+	mu sync.Mutex
+	f  *os.File
+}
+
+// progressEvent is a line of the progress log. Percent is the share of the
+// steps of the build, or of the transfers of an action, that are done.
+type progressEvent struct {
+	Time      string            `json:"time"`
+	BuildName string            `json:"build_name,omitempty"`
+	Event     string            `json:"event"`
+	Step      string            `json:"step,omitempty"`
+	Index     int               `json:"index,omitempty"`
+	Total     int               `json:"total,omitempty"`
+	Percent   *int              `json:"percent,omitempty"`
+	Duration  float64           `json:"duration_seconds,omitempty"`
+	Result    string            `json:"result,omitempty"`
+	Error     string            `json:"error,omitempty"`
+	Action    string            `json:"action,omitempty"`
+	ActionID  int               `json:"action_id,omitempty"`
+	Status    string            `json:"status,omitempty"`
+	Region    string            `json:"region,omitempty"`
+	Resources map[string]string `json:"resources,omitempty"`
+}
+
+// openProgressLog opens path to write progress events to, appending to it
+// when it exists. path can be a file descriptor Packer was started with,
+// such as /dev/fd/3.
+func openProgressLog(path, buildName string) (*progressLog, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &progressLog{buildName: buildName, f: f}, nil
+}
+
+func (p *progressLog) emit(e progressEvent) {
+	e.Time = time.Now().UTC().Format(time.RFC3339Nano)
+	e.BuildName = p.buildName
+	line, err := json.Marshal(e)
+	if err != nil {
+		logf(levelWarn, nil, "Error encoding progress event %s: %s", e.Event, err)
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, err := p.f.Write(append(line, '\n')); err != nil {
+		logf(levelWarn, nil, "Error writing progress event %s: %s", e.Event, err)
+	}
+}
+
+func (p *progressLog) Close() error {
+	return p.f.Close()
+}
+
+// emitProgress writes an event to the progress log of the build, if it
+// has one.
+func emitProgress(state multistep.StateBag, e progressEvent) {
+	if p, ok := state.GetOk("progress_log"); ok {
+		p.(*progressLog).emit(e)
+	}
+}
+
+// percent returns done out of total as a percentage.
+func percent(done, total int) *int {
+	p := 100
+	if total > 0 {
+		p = done * 100 / total
+	}
+	return &p
+}
+
+// progressResources returns the IDs of the resources of the build the state
+// knows of.
+func progressResources(state multistep.StateBag) map[string]string {
+	resources := make(map[string]string)
+	if id, ok := state.GetOk("ssh_key_id"); ok {
+		resources["ssh_key_id"] = strconv.Itoa(id.(int))
+	}
+	if ids, ok := state.GetOk("volume_ids"); ok {
+		for i, id := range ids.([]string) {
+			resources["volume_id_"+strconv.Itoa(i)] = id
+		}
+	}
+	if id, ok := state.GetOk("droplet_id"); ok {
+		resources["droplet_id"] = strconv.Itoa(id.(int))
+	}
+	if ip, ok := state.GetOk("droplet_ip"); ok {
+		resources["droplet_ip"] = ip.(string)
+	}
+	if id, ok := state.GetOk("snapshot_image_id"); ok {
+		resources["snapshot_id"] = strconv.Itoa(id.(int))
+	}
+	if len(resources) == 0 {
+		return nil
+	}
+	return resources
+}
+
+// progressStep writes the start and the end of a step to the progress log.
+type progressStep struct {
+	step  multistep.Step
+	name  string
+	index int
+	total int
+}
+
+// progressSteps wraps every step that runs to report its progress, the
+// steps left out with multistep.If not counting towards the total.
+func progressSteps(steps []multistep.Step) []multistep.Step {
+	total := 0
+	for _, step := range steps {
+		if stepName(step) != "null_step" {
+			total++
+		}
+	}
+	wrapped := make([]multistep.Step, 0, len(steps))
+	index := 0
+	for _, step := range steps {
+		name := stepName(step)
+		if name == "null_step" {
+			wrapped = append(wrapped, step)
+			continue
+		}
+		index++
+		wrapped = append(wrapped, &progressStep{step: step, name: name, index: index, total: total})
+	}
+	return wrapped
+}
+
+func (s *progressStep) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	started := time.Now()
+	emitProgress(state, progressEvent{
+		Event:   progressStepStart,
+		Step:    s.name,
+		Index:   s.index,
+		Total:   s.total,
+		Percent: percent(s.index-1, s.total),
+	})
+
+	action := s.step.Run(ctx, state)
+
+	e := progressEvent{
+		Event:     progressStepFinish,
+		Step:      s.name,
+		Index:     s.index,
+		Total:     s.total,
+		Percent:   percent(s.index, s.total),
+		Duration:  time.Since(started).Seconds(),
+		Result:    "continue",
+		Resources: progressResources(state),
+	}
+	if action == multistep.ActionHalt {
+		e.Result = "halt"
+		if rawErr, ok := state.GetOk("error"); ok {
+			e.Error = rawErr.(error).Error()
+		}
+	}
+	emitProgress(state, e)
+	return action
+}
+
+func (s *progressStep) Cleanup(state multistep.StateBag) {
+	s.step.Cleanup(state)
+}
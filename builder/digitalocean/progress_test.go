@@ -0,0 +1,126 @@
+package digitalocean
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func readProgress(t *testing.T, path string) []progressEvent {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var events []progressEvent
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e progressEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("invalid line %q: %s", scanner.Text(), err)
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestProgressSteps(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "progress.jsonl")
+	progress, err := openProgressLog(path, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := new(multistep.BasicStateBag)
+	state.Put("config", &Config{})
+	state.Put("progress_log", progress)
+	steps := progressSteps([]multistep.Step{
+		&testTracedStep{action: multistep.ActionContinue},
+		multistep.If(false, new(stepReboot)),
+		&testTracedStep{action: multistep.ActionHalt},
+	})
+	runner := &multistep.BasicRunner{Steps: steps}
+	runner.Run(context.Background(), state)
+	progress.Close()
+
+	events := readProgress(t, path)
+	if len(events) != 4 {
+		t.Fatalf("expected 4 events, got %#v", events)
+	}
+	start, finish := events[0], events[1]
+	if start.Event != progressStepStart || start.Step != "test_traced_step" || start.Index != 1 ||
+		start.Total != 2 || *start.Percent != 0 || start.BuildName != "web" || start.Time == "" {
+		t.Errorf("unexpected start event: %#v", start)
+	}
+	if finish.Event != progressStepFinish || finish.Result != "continue" || *finish.Percent != 50 ||
+		finish.Resources["droplet_id"] != "1002" {
+		t.Errorf("unexpected finish event: %#v", finish)
+	}
+	if failed := events[3]; failed.Result != "halt" || failed.Error != "droplet failed" || *failed.Percent != 100 {
+		t.Errorf("unexpected finish event: %#v", failed)
+	}
+}
+
+func TestStepSnapshot_Progress(t *testing.T) {
+	sim, client := testSimulator(t)
+	droplet := sim.AddDroplet(godo.Droplet{Name: "packer-test", Region: &godo.Region{Slug: "nyc3"}, Status: "off"})
+
+	path := filepath.Join(t.TempDir(), "progress.jsonl")
+	progress, err := openProgressLog(path, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("droplet_id", droplet.ID)
+	state.Put("progress_log", progress)
+	state.Put("config", &Config{
+		SnapshotName:    "packer-test",
+		Region:          "nyc3",
+		SnapshotRegions: []string{"ams3", "sfo3"},
+	})
+
+	step := &stepSnapshot{snapshotTimeout: time.Second}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected action continue, got %#v: %s", action, state.Get("error"))
+	}
+	progress.Close()
+
+	var statuses []string
+	for _, e := range readProgress(t, path) {
+		if e.Event != progressActionStatus {
+			t.Errorf("unexpected event %#v", e)
+		}
+		status := e.Action + " " + e.Region + " " + e.Status
+		if e.Percent != nil {
+			status += fmt.Sprintf(" %d%%", *e.Percent)
+		}
+		statuses = append(statuses, status)
+	}
+	expected := []string{
+		"snapshot nyc3 in-progress",
+		"snapshot nyc3 completed",
+		"transfer ams3 in-progress 0%",
+		"transfer ams3 completed 50%",
+		"transfer sfo3 in-progress 50%",
+		"transfer sfo3 completed 100%",
+	}
+	if len(statuses) != len(expected) {
+		t.Fatalf("got %q, expected %q", statuses, expected)
+	}
+	for i := range expected {
+		if statuses[i] != expected[i] {
+			t.Errorf("got %q, expected %q", statuses[i], expected[i])
+		}
+	}
+}
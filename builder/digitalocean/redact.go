@@ -0,0 +1,43 @@
+package digitalocean
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/digitalocean/godo"
+)
+
+// secretPatterns match the secrets that may end up in a log message, such
+// as a dumped request or an error echoing one, along with what replaces
+// them.
+var secretPatterns = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	// Credential headers
+	{regexp.MustCompile(`(?i)(authorization:\s*(?:bearer\s+|basic\s+)?)\S+`), "${1}<redacted>"},
+	{regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/=-]+`), "${1}<redacted>"},
+	// DigitalOcean API tokens
+	{regexp.MustCompile(`do[opr]_v1_[a-f0-9]{64}`), "<redacted>"},
+	// User data, which commonly holds tokens and passwords, in JSON
+	{regexp.MustCompile(`("user_data"\s*:\s*)"(?:[^"\\]|\\.)*"`), `${1}"<redacted>"`},
+}
+
+// redact masks the secrets in a message about to be logged or shown.
+func redact(message string) string {
+	for _, secret := range secretPatterns {
+		message = secret.pattern.ReplaceAllString(message, secret.replacement)
+	}
+	return message
+}
+
+// stringifyCreateRequest returns a droplet create request as it is logged,
+// with the user data replaced by its size. godo.Stringify doesn't escape
+// strings, so the user data can't be told apart from the rest afterwards.
+func stringifyCreateRequest(req *godo.DropletCreateRequest) string {
+	logged := *req
+	if logged.UserData != "" {
+		logged.UserData = fmt.Sprintf("<redacted, %d bytes>", len(req.UserData))
+	}
+	return godo.Stringify(logged)
+}
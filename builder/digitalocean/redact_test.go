@@ -0,0 +1,43 @@
+package digitalocean
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/digitalocean/godo"
+)
+
+func TestRedact(t *testing.T) {
+	token := "dop_v1_" + strings.Repeat("ab", 32)
+	cases := []struct {
+		in       string
+		expected string
+	}{
+		{"Authorization: Bearer secret-token", "Authorization: Bearer <redacted>"},
+		{"header authorization: abc123", "header authorization: <redacted>"},
+		{"using bearer abc.def", "using bearer <redacted>"},
+		{"token " + token + " rejected", "token <redacted> rejected"},
+		{`{"name":"packer","user_data":"#!/bin/sh\necho \"pass\"","size":"s-1vcpu-1gb"}`,
+			`{"name":"packer","user_data":"<redacted>","size":"s-1vcpu-1gb"}`},
+		{"Creating droplet...", "Creating droplet..."},
+	}
+	for _, tt := range cases {
+		if out := redact(tt.in); out != tt.expected {
+			t.Errorf("redact(%q): expected %q, got %q", tt.in, tt.expected, out)
+		}
+	}
+}
+
+func TestStringifyCreateRequest(t *testing.T) {
+	req := &godo.DropletCreateRequest{Name: "packer", UserData: `password: "hunter2"`}
+	out := stringifyCreateRequest(req)
+	if strings.Contains(out, "hunter2") {
+		t.Fatalf("expected user data redacted, got %s", out)
+	}
+	if !strings.Contains(out, "<redacted, 19 bytes>") || !strings.Contains(out, `Name:"packer"`) {
+		t.Fatalf("unexpected request: %s", out)
+	}
+	if req.UserData != `password: "hunter2"` {
+		t.Fatal("expected the request unchanged")
+	}
+}
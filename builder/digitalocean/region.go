@@ -0,0 +1,78 @@
+package digitalocean
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/digitalocean/godo"
+)
+
+// regionLatency measures how long connecting to a region takes. Tests
+// replace it.
+var regionLatency = func(slug string) (time.Duration, error) {
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", fmt.Sprintf("speedtest-%s.digitalocean.com:443", slug), 5*time.Second)
+	if err != nil {
+		return 0, err
+	}
+	conn.Close()
+	return time.Since(start), nil
+}
+
+// selectRegions returns the regions the droplet can be created in for
+// region = "auto", best first according to region_strategy.
+func selectRegions(client *godo.Client, c *Config) ([]string, error) {
+	regions, err := listRegions(client)
+	if err != nil {
+		return nil, err
+	}
+
+	available := make(map[string]bool)
+	var candidates []string
+	for _, region := range regions {
+		if region.Available && containsString(region.Sizes, c.Size) {
+			available[region.Slug] = true
+			candidates = append(candidates, region.Slug)
+		}
+	}
+
+	switch c.RegionStrategy {
+	case "preference":
+		candidates = candidates[:0]
+		for _, region := range c.RegionPreference {
+			if available[region] {
+				candidates = append(candidates, region)
+			}
+		}
+	case "latency":
+		latencies := make(map[string]time.Duration)
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for _, region := range candidates {
+			wg.Add(1)
+			go func(region string) {
+				defer wg.Done()
+				latency, err := regionLatency(region)
+				if err != nil {
+					// Unreachable regions go last
+					latency = time.Hour
+				}
+				mu.Lock()
+				latencies[region] = latency
+				mu.Unlock()
+			}(region)
+		}
+		wg.Wait()
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return latencies[candidates[i]] < latencies[candidates[j]]
+		})
+	}
+
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no available region offers size %s", c.Size)
+	}
+	return candidates, nil
+}
@@ -0,0 +1,57 @@
+package digitalocean
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+)
+
+func TestSelectRegions(t *testing.T) {
+	sim, client := testSimulator(t)
+	sim.AddRegion(godo.Region{Slug: "lon1", Available: false, Sizes: []string{"s-1vcpu-1gb"}})
+	sim.AddRegion(godo.Region{Slug: "blr1", Available: true, Sizes: []string{"s-2vcpu-2gb"}})
+
+	measure := regionLatency
+	defer func() { regionLatency = measure }()
+	latencies := map[string]time.Duration{"nyc1": 90, "nyc3": 80, "sfo3": 10, "ams3": 50}
+	regionLatency = func(slug string) (time.Duration, error) {
+		if latency, ok := latencies[slug]; ok {
+			return latency, nil
+		}
+		return 0, errors.New("unreachable")
+	}
+
+	tt := []struct {
+		name     string
+		config   Config
+		expected []string
+	}{
+		{"first-available", Config{Size: "s-1vcpu-1gb", RegionStrategy: "first-available"},
+			[]string{"nyc1", "nyc3", "sfo3", "ams3", "fra1"}},
+		{"preference", Config{Size: "s-1vcpu-1gb", RegionStrategy: "preference", RegionPreference: []string{"lon1", "ams3", "blr1", "nyc3"}},
+			[]string{"ams3", "nyc3"}},
+		{"latency", Config{Size: "s-1vcpu-1gb", RegionStrategy: "latency"},
+			[]string{"sfo3", "ams3", "nyc3", "nyc1", "fra1"}},
+		{"size", Config{Size: "s-2vcpu-2gb", RegionStrategy: "preference", RegionPreference: []string{"blr1"}},
+			[]string{"blr1"}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			regions, err := selectRegions(client, &tc.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(regions, tc.expected) {
+				t.Errorf("got %v, expected %v", regions, tc.expected)
+			}
+		})
+	}
+
+	_, err := selectRegions(client, &Config{Size: "s-1vcpu-1gb", RegionStrategy: "preference", RegionPreference: []string{"lon1"}})
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
@@ -0,0 +1,156 @@
+package digitalocean
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+// The kinds of resources a build creates.
+const (
+	resourceDroplet              = "droplet"
+	resourceSSHKey               = "ssh key"
+	resourceVolume               = "volume"
+	resourceReservedIP           = "reserved ip"
+	resourceSnapshot             = "snapshot"
+	resourceVolumeSnapshot       = "volume snapshot"
+	resourceIntermediateSnapshot = "intermediate snapshot"
+	resourceTransfer             = "transfer"
+	resourceTag                  = "tag"
+)
+
+// The disposition of a resource at the end of the build.
+const (
+	resourceKept       = "kept"
+	resourceDeleted    = "deleted"
+	resourceReleased   = "released"
+	resourceUnassigned = "unassigned"
+	resourceAdopted    = "adopted"
+	resourceLeaked     = "failed to delete"
+)
+
+// buildResource is a resource created by the build, and what became of it.
+type buildResource struct {
+	Kind        string `json:"kind"`
+	ID          string `json:"id"`
+	Name        string `json:"name,omitempty"`
+	Disposition string `json:"disposition"`
+	Error       string `json:"error,omitempty"`
+	// Adopted is set for an existing resource the build used, rather than
+	// created.
+	Adopted bool `json:"adopted,omitempty"`
+}
+
+// resourceLedger records the resources a build creates and deletes, so that
+// the build ends with a report of what it left in the account. The
+// watchdog deletes the droplet from its own goroutine, hence the lock.
+type resourceLedger struct {
+	mu        sync.Mutex
+	resources []*buildResource
+}
+
+// trackCreated records a resource the build created, which is kept unless
+// trackDeleted is called for it. It does nothing when the state has no
+// ledger.
+func trackCreated(state multistep.StateBag, kind string, id interface{}, name string) {
+	track(state, &buildResource{Kind: kind, ID: fmt.Sprint(id), Name: name, Disposition: resourceKept})
+}
+
+// trackAdopted records an existing resource the build used, such as the
+// reserved IP of reserved_ip, which is never deleted.
+func trackAdopted(state multistep.StateBag, kind string, id interface{}, name string) {
+	track(state, &buildResource{Kind: kind, ID: fmt.Sprint(id), Name: name, Disposition: resourceKept, Adopted: true})
+}
+
+func track(state multistep.StateBag, r *buildResource) {
+	ledger, ok := state.Get("resources").(*resourceLedger)
+	if !ok {
+		return
+	}
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+	ledger.resources = append(ledger.resources, r)
+}
+
+// trackDeleted records the deletion of a resource, which failed when err
+// isn't nil.
+func trackDeleted(state multistep.StateBag, kind string, id interface{}, err error) {
+	disposition := resourceDeleted
+	if err != nil {
+		disposition = resourceLeaked
+	}
+	trackDisposition(state, kind, id, disposition, err)
+}
+
+// trackDisposition sets what became of a resource recorded by trackCreated.
+func trackDisposition(state multistep.StateBag, kind string, id interface{}, disposition string, err error) {
+	ledger, ok := state.Get("resources").(*resourceLedger)
+	if !ok {
+		return
+	}
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+	for _, r := range ledger.resources {
+		if r.Kind == kind && r.ID == fmt.Sprint(id) {
+			r.Disposition = disposition
+			r.Error = ""
+			if err != nil {
+				r.Error = err.Error()
+			}
+		}
+	}
+}
+
+// list returns the resources, followed by the transfers of the snapshot to
+// each region with their availability.
+func (l *resourceLedger) list(state multistep.StateBag) []buildResource {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	resources := make([]buildResource, 0, len(l.resources))
+	for _, r := range l.resources {
+		resources = append(resources, *r)
+	}
+
+	availability, _ := state.Get("region_availability").(map[string]string)
+	regions := make([]string, 0, len(availability))
+	for region := range availability {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	name, _ := state.Get("snapshot_name").(string)
+	for _, region := range regions {
+		resources = append(resources, buildResource{
+			Kind:        resourceTransfer,
+			ID:          region,
+			Name:        name,
+			Disposition: availability[region],
+		})
+	}
+	return resources
+}
+
+// report returns the resources as a table, and whether any of them failed
+// to delete.
+func (l *resourceLedger) report(state multistep.StateBag) (string, bool) {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tID\tNAME\tDISPOSITION")
+	leaked := false
+	for _, r := range l.list(state) {
+		disposition := r.Disposition
+		if disposition == resourceLeaked {
+			leaked = true
+			disposition = fmt.Sprintf("FAILED TO DELETE, delete it manually: %s", r.Error)
+		}
+		if r.Adopted {
+			disposition = resourceAdopted + ", " + disposition
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Kind, r.ID, r.Name, disposition)
+	}
+	w.Flush()
+	return strings.TrimRight(b.String(), "\n"), leaked
+}
@@ -0,0 +1,99 @@
+package digitalocean
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-digitalocean/internal/simulator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestResourceLedger(t *testing.T) {
+	sim, client := testSimulator(t)
+	ledger := new(resourceLedger)
+
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("resources", ledger)
+
+	// A droplet deleted, and one that fails to delete
+	for _, failDelete := range []bool{false, true} {
+		state.Put("config", &Config{DropletName: "packer-test", Region: "nyc3", Size: "s-1vcpu-1gb", Image: "ubuntu-20-04-x64",
+			StateTimeout: time.Second})
+		step := new(stepCreateDroplet)
+		if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+			t.Fatalf("expected action continue, got %#v: %s", action, state.Get("error"))
+		}
+		if failDelete {
+			sim.Inject(simulator.Fault{Method: http.MethodDelete, Path: "/v2/droplets", Status: http.StatusForbidden,
+				ID: "forbidden", Message: "You do not have access for the attempted action.", Times: 100})
+		}
+		step.Cleanup(state)
+	}
+	trackCreated(state, resourceSnapshot, 42, "packer-test-snapshot")
+	state.Put("snapshot_name", "packer-test-snapshot")
+	state.Put("region_availability", map[string]string{"nyc3": regionAvailable, "ams3": regionFailed})
+
+	resources := ledger.list(state)
+	var dispositions []string
+	for _, r := range resources {
+		dispositions = append(dispositions, r.Kind+" "+r.Disposition)
+	}
+	expected := []string{
+		"droplet deleted",
+		"tag deleted",
+		"droplet failed to delete",
+		"tag kept",
+		"snapshot kept",
+		"transfer failed",
+		"transfer available",
+	}
+	if strings.Join(dispositions, ", ") != strings.Join(expected, ", ") {
+		t.Fatalf("expected %v, got %v", expected, dispositions)
+	}
+	if resources[5].ID != "ams3" || resources[5].Name != "packer-test-snapshot" {
+		t.Errorf("unexpected transfer %#v", resources[5])
+	}
+
+	report, leaked := ledger.report(state)
+	if !leaked {
+		t.Error("expected the report to flag the droplet which failed to delete")
+	}
+	lines := strings.Split(report, "\n")
+	if len(lines) != 8 || !strings.HasPrefix(lines[0], "KIND") {
+		t.Fatalf("unexpected report:\n%s", report)
+	}
+	if !strings.Contains(lines[3], "FAILED TO DELETE, delete it manually") || !strings.Contains(lines[3], "403") {
+		t.Errorf("expected the failed deletion in the report, got %q", lines[3])
+	}
+}
+
+func TestResourceLedger_NoLedger(t *testing.T) {
+	state := new(multistep.BasicStateBag)
+
+	// Steps run without a ledger in their own tests
+	trackCreated(state, resourceDroplet, 1, "packer-test")
+	trackDeleted(state, resourceDroplet, 1, nil)
+}
+
+func TestResourceLedger_Adopted(t *testing.T) {
+	ledger := new(resourceLedger)
+	state := new(multistep.BasicStateBag)
+	state.Put("resources", ledger)
+
+	trackAdopted(state, resourceReservedIP, "203.0.113.10", "")
+	trackDisposition(state, resourceReservedIP, "203.0.113.10", resourceUnassigned, nil)
+
+	report, leaked := ledger.report(state)
+	if leaked {
+		t.Error("expected nothing to be flagged")
+	}
+	if lines := strings.Split(report, "\n"); len(lines) != 2 || !strings.HasSuffix(lines[1], "adopted, unassigned") {
+		t.Fatalf("unexpected report:\n%s", report)
+	}
+}
@@ -0,0 +1,124 @@
+package digitalocean
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	statusPageURL     = "https://status.digitalocean.com/api/v2/incidents/unresolved.json"
+	statusPageTimeout = 5 * time.Second
+)
+
+// regionMentionRe matches the slug of a region in the name of an incident or
+// component, such as NYC3 in "Droplet Creation in NYC3".
+var regionMentionRe = regexp.MustCompile(`(?i)\b[a-z]{3}[0-9]\b`)
+
+// statusIncident is an incident of the DigitalOcean status page, which is
+// served by Statuspage.
+type statusIncident struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Shortlink  string `json:"shortlink"`
+	Components []struct {
+		Name string `json:"name"`
+	} `json:"components"`
+}
+
+// mentions returns the names of the incident and of its components.
+func (i statusIncident) mentions() []string {
+	names := []string{i.Name}
+	for _, component := range i.Components {
+		names = append(names, component.Name)
+	}
+	return names
+}
+
+// affects returns whether the incident is about the region, or about one of
+// the services without being about a region in particular.
+func (i statusIncident) affects(region string, services []string) bool {
+	regional := false
+	service := false
+	for _, name := range i.mentions() {
+		for _, mention := range regionMentionRe.FindAllString(name, -1) {
+			if strings.EqualFold(mention, region) {
+				return true
+			}
+			regional = true
+		}
+		for _, s := range services {
+			if strings.Contains(strings.ToLower(name), strings.ToLower(s)) {
+				service = true
+			}
+		}
+	}
+	return service && !regional
+}
+
+// activeIncidents returns the unresolved incidents of the status page
+// affecting the region or the services.
+func activeIncidents(region string, services []string) ([]statusIncident, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), statusPageTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, statusPageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var page struct {
+		Incidents []statusIncident `json:"incidents"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, err
+	}
+	var incidents []statusIncident
+	for _, incident := range page.Incidents {
+		if incident.affects(region, services) {
+			incidents = append(incidents, incident)
+		}
+	}
+	return incidents, nil
+}
+
+// withIncidents appends the active incidents of the DigitalOcean status page
+// affecting the region or the services, such as Droplets, to err, so that an
+// outage is told apart from a problem of the build. The API is always one
+// of the services. err is returned as is when the status page can't be
+// reached or has nothing to report.
+func withIncidents(c *Config, err error, region string, services ...string) error {
+	if c.DisableStatusPage {
+		return err
+	}
+	incidents, statusErr := activeIncidents(region, append(services, "API"))
+	if statusErr != nil {
+		logf(levelDebug, nil, "Unable to check the DigitalOcean status page: %s", statusErr)
+		return err
+	}
+	if len(incidents) == 0 {
+		return err
+	}
+
+	lines := make([]string, 0, len(incidents))
+	for _, incident := range incidents {
+		line := fmt.Sprintf("%s (%s)", incident.Name, incident.Status)
+		if incident.Shortlink != "" {
+			line += " " + incident.Shortlink
+		}
+		lines = append(lines, line)
+	}
+	return fmt.Errorf("%s\nThe DigitalOcean status page reports active incidents that may be the cause:\n  %s",
+		err, strings.Join(lines, "\n  "))
+}
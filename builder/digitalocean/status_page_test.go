@@ -0,0 +1,77 @@
+package digitalocean
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// The errors of the tests aren't to be explained by live incidents
+	statusPageURL = ""
+	os.Exit(m.Run())
+}
+
+const testIncidents = `{"incidents": [
+  {"name": "Droplet Creation Delays in NYC3", "status": "investigating", "shortlink": "https://stspg.io/a",
+   "components": [{"name": "NYC3"}]},
+  {"name": "Spaces Availability in AMS3", "status": "monitoring", "components": [{"name": "Spaces"}, {"name": "AMS3"}]},
+  {"name": "Delayed Droplet Events", "status": "identified", "components": [{"name": "Droplets"}]},
+  {"name": "Degraded Snapshots in SFO3", "status": "investigating", "components": [{"name": "Snapshots"}]}
+]}`
+
+func testStatusPage(t *testing.T, status int, body string) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	url := statusPageURL
+	statusPageURL = srv.URL
+	t.Cleanup(func() { statusPageURL = url })
+}
+
+func TestWithIncidents(t *testing.T) {
+	testStatusPage(t, http.StatusOK, testIncidents)
+	failure := errors.New("timeout while waiting for droplet to become 'active'")
+
+	err := withIncidents(&Config{}, failure, "nyc3", "Droplets")
+	msg := err.Error()
+	if !strings.HasPrefix(msg, failure.Error()) {
+		t.Errorf("expected the error first, got %q", msg)
+	}
+	if !strings.Contains(msg, "Droplet Creation Delays in NYC3 (investigating) https://stspg.io/a") ||
+		!strings.Contains(msg, "Delayed Droplet Events (identified)") {
+		t.Errorf("expected the incidents of nyc3 and droplets, got %q", msg)
+	}
+	if strings.Contains(msg, "AMS3") || strings.Contains(msg, "SFO3") {
+		t.Errorf("expected the incidents of other regions to be left out, got %q", msg)
+	}
+
+	err = withIncidents(&Config{}, failure, "sfo3", "Snapshots")
+	if !strings.Contains(err.Error(), "Degraded Snapshots in SFO3") || strings.Contains(err.Error(), "Droplet") {
+		t.Errorf("unexpected error %q", err)
+	}
+
+	err = withIncidents(&Config{}, failure, "lon1", "Volumes")
+	if err != failure {
+		t.Errorf("expected the error as is without incidents, got %q", err)
+	}
+
+	err = withIncidents(&Config{DisableStatusPage: true}, failure, "nyc3", "Droplets")
+	if err != failure {
+		t.Errorf("expected disable_status_page to leave the error as is, got %q", err)
+	}
+}
+
+func TestWithIncidents_Unavailable(t *testing.T) {
+	testStatusPage(t, http.StatusServiceUnavailable, "")
+	failure := errors.New("unable to create droplet")
+	if err := withIncidents(&Config{}, failure, "nyc3", "Droplets"); err != failure {
+		t.Errorf("expected the error as is, got %q", err)
+	}
+}
@@ -0,0 +1,90 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+// stepCheckBudget enforces max_hourly_price and max_estimated_cost. It runs
+// once before anything is created to check the price of the size, and once
+// more with accrued set after provisioning to check the cost so far. The
+// watchdog checks max_estimated_cost while the build runs as well.
+type stepCheckBudget struct {
+	accrued bool
+}
+
+func (s *stepCheckBudget) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*godo.Client)
+	ui := newStepUi(state, "check_budget")
+	c := state.Get("config").(*Config)
+
+	price, err := hourlyPrice(client, state, c)
+	if err != nil {
+		err := fmt.Errorf("Error looking up the price of size %s: %s", c.Size, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	if !s.accrued {
+		if c.MaxHourlyPrice > 0 && price > c.MaxHourlyPrice {
+			err = fmt.Errorf("Size %s costs $%.5f/hour, which exceeds max_hourly_price of $%.5f/hour",
+				c.Size, price, c.MaxHourlyPrice)
+		}
+	} else if c.MaxEstimatedCost > 0 {
+		cost, duration, costErr := estimatedCost(client, state, c)
+		if costErr == nil && cost > c.MaxEstimatedCost {
+			err = fmt.Errorf("Estimated cost of $%.4f after %s exceeds max_estimated_cost of $%.4f",
+				cost, duration.Round(time.Second), c.MaxEstimatedCost)
+		}
+	}
+
+	if err == nil {
+		return multistep.ActionContinue
+	}
+	if c.BudgetAction == "warn" {
+		ui.Warn(err.Error())
+		return multistep.ActionContinue
+	}
+
+	state.Put("error", err)
+	ui.Error(err.Error())
+	return multistep.ActionHalt
+}
+
+func (s *stepCheckBudget) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
+
+// hourlyPrice returns the hourly price of the size, looking it up the first
+// time.
+func hourlyPrice(client *godo.Client, state multistep.StateBag, c *Config) (float64, error) {
+	if price, ok := state.GetOk("price_hourly"); ok {
+		return price.(float64), nil
+	}
+	size, err := findSize(client, c.Size)
+	if err != nil {
+		return 0, err
+	}
+	state.Put("price_hourly", size.PriceHourly)
+	return size.PriceHourly, nil
+}
+
+// estimatedCost returns the cost accrued since the droplet was created, and
+// for how long it has been running, which are zero before it is.
+func estimatedCost(client *godo.Client, state multistep.StateBag, c *Config) (float64, time.Duration, error) {
+	createdAt, ok := state.GetOk("droplet_created_at")
+	if !ok {
+		return 0, 0, nil
+	}
+	price, err := hourlyPrice(client, state, c)
+	if err != nil {
+		return 0, 0, err
+	}
+	duration := time.Since(createdAt.(time.Time))
+	return price * duration.Hours(), duration, nil
+}
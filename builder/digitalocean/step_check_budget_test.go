@@ -0,0 +1,44 @@
+package digitalocean
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestStepCheckBudget(t *testing.T) {
+	tt := []struct {
+		Name    string
+		Config  Config
+		Accrued bool
+		Action  multistep.StepAction
+	}{
+		{Name: "WithinHourlyPrice", Config: Config{Size: "s-1vcpu-1gb", MaxHourlyPrice: 0.01, BudgetAction: "fail"}, Action: multistep.ActionContinue},
+		{Name: "OverHourlyPrice", Config: Config{Size: "s-4vcpu-8gb", MaxHourlyPrice: 0.01, BudgetAction: "fail"}, Action: multistep.ActionHalt},
+		{Name: "OverHourlyPriceWarn", Config: Config{Size: "s-4vcpu-8gb", MaxHourlyPrice: 0.01, BudgetAction: "warn"}, Action: multistep.ActionContinue},
+		{Name: "UnknownSize", Config: Config{Size: "gd-40vcpu-160gb", MaxHourlyPrice: 0.01, BudgetAction: "warn"}, Action: multistep.ActionHalt},
+		{Name: "WithinEstimatedCost", Config: Config{Size: "s-4vcpu-8gb", MaxEstimatedCost: 1, BudgetAction: "fail"}, Accrued: true, Action: multistep.ActionContinue},
+		{Name: "OverEstimatedCost", Config: Config{Size: "s-4vcpu-8gb", MaxEstimatedCost: 0.05, BudgetAction: "fail"}, Accrued: true, Action: multistep.ActionHalt},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			_, client := testSimulator(t)
+
+			state := new(multistep.BasicStateBag)
+			state.Put("client", client)
+			state.Put("ui", packersdk.TestUi(t))
+			state.Put("config", &tc.Config)
+			state.Put("droplet_created_at", time.Now().Add(-2*time.Hour))
+
+			step := &stepCheckBudget{accrued: tc.Accrued}
+			if action := step.Run(context.Background(), state); action != tc.Action {
+				t.Fatalf("expected action %#v, got %#v", tc.Action, action)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// cloudInitLogs are the files saved to cloud_init_log_dir.
+var cloudInitLogs = []string{"/var/log/cloud-init.log", "/var/log/cloud-init-output.log"}
+
+// stepCloudInitLogs saves the cloud-init logs of the droplet when the build
+// fails. It does nothing until its cleanup, which runs once every later
+// step is cleaned up, before the droplet is destroyed.
+type stepCloudInitLogs struct {
+	// dial connects to the droplet, tests replace it.
+	dial func(network, addr string, config *gossh.ClientConfig) (*gossh.Client, error)
+}
+
+func (s *stepCloudInitLogs) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	return multistep.ActionContinue
+}
+
+func (s *stepCloudInitLogs) Cleanup(state multistep.StateBag) {
+	if _, ok := state.GetOk("error"); !ok {
+		return
+	}
+	ip, ok := state.GetOk("droplet_ip")
+	if !ok {
+		return
+	}
+
+	ui := newStepUi(state, "cloud_init_logs")
+	c := state.Get("config").(*Config)
+
+	ui.Say("Collecting cloud-init logs...")
+	if err := s.collect(state, c, ip.(string)); err != nil {
+		ui.Error(fmt.Sprintf("Error collecting cloud-init logs: %s", err))
+		return
+	}
+	ui.Message(fmt.Sprintf("Saved cloud-init logs to %s", c.CloudInitLogDir))
+}
+
+func (s *stepCloudInitLogs) collect(state multistep.StateBag, c *Config, ip string) error {
+	dial := s.dial
+	if dial == nil {
+		dial = gossh.Dial
+	}
+
+	sshConfig, err := pinnedSSHConfigFunc(c.Comm.SSHConfigFunc())(state)
+	if err != nil {
+		return err
+	}
+	sshConfig.Timeout = 30 * time.Second
+	client, err := dial("tcp", net.JoinHostPort(ip, strconv.Itoa(c.Comm.SSHPort)), sshConfig)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := os.MkdirAll(c.CloudInitLogDir, 0755); err != nil {
+		return err
+	}
+	sudo := ""
+	if c.Comm.SSHUsername != "root" {
+		sudo = "sudo "
+	}
+	for _, path := range cloudInitLogs {
+		session, err := client.NewSession()
+		if err != nil {
+			return err
+		}
+		output, err := session.Output(sudo + "cat " + path)
+		session.Close()
+		if err != nil {
+			logf(levelWarn, []interface{}{"step", "cloud_init_logs"}, "Unable to read %s: %s", path, err)
+			continue
+		}
+		if err := ioutil.WriteFile(filepath.Join(c.CloudInitLogDir, filepath.Base(path)), output, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
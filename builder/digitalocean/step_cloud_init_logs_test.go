@@ -0,0 +1,72 @@
+package digitalocean
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+func TestStepCloudInitLogs(t *testing.T) {
+	for _, failed := range []bool{false, true} {
+		addr := testSSHServer(t)
+		clientKey, _, err := generateHostKey()
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		dir := filepath.Join(t.TempDir(), "logs")
+
+		state := new(multistep.BasicStateBag)
+		state.Put("ui", packersdk.TestUi(t))
+		state.Put("droplet_ip", "192.0.2.10")
+		state.Put("config", &Config{
+			CloudInitLogDir: dir,
+			Comm: communicator.Config{
+				Type: "ssh",
+				SSH: communicator.SSH{
+					SSHUsername:   "root",
+					SSHPort:       22,
+					SSHTimeout:    time.Second,
+					SSHPrivateKey: []byte(clientKey),
+				},
+			},
+		})
+		if failed {
+			state.Put("error", errors.New("Timeout waiting for SSH."))
+		}
+
+		step := &stepCloudInitLogs{
+			dial: func(network, _ string, config *gossh.ClientConfig) (*gossh.Client, error) {
+				return gossh.Dial(network, addr, config)
+			},
+		}
+		if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+			t.Fatalf("expected action continue, got %#v", action)
+		}
+		step.Cleanup(state)
+
+		if !failed {
+			if _, err := os.Stat(dir); !os.IsNotExist(err) {
+				t.Fatalf("expected no logs for a successful build, got %v", err)
+			}
+			continue
+		}
+		for _, path := range cloudInitLogs {
+			data, err := ioutil.ReadFile(filepath.Join(dir, filepath.Base(path)))
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if expected := "ran cat " + path + "\n"; string(data) != expected {
+				t.Fatalf("expected %q, got %q", expected, data)
+			}
+		}
+	}
+}
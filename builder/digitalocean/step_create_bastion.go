@@ -0,0 +1,126 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+// stepCreateBastion creates the droplet of temporary_bastion in the VPC of
+// the build droplet, and sets it as the SSH bastion of the communicator.
+type stepCreateBastion struct {
+	bastionId int
+	keyFile   string
+}
+
+func (s *stepCreateBastion) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*godo.Client)
+	ui := newStepUi(state, "create_bastion")
+	c := state.Get("config").(*Config)
+	dropletId := state.Get("droplet_id").(int)
+
+	droplet, _, err := client.Droplets.Get(context.TODO(), dropletId)
+	if err != nil {
+		err := fmt.Errorf("Error retrieving droplet: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Say("Creating temporary bastion droplet...")
+	bastion, _, err := client.Droplets.Create(context.TODO(), &godo.DropletCreateRequest{
+		Name:    c.DropletName + "-bastion",
+		Region:  c.Region,
+		Size:    c.TemporaryBastionSize,
+		Image:   getImageType(c.TemporaryBastionImage),
+		SSHKeys: dropletSSHKeys(state, c),
+		// Tagged as the build droplet is, which firewalls often select
+		Tags:    c.Tags,
+		VPCUUID: droplet.VPCUUID,
+	})
+	if err != nil {
+		err = withIncidents(c, err, c.Region, "Droplets")
+		err := fmt.Errorf("Error creating bastion droplet: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	s.bastionId = bastion.ID
+	trackCreated(state, resourceDroplet, bastion.ID, bastion.Name)
+	ui.Message(fmt.Sprintf("Created bastion droplet %s (%d) in %s", bastion.Name, bastion.ID, c.Region))
+
+	if err := WaitForDropletState("active", bastion.ID, client, c.StateTimeout); err != nil {
+		err := fmt.Errorf("Error waiting for bastion droplet to become active: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	bastion, _, err = client.Droplets.Get(context.TODO(), bastion.ID)
+	if err != nil {
+		err := fmt.Errorf("Error retrieving bastion droplet: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	ip, ok := dropletIP(bastion, false)
+	if !ok {
+		err := fmt.Errorf("Could not find a public IPv4 address for the bastion droplet")
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// The bastion has the keys of the build droplet, so the communicator
+	// logs in to both with the same one
+	keyFile := c.Comm.SSHPrivateKeyFile
+	if keyFile == "" && len(c.Comm.SSHPrivateKey) > 0 {
+		f, err := ioutil.TempFile("", "packer-bastion-key")
+		if err == nil {
+			s.keyFile = f.Name()
+			_, err = f.Write(c.Comm.SSHPrivateKey)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err != nil {
+			err := fmt.Errorf("Error writing bastion SSH key: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		keyFile = s.keyFile
+	}
+	c.Comm.SSHBastionHost = ip
+	c.Comm.SSHBastionPort = 22
+	c.Comm.SSHBastionUsername = "root"
+	c.Comm.SSHBastionPrivateKeyFile = keyFile
+	c.Comm.SSHBastionAgentAuth = c.Comm.SSHAgentAuth
+	ui.Message(fmt.Sprintf("Connecting through bastion %s", ip))
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateBastion) Cleanup(state multistep.StateBag) {
+	if s.keyFile != "" {
+		os.Remove(s.keyFile)
+	}
+	if s.bastionId == 0 {
+		return
+	}
+
+	client := state.Get("client").(*godo.Client)
+	c := state.Get("config").(*Config)
+	ui := newStepUi(state, "create_bastion")
+
+	ui.Say("Destroying bastion droplet...")
+	err := DestroyDroplet(client, s.bastionId, c.StateTimeout)
+	trackDeleted(state, resourceDroplet, s.bastionId, err)
+	if err != nil {
+		ui.Error(fmt.Sprintf(
+			"Error destroying bastion droplet. Please destroy it manually: %s", err))
+	}
+}
@@ -0,0 +1,71 @@
+package digitalocean
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestStepCreateBastion(t *testing.T) {
+	sim, client := testSimulator(t)
+	vpc := sim.AddVPC(godo.VPC{Name: "default-nyc3", RegionSlug: "nyc3", Default: true})
+	droplet := sim.AddDroplet(godo.Droplet{Name: "packer-test", Region: &godo.Region{Slug: "nyc3"}, Status: "active",
+		VPCUUID: vpc.ID})
+	key := sim.AddKey(godo.Key{Name: "packer-test"})
+
+	c := &Config{
+		DropletName:           "packer-test",
+		Region:                "nyc3",
+		Tags:                  []string{"build"},
+		StateTimeout:          time.Second,
+		TemporaryBastion:      true,
+		TemporaryBastionSize:  "s-1vcpu-1gb",
+		TemporaryBastionImage: "ubuntu-20-04-x64",
+		Comm: communicator.Config{SSH: communicator.SSH{
+			SSHPrivateKey: []byte("private key"),
+		}},
+	}
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("config", c)
+	state.Put("droplet_id", droplet.ID)
+	state.Put("ssh_key_id", key.ID)
+
+	step := new(stepCreateBastion)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected action continue, got %#v: %s", action, state.Get("error"))
+	}
+
+	bastion, ok := sim.Droplet(step.bastionId)
+	if !ok {
+		t.Fatal("expected the bastion to exist")
+	}
+	if bastion.Name != "packer-test-bastion" || bastion.VPCUUID != vpc.ID || bastion.SizeSlug != "s-1vcpu-1gb" {
+		t.Errorf("unexpected bastion: %s, %s, %s", bastion.Name, bastion.VPCUUID, bastion.SizeSlug)
+	}
+	ip, _ := dropletIP(&bastion, false)
+	if c.Comm.SSHBastionHost != ip || c.Comm.SSHBastionPort != 22 || c.Comm.SSHBastionUsername != "root" {
+		t.Errorf("unexpected bastion settings: %s:%d, %s", c.Comm.SSHBastionHost, c.Comm.SSHBastionPort,
+			c.Comm.SSHBastionUsername)
+	}
+	written, err := ioutil.ReadFile(c.Comm.SSHBastionPrivateKeyFile)
+	if err != nil || string(written) != "private key" {
+		t.Errorf("expected the key to be written, got %q: %v", written, err)
+	}
+
+	step.Cleanup(state)
+	if _, ok := sim.Droplet(step.bastionId); ok {
+		t.Error("expected the bastion to be destroyed")
+	}
+	if _, err := os.Stat(c.Comm.SSHBastionPrivateKeyFile); !os.IsNotExist(err) {
+		t.Errorf("expected the key file to be removed, got %v", err)
+	}
+}
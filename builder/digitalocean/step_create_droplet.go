@@ -3,36 +3,70 @@ package digitalocean
 import (
 	"context"
 	"fmt"
-	"log"
+	"net/http"
+	"os"
 	"strconv"
-
-	"io/ioutil"
+	"time"
 
 	"github.com/digitalocean/godo"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
-	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
+	"github.com/hashicorp/packer-plugin-sdk/uuid"
 )
 
+// createAttempts is how many times creating a droplet is tried when the API
+// fails or doesn't answer.
+const createAttempts = 3
+
+// keyPropagationTimeout is how long creating a droplet is retried while the
+// API rejects a temporary SSH key that was just created as unknown.
+const keyPropagationTimeout = time.Minute
+
 type stepCreateDroplet struct {
 	dropletId int
+	buildTag  string
 }
 
 func (s *stepCreateDroplet) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
 	client := state.Get("client").(*godo.Client)
-	ui := state.Get("ui").(packersdk.Ui)
+	ui := newStepUi(state, "create_droplet")
 	c := state.Get("config").(*Config)
 
-	sshKeys := []godo.DropletCreateSSHKey{}
-	sshKeyId, hasSSHkey := state.GetOk("ssh_key_id")
-	if hasSSHkey {
-		sshKeys = append(sshKeys, godo.DropletCreateSSHKey{
-			ID: sshKeyId.(int),
-		})
+	if cp, ok := resumedCheckpoint(state); ok {
+		ui.Say(fmt.Sprintf("Resuming the build with droplet %d...", cp.DropletID))
+		s.dropletId = cp.DropletID
+		trackCreated(state, resourceDroplet, cp.DropletID, "")
+		state.Put("droplet_id", cp.DropletID)
+		state.Put("droplet_created_at", time.Now())
+		state.Put("instance_id", cp.DropletID)
+		return multistep.ActionContinue
 	}
-	if c.SSHKeyID != 0 {
-		sshKeys = append(sshKeys, godo.DropletCreateSSHKey{
-			ID: c.SSHKeyID,
-		})
+
+	// The API allows it, but the droplets are then hard to tell apart
+	if droplets, err := findDropletsByName(client, c.DropletName); err != nil {
+		ui.Debugf("Error looking up droplets named %s: %s", c.DropletName, err)
+	} else if len(droplets) > 0 {
+		ui.Warn(fmt.Sprintf("Droplet %d is already named %s, the droplet of the build will have the same name",
+			droplets[0].ID, c.DropletName))
+	}
+
+	sshKeys := dropletSSHKeys(state, c)
+
+	// Without a VPC, private networking puts the droplet in whichever VPC
+	// the API picks, so the default VPC of the region is made explicit
+	defaultVPC := c.PrivateNetworking && c.VPCUUID == ""
+	vpcUUID := c.VPCUUID
+	if defaultVPC {
+		ui.Warn("private_networking without vpc_uuid is deprecated, set vpc_uuid to the VPC to create the droplet in")
+		vpc, err := findDefaultVPC(client, c.Region)
+		if err != nil {
+			err := fmt.Errorf("Error looking up the default VPC: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		ui.Say(fmt.Sprintf("Using the default VPC of %s, %s (%s)", c.Region, vpc.Name, vpc.ID))
+		vpcUUID = vpc.ID
 	}
 
 	// Create the droplet based on configuration
@@ -40,7 +74,7 @@ func (s *stepCreateDroplet) Run(ctx context.Context, state multistep.StateBag) m
 
 	userData := c.UserData
 	if c.UserDataFile != "" {
-		contents, err := ioutil.ReadFile(c.UserDataFile)
+		contents, err := readUserDataFile(c.UserDataFile, c.UserDataChecksum)
 		if err != nil {
 			state.Put("error", fmt.Errorf("Problem reading user data file: %s", err))
 			return multistep.ActionHalt
@@ -48,9 +82,64 @@ func (s *stepCreateDroplet) Run(ctx context.Context, state multistep.StateBag) m
 
 		userData = string(contents)
 	}
+	if c.UserDataCommand != "" {
+		ui.Say(fmt.Sprintf("Running user_data_command: %s", c.UserDataCommand))
+		output, err := runUserDataCommand(ctx, c.UserDataCommand, append(os.Environ(), hookEnv(state, c)...))
+		if err != nil {
+			err := fmt.Errorf("Error running user_data_command: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		userData = output
+	}
+	if len(c.UserDataVars) > 0 {
+		var err error
+		userData, err = substituteUserDataVars(userData, c.UserDataVars)
+		if err != nil {
+			err := fmt.Errorf("Error substituting user_data_vars: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	// User data of the builder's own, run before the user's
+	var generated []string
+	if c.PinSSHHostKey {
+		privatePEM, hostKey, err := generateHostKey()
+		if err != nil {
+			err := fmt.Errorf("Error generating SSH host key: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		generated = append(generated, hostKeyCloudConfig(privatePEM, hostKey))
+		state.Put("ssh_host_key", hostKey)
+	}
+	generated = append(generated, volumeMountScript(c.Volumes), winrmSetupScript(c))
+	userData, err := mergeUserData(append(generated, userData)...)
+	if err == nil && len(userData) > maxUserDataSize {
+		err = fmt.Errorf("%d bytes long, the maximum is %d", len(userData), maxUserDataSize)
+	}
+	if err != nil {
+		err := fmt.Errorf("Error generating user data: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
 
 	createImage := getImageType(c.Image)
 
+	// Tags the droplet as this build's, so that a retried create finds the
+	// droplet of a call whose response was lost
+	buildUUID := c.buildUUID
+	if buildUUID == "" {
+		buildUUID = uuid.TimeOrderedUUID()
+	}
+	buildTag := "packer-build:" + buildUUID
+	tags := append(append([]string(nil), c.Tags...), buildTag)
+
 	dropletCreateReq := &godo.DropletCreateRequest{
 		Name:              c.DropletName,
 		Region:            c.Region,
@@ -61,14 +150,49 @@ func (s *stepCreateDroplet) Run(ctx context.Context, state multistep.StateBag) m
 		Monitoring:        c.Monitoring,
 		IPv6:              c.IPv6,
 		UserData:          userData,
-		Tags:              c.Tags,
-		VPCUUID:           c.VPCUUID,
+		Tags:              tags,
+		VPCUUID:           vpcUUID,
+		WithDropletAgent:  c.DropletAgent.ToBoolPointer(),
+	}
+	if ids, ok := state.GetOk("volume_ids"); ok {
+		for _, id := range ids.([]string) {
+			dropletCreateReq.Volumes = append(dropletCreateReq.Volumes, godo.DropletCreateVolume{ID: id})
+		}
 	}
 
-	log.Printf("[DEBUG] Droplet create paramaters: %s", godo.Stringify(dropletCreateReq))
+	ui.Debugf("Droplet create paramaters: %s", stringifyCreateRequest(dropletCreateReq))
+
+	droplet, resp, err := createDroplet(client, ui, dropletCreateReq, buildTag)
 
-	droplet, _, err := client.Droplets.Create(context.TODO(), dropletCreateReq)
+	// With region = "auto", fall back to the next best region when this one
+	// can't take the droplet
+	candidates, _ := state.GetOk("region_candidates")
+	regions, _ := candidates.([]string)
+	for err != nil && resp != nil && resp.StatusCode == http.StatusUnprocessableEntity && len(regions) > 0 {
+		ui.Say(fmt.Sprintf("Unable to create droplet in %s, trying %s: %s", c.Region, regions[0], err))
+		c.Region, regions = regions[0], regions[1:]
+		dropletCreateReq.Region = c.Region
+		if defaultVPC {
+			vpc, vpcErr := findDefaultVPC(client, c.Region)
+			if vpcErr != nil {
+				err = fmt.Errorf("%s, and looking up the default VPC of %s failed: %s", err, c.Region, vpcErr)
+				break
+			}
+			dropletCreateReq.VPCUUID = vpc.ID
+		}
+		droplet, resp, err = createDroplet(client, ui, dropletCreateReq, buildTag)
+	}
+	if err != nil && createImage.Slug != "" {
+		// The API only reports a 422 for unknown images, so check whether
+		// that is why and point at the slugs that were probably meant.
+		if _, resp, imageErr := client.Images.GetBySlug(context.TODO(), createImage.Slug); imageErr != nil &&
+			resp != nil && resp.StatusCode == http.StatusNotFound {
+			err = imageNotFoundError(client, createImage.Slug)
+		}
+	}
 	if err != nil {
+		err = explainCreateError(err, dropletCreateReq)
+		err = withIncidents(c, err, c.Region, "Droplets")
 		err := fmt.Errorf("Error creating droplet: %s", err)
 		state.Put("error", err)
 		ui.Error(err.Error())
@@ -77,12 +201,22 @@ func (s *stepCreateDroplet) Run(ctx context.Context, state multistep.StateBag) m
 
 	// We use this in cleanup
 	s.dropletId = droplet.ID
+	s.buildTag = buildTag
+	trackCreated(state, resourceDroplet, droplet.ID, droplet.Name)
+	trackCreated(state, resourceTag, buildTag, "")
+	ui.Message(fmt.Sprintf("Created droplet %s (%d) in %s", droplet.Name, droplet.ID, c.Region))
 
 	// Store the droplet id for later
 	state.Put("droplet_id", droplet.ID)
+	// Droplets are billed from creation, this is used to estimate the cost
+	state.Put("droplet_created_at", time.Now())
 	// instance_id is the generic term used so that users can have access to the
 	// instance id inside of the provisioners, used in step_provision.
 	state.Put("instance_id", droplet.ID)
+	if c.PrivateNetworking {
+		generatedData := &packerbuilderdata.GeneratedData{State: state}
+		generatedData.Put("VPCUUID", dropletCreateReq.VPCUUID)
+	}
 
 	return multistep.ActionContinue
 }
@@ -93,16 +227,101 @@ func (s *stepCreateDroplet) Cleanup(state multistep.StateBag) {
 		return
 	}
 
+	// Kept to resume the build
+	if _, ok := state.GetOk("keep_droplet"); ok {
+		return
+	}
+
 	client := state.Get("client").(*godo.Client)
-	ui := state.Get("ui").(packersdk.Ui)
+	c := state.Get("config").(*Config)
+	ui := newStepUi(state, "create_droplet")
 
-	// Destroy the droplet we just created
-	ui.Say("Destroying droplet...")
-	_, err := client.Droplets.Delete(context.TODO(), s.dropletId)
+	// The build watchdog may already have destroyed it
+	if _, ok := state.GetOk("droplet_destroyed"); !ok {
+		// Destroy the droplet we just created
+		ui.Say("Destroying droplet...")
+		err := DestroyDroplet(client, s.dropletId, c.StateTimeout)
+		trackDeleted(state, resourceDroplet, s.dropletId, err)
+		if err != nil {
+			ui.Error(fmt.Sprintf(
+				"Error destroying droplet. Please destroy it manually: %s", err))
+			return
+		}
+	}
+
+	// The API created the build tag along with the droplet, and nothing
+	// else carries it
+	if s.buildTag == "" {
+		return
+	}
+	_, err := client.Tags.Delete(context.TODO(), s.buildTag)
+	trackDeleted(state, resourceTag, s.buildTag, err)
 	if err != nil {
 		ui.Error(fmt.Sprintf(
-			"Error destroying droplet. Please destroy it manually: %s", err))
+			"Error deleting tag %s. Please delete it manually: %s", s.buildTag, err))
+	}
+}
+
+// createDroplet creates the droplet, retrying when the API fails or doesn't
+// answer. The call may have gone through all the same, so before retrying
+// it adopts the droplet carrying buildTag, if there is one, rather than
+// creating another. An SSH key reported as not found is retried for up to
+// keyPropagationTimeout, since a key takes a moment to be known everywhere
+// once created.
+func createDroplet(client *godo.Client, ui *stepUi, req *godo.DropletCreateRequest, buildTag string) (*godo.Droplet, *godo.Response, error) {
+	var droplet *godo.Droplet
+	var resp *godo.Response
+	var err error
+	keyDeadline := time.Now().Add(keyPropagationTimeout)
+	for attempt := 0; attempt < createAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(pollInterval << uint(attempt))
+
+			droplets, _, listErr := client.Droplets.ListByTag(context.TODO(), buildTag, &godo.ListOptions{Page: 1, PerPage: 1})
+			if listErr != nil {
+				ui.Debugf("Error looking up droplet tagged %s: %s", buildTag, listErr)
+			} else if len(droplets) > 0 {
+				ui.Say(fmt.Sprintf("Droplet %d was created despite the error, using it", droplets[0].ID))
+				return &droplets[0], resp, nil
+			}
+		}
+
+		droplet, resp, err = client.Droplets.Create(context.TODO(), req)
+		for isSSHKeyNotFound(err) && time.Now().Before(keyDeadline) {
+			ui.Debugf("SSH key not known yet, retrying: %s", err)
+			time.Sleep(5 * pollInterval)
+			droplet, resp, err = client.Droplets.Create(context.TODO(), req)
+		}
+		if err == nil || (resp != nil && resp.StatusCode < http.StatusInternalServerError) {
+			return droplet, resp, err
+		}
+		ui.Debugf("Error creating droplet (attempt: %d): %s", attempt+1, err)
+	}
+	return droplet, resp, err
+}
+
+// dropletSSHKeys returns the keys droplets are created with: the temporary
+// one created for the build, if any, and the configured ssh_key_id and
+// ssh_key_ids.
+func dropletSSHKeys(state multistep.StateBag, c *Config) []godo.DropletCreateSSHKey {
+	sshKeys := []godo.DropletCreateSSHKey{}
+	sshKeyId, hasSSHkey := state.GetOk("ssh_key_id")
+	if hasSSHkey {
+		sshKeys = append(sshKeys, godo.DropletCreateSSHKey{
+			ID: sshKeyId.(int),
+		})
+	}
+	if c.SSHKeyID != 0 {
+		sshKeys = append(sshKeys, godo.DropletCreateSSHKey{
+			ID: c.SSHKeyID,
+		})
+	}
+	for _, id := range c.SSHKeyIDs {
+		sshKeys = append(sshKeys, godo.DropletCreateSSHKey{
+			ID: id,
+		})
 	}
+	return sshKeys
 }
 
 func getImageType(image string) godo.DropletCreateImage {
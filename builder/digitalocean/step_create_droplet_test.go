@@ -1,9 +1,19 @@
 package digitalocean
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-digitalocean/internal/simulator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 )
 
 func TestBuilder_GetImageType(t *testing.T) {
@@ -24,3 +34,219 @@ func TestBuilder_GetImageType(t *testing.T) {
 		})
 	}
 }
+
+func TestDropletSSHKeys(t *testing.T) {
+	state := new(multistep.BasicStateBag)
+	state.Put("ssh_key_id", 1001)
+	c := &Config{SSHKeyID: 1002, SSHKeyIDs: []int{1003, 1004}}
+
+	keys := dropletSSHKeys(state, c)
+	want := []godo.DropletCreateSSHKey{{ID: 1001}, {ID: 1002}, {ID: 1003}, {ID: 1004}}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("got %s, want %s", godo.Stringify(keys), godo.Stringify(want))
+	}
+}
+
+func TestStepCreateDroplet_UnknownImage(t *testing.T) {
+	_, client := testSimulator(t)
+
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("config", &Config{DropletName: "packer-test", Region: "nyc3", Size: "s-1vcpu-1gb", Image: "ubuntu-20-04-x46"})
+
+	step := new(stepCreateDroplet)
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("expected action halt, got %#v", action)
+	}
+	expected := "Error creating droplet: image ubuntu-20-04-x46 not found, did you mean ubuntu-20-04-x64?"
+	if err := state.Get("error").(error); err.Error() != expected {
+		t.Fatalf("got %q, expected %q", err, expected)
+	}
+}
+
+func TestStepCreateDroplet_RegionFallback(t *testing.T) {
+	sim, client := testSimulator(t)
+	sim.Inject(simulator.Fault{Method: http.MethodPost, Path: "/v2/droplets", Status: http.StatusUnprocessableEntity,
+		ID: "unprocessable_entity", Message: "Region is currently unavailable for the selected size.", Times: 1})
+
+	config := &Config{DropletName: "packer-test", Region: "nyc3", Size: "s-1vcpu-1gb", Image: "ubuntu-20-04-x64"}
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("config", config)
+	state.Put("region_candidates", []string{"sfo3"})
+
+	step := new(stepCreateDroplet)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected action continue, got %#v: %s", action, state.Get("error"))
+	}
+	if config.Region != "sfo3" {
+		t.Errorf("got region %s, expected sfo3", config.Region)
+	}
+	droplet, _, err := client.Droplets.Get(context.TODO(), state.Get("droplet_id").(int))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if droplet.Region.Slug != "sfo3" {
+		t.Errorf("droplet created in %s, expected sfo3", droplet.Region.Slug)
+	}
+}
+
+func TestStepCreateDroplet_Retry(t *testing.T) {
+	for _, handled := range []bool{true, false} {
+		sim, client := testSimulator(t)
+		// The response is lost, whether the droplet was created or not
+		sim.Inject(simulator.Fault{Method: http.MethodPost, Path: "/v2/droplets", Status: http.StatusGatewayTimeout,
+			ID: "gateway_timeout", Message: "Gateway timeout", Times: 1, Handled: handled})
+
+		state := new(multistep.BasicStateBag)
+		state.Put("client", client)
+		state.Put("ui", packersdk.TestUi(t))
+		state.Put("config", &Config{DropletName: "packer-test", Region: "nyc3", Size: "s-1vcpu-1gb", Image: "ubuntu-20-04-x64"})
+
+		step := new(stepCreateDroplet)
+		if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+			t.Fatalf("handled = %t: expected action continue, got %#v: %s", handled, action, state.Get("error"))
+		}
+		droplets := sim.Droplets()
+		if len(droplets) != 1 {
+			t.Fatalf("handled = %t: expected a single droplet, got %d", handled, len(droplets))
+		}
+		if id := state.Get("droplet_id").(int); id != droplets[0].ID {
+			t.Fatalf("handled = %t: expected droplet %d, got %d", handled, droplets[0].ID, id)
+		}
+	}
+}
+
+func TestStepCreateDroplet_BuildTag(t *testing.T) {
+	for _, destroyed := range []bool{false, true} {
+		sim, client := testSimulator(t)
+
+		state := new(multistep.BasicStateBag)
+		state.Put("client", client)
+		state.Put("ui", packersdk.TestUi(t))
+		state.Put("config", &Config{DropletName: "packer-test", Region: "nyc3", Size: "s-1vcpu-1gb", Image: "ubuntu-20-04-x64",
+			StateTimeout: time.Second, buildUUID: "6bf5a4c3"})
+
+		step := new(stepCreateDroplet)
+		if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+			t.Fatalf("destroyed = %t: expected action continue, got %#v: %s", destroyed, action, state.Get("error"))
+		}
+		droplet, _ := sim.Droplet(state.Get("droplet_id").(int))
+		if !containsString(droplet.Tags, "packer-build:6bf5a4c3") {
+			t.Fatalf("destroyed = %t: expected the droplet tagged with the build UUID, got %v", destroyed, droplet.Tags)
+		}
+
+		// Whoever destroyed the droplet, the tag goes with it
+		if destroyed {
+			state.Put("droplet_destroyed", true)
+		}
+		step.Cleanup(state)
+		if sim.Tag("packer-build:6bf5a4c3") {
+			t.Errorf("destroyed = %t: expected the build tag to be deleted", destroyed)
+		}
+	}
+}
+
+func TestStepCreateDroplet_UserDataTooLarge(t *testing.T) {
+	sim, client := testSimulator(t)
+
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("config", &Config{DropletName: "packer-test", Region: "nyc3", Size: "s-1vcpu-1gb", Image: "ubuntu-20-04-x64",
+		UserData: strings.Repeat("#", maxUserDataSize+1)})
+
+	step := new(stepCreateDroplet)
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("expected action halt, got %#v", action)
+	}
+	if err := state.Get("error").(error); !strings.Contains(err.Error(), "the maximum is 65536") {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if droplets := sim.Droplets(); len(droplets) != 0 {
+		t.Errorf("expected no droplet, got %d", len(droplets))
+	}
+}
+
+func TestStepCreateDroplet_DuplicateName(t *testing.T) {
+	sim, client := testSimulator(t)
+	existing := sim.AddDroplet(godo.Droplet{Name: "packer-test", Region: &godo.Region{Slug: "nyc3"}})
+	sim.AddDroplet(godo.Droplet{Name: "packer-other", Region: &godo.Region{Slug: "nyc3"}})
+
+	var out bytes.Buffer
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("ui", &packersdk.BasicUi{Writer: &out, ErrorWriter: &out})
+	state.Put("config", &Config{DropletName: "packer-test", Region: "nyc3", Size: "s-1vcpu-1gb", Image: "ubuntu-20-04-x64"})
+
+	step := new(stepCreateDroplet)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected action continue, got %#v: %s", action, state.Get("error"))
+	}
+	expected := fmt.Sprintf("Droplet %d is already named packer-test", existing.ID)
+	if !strings.Contains(out.String(), expected) {
+		t.Errorf("expected a warning about droplet %d, got:\n%s", existing.ID, out.String())
+	}
+}
+
+func TestStepCreateDroplet_SSHKeyNotFound(t *testing.T) {
+	sim, client := testSimulator(t)
+	// The key was just created, and isn't known everywhere yet
+	sim.Inject(simulator.Fault{Method: http.MethodPost, Path: "/v2/droplets", Status: http.StatusUnprocessableEntity,
+		ID: "unprocessable_entity", Message: "The ssh key(s) you specified could not be found.", Times: 2})
+
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("config", &Config{DropletName: "packer-test", Region: "nyc3", Size: "s-1vcpu-1gb", Image: "ubuntu-20-04-x64"})
+
+	step := new(stepCreateDroplet)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected action continue, got %#v: %s", action, state.Get("error"))
+	}
+	if droplets := sim.Droplets(); len(droplets) != 1 {
+		t.Fatalf("expected a single droplet, got %d", len(droplets))
+	}
+
+	// Other errors are not retried
+	if isSSHKeyNotFound(&godo.ErrorResponse{Response: &http.Response{StatusCode: http.StatusUnprocessableEntity},
+		Message: "Name is invalid."}) {
+		t.Error("expected an invalid name not to be taken for an unknown SSH key")
+	}
+}
+
+func TestStepCreateDroplet_DefaultVPC(t *testing.T) {
+	sim, client := testSimulator(t)
+	sim.AddVPC(godo.VPC{Name: "default-ams3", RegionSlug: "ams3", Default: true})
+	sim.AddVPC(godo.VPC{Name: "apps-nyc3", RegionSlug: "nyc3"})
+	vpc := sim.AddVPC(godo.VPC{Name: "default-nyc3", RegionSlug: "nyc3", Default: true})
+
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("config", &Config{DropletName: "packer-test", Region: "nyc3", Size: "s-1vcpu-1gb", Image: "ubuntu-20-04-x64",
+		PrivateNetworking: true})
+
+	step := new(stepCreateDroplet)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected action continue, got %#v: %s", action, state.Get("error"))
+	}
+	droplet, _ := sim.Droplet(state.Get("droplet_id").(int))
+	if droplet.VPCUUID != vpc.ID {
+		t.Errorf("expected the droplet in the default VPC %s, got %s", vpc.ID, droplet.VPCUUID)
+	}
+	generated := state.Get("generated_data").(map[string]interface{})
+	if generated["VPCUUID"] != vpc.ID {
+		t.Errorf("expected VPCUUID in the generated data, got %#v", generated)
+	}
+
+	// Without a default VPC in the region
+	state.Put("config", &Config{DropletName: "packer-test", Region: "sfo3", Size: "s-1vcpu-1gb", Image: "ubuntu-20-04-x64",
+		PrivateNetworking: true})
+	step = new(stepCreateDroplet)
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("expected action halt, got %#v", action)
+	}
+}
@@ -3,38 +3,86 @@ package digitalocean
 import (
 	"context"
 	"fmt"
-	"log"
+	"net/http"
+	"os"
+	"strings"
 
 	"github.com/digitalocean/godo"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
-	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/uuid"
+	gossh "golang.org/x/crypto/ssh"
 )
 
 type stepCreateSSHKey struct {
 	keyId int
+	// Set when the build uses the temporary key shared by the run
+	shared *keyManager
 }
 
 func (s *stepCreateSSHKey) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
 	client := state.Get("client").(*godo.Client)
-	ui := state.Get("ui").(packersdk.Ui)
+	ui := newStepUi(state, "create_ssh_key")
 	c := state.Get("config").(*Config)
 
+	if cp, ok := resumedCheckpoint(state); ok {
+		// The droplet of the previous run was created with this key
+		if cp.SSHKeyID != 0 {
+			s.keyId = cp.SSHKeyID
+			trackCreated(state, resourceSSHKey, cp.SSHKeyID, "")
+			state.Put("ssh_key_id", cp.SSHKeyID)
+		}
+		return multistep.ActionContinue
+	}
+
 	if c.Comm.SSHPublicKey == nil {
 		ui.Say("No public SSH key found; skipping SSH public key import...")
 		return multistep.ActionContinue
 	}
 
-	ui.Say("Importing SSH public key...")
-
 	// The name of the public key on DO
-	name := fmt.Sprintf("packer-%s", uuid.TimeOrderedUUID())
+	name := temporaryKeyName(c)
+
+	if c.SharedTemporaryKey {
+		ui.Say("Using SSH key shared by the builds of this run...")
+		manager, err := runKeyManager(c.APIURL, c.APIToken)
+		var key *sharedKey
+		if err == nil {
+			key, err = manager.acquire(client, name, c.Comm.SSHPrivateKey, c.Comm.SSHPublicKey)
+		}
+		if err != nil {
+			err := fmt.Errorf("Error creating shared SSH key: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		s.shared = manager
+		trackCreated(state, resourceSSHKey, key.ID, name)
+		// The key pair generated for this build is dropped, unless it is
+		// the first build of the run
+		c.Comm.SSHPrivateKey = key.PrivateKey
+		c.Comm.SSHPublicKey = key.PublicKey
+		state.Put("ssh_key_id", key.ID)
+		return multistep.ActionContinue
+	}
+
+	ui.Say("Importing SSH public key...")
 
 	// Create the key!
-	key, _, err := client.Keys.Create(context.TODO(), &godo.KeyCreateRequest{
+	key, resp, err := client.Keys.Create(context.TODO(), &godo.KeyCreateRequest{
 		Name:      name,
 		PublicKey: string(c.Comm.SSHPublicKey),
 	})
+	if err != nil && resp != nil && resp.StatusCode == http.StatusUnprocessableEntity {
+		// An account holds a public key once, so a concurrent build using
+		// the same key pair may have imported it already. That key is
+		// used, and left for that build to delete.
+		existing, findErr := findKeyByPublicKey(client, c.Comm.SSHPublicKey)
+		if findErr == nil && existing != nil {
+			ui.Say(fmt.Sprintf("Using SSH key %s (%d), which the public key is already imported as", existing.Name, existing.ID))
+			state.Put("ssh_key_id", existing.ID)
+			return multistep.ActionContinue
+		}
+	}
 	if err != nil {
 		err := fmt.Errorf("Error creating temporary SSH key: %s", err)
 		state.Put("error", err)
@@ -44,8 +92,9 @@ func (s *stepCreateSSHKey) Run(ctx context.Context, state multistep.StateBag) mu
 
 	// We use this to check cleanup
 	s.keyId = key.ID
+	trackCreated(state, resourceSSHKey, key.ID, name)
 
-	log.Printf("temporary ssh key name: %s", name)
+	ui.Debugf("temporary ssh key name: %s", name)
 
 	// Remember some state for the future
 	state.Put("ssh_key_id", key.ID)
@@ -54,19 +103,95 @@ func (s *stepCreateSSHKey) Run(ctx context.Context, state multistep.StateBag) mu
 }
 
 func (s *stepCreateSSHKey) Cleanup(state multistep.StateBag) {
+	if s.shared != nil {
+		client := state.Get("client").(*godo.Client)
+		ui := newStepUi(state, "create_ssh_key")
+		err := s.shared.release(client)
+		if err != nil {
+			trackDeleted(state, resourceSSHKey, state.Get("ssh_key_id"), err)
+			ui.Error(fmt.Sprintf(
+				"Error releasing shared ssh key. Please delete the key manually: %s", err))
+		} else {
+			// Deleted by the last build of the run using it
+			trackDisposition(state, resourceSSHKey, state.Get("ssh_key_id"), resourceReleased, nil)
+		}
+		return
+	}
+
 	// If no key name is set, then we never created it, so just return
 	if s.keyId == 0 {
 		return
 	}
 
+	// Kept along with the droplet to resume the build
+	if _, ok := state.GetOk("keep_droplet"); ok {
+		return
+	}
+
 	client := state.Get("client").(*godo.Client)
-	ui := state.Get("ui").(packersdk.Ui)
+	ui := newStepUi(state, "create_ssh_key")
+	c := state.Get("config").(*Config)
+
+	if c.SkipKeyCleanup {
+		ui.Say(fmt.Sprintf("Keeping temporary ssh key %d, as skip_key_cleanup is set", s.keyId))
+		return
+	}
 
 	ui.Say("Deleting temporary ssh key...")
 	_, err := client.Keys.DeleteByID(context.TODO(), s.keyId)
+	trackDeleted(state, resourceSSHKey, s.keyId, err)
 	if err != nil {
-		log.Printf("Error cleaning up ssh key: %s", err)
+		ui.Debugf("Error cleaning up ssh key: %s", err)
 		ui.Error(fmt.Sprintf(
 			"Error cleaning up ssh key. Please delete the key manually: %s", err))
 	}
 }
+
+// temporaryKeyName returns the name of the temporary SSH key of a build,
+// unique among the concurrent builds of the account: the build name, and
+// the Packer run UUID and build UUID when set, such as
+// packer-web-8c3a3994-0655e5e4-a1a5-57a6-e2fc-63413fba2e29.
+func temporaryKeyName(c *Config) string {
+	parts := []string{"packer"}
+	if c.PackerBuildName != "" {
+		parts = append(parts, c.PackerBuildName)
+	}
+	if run := os.Getenv("PACKER_RUN_UUID"); run != "" {
+		parts = append(parts, strings.SplitN(run, "-", 2)[0])
+	}
+	buildUUID := c.buildUUID
+	if buildUUID == "" {
+		buildUUID = uuid.TimeOrderedUUID()
+	}
+	return strings.Join(append(parts, buildUUID), "-")
+}
+
+// findKeyByPublicKey returns the SSH key of the account holding the public
+// key, or nil if there is none.
+func findKeyByPublicKey(client *godo.Client, publicKey []byte) (*godo.Key, error) {
+	want, _, _, _, err := gossh.ParseAuthorizedKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	opt := &godo.ListOptions{
+		Page:    1,
+		PerPage: 200,
+	}
+	for {
+		keys, resp, err := client.Keys.List(context.TODO(), opt)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			got, _, _, _, err := gossh.ParseAuthorizedKey([]byte(key.PublicKey))
+			if err == nil && string(got.Marshal()) == string(want.Marshal()) {
+				key := key
+				return &key, nil
+			}
+		}
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			return nil, nil
+		}
+		opt.Page++
+	}
+}
@@ -0,0 +1,116 @@
+package digitalocean
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+func testPublicKey(t *testing.T) []byte {
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := gossh.NewPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gossh.MarshalAuthorizedKey(key)
+}
+
+func TestStepCreateSSHKey_Concurrent(t *testing.T) {
+	t.Setenv("PACKER_RUN_UUID", "8c3a3994-09b1-4f1e-b6b5-5c0a4b1ebd4f")
+	sim, client := testSimulator(t)
+
+	shared := testPublicKey(t)
+	run := func(buildUUID string, publicKey []byte) (*stepCreateSSHKey, multistep.StateBag) {
+		state := new(multistep.BasicStateBag)
+		state.Put("client", client)
+		state.Put("ui", packersdk.TestUi(t))
+		state.Put("config", &Config{
+			PackerConfig: common.PackerConfig{PackerBuildName: "web"},
+			Comm:         communicator.Config{SSH: communicator.SSH{SSHPublicKey: publicKey}},
+			buildUUID:    buildUUID,
+		})
+		step := new(stepCreateSSHKey)
+		if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+			t.Fatalf("expected action continue, got %#v: %s", action, state.Get("error"))
+		}
+		return step, state
+	}
+
+	first, firstState := run("0655e5e4-a1a5", testPublicKey(t))
+	second, secondState := run("0655e5e5-b2b6", shared)
+	third, thirdState := run("0655e5e6-c3c7", shared)
+
+	keys := sim.Keys()
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 keys, got %#v", keys)
+	}
+	if keys[0].Name != "packer-web-8c3a3994-0655e5e4-a1a5" || keys[1].Name != "packer-web-8c3a3994-0655e5e5-b2b6" {
+		t.Errorf("unexpected key names %s and %s", keys[0].Name, keys[1].Name)
+	}
+	if thirdState.Get("ssh_key_id") != second.keyId {
+		t.Errorf("expected the build importing the same public key to use key %d, got %v",
+			second.keyId, thirdState.Get("ssh_key_id"))
+	}
+
+	// The build reusing the key leaves it to the one that created it
+	third.Cleanup(thirdState)
+	if _, ok := sim.Key(second.keyId); !ok {
+		t.Fatal("expected the key to be kept for the build that created it")
+	}
+	second.Cleanup(secondState)
+	if _, ok := sim.Key(second.keyId); ok {
+		t.Error("expected the key to be deleted")
+	}
+	if _, ok := sim.Key(first.keyId); !ok {
+		t.Error("expected the key of the other build to be kept")
+	}
+	first.Cleanup(firstState)
+	if len(sim.Keys()) != 0 {
+		t.Errorf("expected every key to be deleted, got %#v", sim.Keys())
+	}
+}
+
+func TestStepCreateSSHKey_SkipKeyCleanup(t *testing.T) {
+	for _, skip := range []bool{false, true} {
+		sim, client := testSimulator(t)
+
+		state := new(multistep.BasicStateBag)
+		state.Put("client", client)
+		state.Put("ui", packersdk.TestUi(t))
+		state.Put("config", &Config{
+			Comm:           communicator.Config{SSH: communicator.SSH{SSHPublicKey: testPublicKey(t)}},
+			SkipKeyCleanup: skip,
+		})
+		step := new(stepCreateSSHKey)
+		if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+			t.Fatalf("expected action continue, got %#v: %s", action, state.Get("error"))
+		}
+		step.Cleanup(state)
+
+		if keys := sim.Keys(); (len(keys) == 1) != skip {
+			t.Errorf("skip_key_cleanup = %t, but found keys %#v", skip, keys)
+		}
+	}
+}
+
+func TestTemporaryKeyName(t *testing.T) {
+	t.Setenv("PACKER_RUN_UUID", "")
+	name := temporaryKeyName(&Config{})
+	if !strings.HasPrefix(name, "packer-") || name == temporaryKeyName(&Config{}) {
+		t.Errorf("expected a unique name, got %s", name)
+	}
+	if name := temporaryKeyName(&Config{buildUUID: "0655e5e4"}); name != "packer-0655e5e4" {
+		t.Errorf("got %s", name)
+	}
+}
@@ -0,0 +1,106 @@
+package digitalocean
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// debugBundleCommands are the commands whose output makes up the debug
+// bundle, by the name of the file holding it.
+var debugBundleCommands = []struct {
+	name    string
+	command string
+}{
+	{"dmesg.txt", "dmesg"},
+	{"journal.txt", "journalctl -b --no-pager"},
+	{"network.txt", "sh -c 'ip addr; ip route; cat /etc/resolv.conf'"},
+	{"cloud-init-status.txt", "cloud-init status --long"},
+}
+
+// stepDebugBundle saves a diagnostics bundle of the droplet when a later
+// step fails. It does nothing until its cleanup, which runs once every
+// later step is cleaned up, while the droplet is still there.
+type stepDebugBundle struct{}
+
+func (s *stepDebugBundle) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	return multistep.ActionContinue
+}
+
+func (s *stepDebugBundle) Cleanup(state multistep.StateBag) {
+	if _, ok := state.GetOk("error"); !ok {
+		return
+	}
+	comm, ok := state.GetOk("communicator")
+	if !ok {
+		return
+	}
+
+	ui := newStepUi(state, "debug_bundle")
+	c := state.Get("config").(*Config)
+
+	ui.Say("Collecting debug bundle...")
+	path := filepath.Join(c.DebugBundleDir, c.DropletName+"-debug.tar.gz")
+	if err := writeDebugBundle(comm.(packersdk.Communicator), c, path); err != nil {
+		ui.Error(fmt.Sprintf("Error collecting debug bundle: %s", err))
+		return
+	}
+	ui.Message(fmt.Sprintf("Saved debug bundle to %s", path))
+}
+
+func writeDebugBundle(comm packersdk.Communicator, c *Config, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	sudo := ""
+	if c.Comm.SSHUsername != "root" {
+		sudo = "sudo "
+	}
+	for _, entry := range debugBundleCommands {
+		// Whatever the command printed is kept, failed or not
+		var output, stderr bytes.Buffer
+		cmd := &packersdk.RemoteCmd{Command: sudo + entry.command, Stdout: &output, Stderr: &stderr}
+		if err := comm.Start(context.TODO(), cmd); err != nil {
+			logf(levelWarn, []interface{}{"step", "debug_bundle"}, "Unable to run %s: %s", entry.command, err)
+			continue
+		}
+		cmd.Wait()
+		output.Write(stderr.Bytes())
+
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    entry.name,
+			Mode:    0644,
+			Size:    int64(output.Len()),
+			ModTime: time.Now(),
+		}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(output.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
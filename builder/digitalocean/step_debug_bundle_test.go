@@ -0,0 +1,82 @@
+package digitalocean
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestStepDebugBundle(t *testing.T) {
+	for _, failed := range []bool{false, true} {
+		dir := t.TempDir()
+		comm := &packersdk.MockCommunicator{StartStdout: "output\n"}
+
+		state := new(multistep.BasicStateBag)
+		state.Put("ui", packersdk.TestUi(t))
+		state.Put("communicator", comm)
+		state.Put("config", &Config{
+			DropletName:    "packer-test",
+			DebugBundleDir: dir,
+			Comm:           communicator.Config{SSH: communicator.SSH{SSHUsername: "root"}},
+		})
+		if failed {
+			state.Put("error", errors.New("Script exited with non-zero exit status: 1."))
+		}
+
+		step := new(stepDebugBundle)
+		if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+			t.Fatalf("expected action continue, got %#v", action)
+		}
+		step.Cleanup(state)
+
+		path := filepath.Join(dir, "packer-test-debug.tar.gz")
+		if !failed {
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Fatalf("expected no bundle for a successful build, got %v", err)
+			}
+			continue
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		defer f.Close()
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		tr := tar.NewReader(gz)
+		var names []string
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			data, err := ioutil.ReadAll(tr)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if string(data) != "output\n" {
+				t.Fatalf("expected the command output in %s, got %q", header.Name, data)
+			}
+			names = append(names, header.Name)
+		}
+		if len(names) != len(debugBundleCommands) {
+			t.Fatalf("expected %d files, got %q", len(debugBundleCommands), names)
+		}
+	}
+}
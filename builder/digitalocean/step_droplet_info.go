@@ -3,24 +3,26 @@ package digitalocean
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/digitalocean/godo"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
-	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 )
 
 type stepDropletInfo struct{}
 
 func (s *stepDropletInfo) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
 	client := state.Get("client").(*godo.Client)
-	ui := state.Get("ui").(packersdk.Ui)
+	ui := newStepUi(state, "droplet_info")
 	c := state.Get("config").(*Config)
 	dropletID := state.Get("droplet_id").(int)
 
 	ui.Say("Waiting for droplet to become active...")
 
-	err := waitForDropletState("active", dropletID, client, c.StateTimeout)
+	err := WaitForDropletState("active", dropletID, client, activeTimeout(client, state, c))
 	if err != nil {
+		err = withIncidents(c, err, c.Region, "Droplets")
 		err := fmt.Errorf("Error waiting for droplet to become active: %s", err)
 		state.Put("error", err)
 		ui.Error(err.Error())
@@ -47,21 +49,16 @@ func (s *stepDropletInfo) Run(ctx context.Context, state multistep.StateBag) mul
 	}
 
 	// Find the ip address which will be used by communicator
-	foundNetwork := false
-	for _, network := range droplet.Networks.V4 {
-		if (c.ConnectWithPrivateIP && network.Type == "private") ||
-			(!(c.ConnectWithPrivateIP) && network.Type == "public") {
-			state.Put("droplet_ip", network.IPAddress)
-			foundNetwork = true
-			break
-		}
-	}
+	ip, foundNetwork := dropletIP(droplet, c.ConnectWithPrivateIP)
 	if !foundNetwork {
 		err := fmt.Errorf("Count not find a public IPv4 address for this droplet")
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
 	}
+	ui.Message(fmt.Sprintf("Droplet addresses: %s", strings.Join(dropletAddresses(droplet), ", ")))
+	ui.Message(fmt.Sprintf("Connecting to %s", ip))
+	state.Put("droplet_ip", ip)
 
 	return multistep.ActionContinue
 }
@@ -69,3 +66,54 @@ func (s *stepDropletInfo) Run(ctx context.Context, state multistep.StateBag) mul
 func (s *stepDropletInfo) Cleanup(state multistep.StateBag) {
 	// no cleanup
 }
+
+// dropletIP returns the private or public IPv4 address of a droplet.
+func dropletIP(droplet *godo.Droplet, private bool) (string, bool) {
+	if droplet.Networks == nil {
+		return "", false
+	}
+	for _, network := range droplet.Networks.V4 {
+		if (private && network.Type == "private") ||
+			(!private && network.Type == "public") {
+			return network.IPAddress, true
+		}
+	}
+	return "", false
+}
+
+// dropletAddresses lists every address of a droplet along with its type,
+// such as "public 192.0.2.10".
+func dropletAddresses(droplet *godo.Droplet) []string {
+	var addresses []string
+	if droplet.Networks == nil {
+		return addresses
+	}
+	for _, network := range droplet.Networks.V4 {
+		addresses = append(addresses, network.Type+" "+network.IPAddress)
+	}
+	for _, network := range droplet.Networks.V6 {
+		addresses = append(addresses, network.Type+" "+network.IPAddress)
+	}
+	return addresses
+}
+
+// customImageTimeoutPerGB is the extra time droplets created from custom
+// images are given to become active, per gigabyte of the image.
+const customImageTimeoutPerGB = time.Minute
+
+// activeTimeout returns how long to wait for the droplet to become active.
+func activeTimeout(client *godo.Client, state multistep.StateBag, c *Config) time.Duration {
+	if c.ActiveTimeout != 0 {
+		return c.ActiveTimeout
+	}
+	image, err := sourceImage(client, state)
+	if err != nil {
+		logf(levelDebug, []interface{}{"step", "droplet_info"}, "Unable to retrieve source image size: %s", err)
+		return c.StateTimeout
+	}
+	if image == nil || image.Type != "custom" {
+		return c.StateTimeout
+	}
+	extra := time.Duration(image.SizeGigaBytes * float64(customImageTimeoutPerGB))
+	return c.StateTimeout + extra.Round(time.Second)
+}
@@ -0,0 +1,55 @@
+package digitalocean
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+func TestDropletAddresses(t *testing.T) {
+	droplet := &godo.Droplet{Networks: &godo.Networks{
+		V4: []godo.NetworkV4{
+			{IPAddress: "192.0.2.10", Type: "public"},
+			{IPAddress: "10.10.0.2", Type: "private"},
+		},
+		V6: []godo.NetworkV6{{IPAddress: "2001:db8::10", Type: "public"}},
+	}}
+
+	expected := []string{"public 192.0.2.10", "private 10.10.0.2", "public 2001:db8::10"}
+	if addresses := dropletAddresses(droplet); !reflect.DeepEqual(addresses, expected) {
+		t.Fatalf("expected %q, got %q", expected, addresses)
+	}
+	if addresses := dropletAddresses(&godo.Droplet{}); len(addresses) != 0 {
+		t.Fatalf("expected no addresses, got %q", addresses)
+	}
+}
+
+func TestActiveTimeout(t *testing.T) {
+	sim, client := testSimulator(t)
+	custom := sim.AddImage(godo.Image{Name: "corp-debian-11", Type: "custom", SizeGigaBytes: 12.5})
+	snapshot := sim.AddImage(godo.Image{Name: "base", Type: "snapshot", SizeGigaBytes: 40})
+
+	tt := []struct {
+		Image    string
+		Override time.Duration
+		Expected time.Duration
+	}{
+		{Image: "ubuntu-20-04-x64", Expected: 6 * time.Minute},
+		{Image: strconv.Itoa(snapshot.ID), Expected: 6 * time.Minute},
+		{Image: strconv.Itoa(custom.ID), Expected: 18*time.Minute + 30*time.Second},
+		{Image: strconv.Itoa(custom.ID), Override: time.Hour, Expected: time.Hour},
+	}
+	for _, tc := range tt {
+		state := new(multistep.BasicStateBag)
+		c := &Config{Image: tc.Image, StateTimeout: 6 * time.Minute, ActiveTimeout: tc.Override}
+		state.Put("config", c)
+
+		if timeout := activeTimeout(client, state, c); timeout != tc.Expected {
+			t.Fatalf("%s: expected %s, got %s", tc.Image, tc.Expected, timeout)
+		}
+	}
+}
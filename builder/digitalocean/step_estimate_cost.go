@@ -0,0 +1,67 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+// snapshotPricePerGBMonth is the published price of snapshot storage in USD
+// per gigabyte per month, charged for each region the snapshot is in.
+const snapshotPricePerGBMonth = 0.06
+
+type stepEstimateCost struct{}
+
+func (s *stepEstimateCost) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*godo.Client)
+	ui := newStepUi(state, "estimate_cost")
+	c := state.Get("config").(*Config)
+
+	// The estimate is informational only, so failing to compute it never
+	// fails the build.
+	createdAt, ok := state.GetOk("droplet_created_at")
+	if !ok {
+		return multistep.ActionContinue
+	}
+	size, err := findSize(client, c.Size)
+	if err != nil {
+		ui.Debugf("Unable to estimate build cost: %s", err)
+		return multistep.ActionContinue
+	}
+
+	duration := time.Since(createdAt.(time.Time))
+	dropletCost := size.PriceHourly * duration.Hours()
+	estimate := map[string]interface{}{
+		"currency":      "USD",
+		"droplet_size":  size.Slug,
+		"droplet_hours": duration.Hours(),
+		"droplet_cost":  dropletCost,
+		"price_hourly":  size.PriceHourly,
+	}
+	ui.Say(fmt.Sprintf("Estimated build cost: $%.4f (%s for %s at $%.5f/hour)",
+		dropletCost, size.Slug, duration.Round(time.Second), size.PriceHourly))
+
+	if imageId, ok := state.GetOk("snapshot_image_id"); ok {
+		image, _, err := client.Images.GetByID(context.TODO(), imageId.(int))
+		if err != nil {
+			ui.Debugf("Unable to estimate snapshot storage cost: %s", err)
+		} else {
+			regions := len(state.Get("regions").([]string))
+			storageCost := image.SizeGigaBytes * snapshotPricePerGBMonth * float64(regions)
+			estimate["snapshot_gigabytes"] = image.SizeGigaBytes
+			estimate["snapshot_monthly_cost"] = storageCost
+			ui.Say(fmt.Sprintf("Estimated snapshot storage cost: $%.2f/month (%.2f GB in %d region(s))",
+				storageCost, image.SizeGigaBytes, regions))
+		}
+	}
+
+	state.Put("estimated_cost", estimate)
+	return multistep.ActionContinue
+}
+
+func (s *stepEstimateCost) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
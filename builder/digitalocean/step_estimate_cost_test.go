@@ -0,0 +1,56 @@
+package digitalocean
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestStepEstimateCost(t *testing.T) {
+	sim, client := testSimulator(t)
+	image := sim.AddImage(godo.Image{Name: "packer-test", Type: "snapshot", SizeGigaBytes: 10, Regions: []string{"nyc3", "sfo3"}})
+
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("config", &Config{Size: "s-2vcpu-2gb"})
+	state.Put("droplet_created_at", time.Now().Add(-30*time.Minute))
+	state.Put("snapshot_image_id", image.ID)
+	state.Put("regions", []string{"nyc3", "sfo3"})
+
+	step := new(stepEstimateCost)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	estimate := state.Get("estimated_cost").(map[string]interface{})
+	if cost := estimate["droplet_cost"].(float64); math.Abs(cost-0.01116) > 0.0001 {
+		t.Fatalf("unexpected droplet cost: %f", cost)
+	}
+	if cost := estimate["snapshot_monthly_cost"].(float64); math.Abs(cost-1.2) > 0.0001 {
+		t.Fatalf("unexpected snapshot cost: %f", cost)
+	}
+}
+
+func TestStepEstimateCost_UnknownSize(t *testing.T) {
+	_, client := testSimulator(t)
+
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("config", &Config{Size: "gd-40vcpu-160gb"})
+	state.Put("droplet_created_at", time.Now())
+
+	step := new(stepEstimateCost)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("estimated_cost"); ok {
+		t.Fatal("no estimate should be stored for an unknown size")
+	}
+}
@@ -0,0 +1,64 @@
+package digitalocean
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// filesystemDigestCommand hashes the sha256sum manifest of every regular
+// file of the root filesystem, in byte order of their paths, leaving out
+// the directories whose content changes as the droplet shuts down or boots.
+const filesystemDigestCommand = `sh -c 'cd / && find . -xdev \( -path ./tmp -o -path ./var/tmp -o -path ./var/log \) -prune -o -type f -print0 | LC_ALL=C sort -z | xargs -0 -r sha256sum | sha256sum'`
+
+var sha256Re = regexp.MustCompile("^[0-9a-f]{64}$")
+
+// stepFilesystemDigest computes the digest of the root filesystem as it is
+// about to be snapshotted. The API can't boot a droplet into recovery mode,
+// so this runs on the droplet itself, once nothing else is left to change
+// the disk.
+type stepFilesystemDigest struct{}
+
+func (s *stepFilesystemDigest) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	comm := state.Get("communicator").(packersdk.Communicator)
+	ui := newStepUi(state, "filesystem_digest")
+	c := state.Get("config").(*Config)
+
+	ui.Say("Computing the digest of the root filesystem...")
+	command := filesystemDigestCommand
+	if c.Comm.SSHUsername != "root" {
+		command = "sudo " + command
+	}
+	var stdout bytes.Buffer
+	cmd := &packersdk.RemoteCmd{Command: command, Stdout: &stdout}
+	if err := cmd.RunWithUi(ctx, comm, ui); err != nil {
+		err := fmt.Errorf("Error computing the filesystem digest: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	fields := strings.Fields(stdout.String())
+	if cmd.ExitStatus() != 0 || len(fields) == 0 || !sha256Re.MatchString(fields[0]) {
+		err := fmt.Errorf("Computing the filesystem digest failed, exit status %d: %q",
+			cmd.ExitStatus(), strings.TrimSpace(stdout.String()))
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	digest := "sha256:" + fields[0]
+	ui.Message(fmt.Sprintf("Filesystem digest: %s", digest))
+	state.Put("filesystem_digest", digest)
+	// Tags the snapshot with the digest, which fits the tag syntax
+	c.SnapshotTags = append(c.SnapshotTags, digest)
+	return multistep.ActionContinue
+}
+
+func (s *stepFilesystemDigest) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
@@ -0,0 +1,57 @@
+package digitalocean
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestStepFilesystemDigest(t *testing.T) {
+	digest := strings.Repeat("9f86d081", 8)
+	cases := []struct {
+		name   string
+		stdout string
+		status int
+		action multistep.StepAction
+	}{
+		{"digest", digest + "  -\n", 0, multistep.ActionContinue},
+		{"failed", "", 1, multistep.ActionHalt},
+		{"garbage", "sha256sum: command not found\n", 0, multistep.ActionHalt},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			comm := &packersdk.MockCommunicator{StartStdout: tt.stdout, StartExitStatus: tt.status}
+
+			c := &Config{SnapshotTags: []string{"golden"}}
+			c.Comm = communicator.Config{SSH: communicator.SSH{SSHUsername: "ubuntu"}}
+			state := new(multistep.BasicStateBag)
+			state.Put("communicator", comm)
+			state.Put("ui", packersdk.TestUi(t))
+			state.Put("config", c)
+
+			step := new(stepFilesystemDigest)
+			if action := step.Run(context.Background(), state); action != tt.action {
+				t.Fatalf("expected action %#v, got %#v", tt.action, action)
+			}
+			if comm.StartCmd.Command != "sudo "+filesystemDigestCommand {
+				t.Errorf("unexpected command %q", comm.StartCmd.Command)
+			}
+			if tt.action != multistep.ActionContinue {
+				return
+			}
+			if got := state.Get("filesystem_digest"); got != "sha256:"+digest {
+				t.Errorf("unexpected digest %v", got)
+			}
+			if len(c.SnapshotTags) != 2 || c.SnapshotTags[1] != "sha256:"+digest {
+				t.Errorf("expected the digest in the snapshot tags, got %v", c.SnapshotTags)
+			}
+			if err := ValidateTag(c.SnapshotTags[1]); err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		})
+	}
+}
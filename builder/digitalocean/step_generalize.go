@@ -0,0 +1,80 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+const generalizeScriptPath = "/tmp/packer-generalize.sh"
+
+// generalizeScript removes everything that ties the droplet to this build,
+// so that droplets created from the snapshot come up as new machines.
+// Commands are best effort, as not every image has every file.
+const generalizeScript = `#!/bin/sh
+%s
+rm -f /etc/ssh/ssh_host_*
+if [ -f /etc/machine-id ]; then truncate -s 0 /etc/machine-id; fi
+if [ -f /var/lib/dbus/machine-id ] && [ ! -L /var/lib/dbus/machine-id ]; then rm -f /var/lib/dbus/machine-id; fi
+if command -v cloud-init >/dev/null 2>&1; then cloud-init clean --logs; fi
+find /var/log -type f -exec truncate -s 0 {} \;
+rm -f /root/.bash_history /home/*/.bash_history
+rm -f "$0"
+exit 0
+`
+
+// stepGeneralize prepares the droplet for being snapshotted, like sysprep
+// does for Windows images.
+type stepGeneralize struct{}
+
+func (s *stepGeneralize) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	comm := state.Get("communicator").(packersdk.Communicator)
+	ui := newStepUi(state, "generalize")
+	c := state.Get("config").(*Config)
+
+	// Drop the temporary key Packer generated from every authorized_keys
+	// file, matching on the key itself as the comment may have been lost
+	// on the way through the DigitalOcean API.
+	var removeKey string
+	if fields := strings.Fields(string(c.Comm.SSHPublicKey)); len(fields) >= 2 {
+		removeKey = fmt.Sprintf(
+			`for f in /root/.ssh/authorized_keys /home/*/.ssh/authorized_keys; do [ -f "$f" ] && grep -vF '%s' "$f" > "$f.packer"; [ -f "$f.packer" ] && cat "$f.packer" > "$f" && rm -f "$f.packer"; done`,
+			fields[1])
+	}
+
+	ui.Say("Generalizing droplet...")
+	script := fmt.Sprintf(generalizeScript, removeKey)
+	if err := comm.Upload(generalizeScriptPath, strings.NewReader(script), nil); err != nil {
+		err := fmt.Errorf("Error uploading generalize script: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	command := "sh " + generalizeScriptPath
+	if c.Comm.SSHUsername != "root" {
+		command = "sudo " + command
+	}
+	cmd := &packersdk.RemoteCmd{Command: command}
+	if err := cmd.RunWithUi(ctx, comm, ui); err != nil {
+		err := fmt.Errorf("Error generalizing droplet: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	if cmd.ExitStatus() != 0 {
+		err := fmt.Errorf("Generalize script exited with non-zero exit status: %d", cmd.ExitStatus())
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepGeneralize) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
@@ -0,0 +1,58 @@
+package digitalocean
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestStepGeneralize(t *testing.T) {
+	tt := []struct {
+		Name       string
+		Username   string
+		ExitStatus int
+		Command    string
+		Action     multistep.StepAction
+	}{
+		{Name: "Root", Username: "root", Command: "sh /tmp/packer-generalize.sh", Action: multistep.ActionContinue},
+		{Name: "Sudo", Username: "core", Command: "sudo sh /tmp/packer-generalize.sh", Action: multistep.ActionContinue},
+		{Name: "Failing", Username: "root", ExitStatus: 1, Command: "sh /tmp/packer-generalize.sh", Action: multistep.ActionHalt},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			comm := &packersdk.MockCommunicator{StartExitStatus: tc.ExitStatus}
+
+			state := new(multistep.BasicStateBag)
+			state.Put("communicator", comm)
+			state.Put("ui", packersdk.TestUi(t))
+			state.Put("config", &Config{
+				Comm: communicator.Config{
+					SSH: communicator.SSH{
+						SSHUsername:  tc.Username,
+						SSHPublicKey: []byte("ssh-rsa AAAAB3Nza/LiPk== packer_6123"),
+					},
+				},
+			})
+
+			step := new(stepGeneralize)
+			if action := step.Run(context.Background(), state); action != tc.Action {
+				t.Fatalf("expected action %#v, got %#v", tc.Action, action)
+			}
+			if comm.UploadPath != generalizeScriptPath {
+				t.Fatalf("expected script at %s, got %s", generalizeScriptPath, comm.UploadPath)
+			}
+			if !strings.Contains(comm.UploadData, "grep -vF 'AAAAB3Nza/LiPk=='") {
+				t.Fatalf("temporary key isn't removed:\n%s", comm.UploadData)
+			}
+			if comm.StartCmd.Command != tc.Command {
+				t.Fatalf("expected command %q, got %q", tc.Command, comm.StartCmd.Command)
+			}
+		})
+	}
+}
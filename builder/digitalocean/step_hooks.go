@@ -0,0 +1,73 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/shell-local/localexec"
+)
+
+// stepHooks runs the local commands of one of the hooks, with details of the
+// droplet and snapshot in their environment.
+type stepHooks struct {
+	hook     string
+	commands []string
+}
+
+func (s *stepHooks) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := newStepUi(state, "hooks")
+	c := state.Get("config").(*Config)
+
+	env := append(os.Environ(), hookEnv(state, c)...)
+	for _, command := range s.commands {
+		ui.Say(fmt.Sprintf("Running %s hook: %s", s.hook, command))
+
+		var cmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+		} else {
+			cmd = exec.CommandContext(ctx, "/bin/sh", "-c", command)
+		}
+		cmd.Env = env
+		if err := localexec.RunAndStream(cmd, ui, c.apiTokens()); err != nil {
+			err := fmt.Errorf("Error running %s hook %q: %s", s.hook, command, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepHooks) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
+
+// hookEnv returns the environment variables describing the build to hooks.
+func hookEnv(state multistep.StateBag, c *Config) []string {
+	env := []string{
+		"PACKER_BUILD_NAME=" + c.PackerBuildName,
+		"DIGITALOCEAN_REGION=" + c.Region,
+		"DIGITALOCEAN_SIZE=" + c.Size,
+		"DIGITALOCEAN_IMAGE=" + c.Image,
+		"DIGITALOCEAN_DROPLET_NAME=" + c.DropletName,
+	}
+	if dropletId, ok := state.GetOk("droplet_id"); ok {
+		env = append(env, "DIGITALOCEAN_DROPLET_ID="+strconv.Itoa(dropletId.(int)))
+	}
+	if ip, ok := state.GetOk("droplet_ip"); ok {
+		env = append(env, "DIGITALOCEAN_DROPLET_IP="+ip.(string))
+	}
+	if imageId, ok := state.GetOk("snapshot_image_id"); ok {
+		env = append(env,
+			"DIGITALOCEAN_SNAPSHOT_ID="+strconv.Itoa(imageId.(int)),
+			"DIGITALOCEAN_SNAPSHOT_NAME="+c.SnapshotName)
+	}
+	return env
+}
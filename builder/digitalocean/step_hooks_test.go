@@ -0,0 +1,49 @@
+package digitalocean
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestStepHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks are run with /bin/sh in this test")
+	}
+
+	out := filepath.Join(t.TempDir(), "hook.out")
+	state := new(multistep.BasicStateBag)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("config", &Config{Region: "nyc3", SnapshotName: "packer-test"})
+	state.Put("droplet_id", 1002)
+	state.Put("droplet_ip", "203.0.113.4")
+	state.Put("snapshot_image_id", 1004)
+
+	step := &stepHooks{hook: "post_snapshot", commands: []string{
+		"echo $DIGITALOCEAN_DROPLET_ID $DIGITALOCEAN_DROPLET_IP $DIGITALOCEAN_REGION > " + out,
+		"echo $DIGITALOCEAN_SNAPSHOT_ID $DIGITALOCEAN_SNAPSHOT_NAME >> " + out,
+	}}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected action continue, got %#v: %s", action, state.Get("error"))
+	}
+	contents, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "1002 203.0.113.4 nyc3\n1004 packer-test\n"; string(contents) != expected {
+		t.Errorf("got %q, expected %q", contents, expected)
+	}
+
+	step = &stepHooks{hook: "pre_snapshot", commands: []string{"exit 3", "touch " + out + ".never"}}
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("expected action halt, got %#v", action)
+	}
+	if _, err := ioutil.ReadFile(out + ".never"); err == nil {
+		t.Error("expected the commands after the failing one not to run")
+	}
+}
@@ -0,0 +1,220 @@
+package digitalocean
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// checkpointDir is where provisioners request intermediate snapshots, by
+// creating a file named after the checkpoint. Once the snapshot is taken,
+// the builder writes its image ID, or an error starting with "error:", to
+// the file of the same name with a .done extension.
+const checkpointDir = "/tmp/packer-checkpoints"
+
+// freezeSafetyTimeout is how long the mount points of freeze_mount_points
+// stay frozen at most, should thawing them fail.
+const freezeSafetyTimeout = time.Minute
+
+// stepIntermediateSnapshots runs the provisioners, taking the intermediate
+// snapshots they request while they run.
+type stepIntermediateSnapshots struct {
+	provision multistep.Step
+
+	taken map[string]bool
+}
+
+func (s *stepIntermediateSnapshots) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	comm := state.Get("communicator").(packersdk.Communicator)
+	ui := newStepUi(state, "intermediate_snapshots")
+	c := state.Get("config").(*Config)
+
+	sudo := ""
+	if c.Comm.SSHUsername != "root" {
+		sudo = "sudo "
+	}
+
+	// Everyone may request a snapshot, as provisioners don't all run as root
+	cmd := &packersdk.RemoteCmd{Command: sudo + "sh -c 'mkdir -p " + checkpointDir + " && chmod 1777 " + checkpointDir + "'"}
+	if err := cmd.RunWithUi(ctx, comm, ui); err != nil || cmd.ExitStatus() != 0 {
+		if err == nil {
+			err = fmt.Errorf("exit status %d", cmd.ExitStatus())
+		}
+		err := fmt.Errorf("Error creating %s: %s", checkpointDir, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	watchCtx, stop := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-watchCtx.Done():
+				return
+			case <-time.After(3 * pollInterval):
+			}
+			s.check(watchCtx, state)
+		}
+	}()
+
+	action := s.provision.Run(ctx, state)
+	stop()
+	<-done
+
+	// For the checkpoints requested last
+	if action == multistep.ActionContinue {
+		s.check(ctx, state)
+	}
+	return action
+}
+
+func (s *stepIntermediateSnapshots) Cleanup(state multistep.StateBag) {
+	s.provision.Cleanup(state)
+}
+
+// check takes the snapshots requested since it last ran.
+func (s *stepIntermediateSnapshots) check(ctx context.Context, state multistep.StateBag) {
+	comm := state.Get("communicator").(packersdk.Communicator)
+	ui := newStepUi(state, "intermediate_snapshots")
+
+	var stdout bytes.Buffer
+	cmd := &packersdk.RemoteCmd{Command: "ls -1 " + checkpointDir, Stdout: &stdout}
+	if err := comm.Start(ctx, cmd); err != nil {
+		ui.Debugf("Unable to list checkpoint requests: %s", err)
+		return
+	}
+	if cmd.Wait() != 0 {
+		return
+	}
+
+	files := strings.Fields(stdout.String())
+	for _, name := range files {
+		if strings.HasSuffix(name, ".done") || s.taken[name] {
+			continue
+		}
+		if s.taken == nil {
+			s.taken = make(map[string]bool)
+		}
+		s.taken[name] = true
+
+		result := "error: "
+		id, err := s.snapshot(ctx, state, name)
+		if err != nil {
+			ui.Error(fmt.Sprintf("Error taking intermediate snapshot %s: %s", name, err))
+			result += err.Error()
+		} else {
+			result = strconv.Itoa(id)
+		}
+		done := path.Join(checkpointDir, name+".done")
+		if err := comm.Upload(done, strings.NewReader(result+"\n"), nil); err != nil {
+			ui.Error(fmt.Sprintf("Error writing %s: %s", done, err))
+		}
+	}
+}
+
+func (s *stepIntermediateSnapshots) snapshot(ctx context.Context, state multistep.StateBag, checkpoint string) (int, error) {
+	client := state.Get("client").(*godo.Client)
+	ui := newStepUi(state, "intermediate_snapshots")
+	c := state.Get("config").(*Config)
+	dropletId := state.Get("droplet_id").(int)
+
+	name := c.SnapshotName + "-" + checkpoint
+	if err := checkImageName(name); err != nil {
+		return 0, fmt.Errorf("snapshot name %q %s", name, err)
+	}
+
+	if len(c.FreezeMountPoints) > 0 {
+		if err := s.run(ctx, state, freezeCommand(c.FreezeMountPoints)); err != nil {
+			return 0, fmt.Errorf("freezing %s: %s", strings.Join(c.FreezeMountPoints, ", "), err)
+		}
+	}
+
+	ui.Say(fmt.Sprintf("Taking intermediate snapshot: %s", name))
+	action, _, err := client.DropletActions.Snapshot(ctx, dropletId, name)
+	if len(c.FreezeMountPoints) > 0 {
+		// The snapshot is of the disk as it was when the action started
+		if thawErr := s.run(ctx, state, thawCommand(c.FreezeMountPoints)); thawErr != nil {
+			ui.Error(fmt.Sprintf("Error thawing %s, which is done in %s anyway: %s",
+				strings.Join(c.FreezeMountPoints, ", "), freezeSafetyTimeout, thawErr))
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := waitForActionState(godo.ActionCompleted, dropletId, action.ID, client, c.SnapshotTimeout); err != nil {
+		return 0, err
+	}
+	image, err := findDropletSnapshot(client, dropletId, name)
+	if err != nil {
+		return 0, err
+	}
+	if image == nil {
+		return 0, fmt.Errorf("snapshot %s not found", name)
+	}
+
+	trackCreated(state, resourceIntermediateSnapshot, image.ID, name)
+	ui.Message(fmt.Sprintf("Intermediate snapshot %s (%d) taken", name, image.ID))
+	snapshots, _ := state.GetOk("intermediate_snapshots")
+	taken, _ := snapshots.(map[string]interface{})
+	if taken == nil {
+		taken = make(map[string]interface{})
+	}
+	taken[checkpoint] = image.ID
+	state.Put("intermediate_snapshots", taken)
+	return image.ID, nil
+}
+
+// run runs a command on the droplet as root.
+func (s *stepIntermediateSnapshots) run(ctx context.Context, state multistep.StateBag, command string) error {
+	comm := state.Get("communicator").(packersdk.Communicator)
+	ui := newStepUi(state, "intermediate_snapshots")
+	c := state.Get("config").(*Config)
+
+	if c.Comm.SSHUsername != "root" {
+		command = "sudo " + command
+	}
+	cmd := &packersdk.RemoteCmd{Command: command}
+	if err := cmd.RunWithUi(ctx, comm, ui); err != nil {
+		return err
+	}
+	if cmd.ExitStatus() != 0 {
+		return fmt.Errorf("exit status %d", cmd.ExitStatus())
+	}
+	return nil
+}
+
+// freezeCommand freezes the mount points, and thaws them again after
+// freezeSafetyTimeout in the background, whether they all froze or not.
+func freezeCommand(mounts []string) string {
+	freeze := make([]string, 0, len(mounts))
+	for _, mount := range mounts {
+		freeze = append(freeze, "fsfreeze --freeze "+mount)
+	}
+	return fmt.Sprintf("sh -c '%s; status=$?; (sleep %d; %s) </dev/null >/dev/null 2>&1 & exit $status'",
+		strings.Join(freeze, " && "), int(freezeSafetyTimeout.Seconds()), thawMounts(mounts))
+}
+
+// thawCommand thaws the mount points.
+func thawCommand(mounts []string) string {
+	return fmt.Sprintf("sh -c '%s'", thawMounts(mounts))
+}
+
+// thawMounts thaws each mount point, failing if any was not frozen.
+func thawMounts(mounts []string) string {
+	thaw := make([]string, 0, len(mounts))
+	for _, mount := range mounts {
+		thaw = append(thaw, "fsfreeze --unfreeze "+mount)
+	}
+	return strings.Join(thaw, "; ")
+}
@@ -0,0 +1,126 @@
+package digitalocean
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+type testProvisionStep struct {
+	ran bool
+}
+
+func (s *testProvisionStep) Run(context.Context, multistep.StateBag) multistep.StepAction {
+	s.ran = true
+	return multistep.ActionContinue
+}
+
+func (s *testProvisionStep) Cleanup(multistep.StateBag) {}
+
+func TestStepIntermediateSnapshots(t *testing.T) {
+	sim, client := testSimulator(t)
+	droplet := sim.AddDroplet(godo.Droplet{Name: "packer-test", Region: &godo.Region{Slug: "nyc3"}, Status: "active"})
+	comm := &packersdk.MockCommunicator{StartStdout: "base\nbase.done\n"}
+
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("communicator", comm)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("droplet_id", droplet.ID)
+	state.Put("config", &Config{SnapshotName: "packer-test", SnapshotTimeout: time.Second})
+
+	provision := new(testProvisionStep)
+	step := &stepIntermediateSnapshots{provision: provision}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected action continue, got %#v: %s", action, state.Get("error"))
+	}
+	if !provision.ran {
+		t.Fatal("expected the provisioners to run")
+	}
+
+	snapshots := state.Get("intermediate_snapshots").(map[string]interface{})
+	id, ok := snapshots["base"].(int)
+	if !ok || len(snapshots) != 1 {
+		t.Fatalf("got intermediate snapshots %#v", snapshots)
+	}
+	image, ok := sim.Image(id)
+	if !ok || image.Name != "packer-test-base" {
+		t.Fatalf("expected the snapshot packer-test-base, got %#v", image)
+	}
+	if comm.UploadPath != checkpointDir+"/base.done" {
+		t.Errorf("got upload path %q", comm.UploadPath)
+	}
+	if got := strings.TrimSpace(comm.UploadData); got != strconv.Itoa(id) {
+		t.Errorf("got done file %q, expected the image ID", got)
+	}
+
+	// A checkpoint is only taken once
+	images := len(sim.Images())
+	step.check(context.Background(), state)
+	if len(sim.Images()) != images {
+		t.Errorf("expected no new snapshot, got %#v", sim.Images())
+	}
+}
+
+func TestStepIntermediateSnapshots_InvalidName(t *testing.T) {
+	sim, client := testSimulator(t)
+	droplet := sim.AddDroplet(godo.Droplet{Name: "packer-test", Region: &godo.Region{Slug: "nyc3"}, Status: "active"})
+	comm := &packersdk.MockCommunicator{StartStdout: "base|1\n"}
+
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("communicator", comm)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("droplet_id", droplet.ID)
+	state.Put("config", &Config{SnapshotName: "packer-test", SnapshotTimeout: time.Second})
+
+	images := len(sim.Images())
+	step := &stepIntermediateSnapshots{provision: new(testProvisionStep)}
+	step.check(context.Background(), state)
+
+	if len(sim.Images()) != images {
+		t.Errorf("expected no snapshot, got %#v", sim.Images())
+	}
+	if !strings.HasPrefix(comm.UploadData, "error: ") {
+		t.Errorf("expected the error in the done file, got %q", comm.UploadData)
+	}
+}
+
+func TestStepIntermediateSnapshots_FreezeMountPoints(t *testing.T) {
+	sim, client := testSimulator(t)
+	droplet := sim.AddDroplet(godo.Droplet{Name: "packer-test", Region: &godo.Region{Slug: "nyc3"}, Status: "active"})
+	comm := new(packersdk.MockCommunicator)
+
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("communicator", comm)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("droplet_id", droplet.ID)
+	state.Put("config", &Config{SnapshotName: "packer-test", SnapshotTimeout: time.Second,
+		FreezeMountPoints: []string{"/data", "/var/lib/db"}})
+
+	step := new(stepIntermediateSnapshots)
+	id, err := step.snapshot(context.Background(), state, "base")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, ok := sim.Image(id); !ok {
+		t.Fatalf("expected the snapshot %d", id)
+	}
+	// Thawed once the snapshot is started
+	expected := "sudo sh -c 'fsfreeze --unfreeze /data; fsfreeze --unfreeze /var/lib/db'"
+	if comm.StartCmd == nil || comm.StartCmd.Command != expected {
+		t.Fatalf("expected the last command %q, got %#v", expected, comm.StartCmd)
+	}
+
+	freeze := freezeCommand([]string{"/data", "/var/lib/db"})
+	if !strings.HasPrefix(freeze, "sh -c 'fsfreeze --freeze /data && fsfreeze --freeze /var/lib/db; status=$?; (sleep 60; ") {
+		t.Errorf("unexpected freeze command %q", freeze)
+	}
+}
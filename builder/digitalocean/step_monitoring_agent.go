@@ -0,0 +1,114 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// monitoringAgentCommand installs the DigitalOcean monitoring agent unless
+// the image already has it. DigitalOcean images get it installed when the
+// droplet is created with monitoring, custom images don't.
+const monitoringAgentCommand = `sh -c 'command -v do-agent >/dev/null 2>&1 || curl -sSL https://repos.insights.digitalocean.com/install.sh | sh'`
+
+// stepMonitoringAgent makes sure droplets created from a snapshot of a
+// custom image report metrics, by installing the monitoring agent and
+// waiting for its first metrics.
+type stepMonitoringAgent struct{}
+
+func (s *stepMonitoringAgent) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*godo.Client)
+	comm := state.Get("communicator").(packersdk.Communicator)
+	ui := newStepUi(state, "monitoring_agent")
+	c := state.Get("config").(*Config)
+	dropletId := state.Get("droplet_id").(int)
+
+	image, err := sourceImage(client, state)
+	if err != nil {
+		err := fmt.Errorf("Error retrieving source image: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	if image == nil || image.Type != "custom" {
+		ui.Debugf("Source image isn't a custom image, the monitoring agent is installed by DigitalOcean")
+		return multistep.ActionContinue
+	}
+
+	ui.Say("Installing the monitoring agent...")
+	command := monitoringAgentCommand
+	if c.Comm.SSHUsername != "root" {
+		command = "sudo " + command
+	}
+	cmd := &packersdk.RemoteCmd{Command: command}
+	if err := cmd.RunWithUi(ctx, comm, ui); err != nil {
+		err := fmt.Errorf("Error installing the monitoring agent: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	if cmd.ExitStatus() != 0 {
+		err := fmt.Errorf("Installing the monitoring agent exited with non-zero exit status: %d", cmd.ExitStatus())
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Say("Waiting for the monitoring agent to report metrics...")
+	if err := waitForMetrics(ctx, client, dropletId, c.StateTimeout); err != nil {
+		err := fmt.Errorf("Error verifying the monitoring agent: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepMonitoringAgent) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
+
+// metricsResponse is the part of a monitoring metrics response needed to
+// tell whether a droplet reports metrics.
+type metricsResponse struct {
+	Data struct {
+		Result []interface{} `json:"result"`
+	} `json:"data"`
+}
+
+// waitForMetrics waits for the droplet to report CPU metrics, which the
+// agent does shortly after it started.
+func waitForMetrics(ctx context.Context, client *godo.Client, dropletId int, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		end := time.Now()
+		path := fmt.Sprintf("v2/monitoring/metrics/droplet/cpu?host_id=%d&start=%d&end=%d",
+			dropletId, end.Add(-10*time.Minute).Unix(), end.Unix())
+		req, err := client.NewRequest(ctx, http.MethodGet, path, nil)
+		if err != nil {
+			return err
+		}
+		metrics := new(metricsResponse)
+		if _, err := client.Do(ctx, req, metrics); err != nil {
+			return err
+		}
+		if len(metrics.Data.Result) > 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("droplet %d reported no metrics within %s", dropletId, timeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * pollInterval):
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package digitalocean
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestStepMonitoringAgent(t *testing.T) {
+	sim, client := testSimulator(t)
+	custom := sim.AddImage(godo.Image{Name: "custom", Type: "custom"})
+	snapshot := sim.AddImage(godo.Image{Name: "snapshot", Type: "snapshot"})
+
+	cases := []struct {
+		name       string
+		image      string
+		monitoring bool
+		installed  bool
+		fails      bool
+	}{
+		{"custom image", strconv.Itoa(custom.ID), true, true, false},
+		{"snapshot", strconv.Itoa(snapshot.ID), true, false, false},
+		{"slug", "ubuntu-20-04-x64", true, false, false},
+		{"not reporting", strconv.Itoa(custom.ID), false, true, true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			features := []string{}
+			if tt.monitoring {
+				features = append(features, "monitoring")
+			}
+			droplet := sim.AddDroplet(godo.Droplet{Name: "packer-test", Features: features})
+			comm := new(packersdk.MockCommunicator)
+
+			state := new(multistep.BasicStateBag)
+			state.Put("client", client)
+			state.Put("communicator", comm)
+			state.Put("ui", packersdk.TestUi(t))
+			state.Put("droplet_id", droplet.ID)
+			state.Put("config", &Config{
+				Image:        tt.image,
+				StateTimeout: 10 * time.Millisecond,
+				Comm:         communicator.Config{SSH: communicator.SSH{SSHUsername: "core"}},
+			})
+
+			step := new(stepMonitoringAgent)
+			action := step.Run(context.Background(), state)
+			if tt.fails {
+				if action != multistep.ActionHalt {
+					t.Fatalf("expected action halt, got %#v", action)
+				}
+			} else if action != multistep.ActionContinue {
+				t.Fatalf("expected action continue, got %#v: %v", action, state.Get("error"))
+			}
+
+			if !tt.installed {
+				if comm.StartCalled {
+					t.Fatalf("expected no command, got %q", comm.StartCmd.Command)
+				}
+				return
+			}
+			if expected := "sudo " + monitoringAgentCommand; comm.StartCmd.Command != expected {
+				t.Fatalf("expected command %q, got %q", expected, comm.StartCmd.Command)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package digitalocean
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// packageInventoryCommand prints the package format and distribution ID,
+// then the name, version and architecture of every installed package, one
+// per line. Debian packages are prefixed with their status, as dpkg also
+// lists removed packages whose configuration is left.
+const packageInventoryCommand = `sh -c '. /etc/os-release 2>/dev/null
+if command -v dpkg-query >/dev/null; then
+	echo "deb ${ID:-unknown}"
+	dpkg-query -W -f "\${db:Status-Abbrev} \${Package} \${Version} \${Architecture}\n"
+elif command -v rpm >/dev/null; then
+	echo "rpm ${ID:-unknown}"
+	rpm -qa --qf "%{NAME} %{VERSION}-%{RELEASE} %{ARCH}\n"
+else
+	echo "neither dpkg nor rpm found" >&2
+	exit 3
+fi'`
+
+// osPackage is an installed package.
+type osPackage struct {
+	Name    string
+	Version string
+	Arch    string
+}
+
+// packageInventory is what packageInventoryCommand found.
+type packageInventory struct {
+	// deb or rpm
+	Format   string
+	Distro   string
+	Packages []osPackage
+}
+
+// stepPackageInventory lists the packages of the droplet once it is
+// provisioned, for the artifact to tell what the image holds.
+type stepPackageInventory struct{}
+
+func (s *stepPackageInventory) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	comm := state.Get("communicator").(packersdk.Communicator)
+	ui := newStepUi(state, "package_inventory")
+	c := state.Get("config").(*Config)
+
+	ui.Say("Listing installed packages...")
+	var stdout, stderr bytes.Buffer
+	cmd := &packersdk.RemoteCmd{Command: packageInventoryCommand, Stdout: &stdout, Stderr: &stderr}
+	if err := comm.Start(ctx, cmd); err != nil {
+		err := fmt.Errorf("Error listing packages: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	if status := cmd.Wait(); status != 0 {
+		err := fmt.Errorf("Listing packages exited with non-zero exit status %d: %s", status, strings.TrimSpace(stderr.String()))
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	inventory, err := parsePackageInventory(stdout.String())
+	if err != nil {
+		err := fmt.Errorf("Error listing packages: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	ui.Message(fmt.Sprintf("Found %d %s packages", len(inventory.Packages), inventory.Format))
+	state.Put("packages", inventory.versions())
+
+	if c.PackageInventoryFile != "" {
+		if err := inventory.writeCycloneDX(c.PackageInventoryFile, c.SnapshotName); err != nil {
+			err := fmt.Errorf("Error writing package_inventory_file: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		ui.Message(fmt.Sprintf("Package inventory written to %s", c.PackageInventoryFile))
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepPackageInventory) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
+
+func parsePackageInventory(output string) (*packageInventory, error) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	header := strings.Fields(lines[0])
+	if len(header) != 2 || (header[0] != "deb" && header[0] != "rpm") {
+		return nil, fmt.Errorf("unexpected output: %q", lines[0])
+	}
+
+	inventory := &packageInventory{Format: header[0], Distro: header[1]}
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if inventory.Format == "deb" {
+			// Only installed packages, not removed ones
+			if len(fields) != 4 || fields[0] != "ii" {
+				continue
+			}
+			fields = fields[1:]
+		}
+		if len(fields) != 3 {
+			continue
+		}
+		inventory.Packages = append(inventory.Packages, osPackage{Name: fields[0], Version: fields[1], Arch: fields[2]})
+	}
+	return inventory, nil
+}
+
+// versions returns the version of each package by name, the name being
+// followed by a colon and the architecture for packages installed for
+// several of them.
+func (i *packageInventory) versions() map[string]string {
+	count := make(map[string]int, len(i.Packages))
+	for _, p := range i.Packages {
+		count[p.Name]++
+	}
+	versions := make(map[string]string, len(i.Packages))
+	for _, p := range i.Packages {
+		name := p.Name
+		if count[p.Name] > 1 {
+			name += ":" + p.Arch
+		}
+		versions[name] = p.Version
+	}
+	return versions
+}
+
+type cycloneDXComponent struct {
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	PURL    string `json:"purl,omitempty"`
+}
+
+// writeCycloneDX saves the inventory as a CycloneDX JSON bill of materials
+// of the image with the given name.
+func (i *packageInventory) writeCycloneDX(path, imageName string) error {
+	components := make([]cycloneDXComponent, 0, len(i.Packages))
+	for _, p := range i.Packages {
+		components = append(components, cycloneDXComponent{
+			Type:    "library",
+			Name:    p.Name,
+			Version: p.Version,
+			PURL: fmt.Sprintf("pkg:%s/%s/%s@%s?arch=%s", i.Format, url.PathEscape(i.Distro), url.PathEscape(p.Name),
+				url.PathEscape(p.Version), url.QueryEscape(p.Arch)),
+		})
+	}
+	bom := map[string]interface{}{
+		"bomFormat":   "CycloneDX",
+		"specVersion": "1.4",
+		"version":     1,
+		"metadata": map[string]interface{}{
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+			"component": cycloneDXComponent{Type: "operating-system", Name: imageName},
+		},
+		"components": components,
+	}
+
+	data, err := json.MarshalIndent(bom, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
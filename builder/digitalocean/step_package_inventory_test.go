@@ -0,0 +1,78 @@
+package digitalocean
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestParsePackageInventory(t *testing.T) {
+	inventory, err := parsePackageInventory("deb ubuntu\n" +
+		"ii  bash 5.0-6ubuntu1.1 amd64\n" +
+		"rc  nano 4.8-1ubuntu1 amd64\n" +
+		"ii  libc6 2.31-0ubuntu9.2 amd64\n" +
+		"ii  libc6 2.31-0ubuntu9.2 i386\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := map[string]string{
+		"bash":        "5.0-6ubuntu1.1",
+		"libc6:amd64": "2.31-0ubuntu9.2",
+		"libc6:i386":  "2.31-0ubuntu9.2",
+	}
+	if got := inventory.versions(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+
+	inventory, err = parsePackageInventory("rpm fedora\nbash 5.1.0-2.fc34 x86_64\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(inventory.Packages, []osPackage{{"bash", "5.1.0-2.fc34", "x86_64"}}) {
+		t.Fatalf("unexpected packages %#v", inventory.Packages)
+	}
+
+	if _, err := parsePackageInventory("bash 5.1.0-2.fc34 x86_64\n"); err == nil {
+		t.Fatal("expected an error without the header")
+	}
+}
+
+func TestStepPackageInventory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sbom", "packer-test.cdx.json")
+	comm := &packersdk.MockCommunicator{StartStdout: "deb debian\nii  bash 5.1-2 amd64\n"}
+
+	state := new(multistep.BasicStateBag)
+	state.Put("communicator", comm)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("config", &Config{SnapshotName: "packer-test", PackageInventory: true, PackageInventoryFile: path})
+
+	step := new(stepPackageInventory)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected action continue, got %#v: %s", action, state.Get("error"))
+	}
+	if got := state.Get("packages").(map[string]string); got["bash"] != "5.1-2" {
+		t.Errorf("unexpected packages %v", got)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var bom struct {
+		BOMFormat  string               `json:"bomFormat"`
+		Components []cycloneDXComponent `json:"components"`
+	}
+	if err := json.Unmarshal(data, &bom); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := []cycloneDXComponent{{Type: "library", Name: "bash", Version: "5.1-2", PURL: "pkg:deb/debian/bash@5.1-2?arch=amd64"}}
+	if bom.BOMFormat != "CycloneDX" || !reflect.DeepEqual(bom.Components, expected) {
+		t.Fatalf("unexpected bill of materials:\n%s", data)
+	}
+}
@@ -3,11 +3,9 @@ package digitalocean
 import (
 	"context"
 	"fmt"
-	"log"
 
 	"github.com/digitalocean/godo"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
-	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 )
 
 type stepPowerOff struct{}
@@ -15,7 +13,7 @@ type stepPowerOff struct{}
 func (s *stepPowerOff) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
 	client := state.Get("client").(*godo.Client)
 	c := state.Get("config").(*Config)
-	ui := state.Get("ui").(packersdk.Ui)
+	ui := newStepUi(state, "power_off")
 	dropletId := state.Get("droplet_id").(int)
 
 	droplet, _, err := client.Droplets.Get(context.TODO(), dropletId)
@@ -33,17 +31,20 @@ func (s *stepPowerOff) Run(ctx context.Context, state multistep.StateBag) multis
 
 	// Pull the plug on the Droplet
 	ui.Say("Forcefully shutting down Droplet...")
-	_, _, err = client.DropletActions.PowerOff(context.TODO(), dropletId)
+	action, _, err := client.DropletActions.PowerOff(context.TODO(), dropletId)
 	if err != nil {
+		err = withIncidents(c, err, c.Region, "Droplets")
 		err := fmt.Errorf("Error powering off droplet: %s", err)
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
 	}
 
-	log.Println("Waiting for poweroff event to complete...")
-	err = waitForDropletState("off", dropletId, client, c.StateTimeout)
+	ui.Debugf("Waiting for poweroff event to complete...")
+	err = waitForActionState(godo.ActionCompleted, dropletId, action.ID, client, c.StateTimeout)
 	if err != nil {
+		err = withIncidents(c, err, c.Region, "Droplets")
+		err := fmt.Errorf("Error powering off droplet: %s", err)
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
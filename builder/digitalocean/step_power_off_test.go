@@ -0,0 +1,37 @@
+package digitalocean
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+func TestStepPowerOff(t *testing.T) {
+	sim, client := testSimulator(t)
+	droplet := sim.AddDroplet(godo.Droplet{Name: "packer-test", Region: &godo.Region{Slug: "nyc3"}, Status: "active"})
+
+	state := testShutdownState(t, client, droplet.ID)
+	if action := new(stepPowerOff).Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected action continue, got %#v: %s", action, state.Get("error"))
+	}
+	if got, _ := sim.Droplet(droplet.ID); got.Status != "off" {
+		t.Fatalf("expected the droplet to be off, got %s", got.Status)
+	}
+}
+
+func TestStepPowerOff_Errored(t *testing.T) {
+	sim, client := testSimulator(t)
+	sim.FailActions("power_off")
+	droplet := sim.AddDroplet(godo.Droplet{Name: "packer-test", Region: &godo.Region{Slug: "nyc3"}, Status: "active"})
+
+	state := testShutdownState(t, client, droplet.ID)
+	if action := new(stepPowerOff).Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("expected action halt, got %#v", action)
+	}
+	if err := state.Get("error").(error); !strings.Contains(err.Error(), "errored") {
+		t.Fatalf("expected an errored action, got: %s", err)
+	}
+}
@@ -0,0 +1,138 @@
+package digitalocean
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+const bootIdCommand = "cat /proc/sys/kernel/random/boot_id"
+
+// bootFinishedCommand waits for systemd to be done starting units, whatever
+// their outcome, which the failed units check tells. Images without
+// systemd, or with one too old to wait, are done once SSH is up.
+const bootFinishedCommand = `sh -c 'command -v systemctl >/dev/null 2>&1 || exit 0; systemctl is-system-running --wait >/dev/null 2>&1; exit 0'`
+
+// failedUnitsCommand lists the systemd units that failed to start, and
+// fails if there are any. Images without systemd have nothing to check.
+const failedUnitsCommand = `sh -c 'command -v systemctl >/dev/null 2>&1 || exit 0; failed=$(systemctl --failed --no-legend --plain); [ -z "$failed" ] || { echo "$failed"; exit 1; }'`
+
+// stepReboot reboots the provisioned droplet before it is snapshotted, to
+// make sure the image boots with every service starting.
+type stepReboot struct{}
+
+func (s *stepReboot) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	comm := state.Get("communicator").(packersdk.Communicator)
+	ui := newStepUi(state, "reboot")
+	c := state.Get("config").(*Config)
+
+	sudo := ""
+	if c.Comm.SSHUsername != "root" {
+		sudo = "sudo "
+	}
+
+	ui.Say("Rebooting droplet...")
+	if err := rebootDroplet(ctx, comm, sudo, c.RebootTimeout); err != nil {
+		err := fmt.Errorf("Error rebooting droplet: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Say("Checking for failed services...")
+	cmd := &packersdk.RemoteCmd{Command: failedUnitsCommand}
+	if err := cmd.RunWithUi(ctx, comm, ui); err != nil {
+		err := fmt.Errorf("Error checking services: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	if cmd.ExitStatus() != 0 {
+		err := fmt.Errorf("Services failed to start after the reboot")
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepReboot) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
+
+// rebootDroplet reboots the droplet and waits for it to be back, telling
+// the reboot apart from the SSH server still being up by the boot ID, and
+// for it to finish booting, so that nothing runs while units are still
+// starting. The communicator reconnects on its own.
+func rebootDroplet(ctx context.Context, comm packersdk.Communicator, sudo string, timeout time.Duration) error {
+	before, err := bootId(ctx, comm)
+	if err != nil {
+		return err
+	}
+
+	// The connection drops with the reboot, so whatever this returns is
+	// meaningless.
+	cmd := &packersdk.RemoteCmd{Command: sudo + "sh -c 'sleep 2 && reboot' >/dev/null 2>&1 &"}
+	if err := comm.Start(ctx, cmd); err == nil {
+		cmd.Wait()
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * pollInterval):
+		}
+
+		after, err := bootId(ctx, comm)
+		if err == nil && after != before {
+			break
+		}
+		if err != nil {
+			logf(levelDebug, []interface{}{"step", "reboot"}, "Droplet isn't back from the reboot yet: %s", err)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout waiting for the droplet to come back from the reboot")
+		}
+	}
+
+	// The SSH server starts before the rest of the units, and the
+	// connection may still drop while they do
+	for {
+		cmd := &packersdk.RemoteCmd{Command: bootFinishedCommand}
+		err := comm.Start(ctx, cmd)
+		if err == nil {
+			cmd.Wait()
+			return nil
+		}
+		logf(levelDebug, []interface{}{"step", "reboot"}, "Droplet hasn't finished booting yet: %s", err)
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout waiting for the droplet to finish booting")
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * pollInterval):
+		}
+	}
+}
+
+func bootId(ctx context.Context, comm packersdk.Communicator) (string, error) {
+	var stdout bytes.Buffer
+	cmd := &packersdk.RemoteCmd{Command: bootIdCommand, Stdout: &stdout}
+	if err := comm.Start(ctx, cmd); err != nil {
+		return "", err
+	}
+	if status := cmd.Wait(); status != 0 {
+		return "", fmt.Errorf("reading the boot ID exited with non-zero exit status: %d", status)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,133 @@
+package digitalocean
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// testRebootCommunicator stands in for a droplet whose package update and
+// failed units check exit with the given status, and which is unreachable
+// for a few commands after a reboot, unless it is stuck and never reboots.
+// Once back, the connection drops the first booting times the boot is
+// waited for.
+type testRebootCommunicator struct {
+	packersdk.MockCommunicator
+
+	mu           sync.Mutex
+	updateStatus int
+	failedStatus int
+	commands     []string
+	stuck        bool
+	boot         int
+	down         int
+	booting      int
+}
+
+func (c *testRebootCommunicator) Start(ctx context.Context, rc *packersdk.RemoteCmd) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.commands = append(c.commands, rc.Command)
+
+	if c.down > 0 {
+		c.down--
+		return errors.New("connection refused")
+	}
+
+	if rc.Command == bootFinishedCommand && c.booting > 0 {
+		c.booting--
+		return errors.New("connection reset by peer")
+	}
+
+	status, stdout := 0, ""
+	switch {
+	case strings.Contains(rc.Command, updatePackagesScriptPath):
+		status = c.updateStatus
+	case rc.Command == failedUnitsCommand:
+		status = c.failedStatus
+	case rc.Command == bootIdCommand:
+		stdout = strings.Repeat("b", c.boot+1)
+	case strings.Contains(rc.Command, "reboot") && !c.stuck:
+		c.boot++
+		c.down = 2
+	}
+	go func() {
+		if rc.Stdout != nil {
+			io.Copy(rc.Stdout, strings.NewReader(stdout))
+		}
+		rc.SetExited(status)
+	}()
+	return nil
+}
+
+func TestStepReboot(t *testing.T) {
+	cases := []struct {
+		name         string
+		failedStatus int
+		action       multistep.StepAction
+	}{
+		{"services started", 0, multistep.ActionContinue},
+		{"services failed", 1, multistep.ActionHalt},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			testSimulator(t)
+			comm := &testRebootCommunicator{failedStatus: tt.failedStatus}
+
+			state := new(multistep.BasicStateBag)
+			state.Put("communicator", comm)
+			state.Put("ui", packersdk.TestUi(t))
+			state.Put("config", &Config{
+				StateTimeout:  time.Second,
+				RebootTimeout: time.Second,
+				Comm:          communicator.Config{SSH: communicator.SSH{SSHUsername: "root"}},
+			})
+
+			step := new(stepReboot)
+			if action := step.Run(context.Background(), state); action != tt.action {
+				t.Fatalf("expected action %#v, got %#v: %v", tt.action, action, state.Get("error"))
+			}
+			if comm.boot != 1 {
+				t.Fatalf("expected one reboot, got %d: %q", comm.boot, comm.commands)
+			}
+			if last := comm.commands[len(comm.commands)-1]; last != failedUnitsCommand {
+				t.Fatalf("expected failed units checked last, got %q", last)
+			}
+		})
+	}
+}
+
+func TestRebootDroplet_Timeout(t *testing.T) {
+	testSimulator(t)
+	comm := &testRebootCommunicator{stuck: true}
+
+	if err := rebootDroplet(context.Background(), comm, "", 10*time.Millisecond); err == nil {
+		t.Fatal("expected a timeout")
+	}
+}
+
+func TestRebootDroplet_WaitsForBoot(t *testing.T) {
+	testSimulator(t)
+	comm := &testRebootCommunicator{booting: 2}
+
+	if err := rebootDroplet(context.Background(), comm, "", time.Second); err != nil {
+		t.Fatal(err)
+	}
+	var waits int
+	for _, command := range comm.commands {
+		if command == bootFinishedCommand {
+			waits++
+		}
+	}
+	if waits != 3 || comm.commands[len(comm.commands)-1] != bootFinishedCommand {
+		t.Errorf("expected the boot to be waited for until the connection held, got %q", comm.commands)
+	}
+}
@@ -0,0 +1,118 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+// stepReservedIP assigns the existing reserved IP of reserved_ip to the
+// droplet, and unassigns it once the build is done. godo still calls
+// reserved IPs floating IPs.
+type stepReservedIP struct {
+	assigned bool
+}
+
+func (s *stepReservedIP) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*godo.Client)
+	ui := newStepUi(state, "reserved_ip")
+	c := state.Get("config").(*Config)
+	dropletId := state.Get("droplet_id").(int)
+
+	ip, _, err := client.FloatingIPs.Get(context.TODO(), c.ReservedIP)
+	if err != nil {
+		err := fmt.Errorf("Error looking up reserved IP %s: %s", c.ReservedIP, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	if ip.Region != nil && ip.Region.Slug != c.Region {
+		err := fmt.Errorf("Reserved IP %s is in region %s, not in %s with the droplet", ip.IP, ip.Region.Slug, c.Region)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	if ip.Droplet != nil && ip.Droplet.ID != dropletId {
+		err := fmt.Errorf("Reserved IP %s is assigned to droplet %s (%d)", ip.IP, ip.Droplet.Name, ip.Droplet.ID)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Say(fmt.Sprintf("Assigning reserved IP %s to the droplet...", ip.IP))
+	action, _, err := client.FloatingIPActions.Assign(context.TODO(), ip.IP, dropletId)
+	if err != nil {
+		err := fmt.Errorf("Error assigning reserved IP %s: %s", ip.IP, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	s.assigned = true
+	trackAdopted(state, resourceReservedIP, ip.IP, "")
+	if err := waitForReservedIPAction(client, ip.IP, action.ID, c.StateTimeout); err != nil {
+		err := fmt.Errorf("Error waiting for reserved IP %s to be assigned: %s", ip.IP, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	state.Put("reserved_ip", ip.IP)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepReservedIP) Cleanup(state multistep.StateBag) {
+	if !s.assigned {
+		return
+	}
+
+	// Destroying the droplet unassigns it anyway
+	if _, ok := state.GetOk("droplet_destroyed"); ok {
+		return
+	}
+
+	// Kept along with the droplet to resume the build
+	if _, ok := state.GetOk("keep_droplet"); ok {
+		return
+	}
+
+	client := state.Get("client").(*godo.Client)
+	ui := newStepUi(state, "reserved_ip")
+	c := state.Get("config").(*Config)
+
+	ui.Say(fmt.Sprintf("Unassigning reserved IP %s...", c.ReservedIP))
+	action, _, err := client.FloatingIPActions.Unassign(context.TODO(), c.ReservedIP)
+	if err == nil {
+		err = waitForReservedIPAction(client, c.ReservedIP, action.ID, c.StateTimeout)
+	}
+	if err != nil {
+		ui.Error(fmt.Sprintf(
+			"Error unassigning reserved IP %s. Please unassign it manually: %s", c.ReservedIP, err))
+		return
+	}
+	trackDisposition(state, resourceReservedIP, c.ReservedIP, resourceUnassigned, nil)
+}
+
+// waitForReservedIPAction waits for an action of a reserved IP to complete.
+func waitForReservedIPAction(client *godo.Client, ip string, actionId int, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		action, _, err := client.FloatingIPActions.Get(context.TODO(), ip, actionId)
+		if err != nil {
+			return err
+		}
+		switch action.Status {
+		case godo.ActionCompleted:
+			return nil
+		case "errored":
+			return fmt.Errorf("Reserved IP %s action %d errored", action.Type, action.ID)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("Timeout while waiting for the %s action", action.Type)
+		}
+		logf(levelDebug, []interface{}{"reserved_ip", ip, "action_id", actionId}, "Reserved IP action is %s", action.Status)
+		time.Sleep(pollInterval)
+	}
+}
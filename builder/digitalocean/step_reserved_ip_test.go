@@ -0,0 +1,68 @@
+package digitalocean
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestStepReservedIP(t *testing.T) {
+	sim, client := testSimulator(t)
+	droplet := sim.AddDroplet(godo.Droplet{Name: "packer-test", Region: &godo.Region{Slug: "nyc3"}})
+	ip := sim.AddFloatingIP(godo.FloatingIP{IP: "203.0.113.10", Region: &godo.Region{Slug: "nyc3"}})
+
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("droplet_id", droplet.ID)
+	state.Put("config", &Config{Region: "nyc3", ReservedIP: ip.IP, StateTimeout: time.Second})
+
+	step := new(stepReservedIP)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected action continue, got %#v: %s", action, state.Get("error"))
+	}
+	assigned, _, err := client.FloatingIPs.Get(context.TODO(), ip.IP)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if assigned.Droplet == nil || assigned.Droplet.ID != droplet.ID {
+		t.Fatalf("expected the reserved IP to be assigned to droplet %d, got %#v", droplet.ID, assigned.Droplet)
+	}
+
+	step.Cleanup(state)
+	unassigned, _, err := client.FloatingIPs.Get(context.TODO(), ip.IP)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if unassigned.Droplet != nil {
+		t.Fatalf("expected the reserved IP to be unassigned, got droplet %d", unassigned.Droplet.ID)
+	}
+}
+
+func TestStepReservedIP_Unavailable(t *testing.T) {
+	sim, client := testSimulator(t)
+	droplet := sim.AddDroplet(godo.Droplet{Name: "packer-test", Region: &godo.Region{Slug: "nyc3"}})
+	other := sim.AddDroplet(godo.Droplet{Name: "web", Region: &godo.Region{Slug: "nyc3"}})
+	inUse := sim.AddFloatingIP(godo.FloatingIP{Region: &godo.Region{Slug: "nyc3"}, Droplet: &other})
+	elsewhere := sim.AddFloatingIP(godo.FloatingIP{Region: &godo.Region{Slug: "ams3"}})
+
+	for _, ip := range []string{inUse.IP, elsewhere.IP, "198.51.100.254"} {
+		state := new(multistep.BasicStateBag)
+		state.Put("client", client)
+		state.Put("ui", packersdk.TestUi(t))
+		state.Put("droplet_id", droplet.ID)
+		state.Put("config", &Config{Region: "nyc3", ReservedIP: ip, StateTimeout: time.Second})
+
+		step := new(stepReservedIP)
+		if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+			t.Errorf("reserved_ip %s: expected action halt, got %#v", ip, action)
+		}
+		if step.assigned {
+			t.Errorf("reserved_ip %s: expected it not to be assigned", ip)
+		}
+	}
+}
@@ -3,28 +3,30 @@ package digitalocean
 import (
 	"context"
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/digitalocean/godo"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
-	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 )
 
+// shutdownRetryInterval is how long a shutdown is given before it is
+// requested again.
+var shutdownRetryInterval = 20 * time.Second
+
 type stepShutdown struct{}
 
 func (s *stepShutdown) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
 	client := state.Get("client").(*godo.Client)
 	c := state.Get("config").(*Config)
-	ui := state.Get("ui").(packersdk.Ui)
+	ui := newStepUi(state, "shutdown")
 	dropletId := state.Get("droplet_id").(int)
 
 	// Gracefully power off the droplet. We have to retry this a number
-	// of times because sometimes it says it completed when it actually
+	// of times because sometimes the action completes when it actually
 	// did absolutely nothing (*ALAKAZAM!* magic!). We give up after
 	// a pretty arbitrary amount of time.
 	ui.Say("Gracefully shutting down droplet...")
-	_, _, err := client.DropletActions.Shutdown(context.TODO(), dropletId)
+	action, _, err := client.DropletActions.Shutdown(context.TODO(), dropletId)
 	if err != nil {
 		// If we get an error the first time, actually report it
 		err := fmt.Errorf("Error shutting down droplet: %s", err)
@@ -33,45 +35,50 @@ func (s *stepShutdown) Run(ctx context.Context, state multistep.StateBag) multis
 		return multistep.ActionHalt
 	}
 
-	// A channel we use as a flag to end our goroutines
-	done := make(chan struct{})
-	shutdownRetryDone := make(chan struct{})
-
-	// Make sure we wait for the shutdown retry goroutine to end
-	// before moving on.
-	defer func() {
-		close(done)
-		<-shutdownRetryDone
-	}()
-
-	// Start a goroutine that just keeps trying to shut down the
-	// droplet.
-	go func() {
-		defer close(shutdownRetryDone)
+	deadline := time.Now().Add(c.StateTimeout)
+	for attempts := 2; ; attempts++ {
+		// Following the action rather than the droplet status reports an
+		// errored shutdown right away
+		if err := waitForActionState(godo.ActionCompleted, dropletId, action.ID,
+			client, time.Until(deadline)); err != nil {
+			err := fmt.Errorf("Error shutting down droplet: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
 
-		for attempts := 2; attempts > 0; attempts++ {
-			log.Printf("ShutdownDroplet attempt #%d...", attempts)
-			_, _, err := client.DropletActions.Shutdown(context.TODO(), dropletId)
-			if err != nil {
-				log.Printf("Shutdown retry error: %s", err)
-			}
+		droplet, _, err := client.Droplets.Get(context.TODO(), dropletId)
+		if err != nil {
+			err := fmt.Errorf("Error checking droplet state: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		if droplet.Status == "off" {
+			break
+		}
 
-			select {
-			case <-done:
-				return
-			case <-time.After(20 * time.Second):
-				// Retry!
-			}
+		wait := shutdownRetryInterval
+		if remaining := time.Until(deadline); remaining < wait {
+			wait = remaining
+		}
+		if wait <= 0 {
+			err := fmt.Errorf("Error shutting down droplet: Timeout while waiting for droplet to become 'off'")
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
 		}
-	}()
+		time.Sleep(wait)
 
-	err = waitForDropletState("off", dropletId, client, c.StateTimeout)
-	if err != nil {
-		// If we get an error the first time, actually report it
-		err := fmt.Errorf("Error shutting down droplet: %s", err)
-		state.Put("error", err)
-		ui.Error(err.Error())
-		return multistep.ActionHalt
+		ui.Debugf("ShutdownDroplet attempt #%d...", attempts)
+		retry, _, err := client.DropletActions.Shutdown(context.TODO(), dropletId)
+		if err != nil {
+			// The droplet may have gone off in the meantime, which the
+			// next check tells
+			ui.Debugf("Shutdown retry error: %s", err)
+			continue
+		}
+		action = retry
 	}
 
 	if err := waitForDropletUnlocked(client, dropletId, c.StateTimeout); err != nil {
@@ -0,0 +1,54 @@
+package digitalocean
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func testShutdownState(t *testing.T, client *godo.Client, dropletId int) multistep.StateBag {
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("droplet_id", dropletId)
+	state.Put("config", &Config{StateTimeout: time.Minute})
+	return state
+}
+
+func TestStepShutdown(t *testing.T) {
+	sim, client := testSimulator(t)
+	sim.ActionPolls = 3
+	droplet := sim.AddDroplet(godo.Droplet{Name: "packer-test", Region: &godo.Region{Slug: "nyc3"}, Status: "active"})
+
+	state := testShutdownState(t, client, droplet.ID)
+	if action := new(stepShutdown).Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected action continue, got %#v: %s", action, state.Get("error"))
+	}
+	if got, _ := sim.Droplet(droplet.ID); got.Status != "off" {
+		t.Fatalf("expected the droplet to be off, got %s", got.Status)
+	}
+}
+
+func TestStepShutdown_Errored(t *testing.T) {
+	sim, client := testSimulator(t)
+	sim.FailActions("shutdown")
+	droplet := sim.AddDroplet(godo.Droplet{Name: "packer-test", Region: &godo.Region{Slug: "nyc3"}, Status: "active"})
+
+	// Reported right away rather than once state_timeout is over
+	state := testShutdownState(t, client, droplet.ID)
+	start := time.Now()
+	if action := new(stepShutdown).Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("expected action halt, got %#v", action)
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Fatalf("the errored shutdown took %s to be reported", time.Since(start))
+	}
+	if err := state.Get("error").(error); !strings.Contains(err.Error(), "errored") {
+		t.Fatalf("expected an errored action, got: %s", err)
+	}
+}
@@ -0,0 +1,230 @@
+package digitalocean
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+// inputsTagPrefix prefixes the hash of the inputs of a build in the tags of
+// its snapshot.
+const inputsTagPrefix = "inputs-sha256:"
+
+// buildInputs are the options changing what the droplet of a build holds,
+// which are hashed by skip_unchanged along with the user data and the
+// input_files. Options only about the build itself or the snapshot, such as
+// timeouts or snapshot_name, are left out, so that they don't force a build.
+type buildInputs struct {
+	SourceImage    int      `json:"source_image"`
+	Size           string   `json:"size"`
+	UserData       string   `json:"user_data"`
+	Monitoring     bool     `json:"monitoring"`
+	DropletAgent   *bool    `json:"droplet_agent"`
+	UpdatePackages bool     `json:"update_packages"`
+	Generalize     bool     `json:"generalize"`
+	Hooks          Hooks    `json:"hooks"`
+	Volumes        []Volume `json:"volumes"`
+	// The command rather than its output, which may hold secrets changing
+	// on every run
+	UserDataCommand string `json:"user_data_command,omitempty"`
+}
+
+// stepSkipUnchanged hashes the inputs of the build and looks for a snapshot
+// of an earlier build with the same hash. When there is none, the hash is
+// added to the snapshot tags of this build.
+type stepSkipUnchanged struct{}
+
+func (s *stepSkipUnchanged) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*godo.Client)
+	ui := newStepUi(state, "skip_unchanged")
+	c := state.Get("config").(*Config)
+
+	sourceImage, err := sourceImageID(client, c)
+	if err != nil {
+		err := fmt.Errorf("Error looking up source image: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	hash, err := inputsHash(c, sourceImage)
+	if err != nil {
+		err := fmt.Errorf("Error hashing the inputs of the build: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	tag := inputsTagPrefix + hash
+	ui.Message(fmt.Sprintf("Inputs hash: %s", hash))
+
+	if c.PackerForce {
+		ui.Say("Building anyway, as -force is set")
+	} else {
+		image, err := findUnchangedSnapshot(client, tag, append([]string{c.Region}, c.SnapshotRegions...))
+		if err != nil {
+			err := fmt.Errorf("Error looking up snapshots with the same inputs: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		if image != nil {
+			ui.Say(fmt.Sprintf("Nothing changed since snapshot %s (%d), skipping the build", image.Name, image.ID))
+			state.Put("unchanged_snapshot", image)
+			return multistep.ActionContinue
+		}
+	}
+
+	c.SnapshotTags = append(c.SnapshotTags, tag)
+	return multistep.ActionContinue
+}
+
+func (s *stepSkipUnchanged) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
+
+// sourceImageID returns the ID of the image the droplet is created from, so
+// that a slug, which DigitalOcean points to newer images over time, hashes
+// differently once it does.
+func sourceImageID(client *godo.Client, c *Config) (int, error) {
+	image := getImageType(c.Image)
+	if image.ID != 0 {
+		return image.ID, nil
+	}
+	found, _, err := client.Images.GetBySlug(context.TODO(), image.Slug)
+	if err != nil {
+		return 0, err
+	}
+	return found.ID, nil
+}
+
+// inputsHash returns the hex encoded sha256 of the inputs of the build.
+func inputsHash(c *Config, sourceImage int) (string, error) {
+	inputs := buildInputs{
+		SourceImage:     sourceImage,
+		Size:            c.Size,
+		UserData:        c.UserData,
+		Monitoring:      c.Monitoring,
+		DropletAgent:    c.DropletAgent.ToBoolPointer(),
+		UpdatePackages:  c.UpdatePackages,
+		Generalize:      c.Generalize,
+		Hooks:           c.Hooks,
+		UserDataCommand: c.UserDataCommand,
+	}
+	if c.UserDataFile != "" {
+		contents, err := readUserDataFile(c.UserDataFile, c.UserDataChecksum)
+		if err != nil {
+			return "", err
+		}
+		inputs.UserData = string(contents)
+	}
+	if len(c.UserDataVars) > 0 {
+		userData, err := substituteUserDataVars(inputs.UserData, c.UserDataVars)
+		if err != nil {
+			return "", err
+		}
+		inputs.UserData = userData
+	}
+	for _, v := range c.Volumes {
+		// Named after the droplet by default
+		v.Name = ""
+		inputs.Volumes = append(inputs.Volumes, v)
+	}
+
+	h := sha256.New()
+	if err := json.NewEncoder(h).Encode(inputs); err != nil {
+		return "", err
+	}
+	for _, path := range c.InputFiles {
+		if err := hashPath(h, path); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashPath writes the path and content of a file, or of every file of a
+// directory in lexical order, to h.
+func hashPath(h io.Writer, root string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		fmt.Fprintf(h, "%s\x00%s\x00%d\x00", filepath.ToSlash(root), filepath.ToSlash(rel), info.Size())
+		_, err = io.Copy(h, f)
+		return err
+	})
+}
+
+// findUnchangedSnapshot returns the newest snapshot tagged with tag that is
+// available in every region, or nil when there is none.
+func findUnchangedSnapshot(client *godo.Client, tag string, regions []string) (*godo.Image, error) {
+	opt := &godo.ListOptions{
+		Page:    1,
+		PerPage: 200,
+	}
+	var matches []godo.Image
+	for {
+		images, resp, err := client.Images.ListByTag(context.TODO(), tag, opt)
+		if err != nil {
+			return nil, err
+		}
+		for _, image := range images {
+			available := true
+			for _, region := range regions {
+				if !containsString(image.Regions, region) {
+					available = false
+				}
+			}
+			if available {
+				matches = append(matches, image)
+			}
+		}
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			break
+		}
+		opt.Page++
+	}
+	if len(matches) == 0 {
+		return nil, nil
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return imageCreated(matches[i]).After(imageCreated(matches[j]))
+	})
+	return &matches[0], nil
+}
+
+// unchangedArtifact returns the artifact of a build skipped for the snapshot
+// of an earlier one.
+func unchangedArtifact(client *godo.Client, image *godo.Image, state multistep.StateBag) *Artifact {
+	return &Artifact{
+		SnapshotName: image.Name,
+		SnapshotId:   image.ID,
+		RegionNames:  image.Regions,
+		Client:       client,
+		StateData: map[string]interface{}{
+			"generated_data": state.Get("generated_data"),
+			"unchanged":      true,
+		},
+	}
+}
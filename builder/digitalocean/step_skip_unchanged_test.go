@@ -0,0 +1,134 @@
+package digitalocean
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestInputsHash(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "setup.sh")
+	if err := ioutil.WriteFile(script, []byte("apt-get install -y nginx\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Config{Size: "s-1vcpu-1gb", UserData: "#cloud-config\n", InputFiles: []string{dir}, SnapshotName: "web-1"}
+	hash, err := inputsHash(c, 1234)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sha256Re.MatchString(hash) {
+		t.Fatalf("unexpected hash %q", hash)
+	}
+
+	// Options about the snapshot don't change the hash
+	c.SnapshotName = "web-2"
+	if other, _ := inputsHash(c, 1234); other != hash {
+		t.Errorf("snapshot_name changed the hash")
+	}
+
+	if other, _ := inputsHash(c, 5678); other == hash {
+		t.Errorf("the source image didn't change the hash")
+	}
+	if err := ioutil.WriteFile(script, []byte("apt-get install -y apache2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if other, _ := inputsHash(c, 1234); other == hash {
+		t.Errorf("input_files didn't change the hash")
+	}
+}
+
+func TestInputsHash_UserDataURL(t *testing.T) {
+	contents := "#cloud-config\npackages: [nginx]\n"
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(contents))
+	}))
+	defer srv.Close()
+	defaultClient := userDataClient
+	userDataClient = srv.Client()
+	defer func() { userDataClient = defaultClient }()
+
+	c := &Config{Size: "s-1vcpu-1gb", UserDataFile: srv.URL + "/cloud-init.yaml"}
+	hash, err := inputsHash(c, 1234)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	inline, err := inputsHash(&Config{Size: "s-1vcpu-1gb", UserData: contents}, 1234)
+	if err != nil || inline != hash {
+		t.Errorf("expected the hash of the downloaded user data, got %s and %s: %v", hash, inline, err)
+	}
+
+	c.UserDataChecksum = "sha256:" + strings.Repeat("0", 64)
+	if _, err := inputsHash(c, 1234); err == nil {
+		t.Fatal("expected a checksum mismatch")
+	}
+}
+
+func TestStepSkipUnchanged(t *testing.T) {
+	sim, client := testSimulator(t)
+	source := sim.AddImage(godo.Image{Slug: "ubuntu-22-04-x64", Public: true, Type: "base", Regions: []string{"nyc3"}})
+
+	config := func() *Config {
+		return &Config{Image: "ubuntu-22-04-x64", Size: "s-1vcpu-1gb", Region: "nyc3", SnapshotRegions: []string{"ams3"}}
+	}
+	hash, err := inputsHash(config(), source.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag := inputsTagPrefix + hash
+
+	run := func(c *Config) multistep.StateBag {
+		state := new(multistep.BasicStateBag)
+		state.Put("client", client)
+		state.Put("ui", packersdk.TestUi(t))
+		state.Put("config", c)
+		step := new(stepSkipUnchanged)
+		if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+			t.Fatalf("expected action continue, got %#v: %s", action, state.Get("error"))
+		}
+		return state
+	}
+
+	// No snapshot with the hash yet
+	c := config()
+	state := run(c)
+	if _, ok := state.GetOk("unchanged_snapshot"); ok {
+		t.Fatal("unexpected unchanged snapshot")
+	}
+	if len(c.SnapshotTags) != 1 || c.SnapshotTags[0] != tag {
+		t.Errorf("expected the snapshot to be tagged %s, got %q", tag, c.SnapshotTags)
+	}
+
+	// Only available in some of the regions
+	sim.AddImage(godo.Image{Name: "web-1", Type: "snapshot", Regions: []string{"nyc3"}, Tags: []string{tag}, Created: "2026-10-01T00:00:00Z"})
+	if _, ok := run(config()).GetOk("unchanged_snapshot"); ok {
+		t.Fatal("unexpected unchanged snapshot missing from ams3")
+	}
+
+	sim.AddImage(godo.Image{Name: "web-2", Type: "snapshot", Regions: []string{"nyc3", "ams3"}, Tags: []string{tag}, Created: "2026-10-02T00:00:00Z"})
+	newest := sim.AddImage(godo.Image{Name: "web-3", Type: "snapshot", Regions: []string{"ams3", "nyc3"}, Tags: []string{tag}, Created: "2026-10-03T00:00:00Z"})
+	c = config()
+	image, ok := run(c).GetOk("unchanged_snapshot")
+	if !ok || image.(*godo.Image).ID != newest.ID {
+		t.Fatalf("expected unchanged snapshot %d, got %#v", newest.ID, image)
+	}
+	if len(c.SnapshotTags) != 0 {
+		t.Errorf("unexpected snapshot tags %q", c.SnapshotTags)
+	}
+
+	// -force builds anyway
+	c = config()
+	c.PackerForce = true
+	if _, ok := run(c).GetOk("unchanged_snapshot"); ok {
+		t.Fatal("unexpected unchanged snapshot with -force")
+	}
+}
@@ -4,28 +4,38 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"strconv"
 	"time"
 
 	"github.com/digitalocean/godo"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
-	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+// The availability of the snapshot in a region, once transfers are done.
+const (
+	regionAvailable = "available"
+	regionFailed    = "failed"
+	regionPending   = "pending"
 )
 
 type stepSnapshot struct {
 	snapshotTimeout time.Duration
+	snapshotId      int
 }
 
 func (s *stepSnapshot) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
 	client := state.Get("client").(*godo.Client)
-	ui := state.Get("ui").(packersdk.Ui)
+	ui := newStepUi(state, "snapshot")
 	c := state.Get("config").(*Config)
 	dropletId := state.Get("droplet_id").(int)
 	var snapshotRegions []string
+	started := time.Now()
 
 	ui.Say(fmt.Sprintf("Creating snapshot: %v", c.SnapshotName))
 	action, _, err := client.DropletActions.Snapshot(context.TODO(), dropletId, c.SnapshotName)
 	if err != nil {
+		err = withIncidents(c, err, c.Region, "Snapshots", "Droplets")
 		err := fmt.Errorf("Error creating snapshot: %s", err)
 		state.Put("error", err)
 		ui.Error(err.Error())
@@ -35,10 +45,29 @@ func (s *stepSnapshot) Run(ctx context.Context, state multistep.StateBag) multis
 	// With the pending state over, verify that we're in the active state
 	// because action can take a long time and may depend on the size of the final snapshot,
 	// the timeout is parameterized
+	emitProgress(state, progressEvent{
+		Event:    progressActionStatus,
+		Action:   "snapshot",
+		ActionID: action.ID,
+		Status:   action.Status,
+		Region:   c.Region,
+	})
+
 	ui.Say("Waiting for snapshot to complete...")
-	if err := waitForActionState(godo.ActionCompleted, dropletId, action.ID,
-		client, s.snapshotTimeout); err != nil {
+	wait := startSpan(state, "snapshot.wait")
+	err = waitForActionState(godo.ActionCompleted, dropletId, action.ID, client, s.snapshotTimeout)
+	wait.finish(err)
+	if err != nil {
+		emitProgress(state, progressEvent{
+			Event:    progressActionStatus,
+			Action:   "snapshot",
+			ActionID: action.ID,
+			Status:   "errored",
+			Region:   c.Region,
+			Error:    err.Error(),
+		})
 		// If we get an error the first time, actually report it
+		err = withIncidents(c, err, c.Region, "Snapshots", "Droplets")
 		err := fmt.Errorf("Error waiting for snapshot: %s", err)
 		state.Put("error", err)
 		ui.Error(err.Error())
@@ -56,8 +85,8 @@ func (s *stepSnapshot) Run(ctx context.Context, state multistep.StateBag) multis
 		return multistep.ActionHalt
 	}
 
-	log.Printf("Looking up snapshot ID for snapshot: %s", c.SnapshotName)
-	images, _, err := client.Droplets.Snapshots(context.TODO(), dropletId, nil)
+	ui.Debugf("Looking up snapshot ID for snapshot: %s", c.SnapshotName)
+	image, err := findDropletSnapshot(client, dropletId, c.SnapshotName)
 	if err != nil {
 		err := fmt.Errorf("Error looking up snapshot ID: %s", err)
 		state.Put("error", err)
@@ -65,11 +94,41 @@ func (s *stepSnapshot) Run(ctx context.Context, state multistep.StateBag) multis
 		return multistep.ActionHalt
 	}
 
+	if image == nil {
+		err := errors.New("Couldn't find snapshot to get the image ID. Bug?")
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	imageId := image.ID
+	// We use this in cleanup
+	s.snapshotId = imageId
+	trackCreated(state, resourceSnapshot, imageId, c.SnapshotName)
+	emitProgress(state, progressEvent{
+		Event:     progressActionStatus,
+		Action:    "snapshot",
+		ActionID:  action.ID,
+		Status:    godo.ActionCompleted,
+		Region:    c.Region,
+		Resources: map[string]string{"snapshot_id": strconv.Itoa(imageId)},
+	})
+
+	if len(c.Webhooks) > 0 {
+		payload := newWebhookPayload(state, webhookSnapshotAvailable)
+		payload.SnapshotID = imageId
+		payload.SnapshotName = c.SnapshotName
+		sendWebhooks(ui, c.Webhooks, payload)
+	}
+
 	if len(c.SnapshotRegions) > 0 {
 		regionSet := make(map[string]struct{})
 		regions := make([]string, 0, len(c.SnapshotRegions))
 		regionSet[c.Region] = struct{}{}
 		for _, region := range c.SnapshotRegions {
+			if region == c.Region {
+				ui.Say(fmt.Sprintf("Snapshot is created in %s, skipping the transfer there", region))
+				continue
+			}
 			// If we already saw the region, then don't look again
 			if _, ok := regionSet[region]; ok {
 				continue
@@ -81,50 +140,227 @@ func (s *stepSnapshot) Run(ctx context.Context, state multistep.StateBag) multis
 			regions = append(regions, region)
 		}
 		snapshotRegions = regions
+	}
 
-		for transfer := range snapshotRegions {
-			transferRequest := &godo.ActionRequest{
-				"type":   "transfer",
-				"region": snapshotRegions[transfer],
-			}
-			imageTransfer, _, err := client.ImageActions.Transfer(context.TODO(), images[0].ID, transferRequest)
-			if err != nil {
-				err := fmt.Errorf("Error transferring snapshot: %s", err)
-				state.Put("error", err)
-				ui.Error(err.Error())
-				return multistep.ActionHalt
+	// Every requested region is pending until its transfer is done
+	availability := map[string]string{c.Region: regionAvailable}
+	for _, region := range snapshotRegions {
+		availability[region] = regionPending
+	}
+
+	available := make([]string, 0, len(snapshotRegions)+1)
+	for i, region := range snapshotRegions {
+		// Already there when the snapshot is of a previous run
+		if containsString(image.Regions, region) {
+			ui.Say(fmt.Sprintf("Snapshot is already available in %s", region))
+			availability[region] = regionAvailable
+			available = append(available, region)
+			continue
+		}
+
+		transfer := startSpan(state, "snapshot.transfer")
+		transfer.setAttr("digitalocean.snapshot.id", imageId)
+		transfer.setAttr("digitalocean.transfer.region", region)
+		transferRequest := &godo.ActionRequest{
+			"type":   "transfer",
+			"region": region,
+		}
+		imageTransfer, _, err := client.ImageActions.Transfer(context.TODO(), imageId, transferRequest)
+		if IsAlreadyAvailable(err) {
+			transfer.finish(nil)
+			ui.Say(fmt.Sprintf("Snapshot is already available in %s", region))
+			availability[region] = regionAvailable
+			available = append(available, region)
+			continue
+		}
+		if err != nil {
+			err = withIncidents(c, err, region, "Snapshots", "Images")
+			err := fmt.Errorf("Error transferring snapshot: %s", err)
+			transfer.finish(err)
+			if c.SnapshotAllowFailedTransfers {
+				ui.Warn(fmt.Sprintf("%s, leaving %s out", err, region))
+				availability[region] = regionFailed
+				continue
 			}
-			ui.Say(fmt.Sprintf("transferring Snapshot ID: %d", imageTransfer.ID))
-			if err := WaitForImageState(godo.ActionCompleted, imageTransfer.ID, action.ID,
-				client, 20*time.Minute); err != nil {
-				// If we get an error the first time, actually report it
-				err := fmt.Errorf("Error waiting for snapshot transfer: %s", err)
-				state.Put("error", err)
-				ui.Error(err.Error())
-				return multistep.ActionHalt
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		ui.Say(fmt.Sprintf("transferring Snapshot ID: %d", imageTransfer.ID))
+		emitProgress(state, progressEvent{
+			Event:    progressActionStatus,
+			Action:   "transfer",
+			ActionID: imageTransfer.ID,
+			Status:   imageTransfer.Status,
+			Region:   region,
+			Percent:  percent(i, len(snapshotRegions)),
+		})
+		err = WaitForImageState(godo.ActionCompleted, imageId, imageTransfer.ID, client, 20*time.Minute)
+		transfer.finish(err)
+		transferred := progressEvent{
+			Event:    progressActionStatus,
+			Action:   "transfer",
+			ActionID: imageTransfer.ID,
+			Status:   godo.ActionCompleted,
+			Region:   region,
+			Percent:  percent(i+1, len(snapshotRegions)),
+		}
+		if err != nil {
+			transferred.Status = "errored"
+			transferred.Error = err.Error()
+		}
+		emitProgress(state, transferred)
+		if err != nil {
+			// If we get an error the first time, actually report it
+			err = withIncidents(c, err, region, "Snapshots", "Images")
+			err := fmt.Errorf("Error waiting for snapshot transfer: %s", err)
+			if c.SnapshotAllowFailedTransfers {
+				ui.Warn(fmt.Sprintf("%s, leaving %s out", err, region))
+				availability[region] = transferOutcome(client, imageId, imageTransfer.ID)
+				continue
 			}
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
 		}
+		availability[region] = regionAvailable
+		available = append(available, region)
 	}
+	snapshotRegions = append(available, c.Region)
 
-	var imageId int
-	if len(images) == 1 {
-		imageId = images[0].ID
-	} else {
-		err := errors.New("Couldn't find snapshot to get the image ID. Bug?")
-		state.Put("error", err)
-		ui.Error(err.Error())
-		return multistep.ActionHalt
+	for _, tag := range c.SnapshotTags {
+		ui.Say(fmt.Sprintf("Tagging snapshot with %s", tag))
+		if err := tagSnapshot(client, imageId, tag); err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
 	}
-	snapshotRegions = append(snapshotRegions, c.Region)
 
-	log.Printf("Snapshot image ID: %d", imageId)
+	if c.SnapshotDescription != "" {
+		if err := describeSnapshot(client, state, c, imageId); err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	ui.Debugf("Snapshot image ID: %d", imageId)
 	state.Put("snapshot_image_id", imageId)
 	state.Put("snapshot_name", c.SnapshotName)
 	state.Put("regions", snapshotRegions)
+	state.Put("region_availability", availability)
+	state.Put("snapshot_duration", time.Since(started))
 
 	return multistep.ActionContinue
 }
 
 func (s *stepSnapshot) Cleanup(state multistep.StateBag) {
-	// no cleanup
+	c := state.Get("config").(*Config)
+	if s.snapshotId == 0 || !c.CleanupSnapshotOnError {
+		return
+	}
+
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if !cancelled && !halted {
+		return
+	}
+
+	client := state.Get("client").(*godo.Client)
+	ui := newStepUi(state, "snapshot")
+
+	ui.Say(fmt.Sprintf("Deleting snapshot %d of failed build...", s.snapshotId))
+	_, err := client.Images.Delete(context.TODO(), s.snapshotId)
+	trackDeleted(state, resourceSnapshot, s.snapshotId, err)
+	if err != nil {
+		ui.Error(fmt.Sprintf(
+			"Error deleting snapshot. Please delete it manually: %s", err))
+	}
+}
+
+// transferOutcome tells whether a transfer that wasn't waited for to the end
+// failed, or is still running.
+func transferOutcome(client *godo.Client, imageId, actionId int) string {
+	action, _, err := client.ImageActions.Get(context.TODO(), imageId, actionId)
+	if err == nil && action.Status == godo.ActionInProgress {
+		return regionPending
+	}
+	return regionFailed
+}
+
+func tagSnapshot(client *godo.Client, imageId int, tag string) error {
+	// Creating a tag that already exists is a no-op
+	_, _, err := client.Tags.Create(context.TODO(), &godo.TagCreateRequest{Name: tag})
+	if err != nil {
+		return fmt.Errorf("Error creating tag %s: %s", tag, err)
+	}
+
+	_, err = client.Tags.TagResources(context.TODO(), tag, &godo.TagResourcesRequest{
+		Resources: []godo.Resource{
+			{ID: strconv.Itoa(imageId), Type: godo.ImageResourceType},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("Error tagging snapshot %d with %s: %s", imageId, tag, err)
+	}
+
+	return nil
+}
+
+// findDropletSnapshot returns the snapshot of the droplet with the given
+// name, going through all the pages of its snapshots.
+func findDropletSnapshot(client *godo.Client, dropletId int, name string) (*godo.Image, error) {
+	opt := &godo.ListOptions{
+		Page:    1,
+		PerPage: 200,
+	}
+	for {
+		images, resp, err := client.Droplets.Snapshots(context.TODO(), dropletId, opt)
+		if err != nil {
+			return nil, err
+		}
+		for i := range images {
+			if images[i].Name == name {
+				return &images[i], nil
+			}
+		}
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			break
+		}
+		opt.Page++
+	}
+	return nil, nil
+}
+
+// describeSnapshot sets the rendered snapshot_description on the snapshot.
+func describeSnapshot(client *godo.Client, state multistep.StateBag, c *Config, imageId int) error {
+	data := map[string]interface{}{}
+	if generated, ok := state.GetOk("generated_data"); ok {
+		for k, v := range generated.(map[string]interface{}) {
+			data[k] = v
+		}
+	}
+	data["SnapshotName"] = c.SnapshotName
+	data["SnapshotID"] = imageId
+	data["SourceImage"] = c.Image
+	data["Region"] = c.Region
+	if dropletId, ok := state.GetOk("droplet_id"); ok {
+		data["DropletID"] = dropletId
+	}
+
+	ctx := c.ctx
+	ctx.Data = data
+	description, err := interpolate.Render(c.SnapshotDescription, &ctx)
+	if err != nil {
+		return fmt.Errorf("Error rendering snapshot_description: %s", err)
+	}
+	_, _, err = client.Images.Update(context.TODO(), imageId, &godo.ImageUpdateRequest{
+		Name:        c.SnapshotName,
+		Description: description,
+	})
+	if err != nil {
+		return fmt.Errorf("Error setting the description of snapshot %d: %s", imageId, err)
+	}
+	return nil
 }
@@ -0,0 +1,308 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// fileManifestCommand prints the sha256sum of every regular file of the root
+// filesystem, leaving out the same directories as filesystemDigestCommand.
+const fileManifestCommand = `sh -c 'cd / && find . -xdev \( -path ./tmp -o -path ./var/tmp -o -path ./var/log \) -prune -o -type f -print0 | LC_ALL=C sort -z | xargs -0 -r sha256sum'`
+
+// imageManifest is what an image holds, the version of each package and the
+// sha256 of each file by path.
+type imageManifest struct {
+	Packages map[string]string
+	Files    map[string]string
+}
+
+// manifestDiff lists what was added, removed or changed between two
+// manifests, sorted.
+type manifestDiff struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	Changed []string `json:"changed"`
+}
+
+// snapshotDiff is the difference between the new snapshot and the image it
+// is compared to.
+type snapshotDiff struct {
+	Previous string       `json:"previous"`
+	Packages manifestDiff `json:"packages"`
+	Files    manifestDiff `json:"files"`
+}
+
+// stepSnapshotDiff boots a droplet from the previous image and one from the
+// new snapshot, lists what each of them holds, and writes the differences
+// to snapshot_diff_file.
+type stepSnapshotDiff struct {
+	dropletIds []int
+
+	// dial connects to the droplets, tests replace it.
+	dial sshDialFunc
+}
+
+func (s *stepSnapshotDiff) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*godo.Client)
+	ui := newStepUi(state, "snapshot_diff")
+	c := state.Get("config").(*Config)
+	imageId := state.Get("snapshot_image_id").(int)
+
+	previous, err := previousImage(client, state, c)
+	if err != nil {
+		err := fmt.Errorf("Error looking up the image to compare the snapshot to: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	if previous == nil {
+		ui.Say("No previous version to compare the snapshot to, skipping snapshot_diff_file")
+		return multistep.ActionContinue
+	}
+
+	ui.Say(fmt.Sprintf("Comparing snapshot to %s (%d)...", previous.Name, previous.ID))
+	var manifests [2]*imageManifest
+	for i, image := range []struct {
+		id   int
+		name string
+	}{
+		{previous.ID, c.DropletName + "-diff-previous"},
+		{imageId, c.DropletName + "-diff-new"},
+	} {
+		manifest, err := s.listImage(ctx, state, ui, image.name, image.id)
+		if err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		manifests[i] = manifest
+	}
+
+	diff := &snapshotDiff{
+		Previous: previous.Name,
+		Packages: diffPackages(manifests[0].Packages, manifests[1].Packages),
+		Files:    diffFiles(manifests[0].Files, manifests[1].Files),
+	}
+	if err := diff.write(c.SnapshotDiffFile, c.SnapshotName); err != nil {
+		err := fmt.Errorf("Error writing snapshot_diff_file: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	ui.Message(fmt.Sprintf("%d packages and %d files differ from %s, written to %s",
+		diff.Packages.count(), diff.Files.count(), previous.Name, c.SnapshotDiffFile))
+	state.Put("snapshot_diff", diff)
+
+	return multistep.ActionContinue
+}
+
+// listImage boots a droplet from the image, lists its packages and files,
+// and destroys it.
+func (s *stepSnapshotDiff) listImage(ctx context.Context, state multistep.StateBag, ui *stepUi, name string, imageId int) (*imageManifest, error) {
+	client := state.Get("client").(*godo.Client)
+	c := state.Get("config").(*Config)
+
+	dropletId, sshClient, err := bootImageDroplet(ctx, state, ui, name, "diff droplet", imageId, c.Size, s.dial)
+	if dropletId != 0 {
+		// Destroyed by Cleanup if listing fails
+		s.dropletIds = append(s.dropletIds, dropletId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer sshClient.Close()
+
+	sudo := ""
+	if c.Comm.SSHUsername != "root" {
+		sudo = "sudo "
+	}
+	output, err := runSSHCommand(sshClient, sudo+packageInventoryCommand)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing packages of image %d: %s", imageId, err)
+	}
+	inventory, err := parsePackageInventory(output)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing packages of image %d: %s", imageId, err)
+	}
+	output, err = runSSHCommand(sshClient, sudo+fileManifestCommand)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing files of image %d: %s", imageId, err)
+	}
+	manifest := &imageManifest{Packages: inventory.versions(), Files: parseFileManifest(output)}
+
+	if err := DestroyDroplet(client, dropletId, c.StateTimeout); err != nil {
+		trackDeleted(state, resourceDroplet, dropletId, err)
+		return nil, fmt.Errorf("Error destroying diff droplet: %s", err)
+	}
+	trackDeleted(state, resourceDroplet, dropletId, nil)
+	s.dropletIds = s.dropletIds[:len(s.dropletIds)-1]
+	return manifest, nil
+}
+
+func (s *stepSnapshotDiff) Cleanup(state multistep.StateBag) {
+	if len(s.dropletIds) == 0 {
+		return
+	}
+
+	client := state.Get("client").(*godo.Client)
+	c := state.Get("config").(*Config)
+	ui := newStepUi(state, "snapshot_diff")
+
+	ui.Say("Destroying diff droplets...")
+	for _, id := range s.dropletIds {
+		err := DestroyDroplet(client, id, c.StateTimeout)
+		trackDeleted(state, resourceDroplet, id, err)
+		if err != nil {
+			ui.Error(fmt.Sprintf(
+				"Error destroying diff droplet %d. Please destroy it manually: %s", id, err))
+		}
+	}
+}
+
+// previousImage returns the image to compare the new snapshot to, or nil for
+// the first version of snapshot_version_prefix.
+func previousImage(client *godo.Client, state multistep.StateBag, c *Config) (*godo.Image, error) {
+	if c.SnapshotDiffImage == "" {
+		name, ok := state.GetOk("previous_snapshot_name")
+		if !ok {
+			return nil, nil
+		}
+		return findImageByName(client, name.(string))
+	}
+
+	image := getImageType(c.SnapshotDiffImage)
+	if image.ID != 0 {
+		found, _, err := client.Images.GetByID(context.TODO(), image.ID)
+		return found, err
+	}
+	if found, _, err := client.Images.GetBySlug(context.TODO(), image.Slug); err == nil {
+		return found, nil
+	}
+	return findImageByName(client, image.Slug)
+}
+
+// runSSHCommand runs a command and returns its output, which is part of the
+// error when it fails.
+func runSSHCommand(client *gossh.Client, command string) (string, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+	var stderr strings.Builder
+	session.Stderr = &stderr
+	output, err := session.Output(command)
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return string(output), nil
+}
+
+// parseFileManifest parses the output of fileManifestCommand.
+func parseFileManifest(output string) map[string]string {
+	files := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		// The sum, two spaces and the path, which may have spaces
+		parts := strings.SplitN(line, "  ", 2)
+		if len(parts) != 2 || !sha256Re.MatchString(parts[0]) {
+			continue
+		}
+		files[strings.TrimPrefix(parts[1], ".")] = parts[0]
+	}
+	return files
+}
+
+// diffPackages compares the package versions of two images, listing the
+// packages as name and version, and the changed ones with both versions.
+func diffPackages(from, to map[string]string) manifestDiff {
+	var diff manifestDiff
+	for name, version := range to {
+		previous, ok := from[name]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, name+" "+version)
+		case previous != version:
+			diff.Changed = append(diff.Changed, fmt.Sprintf("%s %s -> %s", name, previous, version))
+		}
+	}
+	for name, version := range from {
+		if _, ok := to[name]; !ok {
+			diff.Removed = append(diff.Removed, name+" "+version)
+		}
+	}
+	diff.sort()
+	return diff
+}
+
+// diffFiles compares the files of two images by path.
+func diffFiles(from, to map[string]string) manifestDiff {
+	var diff manifestDiff
+	for path, sum := range to {
+		previous, ok := from[path]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, path)
+		case previous != sum:
+			diff.Changed = append(diff.Changed, path)
+		}
+	}
+	for path := range from {
+		if _, ok := to[path]; !ok {
+			diff.Removed = append(diff.Removed, path)
+		}
+	}
+	diff.sort()
+	return diff
+}
+
+func (d *manifestDiff) sort() {
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Strings(d.Changed)
+}
+
+func (d *manifestDiff) count() int {
+	return len(d.Added) + len(d.Removed) + len(d.Changed)
+}
+
+// write saves the diff as a text report, with a line for each package or
+// file, prefixed with +, - or ~ for the added, removed and changed ones.
+func (d *snapshotDiff) write(path, snapshotName string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Changes of %s since %s\n", snapshotName, d.Previous)
+	for _, section := range []struct {
+		title string
+		diff  manifestDiff
+	}{
+		{"Packages", d.Packages},
+		{"Files", d.Files},
+	} {
+		fmt.Fprintf(&b, "\n%s:\n", section.title)
+		if section.diff.count() == 0 {
+			b.WriteString("  none\n")
+		}
+		for _, line := range section.diff.Added {
+			fmt.Fprintf(&b, "+ %s\n", line)
+		}
+		for _, line := range section.diff.Removed {
+			fmt.Fprintf(&b, "- %s\n", line)
+		}
+		for _, line := range section.diff.Changed {
+			fmt.Fprintf(&b, "~ %s\n", line)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, []byte(b.String()), 0644)
+}
@@ -0,0 +1,142 @@
+package digitalocean
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+const (
+	testSumA = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	testSumB = "60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752"
+)
+
+// testImageServer starts an SSH server answering the commands listing the
+// packages and files of an image.
+func testImageServer(t *testing.T, packages, files string) string {
+	return testSSHServerFunc(t, func(command string) (string, uint32) {
+		switch {
+		case strings.Contains(command, "dpkg-query"):
+			return "deb ubuntu\n" + packages, 0
+		case strings.Contains(command, "sha256sum"):
+			return files, 0
+		}
+		return "", 127
+	})
+}
+
+func TestStepSnapshotDiff(t *testing.T) {
+	sim, client := testSimulator(t)
+	previous := sim.AddImage(godo.Image{Name: "web-v1", Type: "snapshot", Regions: []string{"nyc3"}})
+	image := sim.AddImage(godo.Image{Name: "web-v2", Type: "snapshot", Regions: []string{"nyc3"}})
+
+	addrs := []string{
+		testImageServer(t,
+			"ii nginx 1.18.0-6 amd64\nii apache2 2.4.52-1 amd64\nii curl 7.81.0-1 amd64\n",
+			testSumA+"  ./etc/nginx/nginx.conf\n"+testSumA+"  ./etc/apache2/apache2.conf\n"+testSumA+"  ./usr/bin/curl\n"),
+		testImageServer(t,
+			"ii nginx 1.18.0-7 amd64\nii curl 7.81.0-1 amd64\nii jq 1.6-2 amd64\n",
+			testSumB+"  ./etc/nginx/nginx.conf\n"+testSumA+"  ./usr/bin/curl\n"+testSumA+"  ./usr/bin/jq\n"),
+	}
+	dials := 0
+
+	clientKey, _, err := generateHostKey()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	path := filepath.Join(t.TempDir(), "diff.txt")
+
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("snapshot_image_id", image.ID)
+	state.Put("previous_snapshot_name", "web-v1")
+	state.Put("config", &Config{
+		DropletName:      "packer-test",
+		SnapshotName:     "web-v2",
+		Region:           "nyc3",
+		Size:             "s-1vcpu-1gb",
+		SnapshotDiffFile: path,
+		StateTimeout:     time.Second,
+		Comm: communicator.Config{
+			Type: "ssh",
+			SSH: communicator.SSH{
+				SSHUsername:   "root",
+				SSHPort:       22,
+				SSHTimeout:    time.Second,
+				SSHPrivateKey: []byte(clientKey),
+			},
+		},
+	})
+
+	step := &stepSnapshotDiff{
+		dial: func(network, _ string, config *gossh.ClientConfig) (*gossh.Client, error) {
+			addr := addrs[dials]
+			dials++
+			return gossh.Dial(network, addr, config)
+		},
+	}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected action continue, got %#v: %v", action, state.Get("error"))
+	}
+	step.Cleanup(state)
+	if droplets := sim.Droplets(); len(droplets) != 0 {
+		t.Fatalf("expected diff droplets to be destroyed, got %#v", droplets)
+	}
+
+	diff := state.Get("snapshot_diff").(*snapshotDiff)
+	expected := &snapshotDiff{
+		Previous: previous.Name,
+		Packages: manifestDiff{
+			Added:   []string{"jq 1.6-2"},
+			Removed: []string{"apache2 2.4.52-1"},
+			Changed: []string{"nginx 1.18.0-6 -> 1.18.0-7"},
+		},
+		Files: manifestDiff{
+			Added:   []string{"/usr/bin/jq"},
+			Removed: []string{"/etc/apache2/apache2.conf"},
+			Changed: []string{"/etc/nginx/nginx.conf"},
+		},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("got %#v, expected %#v", diff, expected)
+	}
+
+	report, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"Changes of web-v2 since web-v1", "+ jq 1.6-2", "- /etc/apache2/apache2.conf", "~ nginx 1.18.0-6 -> 1.18.0-7"} {
+		if !strings.Contains(string(report), line) {
+			t.Errorf("report is missing %q:\n%s", line, report)
+		}
+	}
+}
+
+func TestStepSnapshotDiff_FirstVersion(t *testing.T) {
+	_, client := testSimulator(t)
+
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("snapshot_image_id", 1234)
+	state.Put("config", &Config{SnapshotDiffFile: filepath.Join(t.TempDir(), "diff.txt"), SnapshotVersionPrefix: "web-"})
+
+	step := new(stepSnapshotDiff)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected action continue, got %#v: %v", action, state.Get("error"))
+	}
+	if _, ok := state.GetOk("snapshot_diff"); ok {
+		t.Error("unexpected diff without a previous version")
+	}
+}
@@ -0,0 +1,204 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-digitalocean/internal/simulator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestStepSnapshot_CleanupOnError(t *testing.T) {
+	for _, cleanup := range []bool{true, false} {
+		sim, client := testSimulator(t)
+		droplet := sim.AddDroplet(godo.Droplet{Name: "packer-test", Region: &godo.Region{Slug: "nyc3"}, Status: "off"})
+		sim.Inject(simulator.Fault{Method: http.MethodPost, Path: "/v2/images", Status: http.StatusInternalServerError,
+			ID: "server_error", Message: "Server was unable to give you a response."})
+
+		state := new(multistep.BasicStateBag)
+		state.Put("client", client)
+		state.Put("ui", packersdk.TestUi(t))
+		state.Put("droplet_id", droplet.ID)
+		state.Put("config", &Config{
+			SnapshotName:           "packer-test",
+			Region:                 "nyc3",
+			SnapshotRegions:        []string{"ams3"},
+			CleanupSnapshotOnError: cleanup,
+		})
+
+		step := &stepSnapshot{snapshotTimeout: time.Second}
+		if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+			t.Fatalf("expected action halt, got %#v", action)
+		}
+		if len(sim.Images()) != 2 {
+			t.Fatalf("expected the snapshot to exist, got %#v", sim.Images())
+		}
+
+		state.Put(multistep.StateHalted, true)
+		step.Cleanup(state)
+
+		if _, ok := sim.Image(step.snapshotId); ok == cleanup {
+			t.Fatalf("cleanup_snapshot_on_error = %t, but snapshot exists = %t", cleanup, ok)
+		}
+	}
+}
+
+func TestStepSnapshot_Tags(t *testing.T) {
+	sim, client := testSimulator(t)
+	droplet := sim.AddDroplet(godo.Droplet{Name: "packer-test", Region: &godo.Region{Slug: "nyc3"}, Status: "off"})
+
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("droplet_id", droplet.ID)
+	state.Put("config", &Config{
+		SnapshotName: "packer-test",
+		Region:       "nyc3",
+		SnapshotTags: []string{"golden", "build-date:2021-07-08"},
+	})
+
+	step := &stepSnapshot{snapshotTimeout: time.Second}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected action continue, got %#v: %s", action, state.Get("error"))
+	}
+
+	image, ok := sim.Image(state.Get("snapshot_image_id").(int))
+	if !ok {
+		t.Fatal("expected the snapshot to exist")
+	}
+	if !reflect.DeepEqual(image.Tags, []string{"golden", "build-date:2021-07-08"}) {
+		t.Errorf("got tags %v", image.Tags)
+	}
+}
+
+func TestStepSnapshot_Description(t *testing.T) {
+	sim, client := testSimulator(t)
+	droplet := sim.AddDroplet(godo.Droplet{Name: "packer-test", Region: &godo.Region{Slug: "nyc3"}, Status: "off"})
+
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("droplet_id", droplet.ID)
+	state.Put("generated_data", map[string]interface{}{"SnapshotVersion": "1.4.0"})
+	state.Put("config", &Config{
+		SnapshotName:        "packer-test",
+		Region:              "nyc3",
+		Image:               "ubuntu-22-04-x64",
+		SnapshotDescription: "{{ .SnapshotName }} {{ .SnapshotVersion }} from {{ .SourceImage }} in {{ .Region }}",
+	})
+
+	step := &stepSnapshot{snapshotTimeout: time.Second}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected action continue, got %#v: %s", action, state.Get("error"))
+	}
+
+	image, ok := sim.Image(state.Get("snapshot_image_id").(int))
+	if !ok {
+		t.Fatal("expected the snapshot to exist")
+	}
+	if expected := "packer-test 1.4.0 from ubuntu-22-04-x64 in nyc3"; image.Description != expected {
+		t.Errorf("got description %q, expected %q", image.Description, expected)
+	}
+}
+
+func TestStepSnapshot_ManySnapshots(t *testing.T) {
+	sim, client := testSimulator(t)
+	// A droplet kept by a checkpoint can have older snapshots, more than
+	// fit a page
+	var ids []int
+	for i := 0; i < 210; i++ {
+		ids = append(ids, sim.AddImage(godo.Image{Name: fmt.Sprintf("packer-old-%d", i), Type: "snapshot"}).ID)
+	}
+	droplet := sim.AddDroplet(godo.Droplet{Name: "packer-test", Region: &godo.Region{Slug: "nyc3"}, Status: "off", SnapshotIDs: ids})
+
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("droplet_id", droplet.ID)
+	state.Put("config", &Config{
+		SnapshotName: "packer-test",
+		Region:       "nyc3",
+	})
+
+	step := &stepSnapshot{snapshotTimeout: time.Second}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected action continue, got %#v: %s", action, state.Get("error"))
+	}
+
+	image, ok := sim.Image(state.Get("snapshot_image_id").(int))
+	if !ok || image.Name != "packer-test" {
+		t.Fatalf("expected the new snapshot, got %#v", image)
+	}
+}
+
+func TestStepSnapshot_AllowFailedTransfers(t *testing.T) {
+	sim, client := testSimulator(t)
+	droplet := sim.AddDroplet(godo.Droplet{Name: "packer-test", Region: &godo.Region{Slug: "nyc3"}, Status: "off"})
+	// The transfer to ams3 is refused
+	sim.Inject(simulator.Fault{Method: http.MethodPost, Path: "/v2/images", Status: http.StatusInternalServerError,
+		ID: "server_error", Message: "Server was unable to give you a response.", Times: 1})
+
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("droplet_id", droplet.ID)
+	state.Put("config", &Config{
+		SnapshotName:                 "packer-test",
+		Region:                       "nyc3",
+		SnapshotRegions:              []string{"ams3", "sfo3"},
+		SnapshotAllowFailedTransfers: true,
+	})
+
+	step := &stepSnapshot{snapshotTimeout: time.Second}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected action continue, got %#v: %s", action, state.Get("error"))
+	}
+
+	if regions := state.Get("regions").([]string); !reflect.DeepEqual(regions, []string{"sfo3", "nyc3"}) {
+		t.Errorf("expected the snapshot to be in sfo3 and nyc3, got %v", regions)
+	}
+	expected := map[string]string{
+		"nyc3": regionAvailable,
+		"ams3": regionFailed,
+		"sfo3": regionAvailable,
+	}
+	if availability := state.Get("region_availability").(map[string]string); !reflect.DeepEqual(availability, expected) {
+		t.Errorf("expected availability %v, got %v", expected, availability)
+	}
+}
+
+func TestStepSnapshot_AlreadyAvailable(t *testing.T) {
+	sim, client := testSimulator(t)
+	droplet := sim.AddDroplet(godo.Droplet{Name: "packer-test", Region: &godo.Region{Slug: "nyc3"}, Status: "off"})
+	// A transfer to ams3 from an earlier attempt went through
+	sim.Inject(simulator.Fault{Method: http.MethodPost, Path: "/v2/images", Status: http.StatusUnprocessableEntity,
+		ID: "unprocessable_entity", Message: "Image is already available in region ams3.", Times: 1})
+
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("droplet_id", droplet.ID)
+	state.Put("config", &Config{
+		SnapshotName:    "packer-test",
+		Region:          "nyc3",
+		SnapshotRegions: []string{"ams3", "sfo3", "nyc3"},
+	})
+
+	step := &stepSnapshot{snapshotTimeout: time.Second}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected action continue, got %#v: %s", action, state.Get("error"))
+	}
+
+	if regions := state.Get("regions").([]string); !reflect.DeepEqual(regions, []string{"ams3", "sfo3", "nyc3"}) {
+		t.Errorf("expected the snapshot to be in ams3, sfo3 and nyc3, got %v", regions)
+	}
+	if availability := state.Get("region_availability").(map[string]string); availability["ams3"] != regionAvailable {
+		t.Errorf("expected the snapshot to be available in ams3, got %v", availability)
+	}
+}
@@ -0,0 +1,74 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+// stepSnapshotVolumes snapshots the volumes of the droplet, once it is
+// powered off and snapshotted, so that the volume snapshots are of the same
+// state as the image.
+type stepSnapshotVolumes struct {
+	snapshotIds []string
+}
+
+func (s *stepSnapshotVolumes) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*godo.Client)
+	ui := newStepUi(state, "snapshot_volumes")
+	c := state.Get("config").(*Config)
+	volumeIds := state.Get("volume_ids").([]string)
+
+	snapshots := make(map[string]interface{}, len(volumeIds))
+	for i, id := range volumeIds {
+		volume := c.Volumes[i]
+		name := c.SnapshotName + "-" + volume.Name
+		ui.Say(fmt.Sprintf("Creating snapshot of volume %s: %s", volume.Name, name))
+		snapshot, _, err := client.Storage.CreateSnapshot(ctx, &godo.SnapshotCreateRequest{
+			VolumeID: id,
+			Name:     name,
+			Tags:     c.SnapshotTags,
+		})
+		if err != nil {
+			err := fmt.Errorf("Error creating snapshot of volume %s: %s", volume.Name, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		ui.Debugf("Volume %s snapshot ID: %s", volume.Name, snapshot.ID)
+		s.snapshotIds = append(s.snapshotIds, snapshot.ID)
+		trackCreated(state, resourceVolumeSnapshot, snapshot.ID, name)
+		snapshots[volume.Name] = snapshot.ID
+	}
+
+	state.Put("volume_snapshots", snapshots)
+	return multistep.ActionContinue
+}
+
+func (s *stepSnapshotVolumes) Cleanup(state multistep.StateBag) {
+	c := state.Get("config").(*Config)
+	if len(s.snapshotIds) == 0 || !c.CleanupSnapshotOnError {
+		return
+	}
+
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if !cancelled && !halted {
+		return
+	}
+
+	client := state.Get("client").(*godo.Client)
+	ui := newStepUi(state, "snapshot_volumes")
+
+	ui.Say("Deleting volume snapshots of failed build...")
+	for _, id := range s.snapshotIds {
+		_, err := client.Storage.DeleteSnapshot(context.TODO(), id)
+		trackDeleted(state, resourceVolumeSnapshot, id, err)
+		if err != nil {
+			ui.Error(fmt.Sprintf(
+				"Error deleting volume snapshot %s. Please delete it manually: %s", id, err))
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestStepSnapshotVolumes(t *testing.T) {
+	for _, cleanup := range []bool{true, false} {
+		sim, client := testSimulator(t)
+
+		state := new(multistep.BasicStateBag)
+		state.Put("client", client)
+		state.Put("ui", packersdk.TestUi(t))
+		state.Put("config", &Config{DropletName: "packer-test", Region: "nyc3", SnapshotName: "appliance-1.0",
+			SnapshotTags: []string{"appliance"}, CleanupSnapshotOnError: cleanup,
+			Volumes: []Volume{{Name: "data", Size: 100}}})
+
+		volumes := new(stepCreateVolumes)
+		if action := volumes.Run(context.Background(), state); action != multistep.ActionContinue {
+			t.Fatalf("expected action continue, got %#v: %s", action, state.Get("error"))
+		}
+
+		step := new(stepSnapshotVolumes)
+		if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+			t.Fatalf("expected action continue, got %#v: %s", action, state.Get("error"))
+		}
+		snapshots := state.Get("volume_snapshots").(map[string]interface{})
+		id, _ := snapshots["data"].(string)
+		snapshot, ok := sim.VolumeSnapshot(id)
+		if !ok {
+			t.Fatalf("expected the volume snapshot to exist, got %#v", snapshots)
+		}
+		if snapshot.Name != "appliance-1.0-data" || !reflect.DeepEqual(snapshot.Tags, []string{"appliance"}) {
+			t.Errorf("unexpected volume snapshot %#v", snapshot)
+		}
+
+		state.Put(multistep.StateHalted, true)
+		step.Cleanup(state)
+		if _, ok := sim.VolumeSnapshot(id); ok == cleanup {
+			t.Fatalf("cleanup_snapshot_on_error = %t, but volume snapshot exists = %t", cleanup, ok)
+		}
+	}
+}
+
+func TestArtifact_VolumeSnapshots(t *testing.T) {
+	sim, client := testSimulator(t)
+	image := sim.AddImage(godo.Image{Name: "appliance-1.0", Type: "snapshot", Regions: []string{"nyc3"}})
+
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("config", &Config{Region: "nyc3", SnapshotName: "appliance-1.0",
+		Volumes: []Volume{{Name: "data", Size: 100}, {Name: "logs", Size: 10}}})
+	if action := new(stepCreateVolumes).Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected action continue, got %#v: %s", action, state.Get("error"))
+	}
+	if action := new(stepSnapshotVolumes).Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected action continue, got %#v: %s", action, state.Get("error"))
+	}
+	snapshots := state.Get("volume_snapshots").(map[string]interface{})
+
+	a := &Artifact{
+		SnapshotName: image.Name,
+		SnapshotId:   image.ID,
+		RegionNames:  image.Regions,
+		Client:       client,
+		StateData:    map[string]interface{}{"volume_snapshots": snapshots},
+	}
+	expected := fmt.Sprintf("A snapshot was created: 'appliance-1.0' (ID: %d) in regions 'nyc3', "+
+		"along with snapshots of volumes 'data' (ID: %s), 'logs' (ID: %s)", image.ID, snapshots["data"], snapshots["logs"])
+	if a.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, a.String())
+	}
+
+	if err := a.Destroy(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, ok := sim.Image(image.ID); ok {
+		t.Error("expected the image to be deleted")
+	}
+	for name, id := range snapshots {
+		if _, ok := sim.VolumeSnapshot(id.(string)); ok {
+			t.Errorf("expected the snapshot of volume %s to be deleted", name)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+// stepSourceImage resolves source_image_filter, or an image given by name
+// rather than by slug or ID, to the image the droplet is created from.
+type stepSourceImage struct{}
+
+func (s *stepSourceImage) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*godo.Client)
+	ui := newStepUi(state, "source_image")
+	c := state.Get("config").(*Config)
+
+	if c.Distribution != "" {
+		ui.Say("Looking up distribution image...")
+		image, err := findDistributionImage(client, c.Distribution, c.Version)
+		if err != nil {
+			err := fmt.Errorf("Error looking up distribution image: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		ui.Message(fmt.Sprintf("Using image %s %s (%s)", image.Distribution, image.Name, image.Slug))
+		c.Image = image.Slug
+		state.Put("source_image", image)
+		return multistep.ActionContinue
+	}
+
+	var image *godo.Image
+	var err error
+	if !c.SourceImageFilter.Empty() {
+		ui.Say("Looking up source image...")
+		image, err = findImage(client, &c.SourceImageFilter)
+	} else if slug := getImageType(c.Image).Slug; slug != "" {
+		var resp *godo.Response
+		_, resp, err = client.Images.GetBySlug(context.TODO(), slug)
+		if err == nil || resp == nil || resp.StatusCode != http.StatusNotFound {
+			// A slug, or an error that isn't ours to report
+			return multistep.ActionContinue
+		}
+		image, err = findImageByName(client, slug)
+	} else {
+		return multistep.ActionContinue
+	}
+	if err != nil {
+		err := fmt.Errorf("Error looking up source image: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Message(fmt.Sprintf("Using image %s (%d)", image.Name, image.ID))
+	c.Image = strconv.Itoa(image.ID)
+	state.Put("source_image", image)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepSourceImage) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
+
+// sourceImage returns the image the droplet is created from, or nil for the
+// DigitalOcean images given by slug.
+func sourceImage(client *godo.Client, state multistep.StateBag) (*godo.Image, error) {
+	if image, ok := state.GetOk("source_image"); ok {
+		return image.(*godo.Image), nil
+	}
+
+	c := state.Get("config").(*Config)
+	id := getImageType(c.Image).ID
+	if id == 0 {
+		return nil, nil
+	}
+	image, _, err := client.Images.GetByID(context.TODO(), id)
+	return image, err
+}
+
+// findImage returns the image matching the filter.
+func findImage(client *godo.Client, filter *ImageFilter) (*godo.Image, error) {
+	name, err := regexp.Compile(filter.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	opt := &godo.ListOptions{
+		Page:    1,
+		PerPage: 200,
+	}
+	var matches []godo.Image
+	for {
+		var images []godo.Image
+		var resp *godo.Response
+		if filter.Tag != "" {
+			images, resp, err = client.Images.ListByTag(context.TODO(), filter.Tag, opt)
+		} else {
+			images, resp, err = client.Images.List(context.TODO(), opt)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, image := range images {
+			if filter.Type != "" && image.Type != filter.Type {
+				continue
+			}
+			if !name.MatchString(image.Name) {
+				continue
+			}
+			matches = append(matches, image)
+		}
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			break
+		}
+		opt.Page++
+	}
+
+	switch {
+	case len(matches) == 0:
+		return nil, fmt.Errorf("no image matches source_image_filter")
+	case len(matches) > 1 && !filter.MostRecent:
+		return nil, fmt.Errorf("%d images match source_image_filter, set most_recent to use the latest one", len(matches))
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return imageCreated(matches[i]).After(imageCreated(matches[j]))
+	})
+	return &matches[0], nil
+}
+
+// findImageByName returns the snapshot or custom image with the given name.
+func findImageByName(client *godo.Client, name string) (*godo.Image, error) {
+	opt := &godo.ListOptions{
+		Page:    1,
+		PerPage: 200,
+	}
+	var matches []godo.Image
+	for {
+		images, resp, err := client.Images.ListUser(context.TODO(), opt)
+		if err != nil {
+			return nil, err
+		}
+		for _, image := range images {
+			if image.Name == name {
+				matches = append(matches, image)
+			}
+		}
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			break
+		}
+		opt.Page++
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, imageNotFoundError(client, name)
+	case 1:
+		return &matches[0], nil
+	}
+
+	ids := make([]string, 0, len(matches))
+	for _, image := range matches {
+		ids = append(ids, strconv.Itoa(image.ID))
+	}
+	return nil, fmt.Errorf("%d images are named %s (%s), use an image ID instead",
+		len(matches), name, strings.Join(ids, ", "))
+}
+
+// findDistributionImage returns the newest image of the distribution whose
+// name starts with version, "lts" standing for the long term support
+// releases and "" for every release.
+func findDistributionImage(client *godo.Client, distribution, version string) (*godo.Image, error) {
+	opt := &godo.ListOptions{
+		Page:    1,
+		PerPage: 200,
+	}
+	var matches []godo.Image
+	var releases []string
+	for {
+		images, resp, err := client.Images.ListDistribution(context.TODO(), opt)
+		if err != nil {
+			return nil, err
+		}
+		for _, image := range images {
+			if !strings.EqualFold(image.Distribution, distribution) || image.Slug == "" {
+				continue
+			}
+			releases = append(releases, image.Name)
+			if matchesVersion(image.Name, version) {
+				matches = append(matches, image)
+			}
+		}
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			break
+		}
+		opt.Page++
+	}
+
+	if len(matches) == 0 {
+		if len(releases) == 0 {
+			return nil, fmt.Errorf("no %s images", distribution)
+		}
+		sort.Strings(releases)
+		return nil, fmt.Errorf("no %s image of version %s, the releases are: %s",
+			distribution, version, strings.Join(releases, ", "))
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		vi, vj := releaseVersion(matches[i].Name), releaseVersion(matches[j].Name)
+		for k := 0; k < len(vi) && k < len(vj); k++ {
+			if vi[k] != vj[k] {
+				return vi[k] > vj[k]
+			}
+		}
+		if len(vi) != len(vj) {
+			return len(vi) > len(vj)
+		}
+		return imageCreated(matches[i]).After(imageCreated(matches[j]))
+	})
+	return &matches[0], nil
+}
+
+// matchesVersion reports whether the name of a distribution image, such as
+// "22.04 (LTS) x64", is of the given version.
+func matchesVersion(name, version string) bool {
+	switch version {
+	case "":
+		return true
+	case "lts", "LTS":
+		return strings.Contains(name, "LTS")
+	}
+	if !strings.HasPrefix(name, version) {
+		return false
+	}
+	// 22 matches 22.04, but not 220
+	rest := name[len(version):]
+	return rest == "" || rest[0] < '0' || rest[0] > '9'
+}
+
+// releaseVersion parses the release a distribution image name starts with,
+// such as 22.04 in "22.04 (LTS) x64".
+func releaseVersion(name string) []int {
+	fields := strings.Fields(name)
+	if len(fields) == 0 {
+		return nil
+	}
+	var version []int
+	for _, part := range strings.Split(fields[0], ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			break
+		}
+		version = append(version, n)
+	}
+	return version
+}
+
+func imageCreated(image godo.Image) time.Time {
+	created, err := time.Parse(time.RFC3339, image.Created)
+	if err != nil {
+		logf(levelDebug, []interface{}{"image_id", image.ID}, "Unable to parse image creation time: %s", err)
+	}
+	return created
+}
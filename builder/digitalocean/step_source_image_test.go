@@ -0,0 +1,138 @@
+package digitalocean
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestStepSourceImage(t *testing.T) {
+	sim, client := testSimulator(t)
+	older := sim.AddImage(godo.Image{Name: "base-2021-07-01", Type: "snapshot", Tags: []string{"golden-base"}, Created: "2021-07-01T10:00:00Z"})
+	newer := sim.AddImage(godo.Image{Name: "base-2021-07-08", Type: "snapshot", Tags: []string{"golden-base"}, Created: "2021-07-08T10:00:00Z"})
+	sim.AddImage(godo.Image{Name: "base-2021-07-15", Type: "snapshot", Created: "2021-07-15T10:00:00Z"})
+
+	tt := []struct {
+		Name   string
+		Filter ImageFilter
+		Image  int
+		Action multistep.StepAction
+	}{
+		{Name: "MostRecent", Filter: ImageFilter{Name: "^base-", Tag: "golden-base", MostRecent: true}, Image: newer.ID, Action: multistep.ActionContinue},
+		{Name: "Single", Filter: ImageFilter{Name: "07-01$", Type: "snapshot"}, Image: older.ID, Action: multistep.ActionContinue},
+		{Name: "Ambiguous", Filter: ImageFilter{Name: "^base-", Tag: "golden-base"}, Action: multistep.ActionHalt},
+		{Name: "NoMatch", Filter: ImageFilter{Name: "^base-", Type: "custom"}, Action: multistep.ActionHalt},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			config := &Config{SourceImageFilter: tc.Filter}
+
+			state := new(multistep.BasicStateBag)
+			state.Put("client", client)
+			state.Put("ui", packersdk.TestUi(t))
+			state.Put("config", config)
+
+			step := new(stepSourceImage)
+			if action := step.Run(context.Background(), state); action != tc.Action {
+				t.Fatalf("expected action %#v, got %#v: %v", tc.Action, action, state.Get("error"))
+			}
+			if tc.Action == multistep.ActionContinue && config.Image != strconv.Itoa(tc.Image) {
+				t.Fatalf("expected image %d, got %s", tc.Image, config.Image)
+			}
+		})
+	}
+}
+
+func TestStepSourceImage_Name(t *testing.T) {
+	sim, client := testSimulator(t)
+	custom := sim.AddImage(godo.Image{Name: "corp-debian-11", Type: "custom"})
+	sim.AddImage(godo.Image{Name: "corp-fedora-34", Type: "custom"})
+	sim.AddImage(godo.Image{Name: "corp-fedora-34", Type: "custom"})
+
+	tt := []struct {
+		Image    string
+		Expected string
+		Action   multistep.StepAction
+	}{
+		{Image: "ubuntu-20-04-x64", Expected: "ubuntu-20-04-x64", Action: multistep.ActionContinue},
+		{Image: "123456", Expected: "123456", Action: multistep.ActionContinue},
+		{Image: "corp-debian-11", Expected: strconv.Itoa(custom.ID), Action: multistep.ActionContinue},
+		{Image: "corp-fedora-34", Action: multistep.ActionHalt},
+		{Image: "corp-centos-8", Action: multistep.ActionHalt},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.Image, func(t *testing.T) {
+			config := &Config{Image: tc.Image}
+
+			state := new(multistep.BasicStateBag)
+			state.Put("client", client)
+			state.Put("ui", packersdk.TestUi(t))
+			state.Put("config", config)
+
+			step := new(stepSourceImage)
+			if action := step.Run(context.Background(), state); action != tc.Action {
+				t.Fatalf("expected action %#v, got %#v: %v", tc.Action, action, state.Get("error"))
+			}
+			if tc.Action == multistep.ActionContinue && config.Image != tc.Expected {
+				t.Fatalf("expected image %s, got %s", tc.Expected, config.Image)
+			}
+		})
+	}
+}
+
+func TestStepSourceImage_Distribution(t *testing.T) {
+	sim, client := testSimulator(t)
+	for _, image := range []godo.Image{
+		{Name: "22.04 (LTS) x64", Distribution: "Ubuntu", Slug: "ubuntu-22-04-x64"},
+		{Name: "21.10 x64", Distribution: "Ubuntu", Slug: "ubuntu-21-10-x64"},
+		{Name: "11 x64", Distribution: "Debian", Slug: "debian-11-x64"},
+		{Name: "10 x64", Distribution: "Debian", Slug: "debian-10-x64"},
+	} {
+		image.Type = "base"
+		image.Public = true
+		sim.AddImage(image)
+	}
+
+	tt := []struct {
+		Distribution string
+		Version      string
+		Expected     string
+		Action       multistep.StepAction
+	}{
+		{Distribution: "ubuntu", Version: "", Expected: "ubuntu-22-04-x64", Action: multistep.ActionContinue},
+		{Distribution: "Ubuntu", Version: "20.04", Expected: "ubuntu-20-04-x64", Action: multistep.ActionContinue},
+		{Distribution: "ubuntu", Version: "21", Expected: "ubuntu-21-10-x64", Action: multistep.ActionContinue},
+		{Distribution: "ubuntu", Version: "lts", Expected: "ubuntu-22-04-x64", Action: multistep.ActionContinue},
+		{Distribution: "debian", Version: "1", Action: multistep.ActionHalt},
+		{Distribution: "debian", Version: "10", Expected: "debian-10-x64", Action: multistep.ActionContinue},
+		{Distribution: "arch", Action: multistep.ActionHalt},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.Distribution+"-"+tc.Version, func(t *testing.T) {
+			config := &Config{Distribution: tc.Distribution, Version: tc.Version}
+
+			state := new(multistep.BasicStateBag)
+			state.Put("client", client)
+			state.Put("ui", packersdk.TestUi(t))
+			state.Put("config", config)
+
+			step := new(stepSourceImage)
+			if action := step.Run(context.Background(), state); action != tc.Action {
+				t.Fatalf("expected action %#v, got %#v: %v", tc.Action, action, state.Get("error"))
+			}
+			if tc.Action == multistep.ActionContinue && config.Image != tc.Expected {
+				t.Fatalf("expected image %s, got %s", tc.Expected, config.Image)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// trimDiskCommand discards unused blocks so that they aren't stored in the
+// snapshot. Filesystems that don't support discard get their free space
+// zeroed instead, which compresses to next to nothing.
+const trimDiskCommand = `sh -c 'fstrim -av || { dd if=/dev/zero of=/var/tmp/packer-zero bs=1M 2>/dev/null; sync; rm -f /var/tmp/packer-zero; sync; }'`
+
+type stepTrimDisk struct{}
+
+func (s *stepTrimDisk) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	comm := state.Get("communicator").(packersdk.Communicator)
+	ui := newStepUi(state, "trim_disk")
+	c := state.Get("config").(*Config)
+
+	ui.Say("Trimming unused disk space...")
+	command := trimDiskCommand
+	if c.Comm.SSHUsername != "root" {
+		command = "sudo " + command
+	}
+	cmd := &packersdk.RemoteCmd{Command: command}
+	if err := cmd.RunWithUi(ctx, comm, ui); err != nil {
+		err := fmt.Errorf("Error trimming disk: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	if cmd.ExitStatus() != 0 {
+		err := fmt.Errorf("Trimming disk exited with non-zero exit status: %d", cmd.ExitStatus())
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepTrimDisk) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
@@ -0,0 +1,36 @@
+package digitalocean
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestStepTrimDisk(t *testing.T) {
+	for _, username := range []string{"root", "core"} {
+		comm := new(packersdk.MockCommunicator)
+
+		state := new(multistep.BasicStateBag)
+		state.Put("communicator", comm)
+		state.Put("ui", packersdk.TestUi(t))
+		state.Put("config", &Config{
+			Comm: communicator.Config{SSH: communicator.SSH{SSHUsername: username}},
+		})
+
+		step := new(stepTrimDisk)
+		if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+			t.Fatalf("expected action continue, got %#v", action)
+		}
+
+		expected := trimDiskCommand
+		if username != "root" {
+			expected = "sudo " + trimDiskCommand
+		}
+		if comm.StartCmd.Command != expected {
+			t.Fatalf("expected command %q, got %q", expected, comm.StartCmd.Command)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+const updatePackagesScriptPath = "/tmp/packer-update-packages.sh"
+
+// updateRebootExitStatus is what the update script exits with when the
+// droplet has to be rebooted for the update to take effect.
+const updateRebootExitStatus = 194
+
+// updatePackagesScript updates every package with the package manager of
+// the image, and tells whether the update requires a reboot.
+const updatePackagesScript = `#!/bin/sh
+set -e
+rm -f "$0"
+reboot=0
+if command -v apt-get >/dev/null 2>&1; then
+	export DEBIAN_FRONTEND=noninteractive
+	apt-get -q update
+	apt-get -q -y -o Dpkg::Options::=--force-confdef -o Dpkg::Options::=--force-confold dist-upgrade
+	if [ -f /var/run/reboot-required ]; then reboot=1; fi
+elif command -v dnf >/dev/null 2>&1; then
+	dnf -q -y upgrade
+	if command -v needs-restarting >/dev/null 2>&1; then needs-restarting -r >/dev/null || reboot=1; fi
+elif command -v zypper >/dev/null 2>&1; then
+	zypper --non-interactive refresh
+	rc=0
+	zypper --non-interactive update --auto-agree-with-licenses || rc=$?
+	case $rc in
+	0|103) ;;
+	102) reboot=1 ;;
+	*) exit $rc ;;
+	esac
+else
+	echo "No supported package manager (apt, dnf or zypper) found" >&2
+	exit 1
+fi
+if [ $reboot = 1 ]; then exit %d; fi
+exit 0
+`
+
+// stepUpdatePackages updates the droplet's packages ahead of the
+// provisioners, rebooting the droplet if needed.
+type stepUpdatePackages struct{}
+
+func (s *stepUpdatePackages) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	comm := state.Get("communicator").(packersdk.Communicator)
+	ui := newStepUi(state, "update_packages")
+	c := state.Get("config").(*Config)
+
+	sudo := ""
+	if c.Comm.SSHUsername != "root" {
+		sudo = "sudo "
+	}
+
+	ui.Say("Updating packages...")
+	script := fmt.Sprintf(updatePackagesScript, updateRebootExitStatus)
+	if err := comm.Upload(updatePackagesScriptPath, strings.NewReader(script), nil); err != nil {
+		err := fmt.Errorf("Error uploading package update script: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	cmd := &packersdk.RemoteCmd{Command: sudo + "sh " + updatePackagesScriptPath}
+	if err := cmd.RunWithUi(ctx, comm, ui); err != nil {
+		err := fmt.Errorf("Error updating packages: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	switch cmd.ExitStatus() {
+	case 0:
+		return multistep.ActionContinue
+	case updateRebootExitStatus:
+	default:
+		err := fmt.Errorf("Package update exited with non-zero exit status: %d", cmd.ExitStatus())
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Say("Rebooting droplet to complete the package update...")
+	if err := rebootDroplet(ctx, comm, sudo, c.RebootTimeout); err != nil {
+		err := fmt.Errorf("Error rebooting droplet: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepUpdatePackages) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
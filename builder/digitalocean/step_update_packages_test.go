@@ -0,0 +1,54 @@
+package digitalocean
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestStepUpdatePackages(t *testing.T) {
+	cases := []struct {
+		name     string
+		status   int
+		action   multistep.StepAction
+		rebooted bool
+	}{
+		{"up to date", 0, multistep.ActionContinue, false},
+		{"reboot", updateRebootExitStatus, multistep.ActionContinue, true},
+		{"failed", 100, multistep.ActionHalt, false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			testSimulator(t)
+			comm := &testRebootCommunicator{updateStatus: tt.status}
+
+			state := new(multistep.BasicStateBag)
+			state.Put("communicator", comm)
+			state.Put("ui", packersdk.TestUi(t))
+			state.Put("config", &Config{
+				StateTimeout:  time.Second,
+				RebootTimeout: time.Second,
+				Comm:          communicator.Config{SSH: communicator.SSH{SSHUsername: "core"}},
+			})
+
+			step := new(stepUpdatePackages)
+			if action := step.Run(context.Background(), state); action != tt.action {
+				t.Fatalf("expected action %#v, got %#v: %v", tt.action, action, state.Get("error"))
+			}
+
+			if comm.UploadPath != updatePackagesScriptPath {
+				t.Fatalf("expected script uploaded to %s, got %q", updatePackagesScriptPath, comm.UploadPath)
+			}
+			if expected := "sudo sh " + updatePackagesScriptPath; comm.commands[0] != expected {
+				t.Fatalf("expected command %q, got %q", expected, comm.commands[0])
+			}
+			if rebooted := comm.boot > 0; rebooted != tt.rebooted {
+				t.Fatalf("expected rebooted %t, got %t: %q", tt.rebooted, rebooted, comm.commands)
+			}
+		})
+	}
+}
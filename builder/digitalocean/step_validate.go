@@ -0,0 +1,98 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// stepValidate checks, using read-only API calls, that everything the build
+// refers to exists and can be used together. Nothing is created.
+type stepValidate struct{}
+
+func (s *stepValidate) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*godo.Client)
+	ui := newStepUi(state, "validate")
+	c := state.Get("config").(*Config)
+
+	ui.Say("Validating configuration against the DigitalOcean API...")
+
+	if _, _, err := client.Account.Get(context.TODO()); err != nil {
+		err := fmt.Errorf("Error validating API token: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	var errs *packersdk.MultiError
+
+	size, err := findSize(client, c.Size)
+	if err != nil {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("Error looking up size: %s", err))
+	} else if !size.Available || !containsString(size.Regions, c.Region) {
+		errs = packersdk.MultiErrorAppend(errs,
+			fmt.Errorf("Size %s is not available in region %s", c.Size, c.Region))
+	}
+
+	// The snapshot of source_build is only created by the build
+	if c.SourceBuild == "" {
+		image := getImageType(c.Image)
+		if image.ID != 0 {
+			_, _, err = client.Images.GetByID(context.TODO(), image.ID)
+		} else {
+			var resp *godo.Response
+			_, resp, err = client.Images.GetBySlug(context.TODO(), image.Slug)
+			if err != nil && resp != nil && resp.StatusCode == http.StatusNotFound {
+				err = imageNotFoundError(client, image.Slug)
+			}
+		}
+		if err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("Error looking up image %s: %s", c.Image, err))
+		}
+	}
+
+	if c.VPCUUID != "" {
+		vpc, _, err := client.VPCs.Get(context.TODO(), c.VPCUUID)
+		if err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("Error looking up VPC %s: %s", c.VPCUUID, err))
+		} else if vpc.RegionSlug != c.Region {
+			errs = packersdk.MultiErrorAppend(errs,
+				fmt.Errorf("VPC %s is in region %s, not %s", c.VPCUUID, vpc.RegionSlug, c.Region))
+		}
+	}
+
+	for _, id := range append([]int{c.SSHKeyID}, c.SSHKeyIDs...) {
+		if id == 0 {
+			continue
+		}
+		if _, _, err := client.Keys.GetByID(context.TODO(), id); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("Error looking up SSH key %d: %s", id, err))
+		}
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		state.Put("error", errs)
+		ui.Error(errs.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Say("Configuration is valid.")
+	return multistep.ActionContinue
+}
+
+func (s *stepValidate) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package digitalocean
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-digitalocean/internal/simulator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestStepValidate(t *testing.T) {
+	tt := []struct {
+		Name   string
+		Config func(sim *simulator.Server) Config
+		Fault  *simulator.Fault
+		Action multistep.StepAction
+	}{
+		{
+			Name: "Valid",
+			Config: func(sim *simulator.Server) Config {
+				vpc := sim.AddVPC(godo.VPC{Name: "default-nyc3", RegionSlug: "nyc3"})
+				key := sim.AddKey(godo.Key{Name: "packer", Fingerprint: "3b:16:bf:e4:8b:00:8b:b8:59:8c:a9:d3:f0:19:45:fa"})
+				return Config{Region: "nyc3", Size: "s-1vcpu-1gb", Image: "ubuntu-20-04-x64", VPCUUID: vpc.ID, SSHKeyID: key.ID}
+			},
+			Action: multistep.ActionContinue,
+		},
+		{
+			Name: "InvalidToken",
+			Config: func(sim *simulator.Server) Config {
+				return Config{Region: "nyc3", Size: "s-1vcpu-1gb", Image: "ubuntu-20-04-x64"}
+			},
+			Fault:  &simulator.Fault{Path: "/v2/account", Status: http.StatusUnauthorized, ID: "unauthorized", Message: "Unable to authenticate you."},
+			Action: multistep.ActionHalt,
+		},
+		{
+			Name: "UnknownImage",
+			Config: func(sim *simulator.Server) Config {
+				return Config{Region: "nyc3", Size: "s-1vcpu-1gb", Image: "ubuntu-12-04-x64"}
+			},
+			Action: multistep.ActionHalt,
+		},
+		{
+			Name: "UnknownSize",
+			Config: func(sim *simulator.Server) Config {
+				return Config{Region: "nyc3", Size: "gd-40vcpu-160gb", Image: "ubuntu-20-04-x64"}
+			},
+			Action: multistep.ActionHalt,
+		},
+		{
+			Name: "VPCInOtherRegion",
+			Config: func(sim *simulator.Server) Config {
+				vpc := sim.AddVPC(godo.VPC{Name: "default-ams3", RegionSlug: "ams3"})
+				return Config{Region: "nyc3", Size: "s-1vcpu-1gb", Image: "ubuntu-20-04-x64", VPCUUID: vpc.ID}
+			},
+			Action: multistep.ActionHalt,
+		},
+		{
+			Name: "UnknownKey",
+			Config: func(sim *simulator.Server) Config {
+				return Config{Region: "nyc3", Size: "s-1vcpu-1gb", Image: "ubuntu-20-04-x64", SSHKeyID: 42}
+			},
+			Action: multistep.ActionHalt,
+		},
+		{
+			Name: "UnknownKeyInList",
+			Config: func(sim *simulator.Server) Config {
+				key := sim.AddKey(godo.Key{Name: "break-glass", Fingerprint: "3b:16:bf:e4:8b:00:8b:b8:59:8c:a9:d3:f0:19:45:fa"})
+				return Config{Region: "nyc3", Size: "s-1vcpu-1gb", Image: "ubuntu-20-04-x64", SSHKeyIDs: []int{key.ID, 42}}
+			},
+			Action: multistep.ActionHalt,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			sim, client := testSimulator(t)
+			config := tc.Config(sim)
+			if tc.Fault != nil {
+				sim.Inject(*tc.Fault)
+			}
+
+			state := new(multistep.BasicStateBag)
+			state.Put("client", client)
+			state.Put("ui", packersdk.TestUi(t))
+			state.Put("config", &config)
+
+			step := new(stepValidate)
+			if action := step.Run(context.Background(), state); action != tc.Action {
+				t.Fatalf("expected action %#v, got %#v: %v", tc.Action, action, state.Get("error"))
+			}
+			for _, req := range sim.Requests() {
+				if req[:4] != "GET " {
+					t.Fatalf("unexpected non read-only request %s", req)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// stepVerify boots a droplet from the new snapshot and runs verify_commands
+// on it, so that images which don't come up fail the build rather than
+// whatever uses them next.
+type stepVerify struct {
+	dropletId int
+
+	// dial connects to the droplet, tests replace it.
+	dial sshDialFunc
+}
+
+func (s *stepVerify) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := newStepUi(state, "verify")
+	c := state.Get("config").(*Config)
+	imageId := state.Get("snapshot_image_id").(int)
+
+	ui.Say("Creating droplet from snapshot to verify it...")
+	dropletId, sshClient, err := bootImageDroplet(ctx, state, ui, c.DropletName+"-verify", "verification droplet",
+		imageId, c.VerifySize, s.dial)
+	// We use this in cleanup
+	s.dropletId = dropletId
+	if err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	defer sshClient.Close()
+
+	for _, command := range c.VerifyCommands {
+		ui.Say(fmt.Sprintf("Running verify command: %s", command))
+		session, err := sshClient.NewSession()
+		if err != nil {
+			err := fmt.Errorf("Error running verify command: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		output, err := session.CombinedOutput(command)
+		session.Close()
+		if len(output) > 0 {
+			ui.Message(string(output))
+		}
+		if err != nil {
+			err := fmt.Errorf("Verify command %q failed: %s", command, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	ui.Say("Snapshot verified")
+	return multistep.ActionContinue
+}
+
+// sshDialFunc connects to a droplet over SSH, as gossh.Dial does.
+type sshDialFunc func(network, addr string, config *gossh.ClientConfig) (*gossh.Client, error)
+
+// bootImageDroplet creates a droplet of the given size from an image, named
+// as given, and connects to it over SSH once it is active. what describes
+// the droplet in errors. The ID of the droplet is returned as soon as it is
+// created, for the caller to destroy it, even when connecting fails.
+func bootImageDroplet(ctx context.Context, state multistep.StateBag, ui *stepUi, name, what string, imageId int, size string, dial sshDialFunc) (int, *gossh.Client, error) {
+	client := state.Get("client").(*godo.Client)
+	c := state.Get("config").(*Config)
+
+	var userData string
+	var hostKey gossh.PublicKey
+	if c.PinSSHHostKey {
+		privatePEM, public, err := generateHostKey()
+		if err == nil {
+			userData, err = hostKeyUserData("", privatePEM, public)
+		}
+		if err != nil {
+			return 0, nil, fmt.Errorf("Error generating SSH host key: %s", err)
+		}
+		hostKey = public
+	}
+
+	createReq := &godo.DropletCreateRequest{
+		Name:              name,
+		Region:            c.Region,
+		Size:              size,
+		Image:             godo.DropletCreateImage{ID: imageId},
+		SSHKeys:           dropletSSHKeys(state, c),
+		PrivateNetworking: c.PrivateNetworking,
+		UserData:          userData,
+		Tags:              c.Tags,
+		VPCUUID:           c.VPCUUID,
+	}
+	droplet, _, err := client.Droplets.Create(context.TODO(), createReq)
+	if err != nil {
+		return 0, nil, fmt.Errorf("Error creating %s: %s", what, explainCreateError(err, createReq))
+	}
+	id := droplet.ID
+	trackCreated(state, resourceDroplet, id, name)
+
+	if err := WaitForDropletState("active", id, client, c.StateTimeout); err != nil {
+		return id, nil, fmt.Errorf("Error waiting for %s to become active: %s", what, err)
+	}
+
+	active, _, err := client.Droplets.Get(context.TODO(), id)
+	if err != nil {
+		return id, nil, fmt.Errorf("Error retrieving %s: %s", what, err)
+	}
+	ip, ok := dropletIP(active, c.ConnectWithPrivateIP)
+	if !ok {
+		return id, nil, fmt.Errorf("Could not find an IPv4 address for the %s", what)
+	}
+
+	sshConfig, err := c.Comm.SSHConfigFunc()(state)
+	if err != nil {
+		return id, nil, fmt.Errorf("Error configuring SSH: %s", err)
+	}
+	if hostKey != nil {
+		sshConfig.HostKeyCallback = gossh.FixedHostKey(hostKey)
+		sshConfig.HostKeyAlgorithms = []string{hostKey.Type()}
+	}
+
+	ui.Say(fmt.Sprintf("Waiting for SSH to become available on %s...", ip))
+	sshClient, err := connectSSH(ctx, dial, net.JoinHostPort(ip, strconv.Itoa(c.Comm.SSHPort)), sshConfig, c.Comm.SSHTimeout)
+	if err != nil {
+		return id, nil, fmt.Errorf("Error connecting to %s: %s", what, err)
+	}
+	return id, sshClient, nil
+}
+
+// connectSSH retries connecting until the droplet's SSH server is up.
+func connectSSH(ctx context.Context, dial sshDialFunc, addr string, config *gossh.ClientConfig, timeout time.Duration) (*gossh.Client, error) {
+	if dial == nil {
+		dial = gossh.Dial
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 30 * time.Second
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		client, err := dial("tcp", addr, config)
+		if err == nil {
+			return client, nil
+		}
+		logf(levelDebug, []interface{}{"addr", addr}, "SSH connection to droplet failed: %s", err)
+		if time.Now().After(deadline) {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(5 * pollInterval):
+		}
+	}
+}
+
+func (s *stepVerify) Cleanup(state multistep.StateBag) {
+	if s.dropletId == 0 {
+		return
+	}
+
+	client := state.Get("client").(*godo.Client)
+	c := state.Get("config").(*Config)
+	ui := newStepUi(state, "verify")
+
+	ui.Say("Destroying verification droplet...")
+	err := DestroyDroplet(client, s.dropletId, c.StateTimeout)
+	trackDeleted(state, resourceDroplet, s.dropletId, err)
+	if err != nil {
+		ui.Error(fmt.Sprintf(
+			"Error destroying verification droplet. Please destroy it manually: %s", err))
+	}
+}
@@ -0,0 +1,178 @@
+package digitalocean
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-digitalocean/internal/simulator"
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// testSSHServer starts an SSH server that runs nothing, and only reports
+// commands named "false" as failing.
+func testSSHServer(t *testing.T) string {
+	return testSSHServerFunc(t, func(command string) (string, uint32) {
+		if command == "false" {
+			return "ran false\n", 1
+		}
+		return "ran " + command + "\n", 0
+	})
+}
+
+// testSSHServerFunc starts an SSH server answering commands with the output
+// and exit status run returns for them.
+func testSSHServerFunc(t *testing.T, run func(command string) (string, uint32)) string {
+	privatePEM, _, err := generateHostKey()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	signer, err := gossh.ParsePrivateKey([]byte(privatePEM))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	config := &gossh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(signer)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, chans, reqs, err := gossh.NewServerConn(conn, config)
+				if err != nil {
+					return
+				}
+				go gossh.DiscardRequests(reqs)
+				for newChan := range chans {
+					ch, reqs, err := newChan.Accept()
+					if err != nil {
+						return
+					}
+					go func() {
+						defer ch.Close()
+						for req := range reqs {
+							if req.Type != "exec" {
+								req.Reply(false, nil)
+								continue
+							}
+							req.Reply(true, nil)
+							output, status := run(string(req.Payload[4:]))
+							ch.Write([]byte(output))
+							ch.SendRequest("exit-status", false, gossh.Marshal(struct{ Status uint32 }{status}))
+							return
+						}
+					}()
+				}
+			}()
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func TestStepVerify(t *testing.T) {
+	tt := []struct {
+		Name     string
+		Commands []string
+		Action   multistep.StepAction
+	}{
+		{Name: "Passing", Commands: []string{"true", "systemctl is-system-running"}, Action: multistep.ActionContinue},
+		{Name: "Failing", Commands: []string{"true", "false"}, Action: multistep.ActionHalt},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			sim, client := testSimulator(t)
+			addr := testSSHServer(t)
+			image := sim.AddImage(godo.Image{Name: "packer-test", Type: "snapshot", Regions: []string{"nyc3"}})
+
+			clientKey, _, err := generateHostKey()
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			state := new(multistep.BasicStateBag)
+			state.Put("client", client)
+			state.Put("ui", packersdk.TestUi(t))
+			state.Put("snapshot_image_id", image.ID)
+			state.Put("config", &Config{
+				DropletName:    "packer-test",
+				Region:         "nyc3",
+				VerifySize:     "s-1vcpu-1gb",
+				VerifyCommands: tc.Commands,
+				StateTimeout:   time.Second,
+				Comm: communicator.Config{
+					Type: "ssh",
+					SSH: communicator.SSH{
+						SSHUsername:   "root",
+						SSHPort:       22,
+						SSHTimeout:    time.Second,
+						SSHPrivateKey: []byte(clientKey),
+					},
+				},
+			})
+
+			step := &stepVerify{
+				dial: func(network, _ string, config *gossh.ClientConfig) (*gossh.Client, error) {
+					return gossh.Dial(network, addr, config)
+				},
+			}
+			if action := step.Run(context.Background(), state); action != tc.Action {
+				t.Fatalf("expected action %#v, got %#v: %v", tc.Action, action, state.Get("error"))
+			}
+
+			step.Cleanup(state)
+			if droplets := sim.Droplets(); len(droplets) != 0 {
+				t.Fatalf("expected verification droplet to be destroyed, got %#v", droplets)
+			}
+		})
+	}
+}
+
+func TestStepVerify_RetrieveFailure(t *testing.T) {
+	sim, client := testSimulator(t)
+	image := sim.AddImage(godo.Image{Name: "packer-test", Type: "snapshot", Regions: []string{"nyc3"}})
+	sim.DropletPolls = 0
+
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("snapshot_image_id", image.ID)
+	state.Put("config", &Config{
+		DropletName:    "packer-test",
+		Region:         "nyc3",
+		VerifySize:     "s-1vcpu-1gb",
+		VerifyCommands: []string{"true"},
+		StateTimeout:   time.Second,
+	})
+
+	// The droplet is active on the first poll, and fetching it afterwards
+	// fails
+	sim.Inject(simulator.Fault{Method: http.MethodGet, Path: "/v2/droplets/", Status: http.StatusInternalServerError,
+		ID: "server_error", Message: "Server was unable to give you a response.", After: 1, Times: 1})
+
+	step := new(stepVerify)
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("expected action halt, got %#v", action)
+	}
+	step.Cleanup(state)
+	if droplets := sim.Droplets(); len(droplets) != 0 {
+		t.Fatalf("expected verification droplet to be destroyed, got %#v", droplets)
+	}
+}
@@ -0,0 +1,124 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+// stepCreateVolumes creates the volumes attached to the droplet when it is
+// created, and deletes them once it is destroyed.
+type stepCreateVolumes struct {
+	volumeIds []string
+}
+
+func (s *stepCreateVolumes) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*godo.Client)
+	ui := newStepUi(state, "create_volumes")
+	c := state.Get("config").(*Config)
+
+	for _, v := range c.Volumes {
+		ui.Say(fmt.Sprintf("Creating volume %s (%d GiB)...", v.Name, v.Size))
+		volume, _, err := client.Storage.CreateVolume(ctx, &godo.VolumeCreateRequest{
+			Region:        c.Region,
+			Name:          v.Name,
+			Description:   "Created by Packer for " + c.DropletName,
+			SizeGigaBytes: v.Size,
+		})
+		if err != nil {
+			err := fmt.Errorf("Error creating volume %s: %s", v.Name, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		s.volumeIds = append(s.volumeIds, volume.ID)
+		trackCreated(state, resourceVolume, volume.ID, volume.Name)
+		state.Put("volume_ids", s.volumeIds)
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateVolumes) Cleanup(state multistep.StateBag) {
+	if len(s.volumeIds) == 0 {
+		return
+	}
+
+	client := state.Get("client").(*godo.Client)
+	ui := newStepUi(state, "create_volumes")
+	c := state.Get("config").(*Config)
+
+	ui.Say("Deleting volumes...")
+	for _, id := range s.volumeIds {
+		err := deleteVolume(client, id, c.StateTimeout)
+		trackDeleted(state, resourceVolume, id, err)
+		if err != nil {
+			ui.Error(fmt.Sprintf("Error deleting volume %s. Please delete it manually: %s", id, err))
+		}
+	}
+}
+
+// deleteVolume deletes a volume, retrying while it is still attached to the
+// droplet being destroyed. A volume that no longer exists is not an error.
+func deleteVolume(client *godo.Client, id string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		resp, err := client.Storage.DeleteVolume(context.TODO(), id)
+		if err == nil || (resp != nil && resp.StatusCode == http.StatusNotFound) {
+			return nil
+		}
+		if resp == nil || resp.StatusCode != http.StatusConflict || time.Now().After(deadline) {
+			return err
+		}
+		logf(levelDebug, []interface{}{"volume_id", id}, "Volume still attached: %s", err)
+		time.Sleep(5 * pollInterval)
+	}
+}
+
+// volumeDevice returns the device a volume is attached as.
+func volumeDevice(name string) string {
+	return "/dev/disk/by-id/scsi-0DO_Volume_" + name
+}
+
+// mountVolumeFunc is the shell function of volumeMountScript mounting a
+// volume, given its device, filesystem type and mount path.
+const mountVolumeFunc = `mount_volume() {
+	# The device only shows up once udev has handled the attachment
+	i=0
+	until [ -e "$1" ]; do
+		i=$((i + 1))
+		if [ $i -gt 120 ]; then
+			echo "packer: $1 not found" >&2
+			exit 1
+		fi
+		udevadm settle 2>/dev/null || true
+		sleep 1
+	done
+	udevadm settle 2>/dev/null || true
+	blkid "$1" >/dev/null || mkfs."$2" "$1"
+	mkdir -p "$3"
+	grep -q " $3 " /etc/fstab || echo "$1 $3 $2 defaults,nofail,discard 0 2" >>/etc/fstab
+	mountpoint -q "$3" || mount "$3"
+}
+`
+
+// volumeMountScript returns the user data script formatting and mounting
+// the volumes with a mount path, if any.
+func volumeMountScript(volumes []Volume) string {
+	var script strings.Builder
+	for _, v := range volumes {
+		if v.MountPath == "" {
+			continue
+		}
+		if script.Len() == 0 {
+			script.WriteString("#!/bin/sh\nset -e\n\n" + mountVolumeFunc + "\n")
+		}
+		fmt.Fprintf(&script, "mount_volume %s %s %s\n", volumeDevice(v.Name), v.FilesystemType, v.MountPath)
+	}
+	return script.String()
+}
@@ -0,0 +1,77 @@
+package digitalocean
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestStepCreateVolumes(t *testing.T) {
+	sim, client := testSimulator(t)
+
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("config", &Config{DropletName: "packer-test", Region: "nyc3", Size: "s-1vcpu-1gb", Image: "ubuntu-20-04-x64",
+		StateTimeout: time.Second,
+		Volumes:      []Volume{{Name: "packer-test-0", Size: 10}, {Name: "data", Size: 100}}})
+
+	volumes := new(stepCreateVolumes)
+	if action := volumes.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected action continue, got %#v: %s", action, state.Get("error"))
+	}
+	ids := state.Get("volume_ids").([]string)
+	if len(ids) != 2 {
+		t.Fatalf("expected 2 volumes, got %v", ids)
+	}
+
+	create := new(stepCreateDroplet)
+	if action := create.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected action continue, got %#v: %s", action, state.Get("error"))
+	}
+	droplet, _ := sim.Droplet(state.Get("droplet_id").(int))
+	if len(droplet.VolumeIDs) != 2 || droplet.VolumeIDs[0] != ids[0] || droplet.VolumeIDs[1] != ids[1] {
+		t.Fatalf("expected the volumes to be attached, got %v", droplet.VolumeIDs)
+	}
+
+	// Attached volumes can't be deleted
+	if err := deleteVolume(client, ids[0], 10*time.Millisecond); err == nil {
+		t.Fatal("expected an error deleting an attached volume")
+	}
+
+	create.Cleanup(state)
+	volumes.Cleanup(state)
+	if len(sim.Volumes()) != 0 {
+		t.Fatalf("expected the volumes to be deleted, got %#v", sim.Volumes())
+	}
+}
+
+func TestVolumeMountScript(t *testing.T) {
+	if script := volumeMountScript([]Volume{{Name: "scratch", Size: 10}}); script != "" {
+		t.Fatalf("expected no script without mount paths, got %q", script)
+	}
+
+	script := volumeMountScript([]Volume{
+		{Name: "scratch", Size: 10},
+		{Name: "data", Size: 100, FilesystemType: "ext4", MountPath: "/srv/data"},
+		{Name: "logs", Size: 10, FilesystemType: "xfs", MountPath: "/var/log/app"},
+	})
+	if !strings.HasPrefix(script, "#!/bin/sh\n") {
+		t.Errorf("expected a shell script, got %q", script)
+	}
+	for _, line := range []string{
+		"\nmount_volume /dev/disk/by-id/scsi-0DO_Volume_data ext4 /srv/data\n",
+		"\nmount_volume /dev/disk/by-id/scsi-0DO_Volume_logs xfs /var/log/app\n",
+	} {
+		if !strings.Contains(script, line) {
+			t.Errorf("expected %q in the script:\n%s", line, script)
+		}
+	}
+	if strings.Contains(script, "scratch") {
+		t.Errorf("expected the volume without a mount path to be left alone:\n%s", script)
+	}
+}
@@ -0,0 +1,54 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// dropletAgentCommand succeeds once the droplet agent is running, checked
+// with systemd when the image has it.
+const dropletAgentCommand = `sh -c 'if command -v systemctl >/dev/null 2>&1; then systemctl is-active --quiet droplet-agent; else pgrep -x droplet-agent >/dev/null; fi'`
+
+// stepWaitForDropletAgent waits for the droplet agent, which DigitalOcean
+// installs while the droplet boots, to be running.
+type stepWaitForDropletAgent struct{}
+
+func (s *stepWaitForDropletAgent) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	comm := state.Get("communicator").(packersdk.Communicator)
+	ui := newStepUi(state, "wait_for_droplet_agent")
+	c := state.Get("config").(*Config)
+
+	ui.Say("Waiting for the droplet agent to be running...")
+	deadline := time.Now().Add(c.StateTimeout)
+	for {
+		cmd := &packersdk.RemoteCmd{Command: dropletAgentCommand}
+		err := comm.Start(ctx, cmd)
+		if err == nil && cmd.Wait() == 0 {
+			return multistep.ActionContinue
+		}
+		if err != nil {
+			ui.Debugf("Unable to check the droplet agent: %s", err)
+		}
+		if time.Now().After(deadline) {
+			err := fmt.Errorf("Timeout waiting for the droplet agent to be running, the image may not support it")
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		select {
+		case <-ctx.Done():
+			state.Put("error", ctx.Err())
+			return multistep.ActionHalt
+		case <-time.After(5 * pollInterval):
+		}
+	}
+}
+
+func (s *stepWaitForDropletAgent) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
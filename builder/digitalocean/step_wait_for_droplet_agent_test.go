@@ -0,0 +1,41 @@
+package digitalocean
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestStepWaitForDropletAgent(t *testing.T) {
+	testSimulator(t)
+
+	cases := []struct {
+		name   string
+		status int
+		action multistep.StepAction
+	}{
+		{"running", 0, multistep.ActionContinue},
+		{"not running", 3, multistep.ActionHalt},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			comm := &packersdk.MockCommunicator{StartExitStatus: tt.status}
+
+			state := new(multistep.BasicStateBag)
+			state.Put("communicator", comm)
+			state.Put("ui", packersdk.TestUi(t))
+			state.Put("config", &Config{StateTimeout: 20 * time.Millisecond})
+
+			step := new(stepWaitForDropletAgent)
+			if action := step.Run(context.Background(), state); action != tt.action {
+				t.Fatalf("expected action %#v, got %#v", tt.action, action)
+			}
+			if got := comm.StartCmd.Command; got != dropletAgentCommand {
+				t.Errorf("unexpected command %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// stepWaitForMounts waits for the user data to mount the volumes with a
+// mount path, as SSH is up before cloud-init runs user scripts.
+type stepWaitForMounts struct{}
+
+func (s *stepWaitForMounts) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	comm := state.Get("communicator").(packersdk.Communicator)
+	ui := newStepUi(state, "wait_for_mounts")
+	c := state.Get("config").(*Config)
+
+	var checks []string
+	for _, v := range c.Volumes {
+		if v.MountPath != "" {
+			checks = append(checks, "mountpoint -q "+v.MountPath)
+		}
+	}
+	if len(checks) == 0 {
+		return multistep.ActionContinue
+	}
+	command := strings.Join(checks, " && ")
+
+	ui.Say("Waiting for the volumes to be mounted...")
+	deadline := time.Now().Add(c.StateTimeout)
+	for {
+		cmd := &packersdk.RemoteCmd{Command: command}
+		err := comm.Start(ctx, cmd)
+		if err == nil && cmd.Wait() == 0 {
+			return multistep.ActionContinue
+		}
+		if err != nil {
+			ui.Debugf("Unable to check the mounts: %s", err)
+		}
+		if time.Now().After(deadline) {
+			err := fmt.Errorf("Timeout waiting for the volumes to be mounted, the user data script may have failed; see cloud_init_log_dir")
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		select {
+		case <-ctx.Done():
+			state.Put("error", ctx.Err())
+			return multistep.ActionHalt
+		case <-time.After(5 * pollInterval):
+		}
+	}
+}
+
+func (s *stepWaitForMounts) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
@@ -0,0 +1,45 @@
+package digitalocean
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestStepWaitForMounts(t *testing.T) {
+	testSimulator(t)
+
+	cases := []struct {
+		name   string
+		status int
+		action multistep.StepAction
+	}{
+		{"mounted", 0, multistep.ActionContinue},
+		{"not mounted", 1, multistep.ActionHalt},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			comm := &packersdk.MockCommunicator{StartExitStatus: tt.status}
+
+			state := new(multistep.BasicStateBag)
+			state.Put("communicator", comm)
+			state.Put("ui", packersdk.TestUi(t))
+			state.Put("config", &Config{StateTimeout: 20 * time.Millisecond, Volumes: []Volume{
+				{Name: "scratch", Size: 10},
+				{Name: "data", Size: 100, FilesystemType: "ext4", MountPath: "/srv/data"},
+				{Name: "logs", Size: 10, FilesystemType: "xfs", MountPath: "/var/log/app"},
+			}})
+
+			step := new(stepWaitForMounts)
+			if action := step.Run(context.Background(), state); action != tt.action {
+				t.Fatalf("expected action %#v, got %#v", tt.action, action)
+			}
+			if got := comm.StartCmd.Command; got != "mountpoint -q /srv/data && mountpoint -q /var/log/app" {
+				t.Errorf("unexpected command %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package digitalocean
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+// The milestones of a build webhooks are notified of.
+const (
+	webhookDropletCreated       = "droplet_created"
+	webhookProvisioningFinished = "provisioning_finished"
+	webhookSnapshotAvailable    = "snapshot_available"
+	webhookTransfersComplete    = "transfers_complete"
+	webhookBuildFailed          = "build_failed"
+	webhookAttempts             = 3
+	webhookTimeout              = 10 * time.Second
+	webhookUserAgent            = "packer-plugin-digitalocean"
+)
+
+var webhookRetryDelay = 2 * time.Second
+
+// webhookPayload is the JSON body POSTed to webhooks.
+type webhookPayload struct {
+	Event        string            `json:"event"`
+	Time         string            `json:"time"`
+	BuildName    string            `json:"build_name,omitempty"`
+	BuildUUID    string            `json:"build_uuid,omitempty"`
+	RunUUID      string            `json:"run_uuid,omitempty"`
+	Region       string            `json:"region"`
+	DropletID    int               `json:"droplet_id,omitempty"`
+	DropletName  string            `json:"droplet_name,omitempty"`
+	DropletIP    string            `json:"droplet_ip,omitempty"`
+	SnapshotID   int               `json:"snapshot_id,omitempty"`
+	SnapshotName string            `json:"snapshot_name,omitempty"`
+	Regions      map[string]string `json:"regions,omitempty"`
+	Error        string            `json:"error,omitempty"`
+}
+
+// newWebhookPayload returns the payload of event, with what the state knows
+// of the droplet and snapshot so far.
+func newWebhookPayload(state multistep.StateBag, event string) *webhookPayload {
+	c := state.Get("config").(*Config)
+	payload := &webhookPayload{
+		Event:       event,
+		Time:        time.Now().UTC().Format(time.RFC3339),
+		BuildName:   c.PackerBuildName,
+		BuildUUID:   c.buildUUID,
+		RunUUID:     os.Getenv("PACKER_RUN_UUID"),
+		Region:      c.Region,
+		DropletName: c.DropletName,
+	}
+	if dropletId, ok := state.GetOk("droplet_id"); ok {
+		payload.DropletID = dropletId.(int)
+	}
+	if ip, ok := state.GetOk("droplet_ip"); ok {
+		payload.DropletIP = ip.(string)
+	}
+	if imageId, ok := state.GetOk("snapshot_image_id"); ok {
+		payload.SnapshotID = imageId.(int)
+		payload.SnapshotName = c.SnapshotName
+	}
+	if availability, ok := state.GetOk("region_availability"); ok {
+		payload.Regions = availability.(map[string]string)
+	}
+	if rawErr, ok := state.GetOk("error"); ok {
+		payload.Error = rawErr.(error).Error()
+	}
+	return payload
+}
+
+// sendWebhooks POSTs payload to every webhook. A webhook that can't be
+// reached doesn't fail the build, it is only warned about.
+func sendWebhooks(ui *stepUi, webhooks []string, payload *webhookPayload) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		ui.Warn(fmt.Sprintf("Error encoding %s webhook payload: %s", payload.Event, err))
+		return
+	}
+	client := &http.Client{Timeout: webhookTimeout}
+	for _, webhook := range webhooks {
+		ui.Debugf("Notifying %s of %s", webhookHost(webhook), payload.Event)
+		if err := postWebhook(client, webhook, payload.Event, body); err != nil {
+			ui.Warn(fmt.Sprintf("Error notifying %s of %s: %s", webhookHost(webhook), payload.Event, err))
+		}
+	}
+}
+
+// postWebhook POSTs body to webhook, trying again when it can't be reached
+// or answers with a server error.
+func postWebhook(client *http.Client, webhook, event string, body []byte) error {
+	var err error
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(webhookRetryDelay)
+		}
+		var req *http.Request
+		req, err = http.NewRequestWithContext(context.TODO(), http.MethodPost, webhook, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", webhookUserAgent)
+		req.Header.Set("X-Packer-Event", event)
+
+		var resp *http.Response
+		resp, err = client.Do(req)
+		if uerr, ok := err.(*url.Error); ok {
+			// Without the URL, which may carry credentials
+			err = uerr.Err
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 300 {
+			return nil
+		}
+		err = fmt.Errorf("unexpected status %s", resp.Status)
+		if resp.StatusCode < 500 {
+			return err
+		}
+	}
+	return err
+}
+
+// webhookHost returns the host of a webhook, which unlike the full URL
+// doesn't carry the credentials it may have in its path or query.
+func webhookHost(webhook string) string {
+	u, err := url.Parse(webhook)
+	if err != nil {
+		return "webhook"
+	}
+	return u.Host
+}
+
+// stepWebhooks notifies the webhooks that the build reached event.
+type stepWebhooks struct {
+	event string
+}
+
+func (s *stepWebhooks) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := newStepUi(state, "webhooks")
+	c := state.Get("config").(*Config)
+
+	sendWebhooks(ui, c.Webhooks, newWebhookPayload(state, s.event))
+	return multistep.ActionContinue
+}
+
+func (s *stepWebhooks) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
@@ -0,0 +1,137 @@
+package digitalocean
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestStepWebhooks(t *testing.T) {
+	defer func(d time.Duration) { webhookRetryDelay = d }(webhookRetryDelay)
+	webhookRetryDelay = time.Millisecond
+
+	var mu sync.Mutex
+	var payloads []webhookPayload
+	failures := 1
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected %s request with content type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		var payload webhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+		if r.Header.Get("X-Packer-Event") != payload.Event {
+			t.Errorf("got event header %q for %s", r.Header.Get("X-Packer-Event"), payload.Event)
+		}
+		payloads = append(payloads, payload)
+	}))
+	defer srv.Close()
+
+	t.Setenv("PACKER_RUN_UUID", "8c3a3994-09b1-4f1e-b6b5-5c0a4b1ebd4f")
+	state := new(multistep.BasicStateBag)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("config", &Config{
+		PackerConfig: common.PackerConfig{PackerBuildName: "web"},
+		buildUUID:    "2f1d7c0e-5b8a-4a52-9d1e-3c6f0b7a9e21",
+		Region:       "nyc3",
+		DropletName:  "packer-test",
+		SnapshotName: "web-1",
+		Webhooks:     []string{srv.URL + "/hook?token=secret"},
+	})
+	state.Put("droplet_id", 1002)
+	state.Put("droplet_ip", "203.0.113.4")
+
+	step := &stepWebhooks{event: webhookDropletCreated}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected action continue, got %#v", action)
+	}
+
+	state.Put("snapshot_image_id", 1004)
+	state.Put("region_availability", map[string]string{"nyc3": regionAvailable, "sfo3": regionFailed})
+	step = &stepWebhooks{event: webhookTransfersComplete}
+	step.Run(context.Background(), state)
+
+	if len(payloads) != 2 {
+		t.Fatalf("expected 2 payloads, got %d", len(payloads))
+	}
+	created := payloads[0]
+	if created.Event != webhookDropletCreated || created.BuildName != "web" || created.Region != "nyc3" ||
+		created.DropletID != 1002 || created.DropletIP != "203.0.113.4" || created.DropletName != "packer-test" ||
+		created.SnapshotID != 0 || created.Time == "" ||
+		created.BuildUUID != "2f1d7c0e-5b8a-4a52-9d1e-3c6f0b7a9e21" || created.RunUUID != "8c3a3994-09b1-4f1e-b6b5-5c0a4b1ebd4f" {
+		t.Errorf("unexpected payload: %#v", created)
+	}
+	transfers := payloads[1]
+	if transfers.Event != webhookTransfersComplete || transfers.SnapshotID != 1004 ||
+		transfers.SnapshotName != "web-1" || transfers.Regions["sfo3"] != regionFailed {
+		t.Errorf("unexpected payload: %#v", transfers)
+	}
+
+	state.Put("error", errors.New("snapshot failed"))
+	payload := newWebhookPayload(state, webhookBuildFailed)
+	if payload.Error != "snapshot failed" {
+		t.Errorf("got error %q", payload.Error)
+	}
+}
+
+func TestPostWebhook(t *testing.T) {
+	defer func(d time.Duration) { webhookRetryDelay = d }(webhookRetryDelay)
+	webhookRetryDelay = time.Millisecond
+
+	calls := 0
+	status := http.StatusServiceUnavailable
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	if err := postWebhook(srv.Client(), srv.URL, webhookBuildFailed, []byte("{}")); err == nil {
+		t.Error("expected an error")
+	}
+	if calls != webhookAttempts {
+		t.Errorf("expected %d attempts, got %d", webhookAttempts, calls)
+	}
+
+	calls = 0
+	status = http.StatusNotFound
+	if err := postWebhook(srv.Client(), srv.URL, webhookBuildFailed, []byte("{}")); err == nil {
+		t.Error("expected an error")
+	}
+	if calls != 1 {
+		t.Errorf("expected client errors not to be retried, got %d attempts", calls)
+	}
+
+	// The URL can't be reached, and the warning about it doesn't leak it
+	var out bytes.Buffer
+	state := new(multistep.BasicStateBag)
+	state.Put("ui", &packersdk.BasicUi{Writer: &out, ErrorWriter: &out, Reader: strings.NewReader("")})
+	sendWebhooks(newStepUi(state, "webhooks"), []string{"http://127.0.0.1:1/hook?token=secret"},
+		&webhookPayload{Event: webhookBuildFailed})
+	logs := out.String()
+	if !strings.Contains(logs, "Error notifying 127.0.0.1:1 of build_failed") {
+		t.Errorf("expected a warning, got %q", logs)
+	}
+	if strings.Contains(logs, "secret") {
+		t.Errorf("expected the warning not to carry the URL, got %q", logs)
+	}
+}
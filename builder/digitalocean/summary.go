@@ -0,0 +1,107 @@
+package digitalocean
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+// buildSummary is what summary_file holds: what the build actually used
+// and made, which only this builder knows of.
+type buildSummary struct {
+	BuildName          string                 `json:"build_name"`
+	Succeeded          bool                   `json:"succeeded"`
+	Error              string                 `json:"error,omitempty"`
+	Started            time.Time              `json:"started"`
+	Durations          map[string]string      `json:"durations"`
+	Droplet            *summaryDroplet        `json:"droplet,omitempty"`
+	SourceImage        string                 `json:"source_image"`
+	Snapshot           *summarySnapshot       `json:"snapshot,omitempty"`
+	RegionAvailability map[string]string      `json:"region_availability,omitempty"`
+	APIUsage           map[string]interface{} `json:"api_usage"`
+	EstimatedCost      map[string]interface{} `json:"estimated_cost,omitempty"`
+}
+
+type summaryDroplet struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Size   string `json:"size"`
+	Region string `json:"region"`
+	IP     string `json:"ip,omitempty"`
+}
+
+type summarySnapshot struct {
+	ID              int                    `json:"id"`
+	Name            string                 `json:"name"`
+	Regions         []string               `json:"regions"`
+	VolumeSnapshots map[string]interface{} `json:"volume_snapshots,omitempty"`
+}
+
+// newBuildSummary gathers the summary of a build that started at the given
+// time from the state it left.
+func newBuildSummary(state multistep.StateBag, started time.Time, usage *apiUsage) *buildSummary {
+	c := state.Get("config").(*Config)
+	summary := &buildSummary{
+		BuildName:   c.PackerBuildName,
+		Started:     started,
+		Durations:   map[string]string{"build": time.Since(started).Round(time.Second).String()},
+		SourceImage: c.Image,
+		APIUsage:    usage.StateData(),
+	}
+	if rawErr, ok := state.GetOk("error"); ok {
+		summary.Error = rawErr.(error).Error()
+	} else {
+		summary.Succeeded = true
+	}
+
+	if id, ok := state.GetOk("droplet_id"); ok {
+		summary.Droplet = &summaryDroplet{
+			ID:     id.(int),
+			Name:   c.DropletName,
+			Size:   c.Size,
+			Region: c.Region,
+		}
+		if ip, ok := state.GetOk("droplet_ip"); ok {
+			summary.Droplet.IP = ip.(string)
+		}
+	}
+	if createdAt, ok := state.GetOk("droplet_created_at"); ok {
+		summary.Durations["droplet"] = time.Since(createdAt.(time.Time)).Round(time.Second).String()
+	}
+	if duration, ok := state.GetOk("snapshot_duration"); ok {
+		summary.Durations["snapshot"] = duration.(time.Duration).Round(time.Second).String()
+	}
+	if id, ok := state.GetOk("snapshot_image_id"); ok {
+		summary.Snapshot = &summarySnapshot{
+			ID:      id.(int),
+			Name:    state.Get("snapshot_name").(string),
+			Regions: state.Get("regions").([]string),
+		}
+		if snapshots, ok := state.GetOk("volume_snapshots"); ok {
+			summary.Snapshot.VolumeSnapshots = snapshots.(map[string]interface{})
+		}
+	}
+	if availability, ok := state.GetOk("region_availability"); ok {
+		summary.RegionAvailability = availability.(map[string]string)
+	}
+	if estimate, ok := state.GetOk("estimated_cost"); ok {
+		summary.EstimatedCost = estimate.(map[string]interface{})
+	}
+	return summary
+}
+
+// write saves the summary to path as JSON.
+func (s *buildSummary) write(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,71 @@
+package digitalocean
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+func TestBuildSummary(t *testing.T) {
+	state := new(multistep.BasicStateBag)
+	state.Put("config", &Config{
+		PackerConfig: common.PackerConfig{PackerBuildName: "ubuntu"},
+		DropletName:  "packer-test",
+		Size:         "s-1vcpu-1gb",
+		Region:       "nyc3",
+		Image:        "ubuntu-20-04-x64",
+	})
+	started := time.Now().Add(-10 * time.Minute)
+	state.Put("droplet_id", 42)
+	state.Put("droplet_ip", "192.0.2.10")
+	state.Put("droplet_created_at", started.Add(time.Minute))
+	state.Put("snapshot_image_id", 1001)
+	state.Put("snapshot_name", "packer-snapshot")
+	state.Put("regions", []string{"nyc3", "ams3"})
+	state.Put("region_availability", map[string]string{"nyc3": regionAvailable, "ams3": regionAvailable})
+	state.Put("snapshot_duration", 3*time.Minute)
+
+	path := filepath.Join(t.TempDir(), "build", "summary.json")
+	if err := newBuildSummary(state, started, newAPIUsage(nil)).write(path); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var summary buildSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !summary.Succeeded || summary.BuildName != "ubuntu" {
+		t.Fatalf("unexpected summary: %s", data)
+	}
+	if d := summary.Droplet; d == nil || d.ID != 42 || d.Size != "s-1vcpu-1gb" || d.Region != "nyc3" || d.IP != "192.0.2.10" {
+		t.Fatalf("unexpected droplet: %s", data)
+	}
+	if s := summary.Snapshot; s == nil || s.ID != 1001 || len(s.Regions) != 2 {
+		t.Fatalf("unexpected snapshot: %s", data)
+	}
+	expected := map[string]string{"build": "10m0s", "droplet": "9m0s", "snapshot": "3m0s"}
+	for name, duration := range expected {
+		if summary.Durations[name] != duration {
+			t.Fatalf("expected %s duration %s, got %s", name, duration, summary.Durations[name])
+		}
+	}
+	if _, ok := summary.APIUsage["calls"]; !ok {
+		t.Fatalf("expected API usage, got %s", data)
+	}
+
+	state.Put("error", errors.New("Error creating snapshot"))
+	failed := newBuildSummary(state, started, newAPIUsage(nil))
+	if failed.Succeeded || failed.Error != "Error creating snapshot" {
+		t.Fatalf("expected a failed summary, got %#v", failed)
+	}
+}
@@ -0,0 +1,86 @@
+package digitalocean
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var tagRe = regexp.MustCompile("^[[:alnum:]:_-]*$")
+
+// ValidateTag checks that tag is accepted by the DigitalOcean API, and
+// otherwise explains why it isn't, so that invalid tags are reported before
+// the API rejects the request with a bare 422.
+func ValidateTag(tag string) error {
+	switch {
+	case tag == "":
+		return fmt.Errorf("tags can't be empty")
+	case len(tag) > 255:
+		return fmt.Errorf("tag %q is %d characters long, the limit is 255", tag, len(tag))
+	case !tagRe.MatchString(tag):
+		return fmt.Errorf("tag %q may only contain letters, numbers, colons, dashes and underscores", tag)
+	}
+	return nil
+}
+
+// hasTagKey reports whether tags contain key, either on its own or as the
+// key of a key:value tag.
+func hasTagKey(tags []string, key string) bool {
+	for _, tag := range tags {
+		if tag == key || strings.HasPrefix(tag, key+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// ciSystem names the environment variables a CI system describes its runs
+// with.
+type ciSystem struct {
+	name     string
+	detect   string
+	sha      string
+	branch   string
+	pipeline string
+	job      string
+}
+
+var ciSystems = []ciSystem{
+	{name: "github", detect: "GITHUB_ACTIONS", sha: "GITHUB_SHA", branch: "GITHUB_REF_NAME", pipeline: "GITHUB_RUN_ID", job: "GITHUB_JOB"},
+	{name: "gitlab", detect: "GITLAB_CI", sha: "CI_COMMIT_SHA", branch: "CI_COMMIT_REF_NAME", pipeline: "CI_PIPELINE_ID", job: "CI_JOB_ID"},
+	{name: "circleci", detect: "CIRCLECI", sha: "CIRCLE_SHA1", branch: "CIRCLE_BRANCH", pipeline: "CIRCLE_WORKFLOW_ID", job: "CIRCLE_BUILD_NUM"},
+	{name: "buildkite", detect: "BUILDKITE", sha: "BUILDKITE_COMMIT", branch: "BUILDKITE_BRANCH", pipeline: "BUILDKITE_BUILD_ID", job: "BUILDKITE_JOB_ID"},
+	{name: "azure", detect: "TF_BUILD", sha: "BUILD_SOURCEVERSION", branch: "BUILD_SOURCEBRANCHNAME", pipeline: "BUILD_BUILDID", job: "SYSTEM_JOBID"},
+	{name: "jenkins", detect: "JENKINS_URL", sha: "GIT_COMMIT", branch: "GIT_BRANCH", pipeline: "BUILD_ID", job: "JOB_NAME"},
+}
+
+var invalidTagCharRe = regexp.MustCompile("[^[:alnum:]:_-]+")
+
+// ciTags returns the tags describing the CI run the build is a part of, if
+// any, from the environment variables getenv returns.
+func ciTags(getenv func(string) string) []string {
+	for _, ci := range ciSystems {
+		if getenv(ci.detect) == "" {
+			continue
+		}
+		tags := []string{"ci:" + ci.name}
+		for _, kv := range [][2]string{
+			{"git-sha", ci.sha},
+			{"git-branch", ci.branch},
+			{"ci-pipeline", ci.pipeline},
+			{"ci-job", ci.job},
+		} {
+			value := invalidTagCharRe.ReplaceAllString(getenv(kv[1]), "-")
+			if value == "" {
+				continue
+			}
+			tag := kv[0] + ":" + value
+			if len(tag) > 255 {
+				tag = tag[:255]
+			}
+			tags = append(tags, tag)
+		}
+		return tags
+	}
+	return nil
+}
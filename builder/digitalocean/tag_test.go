@@ -0,0 +1,65 @@
+package digitalocean
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateTag(t *testing.T) {
+	tt := []struct {
+		tag   string
+		valid bool
+	}{
+		{"packer", true},
+		{"cost-center:1234_a", true},
+		{strings.Repeat("a", 255), true},
+		{"", false},
+		{strings.Repeat("a", 256), false},
+		{"golden image", false},
+		{"owner=ops", false},
+		{"équipe", false},
+	}
+	for _, tc := range tt {
+		if err := ValidateTag(tc.tag); (err == nil) != tc.valid {
+			t.Errorf("ValidateTag(%q) = %v, expected valid = %t", tc.tag, err, tc.valid)
+		}
+	}
+}
+
+func TestHasTagKey(t *testing.T) {
+	tags := []string{"packer", "owner:ops"}
+	for key, expected := range map[string]bool{"packer": true, "owner": true, "own": false, "ops": false} {
+		if hasTagKey(tags, key) != expected {
+			t.Errorf("hasTagKey(%v, %q) = %t", tags, key, !expected)
+		}
+	}
+}
+
+func TestCITags(t *testing.T) {
+	env := map[string]string{
+		"GITLAB_CI":          "true",
+		"CI_COMMIT_SHA":      "6fe3c9a1b2d4e5f60718293a4b5c6d7e8f901234",
+		"CI_COMMIT_REF_NAME": "feature/new image",
+		"CI_PIPELINE_ID":     "48213",
+	}
+	tags := ciTags(func(key string) string { return env[key] })
+	expected := []string{
+		"ci:gitlab",
+		"git-sha:6fe3c9a1b2d4e5f60718293a4b5c6d7e8f901234",
+		"git-branch:feature-new-image",
+		"ci-pipeline:48213",
+	}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Errorf("got %q, expected %q", tags, expected)
+	}
+	for _, tag := range tags {
+		if err := ValidateTag(tag); err != nil {
+			t.Error(err)
+		}
+	}
+
+	if tags := ciTags(func(string) string { return "" }); tags != nil {
+		t.Errorf("expected no tags outside of CI, got %q", tags)
+	}
+}
@@ -0,0 +1,42 @@
+package digitalocean
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/digitalocean/godo"
+)
+
+// checkToken fails when the API token is invalid or can't create resources,
+// so that builds stop before anything is created rather than at the first
+// write. Write access is probed with a droplet create request missing every
+// required field: the API rejects it as unprocessable for tokens with write
+// scope, and as forbidden for read-only ones.
+func checkToken(client *godo.Client) error {
+	_, resp, err := client.Account.Get(context.TODO())
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusUnauthorized {
+			return errors.New("token invalid: the API token was rejected, check api_token or DIGITALOCEAN_API_TOKEN")
+		}
+		return fmt.Errorf("Error validating API token: %s", err)
+	}
+
+	_, resp, err = client.Droplets.Create(context.TODO(), &godo.DropletCreateRequest{})
+	if err == nil {
+		return errors.New("Error validating API token: an empty droplet create request was accepted")
+	}
+	if resp == nil {
+		return fmt.Errorf("Error validating API token: %s", err)
+	}
+	switch resp.StatusCode {
+	case http.StatusUnprocessableEntity, http.StatusBadRequest:
+		return nil
+	case http.StatusForbidden:
+		return errors.New("token is read-only: the API token needs write scope to create droplets and snapshots")
+	case http.StatusUnauthorized:
+		return errors.New("token invalid: the API token was rejected, check api_token or DIGITALOCEAN_API_TOKEN")
+	}
+	return fmt.Errorf("Error validating API token: %s", err)
+}
@@ -0,0 +1,52 @@
+package digitalocean
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-digitalocean/internal/simulator"
+)
+
+func TestCheckToken(t *testing.T) {
+	tt := []struct {
+		name     string
+		fault    *simulator.Fault
+		expected string
+	}{
+		{name: "valid"},
+		{
+			name:     "invalid",
+			fault:    &simulator.Fault{Path: "/v2/account", Status: http.StatusUnauthorized, ID: "unauthorized", Message: "Unable to authenticate you."},
+			expected: "token invalid",
+		},
+		{
+			name: "read-only",
+			fault: &simulator.Fault{Method: http.MethodPost, Path: "/v2/droplets", Status: http.StatusForbidden,
+				ID: "forbidden", Message: "You do not have access for the attempted action."},
+			expected: "token is read-only",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			sim, client := testSimulator(t)
+			if tc.fault != nil {
+				sim.Inject(*tc.fault)
+			}
+
+			err := checkToken(client)
+			if tc.expected == "" {
+				if err != nil {
+					t.Fatalf("should not have error: %s", err)
+				}
+				if len(sim.Droplets()) != 0 {
+					t.Fatalf("expected no droplet to be created, got %#v", sim.Droplets())
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tc.expected) {
+				t.Fatalf("got %v, expected %q", err, tc.expected)
+			}
+		})
+	}
+}
@@ -1,6 +1,16 @@
 package digitalocean
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-digitalocean/version"
 	"golang.org/x/oauth2"
 )
 
@@ -13,3 +23,130 @@ func (t *apiTokenSource) Token() (*oauth2.Token, error) {
 		AccessToken: t.AccessToken,
 	}, nil
 }
+
+// tokenPool is an http.RoundTripper authenticating requests with one of
+// several API tokens. When the API rate limits the token in use, or answers
+// that it isn't valid anymore, the request is sent again with the next
+// token, which the following requests use too.
+type tokenPool struct {
+	base   http.RoundTripper
+	tokens []string
+
+	mu      sync.Mutex
+	current int
+}
+
+// newTokenPool returns a pool of tokens sending requests through base,
+// which defaults to http.DefaultTransport.
+func newTokenPool(tokens []string, base http.RoundTripper) *tokenPool {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &tokenPool{base: base, tokens: tokens}
+}
+
+func (p *tokenPool) RoundTrip(req *http.Request) (*http.Response, error) {
+	p.mu.Lock()
+	first := p.current
+	p.mu.Unlock()
+
+	for i := 0; ; i++ {
+		current := (first + i) % len(p.tokens)
+		attempt := req.Clone(req.Context())
+		if i > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attempt.Body = body
+		}
+		attempt.Header.Set("Authorization", "Bearer "+p.tokens[current])
+
+		resp, err := p.base.RoundTrip(attempt)
+		if err != nil || !p.exhausted(resp) {
+			return resp, err
+		}
+		// The request can't be sent again once its body is read
+		if i == len(p.tokens)-1 || (req.Body != nil && req.GetBody == nil) {
+			return resp, err
+		}
+		resp.Body.Close()
+		p.rotate(current, resp.StatusCode)
+	}
+}
+
+// exhausted returns whether the token a response was for can't be used
+// anymore, for now or at all.
+func (p *tokenPool) exhausted(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusUnauthorized
+}
+
+// rotate moves on from the token at index from, unless a concurrent request
+// already did.
+func (p *tokenPool) rotate(from int, status int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.current != from {
+		return
+	}
+	p.current = (from + 1) % len(p.tokens)
+	logf(levelWarn, []interface{}{"status", status},
+		"API token %d of %d can't be used, switching to token %d", from+1, len(p.tokens), p.current+1)
+}
+
+// NewClient returns a godo client authenticated with the given API token.
+// When apiURL is not empty it replaces the default API endpoint.
+func NewClient(token string, apiURL string) (*godo.Client, error) {
+	return newClient(token, apiURL, "", nil)
+}
+
+// newClient is NewClient sending requests through transport, unless it is
+// nil, on behalf of the build with the given UUID, if any.
+func newClient(token string, apiURL string, buildUUID string, transport http.RoundTripper) (*godo.Client, error) {
+	ctx := context.TODO()
+	if transport != nil {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: transport})
+	}
+	client := godo.NewClient(oauth2.NewClient(ctx, &apiTokenSource{
+		AccessToken: token,
+	}))
+	if apiURL != "" {
+		u, err := url.Parse(apiURL)
+		if err != nil {
+			return nil, err
+		}
+		client.BaseURL = u
+	}
+	client.UserAgent = fmt.Sprintf("%s %s", userAgent(buildUUID), client.UserAgent)
+	client.OnRequestCompleted(logFailedRequest)
+
+	return client, nil
+}
+
+// userAgent names the plugin and its version, and the Packer run and build
+// the requests are made for, so that DigitalOcean support can find them.
+func userAgent(buildUUID string) string {
+	ua := "packer-plugin-digitalocean/" + version.PluginVersion.FormattedVersion()
+	var ids []string
+	if run := os.Getenv("PACKER_RUN_UUID"); run != "" {
+		ids = append(ids, "run "+run)
+	}
+	if buildUUID != "" {
+		ids = append(ids, "build "+buildUUID)
+	}
+	if len(ids) > 0 {
+		ua += fmt.Sprintf(" (%s)", strings.Join(ids, "; "))
+	}
+	return ua
+}
+
+// logFailedRequest logs the request ID of failed API calls, which
+// DigitalOcean support asks for, including the ones retried or handled
+// without an error being reported.
+func logFailedRequest(req *http.Request, resp *http.Response) {
+	if resp == nil || resp.StatusCode < 400 {
+		return
+	}
+	logf(levelWarn, []interface{}{"status", resp.StatusCode, "request_id", resp.Header.Get("X-Request-Id")},
+		"API call %s %s failed", req.Method, req.URL.Path)
+}
@@ -0,0 +1,120 @@
+package digitalocean
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/digitalocean/godo"
+
+	"github.com/hashicorp/packer-plugin-digitalocean/version"
+)
+
+func TestNewClient_UserAgent(t *testing.T) {
+	t.Setenv("PACKER_RUN_UUID", "8c3a3994-09b1-4f1e-b6b5-5c0a4b1ebd4f")
+
+	client, err := NewClient("token", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "packer-plugin-digitalocean/" + version.PluginVersion.FormattedVersion() +
+		" (run 8c3a3994-09b1-4f1e-b6b5-5c0a4b1ebd4f) godo/"
+	if !strings.HasPrefix(client.UserAgent, expected) {
+		t.Fatalf("expected the user agent to start with %q, got %q", expected, client.UserAgent)
+	}
+
+	client, err = newClient("token", "", "2f1d7c0e-5b8a-4a52-9d1e-3c6f0b7a9e21", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "packer-plugin-digitalocean/" + version.PluginVersion.FormattedVersion() +
+		" (run 8c3a3994-09b1-4f1e-b6b5-5c0a4b1ebd4f; build 2f1d7c0e-5b8a-4a52-9d1e-3c6f0b7a9e21) godo/"
+	if !strings.HasPrefix(client.UserAgent, expected) {
+		t.Fatalf("expected the user agent to start with %q, got %q", expected, client.UserAgent)
+	}
+}
+
+func TestNewClient_LogsFailedRequests(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	sim, _ := testSimulator(t)
+	client, err := NewClient("token", sim.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = client.Droplets.Get(context.TODO(), 42)
+	if err == nil {
+		t.Fatal("expected getting an unknown droplet to fail")
+	}
+	if !strings.Contains(err.Error(), `"sim-404"`) {
+		t.Errorf("expected the error to carry the request ID, got: %s", err)
+	}
+	expected := "[WARN] status=404 request_id=sim-404 API call GET /v2/droplets/42 failed"
+	if !strings.Contains(logs.String(), expected) {
+		t.Errorf("expected the log to contain %q, got:\n%s", expected, logs.String())
+	}
+}
+
+func TestTokenPool(t *testing.T) {
+	var mu sync.Mutex
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		seen = append(seen, r.Header.Get("Authorization")+" "+string(bytes.TrimSpace(body)))
+		mu.Unlock()
+		switch r.Header.Get("Authorization") {
+		case "Bearer saturated":
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "Bearer revoked":
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"tag": {"name": "packer"}}`))
+		}
+	}))
+	defer srv.Close()
+
+	pool := newTokenPool([]string{"saturated", "revoked", "fresh"}, nil)
+	client, err := newClient("saturated", srv.URL, "", pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := client.Tags.Create(context.TODO(), &godo.TagCreateRequest{Name: "packer"}); err != nil {
+		t.Fatalf("expected the request to fail over, got %s", err)
+	}
+	if _, _, err := client.Tags.Get(context.TODO(), "packer"); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		`Bearer saturated {"name":"packer"}`,
+		`Bearer revoked {"name":"packer"}`,
+		`Bearer fresh {"name":"packer"}`,
+		"Bearer fresh ",
+	}
+	if strings.Join(seen, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got requests %q, expected %q", seen, expected)
+	}
+
+	// Once every token was tried, the last response is the one reported
+	exhausted := newTokenPool([]string{"saturated", "revoked"}, nil)
+	client, err = newClient("saturated", srv.URL, "", exhausted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, resp, err := client.Tags.Get(context.TODO(), "packer")
+	if err == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected the error of the last token, got %v", err)
+	}
+}
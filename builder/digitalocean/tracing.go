@@ -0,0 +1,384 @@
+package digitalocean
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+const otlpTracesPath = "/v1/traces"
+
+// traceparentRe matches a W3C traceparent, such as the TRACEPARENT set by CI
+// systems tracing their pipelines, which builds are traced as a part of.
+var traceparentRe = regexp.MustCompile(`^[0-9a-f]{2}-([0-9a-f]{32})-([0-9a-f]{16})-[0-9a-f]{2}$`)
+
+// tracer records the spans of a build and exports them to an OTLP/HTTP
+// collector, encoded as JSON, once the build is over.
+type tracer struct {
+	endpoint string
+	headers  map[string]string
+	resource map[string]interface{}
+	client   *http.Client
+
+	traceID  string
+	parentID string
+
+	mu    sync.Mutex
+	spans []*span
+}
+
+// span is an operation of the build. The methods of a nil span do nothing,
+// so that steps don't have to check whether the build is traced.
+type span struct {
+	tracer   *tracer
+	id       string
+	parentID string
+	name     string
+	start    time.Time
+	end      time.Time
+	attrs    map[string]interface{}
+	err      string
+}
+
+// newTracer returns a tracer exporting to the OTLP endpoint, continuing the
+// trace of traceparent when it is a valid one.
+func newTracer(endpoint string, headers map[string]string, traceparent string, resource map[string]interface{}) *tracer {
+	t := &tracer{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		headers:  headers,
+		resource: resource,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+	if !strings.HasSuffix(t.endpoint, otlpTracesPath) {
+		t.endpoint += otlpTracesPath
+	}
+	if m := traceparentRe.FindStringSubmatch(traceparent); m != nil {
+		t.traceID, t.parentID = m[1], m[2]
+	} else {
+		t.traceID = randomHex(16)
+	}
+	return t
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// start starts a span, a child of parent or, without one, of the span of the
+// trace the build is a part of.
+func (t *tracer) start(name string, parent *span) *span {
+	s := &span{
+		tracer:   t,
+		id:       randomHex(8),
+		parentID: t.parentID,
+		name:     name,
+		start:    time.Now(),
+		attrs:    make(map[string]interface{}),
+	}
+	if parent != nil {
+		s.parentID = parent.id
+	}
+	return s
+}
+
+// startSpan starts a span as a child of the span of the running step, and
+// returns nil when the build isn't traced.
+func startSpan(state multistep.StateBag, name string) *span {
+	parent, ok := state.GetOk("trace_span")
+	if !ok {
+		return nil
+	}
+	p := parent.(*span)
+	return p.tracer.start(name, p)
+}
+
+func (s *span) setAttr(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.attrs[key] = value
+}
+
+// finish ends the span, with an error status when err isn't nil.
+func (s *span) finish(err error) {
+	if s == nil {
+		return
+	}
+	s.end = time.Now()
+	if err != nil {
+		s.err = err.Error()
+	}
+	s.tracer.mu.Lock()
+	s.tracer.spans = append(s.tracer.spans, s)
+	s.tracer.mu.Unlock()
+}
+
+// export sends the finished spans to the collector.
+func (t *tracer) export() error {
+	t.mu.Lock()
+	spans := t.spans
+	t.spans = nil
+	t.mu.Unlock()
+	if len(spans) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(t.request(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(context.TODO(), http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// The OTLP JSON encoding of spans.
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+// The SPAN_KIND_INTERNAL kind and the STATUS_CODE_OK and STATUS_CODE_ERROR
+// status codes of OTLP.
+const (
+	otlpKindInternal = 1
+	otlpStatusOK     = 1
+	otlpStatusError  = 2
+)
+
+func (t *tracer) request(spans []*span) *otlpRequest {
+	scope := otlpScopeSpans{}
+	scope.Scope.Name = "packer-plugin-digitalocean"
+	for _, s := range spans {
+		encoded := otlpSpan{
+			TraceID:           t.traceID,
+			SpanID:            s.id,
+			ParentSpanID:      s.parentID,
+			Name:              s.name,
+			Kind:              otlpKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        otlpAttributes(s.attrs),
+			Status:            otlpStatus{Code: otlpStatusOK},
+		}
+		if s.err != "" {
+			encoded.Status = otlpStatus{Code: otlpStatusError, Message: s.err}
+		}
+		scope.Spans = append(scope.Spans, encoded)
+	}
+	resource := otlpResourceSpans{ScopeSpans: []otlpScopeSpans{scope}}
+	resource.Resource.Attributes = otlpAttributes(t.resource)
+	return &otlpRequest{ResourceSpans: []otlpResourceSpans{resource}}
+}
+
+func otlpAttributes(attrs map[string]interface{}) []otlpAttribute {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	encoded := make([]otlpAttribute, 0, len(keys))
+	for _, k := range keys {
+		var value map[string]interface{}
+		switch v := attrs[k].(type) {
+		case int:
+			// 64 bit integers are strings in the JSON encoding
+			value = map[string]interface{}{"intValue": strconv.Itoa(v)}
+		case bool:
+			value = map[string]interface{}{"boolValue": v}
+		case []string:
+			values := make([]map[string]interface{}, 0, len(v))
+			for _, s := range v {
+				values = append(values, map[string]interface{}{"stringValue": s})
+			}
+			value = map[string]interface{}{"arrayValue": map[string]interface{}{"values": values}}
+		default:
+			value = map[string]interface{}{"stringValue": fmt.Sprint(v)}
+		}
+		encoded = append(encoded, otlpAttribute{Key: k, Value: value})
+	}
+	return encoded
+}
+
+// otlpHeaders parses OTEL_EXPORTER_OTLP_HEADERS, key=value pairs separated
+// by commas.
+func otlpHeaders(s string) map[string]string {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			continue
+		}
+		headers[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return headers
+}
+
+// tracedStep runs a step in a span of its own, named after the step, with
+// the attributes of the droplet and image known once it is done.
+type tracedStep struct {
+	step multistep.Step
+	name string
+	root *span
+}
+
+// traceSteps wraps every step that runs in a span, children of root.
+func traceSteps(steps []multistep.Step, root *span) []multistep.Step {
+	traced := make([]multistep.Step, 0, len(steps))
+	for _, step := range steps {
+		name := stepName(step)
+		if name == "null_step" {
+			// A step left out with multistep.If
+			traced = append(traced, step)
+			continue
+		}
+		traced = append(traced, &tracedStep{step: step, name: name, root: root})
+	}
+	return traced
+}
+
+func (s *tracedStep) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	sp := s.root.tracer.start(s.name, s.root)
+	state.Put("trace_span", sp)
+	defer state.Put("trace_span", s.root)
+
+	action := s.step.Run(ctx, state)
+	setBuildAttrs(sp, state)
+	var err error
+	if rawErr, ok := state.GetOk("error"); ok && action == multistep.ActionHalt {
+		err = rawErr.(error)
+	}
+	sp.finish(err)
+	return action
+}
+
+func (s *tracedStep) Cleanup(state multistep.StateBag) {
+	s.step.Cleanup(state)
+}
+
+// setBuildAttrs sets the attributes of the droplet and images the state
+// knows of on a span.
+func setBuildAttrs(s *span, state multistep.StateBag) {
+	c := state.Get("config").(*Config)
+	s.setAttr("digitalocean.region", c.Region)
+	s.setAttr("digitalocean.size", c.Size)
+	s.setAttr("digitalocean.image", c.Image)
+	if dropletId, ok := state.GetOk("droplet_id"); ok {
+		s.setAttr("digitalocean.droplet.id", dropletId.(int))
+		s.setAttr("digitalocean.droplet.name", c.DropletName)
+	}
+	if imageId, ok := state.GetOk("snapshot_image_id"); ok {
+		s.setAttr("digitalocean.snapshot.id", imageId.(int))
+		s.setAttr("digitalocean.snapshot.name", c.SnapshotName)
+	}
+	if regions, ok := state.GetOk("regions"); ok {
+		s.setAttr("digitalocean.snapshot.regions", regions.([]string))
+	}
+}
+
+// stepName returns the name of the type of a step in snake case, without
+// the step prefix, such as create_droplet for stepCreateDroplet.
+func stepName(step multistep.Step) string {
+	if traced, ok := step.(*tracedStep); ok {
+		return traced.name
+	}
+	name := fmt.Sprintf("%T", step)
+	name = name[strings.LastIndex(name, ".")+1:]
+	if strings.HasPrefix(name, "step") || strings.HasPrefix(name, "Step") {
+		name = name[len("step"):]
+	}
+
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 &&
+			(unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// traceResource returns the attributes of the OTLP resource builds are
+// traced as.
+func traceResource(c *Config) map[string]interface{} {
+	resource := map[string]interface{}{
+		"service.name":      "packer",
+		"packer.builder":    BuilderId,
+		"packer.build_name": c.PackerBuildName,
+		"packer.build_type": c.PackerBuilderType,
+		"packer.run_uuid":   os.Getenv("PACKER_RUN_UUID"),
+	}
+	for k, v := range resource {
+		if v == "" {
+			delete(resource, k)
+		}
+	}
+	return resource
+}
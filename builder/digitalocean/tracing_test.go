@@ -0,0 +1,149 @@
+package digitalocean
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/multistep/commonsteps"
+)
+
+type testTracedStep struct {
+	action multistep.StepAction
+}
+
+func (s *testTracedStep) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	child := startSpan(state, "child")
+	child.setAttr("attempt", 2)
+	child.finish(nil)
+	state.Put("droplet_id", 1002)
+	if s.action == multistep.ActionHalt {
+		state.Put("error", errors.New("droplet failed"))
+	}
+	return s.action
+}
+
+func (s *testTracedStep) Cleanup(state multistep.StateBag) {}
+
+func TestTracer(t *testing.T) {
+	var got otlpRequest
+	var header string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		header = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	traceparent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	trace := newTracer(srv.URL+"/", map[string]string{"Authorization": "Bearer abc"}, traceparent,
+		traceResource(&Config{}))
+	root := trace.start("build", nil)
+
+	state := new(multistep.BasicStateBag)
+	state.Put("config", &Config{Region: "nyc3", Size: "s-1vcpu-1gb", Image: "ubuntu-22-04-x64", DropletName: "packer-test"})
+	steps := traceSteps([]multistep.Step{
+		&testTracedStep{action: multistep.ActionContinue},
+		multistep.If(false, new(stepReboot)),
+		&testTracedStep{action: multistep.ActionHalt},
+	}, root)
+	if _, ok := steps[1].(*tracedStep); ok {
+		t.Error("expected steps left out not to be traced")
+	}
+	state.Put("trace_span", root)
+	runner := &multistep.BasicRunner{Steps: steps}
+	runner.Run(context.Background(), state)
+	root.finish(state.Get("error").(error))
+
+	if err := trace.export(); err != nil {
+		t.Fatal(err)
+	}
+	if header != "Bearer abc" {
+		t.Errorf("expected the headers to be sent, got %q", header)
+	}
+
+	if len(got.ResourceSpans) != 1 || len(got.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("unexpected request: %#v", got)
+	}
+	spans := got.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 5 {
+		t.Fatalf("expected 5 spans, got %d", len(spans))
+	}
+	byName := make(map[string][]otlpSpan)
+	for _, s := range spans {
+		if s.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("expected the trace of the traceparent, got %s", s.TraceID)
+		}
+		byName[s.Name] = append(byName[s.Name], s)
+	}
+	build := byName["build"][0]
+	if build.ParentSpanID != "00f067aa0ba902b7" || build.Status.Code != otlpStatusError ||
+		build.Status.Message != "droplet failed" {
+		t.Errorf("unexpected build span: %#v", build)
+	}
+	steps0 := byName["test_traced_step"]
+	if len(steps0) != 2 || steps0[0].ParentSpanID != build.SpanID ||
+		steps0[0].Status.Code != otlpStatusOK || steps0[1].Status.Code != otlpStatusError {
+		t.Fatalf("unexpected step spans: %#v", steps0)
+	}
+	children := byName["child"]
+	if len(children) != 2 || children[0].ParentSpanID != steps0[0].SpanID {
+		t.Errorf("unexpected child spans: %#v", children)
+	}
+	attrs := make(map[string]map[string]interface{})
+	for _, a := range steps0[0].Attributes {
+		attrs[a.Key] = a.Value
+	}
+	if attrs["digitalocean.droplet.id"]["intValue"] != "1002" ||
+		attrs["digitalocean.region"]["stringValue"] != "nyc3" {
+		t.Errorf("unexpected attributes: %#v", attrs)
+	}
+
+	// Nothing is left to export
+	got = otlpRequest{}
+	if err := trace.export(); err != nil || got.ResourceSpans != nil {
+		t.Errorf("expected nothing to be exported, got %#v: %v", got, err)
+	}
+}
+
+func TestStartSpan_Untraced(t *testing.T) {
+	state := new(multistep.BasicStateBag)
+	s := startSpan(state, "snapshot.wait")
+	if s != nil {
+		t.Fatalf("expected no span, got %#v", s)
+	}
+	s.setAttr("region", "nyc3")
+	s.finish(errors.New("ignored"))
+}
+
+func TestStepName(t *testing.T) {
+	cases := map[string]multistep.Step{
+		"create_droplet":    new(stepCreateDroplet),
+		"create_ssh_key":    new(stepCreateSSHKey),
+		"ssh_key_gen":       new(communicator.StepSSHKeyGen),
+		"provision":         new(commonsteps.StepProvision),
+		"cleanup_temp_keys": new(commonsteps.StepCleanupTempKeys),
+		"null_step":         multistep.If(false, new(stepReboot)),
+	}
+	for expected, step := range cases {
+		if name := stepName(step); name != expected {
+			t.Errorf("got %s, expected %s", name, expected)
+		}
+	}
+}
+
+func TestOTLPHeaders(t *testing.T) {
+	headers := otlpHeaders("Authorization=Bearer abc, x-team = platform,invalid")
+	if len(headers) != 2 || headers["Authorization"] != "Bearer abc" || headers["x-team"] != "platform" {
+		t.Errorf("unexpected headers: %#v", headers)
+	}
+}
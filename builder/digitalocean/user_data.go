@@ -0,0 +1,136 @@
+package digitalocean
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// maxUserDataSize is the most user data, in bytes, a droplet can be created
+// with.
+const maxUserDataSize = 64 * 1024
+
+// userDataClient downloads user_data_file URLs, tests replace it.
+var userDataClient = &http.Client{Timeout: time.Minute}
+
+// checksumHashes are the hash types user_data_checksum takes.
+var checksumHashes = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+var userDataVarPattern = regexp.MustCompile(`@\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// substituteUserDataVars replaces the @{name} placeholders of user data with
+// the value of name in vars.
+func substituteUserDataVars(userData string, vars map[string]string) (string, error) {
+	var missing []string
+	seen := make(map[string]bool)
+	substituted := userDataVarPattern.ReplaceAllStringFunc(userData, func(placeholder string) string {
+		name := placeholder[2 : len(placeholder)-1]
+		value, ok := vars[name]
+		if !ok {
+			if !seen[name] {
+				missing = append(missing, name)
+				seen[name] = true
+			}
+			return placeholder
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("no user_data_vars value for %s", strings.Join(missing, ", "))
+	}
+	return substituted, nil
+}
+
+// parseChecksum splits a checksum such as "sha256:..." into its hash and
+// value.
+func parseChecksum(checksum string) (func() hash.Hash, []byte, error) {
+	parts := strings.SplitN(checksum, ":", 2)
+	if len(parts) != 2 {
+		return nil, nil, fmt.Errorf("%q isn't of the form type:value", checksum)
+	}
+	newHash, ok := checksumHashes[strings.ToLower(parts[0])]
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported hash type %q, use sha256 or sha512", parts[0])
+	}
+	value, err := hex.DecodeString(parts[1])
+	if err != nil || len(value) != newHash().Size() {
+		return nil, nil, fmt.Errorf("%q isn't a hex encoded %s hash", parts[1], parts[0])
+	}
+	return newHash, value, nil
+}
+
+// readUserDataFile returns the contents of user_data_file, downloading it
+// when it is an https URL, and checks them against the checksum when one is
+// given.
+func readUserDataFile(path, checksum string) ([]byte, error) {
+	var contents []byte
+	if strings.HasPrefix(path, "https://") {
+		resp, err := userDataClient.Get(path)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("downloading %s: %s", path, resp.Status)
+		}
+		if contents, err = ioutil.ReadAll(resp.Body); err != nil {
+			return nil, fmt.Errorf("downloading %s: %s", path, err)
+		}
+	} else {
+		var err error
+		if contents, err = ioutil.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if checksum == "" {
+		return contents, nil
+	}
+	newHash, expected, err := parseChecksum(checksum)
+	if err != nil {
+		return nil, err
+	}
+	h := newHash()
+	h.Write(contents)
+	if sum := h.Sum(nil); !bytes.Equal(sum, expected) {
+		return nil, fmt.Errorf("checksum of %s is %x, expected %x", path, sum, expected)
+	}
+	return contents, nil
+}
+
+// runUserDataCommand runs user_data_command with the shell, as hooks are,
+// and returns its standard output. Its standard error is part of the error
+// when it fails, and is otherwise dropped, as the output isn't shown.
+func runUserDataCommand(ctx context.Context, command string, env []string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	}
+	cmd.Env = env
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %s", err, msg)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,106 @@
+package digitalocean
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSubstituteUserDataVars(t *testing.T) {
+	vars := map[string]string{"db_password": "hunter2", "region": "nyc3"}
+	in := "#!/bin/sh\necho @{db_password} > /etc/app/secret\necho ${HOME} @{region} @{region}\n"
+	expected := "#!/bin/sh\necho hunter2 > /etc/app/secret\necho ${HOME} nyc3 nyc3\n"
+
+	out, err := substituteUserDataVars(in, vars)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if out != expected {
+		t.Fatalf("expected %q, got %q", expected, out)
+	}
+
+	_, err = substituteUserDataVars("@{token} @{token} @{region}", vars)
+	if err == nil || err.Error() != "no user_data_vars value for token" {
+		t.Fatalf("expected the missing value reported once, got %v", err)
+	}
+}
+
+func TestReadUserDataFile(t *testing.T) {
+	contents := "#cloud-config\npackages: [nginx]\n"
+	sum := sha256.Sum256([]byte(contents))
+	checksum := "sha256:" + hex.EncodeToString(sum[:])
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cloud-init.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(contents))
+	}))
+	defer srv.Close()
+	defaultClient := userDataClient
+	userDataClient = srv.Client()
+	defer func() { userDataClient = defaultClient }()
+
+	path := filepath.Join(t.TempDir(), "cloud-init.yaml")
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for _, file := range []string{path, srv.URL + "/cloud-init.yaml"} {
+		data, err := readUserDataFile(file, checksum)
+		if err != nil {
+			t.Fatalf("%s: %s", file, err)
+		}
+		if string(data) != contents {
+			t.Fatalf("%s: expected %q, got %q", file, contents, data)
+		}
+	}
+
+	wrong := "sha256:" + strings.Repeat("0", 64)
+	if _, err := readUserDataFile(srv.URL+"/cloud-init.yaml", wrong); err == nil {
+		t.Fatal("expected a checksum mismatch")
+	}
+	if _, err := readUserDataFile(srv.URL+"/missing.yaml", ""); err == nil {
+		t.Fatal("expected a download error")
+	}
+}
+
+func TestParseChecksum(t *testing.T) {
+	for _, checksum := range []string{"sha256", "md5:d41d8cd98f00b204e9800998ecf8427e", "sha256:abc", "sha512:" + strings.Repeat("0", 64)} {
+		if _, _, err := parseChecksum(checksum); err == nil {
+			t.Fatalf("expected an error for %q", checksum)
+		}
+	}
+	if _, _, err := parseChecksum("SHA512:" + strings.Repeat("0", 128)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestRunUserDataCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("commands are run with /bin/sh in this test")
+	}
+
+	output, err := runUserDataCommand(context.Background(),
+		`printf '#cloud-config\nhostname: %s\n' "$DIGITALOCEAN_DROPLET_NAME"; echo ignored >&2`,
+		[]string{"DIGITALOCEAN_DROPLET_NAME=web"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if output != "#cloud-config\nhostname: web\n" {
+		t.Errorf("unexpected output %q", output)
+	}
+
+	_, err = runUserDataCommand(context.Background(), "echo 'vault: permission denied' >&2; exit 3", nil)
+	if err == nil || !strings.Contains(err.Error(), "vault: permission denied") {
+		t.Errorf("expected the standard error in the error, got %v", err)
+	}
+}
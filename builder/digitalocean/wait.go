@@ -3,12 +3,15 @@ package digitalocean
 import (
 	"context"
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/digitalocean/godo"
 )
 
+// pollInterval is how long the wait helpers sleep between API calls. Tests
+// lower it to exercise the wait logic quickly.
+var pollInterval = 3 * time.Second
+
 // waitForDropletUnlocked waits for the Droplet to be unlocked to
 // avoid "pending" errors when making state changes.
 func waitForDropletUnlocked(
@@ -22,7 +25,7 @@ func waitForDropletUnlocked(
 		for {
 			attempts += 1
 
-			log.Printf("[DEBUG] Checking droplet lock state... (attempt: %d)", attempts)
+			logf(levelDebug, []interface{}{"droplet_id", dropletId}, "Checking droplet lock state... (attempt: %d)", attempts)
 			droplet, _, err := client.Droplets.Get(context.TODO(), dropletId)
 			if err != nil {
 				result <- err
@@ -34,8 +37,8 @@ func waitForDropletUnlocked(
 				return
 			}
 
-			// Wait 3 seconds in between
-			time.Sleep(3 * time.Second)
+			// Wait in between attempts
+			time.Sleep(pollInterval)
 
 			// Verify we shouldn't exit
 			select {
@@ -48,7 +51,7 @@ func waitForDropletUnlocked(
 		}
 	}()
 
-	log.Printf("[DEBUG] Waiting for up to %d seconds for droplet to unlock", timeout/time.Second)
+	logf(levelDebug, []interface{}{"droplet_id", dropletId}, "Waiting for up to %d seconds for droplet to unlock", timeout/time.Second)
 	select {
 	case err := <-result:
 		return err
@@ -58,9 +61,16 @@ func waitForDropletUnlocked(
 	}
 }
 
-// waitForDropletState simply blocks until the droplet is in
-// a state we expect, while eventually timing out.
-func waitForDropletState(
+// actionCheckPolls is how many polls of a droplet whose status doesn't
+// change WaitForDropletState waits for before checking its last action.
+const actionCheckPolls = 5
+
+// WaitForDropletState simply blocks until the droplet is in
+// a state we expect, while eventually timing out. A droplet becoming active
+// whose status doesn't change has its last action checked every few polls,
+// so that an errored create doesn't wait out the timeout, and the last
+// action is described in the error when it times out.
+func WaitForDropletState(
 	desiredState string, dropletId int,
 	client *godo.Client, timeout time.Duration) error {
 	done := make(chan struct{})
@@ -69,23 +79,47 @@ func waitForDropletState(
 	result := make(chan error, 1)
 	go func() {
 		attempts := 0
+		status, unchanged := "", 0
 		for {
 			attempts += 1
 
-			log.Printf("Checking droplet status... (attempt: %d)", attempts)
+			logf(levelDebug, []interface{}{"droplet_id", dropletId}, "Checking droplet status... (attempt: %d)", attempts)
 			droplet, _, err := client.Droplets.Get(context.TODO(), dropletId)
 			if err != nil {
 				result <- err
 				return
 			}
+			if droplet.Status == status {
+				unchanged++
+			} else {
+				status, unchanged = droplet.Status, 0
+			}
 
 			if droplet.Status == desiredState {
 				result <- nil
 				return
 			}
 
-			// Wait 3 seconds in between
-			time.Sleep(3 * time.Second)
+			// Don't wait out the timeout for a droplet that will never get
+			// there
+			if droplet.Status == "archive" {
+				result <- fmt.Errorf("Droplet was archived while waiting for it to become '%s'", desiredState)
+				return
+			}
+			if desiredState == "active" && unchanged > 0 && unchanged%actionCheckPolls == 0 {
+				action, err := lastDropletAction(client, dropletId)
+				if err != nil {
+					result <- err
+					return
+				}
+				if action != nil && action.Status == "errored" {
+					result <- fmt.Errorf("Droplet %s", describeAction(action))
+					return
+				}
+			}
+
+			// Wait in between attempts
+			time.Sleep(pollInterval)
 
 			// Verify we shouldn't exit
 			select {
@@ -98,16 +132,42 @@ func waitForDropletState(
 		}
 	}()
 
-	log.Printf("Waiting for up to %d seconds for droplet to become %s", timeout/time.Second, desiredState)
+	logf(levelDebug, []interface{}{"droplet_id", dropletId}, "Waiting for up to %d seconds for droplet to become %s", timeout/time.Second, desiredState)
 	select {
 	case err := <-result:
 		return err
 	case <-time.After(timeout):
 		err := fmt.Errorf("Timeout while waiting to for droplet to become '%s'", desiredState)
+		if action, actionErr := lastDropletAction(client, dropletId); actionErr == nil && action != nil {
+			err = fmt.Errorf("%s, its last action: %s", err, describeAction(action))
+		}
 		return err
 	}
 }
 
+// lastDropletAction returns the latest action of the droplet, or nil when it
+// has none.
+func lastDropletAction(client *godo.Client, dropletId int) (*godo.Action, error) {
+	actions, _, err := client.Droplets.Actions(context.TODO(), dropletId, &godo.ListOptions{PerPage: 1})
+	if err != nil || len(actions) == 0 {
+		return nil, err
+	}
+	return &actions[0], nil
+}
+
+// describeAction describes an action by type, ID, region and status, such
+// as "create action 1234 in nyc3 errored".
+func describeAction(action *godo.Action) string {
+	region := action.RegionSlug
+	if region == "" && action.Region != nil {
+		region = action.Region.Slug
+	}
+	if region == "" {
+		return fmt.Sprintf("%s action %d %s", action.Type, action.ID, action.Status)
+	}
+	return fmt.Sprintf("%s action %d in %s %s", action.Type, action.ID, region, action.Status)
+}
+
 // waitForActionState simply blocks until the droplet action is in
 // a state we expect, while eventually timing out.
 func waitForActionState(
@@ -122,7 +182,7 @@ func waitForActionState(
 		for {
 			attempts += 1
 
-			log.Printf("Checking action status... (attempt: %d)", attempts)
+			logf(levelDebug, []interface{}{"droplet_id", dropletId, "action_id", actionId}, "Checking action status... (attempt: %d)", attempts)
 			action, _, err := client.DropletActions.Get(context.TODO(), dropletId, actionId)
 			if err != nil {
 				result <- err
@@ -134,8 +194,13 @@ func waitForActionState(
 				return
 			}
 
-			// Wait 3 seconds in between
-			time.Sleep(3 * time.Second)
+			if action.Status == "errored" {
+				result <- fmt.Errorf("Droplet %s action %d errored", action.Type, action.ID)
+				return
+			}
+
+			// Wait in between attempts
+			time.Sleep(pollInterval)
 
 			// Verify we shouldn't exit
 			select {
@@ -148,7 +213,7 @@ func waitForActionState(
 		}
 	}()
 
-	log.Printf("Waiting for up to %d seconds for action to become %s", timeout/time.Second, desiredState)
+	logf(levelDebug, []interface{}{"droplet_id", dropletId, "action_id", actionId}, "Waiting for up to %d seconds for action to become %s", timeout/time.Second, desiredState)
 	select {
 	case err := <-result:
 		return err
@@ -172,7 +237,7 @@ func WaitForImageState(
 		for {
 			attempts += 1
 
-			log.Printf("Checking action status... (attempt: %d)", attempts)
+			logf(levelDebug, []interface{}{"image_id", imageId, "action_id", actionId}, "Checking action status... (attempt: %d)", attempts)
 			action, _, err := client.ImageActions.Get(context.TODO(), imageId, actionId)
 			if err != nil {
 				result <- err
@@ -184,8 +249,13 @@ func WaitForImageState(
 				return
 			}
 
-			// Wait 3 seconds in between
-			time.Sleep(3 * time.Second)
+			if action.Status == "errored" {
+				result <- fmt.Errorf("Image %s action %d errored", action.Type, action.ID)
+				return
+			}
+
+			// Wait in between attempts
+			time.Sleep(pollInterval)
 
 			// Verify we shouldn't exit
 			select {
@@ -198,7 +268,7 @@ func WaitForImageState(
 		}
 	}()
 
-	log.Printf("Waiting for up to %d seconds for image transfer to become %s", timeout/time.Second, desiredState)
+	logf(levelDebug, []interface{}{"image_id", imageId, "action_id", actionId}, "Waiting for up to %d seconds for image transfer to become %s", timeout/time.Second, desiredState)
 	select {
 	case err := <-result:
 		return err
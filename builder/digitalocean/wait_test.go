@@ -0,0 +1,175 @@
+package digitalocean
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-digitalocean/internal/simulator"
+)
+
+var fastPolling sync.Once
+
+func testSimulator(t *testing.T) (*simulator.Server, *godo.Client) {
+	sim := simulator.New()
+	t.Cleanup(sim.Close)
+
+	// Set once and never restored, as goroutines of timed out waits may
+	// still be polling.
+	fastPolling.Do(func() { pollInterval = time.Millisecond })
+
+	return sim, sim.Client()
+}
+
+func TestWaitForDropletState(t *testing.T) {
+	sim, client := testSimulator(t)
+	sim.DropletPolls = 3
+	droplet := sim.AddDroplet(godo.Droplet{Name: "packer-test", Status: "new"})
+
+	if err := WaitForDropletState("active", droplet.ID, client, time.Second); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestWaitForDropletState_Timeout(t *testing.T) {
+	sim, client := testSimulator(t)
+	droplet := sim.AddDroplet(godo.Droplet{Name: "packer-test", Status: "off"})
+
+	err := WaitForDropletState("active", droplet.ID, client, 50*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "Timeout") {
+		t.Fatalf("expected a timeout, got: %v", err)
+	}
+}
+
+func TestWaitForDropletState_APIError(t *testing.T) {
+	sim, client := testSimulator(t)
+	droplet := sim.AddDroplet(godo.Droplet{Name: "packer-test", Status: "new"})
+	sim.RateLimit(1)
+
+	err := WaitForDropletState("active", droplet.ID, client, time.Second)
+	if err == nil || !strings.Contains(err.Error(), "429") {
+		t.Fatalf("expected a rate limit error, got: %v", err)
+	}
+}
+
+func TestWaitForActionState_Stuck(t *testing.T) {
+	sim, client := testSimulator(t)
+	sim.StickActions("shutdown")
+	droplet := sim.AddDroplet(godo.Droplet{Name: "packer-test", Region: &godo.Region{Slug: "nyc3"}})
+
+	action, _, err := client.DropletActions.Shutdown(context.TODO(), droplet.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	err = waitForActionState(godo.ActionCompleted, droplet.ID, action.ID, client, 50*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "Timeout") {
+		t.Fatalf("expected a timeout, got: %v", err)
+	}
+}
+
+func TestWaitForDropletUnlocked(t *testing.T) {
+	sim, client := testSimulator(t)
+	sim.ActionPolls = 2
+	droplet := sim.AddDroplet(godo.Droplet{Name: "packer-test", Region: &godo.Region{Slug: "nyc3"}})
+
+	action, _, err := client.DropletActions.PowerOff(context.TODO(), droplet.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := waitForActionState(godo.ActionCompleted, droplet.ID, action.ID, client, time.Second); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := waitForDropletUnlocked(client, droplet.ID, time.Second); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	d, _ := sim.Droplet(droplet.ID)
+	if d.Status != "off" {
+		t.Fatalf("expected droplet to be off, got %q", d.Status)
+	}
+}
+
+func TestWaitForDropletState_Archived(t *testing.T) {
+	sim, client := testSimulator(t)
+	droplet := sim.AddDroplet(godo.Droplet{Name: "packer-test", Status: "archive"})
+
+	err := WaitForDropletState("active", droplet.ID, client, time.Minute)
+	if err == nil || !strings.Contains(err.Error(), "archived") {
+		t.Fatalf("expected an archived error, got: %v", err)
+	}
+}
+
+func TestWaitForDropletState_CreateErrored(t *testing.T) {
+	sim, client := testSimulator(t)
+	sim.DropletPolls = 1000
+	sim.FailActions("create")
+
+	droplet, _, err := client.Droplets.Create(context.TODO(), &godo.DropletCreateRequest{
+		Name:   "packer-test",
+		Region: "nyc3",
+		Size:   "s-1vcpu-1gb",
+		Image:  godo.DropletCreateImage{Slug: "ubuntu-20-04-x64"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	err = WaitForDropletState("active", droplet.ID, client, time.Minute)
+	if err == nil || !strings.Contains(err.Error(), "create action") || !strings.Contains(err.Error(), "in nyc3 errored") {
+		t.Fatalf("expected an errored action, got: %v", err)
+	}
+}
+
+func TestWaitForActionState_Errored(t *testing.T) {
+	sim, client := testSimulator(t)
+	sim.FailActions("snapshot")
+	droplet := sim.AddDroplet(godo.Droplet{Name: "packer-test", Region: &godo.Region{Slug: "nyc3"}})
+
+	action, _, err := client.DropletActions.Snapshot(context.TODO(), droplet.ID, "packer-test")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	err = waitForActionState(godo.ActionCompleted, droplet.ID, action.ID, client, time.Minute)
+	if err == nil || !strings.Contains(err.Error(), "errored") {
+		t.Fatalf("expected an errored action, got: %v", err)
+	}
+}
+
+func TestWaitForDropletState_TimeoutLastAction(t *testing.T) {
+	sim, client := testSimulator(t)
+	sim.DropletPolls = 1000
+
+	droplet, _, err := client.Droplets.Create(context.TODO(), &godo.DropletCreateRequest{
+		Name:   "packer-test",
+		Region: "nyc3",
+		Size:   "s-1vcpu-1gb",
+		Image:  godo.DropletCreateImage{Slug: "ubuntu-20-04-x64"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	err = WaitForDropletState("active", droplet.ID, client, 10*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "its last action: create action") || !strings.Contains(err.Error(), "in nyc3") {
+		t.Fatalf("expected the last action in the error, got: %v", err)
+	}
+
+	// The actions are only listed every few polls of the droplet
+	polls, lists := 0, 0
+	for _, req := range sim.Requests() {
+		switch {
+		case strings.HasSuffix(req, "/actions"):
+			lists++
+		case strings.HasPrefix(req, "GET /v2/droplets/"):
+			polls++
+		}
+	}
+	if lists > polls/actionCheckPolls+1 {
+		t.Errorf("listed the actions %d times for %d polls", lists, polls)
+	}
+}
@@ -0,0 +1,94 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+// watchdogInterval is how often the watchdog checks max_estimated_cost.
+var watchdogInterval = time.Minute
+
+// startWatchdog cancels the returned context once the build has been running
+// for longer than maxDuration, or once the droplet has cost more than
+// maxCost, when they are set. Cancelling alone isn't enough when a
+// provisioner is wedged and ignores it, so the droplet is also destroyed
+// straight away rather than waiting for the step cleanups to get to it.
+// With budget_action set to warn, exceeding maxCost is only reported.
+func startWatchdog(ctx context.Context, state multistep.StateBag, maxDuration time.Duration, maxCost float64) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	var expired <-chan time.Time
+	timer := time.NewTimer(maxDuration)
+	if maxDuration > 0 {
+		expired = timer.C
+	}
+	var checks <-chan time.Time
+	ticker := time.NewTicker(watchdogInterval)
+	if maxCost > 0 {
+		checks = ticker.C
+	}
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		client := state.Get("client").(*godo.Client)
+		c := state.Get("config").(*Config)
+		ui := newStepUi(state, "watchdog")
+
+		var err error
+		for err == nil {
+			select {
+			case <-expired:
+				err = fmt.Errorf("Build exceeded max_build_duration of %s", maxDuration)
+			case <-checks:
+				cost, duration, checkErr := estimatedCost(client, state, c)
+				if checkErr != nil {
+					ui.Debugf("Error estimating the cost of the build: %s", checkErr)
+					continue
+				}
+				if cost <= maxCost {
+					continue
+				}
+				budgetErr := fmt.Errorf("Estimated cost of $%.4f after %s exceeds max_estimated_cost of $%.4f",
+					cost, duration.Round(time.Second), maxCost)
+				if c.BudgetAction == "warn" {
+					ui.Warn(budgetErr.Error())
+					checks = nil
+					continue
+				}
+				err = budgetErr
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		state.Put("error", err)
+		ui.Error(err.Error())
+		cancel()
+
+		dropletID, ok := state.GetOk("droplet_id")
+		if !ok {
+			return
+		}
+
+		ui.Say("Destroying droplet...")
+		err = DestroyDroplet(client, dropletID.(int), c.StateTimeout)
+		trackDeleted(state, resourceDroplet, dropletID, err)
+		if err != nil {
+			ui.Error(fmt.Sprintf(
+				"Error destroying droplet. Please destroy it manually: %s", err))
+			return
+		}
+		state.Put("droplet_destroyed", true)
+	}()
+
+	return ctx, func() {
+		timer.Stop()
+		ticker.Stop()
+		cancel()
+		<-done
+	}
+}
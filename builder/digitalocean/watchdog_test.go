@@ -0,0 +1,105 @@
+package digitalocean
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestWatchdog(t *testing.T) {
+	sim, client := testSimulator(t)
+	d := sim.AddDroplet(godo.Droplet{Name: "packer-test", Status: "active"})
+
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("config", &Config{StateTimeout: time.Minute})
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("droplet_id", d.ID)
+
+	ctx, stop := startWatchdog(context.Background(), state, 10*time.Millisecond, 0)
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("context wasn't cancelled")
+	}
+	stop()
+
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("expected an error in state")
+	}
+	if _, ok := state.GetOk("droplet_destroyed"); !ok {
+		t.Fatal("expected droplet to be destroyed")
+	}
+	if _, ok := sim.Droplet(d.ID); ok {
+		t.Fatal("droplet still exists")
+	}
+}
+
+func TestWatchdog_Stopped(t *testing.T) {
+	_, client := testSimulator(t)
+
+	state := new(multistep.BasicStateBag)
+	state.Put("client", client)
+	state.Put("config", &Config{StateTimeout: time.Minute})
+	state.Put("ui", packersdk.TestUi(t))
+
+	ctx, stop := startWatchdog(context.Background(), state, time.Hour, 0)
+	stop()
+
+	if ctx.Err() == nil {
+		t.Fatal("expected context to be cancelled")
+	}
+	if _, ok := state.GetOk("error"); ok {
+		t.Fatal("should not have an error in state")
+	}
+}
+
+func TestWatchdog_MaxEstimatedCost(t *testing.T) {
+	defer func(interval time.Duration) { watchdogInterval = interval }(watchdogInterval)
+	watchdogInterval = time.Millisecond
+
+	for _, action := range []string{"fail", "warn"} {
+		sim, client := testSimulator(t)
+		d := sim.AddDroplet(godo.Droplet{Name: "packer-test", Status: "active"})
+
+		state := new(multistep.BasicStateBag)
+		state.Put("client", client)
+		state.Put("config", &Config{StateTimeout: time.Minute, BudgetAction: action})
+		state.Put("ui", packersdk.TestUi(t))
+		state.Put("droplet_id", d.ID)
+		state.Put("price_hourly", 0.5)
+		// A provisioner that has been running for hours
+		state.Put("droplet_created_at", time.Now().Add(-3*time.Hour))
+
+		ctx, stop := startWatchdog(context.Background(), state, 0, 1)
+		select {
+		case <-ctx.Done():
+		case <-time.After(100 * time.Millisecond):
+		}
+		stopped := ctx.Err() != nil
+		stop()
+
+		if action == "warn" {
+			if stopped {
+				t.Errorf("%s: expected the build to go on", action)
+			}
+			if _, ok := sim.Droplet(d.ID); !ok {
+				t.Errorf("%s: expected the droplet to be kept", action)
+			}
+			continue
+		}
+		if !stopped {
+			t.Fatalf("%s: context wasn't cancelled", action)
+		}
+		if _, ok := state.GetOk("droplet_destroyed"); !ok {
+			t.Fatalf("%s: expected droplet to be destroyed", action)
+		}
+		if _, ok := sim.Droplet(d.ID); ok {
+			t.Fatalf("%s: droplet still exists", action)
+		}
+	}
+}
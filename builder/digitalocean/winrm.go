@@ -0,0 +1,66 @@
+package digitalocean
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// randomWinRMPassword returns a password meeting the complexity requirements
+// of Windows, with upper and lower case letters, digits and symbols.
+func randomWinRMPassword() (string, error) {
+	b := make([]byte, 18)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b) + "Aa1!", nil
+}
+
+// powershellQuote quotes s as a PowerShell string literal.
+func powershellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// winrmSetupScript returns the user data run by cloudbase-init that sets the
+// password of the WinRM user and enables WinRM on the port of the
+// communicator, over HTTPS with a self-signed certificate when it uses SSL.
+func winrmSetupScript(c *Config) string {
+	if c.Comm.Type != "winrm" || c.SkipWinRMSetup {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("#ps1_sysnative\n")
+	fmt.Fprintf(&b, "$user = %s\n", powershellQuote(c.Comm.WinRMUser))
+	fmt.Fprintf(&b, "$password = %s\n", powershellQuote(c.Comm.WinRMPassword))
+	b.WriteString(`if (Get-LocalUser -Name $user -ErrorAction SilentlyContinue) {
+  net user $user $password
+} else {
+  net user $user $password /add
+  net localgroup Administrators $user /add
+}
+Enable-PSRemoting -Force -SkipNetworkProfileCheck
+winrm set winrm/config/service/auth '@{Basic="true"}'
+`)
+	if c.Comm.WinRMUseSSL {
+		b.WriteString(`$cert = New-SelfSignedCertificate -DnsName $env:COMPUTERNAME -CertStoreLocation Cert:\LocalMachine\My
+Get-ChildItem WSMan:\localhost\Listener | Where-Object { $_.Keys -contains 'Transport=HTTPS' } | Remove-Item -Recurse
+New-Item -Path WSMan:\localhost\Listener -Transport HTTPS -Address * -CertificateThumbPrint $cert.Thumbprint -Force
+`)
+	} else {
+		b.WriteString(`winrm set winrm/config/service '@{AllowUnencrypted="true"}'
+`)
+	}
+	fmt.Fprintf(&b, "winrm set winrm/config/listener?Address=*+Transport=%s '@{Port=\"%d\"}'\n", winrmTransport(c), c.Comm.WinRMPort)
+	fmt.Fprintf(&b, "New-NetFirewallRule -DisplayName 'Packer WinRM' -Direction Inbound -Protocol TCP -LocalPort %d -Action Allow\n", c.Comm.WinRMPort)
+	b.WriteString("Restart-Service WinRM\n")
+	return b.String()
+}
+
+func winrmTransport(c *Config) string {
+	if c.Comm.WinRMUseSSL {
+		return "HTTPS"
+	}
+	return "HTTP"
+}
@@ -0,0 +1,41 @@
+package digitalocean
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+)
+
+func TestWinRMSetupScript(t *testing.T) {
+	c := &Config{Comm: communicator.Config{
+		Type: "winrm",
+		WinRM: communicator.WinRM{
+			WinRMUser:     "packer",
+			WinRMPassword: "it's-secret",
+			WinRMPort:     5986,
+			WinRMUseSSL:   true,
+		},
+	}}
+	script := winrmSetupScript(c)
+	for _, expected := range []string{
+		"#ps1_sysnative\n",
+		"$user = 'packer'\n",
+		"$password = 'it''s-secret'\n",
+		"New-SelfSignedCertificate",
+		"Transport=HTTPS '@{Port=\"5986\"}'",
+		"-LocalPort 5986",
+	} {
+		if !strings.Contains(script, expected) {
+			t.Errorf("script is missing %q:\n%s", expected, script)
+		}
+	}
+	if strings.Contains(script, "AllowUnencrypted") {
+		t.Errorf("unexpected unencrypted WinRM with SSL:\n%s", script)
+	}
+
+	c.SkipWinRMSetup = true
+	if script := winrmSetupScript(c); script != "" {
+		t.Errorf("unexpected script with skip_winrm_setup:\n%s", script)
+	}
+}
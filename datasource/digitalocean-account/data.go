@@ -0,0 +1,127 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,DatasourceOutput
+
+package digitaloceanaccount
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-digitalocean/builder/digitalocean"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/zclconf/go-cty/cty"
+)
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	APIToken string `mapstructure:"api_token"`
+	APIURL   string `mapstructure:"api_url"`
+}
+
+type Datasource struct {
+	config Config
+}
+
+type DatasourceOutput struct {
+	UUID            string `mapstructure:"uuid"`
+	Email           string `mapstructure:"email"`
+	EmailVerified   bool   `mapstructure:"email_verified"`
+	Status          string `mapstructure:"status"`
+	StatusMessage   string `mapstructure:"status_message"`
+	DropletLimit    int    `mapstructure:"droplet_limit"`
+	DropletCount    int    `mapstructure:"droplet_count"`
+	VolumeLimit     int    `mapstructure:"volume_limit"`
+	FloatingIPLimit int    `mapstructure:"floating_ip_limit"`
+	TeamUUID        string `mapstructure:"team_uuid"`
+	TeamName        string `mapstructure:"team_name"`
+}
+
+// account is godo.Account along with the team, which godo doesn't decode.
+type account struct {
+	godo.Account
+	Team struct {
+		UUID string `json:"uuid"`
+		Name string `json:"name"`
+	} `json:"team"`
+}
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	if d.config.APIToken == "" {
+		d.config.APIToken = os.Getenv("DIGITALOCEAN_API_TOKEN")
+	}
+
+	if d.config.APIURL == "" {
+		d.config.APIURL = os.Getenv("DIGITALOCEAN_API_URL")
+	}
+
+	if d.config.APIToken == "" {
+		return fmt.Errorf("api_token must be set")
+	}
+
+	packersdk.LogSecretFilter.Set(d.config.APIToken)
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	client, err := digitalocean.NewClient(d.config.APIToken, d.config.APIURL)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), fmt.Errorf("Invalid API URL: %s", err)
+	}
+
+	req, err := client.NewRequest(context.TODO(), http.MethodGet, "v2/account", nil)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+	root := new(struct {
+		Account account `json:"account"`
+	})
+	if _, err := client.Do(context.TODO(), req, root); err != nil {
+		return cty.NullVal(cty.EmptyObject), fmt.Errorf("Error retrieving account: %s", err)
+	}
+
+	// Only the total is needed, which every page carries
+	_, resp, err := client.Droplets.List(context.TODO(), &godo.ListOptions{Page: 1, PerPage: 1})
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), fmt.Errorf("Error counting droplets: %s", err)
+	}
+	count := 0
+	if resp.Meta != nil {
+		count = resp.Meta.Total
+	}
+
+	acct := root.Account
+	output := DatasourceOutput{
+		UUID:            acct.UUID,
+		Email:           acct.Email,
+		EmailVerified:   acct.EmailVerified,
+		Status:          acct.Status,
+		StatusMessage:   acct.StatusMessage,
+		DropletLimit:    acct.DropletLimit,
+		DropletCount:    count,
+		VolumeLimit:     acct.VolumeLimit,
+		FloatingIPLimit: acct.FloatingIPLimit,
+		TeamUUID:        acct.Team.UUID,
+		TeamName:        acct.Team.Name,
+	}
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
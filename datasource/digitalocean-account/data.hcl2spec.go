@@ -0,0 +1,92 @@
+// Code generated by "packer-sdc mapstructure-to-hcl2"; DO NOT EDIT.
+
+package digitaloceanaccount
+
+import (
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// FlatConfig is an auto-generated flat version of Config.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatConfig struct {
+	PackerBuildName     *string           `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
+	PackerBuilderType   *string           `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
+	PackerCoreVersion   *string           `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
+	PackerDebug         *bool             `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
+	PackerForce         *bool             `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
+	PackerOnError       *string           `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
+	PackerUserVars      map[string]string `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
+	PackerSensitiveVars []string          `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
+	APIToken            *string           `mapstructure:"api_token" cty:"api_token" hcl:"api_token"`
+	APIURL              *string           `mapstructure:"api_url" cty:"api_url" hcl:"api_url"`
+}
+
+// FlatMapstructure returns a new FlatConfig.
+// FlatConfig is an auto-generated flat version of Config.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*Config) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatConfig)
+}
+
+// HCL2Spec returns the hcl spec of a Config.
+// This spec is used by HCL to read the fields of Config.
+// The decoded values from this spec will then be applied to a FlatConfig.
+func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"packer_build_name":          &hcldec.AttrSpec{Name: "packer_build_name", Type: cty.String, Required: false},
+		"packer_builder_type":        &hcldec.AttrSpec{Name: "packer_builder_type", Type: cty.String, Required: false},
+		"packer_core_version":        &hcldec.AttrSpec{Name: "packer_core_version", Type: cty.String, Required: false},
+		"packer_debug":               &hcldec.AttrSpec{Name: "packer_debug", Type: cty.Bool, Required: false},
+		"packer_force":               &hcldec.AttrSpec{Name: "packer_force", Type: cty.Bool, Required: false},
+		"packer_on_error":            &hcldec.AttrSpec{Name: "packer_on_error", Type: cty.String, Required: false},
+		"packer_user_variables":      &hcldec.AttrSpec{Name: "packer_user_variables", Type: cty.Map(cty.String), Required: false},
+		"packer_sensitive_variables": &hcldec.AttrSpec{Name: "packer_sensitive_variables", Type: cty.List(cty.String), Required: false},
+		"api_token":                  &hcldec.AttrSpec{Name: "api_token", Type: cty.String, Required: false},
+		"api_url":                    &hcldec.AttrSpec{Name: "api_url", Type: cty.String, Required: false},
+	}
+	return s
+}
+
+// FlatDatasourceOutput is an auto-generated flat version of DatasourceOutput.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatDatasourceOutput struct {
+	UUID            *string `mapstructure:"uuid" cty:"uuid" hcl:"uuid"`
+	Email           *string `mapstructure:"email" cty:"email" hcl:"email"`
+	EmailVerified   *bool   `mapstructure:"email_verified" cty:"email_verified" hcl:"email_verified"`
+	Status          *string `mapstructure:"status" cty:"status" hcl:"status"`
+	StatusMessage   *string `mapstructure:"status_message" cty:"status_message" hcl:"status_message"`
+	DropletLimit    *int    `mapstructure:"droplet_limit" cty:"droplet_limit" hcl:"droplet_limit"`
+	DropletCount    *int    `mapstructure:"droplet_count" cty:"droplet_count" hcl:"droplet_count"`
+	VolumeLimit     *int    `mapstructure:"volume_limit" cty:"volume_limit" hcl:"volume_limit"`
+	FloatingIPLimit *int    `mapstructure:"floating_ip_limit" cty:"floating_ip_limit" hcl:"floating_ip_limit"`
+	TeamUUID        *string `mapstructure:"team_uuid" cty:"team_uuid" hcl:"team_uuid"`
+	TeamName        *string `mapstructure:"team_name" cty:"team_name" hcl:"team_name"`
+}
+
+// FlatMapstructure returns a new FlatDatasourceOutput.
+// FlatDatasourceOutput is an auto-generated flat version of DatasourceOutput.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*DatasourceOutput) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatDatasourceOutput)
+}
+
+// HCL2Spec returns the hcl spec of a DatasourceOutput.
+// This spec is used by HCL to read the fields of DatasourceOutput.
+// The decoded values from this spec will then be applied to a FlatDatasourceOutput.
+func (*FlatDatasourceOutput) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"uuid":              &hcldec.AttrSpec{Name: "uuid", Type: cty.String, Required: false},
+		"email":             &hcldec.AttrSpec{Name: "email", Type: cty.String, Required: false},
+		"email_verified":    &hcldec.AttrSpec{Name: "email_verified", Type: cty.Bool, Required: false},
+		"status":            &hcldec.AttrSpec{Name: "status", Type: cty.String, Required: false},
+		"status_message":    &hcldec.AttrSpec{Name: "status_message", Type: cty.String, Required: false},
+		"droplet_limit":     &hcldec.AttrSpec{Name: "droplet_limit", Type: cty.Number, Required: false},
+		"droplet_count":     &hcldec.AttrSpec{Name: "droplet_count", Type: cty.Number, Required: false},
+		"volume_limit":      &hcldec.AttrSpec{Name: "volume_limit", Type: cty.Number, Required: false},
+		"floating_ip_limit": &hcldec.AttrSpec{Name: "floating_ip_limit", Type: cty.Number, Required: false},
+		"team_uuid":         &hcldec.AttrSpec{Name: "team_uuid", Type: cty.String, Required: false},
+		"team_name":         &hcldec.AttrSpec{Name: "team_name", Type: cty.String, Required: false},
+	}
+	return s
+}
@@ -0,0 +1,64 @@
+package digitaloceanaccount
+
+import (
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-digitalocean/internal/simulator"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestDatasource_ImplementsDatasource(t *testing.T) {
+	var _ packersdk.Datasource = new(Datasource)
+}
+
+func TestDatasource_Configure(t *testing.T) {
+	t.Setenv("DIGITALOCEAN_API_TOKEN", "")
+
+	var d Datasource
+	if err := d.Configure(map[string]interface{}{}); err == nil {
+		t.Fatal("expected an error without api_token")
+	}
+
+	t.Setenv("DIGITALOCEAN_API_TOKEN", "foo")
+	d = Datasource{}
+	if err := d.Configure(map[string]interface{}{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestDatasource_Execute(t *testing.T) {
+	sim := simulator.New()
+	defer sim.Close()
+	sim.AddDroplet(godo.Droplet{Name: "web-1", Status: "active"})
+	sim.AddDroplet(godo.Droplet{Name: "web-2", Status: "active"})
+
+	var d Datasource
+	err := d.Configure(map[string]interface{}{
+		"api_token": "foo",
+		"api_url":   sim.URL(),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	value, err := d.Execute()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := value.GetAttr("status").AsString(); got != "active" {
+		t.Errorf("expected status active, got %s", got)
+	}
+	if got := value.GetAttr("email").AsString(); got != "packer@example.com" {
+		t.Errorf("expected email packer@example.com, got %s", got)
+	}
+	if got, _ := value.GetAttr("droplet_limit").AsBigFloat().Int64(); got != 25 {
+		t.Errorf("expected droplet_limit 25, got %d", got)
+	}
+	if got, _ := value.GetAttr("droplet_count").AsBigFloat().Int64(); got != 2 {
+		t.Errorf("expected droplet_count 2, got %d", got)
+	}
+	if got := value.GetAttr("team_name").AsString(); got != "My Team" {
+		t.Errorf("expected team_name My Team, got %s", got)
+	}
+}
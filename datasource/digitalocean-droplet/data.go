@@ -0,0 +1,169 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,DatasourceOutput
+
+package digitaloceandroplet
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-digitalocean/builder/digitalocean"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/zclconf/go-cty/cty"
+)
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	APIToken string `mapstructure:"api_token"`
+	APIURL   string `mapstructure:"api_url"`
+
+	Name string `mapstructure:"name"`
+	Tag  string `mapstructure:"tag"`
+}
+
+type Datasource struct {
+	config Config
+}
+
+type DatasourceOutput struct {
+	ID                 int      `mapstructure:"id"`
+	Name               string   `mapstructure:"name"`
+	Status             string   `mapstructure:"status"`
+	Region             string   `mapstructure:"region"`
+	Size               string   `mapstructure:"size"`
+	ImageID            int      `mapstructure:"image_id"`
+	ImageSlug          string   `mapstructure:"image_slug"`
+	ImageName          string   `mapstructure:"image_name"`
+	IPv4Address        string   `mapstructure:"ipv4_address"`
+	IPv4AddressPrivate string   `mapstructure:"ipv4_address_private"`
+	IPv6Address        string   `mapstructure:"ipv6_address"`
+	VPCUUID            string   `mapstructure:"vpc_uuid"`
+	Tags               []string `mapstructure:"tags"`
+}
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	if d.config.APIToken == "" {
+		d.config.APIToken = os.Getenv("DIGITALOCEAN_API_TOKEN")
+	}
+
+	if d.config.APIURL == "" {
+		d.config.APIURL = os.Getenv("DIGITALOCEAN_API_URL")
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if d.config.APIToken == "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("api_token must be set"))
+	}
+
+	if d.config.Name == "" && d.config.Tag == "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("name or tag must be set"))
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	packersdk.LogSecretFilter.Set(d.config.APIToken)
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	client, err := digitalocean.NewClient(d.config.APIToken, d.config.APIURL)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), fmt.Errorf("Invalid API URL: %s", err)
+	}
+
+	droplet, err := findDroplet(client, d.config.Name, d.config.Tag)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	output := DatasourceOutput{
+		ID:      droplet.ID,
+		Name:    droplet.Name,
+		Status:  droplet.Status,
+		Size:    droplet.SizeSlug,
+		VPCUUID: droplet.VPCUUID,
+		Tags:    droplet.Tags,
+	}
+	if droplet.Region != nil {
+		output.Region = droplet.Region.Slug
+	}
+	if droplet.Image != nil {
+		output.ImageID, output.ImageSlug, output.ImageName = droplet.Image.ID, droplet.Image.Slug, droplet.Image.Name
+	}
+	// Droplets without the network have no address of the kind
+	output.IPv4Address, _ = droplet.PublicIPv4()
+	output.IPv4AddressPrivate, _ = droplet.PrivateIPv4()
+	output.IPv6Address, _ = droplet.PublicIPv6()
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
+
+// findDroplet returns the droplet with the given name and tag, either of
+// which may be empty.
+func findDroplet(client *godo.Client, name, tag string) (*godo.Droplet, error) {
+	opt := &godo.ListOptions{
+		Page:    1,
+		PerPage: 200,
+	}
+	var matches []godo.Droplet
+	for {
+		var droplets []godo.Droplet
+		var resp *godo.Response
+		var err error
+		if tag != "" {
+			droplets, resp, err = client.Droplets.ListByTag(context.TODO(), tag, opt)
+		} else {
+			droplets, resp, err = client.Droplets.List(context.TODO(), opt)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Error listing droplets: %s", err)
+		}
+		for _, droplet := range droplets {
+			if name == "" || droplet.Name == name {
+				matches = append(matches, droplet)
+			}
+		}
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			break
+		}
+		opt.Page++
+	}
+
+	what := "named " + name
+	switch {
+	case name == "":
+		what = "tagged " + tag
+	case tag != "":
+		what += " and tagged " + tag
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no droplet is %s", what)
+	case 1:
+		return &matches[0], nil
+	}
+	return nil, fmt.Errorf("%d droplets are %s", len(matches), what)
+}
@@ -0,0 +1,100 @@
+// Code generated by "packer-sdc mapstructure-to-hcl2"; DO NOT EDIT.
+
+package digitaloceandroplet
+
+import (
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// FlatConfig is an auto-generated flat version of Config.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatConfig struct {
+	PackerBuildName     *string           `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
+	PackerBuilderType   *string           `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
+	PackerCoreVersion   *string           `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
+	PackerDebug         *bool             `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
+	PackerForce         *bool             `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
+	PackerOnError       *string           `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
+	PackerUserVars      map[string]string `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
+	PackerSensitiveVars []string          `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
+	APIToken            *string           `mapstructure:"api_token" cty:"api_token" hcl:"api_token"`
+	APIURL              *string           `mapstructure:"api_url" cty:"api_url" hcl:"api_url"`
+	Name                *string           `mapstructure:"name" cty:"name" hcl:"name"`
+	Tag                 *string           `mapstructure:"tag" cty:"tag" hcl:"tag"`
+}
+
+// FlatMapstructure returns a new FlatConfig.
+// FlatConfig is an auto-generated flat version of Config.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*Config) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatConfig)
+}
+
+// HCL2Spec returns the hcl spec of a Config.
+// This spec is used by HCL to read the fields of Config.
+// The decoded values from this spec will then be applied to a FlatConfig.
+func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"packer_build_name":          &hcldec.AttrSpec{Name: "packer_build_name", Type: cty.String, Required: false},
+		"packer_builder_type":        &hcldec.AttrSpec{Name: "packer_builder_type", Type: cty.String, Required: false},
+		"packer_core_version":        &hcldec.AttrSpec{Name: "packer_core_version", Type: cty.String, Required: false},
+		"packer_debug":               &hcldec.AttrSpec{Name: "packer_debug", Type: cty.Bool, Required: false},
+		"packer_force":               &hcldec.AttrSpec{Name: "packer_force", Type: cty.Bool, Required: false},
+		"packer_on_error":            &hcldec.AttrSpec{Name: "packer_on_error", Type: cty.String, Required: false},
+		"packer_user_variables":      &hcldec.AttrSpec{Name: "packer_user_variables", Type: cty.Map(cty.String), Required: false},
+		"packer_sensitive_variables": &hcldec.AttrSpec{Name: "packer_sensitive_variables", Type: cty.List(cty.String), Required: false},
+		"api_token":                  &hcldec.AttrSpec{Name: "api_token", Type: cty.String, Required: false},
+		"api_url":                    &hcldec.AttrSpec{Name: "api_url", Type: cty.String, Required: false},
+		"name":                       &hcldec.AttrSpec{Name: "name", Type: cty.String, Required: false},
+		"tag":                        &hcldec.AttrSpec{Name: "tag", Type: cty.String, Required: false},
+	}
+	return s
+}
+
+// FlatDatasourceOutput is an auto-generated flat version of DatasourceOutput.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatDatasourceOutput struct {
+	ID                 *int     `mapstructure:"id" cty:"id" hcl:"id"`
+	Name               *string  `mapstructure:"name" cty:"name" hcl:"name"`
+	Status             *string  `mapstructure:"status" cty:"status" hcl:"status"`
+	Region             *string  `mapstructure:"region" cty:"region" hcl:"region"`
+	Size               *string  `mapstructure:"size" cty:"size" hcl:"size"`
+	ImageID            *int     `mapstructure:"image_id" cty:"image_id" hcl:"image_id"`
+	ImageSlug          *string  `mapstructure:"image_slug" cty:"image_slug" hcl:"image_slug"`
+	ImageName          *string  `mapstructure:"image_name" cty:"image_name" hcl:"image_name"`
+	IPv4Address        *string  `mapstructure:"ipv4_address" cty:"ipv4_address" hcl:"ipv4_address"`
+	IPv4AddressPrivate *string  `mapstructure:"ipv4_address_private" cty:"ipv4_address_private" hcl:"ipv4_address_private"`
+	IPv6Address        *string  `mapstructure:"ipv6_address" cty:"ipv6_address" hcl:"ipv6_address"`
+	VPCUUID            *string  `mapstructure:"vpc_uuid" cty:"vpc_uuid" hcl:"vpc_uuid"`
+	Tags               []string `mapstructure:"tags" cty:"tags" hcl:"tags"`
+}
+
+// FlatMapstructure returns a new FlatDatasourceOutput.
+// FlatDatasourceOutput is an auto-generated flat version of DatasourceOutput.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*DatasourceOutput) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatDatasourceOutput)
+}
+
+// HCL2Spec returns the hcl spec of a DatasourceOutput.
+// This spec is used by HCL to read the fields of DatasourceOutput.
+// The decoded values from this spec will then be applied to a FlatDatasourceOutput.
+func (*FlatDatasourceOutput) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"id":                   &hcldec.AttrSpec{Name: "id", Type: cty.Number, Required: false},
+		"name":                 &hcldec.AttrSpec{Name: "name", Type: cty.String, Required: false},
+		"status":               &hcldec.AttrSpec{Name: "status", Type: cty.String, Required: false},
+		"region":               &hcldec.AttrSpec{Name: "region", Type: cty.String, Required: false},
+		"size":                 &hcldec.AttrSpec{Name: "size", Type: cty.String, Required: false},
+		"image_id":             &hcldec.AttrSpec{Name: "image_id", Type: cty.Number, Required: false},
+		"image_slug":           &hcldec.AttrSpec{Name: "image_slug", Type: cty.String, Required: false},
+		"image_name":           &hcldec.AttrSpec{Name: "image_name", Type: cty.String, Required: false},
+		"ipv4_address":         &hcldec.AttrSpec{Name: "ipv4_address", Type: cty.String, Required: false},
+		"ipv4_address_private": &hcldec.AttrSpec{Name: "ipv4_address_private", Type: cty.String, Required: false},
+		"ipv6_address":         &hcldec.AttrSpec{Name: "ipv6_address", Type: cty.String, Required: false},
+		"vpc_uuid":             &hcldec.AttrSpec{Name: "vpc_uuid", Type: cty.String, Required: false},
+		"tags":                 &hcldec.AttrSpec{Name: "tags", Type: cty.List(cty.String), Required: false},
+	}
+	return s
+}
@@ -0,0 +1,109 @@
+package digitaloceandroplet
+
+import (
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-digitalocean/internal/simulator"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestDatasource_ImplementsDatasource(t *testing.T) {
+	var _ packersdk.Datasource = new(Datasource)
+}
+
+func TestDatasource_Configure(t *testing.T) {
+	tt := []struct {
+		Name   string
+		Config map[string]interface{}
+		Valid  bool
+	}{
+		{Name: "Name", Config: map[string]interface{}{"api_token": "foo", "name": "web-1"}, Valid: true},
+		{Name: "Tag", Config: map[string]interface{}{"api_token": "foo", "tag": "web"}, Valid: true},
+		{Name: "MissingNameAndTag", Config: map[string]interface{}{"api_token": "foo"}},
+		{Name: "MissingToken", Config: map[string]interface{}{"name": "web-1"}},
+	}
+
+	t.Setenv("DIGITALOCEAN_API_TOKEN", "")
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			var d Datasource
+			err := d.Configure(tc.Config)
+			if tc.Valid && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !tc.Valid && err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestDatasource_Execute(t *testing.T) {
+	sim := simulator.New()
+	defer sim.Close()
+	sim.AddDroplet(godo.Droplet{Name: "web-2", Tags: []string{"web"}})
+	droplet := sim.AddDroplet(godo.Droplet{
+		Name:     "web-1",
+		SizeSlug: "s-1vcpu-1gb",
+		Region:   &godo.Region{Slug: "nyc3"},
+		Image:    &godo.Image{ID: 42, Slug: "ubuntu-20-04-x64", Name: "20.04 (LTS) x64"},
+		Networks: &godo.Networks{V4: []godo.NetworkV4{
+			{IPAddress: "203.0.113.10", Type: "public"},
+			{IPAddress: "10.10.0.2", Type: "private"},
+		}},
+		Tags: []string{"web", "pet"},
+	})
+
+	tt := []struct {
+		Name  string
+		Tag   string
+		Valid bool
+	}{
+		{Name: "web-1", Valid: true},
+		{Tag: "pet", Valid: true},
+		{Name: "web-1", Tag: "web", Valid: true},
+		{Name: "missing"},
+		{Tag: "web"},
+		{Name: "web-2", Tag: "pet"},
+	}
+	for _, tc := range tt {
+		var d Datasource
+		err := d.Configure(map[string]interface{}{
+			"api_token": "foo",
+			"api_url":   sim.URL(),
+			"name":      tc.Name,
+			"tag":       tc.Tag,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		value, err := d.Execute()
+		if !tc.Valid {
+			if err == nil {
+				t.Errorf("expected an error looking up name %q and tag %q", tc.Name, tc.Tag)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if got, _ := value.GetAttr("id").AsBigFloat().Int64(); int(got) != droplet.ID {
+			t.Errorf("expected id %d, got %d", droplet.ID, got)
+		}
+		if got := value.GetAttr("ipv4_address").AsString(); got != "203.0.113.10" {
+			t.Errorf("expected ipv4_address 203.0.113.10, got %s", got)
+		}
+		if got := value.GetAttr("ipv4_address_private").AsString(); got != "10.10.0.2" {
+			t.Errorf("expected ipv4_address_private 10.10.0.2, got %s", got)
+		}
+		if got := value.GetAttr("region").AsString(); got != "nyc3" {
+			t.Errorf("expected region nyc3, got %s", got)
+		}
+		if got := value.GetAttr("image_slug").AsString(); got != "ubuntu-20-04-x64" {
+			t.Errorf("expected image_slug ubuntu-20-04-x64, got %s", got)
+		}
+	}
+}
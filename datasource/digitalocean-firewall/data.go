@@ -0,0 +1,162 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,DatasourceOutput,Rule
+
+package digitaloceanfirewall
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-digitalocean/builder/digitalocean"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/zclconf/go-cty/cty"
+)
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	APIToken string `mapstructure:"api_token"`
+	APIURL   string `mapstructure:"api_url"`
+
+	Name string `mapstructure:"name"`
+}
+
+type Datasource struct {
+	config Config
+}
+
+// Rule is an inbound or outbound firewall rule. Addresses, DropletIDs, Tags
+// and LoadBalancerUIDs are the sources of an inbound rule and the
+// destinations of an outbound one.
+type Rule struct {
+	Protocol         string   `mapstructure:"protocol"`
+	Ports            string   `mapstructure:"ports"`
+	Addresses        []string `mapstructure:"addresses"`
+	DropletIDs       []int    `mapstructure:"droplet_ids"`
+	Tags             []string `mapstructure:"tags"`
+	LoadBalancerUIDs []string `mapstructure:"load_balancer_uids"`
+}
+
+type DatasourceOutput struct {
+	ID            string   `mapstructure:"id"`
+	Name          string   `mapstructure:"name"`
+	Status        string   `mapstructure:"status"`
+	InboundRules  []Rule   `mapstructure:"inbound_rules"`
+	OutboundRules []Rule   `mapstructure:"outbound_rules"`
+	DropletIDs    []int    `mapstructure:"droplet_ids"`
+	Tags          []string `mapstructure:"tags"`
+}
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	if d.config.APIToken == "" {
+		d.config.APIToken = os.Getenv("DIGITALOCEAN_API_TOKEN")
+	}
+
+	if d.config.APIURL == "" {
+		d.config.APIURL = os.Getenv("DIGITALOCEAN_API_URL")
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if d.config.APIToken == "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("api_token must be set"))
+	}
+
+	if d.config.Name == "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("name must be set"))
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	packersdk.LogSecretFilter.Set(d.config.APIToken)
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	client, err := digitalocean.NewClient(d.config.APIToken, d.config.APIURL)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), fmt.Errorf("Invalid API URL: %s", err)
+	}
+
+	firewall, err := findFirewall(client, d.config.Name)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	output := DatasourceOutput{
+		ID:         firewall.ID,
+		Name:       firewall.Name,
+		Status:     firewall.Status,
+		DropletIDs: firewall.DropletIDs,
+		Tags:       firewall.Tags,
+	}
+	for _, rule := range firewall.InboundRules {
+		r := Rule{Protocol: rule.Protocol, Ports: rule.PortRange}
+		if s := rule.Sources; s != nil {
+			r.Addresses, r.DropletIDs, r.Tags, r.LoadBalancerUIDs = s.Addresses, s.DropletIDs, s.Tags, s.LoadBalancerUIDs
+		}
+		output.InboundRules = append(output.InboundRules, r)
+	}
+	for _, rule := range firewall.OutboundRules {
+		r := Rule{Protocol: rule.Protocol, Ports: rule.PortRange}
+		if s := rule.Destinations; s != nil {
+			r.Addresses, r.DropletIDs, r.Tags, r.LoadBalancerUIDs = s.Addresses, s.DropletIDs, s.Tags, s.LoadBalancerUIDs
+		}
+		output.OutboundRules = append(output.OutboundRules, r)
+	}
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
+
+// findFirewall returns the Cloud Firewall with the given name.
+func findFirewall(client *godo.Client, name string) (*godo.Firewall, error) {
+	opt := &godo.ListOptions{
+		Page:    1,
+		PerPage: 200,
+	}
+	var matches []godo.Firewall
+	for {
+		firewalls, resp, err := client.Firewalls.List(context.TODO(), opt)
+		if err != nil {
+			return nil, fmt.Errorf("Error listing firewalls: %s", err)
+		}
+		for _, firewall := range firewalls {
+			if firewall.Name == name {
+				matches = append(matches, firewall)
+			}
+		}
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			break
+		}
+		opt.Page++
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no firewall is named %s", name)
+	case 1:
+		return &matches[0], nil
+	}
+	return nil, fmt.Errorf("%d firewalls are named %s", len(matches), name)
+}
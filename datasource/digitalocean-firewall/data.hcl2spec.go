@@ -0,0 +1,119 @@
+// Code generated by "packer-sdc mapstructure-to-hcl2"; DO NOT EDIT.
+
+package digitaloceanfirewall
+
+import (
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// FlatConfig is an auto-generated flat version of Config.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatConfig struct {
+	PackerBuildName     *string           `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
+	PackerBuilderType   *string           `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
+	PackerCoreVersion   *string           `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
+	PackerDebug         *bool             `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
+	PackerForce         *bool             `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
+	PackerOnError       *string           `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
+	PackerUserVars      map[string]string `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
+	PackerSensitiveVars []string          `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
+	APIToken            *string           `mapstructure:"api_token" cty:"api_token" hcl:"api_token"`
+	APIURL              *string           `mapstructure:"api_url" cty:"api_url" hcl:"api_url"`
+	Name                *string           `mapstructure:"name" cty:"name" hcl:"name"`
+}
+
+// FlatMapstructure returns a new FlatConfig.
+// FlatConfig is an auto-generated flat version of Config.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*Config) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatConfig)
+}
+
+// HCL2Spec returns the hcl spec of a Config.
+// This spec is used by HCL to read the fields of Config.
+// The decoded values from this spec will then be applied to a FlatConfig.
+func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"packer_build_name":          &hcldec.AttrSpec{Name: "packer_build_name", Type: cty.String, Required: false},
+		"packer_builder_type":        &hcldec.AttrSpec{Name: "packer_builder_type", Type: cty.String, Required: false},
+		"packer_core_version":        &hcldec.AttrSpec{Name: "packer_core_version", Type: cty.String, Required: false},
+		"packer_debug":               &hcldec.AttrSpec{Name: "packer_debug", Type: cty.Bool, Required: false},
+		"packer_force":               &hcldec.AttrSpec{Name: "packer_force", Type: cty.Bool, Required: false},
+		"packer_on_error":            &hcldec.AttrSpec{Name: "packer_on_error", Type: cty.String, Required: false},
+		"packer_user_variables":      &hcldec.AttrSpec{Name: "packer_user_variables", Type: cty.Map(cty.String), Required: false},
+		"packer_sensitive_variables": &hcldec.AttrSpec{Name: "packer_sensitive_variables", Type: cty.List(cty.String), Required: false},
+		"api_token":                  &hcldec.AttrSpec{Name: "api_token", Type: cty.String, Required: false},
+		"api_url":                    &hcldec.AttrSpec{Name: "api_url", Type: cty.String, Required: false},
+		"name":                       &hcldec.AttrSpec{Name: "name", Type: cty.String, Required: false},
+	}
+	return s
+}
+
+// FlatDatasourceOutput is an auto-generated flat version of DatasourceOutput.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatDatasourceOutput struct {
+	ID            *string    `mapstructure:"id" cty:"id" hcl:"id"`
+	Name          *string    `mapstructure:"name" cty:"name" hcl:"name"`
+	Status        *string    `mapstructure:"status" cty:"status" hcl:"status"`
+	InboundRules  []FlatRule `mapstructure:"inbound_rules" cty:"inbound_rules" hcl:"inbound_rules"`
+	OutboundRules []FlatRule `mapstructure:"outbound_rules" cty:"outbound_rules" hcl:"outbound_rules"`
+	DropletIDs    []int      `mapstructure:"droplet_ids" cty:"droplet_ids" hcl:"droplet_ids"`
+	Tags          []string   `mapstructure:"tags" cty:"tags" hcl:"tags"`
+}
+
+// FlatMapstructure returns a new FlatDatasourceOutput.
+// FlatDatasourceOutput is an auto-generated flat version of DatasourceOutput.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*DatasourceOutput) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatDatasourceOutput)
+}
+
+// HCL2Spec returns the hcl spec of a DatasourceOutput.
+// This spec is used by HCL to read the fields of DatasourceOutput.
+// The decoded values from this spec will then be applied to a FlatDatasourceOutput.
+func (*FlatDatasourceOutput) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"id":             &hcldec.AttrSpec{Name: "id", Type: cty.String, Required: false},
+		"name":           &hcldec.AttrSpec{Name: "name", Type: cty.String, Required: false},
+		"status":         &hcldec.AttrSpec{Name: "status", Type: cty.String, Required: false},
+		"inbound_rules":  &hcldec.BlockListSpec{TypeName: "inbound_rules", Nested: hcldec.ObjectSpec((*FlatRule)(nil).HCL2Spec())},
+		"outbound_rules": &hcldec.BlockListSpec{TypeName: "outbound_rules", Nested: hcldec.ObjectSpec((*FlatRule)(nil).HCL2Spec())},
+		"droplet_ids":    &hcldec.AttrSpec{Name: "droplet_ids", Type: cty.List(cty.Number), Required: false},
+		"tags":           &hcldec.AttrSpec{Name: "tags", Type: cty.List(cty.String), Required: false},
+	}
+	return s
+}
+
+// FlatRule is an auto-generated flat version of Rule.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatRule struct {
+	Protocol         *string  `mapstructure:"protocol" cty:"protocol" hcl:"protocol"`
+	Ports            *string  `mapstructure:"ports" cty:"ports" hcl:"ports"`
+	Addresses        []string `mapstructure:"addresses" cty:"addresses" hcl:"addresses"`
+	DropletIDs       []int    `mapstructure:"droplet_ids" cty:"droplet_ids" hcl:"droplet_ids"`
+	Tags             []string `mapstructure:"tags" cty:"tags" hcl:"tags"`
+	LoadBalancerUIDs []string `mapstructure:"load_balancer_uids" cty:"load_balancer_uids" hcl:"load_balancer_uids"`
+}
+
+// FlatMapstructure returns a new FlatRule.
+// FlatRule is an auto-generated flat version of Rule.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*Rule) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatRule)
+}
+
+// HCL2Spec returns the hcl spec of a Rule.
+// This spec is used by HCL to read the fields of Rule.
+// The decoded values from this spec will then be applied to a FlatRule.
+func (*FlatRule) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"protocol":           &hcldec.AttrSpec{Name: "protocol", Type: cty.String, Required: false},
+		"ports":              &hcldec.AttrSpec{Name: "ports", Type: cty.String, Required: false},
+		"addresses":          &hcldec.AttrSpec{Name: "addresses", Type: cty.List(cty.String), Required: false},
+		"droplet_ids":        &hcldec.AttrSpec{Name: "droplet_ids", Type: cty.List(cty.Number), Required: false},
+		"tags":               &hcldec.AttrSpec{Name: "tags", Type: cty.List(cty.String), Required: false},
+		"load_balancer_uids": &hcldec.AttrSpec{Name: "load_balancer_uids", Type: cty.List(cty.String), Required: false},
+	}
+	return s
+}
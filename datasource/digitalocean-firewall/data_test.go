@@ -0,0 +1,94 @@
+package digitaloceanfirewall
+
+import (
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-digitalocean/internal/simulator"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestDatasource_ImplementsDatasource(t *testing.T) {
+	var _ packersdk.Datasource = new(Datasource)
+}
+
+func TestDatasource_Configure(t *testing.T) {
+	tt := []struct {
+		Name   string
+		Config map[string]interface{}
+		Valid  bool
+	}{
+		{Name: "Valid", Config: map[string]interface{}{"api_token": "foo", "name": "web"}, Valid: true},
+		{Name: "MissingName", Config: map[string]interface{}{"api_token": "foo"}},
+		{Name: "MissingToken", Config: map[string]interface{}{"name": "web"}},
+	}
+
+	t.Setenv("DIGITALOCEAN_API_TOKEN", "")
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			var d Datasource
+			err := d.Configure(tc.Config)
+			if tc.Valid && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !tc.Valid && err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestDatasource_Execute(t *testing.T) {
+	sim := simulator.New()
+	defer sim.Close()
+	sim.AddFirewall(godo.Firewall{Name: "ssh"})
+	firewall := sim.AddFirewall(godo.Firewall{
+		Name: "web",
+		InboundRules: []godo.InboundRule{
+			{Protocol: "tcp", PortRange: "443", Sources: &godo.Sources{Addresses: []string{"0.0.0.0/0", "::/0"}}},
+		},
+		OutboundRules: []godo.OutboundRule{
+			{Protocol: "tcp", PortRange: "all", Destinations: &godo.Destinations{Tags: []string{"db"}}},
+		},
+		Tags: []string{"web"},
+	})
+	sim.AddFirewall(godo.Firewall{Name: "twice"})
+	sim.AddFirewall(godo.Firewall{Name: "twice"})
+
+	var d Datasource
+	err := d.Configure(map[string]interface{}{
+		"api_token": "foo",
+		"api_url":   sim.URL(),
+		"name":      "web",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	value, err := d.Execute()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := value.GetAttr("id").AsString(); got != firewall.ID {
+		t.Errorf("expected id %s, got %s", firewall.ID, got)
+	}
+	inbound := value.GetAttr("inbound_rules").AsValueSlice()
+	if len(inbound) != 1 || inbound[0].GetAttr("ports").AsString() != "443" {
+		t.Fatalf("unexpected inbound_rules: %#v", value.GetAttr("inbound_rules"))
+	}
+	if n := inbound[0].GetAttr("addresses").LengthInt(); n != 2 {
+		t.Errorf("expected 2 addresses, got %d", n)
+	}
+	outbound := value.GetAttr("outbound_rules").AsValueSlice()
+	if len(outbound) != 1 || outbound[0].GetAttr("tags").AsValueSlice()[0].AsString() != "db" {
+		t.Fatalf("unexpected outbound_rules: %#v", value.GetAttr("outbound_rules"))
+	}
+
+	for _, name := range []string{"missing", "twice"} {
+		d.config.Name = name
+		if _, err := d.Execute(); err == nil {
+			t.Errorf("expected an error looking up %s", name)
+		}
+	}
+}
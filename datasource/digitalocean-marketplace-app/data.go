@@ -0,0 +1,156 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,DatasourceOutput
+
+package digitaloceanmarketplaceapp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-digitalocean/builder/digitalocean"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/zclconf/go-cty/cty"
+)
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	APIToken string `mapstructure:"api_token"`
+	APIURL   string `mapstructure:"api_url"`
+
+	// A regular expression the application image name must match.
+	Name string `mapstructure:"name"`
+	// Use the most recently created image when several match.
+	MostRecent bool `mapstructure:"most_recent"`
+}
+
+type Datasource struct {
+	config Config
+}
+
+type DatasourceOutput struct {
+	ID           int      `mapstructure:"id"`
+	Slug         string   `mapstructure:"slug"`
+	Name         string   `mapstructure:"name"`
+	Distribution string   `mapstructure:"distribution"`
+	Regions      []string `mapstructure:"regions"`
+	MinDiskSize  int      `mapstructure:"min_disk_size"`
+	Created      string   `mapstructure:"created"`
+}
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	if d.config.APIToken == "" {
+		d.config.APIToken = os.Getenv("DIGITALOCEAN_API_TOKEN")
+	}
+
+	if d.config.APIURL == "" {
+		d.config.APIURL = os.Getenv("DIGITALOCEAN_API_URL")
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if d.config.APIToken == "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("api_token must be set"))
+	}
+
+	if d.config.Name == "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("name must be set"))
+	} else if _, err := regexp.Compile(d.config.Name); err != nil {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("name is not a valid regular expression: %s", err))
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	packersdk.LogSecretFilter.Set(d.config.APIToken)
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	client, err := digitalocean.NewClient(d.config.APIToken, d.config.APIURL)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), fmt.Errorf("Invalid API URL: %s", err)
+	}
+
+	image, err := findApplication(client, regexp.MustCompile(d.config.Name), d.config.MostRecent)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	output := DatasourceOutput{
+		ID:           image.ID,
+		Slug:         image.Slug,
+		Name:         image.Name,
+		Distribution: image.Distribution,
+		Regions:      image.Regions,
+		MinDiskSize:  image.MinDiskSize,
+		Created:      image.Created,
+	}
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
+
+// findApplication returns the 1-Click application image whose name matches.
+func findApplication(client *godo.Client, name *regexp.Regexp, mostRecent bool) (*godo.Image, error) {
+	opt := &godo.ListOptions{
+		Page:    1,
+		PerPage: 200,
+	}
+	var matches []godo.Image
+	for {
+		images, resp, err := client.Images.ListApplication(context.TODO(), opt)
+		if err != nil {
+			return nil, fmt.Errorf("Error listing application images: %s", err)
+		}
+		for _, image := range images {
+			if name.MatchString(image.Name) {
+				matches = append(matches, image)
+			}
+		}
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			break
+		}
+		opt.Page++
+	}
+
+	switch {
+	case len(matches) == 0:
+		return nil, fmt.Errorf("no application image matches %s", name)
+	case len(matches) > 1 && !mostRecent:
+		return nil, fmt.Errorf("%d application images match %s, set most_recent to use the latest one", len(matches), name)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return created(matches[i]).After(created(matches[j]))
+	})
+	return &matches[0], nil
+}
+
+func created(image godo.Image) time.Time {
+	t, _ := time.Parse(time.RFC3339, image.Created)
+	return t
+}
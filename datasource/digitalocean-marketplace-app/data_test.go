@@ -0,0 +1,93 @@
+package digitaloceanmarketplaceapp
+
+import (
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-digitalocean/internal/simulator"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestDatasource_ImplementsDatasource(t *testing.T) {
+	var _ packersdk.Datasource = new(Datasource)
+}
+
+func TestDatasource_Configure(t *testing.T) {
+	tt := []struct {
+		Name   string
+		Config map[string]interface{}
+		Valid  bool
+	}{
+		{Name: "Valid", Config: map[string]interface{}{"api_token": "foo", "name": "^Docker "}, Valid: true},
+		{Name: "MissingName", Config: map[string]interface{}{"api_token": "foo"}},
+		{Name: "InvalidName", Config: map[string]interface{}{"api_token": "foo", "name": "Docker ("}},
+		{Name: "MissingToken", Config: map[string]interface{}{"name": "^Docker "}},
+	}
+
+	t.Setenv("DIGITALOCEAN_API_TOKEN", "")
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			var d Datasource
+			err := d.Configure(tc.Config)
+			if tc.Valid && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !tc.Valid && err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestDatasource_Execute(t *testing.T) {
+	sim := simulator.New()
+	defer sim.Close()
+	sim.AddImage(godo.Image{
+		Name:    "Docker 19.03.12 on Ubuntu 20.04",
+		Slug:    "docker-18-04",
+		Type:    "application",
+		Public:  true,
+		Created: "2020-08-01T00:00:00Z",
+	})
+	latest := sim.AddImage(godo.Image{
+		Name:    "Docker 20.10.7 on Ubuntu 20.04",
+		Slug:    "docker-20-04",
+		Type:    "application",
+		Public:  true,
+		Regions: []string{"nyc3"},
+		Created: "2021-07-01T00:00:00Z",
+	})
+
+	var d Datasource
+	err := d.Configure(map[string]interface{}{
+		"api_token": "foo",
+		"api_url":   sim.URL(),
+		"name":      "^Docker ",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := d.Execute(); err == nil {
+		t.Fatal("expected an error when several images match")
+	}
+
+	d.config.MostRecent = true
+	value, err := d.Execute()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := value.GetAttr("slug").AsString(); got != latest.Slug {
+		t.Errorf("expected slug %s, got %s", latest.Slug, got)
+	}
+	if got, _ := value.GetAttr("id").AsBigFloat().Int64(); int(got) != latest.ID {
+		t.Errorf("expected id %d, got %d", latest.ID, got)
+	}
+
+	// The distribution images are not applications
+	d.config.Name = "^20.04"
+	if _, err := d.Execute(); err == nil {
+		t.Error("expected an error when no application image matches")
+	}
+}
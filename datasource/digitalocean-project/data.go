@@ -0,0 +1,129 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,DatasourceOutput
+
+package digitaloceanproject
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-digitalocean/builder/digitalocean"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/zclconf/go-cty/cty"
+)
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	APIToken string `mapstructure:"api_token"`
+	APIURL   string `mapstructure:"api_url"`
+
+	// The project to look up, the default project when empty.
+	Name string `mapstructure:"name"`
+}
+
+type Datasource struct {
+	config Config
+}
+
+type DatasourceOutput struct {
+	ID          string `mapstructure:"id"`
+	Name        string `mapstructure:"name"`
+	Description string `mapstructure:"description"`
+	Purpose     string `mapstructure:"purpose"`
+	Environment string `mapstructure:"environment"`
+	IsDefault   bool   `mapstructure:"is_default"`
+	// The ID of the default project, whichever project was looked up.
+	DefaultID string `mapstructure:"default_id"`
+}
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	if d.config.APIToken == "" {
+		d.config.APIToken = os.Getenv("DIGITALOCEAN_API_TOKEN")
+	}
+
+	if d.config.APIURL == "" {
+		d.config.APIURL = os.Getenv("DIGITALOCEAN_API_URL")
+	}
+
+	if d.config.APIToken == "" {
+		return fmt.Errorf("api_token must be set")
+	}
+
+	packersdk.LogSecretFilter.Set(d.config.APIToken)
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	client, err := digitalocean.NewClient(d.config.APIToken, d.config.APIURL)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), fmt.Errorf("Invalid API URL: %s", err)
+	}
+
+	def, _, err := client.Projects.GetDefault(context.TODO())
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), fmt.Errorf("Error retrieving default project: %s", err)
+	}
+
+	project := def
+	if d.config.Name != "" {
+		project, err = findProject(client, d.config.Name)
+		if err != nil {
+			return cty.NullVal(cty.EmptyObject), err
+		}
+	}
+
+	output := DatasourceOutput{
+		ID:          project.ID,
+		Name:        project.Name,
+		Description: project.Description,
+		Purpose:     project.Purpose,
+		Environment: project.Environment,
+		IsDefault:   project.IsDefault,
+		DefaultID:   def.ID,
+	}
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
+
+// findProject returns the project with the given name.
+func findProject(client *godo.Client, name string) (*godo.Project, error) {
+	opt := &godo.ListOptions{
+		Page:    1,
+		PerPage: 200,
+	}
+	for {
+		projects, resp, err := client.Projects.List(context.TODO(), opt)
+		if err != nil {
+			return nil, fmt.Errorf("Error listing projects: %s", err)
+		}
+		// Project names are unique within an account
+		for _, project := range projects {
+			if project.Name == name {
+				return &project, nil
+			}
+		}
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			break
+		}
+		opt.Page++
+	}
+	return nil, fmt.Errorf("no project is named %s", name)
+}
@@ -0,0 +1,77 @@
+package digitaloceanproject
+
+import (
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-digitalocean/internal/simulator"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestDatasource_ImplementsDatasource(t *testing.T) {
+	var _ packersdk.Datasource = new(Datasource)
+}
+
+func TestDatasource_Configure(t *testing.T) {
+	t.Setenv("DIGITALOCEAN_API_TOKEN", "")
+
+	var d Datasource
+	if err := d.Configure(map[string]interface{}{"name": "images"}); err == nil {
+		t.Fatal("expected an error without api_token")
+	}
+
+	d = Datasource{}
+	if err := d.Configure(map[string]interface{}{"api_token": "foo"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestDatasource_Execute(t *testing.T) {
+	sim := simulator.New()
+	defer sim.Close()
+	project := sim.AddProject(godo.Project{Name: "images", Environment: "Production"})
+
+	var d Datasource
+	err := d.Configure(map[string]interface{}{
+		"api_token": "foo",
+		"api_url":   sim.URL(),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	value, err := d.Execute()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := value.GetAttr("name").AsString(); got != "first-project" {
+		t.Errorf("expected the default project, got %s", got)
+	}
+	if !value.GetAttr("is_default").True() {
+		t.Error("expected is_default")
+	}
+	defaultID := value.GetAttr("default_id").AsString()
+	if got := value.GetAttr("id").AsString(); got != defaultID {
+		t.Errorf("expected id %s, got %s", defaultID, got)
+	}
+
+	d.config.Name = "images"
+	value, err = d.Execute()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := value.GetAttr("id").AsString(); got != project.ID {
+		t.Errorf("expected id %s, got %s", project.ID, got)
+	}
+	if got := value.GetAttr("environment").AsString(); got != "Production" {
+		t.Errorf("expected environment Production, got %s", got)
+	}
+	if got := value.GetAttr("default_id").AsString(); got != defaultID {
+		t.Errorf("expected default_id %s, got %s", defaultID, got)
+	}
+
+	d.config.Name = "missing"
+	if _, err := d.Execute(); err == nil {
+		t.Error("expected an error looking up a missing project")
+	}
+}
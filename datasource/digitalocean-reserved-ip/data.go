@@ -0,0 +1,188 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,DatasourceOutput
+
+package digitaloceanreservedip
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-digitalocean/builder/digitalocean"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/zclconf/go-cty/cty"
+)
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	APIToken string `mapstructure:"api_token"`
+	APIURL   string `mapstructure:"api_url"`
+
+	IP     string `mapstructure:"ip"`
+	Tag    string `mapstructure:"tag"`
+	Region string `mapstructure:"region"`
+}
+
+type Datasource struct {
+	config Config
+}
+
+type DatasourceOutput struct {
+	IP          string `mapstructure:"ip"`
+	Region      string `mapstructure:"region"`
+	Assigned    bool   `mapstructure:"assigned"`
+	DropletID   int    `mapstructure:"droplet_id"`
+	DropletName string `mapstructure:"droplet_name"`
+}
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	if d.config.APIToken == "" {
+		d.config.APIToken = os.Getenv("DIGITALOCEAN_API_TOKEN")
+	}
+
+	if d.config.APIURL == "" {
+		d.config.APIURL = os.Getenv("DIGITALOCEAN_API_URL")
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if d.config.APIToken == "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("api_token must be set"))
+	}
+
+	if d.config.IP == "" && d.config.Tag == "" && d.config.Region == "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("ip, tag or region must be set"))
+	}
+
+	if d.config.IP != "" && d.config.Tag != "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("ip and tag can't both be set"))
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	packersdk.LogSecretFilter.Set(d.config.APIToken)
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	client, err := digitalocean.NewClient(d.config.APIToken, d.config.APIURL)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), fmt.Errorf("Invalid API URL: %s", err)
+	}
+
+	ip, err := findReservedIP(client, d.config.IP, d.config.Tag, d.config.Region)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	output := DatasourceOutput{
+		IP: ip.IP,
+	}
+	if ip.Region != nil {
+		output.Region = ip.Region.Slug
+	}
+	if ip.Droplet != nil {
+		output.Assigned, output.DropletID, output.DropletName = true, ip.Droplet.ID, ip.Droplet.Name
+	}
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
+
+// findReservedIP returns the reserved IP with the given address, or the one
+// assigned to the droplet with the given tag, as reserved IPs can't be
+// tagged themselves. With neither, it returns the first reserved IP of the
+// region that isn't assigned, so that templates don't take one in use.
+// region may be empty when address or tag isn't.
+func findReservedIP(client *godo.Client, address, tag, region string) (*godo.FloatingIP, error) {
+	if address != "" {
+		ip, resp, err := client.FloatingIPs.Get(context.TODO(), address)
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("no reserved IP is %s", address)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Error retrieving reserved IP: %s", err)
+		}
+		if region != "" && (ip.Region == nil || ip.Region.Slug != region) {
+			return nil, fmt.Errorf("reserved IP %s isn't in %s", address, region)
+		}
+		return ip, nil
+	}
+
+	opt := &godo.ListOptions{
+		Page:    1,
+		PerPage: 200,
+	}
+	var matches []godo.FloatingIP
+	for {
+		ips, resp, err := client.FloatingIPs.List(context.TODO(), opt)
+		if err != nil {
+			return nil, fmt.Errorf("Error listing reserved IPs: %s", err)
+		}
+		for _, ip := range ips {
+			if region != "" && (ip.Region == nil || ip.Region.Slug != region) {
+				continue
+			}
+			if tag == "" && ip.Droplet == nil {
+				matches = append(matches, ip)
+			}
+			if tag != "" && ip.Droplet != nil && hasTag(ip.Droplet.Tags, tag) {
+				matches = append(matches, ip)
+			}
+		}
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			break
+		}
+		opt.Page++
+	}
+
+	if tag == "" {
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no reserved IP of %s is unassigned", region)
+		}
+		return &matches[0], nil
+	}
+
+	what := "assigned to a droplet tagged " + tag
+	if region != "" {
+		what += " in " + region
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no reserved IP is %s", what)
+	case 1:
+		return &matches[0], nil
+	}
+	return nil, fmt.Errorf("%d reserved IPs are %s", len(matches), what)
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
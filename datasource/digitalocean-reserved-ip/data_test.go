@@ -0,0 +1,107 @@
+package digitaloceanreservedip
+
+import (
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-digitalocean/internal/simulator"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestDatasource_ImplementsDatasource(t *testing.T) {
+	var _ packersdk.Datasource = new(Datasource)
+}
+
+func TestDatasource_Configure(t *testing.T) {
+	tt := []struct {
+		Name   string
+		Config map[string]interface{}
+		Valid  bool
+	}{
+		{Name: "IP", Config: map[string]interface{}{"api_token": "foo", "ip": "198.51.100.2"}, Valid: true},
+		{Name: "Tag", Config: map[string]interface{}{"api_token": "foo", "tag": "web"}, Valid: true},
+		{Name: "Region", Config: map[string]interface{}{"api_token": "foo", "region": "nyc3"}, Valid: true},
+		{Name: "IPAndTag", Config: map[string]interface{}{"api_token": "foo", "ip": "198.51.100.2", "tag": "web"}},
+		{Name: "MissingLookup", Config: map[string]interface{}{"api_token": "foo"}},
+		{Name: "MissingToken", Config: map[string]interface{}{"ip": "198.51.100.2"}},
+	}
+
+	t.Setenv("DIGITALOCEAN_API_TOKEN", "")
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			var d Datasource
+			err := d.Configure(tc.Config)
+			if tc.Valid && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !tc.Valid && err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestDatasource_Execute(t *testing.T) {
+	sim := simulator.New()
+	defer sim.Close()
+	web := sim.AddDroplet(godo.Droplet{Name: "web-1", Tags: []string{"web"}})
+	sim.AddDroplet(godo.Droplet{Name: "web-2", Tags: []string{"web", "blue"}})
+	assigned := sim.AddFloatingIP(godo.FloatingIP{IP: "198.51.100.2", Region: &godo.Region{Slug: "nyc3"}, Droplet: &web})
+	free := sim.AddFloatingIP(godo.FloatingIP{IP: "198.51.100.3", Region: &godo.Region{Slug: "nyc3"}})
+	sim.AddFloatingIP(godo.FloatingIP{IP: "198.51.100.4", Region: &godo.Region{Slug: "ams3"}})
+
+	tt := []struct {
+		IP, Tag, Region string
+		Expected        string
+		Assigned        bool
+	}{
+		{IP: assigned.IP, Expected: assigned.IP, Assigned: true},
+		{IP: free.IP, Region: "nyc3", Expected: free.IP},
+		{Tag: "web", Expected: assigned.IP, Assigned: true},
+		{Tag: "web", Region: "nyc3", Expected: assigned.IP, Assigned: true},
+		{Region: "nyc3", Expected: free.IP},
+		{IP: "198.51.100.9"},
+		{IP: free.IP, Region: "ams3"},
+		{Tag: "blue"},
+		{Region: "sfo3"},
+	}
+	for _, tc := range tt {
+		var d Datasource
+		err := d.Configure(map[string]interface{}{
+			"api_token": "foo",
+			"api_url":   sim.URL(),
+			"ip":        tc.IP,
+			"tag":       tc.Tag,
+			"region":    tc.Region,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		value, err := d.Execute()
+		if tc.Expected == "" {
+			if err == nil {
+				t.Errorf("expected an error looking up ip %q, tag %q and region %q", tc.IP, tc.Tag, tc.Region)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if got := value.GetAttr("ip").AsString(); got != tc.Expected {
+			t.Errorf("expected ip %s, got %s", tc.Expected, got)
+		}
+		if got := value.GetAttr("assigned").True(); got != tc.Assigned {
+			t.Errorf("%s: expected assigned %t, got %t", tc.Expected, tc.Assigned, got)
+		}
+		if got := value.GetAttr("region").AsString(); got != "nyc3" {
+			t.Errorf("expected region nyc3, got %s", got)
+		}
+		if tc.Assigned {
+			if got, _ := value.GetAttr("droplet_id").AsBigFloat().Int64(); int(got) != web.ID {
+				t.Errorf("expected droplet_id %d, got %d", web.ID, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,172 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,DatasourceOutput
+
+package digitaloceanvolumesnapshot
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-digitalocean/builder/digitalocean"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/zclconf/go-cty/cty"
+)
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	APIToken string `mapstructure:"api_token"`
+	APIURL   string `mapstructure:"api_url"`
+
+	NamePrefix string `mapstructure:"name_prefix"`
+	Tag        string `mapstructure:"tag"`
+	Region     string `mapstructure:"region"`
+}
+
+type Datasource struct {
+	config Config
+}
+
+type DatasourceOutput struct {
+	ID            string   `mapstructure:"id"`
+	Name          string   `mapstructure:"name"`
+	VolumeID      string   `mapstructure:"volume_id"`
+	SizeGigaBytes float64  `mapstructure:"size_gigabytes"`
+	MinDiskSize   int      `mapstructure:"min_disk_size"`
+	Regions       []string `mapstructure:"regions"`
+	Tags          []string `mapstructure:"tags"`
+	CreatedAt     string   `mapstructure:"created_at"`
+}
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	if d.config.APIToken == "" {
+		d.config.APIToken = os.Getenv("DIGITALOCEAN_API_TOKEN")
+	}
+
+	if d.config.APIURL == "" {
+		d.config.APIURL = os.Getenv("DIGITALOCEAN_API_URL")
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if d.config.APIToken == "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("api_token must be set"))
+	}
+
+	if d.config.NamePrefix == "" && d.config.Tag == "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("name_prefix or tag must be set"))
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	packersdk.LogSecretFilter.Set(d.config.APIToken)
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	client, err := digitalocean.NewClient(d.config.APIToken, d.config.APIURL)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), fmt.Errorf("Invalid API URL: %s", err)
+	}
+
+	snapshot, err := latestVolumeSnapshot(client, d.config.NamePrefix, d.config.Tag, d.config.Region)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	output := DatasourceOutput{
+		ID:            snapshot.ID,
+		Name:          snapshot.Name,
+		VolumeID:      snapshot.ResourceID,
+		SizeGigaBytes: snapshot.SizeGigaBytes,
+		MinDiskSize:   snapshot.MinDiskSize,
+		Regions:       snapshot.Regions,
+		Tags:          snapshot.Tags,
+		CreatedAt:     snapshot.Created,
+	}
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
+
+// latestVolumeSnapshot returns the most recently created volume snapshot
+// whose name starts with prefix, with the tag, and available in the region,
+// any of which may be empty.
+func latestVolumeSnapshot(client *godo.Client, prefix, tag, region string) (*godo.Snapshot, error) {
+	opt := &godo.ListOptions{
+		Page:    1,
+		PerPage: 200,
+	}
+	var latest *godo.Snapshot
+	var latestCreated time.Time
+	for {
+		snapshots, resp, err := client.Snapshots.ListVolume(context.TODO(), opt)
+		if err != nil {
+			return nil, fmt.Errorf("Error listing volume snapshots: %s", err)
+		}
+		for i, snapshot := range snapshots {
+			if !strings.HasPrefix(snapshot.Name, prefix) ||
+				(tag != "" && !contains(snapshot.Tags, tag)) ||
+				(region != "" && !contains(snapshot.Regions, region)) {
+				continue
+			}
+			created, err := time.Parse(time.RFC3339, snapshot.Created)
+			if err != nil {
+				return nil, fmt.Errorf("Error parsing the creation time of volume snapshot %s: %s", snapshot.ID, err)
+			}
+			if latest == nil || created.After(latestCreated) {
+				latest, latestCreated = &snapshots[i], created
+			}
+		}
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			break
+		}
+		opt.Page++
+	}
+
+	if latest == nil {
+		var what []string
+		if prefix != "" {
+			what = append(what, "named "+prefix+"*")
+		}
+		if tag != "" {
+			what = append(what, "tagged "+tag)
+		}
+		if region != "" {
+			what = append(what, "in "+region)
+		}
+		return nil, fmt.Errorf("no volume snapshot is %s", strings.Join(what, ", "))
+	}
+	return latest, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package digitaloceanvolumesnapshot
+
+import (
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-digitalocean/internal/simulator"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestDatasource_ImplementsDatasource(t *testing.T) {
+	var _ packersdk.Datasource = new(Datasource)
+}
+
+func TestDatasource_Configure(t *testing.T) {
+	tt := []struct {
+		Name   string
+		Config map[string]interface{}
+		Valid  bool
+	}{
+		{Name: "NamePrefix", Config: map[string]interface{}{"api_token": "foo", "name_prefix": "data-"}, Valid: true},
+		{Name: "Tag", Config: map[string]interface{}{"api_token": "foo", "tag": "seed"}, Valid: true},
+		{Name: "RegionOnly", Config: map[string]interface{}{"api_token": "foo", "region": "nyc3"}},
+		{Name: "MissingToken", Config: map[string]interface{}{"name_prefix": "data-"}},
+	}
+
+	t.Setenv("DIGITALOCEAN_API_TOKEN", "")
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			var d Datasource
+			err := d.Configure(tc.Config)
+			if tc.Valid && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !tc.Valid && err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestDatasource_Execute(t *testing.T) {
+	sim := simulator.New()
+	defer sim.Close()
+	sim.AddVolumeSnapshot(godo.Snapshot{Name: "data-v1", Regions: []string{"nyc3"}, Tags: []string{"seed"},
+		Created: "2021-07-01T10:00:00Z"})
+	latest := sim.AddVolumeSnapshot(godo.Snapshot{Name: "data-v3", ResourceID: "506f78a4-e098-11e5-ad9f-000000001001",
+		Regions: []string{"nyc3"}, SizeGigaBytes: 2.5, MinDiskSize: 10, Tags: []string{"seed"},
+		Created: "2021-07-03T10:00:00Z"})
+	sim.AddVolumeSnapshot(godo.Snapshot{Name: "data-v2", Regions: []string{"ams3"}, Tags: []string{"seed"},
+		Created: "2021-07-02T10:00:00Z"})
+	sim.AddVolumeSnapshot(godo.Snapshot{Name: "logs-v9", Regions: []string{"nyc3"}, Created: "2021-07-09T10:00:00Z"})
+
+	tt := []struct {
+		NamePrefix, Tag, Region string
+		Expected                string
+	}{
+		{NamePrefix: "data-", Expected: "data-v3"},
+		{Tag: "seed", Expected: "data-v3"},
+		{NamePrefix: "data-", Region: "ams3", Expected: "data-v2"},
+		{NamePrefix: "logs-", Tag: "seed"},
+		{NamePrefix: "data-", Region: "sfo3"},
+	}
+	for _, tc := range tt {
+		var d Datasource
+		err := d.Configure(map[string]interface{}{
+			"api_token":   "foo",
+			"api_url":     sim.URL(),
+			"name_prefix": tc.NamePrefix,
+			"tag":         tc.Tag,
+			"region":      tc.Region,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		value, err := d.Execute()
+		if tc.Expected == "" {
+			if err == nil {
+				t.Errorf("expected an error looking up name prefix %q, tag %q and region %q", tc.NamePrefix, tc.Tag, tc.Region)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if got := value.GetAttr("name").AsString(); got != tc.Expected {
+			t.Errorf("expected name %s, got %s", tc.Expected, got)
+		}
+	}
+
+	var d Datasource
+	if err := d.Configure(map[string]interface{}{"api_token": "foo", "api_url": sim.URL(), "name_prefix": "data-"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	value, err := d.Execute()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := value.GetAttr("id").AsString(); got != latest.ID {
+		t.Errorf("expected id %s, got %s", latest.ID, got)
+	}
+	if got := value.GetAttr("volume_id").AsString(); got != latest.ResourceID {
+		t.Errorf("expected volume_id %s, got %s", latest.ResourceID, got)
+	}
+	if got, _ := value.GetAttr("size_gigabytes").AsBigFloat().Float64(); got != 2.5 {
+		t.Errorf("expected size_gigabytes 2.5, got %v", got)
+	}
+	if got, _ := value.GetAttr("min_disk_size").AsBigFloat().Int64(); got != 10 {
+		t.Errorf("expected min_disk_size 10, got %d", got)
+	}
+}
@@ -0,0 +1,405 @@
+package simulator
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/digitalocean/godo"
+)
+
+func (s *Server) handleDroplets(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			s.listDroplets(w, r)
+		case http.MethodPost:
+			s.createDroplet(w, r)
+		default:
+			notFound(w)
+		}
+		return
+	}
+
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		notFound(w)
+		return
+	}
+	d, ok := s.droplets[id]
+	if !ok {
+		notFound(w)
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		// Pending actions progress on their own, not only when polled
+		for _, a := range s.actions {
+			if a.ResourceType == "droplet" && a.ResourceID == d.ID {
+				s.advance(a)
+			}
+		}
+		if d.polls > 0 {
+			d.polls--
+		} else if d.Status == "new" {
+			d.Status = "active"
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"droplet": d.Droplet})
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		if d.Locked {
+			writeError(w, http.StatusUnprocessableEntity, "unprocessable_entity",
+				"Droplet already has a pending event.")
+			return
+		}
+		delete(s.droplets, id)
+		w.WriteHeader(http.StatusNoContent)
+	case len(parts) == 2 && parts[1] == "snapshots" && r.Method == http.MethodGet:
+		var snapshots []godo.Image
+		for _, imageID := range d.SnapshotIDs {
+			if i, ok := s.images[imageID]; ok {
+				snapshots = append(snapshots, *i)
+			}
+		}
+		page, links := paginate(r, len(snapshots))
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"snapshots": snapshots[page.start:page.end],
+			"links":     links,
+			"meta":      godo.Meta{Total: len(snapshots)},
+		})
+	case len(parts) == 2 && parts[1] == "actions" && r.Method == http.MethodGet:
+		// Most recent first, like the real API
+		var actions []godo.Action
+		for id := s.nextID; id > 0; id-- {
+			if a, ok := s.actions[id]; ok && a.ResourceType == "droplet" && a.ResourceID == d.ID {
+				s.advance(a)
+				actions = append(actions, a.Action)
+			}
+		}
+		page, links := paginate(r, len(actions))
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"actions": actions[page.start:page.end],
+			"links":   links,
+			"meta":    godo.Meta{Total: len(actions)},
+		})
+	case len(parts) == 2 && parts[1] == "actions" && r.Method == http.MethodPost:
+		s.createDropletAction(w, r, d)
+	case len(parts) == 3 && parts[1] == "actions" && r.Method == http.MethodGet:
+		s.handleAction(w, parts[2])
+	default:
+		notFound(w)
+	}
+}
+
+func (s *Server) listDroplets(w http.ResponseWriter, r *http.Request) {
+	tag := r.URL.Query().Get("tag_name")
+	name := r.URL.Query().Get("name")
+
+	var droplets []godo.Droplet
+	for _, id := range dropletIDs(s.droplets) {
+		d := s.droplets[id]
+		if tag != "" && !contains(d.Tags, tag) {
+			continue
+		}
+		if name != "" && d.Name != name {
+			continue
+		}
+		droplets = append(droplets, d.Droplet)
+	}
+
+	page, links := paginate(r, len(droplets))
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"droplets": droplets[page.start:page.end],
+		"links":    links,
+		"meta":     godo.Meta{Total: len(droplets)},
+	})
+}
+
+func (s *Server) createDroplet(w http.ResponseWriter, r *http.Request) {
+	// Images and SSH keys are sent either as IDs or as slugs/fingerprints,
+	// which godo's request types can't decode on their own.
+	var req struct {
+		godo.DropletCreateRequest
+		Image   json.RawMessage   `json:"image"`
+		SSHKeys []json.RawMessage `json:"ssh_keys"`
+	}
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
+	region := s.region(req.Region)
+	if region == nil {
+		writeError(w, http.StatusUnprocessableEntity, "unprocessable_entity", "You specified an invalid region for Droplet creation.")
+		return
+	}
+	size := s.size(req.Size)
+	if size == nil {
+		writeError(w, http.StatusUnprocessableEntity, "unprocessable_entity", "You specified an invalid size for Droplet creation.")
+		return
+	}
+	var imageRef godo.DropletCreateImage
+	if err := json.Unmarshal(req.Image, &imageRef.ID); err != nil {
+		_ = json.Unmarshal(req.Image, &imageRef.Slug)
+	}
+	image := s.image(imageRef)
+	if image == nil {
+		writeError(w, http.StatusUnprocessableEntity, "unprocessable_entity", "You specified an invalid image for Droplet creation.")
+		return
+	}
+	for _, raw := range req.SSHKeys {
+		if !s.knownKey(raw) {
+			writeError(w, http.StatusUnprocessableEntity, "unprocessable_entity", "ssh_keys are invalid.")
+			return
+		}
+	}
+	var volumes []*godo.Volume
+	for _, ref := range req.Volumes {
+		v, ok := s.volumes[ref.ID]
+		if !ok || v.Region.Slug != region.Slug {
+			writeError(w, http.StatusUnprocessableEntity, "unprocessable_entity",
+				"You specified an invalid volume for Droplet creation.")
+			return
+		}
+		volumes = append(volumes, v)
+	}
+
+	d := &droplet{
+		Droplet: godo.Droplet{
+			ID:       s.id(),
+			Name:     req.Name,
+			Memory:   size.Memory,
+			Vcpus:    size.Vcpus,
+			Disk:     size.Disk,
+			Region:   region,
+			Image:    image,
+			Size:     size,
+			SizeSlug: size.Slug,
+			Status:   "new",
+			Tags:     req.Tags,
+			VPCUUID:  req.VPCUUID,
+			Created:  time.Now().UTC().Format(time.RFC3339),
+		},
+		polls: s.DropletPolls,
+	}
+	if d.Tags == nil {
+		d.Tags = []string{}
+	}
+	for _, t := range d.Tags {
+		s.tags[t] = struct{}{}
+	}
+	if req.Monitoring {
+		d.Features = append(d.Features, "monitoring")
+	}
+	d.Networks = &godo.Networks{
+		V4: []godo.NetworkV4{
+			{IPAddress: "203.0.113." + strconv.Itoa(d.ID%250+1), Netmask: "255.255.240.0", Type: "public"},
+		},
+	}
+	if req.PrivateNetworking || req.VPCUUID != "" {
+		d.Networks.V4 = append(d.Networks.V4, godo.NetworkV4{
+			IPAddress: "10.10.0." + strconv.Itoa(d.ID%250+1), Netmask: "255.255.0.0", Type: "private",
+		})
+	}
+	d.VolumeIDs = []string{}
+	for _, v := range volumes {
+		d.VolumeIDs = append(d.VolumeIDs, v.ID)
+		v.DropletIDs = append(v.DropletIDs, d.ID)
+	}
+	s.droplets[d.ID] = d
+
+	a := s.newAction("create", d.ID, "droplet", region.Slug)
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"droplet": d.Droplet,
+		"links": godo.Links{
+			Actions: []godo.LinkAction{{ID: a.ID, Rel: "create"}},
+		},
+	})
+}
+
+func (s *Server) createDropletAction(w http.ResponseWriter, r *http.Request, d *droplet) {
+	req := make(map[string]interface{})
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+	actionType, _ := req["type"].(string)
+
+	var complete func()
+	switch actionType {
+	case "shutdown", "power_off":
+		complete = func() { d.Status = "off" }
+	case "power_on", "reboot", "power_cycle":
+		complete = func() { d.Status = "active" }
+	case "snapshot":
+		name, _ := req["name"].(string)
+		complete = func() {
+			i := &godo.Image{
+				ID:            s.id(),
+				Name:          name,
+				Type:          "snapshot",
+				Regions:       []string{d.Region.Slug},
+				MinDiskSize:   d.Disk,
+				SizeGigaBytes: 2.36,
+				Created:       time.Now().UTC().Format(time.RFC3339),
+				Status:        "available",
+			}
+			if d.Image != nil {
+				i.Distribution = d.Image.Distribution
+			}
+			s.images[i.ID] = i
+			d.SnapshotIDs = append(d.SnapshotIDs, i.ID)
+		}
+	default:
+		writeError(w, http.StatusUnprocessableEntity, "unprocessable_entity", "Unknown action type: "+actionType)
+		return
+	}
+
+	if d.Locked {
+		writeError(w, http.StatusUnprocessableEntity, "unprocessable_entity",
+			"Droplet already has a pending event.")
+		return
+	}
+
+	a := s.newAction(actionType, d.ID, "droplet", d.Region.Slug)
+	d.Locked = true
+	a.complete = func() {
+		d.Locked = false
+		complete()
+	}
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"action": a.Action})
+}
+
+// newAction registers an in-progress action. The caller is responsible for
+// setting its completion side effects.
+func (s *Server) newAction(actionType string, resourceID int, resourceType, region string) *action {
+	a := &action{
+		Action: godo.Action{
+			ID:           s.id(),
+			Status:       godo.ActionInProgress,
+			Type:         actionType,
+			StartedAt:    &godo.Timestamp{Time: time.Now().UTC()},
+			ResourceID:   resourceID,
+			ResourceType: resourceType,
+			RegionSlug:   region,
+		},
+		polls:   s.ActionPolls,
+		stuck:   contains(s.stuckTypes, actionType),
+		errored: contains(s.erroredTypes, actionType),
+	}
+	s.actions[a.ID] = a
+	return a
+}
+
+func (s *Server) handleAction(w http.ResponseWriter, rawID string) {
+	id, err := strconv.Atoi(rawID)
+	if err != nil {
+		notFound(w)
+		return
+	}
+	a, ok := s.actions[id]
+	if !ok {
+		notFound(w)
+		return
+	}
+
+	s.advance(a)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"action": a.Action})
+}
+
+// advance moves an in-progress action one poll closer to completion.
+func (s *Server) advance(a *action) {
+	if a.Status != godo.ActionInProgress || a.stuck {
+		return
+	}
+	if a.polls > 0 {
+		a.polls--
+	}
+	if a.polls > 0 {
+		return
+	}
+
+	a.CompletedAt = &godo.Timestamp{Time: time.Now().UTC()}
+	if a.errored {
+		a.Status = "errored"
+		if d, ok := s.droplets[a.ResourceID]; ok && a.ResourceType == "droplet" {
+			d.Locked = false
+		}
+	} else {
+		a.Status = godo.ActionCompleted
+		if a.complete != nil {
+			a.complete()
+		}
+	}
+}
+
+func (s *Server) region(slug string) *godo.Region {
+	for i := range s.regions {
+		if s.regions[i].Slug == slug {
+			r := s.regions[i]
+			return &r
+		}
+	}
+	return nil
+}
+
+func (s *Server) size(slug string) *godo.Size {
+	for i := range s.sizes {
+		if s.sizes[i].Slug == slug {
+			sz := s.sizes[i]
+			return &sz
+		}
+	}
+	return nil
+}
+
+func (s *Server) image(ref godo.DropletCreateImage) *godo.Image {
+	if ref.ID != 0 {
+		if i, ok := s.images[ref.ID]; ok {
+			image := *i
+			return &image
+		}
+		return nil
+	}
+	for _, id := range imageIDs(s.images) {
+		if i := s.images[id]; i.Slug != "" && i.Slug == ref.Slug {
+			image := *i
+			return &image
+		}
+	}
+	return nil
+}
+
+func (s *Server) knownKey(raw json.RawMessage) bool {
+	var id int
+	var fingerprint string
+	if err := json.Unmarshal(raw, &id); err != nil {
+		_ = json.Unmarshal(raw, &fingerprint)
+	}
+	for _, k := range s.keys {
+		if (id != 0 && k.ID == id) || (fingerprint != "" && k.Fingerprint == fingerprint) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func dropletIDs(m map[int]*droplet) []int {
+	ids := make([]int, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
@@ -0,0 +1,57 @@
+package simulator
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/digitalocean/godo"
+)
+
+func (s *Server) handleFirewalls(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) == 0 {
+		if r.Method != http.MethodGet {
+			notFound(w)
+			return
+		}
+		var firewalls []*godo.Firewall
+		for _, id := range firewallIDs(s.firewalls) {
+			firewalls = append(firewalls, s.firewalls[id])
+		}
+		page, links := paginate(r, len(firewalls))
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"firewalls": firewalls[page.start:page.end],
+			"links":     links,
+			"meta":      godo.Meta{Total: len(firewalls)},
+		})
+		return
+	}
+
+	f, ok := s.firewalls[parts[0]]
+	if !ok || len(parts) != 1 || r.Method != http.MethodGet {
+		notFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"firewall": f})
+}
+
+// AddFirewall seeds a Cloud Firewall and returns it with its ID assigned.
+func (s *Server) AddFirewall(f godo.Firewall) godo.Firewall {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f.ID = fmt.Sprintf("bb4b2611-3d72-467b-8602-%012d", s.id())
+	if f.Status == "" {
+		f.Status = "succeeded"
+	}
+	s.firewalls[f.ID] = &f
+	return f
+}
+
+func firewallIDs(m map[string]*godo.Firewall) []string {
+	ids := make([]string, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
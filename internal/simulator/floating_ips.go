@@ -0,0 +1,124 @@
+package simulator
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/digitalocean/godo"
+)
+
+// floatingIP is a reserved IP, which godo still calls a floating IP.
+type floatingIP struct {
+	ip        string
+	region    string
+	dropletID int
+}
+
+func (s *Server) handleFloatingIPs(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) == 0 {
+		if r.Method != http.MethodGet {
+			notFound(w)
+			return
+		}
+		ips := make([]string, 0, len(s.floating))
+		for ip := range s.floating {
+			ips = append(ips, ip)
+		}
+		sort.Strings(ips)
+		floatingIPs := make([]godo.FloatingIP, 0, len(ips))
+		for _, ip := range ips {
+			floatingIPs = append(floatingIPs, s.floatingIP(s.floating[ip]))
+		}
+		page, links := paginate(r, len(floatingIPs))
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"floating_ips": floatingIPs[page.start:page.end],
+			"links":        links,
+			"meta":         godo.Meta{Total: len(floatingIPs)},
+		})
+		return
+	}
+
+	f, ok := s.floating[parts[0]]
+	switch {
+	case !ok:
+		notFound(w)
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"floating_ip": s.floatingIP(f)})
+	case len(parts) == 2 && parts[1] == "actions" && r.Method == http.MethodPost:
+		s.createFloatingIPAction(w, r, f)
+	case len(parts) == 3 && parts[1] == "actions" && r.Method == http.MethodGet:
+		s.handleAction(w, parts[2])
+	default:
+		notFound(w)
+	}
+}
+
+// createFloatingIPAction assigns the reserved IP to a droplet in its region,
+// or unassigns it, once the action completes.
+func (s *Server) createFloatingIPAction(w http.ResponseWriter, r *http.Request, f *floatingIP) {
+	req := make(map[string]interface{})
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+	actionType, _ := req["type"].(string)
+	switch actionType {
+	case "assign":
+		id, _ := req["droplet_id"].(float64)
+		d, ok := s.droplets[int(id)]
+		if !ok {
+			writeError(w, http.StatusUnprocessableEntity, "unprocessable_entity", "Droplet not found.")
+			return
+		}
+		if d.Region == nil || d.Region.Slug != f.region {
+			writeError(w, http.StatusUnprocessableEntity, "unprocessable_entity",
+				"The droplet must be in the same region as the reserved IP.")
+			return
+		}
+		a := s.newAction(actionType, int(id), "floating_ip", f.region)
+		a.complete = func() { f.dropletID = int(id) }
+		writeJSON(w, http.StatusCreated, map[string]interface{}{"action": a.Action})
+	case "unassign":
+		a := s.newAction(actionType, f.dropletID, "floating_ip", f.region)
+		a.complete = func() { f.dropletID = 0 }
+		writeJSON(w, http.StatusCreated, map[string]interface{}{"action": a.Action})
+	default:
+		writeError(w, http.StatusUnprocessableEntity, "unprocessable_entity", "Unknown action type: "+actionType)
+	}
+}
+
+// floatingIP returns the reserved IP as the API does, with the droplet it is
+// assigned to, if it still exists.
+func (s *Server) floatingIP(f *floatingIP) godo.FloatingIP {
+	ip := godo.FloatingIP{IP: f.ip, Region: &godo.Region{Slug: f.region}}
+	for i := range s.regions {
+		if s.regions[i].Slug == f.region {
+			region := s.regions[i]
+			ip.Region = &region
+		}
+	}
+	if d, ok := s.droplets[f.dropletID]; ok {
+		ip.Droplet = &d.Droplet
+	}
+	return ip
+}
+
+// AddFloatingIP seeds a reserved IP, assigned to its droplet when it has one,
+// and returns it with its address assigned unless it had one.
+func (s *Server) AddFloatingIP(f godo.FloatingIP) godo.FloatingIP {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := &floatingIP{ip: f.IP}
+	if stored.ip == "" {
+		stored.ip = "198.51.100." + strconv.Itoa(s.id()%250+1)
+	}
+	if f.Region != nil {
+		stored.region = f.Region.Slug
+	}
+	if f.Droplet != nil {
+		stored.dropletID = f.Droplet.ID
+	}
+	s.floating[stored.ip] = stored
+	return s.floatingIP(stored)
+}
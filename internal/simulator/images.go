@@ -0,0 +1,136 @@
+package simulator
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/digitalocean/godo"
+)
+
+func (s *Server) handleImages(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) == 0 {
+		if r.Method != http.MethodGet {
+			notFound(w)
+			return
+		}
+		s.listImages(w, r)
+		return
+	}
+
+	var i *godo.Image
+	if id, err := strconv.Atoi(parts[0]); err == nil {
+		i = s.images[id]
+	} else {
+		for _, id := range imageIDs(s.images) {
+			if s.images[id].Slug == parts[0] {
+				i = s.images[id]
+				break
+			}
+		}
+	}
+	if i == nil {
+		notFound(w)
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"image": i})
+	case len(parts) == 1 && r.Method == http.MethodPut:
+		var req godo.ImageUpdateRequest
+		if err := decodeBody(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+			return
+		}
+		if req.Name != "" {
+			i.Name = req.Name
+		}
+		if req.Description != "" {
+			i.Description = req.Description
+		}
+		if req.Distribution != "" {
+			i.Distribution = req.Distribution
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"image": i})
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		delete(s.images, i.ID)
+		w.WriteHeader(http.StatusNoContent)
+	case len(parts) == 2 && parts[1] == "actions" && r.Method == http.MethodPost:
+		s.createImageAction(w, r, i)
+	case len(parts) == 3 && parts[1] == "actions" && r.Method == http.MethodGet:
+		s.handleAction(w, parts[2])
+	default:
+		notFound(w)
+	}
+}
+
+func (s *Server) listImages(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	imageType := q.Get("type")
+	private := q.Get("private") == "true"
+	tag := q.Get("tag_name")
+
+	var images []godo.Image
+	for _, id := range imageIDs(s.images) {
+		i := s.images[id]
+		if private && i.Public {
+			continue
+		}
+		if imageType == "distribution" && !(i.Public && i.Type != "application") {
+			continue
+		}
+		if imageType == "application" && i.Type != "application" {
+			continue
+		}
+		if tag != "" && !contains(i.Tags, tag) {
+			continue
+		}
+		images = append(images, *i)
+	}
+
+	page, links := paginate(r, len(images))
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"images": images[page.start:page.end],
+		"links":  links,
+		"meta":   godo.Meta{Total: len(images)},
+	})
+}
+
+func (s *Server) createImageAction(w http.ResponseWriter, r *http.Request, i *godo.Image) {
+	req := make(map[string]interface{})
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+	actionType, _ := req["type"].(string)
+	if actionType != "transfer" {
+		writeError(w, http.StatusUnprocessableEntity, "unprocessable_entity", "Unknown action type: "+actionType)
+		return
+	}
+
+	region, _ := req["region"].(string)
+	if s.region(region) == nil {
+		writeError(w, http.StatusUnprocessableEntity, "unprocessable_entity", "You specified an invalid region.")
+		return
+	}
+	if contains(i.Regions, region) {
+		writeError(w, http.StatusUnprocessableEntity, "unprocessable_entity", "Image is already available in region "+region+".")
+		return
+	}
+
+	a := s.newAction(actionType, i.ID, "image", region)
+	a.complete = func() {
+		i.Regions = append(i.Regions, region)
+	}
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"action": a.Action})
+}
+
+func imageIDs(m map[int]*godo.Image) []int {
+	ids := make([]int, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
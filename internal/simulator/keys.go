@@ -0,0 +1,98 @@
+package simulator
+
+import (
+	"crypto/md5"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/digitalocean/godo"
+)
+
+func (s *Server) handleKeys(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			var keys []godo.Key
+			for _, id := range keyIDs(s.keys) {
+				keys = append(keys, *s.keys[id])
+			}
+			page, links := paginate(r, len(keys))
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"ssh_keys": keys[page.start:page.end],
+				"links":    links,
+				"meta":     godo.Meta{Total: len(keys)},
+			})
+		case http.MethodPost:
+			var req godo.KeyCreateRequest
+			if err := decodeBody(r, &req); err != nil {
+				writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+				return
+			}
+			fingerprint := fingerprint(req.PublicKey)
+			for _, k := range s.keys {
+				if k.Fingerprint == fingerprint {
+					writeError(w, http.StatusUnprocessableEntity, "unprocessable_entity", "SSH Key is already in use on your account")
+					return
+				}
+			}
+			k := &godo.Key{
+				ID:          s.id(),
+				Name:        req.Name,
+				PublicKey:   req.PublicKey,
+				Fingerprint: fingerprint,
+			}
+			s.keys[k.ID] = k
+			writeJSON(w, http.StatusCreated, map[string]interface{}{"ssh_key": k})
+		default:
+			notFound(w)
+		}
+		return
+	}
+
+	var k *godo.Key
+	if id, err := strconv.Atoi(parts[0]); err == nil {
+		k = s.keys[id]
+	} else {
+		for _, key := range s.keys {
+			if key.Fingerprint == parts[0] {
+				k = key
+			}
+		}
+	}
+	if k == nil {
+		notFound(w)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"ssh_key": k})
+	case http.MethodDelete:
+		delete(s.keys, k.ID)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		notFound(w)
+	}
+}
+
+// fingerprint mimics the MD5 fingerprint format the API reports for keys.
+func fingerprint(publicKey string) string {
+	sum := md5.Sum([]byte(strings.TrimSpace(publicKey)))
+	hex := make([]string, len(sum))
+	for i, b := range sum {
+		hex[i] = fmt.Sprintf("%02x", b)
+	}
+	return strings.Join(hex, ":")
+}
+
+func keyIDs(m map[int]*godo.Key) []int {
+	ids := make([]int, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
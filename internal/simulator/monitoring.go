@@ -0,0 +1,36 @@
+package simulator
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// handleMonitoring serves droplet metrics, which only droplets with the
+// monitoring feature, whose agent reports them, have.
+func (s *Server) handleMonitoring(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) != 3 || parts[0] != "metrics" || parts[1] != "droplet" || r.Method != http.MethodGet {
+		notFound(w)
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Query().Get("host_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "host_id is required")
+		return
+	}
+	result := []interface{}{}
+	if d, ok := s.droplets[id]; ok && contains(d.Features, "monitoring") {
+		result = append(result, map[string]interface{}{
+			"metric": map[string]string{"host_id": strconv.Itoa(id)},
+			"values": [][]interface{}{{time.Now().Unix(), "0.5"}},
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data": map[string]interface{}{
+			"resultType": "matrix",
+			"result":     result,
+		},
+	})
+}
@@ -0,0 +1,75 @@
+package simulator
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/digitalocean/godo"
+)
+
+func (s *Server) handleProjects(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) == 0 {
+		if r.Method != http.MethodGet {
+			notFound(w)
+			return
+		}
+		var projects []*godo.Project
+		for _, id := range projectIDs(s.projects) {
+			projects = append(projects, s.projects[id])
+		}
+		page, links := paginate(r, len(projects))
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"projects": projects[page.start:page.end],
+			"links":    links,
+			"meta":     godo.Meta{Total: len(projects)},
+		})
+		return
+	}
+
+	if len(parts) != 1 || r.Method != http.MethodGet {
+		notFound(w)
+		return
+	}
+	if parts[0] == "default" {
+		for _, p := range s.projects {
+			if p.IsDefault {
+				writeJSON(w, http.StatusOK, map[string]interface{}{"project": p})
+				return
+			}
+		}
+		notFound(w)
+		return
+	}
+	p, ok := s.projects[parts[0]]
+	if !ok {
+		notFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"project": p})
+}
+
+// AddProject seeds a project and returns it with its ID assigned. A
+// project seeded as the default replaces the previous default.
+func (s *Server) AddProject(p godo.Project) godo.Project {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p.ID = fmt.Sprintf("4e1bfbc3-dc3e-41f2-a18f-%012d", s.id())
+	p.OwnerUUID = s.account.UUID
+	if p.IsDefault {
+		for _, other := range s.projects {
+			other.IsDefault = false
+		}
+	}
+	s.projects[p.ID] = &p
+	return p
+}
+
+func projectIDs(m map[string]*godo.Project) []string {
+	ids := make([]string, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,547 @@
+// Package simulator provides an in-memory fake of the subset of the
+// DigitalOcean API used by this plugin. It is meant to be used from tests so
+// that steps can be exercised against realistic responses, including
+// injected faults, without talking to the real API.
+package simulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/digitalocean/godo"
+)
+
+// Fault describes an error response the simulator returns instead of, or
+// after, handling a matching request.
+type Fault struct {
+	// Method to match, such as "POST". Empty matches any method.
+	Method string
+	// Path prefix to match, such as "/v2/droplets". Empty matches any path.
+	Path string
+	// HTTP status code of the response.
+	Status int
+	// Error ID and message returned in the response body.
+	ID      string
+	Message string
+	// Number of times the fault fires before it is removed. Zero means the
+	// fault fires for every matching request.
+	Times int
+	// Number of matching requests handled normally before the fault fires.
+	After int
+	// Handle the request before returning the error, as when the response
+	// of a call that went through is lost.
+	Handled bool
+}
+
+// Team is the team an account belongs to, which godo doesn't expose.
+type Team struct {
+	UUID string `json:"uuid"`
+	Name string `json:"name"`
+}
+
+// Server is a fake DigitalOcean API backed by an httptest.Server.
+type Server struct {
+	// ActionPolls is the number of times an action must be fetched before
+	// it is reported as completed.
+	ActionPolls int
+	// DropletPolls is the number of times a new droplet must be fetched
+	// before it is reported as active.
+	DropletPolls int
+
+	srv *httptest.Server
+
+	mu        sync.Mutex
+	nextID    int
+	account   godo.Account
+	team      Team
+	regions   []godo.Region
+	sizes     []godo.Size
+	droplets  map[int]*droplet
+	images    map[int]*godo.Image
+	keys      map[int]*godo.Key
+	actions   map[int]*action
+	tags      map[string]struct{}
+	vpcs      map[string]*godo.VPC
+	firewalls map[string]*godo.Firewall
+	projects  map[string]*godo.Project
+	volumes   map[string]*godo.Volume
+	snapshots map[string]*godo.Snapshot
+	floating  map[string]*floatingIP
+	faults    []*Fault
+	requests  []string
+
+	stuckTypes   []string
+	erroredTypes []string
+}
+
+type droplet struct {
+	godo.Droplet
+	polls int
+}
+
+type action struct {
+	godo.Action
+	polls    int
+	stuck    bool
+	errored  bool
+	complete func()
+}
+
+// New starts a simulator seeded with a handful of regions, sizes and public
+// images, and a default project. Callers must Close it when done.
+func New() *Server {
+	s := &Server{
+		ActionPolls:  1,
+		DropletPolls: 1,
+		nextID:       1000,
+		account: godo.Account{
+			DropletLimit:  25,
+			VolumeLimit:   100,
+			Email:         "packer@example.com",
+			UUID:          "b6fr89dbf6d9156cace5f3c78dc9851d957381ef",
+			EmailVerified: true,
+			Status:        "active",
+		},
+		team: Team{
+			UUID: "5df3e3004a17e242b7c20ca6c9fc25b701a47ece",
+			Name: "My Team",
+		},
+		droplets:  make(map[int]*droplet),
+		images:    make(map[int]*godo.Image),
+		keys:      make(map[int]*godo.Key),
+		actions:   make(map[int]*action),
+		tags:      make(map[string]struct{}),
+		vpcs:      make(map[string]*godo.VPC),
+		firewalls: make(map[string]*godo.Firewall),
+		projects:  make(map[string]*godo.Project),
+		volumes:   make(map[string]*godo.Volume),
+		snapshots: make(map[string]*godo.Snapshot),
+		floating:  make(map[string]*floatingIP),
+	}
+
+	for _, slug := range []string{"nyc1", "nyc3", "sfo3", "ams3", "fra1"} {
+		s.regions = append(s.regions, godo.Region{
+			Slug:      slug,
+			Name:      slug,
+			Available: true,
+			Sizes:     []string{"s-1vcpu-1gb", "s-2vcpu-2gb", "s-4vcpu-8gb"},
+		})
+	}
+	s.sizes = []godo.Size{
+		{Slug: "s-1vcpu-1gb", Memory: 1024, Vcpus: 1, Disk: 25, PriceMonthly: 5, PriceHourly: 0.00744, Available: true},
+		{Slug: "s-2vcpu-2gb", Memory: 2048, Vcpus: 2, Disk: 60, PriceMonthly: 15, PriceHourly: 0.02232, Available: true},
+		{Slug: "s-4vcpu-8gb", Memory: 8192, Vcpus: 4, Disk: 160, PriceMonthly: 40, PriceHourly: 0.05952, Available: true},
+	}
+	for i := range s.sizes {
+		s.sizes[i].Regions = []string{"nyc1", "nyc3", "sfo3", "ams3", "fra1"}
+	}
+	s.AddImage(godo.Image{
+		Name:         "20.04 (LTS) x64",
+		Type:         "base",
+		Distribution: "Ubuntu",
+		Slug:         "ubuntu-20-04-x64",
+		Public:       true,
+		Regions:      []string{"nyc1", "nyc3", "sfo3", "ams3", "fra1"},
+		MinDiskSize:  15,
+		Status:       "available",
+	})
+
+	s.projects["4e1bfbc3-dc3e-41f2-a18f-1cf2c9a47b0f"] = &godo.Project{
+		ID:          "4e1bfbc3-dc3e-41f2-a18f-1cf2c9a47b0f",
+		OwnerUUID:   s.account.UUID,
+		Name:        "first-project",
+		Description: "Update your project information under Settings",
+		Purpose:     "Other",
+		IsDefault:   true,
+	}
+
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// URL returns the base URL of the simulator, suitable for api_url.
+func (s *Server) URL() string {
+	return s.srv.URL + "/"
+}
+
+// Close shuts down the simulator.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Client returns a godo client that talks to the simulator.
+func (s *Server) Client() *godo.Client {
+	client := godo.NewClient(s.srv.Client())
+	client.BaseURL, _ = url.Parse(s.URL())
+	return client
+}
+
+// Inject registers a fault. Faults are matched in the order they were
+// injected.
+func (s *Server) Inject(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// RateLimit makes the next n requests fail with 429 Too Many Requests.
+func (s *Server) RateLimit(n int) {
+	s.Inject(Fault{
+		Status:  http.StatusTooManyRequests,
+		ID:      "too_many_requests",
+		Message: "API Rate limit exceeded.",
+		Times:   n,
+	})
+}
+
+// CapacityError makes the next n droplet creations fail because the region
+// is out of capacity.
+func (s *Server) CapacityError(n int) {
+	s.Inject(Fault{
+		Method:  http.MethodPost,
+		Path:    "/v2/droplets",
+		Status:  http.StatusUnprocessableEntity,
+		ID:      "unprocessable_entity",
+		Message: "Region is currently unavailable for the selected size.",
+		Times:   n,
+	})
+}
+
+// Requests returns the "METHOD /path" of every request the simulator has
+// received, in order.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// Droplet returns a copy of the droplet with the given ID.
+func (s *Server) Droplet(id int) (godo.Droplet, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.droplets[id]
+	if !ok {
+		return godo.Droplet{}, false
+	}
+	return d.Droplet, true
+}
+
+// Droplets returns a copy of every droplet that currently exists.
+func (s *Server) Droplets() []godo.Droplet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []godo.Droplet
+	for _, id := range dropletIDs(s.droplets) {
+		out = append(out, s.droplets[id].Droplet)
+	}
+	return out
+}
+
+// AddDroplet seeds a droplet and returns it with its ID assigned.
+func (s *Server) AddDroplet(d godo.Droplet) godo.Droplet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d.ID = s.id()
+	if d.Status == "" {
+		d.Status = "active"
+	}
+	s.droplets[d.ID] = &droplet{Droplet: d, polls: s.DropletPolls}
+	return d
+}
+
+// SetDropletStatus changes the status of an existing droplet.
+func (s *Server) SetDropletStatus(id int, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if d, ok := s.droplets[id]; ok {
+		d.Status = status
+		d.polls = s.DropletPolls
+	}
+}
+
+// Image returns a copy of the image with the given ID.
+func (s *Server) Image(id int) (godo.Image, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.images[id]
+	if !ok {
+		return godo.Image{}, false
+	}
+	return *i, true
+}
+
+// Images returns a copy of every image that currently exists.
+func (s *Server) Images() []godo.Image {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []godo.Image
+	for _, id := range imageIDs(s.images) {
+		out = append(out, *s.images[id])
+	}
+	return out
+}
+
+// AddImage seeds an image and returns it with its ID assigned.
+func (s *Server) AddImage(i godo.Image) godo.Image {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i.ID = s.id()
+	if i.Status == "" {
+		i.Status = "available"
+	}
+	s.images[i.ID] = &i
+	return i
+}
+
+// Key returns a copy of the SSH key with the given ID.
+func (s *Server) Key(id int) (godo.Key, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k, ok := s.keys[id]
+	if !ok {
+		return godo.Key{}, false
+	}
+	return *k, true
+}
+
+// Keys returns a copy of every SSH key that currently exists.
+func (s *Server) Keys() []godo.Key {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []godo.Key
+	for _, id := range keyIDs(s.keys) {
+		out = append(out, *s.keys[id])
+	}
+	return out
+}
+
+// AddKey seeds an SSH key and returns it with its ID assigned.
+func (s *Server) AddKey(k godo.Key) godo.Key {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k.ID = s.id()
+	s.keys[k.ID] = &k
+	return k
+}
+
+// Tag reports whether a tag with the given name exists.
+func (s *Server) Tag(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.tags[name]
+	return ok
+}
+
+// AddRegion seeds an additional region.
+func (s *Server) AddRegion(r godo.Region) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.regions = append(s.regions, r)
+}
+
+// AddSize seeds an additional droplet size.
+func (s *Server) AddSize(sz godo.Size) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sizes = append(s.sizes, sz)
+}
+
+// StickActions makes every action of the given types, such as "shutdown" or
+// "snapshot", stay in-progress forever.
+func (s *Server) StickActions(types ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stuckTypes = append(s.stuckTypes, types...)
+}
+
+// FailActions makes every action of the given types end up errored.
+func (s *Server) FailActions(types ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.erroredTypes = append(s.erroredTypes, types...)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+
+	if f := s.matchFault(r); f != nil {
+		if f.Handled {
+			s.route(httptest.NewRecorder(), r)
+		}
+		writeError(w, f.Status, f.ID, f.Message)
+		return
+	}
+
+	s.route(w, r)
+}
+
+func (s *Server) route(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 2 || parts[0] != "v2" {
+		writeError(w, http.StatusNotFound, "not_found", "The resource you were accessing could not be found.")
+		return
+	}
+
+	switch parts[1] {
+	case "account":
+		if len(parts) > 2 && parts[2] == "keys" {
+			s.handleKeys(w, r, parts[3:])
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"account": struct {
+			godo.Account
+			Team Team `json:"team"`
+		}{s.account, s.team}})
+	case "regions":
+		page, links := paginate(r, len(s.regions))
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"regions": s.regions[page.start:page.end],
+			"links":   links,
+			"meta":    godo.Meta{Total: len(s.regions)},
+		})
+	case "sizes":
+		page, links := paginate(r, len(s.sizes))
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"sizes": s.sizes[page.start:page.end],
+			"links": links,
+			"meta":  godo.Meta{Total: len(s.sizes)},
+		})
+	case "droplets":
+		s.handleDroplets(w, r, parts[2:])
+	case "images":
+		s.handleImages(w, r, parts[2:])
+	case "tags":
+		s.handleTags(w, r, parts[2:])
+	case "vpcs":
+		s.handleVPCs(w, r, parts[2:])
+	case "firewalls":
+		s.handleFirewalls(w, r, parts[2:])
+	case "projects":
+		s.handleProjects(w, r, parts[2:])
+	case "monitoring":
+		s.handleMonitoring(w, r, parts[2:])
+	case "volumes":
+		s.handleVolumes(w, r, parts[2:])
+	case "snapshots":
+		s.handleSnapshots(w, r, parts[2:])
+	case "floating_ips":
+		s.handleFloatingIPs(w, r, parts[2:])
+	case "actions":
+		if len(parts) != 3 {
+			writeError(w, http.StatusNotFound, "not_found", "The resource you were accessing could not be found.")
+			return
+		}
+		s.handleAction(w, parts[2])
+	default:
+		writeError(w, http.StatusNotFound, "not_found", "The resource you were accessing could not be found.")
+	}
+}
+
+func (s *Server) matchFault(r *http.Request) *Fault {
+	for i, f := range s.faults {
+		if f.Method != "" && f.Method != r.Method {
+			continue
+		}
+		if f.Path != "" && !strings.HasPrefix(r.URL.Path, f.Path) {
+			continue
+		}
+		if f.After > 0 {
+			f.After--
+			continue
+		}
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+func (s *Server) id() int {
+	s.nextID++
+	return s.nextID
+}
+
+type pageBounds struct {
+	start, end int
+}
+
+// paginate applies the page and per_page query parameters the same way the
+// real API does, including the default page size of 20.
+func paginate(r *http.Request, total int) (pageBounds, *godo.Links) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if perPage < 1 {
+		perPage = 20
+	}
+	if perPage > 200 {
+		perPage = 200
+	}
+
+	start := (page - 1) * perPage
+	if start > total {
+		start = total
+	}
+	end := start + perPage
+	if end > total {
+		end = total
+	}
+
+	links := &godo.Links{Pages: &godo.Pages{}}
+	pageURL := func(p int) string {
+		u := *r.URL
+		q := u.Query()
+		q.Set("page", strconv.Itoa(p))
+		q.Set("per_page", strconv.Itoa(perPage))
+		u.RawQuery = q.Encode()
+		return "http://" + r.Host + u.String()
+	}
+	if page > 1 {
+		links.Pages.First = pageURL(1)
+		links.Pages.Prev = pageURL(page - 1)
+	}
+	if end < total {
+		links.Pages.Next = pageURL(page + 1)
+		links.Pages.Last = pageURL((total + perPage - 1) / perPage)
+	}
+
+	return pageBounds{start, end}, links
+}
+
+func decodeBody(r *http.Request, v interface{}) error {
+	defer r.Body.Close()
+	return json.NewDecoder(r.Body).Decode(v)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Request-Id", fmt.Sprintf("sim-%d", status))
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, id, message string) {
+	writeJSON(w, status, map[string]string{
+		"id":      id,
+		"message": message,
+	})
+}
+
+func notFound(w http.ResponseWriter) {
+	writeError(w, http.StatusNotFound, "not_found", "The resource you were accessing could not be found.")
+}
@@ -0,0 +1,139 @@
+package simulator
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/digitalocean/godo"
+)
+
+func TestServer_DropletLifecycle(t *testing.T) {
+	sim := New()
+	defer sim.Close()
+	client := sim.Client()
+
+	droplet, _, err := client.Droplets.Create(context.TODO(), &godo.DropletCreateRequest{
+		Name:   "packer-test",
+		Region: "nyc3",
+		Size:   "s-1vcpu-1gb",
+		Image:  godo.DropletCreateImage{Slug: "ubuntu-20-04-x64"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if droplet.Status != "new" {
+		t.Fatalf("expected new droplet, got %q", droplet.Status)
+	}
+
+	// The first poll still reports the droplet as new.
+	for _, want := range []string{"new", "active"} {
+		d, _, err := client.Droplets.Get(context.TODO(), droplet.ID)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if d.Status != want {
+			t.Fatalf("expected status %q, got %q", want, d.Status)
+		}
+	}
+
+	action, _, err := client.DropletActions.Snapshot(context.TODO(), droplet.ID, "packer-snapshot")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	action, _, err = client.DropletActions.Get(context.TODO(), droplet.ID, action.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if action.Status != godo.ActionCompleted {
+		t.Fatalf("expected completed action, got %q", action.Status)
+	}
+
+	snapshots, _, err := client.Droplets.Snapshots(context.TODO(), droplet.ID, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(snapshots) != 1 || snapshots[0].Name != "packer-snapshot" {
+		t.Fatalf("unexpected snapshots: %s", godo.Stringify(snapshots))
+	}
+
+	transfer, _, err := client.ImageActions.Transfer(context.TODO(), snapshots[0].ID, &godo.ActionRequest{
+		"type":   "transfer",
+		"region": "sfo3",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, _, err := client.ImageActions.Get(context.TODO(), snapshots[0].ID, transfer.ID); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	image, _ := sim.Image(snapshots[0].ID)
+	if len(image.Regions) != 2 {
+		t.Fatalf("expected image in two regions, got %v", image.Regions)
+	}
+
+	if _, err := client.Droplets.Delete(context.TODO(), droplet.ID); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(sim.Droplets()) != 0 {
+		t.Fatalf("expected droplet to be deleted")
+	}
+}
+
+func TestServer_Faults(t *testing.T) {
+	sim := New()
+	defer sim.Close()
+	client := sim.Client()
+
+	sim.RateLimit(1)
+	_, resp, err := client.Account.Get(context.TODO())
+	if err == nil || resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected rate limit error, got: %v", err)
+	}
+	if _, _, err := client.Account.Get(context.TODO()); err != nil {
+		t.Fatalf("expected fault to be cleared, got: %s", err)
+	}
+
+	sim.CapacityError(1)
+	_, _, err = client.Droplets.Create(context.TODO(), &godo.DropletCreateRequest{
+		Name:   "packer-test",
+		Region: "nyc3",
+		Size:   "s-1vcpu-1gb",
+		Image:  godo.DropletCreateImage{Slug: "ubuntu-20-04-x64"},
+	})
+	if err == nil {
+		t.Fatal("expected capacity error")
+	}
+	if len(sim.Droplets()) != 0 {
+		t.Fatalf("no droplet should have been created")
+	}
+}
+
+func TestServer_Pagination(t *testing.T) {
+	sim := New()
+	defer sim.Close()
+	client := sim.Client()
+
+	for i := 0; i < 45; i++ {
+		sim.AddImage(godo.Image{Name: "snapshot", Type: "snapshot"})
+	}
+
+	images, resp, err := client.Images.ListUser(context.TODO(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(images) != 20 {
+		t.Fatalf("expected default page size of 20, got %d", len(images))
+	}
+	if resp.Links.IsLastPage() {
+		t.Fatal("expected more pages")
+	}
+
+	images, resp, err = client.Images.ListUser(context.TODO(), &godo.ListOptions{Page: 3, PerPage: 20})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(images) != 5 || !resp.Links.IsLastPage() {
+		t.Fatalf("expected 5 images on the last page, got %d", len(images))
+	}
+}
@@ -0,0 +1,97 @@
+package simulator
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/digitalocean/godo"
+)
+
+func (s *Server) handleTags(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) == 0 {
+		if r.Method != http.MethodPost {
+			notFound(w)
+			return
+		}
+		var req godo.TagCreateRequest
+		if err := decodeBody(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+			return
+		}
+		s.tags[req.Name] = struct{}{}
+		writeJSON(w, http.StatusCreated, map[string]interface{}{"tag": godo.Tag{Name: req.Name}})
+		return
+	}
+
+	name := parts[0]
+	if len(parts) == 1 && r.Method == http.MethodDelete {
+		if _, ok := s.tags[name]; !ok {
+			notFound(w)
+			return
+		}
+		delete(s.tags, name)
+		for _, d := range s.droplets {
+			d.Tags = without(d.Tags, name)
+		}
+		for _, i := range s.images {
+			i.Tags = without(i.Tags, name)
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if len(parts) != 2 || parts[1] != "resources" {
+		notFound(w)
+		return
+	}
+	if _, ok := s.tags[name]; !ok {
+		notFound(w)
+		return
+	}
+
+	var req godo.TagResourcesRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
+	for _, res := range req.Resources {
+		id, _ := strconv.Atoi(res.ID)
+		var tags *[]string
+		switch res.Type {
+		case godo.DropletResourceType:
+			if d, ok := s.droplets[id]; ok {
+				tags = &d.Tags
+			}
+		case godo.ImageResourceType:
+			if i, ok := s.images[id]; ok {
+				tags = &i.Tags
+			}
+		}
+		if tags == nil {
+			writeError(w, http.StatusUnprocessableEntity, "unprocessable_entity", "Resource "+res.ID+" not found.")
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPost:
+			if !contains(*tags, name) {
+				*tags = append(*tags, name)
+			}
+		case http.MethodDelete:
+			*tags = without(*tags, name)
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// without returns tags with every occurrence of name removed.
+func without(tags []string, name string) []string {
+	kept := tags[:0]
+	for _, t := range tags {
+		if t != name {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
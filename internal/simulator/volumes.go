@@ -0,0 +1,216 @@
+package simulator
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/digitalocean/godo"
+)
+
+func (s *Server) handleVolumes(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) == 0 {
+		if r.Method != http.MethodPost {
+			notFound(w)
+			return
+		}
+		s.createVolume(w, r)
+		return
+	}
+
+	v, ok := s.volumes[parts[0]]
+	if ok && len(parts) == 2 && parts[1] == "snapshots" && r.Method == http.MethodPost {
+		s.createVolumeSnapshot(w, r, v)
+		return
+	}
+	if !ok || len(parts) != 1 {
+		notFound(w)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"volume": v})
+	case http.MethodDelete:
+		for _, id := range v.DropletIDs {
+			if _, ok := s.droplets[id]; ok {
+				writeError(w, http.StatusConflict, "conflict",
+					"This volume is currently attached to a Droplet. Please detach it first.")
+				return
+			}
+		}
+		delete(s.volumes, v.ID)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		notFound(w)
+	}
+}
+
+func (s *Server) createVolume(w http.ResponseWriter, r *http.Request) {
+	var req godo.VolumeCreateRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
+	region := s.region(req.Region)
+	if region == nil {
+		writeError(w, http.StatusUnprocessableEntity, "unprocessable_entity", "invalid region")
+		return
+	}
+	if req.SizeGigaBytes <= 0 {
+		writeError(w, http.StatusUnprocessableEntity, "unprocessable_entity", "size_gigabytes must be provided")
+		return
+	}
+	for _, v := range s.volumes {
+		if v.Name == req.Name && v.Region.Slug == region.Slug {
+			writeError(w, http.StatusConflict, "conflict", "a volume with that name already exists")
+			return
+		}
+	}
+
+	v := &godo.Volume{
+		ID:              fmt.Sprintf("506f78a4-e098-11e5-ad9f-%012d", s.id()),
+		Region:          region,
+		Name:            req.Name,
+		SizeGigaBytes:   req.SizeGigaBytes,
+		Description:     req.Description,
+		DropletIDs:      []int{},
+		CreatedAt:       time.Now().UTC(),
+		FilesystemType:  req.FilesystemType,
+		FilesystemLabel: req.FilesystemLabel,
+		Tags:            req.Tags,
+	}
+	s.volumes[v.ID] = v
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"volume": v})
+}
+
+func (s *Server) createVolumeSnapshot(w http.ResponseWriter, r *http.Request, v *godo.Volume) {
+	var req godo.SnapshotCreateRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+	if req.Name == "" {
+		writeError(w, http.StatusUnprocessableEntity, "unprocessable_entity", "name is required")
+		return
+	}
+
+	snapshot := &godo.Snapshot{
+		ID:           fmt.Sprintf("8fa70202-873f-11e6-8b68-%012d", s.id()),
+		Name:         req.Name,
+		ResourceID:   v.ID,
+		ResourceType: "volume",
+		Regions:      []string{v.Region.Slug},
+		MinDiskSize:  int(v.SizeGigaBytes),
+		Created:      time.Now().UTC().Format(time.RFC3339),
+		Tags:         req.Tags,
+	}
+	s.snapshots[snapshot.ID] = snapshot
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"snapshot": snapshot})
+}
+
+func (s *Server) handleSnapshots(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) == 0 && r.Method == http.MethodGet {
+		// Only volume snapshots are kept here, droplet ones are images
+		var snapshots []*godo.Snapshot
+		if t := r.URL.Query().Get("resource_type"); t == "" || t == "volume" {
+			for _, id := range snapshotIDs(s.snapshots) {
+				snapshots = append(snapshots, s.snapshots[id])
+			}
+		}
+		page, links := paginate(r, len(snapshots))
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"snapshots": snapshots[page.start:page.end],
+			"links":     links,
+			"meta":      godo.Meta{Total: len(snapshots)},
+		})
+		return
+	}
+	if len(parts) != 1 {
+		notFound(w)
+		return
+	}
+	snapshot, ok := s.snapshots[parts[0]]
+	if !ok {
+		notFound(w)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"snapshot": snapshot})
+	case http.MethodDelete:
+		delete(s.snapshots, snapshot.ID)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		notFound(w)
+	}
+}
+
+// AddVolumeSnapshot seeds a volume snapshot and returns it with its ID
+// assigned.
+func (s *Server) AddVolumeSnapshot(snapshot godo.Snapshot) godo.Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot.ID = fmt.Sprintf("8fa70202-873f-11e6-8b68-%012d", s.id())
+	snapshot.ResourceType = "volume"
+	if snapshot.Created == "" {
+		snapshot.Created = time.Now().UTC().Format(time.RFC3339)
+	}
+	if snapshot.Tags == nil {
+		snapshot.Tags = []string{}
+	}
+	s.snapshots[snapshot.ID] = &snapshot
+	return snapshot
+}
+
+func snapshotIDs(m map[string]*godo.Snapshot) []string {
+	ids := make([]string, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// VolumeSnapshot returns a copy of the volume snapshot with the given ID.
+func (s *Server) VolumeSnapshot(id string) (godo.Snapshot, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot, ok := s.snapshots[id]
+	if !ok {
+		return godo.Snapshot{}, false
+	}
+	return *snapshot, true
+}
+
+// Volume returns a copy of the volume with the given ID.
+func (s *Server) Volume(id string) (godo.Volume, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.volumes[id]
+	if !ok {
+		return godo.Volume{}, false
+	}
+	return *v, true
+}
+
+// Volumes returns a copy of every volume, ordered by ID.
+func (s *Server) Volumes() []godo.Volume {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []godo.Volume
+	for _, id := range volumeIDs(s.volumes) {
+		out = append(out, *s.volumes[id])
+	}
+	return out
+}
+
+func volumeIDs(m map[string]*godo.Volume) []string {
+	ids := make([]string, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,55 @@
+package simulator
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/digitalocean/godo"
+)
+
+func (s *Server) handleVPCs(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) == 0 {
+		if r.Method != http.MethodGet {
+			notFound(w)
+			return
+		}
+		var vpcs []*godo.VPC
+		for _, id := range vpcIDs(s.vpcs) {
+			vpcs = append(vpcs, s.vpcs[id])
+		}
+		page, links := paginate(r, len(vpcs))
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"vpcs":  vpcs[page.start:page.end],
+			"links": links,
+			"meta":  godo.Meta{Total: len(vpcs)},
+		})
+		return
+	}
+
+	v, ok := s.vpcs[parts[0]]
+	if !ok || len(parts) != 1 || r.Method != http.MethodGet {
+		notFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"vpc": v})
+}
+
+// AddVPC seeds a VPC and returns it with its ID assigned.
+func (s *Server) AddVPC(v godo.VPC) godo.VPC {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v.ID = fmt.Sprintf("5a4981aa-9653-4bd1-bef5-%012d", s.id())
+	v.URN = "do:vpc:" + v.ID
+	s.vpcs[v.ID] = &v
+	return v
+}
+
+func vpcIDs(m map[string]*godo.VPC) []string {
+	ids := make([]string, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -5,7 +5,23 @@ import (
 	"os"
 
 	"github.com/hashicorp/packer-plugin-digitalocean/builder/digitalocean"
+	digitaloceanAccountDS "github.com/hashicorp/packer-plugin-digitalocean/datasource/digitalocean-account"
+	digitaloceanDropletDS "github.com/hashicorp/packer-plugin-digitalocean/datasource/digitalocean-droplet"
+	digitaloceanFirewallDS "github.com/hashicorp/packer-plugin-digitalocean/datasource/digitalocean-firewall"
+	digitaloceanMarketplaceAppDS "github.com/hashicorp/packer-plugin-digitalocean/datasource/digitalocean-marketplace-app"
+	digitaloceanProjectDS "github.com/hashicorp/packer-plugin-digitalocean/datasource/digitalocean-project"
+	digitaloceanReservedIPDS "github.com/hashicorp/packer-plugin-digitalocean/datasource/digitalocean-reserved-ip"
+	digitaloceanVolumeSnapshotDS "github.com/hashicorp/packer-plugin-digitalocean/datasource/digitalocean-volume-snapshot"
+	digitaloceanArtificePP "github.com/hashicorp/packer-plugin-digitalocean/post-processor/digitalocean-artifice"
+	digitaloceanBootTestPP "github.com/hashicorp/packer-plugin-digitalocean/post-processor/digitalocean-boot-test"
+	digitaloceanCatalogPP "github.com/hashicorp/packer-plugin-digitalocean/post-processor/digitalocean-catalog"
+	digitaloceanImageReplicatePP "github.com/hashicorp/packer-plugin-digitalocean/post-processor/digitalocean-image-replicate"
+	digitaloceanImageUpdatePP "github.com/hashicorp/packer-plugin-digitalocean/post-processor/digitalocean-image-update"
 	digitaloceanPP "github.com/hashicorp/packer-plugin-digitalocean/post-processor/digitalocean-import"
+	digitaloceanPromotePP "github.com/hashicorp/packer-plugin-digitalocean/post-processor/digitalocean-promote"
+	digitaloceanRollbackPP "github.com/hashicorp/packer-plugin-digitalocean/post-processor/digitalocean-rollback"
+	digitaloceanSpacesPP "github.com/hashicorp/packer-plugin-digitalocean/post-processor/digitalocean-spaces"
+	digitaloceanTfvarsPP "github.com/hashicorp/packer-plugin-digitalocean/post-processor/digitalocean-tfvars"
 	"github.com/hashicorp/packer-plugin-digitalocean/version"
 
 	"github.com/hashicorp/packer-plugin-sdk/plugin"
@@ -15,6 +31,22 @@ func main() {
 	pps := plugin.NewSet()
 	pps.RegisterBuilder(plugin.DEFAULT_NAME, new(digitalocean.Builder))
 	pps.RegisterPostProcessor("import", new(digitaloceanPP.PostProcessor))
+	pps.RegisterPostProcessor("image-update", new(digitaloceanImageUpdatePP.PostProcessor))
+	pps.RegisterPostProcessor("image-replicate", new(digitaloceanImageReplicatePP.PostProcessor))
+	pps.RegisterPostProcessor("spaces", new(digitaloceanSpacesPP.PostProcessor))
+	pps.RegisterPostProcessor("boot-test", new(digitaloceanBootTestPP.PostProcessor))
+	pps.RegisterPostProcessor("promote", new(digitaloceanPromotePP.PostProcessor))
+	pps.RegisterPostProcessor("tfvars", new(digitaloceanTfvarsPP.PostProcessor))
+	pps.RegisterPostProcessor("catalog", new(digitaloceanCatalogPP.PostProcessor))
+	pps.RegisterPostProcessor("artifice", new(digitaloceanArtificePP.PostProcessor))
+	pps.RegisterPostProcessor("rollback", new(digitaloceanRollbackPP.PostProcessor))
+	pps.RegisterDatasource("account", new(digitaloceanAccountDS.Datasource))
+	pps.RegisterDatasource("firewall", new(digitaloceanFirewallDS.Datasource))
+	pps.RegisterDatasource("project", new(digitaloceanProjectDS.Datasource))
+	pps.RegisterDatasource("marketplace-app", new(digitaloceanMarketplaceAppDS.Datasource))
+	pps.RegisterDatasource("droplet", new(digitaloceanDropletDS.Datasource))
+	pps.RegisterDatasource("reserved-ip", new(digitaloceanReservedIPDS.Datasource))
+	pps.RegisterDatasource("volume-snapshot", new(digitaloceanVolumeSnapshotDS.Datasource))
 	pps.SetVersion(version.PluginVersion)
 	err := pps.Run()
 	if err != nil {
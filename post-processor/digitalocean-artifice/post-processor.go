@@ -0,0 +1,105 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+package digitaloceanartifice
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-digitalocean/builder/digitalocean"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+const BuilderId = "packer.post-processor.digitalocean-artifice"
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	APIToken string `mapstructure:"api_token"`
+	APIURL   string `mapstructure:"api_url"`
+
+	ImageID int `mapstructure:"image_id"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:         BuilderId,
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	if p.config.APIToken == "" {
+		p.config.APIToken = os.Getenv("DIGITALOCEAN_API_TOKEN")
+	}
+
+	if p.config.APIURL == "" {
+		p.config.APIURL = os.Getenv("DIGITALOCEAN_API_URL")
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if p.config.APIToken == "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("api_token must be set"))
+	}
+
+	if p.config.ImageID == 0 {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("image_id must be set"))
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	packersdk.LogSecretFilter.Set(p.config.APIToken)
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifact packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
+	client, err := digitalocean.NewClient(p.config.APIToken, p.config.APIURL)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Invalid API URL: %s", err)
+	}
+
+	image, _, err := client.Images.GetByID(context.TODO(), p.config.ImageID)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Error retrieving image %d: %s", p.config.ImageID, err)
+	}
+	// Distribution and 1-Click images can't be transferred, renamed or
+	// deleted, which is all the other post-processors do
+	if image.Public {
+		return nil, false, false, fmt.Errorf("Image %d (%s) is a public image, not a snapshot or custom image of the account",
+			image.ID, image.Name)
+	}
+
+	log.Printf("Wrapping image %d (%s) in regions %v", image.ID, image.Name, image.Regions)
+	ui.Message(fmt.Sprintf("Using image %d (%s)", image.ID, image.Name))
+	artifact = &digitalocean.Artifact{
+		SnapshotName: image.Name,
+		SnapshotId:   image.ID,
+		RegionNames:  append([]string{}, image.Regions...),
+		Client:       client,
+		StateData:    map[string]interface{}{"generated_data": artifact.State("generated_data")},
+	}
+
+	// Whatever the input artifact is, it has nothing to do with the image.
+	return artifact, true, false, nil
+}
@@ -0,0 +1,79 @@
+package digitaloceanartifice
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-digitalocean/builder/digitalocean"
+	"github.com/hashicorp/packer-plugin-digitalocean/internal/simulator"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packersdk.PostProcessor = new(PostProcessor)
+}
+
+func TestPostProcessor_Configure(t *testing.T) {
+	t.Setenv("DIGITALOCEAN_API_TOKEN", "")
+
+	var p PostProcessor
+	if err := p.Configure(map[string]interface{}{"api_token": "foo"}); err == nil {
+		t.Fatal("expected an error without image_id")
+	}
+
+	p = PostProcessor{}
+	if err := p.Configure(map[string]interface{}{"image_id": 42}); err == nil {
+		t.Fatal("expected an error without api_token")
+	}
+}
+
+func TestPostProcessor_PostProcess(t *testing.T) {
+	sim := simulator.New()
+	defer sim.Close()
+	image := sim.AddImage(godo.Image{Name: "packer-test", Type: "snapshot", Regions: []string{"nyc3", "ams3"}})
+
+	var p PostProcessor
+	err := p.Configure(map[string]interface{}{
+		"api_token": "foo",
+		"api_url":   sim.URL(),
+		"image_id":  image.ID,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	source := &packersdk.MockArtifact{}
+	artifact, _, _, err := p.PostProcess(context.Background(), packersdk.TestUi(t), source)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if artifact.BuilderId() != digitalocean.BuilderId {
+		t.Fatalf("expected a DigitalOcean artifact, got %s", artifact.BuilderId())
+	}
+	expected := fmt.Sprintf("nyc3,ams3:%d", image.ID)
+	if artifact.Id() != expected {
+		t.Fatalf("expected artifact ID %s, got %s", expected, artifact.Id())
+	}
+}
+
+func TestPostProcessor_PostProcess_PublicImage(t *testing.T) {
+	sim := simulator.New()
+	defer sim.Close()
+	image := sim.AddImage(godo.Image{Name: "Ubuntu 20.04 (LTS) x64", Type: "base", Public: true, Regions: []string{"nyc3"}})
+
+	var p PostProcessor
+	err := p.Configure(map[string]interface{}{
+		"api_token": "foo",
+		"api_url":   sim.URL(),
+		"image_id":  image.ID,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, _, _, err := p.PostProcess(context.Background(), packersdk.TestUi(t), &packersdk.MockArtifact{}); err == nil {
+		t.Fatal("expected an error for a public image")
+	}
+}
@@ -0,0 +1,390 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+package digitaloceanboottest
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-digitalocean/builder/digitalocean"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+	"github.com/hashicorp/packer-plugin-sdk/uuid"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+const BuilderId = "packer.post-processor.digitalocean-boot-test"
+
+// retryInterval is how long to wait between SSH connection attempts.
+var retryInterval = 5 * time.Second
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	APIToken string `mapstructure:"api_token"`
+	APIURL   string `mapstructure:"api_url"`
+
+	ImageID int    `mapstructure:"image_id"`
+	Region  string `mapstructure:"region"`
+	Size    string `mapstructure:"size"`
+
+	TestFile string `mapstructure:"test_file"`
+	TestType string `mapstructure:"test_type"`
+
+	GossInstallCommand string `mapstructure:"goss_install_command"`
+
+	SSHUsername  string        `mapstructure:"ssh_username"`
+	SSHPort      int           `mapstructure:"ssh_port"`
+	SSHTimeout   time.Duration `mapstructure:"ssh_timeout"`
+	StateTimeout time.Duration `mapstructure:"state_timeout"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+
+	// dial connects to the droplet, tests replace it.
+	dial func(network, addr string, config *gossh.ClientConfig) (*gossh.Client, error)
+}
+
+func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:         BuilderId,
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	if p.config.APIToken == "" {
+		p.config.APIToken = os.Getenv("DIGITALOCEAN_API_TOKEN")
+	}
+
+	if p.config.APIURL == "" {
+		p.config.APIURL = os.Getenv("DIGITALOCEAN_API_URL")
+	}
+
+	if p.config.TestType == "" {
+		switch filepath.Ext(p.config.TestFile) {
+		case ".yaml", ".yml":
+			p.config.TestType = "goss"
+		default:
+			p.config.TestType = "script"
+		}
+	}
+
+	if p.config.GossInstallCommand == "" {
+		p.config.GossInstallCommand = "command -v goss || curl -fsSL https://goss.rocks/install | GOSS_DST=/usr/local/bin sh"
+	}
+
+	if p.config.SSHUsername == "" {
+		p.config.SSHUsername = "root"
+	}
+
+	if p.config.SSHPort == 0 {
+		p.config.SSHPort = 22
+	}
+
+	if p.config.SSHTimeout == 0 {
+		p.config.SSHTimeout = 5 * time.Minute
+	}
+
+	if p.config.StateTimeout == 0 {
+		p.config.StateTimeout = 6 * time.Minute
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if p.config.APIToken == "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("api_token must be set"))
+	}
+
+	if p.config.TestFile == "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("test_file must be set"))
+	} else if _, err := os.Stat(p.config.TestFile); err != nil {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("test_file is invalid: %s", err))
+	}
+
+	if p.config.TestType != "goss" && p.config.TestType != "script" {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("test_type must be one of goss or script, got %q", p.config.TestType))
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	packersdk.LogSecretFilter.Set(p.config.APIToken)
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifact packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
+	imageId := p.config.ImageID
+	if imageId == 0 {
+		if artifact.BuilderId() != digitalocean.BuilderId {
+			return nil, false, false, fmt.Errorf(
+				"Unknown artifact type: %s\nCan only boot-test images created by the DigitalOcean builder or import post-processor, or set image_id.",
+				artifact.BuilderId())
+		}
+
+		var err error
+		_, imageId, err = digitalocean.ParseArtifactId(artifact.Id())
+		if err != nil {
+			return nil, false, false, err
+		}
+	}
+
+	client, err := digitalocean.NewClient(p.config.APIToken, p.config.APIURL)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Invalid API URL: %s", err)
+	}
+
+	image, _, err := client.Images.GetByID(context.TODO(), imageId)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Error retrieving image %d: %s", imageId, err)
+	}
+
+	region := p.config.Region
+	if region == "" {
+		if len(image.Regions) == 0 {
+			return nil, false, false, fmt.Errorf("Image %d is not available in any region", imageId)
+		}
+		region = image.Regions[0]
+	}
+
+	size := p.config.Size
+	if size == "" {
+		size, err = smallestSize(client, region, image.MinDiskSize)
+		if err != nil {
+			return nil, false, false, err
+		}
+	}
+
+	signer, publicKey, err := generateKey()
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Error generating SSH key: %s", err)
+	}
+
+	name := fmt.Sprintf("packer-boot-test-%s", uuid.TimeOrderedUUID())
+	key, _, err := client.Keys.Create(context.TODO(), &godo.KeyCreateRequest{
+		Name:      name,
+		PublicKey: publicKey,
+	})
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Error creating temporary SSH key: %s", err)
+	}
+	defer func() {
+		if _, err := client.Keys.DeleteByID(context.TODO(), key.ID); err != nil {
+			ui.Error(fmt.Sprintf(
+				"Error cleaning up SSH key. Please delete the key manually: %s", err))
+		}
+	}()
+
+	ui.Message(fmt.Sprintf("Creating %s droplet from image %d in %s", size, imageId, region))
+	droplet, _, err := client.Droplets.Create(context.TODO(), &godo.DropletCreateRequest{
+		Name:    name,
+		Region:  region,
+		Size:    size,
+		Image:   godo.DropletCreateImage{ID: imageId},
+		SSHKeys: []godo.DropletCreateSSHKey{{ID: key.ID}},
+	})
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Error creating droplet: %s", err)
+	}
+	dropletId := droplet.ID
+	defer func() {
+		ui.Message("Destroying droplet")
+		if err := digitalocean.DestroyDroplet(client, dropletId, p.config.StateTimeout); err != nil {
+			ui.Error(fmt.Sprintf(
+				"Error destroying droplet. Please destroy it manually: %s", err))
+		}
+	}()
+
+	if err := digitalocean.WaitForDropletState("active", dropletId, client, p.config.StateTimeout); err != nil {
+		return nil, false, false, fmt.Errorf("Error waiting for droplet to become active: %s", err)
+	}
+	active, _, err := client.Droplets.Get(context.TODO(), dropletId)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Error retrieving droplet: %s", err)
+	}
+	ip, err := active.PublicIPv4()
+	if err != nil || ip == "" {
+		return nil, false, false, fmt.Errorf("Could not find a public IPv4 address for droplet %d", dropletId)
+	}
+
+	ui.Message(fmt.Sprintf("Waiting for SSH to become available on %s", ip))
+	sshClient, err := p.connect(ctx, net.JoinHostPort(ip, strconv.Itoa(p.config.SSHPort)), &gossh.ClientConfig{
+		User:            p.config.SSHUsername,
+		Auth:            []gossh.AuthMethod{gossh.PublicKeys(signer)},
+		HostKeyCallback: gossh.InsecureIgnoreHostKey(),
+		Timeout:         30 * time.Second,
+	})
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Error connecting to droplet: %s", err)
+	}
+	defer sshClient.Close()
+
+	remotePath := path.Join("/tmp", filepath.Base(p.config.TestFile))
+	ui.Message(fmt.Sprintf("Uploading %s", p.config.TestFile))
+	if err := upload(sshClient, p.config.TestFile, remotePath); err != nil {
+		return nil, false, false, fmt.Errorf("Error uploading test file: %s", err)
+	}
+
+	var commands []string
+	switch p.config.TestType {
+	case "goss":
+		commands = []string{
+			p.config.GossInstallCommand,
+			fmt.Sprintf("goss -g %s validate --format documentation", remotePath),
+		}
+	case "script":
+		commands = []string{fmt.Sprintf("chmod +x %s && %s", remotePath, remotePath)}
+	}
+	for _, command := range commands {
+		if err := run(ui, sshClient, command); err != nil {
+			return nil, false, false, err
+		}
+	}
+
+	ui.Message(fmt.Sprintf("Image %d passed boot test", imageId))
+
+	// Nothing about the image changed, so the input artifact is passed on.
+	return artifact, true, true, nil
+}
+
+// connect retries connecting until the droplet's SSH server is up.
+func (p *PostProcessor) connect(ctx context.Context, addr string, config *gossh.ClientConfig) (*gossh.Client, error) {
+	dial := p.dial
+	if dial == nil {
+		dial = gossh.Dial
+	}
+
+	deadline := time.Now().Add(p.config.SSHTimeout)
+	for {
+		client, err := dial("tcp", addr, config)
+		if err == nil {
+			return client, nil
+		}
+		log.Printf("SSH connection to droplet failed: %s", err)
+		if time.Now().After(deadline) {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+func upload(client *gossh.Client, src, dst string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	session.Stdin = f
+	return session.Run(fmt.Sprintf("cat > %s", dst))
+}
+
+func run(ui packersdk.Ui, client *gossh.Client, command string) error {
+	session, err := client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	log.Printf("Running boot test command: %s", command)
+	output, err := session.CombinedOutput(command)
+	if len(output) > 0 {
+		ui.Message(strings.TrimRight(string(output), "\n"))
+	}
+	if err != nil {
+		return fmt.Errorf("Boot test command %q failed: %s", command, err)
+	}
+	return nil
+}
+
+// smallestSize returns the cheapest size available in the region with a
+// disk big enough for the image.
+func smallestSize(client *godo.Client, region string, minDisk int) (string, error) {
+	opt := &godo.ListOptions{
+		Page:    1,
+		PerPage: 200,
+	}
+	var sizes []godo.Size
+	for {
+		page, resp, err := client.Sizes.List(context.TODO(), opt)
+		if err != nil {
+			return "", fmt.Errorf("Error listing sizes: %s", err)
+		}
+		sizes = append(sizes, page...)
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			break
+		}
+		opt.Page++
+	}
+
+	var best *godo.Size
+	for i, size := range sizes {
+		if !size.Available || size.Disk < minDisk {
+			continue
+		}
+		inRegion := false
+		for _, r := range size.Regions {
+			if r == region {
+				inRegion = true
+				break
+			}
+		}
+		if inRegion && (best == nil || size.PriceHourly < best.PriceHourly) {
+			best = &sizes[i]
+		}
+	}
+	if best == nil {
+		return "", fmt.Errorf("No size in %s has a disk of at least %d GB", region, minDisk)
+	}
+	return best.Slug, nil
+}
+
+func generateKey() (gossh.Signer, string, error) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, "", err
+	}
+	signer, err := gossh.NewSignerFromKey(priv)
+	if err != nil {
+		return nil, "", err
+	}
+	return signer, string(gossh.MarshalAuthorizedKey(signer.PublicKey())), nil
+}
@@ -0,0 +1,262 @@
+package digitaloceanboottest
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-digitalocean/builder/digitalocean"
+	"github.com/hashicorp/packer-plugin-digitalocean/internal/simulator"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packersdk.PostProcessor = new(PostProcessor)
+}
+
+func TestPostProcessor_Configure(t *testing.T) {
+	t.Setenv("DIGITALOCEAN_API_TOKEN", "")
+	testFile := filepath.Join(t.TempDir(), "goss.yaml")
+	if err := os.WriteFile(testFile, []byte("service: {}\n"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var p PostProcessor
+	if err := p.Configure(map[string]interface{}{"test_file": testFile}); err == nil {
+		t.Fatal("expected an error without api_token")
+	}
+
+	p = PostProcessor{}
+	if err := p.Configure(map[string]interface{}{"api_token": "foo"}); err == nil {
+		t.Fatal("expected an error without test_file")
+	}
+
+	p = PostProcessor{}
+	if err := p.Configure(map[string]interface{}{"api_token": "foo", "test_file": testFile}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if p.config.TestType != "goss" {
+		t.Fatalf("expected test_type goss, got %s", p.config.TestType)
+	}
+}
+
+// testSSHServer starts an SSH server that records the commands it is asked
+// to run, and reports those mentioning "fail" as failing.
+func testSSHServer(t *testing.T) (string, func() []string) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	signer, err := gossh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	config := &gossh.ServerConfig{
+		PublicKeyCallback: func(gossh.ConnMetadata, gossh.PublicKey) (*gossh.Permissions, error) {
+			return nil, nil
+		},
+	}
+	config.AddHostKey(signer)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	var mu sync.Mutex
+	var commands []string
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, chans, reqs, err := gossh.NewServerConn(conn, config)
+				if err != nil {
+					return
+				}
+				go gossh.DiscardRequests(reqs)
+				for newChan := range chans {
+					ch, reqs, err := newChan.Accept()
+					if err != nil {
+						return
+					}
+					go func() {
+						defer ch.Close()
+						for req := range reqs {
+							if req.Type != "exec" {
+								req.Reply(false, nil)
+								continue
+							}
+							req.Reply(true, nil)
+							command := string(req.Payload[4:])
+							mu.Lock()
+							commands = append(commands, command)
+							mu.Unlock()
+							status := uint32(0)
+							if strings.Contains(command, "fail") && !strings.HasPrefix(command, "cat") {
+								status = 1
+							}
+							ch.CloseWrite()
+							ch.SendRequest("exit-status", false, gossh.Marshal(struct{ Status uint32 }{status}))
+							return
+						}
+					}()
+				}
+			}()
+		}
+	}()
+
+	return l.Addr().String(), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), commands...)
+	}
+}
+
+func TestPostProcessor_PostProcess(t *testing.T) {
+	retryInterval = time.Millisecond
+	sim := simulator.New()
+	defer sim.Close()
+	sim.DropletPolls = 0
+	image := sim.AddImage(godo.Image{Name: "packer-test", Type: "snapshot", Regions: []string{"sfo3"}, MinDiskSize: 50})
+	addr, commands := testSSHServer(t)
+
+	testFile := filepath.Join(t.TempDir(), "goss.yaml")
+	if err := os.WriteFile(testFile, []byte("service:\n  sshd:\n    running: true\n"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	p := PostProcessor{
+		dial: func(network, _ string, config *gossh.ClientConfig) (*gossh.Client, error) {
+			return gossh.Dial(network, addr, config)
+		},
+	}
+	err := p.Configure(map[string]interface{}{
+		"api_token":            "foo",
+		"api_url":              sim.URL(),
+		"test_file":            testFile,
+		"goss_install_command": "true",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	source := &digitalocean.Artifact{
+		SnapshotName: image.Name,
+		SnapshotId:   image.ID,
+		RegionNames:  image.Regions,
+	}
+	artifact, keep, _, err := p.PostProcess(context.Background(), packersdk.TestUi(t), source)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !keep || artifact != source {
+		t.Fatal("the input artifact must be passed on")
+	}
+
+	expected := []string{"cat > /tmp/goss.yaml", "true", "goss -g /tmp/goss.yaml validate --format documentation"}
+	if got := commands(); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected commands %#v, got %#v", expected, got)
+	}
+
+	// The droplet and key are cleaned up
+	if droplets := sim.Droplets(); len(droplets) != 0 {
+		t.Fatalf("expected droplet to be destroyed, got %#v", droplets)
+	}
+	if keys := sim.Keys(); len(keys) != 0 {
+		t.Fatalf("expected key to be deleted, got %#v", keys)
+	}
+	for _, req := range sim.Requests() {
+		if req == "POST /v2/droplets" {
+			return
+		}
+	}
+	t.Fatal("no droplet was created")
+}
+
+func TestPostProcessor_PostProcessFailing(t *testing.T) {
+	retryInterval = time.Millisecond
+	sim := simulator.New()
+	defer sim.Close()
+	sim.DropletPolls = 0
+	image := sim.AddImage(godo.Image{Name: "packer-test", Type: "snapshot", Regions: []string{"sfo3"}})
+	addr, _ := testSSHServer(t)
+
+	testFile := filepath.Join(t.TempDir(), "fail.sh")
+	if err := os.WriteFile(testFile, []byte("#!/bin/sh\nexit 1\n"), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	p := PostProcessor{
+		dial: func(network, _ string, config *gossh.ClientConfig) (*gossh.Client, error) {
+			return gossh.Dial(network, addr, config)
+		},
+	}
+	err := p.Configure(map[string]interface{}{
+		"api_token": "foo",
+		"api_url":   sim.URL(),
+		"image_id":  image.ID,
+		"test_file": testFile,
+		"size":      "s-1vcpu-1gb",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	_, _, _, err = p.PostProcess(context.Background(), packersdk.TestUi(t), &packersdk.MockArtifact{})
+	if err == nil || !strings.Contains(err.Error(), "/tmp/fail.sh") {
+		t.Fatalf("expected the script to fail, got: %v", err)
+	}
+	if droplets := sim.Droplets(); len(droplets) != 0 {
+		t.Fatalf("expected droplet to be destroyed, got %#v", droplets)
+	}
+}
+
+func TestPostProcessor_PostProcessRetrieveFailure(t *testing.T) {
+	sim := simulator.New()
+	defer sim.Close()
+	sim.DropletPolls = 0
+	image := sim.AddImage(godo.Image{Name: "packer-test", Type: "snapshot", Regions: []string{"sfo3"}})
+
+	testFile := filepath.Join(t.TempDir(), "test.sh")
+	if err := os.WriteFile(testFile, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var p PostProcessor
+	err := p.Configure(map[string]interface{}{
+		"api_token": "foo",
+		"api_url":   sim.URL(),
+		"image_id":  image.ID,
+		"test_file": testFile,
+		"size":      "s-1vcpu-1gb",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The droplet is active on the first poll, and fetching it afterwards
+	// fails
+	sim.Inject(simulator.Fault{Method: http.MethodGet, Path: "/v2/droplets/", Status: http.StatusInternalServerError,
+		ID: "server_error", Message: "Server was unable to give you a response.", After: 1, Times: 1})
+	_, _, _, err = p.PostProcess(context.Background(), packersdk.TestUi(t), &packersdk.MockArtifact{})
+	if err == nil || !strings.Contains(err.Error(), "Error retrieving droplet") {
+		t.Fatalf("expected an error retrieving the droplet, got: %v", err)
+	}
+	if droplets := sim.Droplets(); len(droplets) != 0 {
+		t.Fatalf("expected droplet to be destroyed, got %#v", droplets)
+	}
+}
@@ -0,0 +1,340 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+package digitaloceancatalog
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-digitalocean/builder/digitalocean"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+const BuilderId = "packer.post-processor.digitalocean-catalog"
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	APIToken string `mapstructure:"api_token"`
+	APIURL   string `mapstructure:"api_url"`
+
+	SpacesKey    string `mapstructure:"spaces_key"`
+	SpacesSecret string `mapstructure:"spaces_secret"`
+
+	SpacesRegion  string            `mapstructure:"spaces_region"`
+	SpaceName     string            `mapstructure:"space_name"`
+	CatalogKey    string            `mapstructure:"catalog_key"`
+	ACL           string            `mapstructure:"acl"`
+	ChecksumFiles []string          `mapstructure:"checksum_files"`
+	BuildInfo     map[string]string `mapstructure:"build_info"`
+
+	ctx interpolate.Context
+}
+
+// Catalog is the index of images kept in the Space.
+type Catalog struct {
+	Images  []CatalogImage `json:"images"`
+	Updated string         `json:"updated"`
+}
+
+// CatalogImage is the entry of an image in the catalog.
+type CatalogImage struct {
+	Name         string            `json:"name"`
+	ID           int               `json:"id"`
+	Regions      []string          `json:"regions"`
+	Distribution string            `json:"distribution,omitempty"`
+	MinDiskSize  int               `json:"min_disk_size"`
+	Created      string            `json:"created"`
+	Checksums    map[string]string `json:"checksums,omitempty"`
+	BuildName    string            `json:"build_name,omitempty"`
+	BuilderType  string            `json:"builder_type,omitempty"`
+	BuildInfo    map[string]string `json:"build_info,omitempty"`
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:         BuilderId,
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{"build_info"},
+		},
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	if p.config.APIToken == "" {
+		p.config.APIToken = os.Getenv("DIGITALOCEAN_API_TOKEN")
+	}
+
+	if p.config.APIURL == "" {
+		p.config.APIURL = os.Getenv("DIGITALOCEAN_API_URL")
+	}
+
+	if p.config.SpacesKey == "" {
+		p.config.SpacesKey = os.Getenv("DIGITALOCEAN_SPACES_ACCESS_KEY")
+	}
+
+	if p.config.SpacesSecret == "" {
+		p.config.SpacesSecret = os.Getenv("DIGITALOCEAN_SPACES_SECRET_KEY")
+	}
+
+	if p.config.CatalogKey == "" {
+		p.config.CatalogKey = "catalog.json"
+	}
+
+	if p.config.ACL == "" {
+		p.config.ACL = "private"
+	}
+
+	errs := new(packersdk.MultiError)
+
+	requiredArgs := map[string]*string{
+		"api_token":     &p.config.APIToken,
+		"spaces_key":    &p.config.SpacesKey,
+		"spaces_secret": &p.config.SpacesSecret,
+		"spaces_region": &p.config.SpacesRegion,
+		"space_name":    &p.config.SpaceName,
+	}
+	for key, ptr := range requiredArgs {
+		if *ptr == "" {
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("%s must be set", key))
+		}
+	}
+
+	// Spaces only supports these two canned ACLs
+	if p.config.ACL != "private" && p.config.ACL != "public-read" {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("acl must be one of private or public-read, got %q", p.config.ACL))
+	}
+
+	for key, value := range p.config.BuildInfo {
+		if err = interpolate.Validate(value, &p.config.ctx); err != nil {
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("Error parsing build_info %s template: %s", key, err))
+		}
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	packersdk.LogSecretFilter.Set(p.config.APIToken, p.config.SpacesKey, p.config.SpacesSecret)
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifact packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
+	if artifact.BuilderId() != digitalocean.BuilderId {
+		return nil, false, false, fmt.Errorf(
+			"Unknown artifact type: %s\nCan only catalog images created by the DigitalOcean builder or import post-processor.",
+			artifact.BuilderId())
+	}
+
+	regions, imageId, err := digitalocean.ParseArtifactId(artifact.Id())
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	generatedData := artifact.State("generated_data")
+	if generatedData == nil {
+		// Make sure it's not a nil map so we can assign to it later.
+		generatedData = make(map[string]interface{})
+	}
+	p.config.ctx.Data = generatedData
+
+	var buildInfo map[string]string
+	for key, value := range p.config.BuildInfo {
+		rendered, err := interpolate.Render(value, &p.config.ctx)
+		if err != nil {
+			return nil, false, false, fmt.Errorf("Error rendering build_info %s template: %s", key, err)
+		}
+		if buildInfo == nil {
+			buildInfo = make(map[string]string, len(p.config.BuildInfo))
+		}
+		buildInfo[key] = rendered
+	}
+
+	checksums, err := fileChecksums(p.config.ChecksumFiles)
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	client, err := digitalocean.NewClient(p.config.APIToken, p.config.APIURL)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Invalid API URL: %s", err)
+	}
+
+	image, _, err := client.Images.GetByID(context.TODO(), imageId)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Error retrieving image %d: %s", imageId, err)
+	}
+
+	entry := CatalogImage{
+		Name:         image.Name,
+		ID:           image.ID,
+		Regions:      regions,
+		Distribution: image.Distribution,
+		MinDiskSize:  image.MinDiskSize,
+		Created:      image.Created,
+		Checksums:    checksums,
+		BuildName:    p.config.PackerBuildName,
+		BuilderType:  p.config.PackerBuilderType,
+		BuildInfo:    buildInfo,
+	}
+
+	spacesCreds := credentials.NewStaticCredentials(p.config.SpacesKey, p.config.SpacesSecret, "")
+	spacesEndpoint := fmt.Sprintf("https://%s.digitaloceanspaces.com", p.config.SpacesRegion)
+	sess, err := session.NewSession(&aws.Config{
+		Credentials: spacesCreds,
+		Endpoint:    aws.String(spacesEndpoint),
+		Region:      aws.String(p.config.SpacesRegion),
+	})
+	if err != nil {
+		return nil, false, false, err
+	}
+	s3conn := s3.New(sess)
+
+	ui.Message(fmt.Sprintf("Adding image %d (%s) to spaces://%s/%s", image.ID, image.Name, p.config.SpaceName, p.config.CatalogKey))
+	existing, err := p.download(s3conn)
+	if err != nil {
+		return nil, false, false, err
+	}
+	data, err := updateCatalog(existing, entry, time.Now().UTC())
+	if err != nil {
+		return nil, false, false, err
+	}
+	if err := p.upload(s3conn, data); err != nil {
+		return nil, false, false, err
+	}
+
+	log.Printf("Cataloged image %d in spaces://%s/%s", image.ID, p.config.SpaceName, p.config.CatalogKey)
+	// Nothing about the image changed, so the input artifact is passed on.
+	return artifact, true, true, nil
+}
+
+// download returns the current catalog, or nil when there is none yet.
+func (p *PostProcessor) download(s3conn *s3.S3) ([]byte, error) {
+	out, err := s3conn.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(p.config.SpaceName),
+		Key:    aws.String(p.config.CatalogKey),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to download the catalog: %s", err)
+	}
+	defer out.Body.Close()
+
+	data, err := ioutil.ReadAll(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to download the catalog: %s", err)
+	}
+	return data, nil
+}
+
+func (p *PostProcessor) upload(s3conn *s3.S3, data []byte) error {
+	_, err := s3conn.PutObject(&s3.PutObjectInput{
+		Body:        bytes.NewReader(data),
+		Bucket:      aws.String(p.config.SpaceName),
+		Key:         aws.String(p.config.CatalogKey),
+		ACL:         aws.String(p.config.ACL),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to upload the catalog: %s", err)
+	}
+	return nil
+}
+
+// updateCatalog adds the entry to the catalog, replacing the entry of the
+// same image, if any, so that a retried build doesn't list it twice.
+func updateCatalog(existing []byte, entry CatalogImage, now time.Time) ([]byte, error) {
+	var catalog Catalog
+	if len(bytes.TrimSpace(existing)) > 0 {
+		if err := json.Unmarshal(existing, &catalog); err != nil {
+			return nil, fmt.Errorf("Invalid catalog: %s", err)
+		}
+	}
+
+	replaced := false
+	for i := range catalog.Images {
+		if catalog.Images[i].ID == entry.ID {
+			catalog.Images[i] = entry
+			replaced = true
+		}
+	}
+	if !replaced {
+		catalog.Images = append(catalog.Images, entry)
+	}
+	catalog.Updated = now.Format(time.RFC3339)
+
+	data, err := json.MarshalIndent(catalog, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// fileChecksums returns the SHA256 checksums of the files, by base name.
+func fileChecksums(files []string) (map[string]string, error) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	checksums := make(map[string]string, len(files))
+	for _, source := range files {
+		name := filepath.Base(source)
+		if _, ok := checksums[name]; ok {
+			return nil, fmt.Errorf("checksum_files has several files named %s", name)
+		}
+		sum, err := fileChecksum(source)
+		if err != nil {
+			return nil, err
+		}
+		checksums[name] = "sha256:" + sum
+	}
+	return checksums, nil
+}
+
+func fileChecksum(source string) (string, error) {
+	file, err := os.Open(source)
+	if err != nil {
+		return "", fmt.Errorf("Failed to open %s: %s", source, err)
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", fmt.Errorf("Failed to read %s: %s", source, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
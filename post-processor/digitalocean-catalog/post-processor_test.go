@@ -0,0 +1,119 @@
+package digitaloceancatalog
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packersdk.PostProcessor = new(PostProcessor)
+}
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"api_token":     "foo",
+		"spaces_key":    "key",
+		"spaces_secret": "secret",
+		"spaces_region": "nyc3",
+		"space_name":    "images",
+	}
+}
+
+func TestPostProcessor_Configure(t *testing.T) {
+	var p PostProcessor
+	if err := p.Configure(testConfig()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if p.config.CatalogKey != "catalog.json" {
+		t.Fatalf("expected catalog_key to default to catalog.json, got %q", p.config.CatalogKey)
+	}
+
+	c := testConfig()
+	c["acl"] = "authenticated-read"
+	p = PostProcessor{}
+	if err := p.Configure(c); err == nil {
+		t.Fatal("expected an error for an unsupported acl")
+	}
+
+	c = testConfig()
+	c["build_info"] = map[string]string{"commit": "{{ .Commit"}
+	p = PostProcessor{}
+	if err := p.Configure(c); err == nil {
+		t.Fatal("expected an error for an invalid build_info template")
+	}
+
+	t.Setenv("DIGITALOCEAN_API_TOKEN", "")
+	c = testConfig()
+	delete(c, "api_token")
+	p = PostProcessor{}
+	if err := p.Configure(c); err == nil {
+		t.Fatal("expected an error without api_token")
+	}
+}
+
+func TestPostProcessor_UpdateCatalog(t *testing.T) {
+	now := time.Date(2021, 7, 10, 12, 0, 0, 0, time.UTC)
+
+	data, err := updateCatalog(nil, CatalogImage{Name: "web-v1", ID: 1}, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	data, err = updateCatalog(data, CatalogImage{Name: "web-v2", ID: 2}, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// A retried build replaces its entry
+	data, err = updateCatalog(data, CatalogImage{Name: "web-v1", ID: 1, Regions: []string{"nyc3"}}, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var catalog Catalog
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := Catalog{
+		Images: []CatalogImage{
+			{Name: "web-v1", ID: 1, Regions: []string{"nyc3"}},
+			{Name: "web-v2", ID: 2},
+		},
+		Updated: "2021-07-10T12:00:00Z",
+	}
+	if !reflect.DeepEqual(catalog, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, catalog)
+	}
+
+	if _, err := updateCatalog([]byte("<html>"), CatalogImage{ID: 3}, now); err == nil {
+		t.Fatal("expected an error for an invalid catalog")
+	}
+}
+
+func TestPostProcessor_FileChecksums(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "image.qcow2")
+	if err := ioutil.WriteFile(source, []byte("image"), 0644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	checksums, err := fileChecksums([]string{source})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := map[string]string{
+		"image.qcow2": "sha256:6105d6cc76af400325e94d588ce511be5bfdbb73b437dc51eca43917d7a43e3d",
+	}
+	if !reflect.DeepEqual(checksums, expected) {
+		t.Fatalf("expected %v, got %v", expected, checksums)
+	}
+
+	other := filepath.Join(dir, "other", "image.qcow2")
+	if _, err := fileChecksums([]string{source, other}); err == nil {
+		t.Fatal("expected an error for files of the same name")
+	}
+}
@@ -0,0 +1,162 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+package digitaloceanimagereplicate
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-digitalocean/builder/digitalocean"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+const BuilderId = "packer.post-processor.digitalocean-image-replicate"
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	APIToken string `mapstructure:"api_token"`
+	APIURL   string `mapstructure:"api_url"`
+
+	ImageID      int      `mapstructure:"image_id"`
+	ImageRegions []string `mapstructure:"image_regions"`
+
+	Timeout time.Duration `mapstructure:"timeout"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:         BuilderId,
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	if p.config.APIToken == "" {
+		p.config.APIToken = os.Getenv("DIGITALOCEAN_API_TOKEN")
+	}
+
+	if p.config.APIURL == "" {
+		p.config.APIURL = os.Getenv("DIGITALOCEAN_API_URL")
+	}
+
+	if p.config.Timeout == 0 {
+		p.config.Timeout = 20 * time.Minute
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if p.config.APIToken == "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("api_token must be set"))
+	}
+
+	if len(p.config.ImageRegions) == 0 {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("image_regions must be set"))
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	packersdk.LogSecretFilter.Set(p.config.APIToken)
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifact packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
+	imageId := p.config.ImageID
+	if imageId == 0 {
+		if artifact.BuilderId() != digitalocean.BuilderId {
+			return nil, false, false, fmt.Errorf(
+				"Unknown artifact type: %s\nCan only replicate images created by the DigitalOcean builder or import post-processor, or set image_id.",
+				artifact.BuilderId())
+		}
+
+		var err error
+		_, imageId, err = digitalocean.ParseArtifactId(artifact.Id())
+		if err != nil {
+			return nil, false, false, err
+		}
+	}
+
+	client, err := digitalocean.NewClient(p.config.APIToken, p.config.APIURL)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Invalid API URL: %s", err)
+	}
+
+	image, _, err := client.Images.GetByID(context.TODO(), imageId)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Error retrieving image %d: %s", imageId, err)
+	}
+
+	available := make(map[string]struct{})
+	for _, region := range image.Regions {
+		available[region] = struct{}{}
+	}
+	regions := append([]string{}, image.Regions...)
+
+	for _, region := range p.config.ImageRegions {
+		if _, ok := available[region]; ok {
+			log.Printf("Image %d is already available in %s", imageId, region)
+			continue
+		}
+		available[region] = struct{}{}
+
+		ui.Message(fmt.Sprintf("Transferring image %d to %s", imageId, region))
+		if err := transferImage(client, imageId, region, p.config.Timeout); err != nil {
+			return nil, false, false, err
+		}
+		regions = append(regions, region)
+	}
+
+	artifact = &digitalocean.Artifact{
+		SnapshotName: image.Name,
+		SnapshotId:   image.ID,
+		RegionNames:  regions,
+		Client:       client,
+		StateData:    map[string]interface{}{"generated_data": artifact.State("generated_data")},
+	}
+
+	// Transfers don't create a new image, so the input artifact must be kept.
+	return artifact, true, true, nil
+}
+
+func transferImage(client *godo.Client, imageId int, region string, timeout time.Duration) error {
+	transferRequest := &godo.ActionRequest{
+		"type":   "transfer",
+		"region": region,
+	}
+	action, _, err := client.ImageActions.Transfer(context.TODO(), imageId, transferRequest)
+	if digitalocean.IsAlreadyAvailable(err) {
+		log.Printf("Image %d is already available in %s", imageId, region)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error transferring image to %s: %s", region, err)
+	}
+
+	if err := digitalocean.WaitForImageState(godo.ActionCompleted, imageId, action.ID, client, timeout); err != nil {
+		return fmt.Errorf("Error waiting for image transfer to %s: %s", region, err)
+	}
+
+	return nil
+}
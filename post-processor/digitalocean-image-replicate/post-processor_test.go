@@ -0,0 +1,69 @@
+package digitaloceanimagereplicate
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-digitalocean/builder/digitalocean"
+	"github.com/hashicorp/packer-plugin-digitalocean/internal/simulator"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packersdk.PostProcessor = new(PostProcessor)
+}
+
+func TestPostProcessor_Configure(t *testing.T) {
+	t.Setenv("DIGITALOCEAN_API_TOKEN", "")
+
+	var p PostProcessor
+	if err := p.Configure(map[string]interface{}{"api_token": "foo"}); err == nil {
+		t.Fatal("expected an error without image_regions")
+	}
+
+	p = PostProcessor{}
+	if err := p.Configure(map[string]interface{}{"image_regions": []string{"sfo3"}}); err == nil {
+		t.Fatal("expected an error without api_token")
+	}
+}
+
+func TestPostProcessor_PostProcess(t *testing.T) {
+	sim := simulator.New()
+	defer sim.Close()
+	image := sim.AddImage(godo.Image{Name: "packer-test", Type: "snapshot", Regions: []string{"nyc3"}})
+
+	var p PostProcessor
+	err := p.Configure(map[string]interface{}{
+		"api_token":     "foo",
+		"api_url":       sim.URL(),
+		"image_regions": []string{"nyc3", "sfo3", "ams3"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	source := &digitalocean.Artifact{
+		SnapshotName: image.Name,
+		SnapshotId:   image.ID,
+		RegionNames:  image.Regions,
+	}
+	artifact, keep, _, err := p.PostProcess(context.Background(), packersdk.TestUi(t), source)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !keep {
+		t.Fatal("the input artifact must be kept")
+	}
+
+	expected := fmt.Sprintf("nyc3,sfo3,ams3:%d", image.ID)
+	if artifact.Id() != expected {
+		t.Fatalf("expected artifact ID %s, got %s", expected, artifact.Id())
+	}
+
+	replicated, _ := sim.Image(image.ID)
+	if len(replicated.Regions) != 3 {
+		t.Fatalf("expected image in three regions, got %v", replicated.Regions)
+	}
+}
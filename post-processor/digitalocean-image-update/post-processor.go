@@ -0,0 +1,187 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+package digitaloceanimageupdate
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-digitalocean/builder/digitalocean"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+const BuilderId = "packer.post-processor.digitalocean-image-update"
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	APIToken string `mapstructure:"api_token"`
+	APIURL   string `mapstructure:"api_url"`
+
+	Name        string   `mapstructure:"image_name"`
+	Description string   `mapstructure:"image_description"`
+	Tags        []string `mapstructure:"image_tags"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:         BuilderId,
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{"image_name", "image_description"},
+		},
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	if p.config.APIToken == "" {
+		p.config.APIToken = os.Getenv("DIGITALOCEAN_API_TOKEN")
+	}
+
+	if p.config.APIURL == "" {
+		p.config.APIURL = os.Getenv("DIGITALOCEAN_API_URL")
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if p.config.APIToken == "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("api_token must be set"))
+	}
+
+	if p.config.Name == "" && p.config.Description == "" && len(p.config.Tags) == 0 {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("at least one of image_name, image_description or image_tags must be set"))
+	}
+
+	templates := map[string]string{
+		"image_name":        p.config.Name,
+		"image_description": p.config.Description,
+	}
+	for key, tpl := range templates {
+		if err = interpolate.Validate(tpl, &p.config.ctx); err != nil {
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("Error parsing %s template: %s", key, err))
+		}
+	}
+
+	for _, t := range p.config.Tags {
+		if err := digitalocean.ValidateTag(t); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("invalid value in image_tags: %s", err))
+		}
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	packersdk.LogSecretFilter.Set(p.config.APIToken)
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifact packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
+	if artifact.BuilderId() != digitalocean.BuilderId {
+		return nil, false, false, fmt.Errorf(
+			"Unknown artifact type: %s\nCan only update images created by the DigitalOcean builder or import post-processor.",
+			artifact.BuilderId())
+	}
+
+	regions, imageId, err := digitalocean.ParseArtifactId(artifact.Id())
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	generatedData := artifact.State("generated_data")
+	if generatedData == nil {
+		// Make sure it's not a nil map so we can assign to it later.
+		generatedData = make(map[string]interface{})
+	}
+	p.config.ctx.Data = generatedData
+
+	name, err := interpolate.Render(p.config.Name, &p.config.ctx)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Error rendering image_name template: %s", err)
+	}
+	description, err := interpolate.Render(p.config.Description, &p.config.ctx)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Error rendering image_description template: %s", err)
+	}
+
+	client, err := digitalocean.NewClient(p.config.APIToken, p.config.APIURL)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Invalid API URL: %s", err)
+	}
+
+	image, _, err := client.Images.GetByID(context.TODO(), imageId)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Error retrieving image %d: %s", imageId, err)
+	}
+
+	if name != "" || description != "" {
+		ui.Message(fmt.Sprintf("Updating image %d (%s)", image.ID, image.Name))
+		image, _, err = client.Images.Update(context.TODO(), imageId, &godo.ImageUpdateRequest{
+			Name:        name,
+			Description: description,
+		})
+		if err != nil {
+			return nil, false, false, fmt.Errorf("Error updating image %d: %s", imageId, err)
+		}
+	}
+
+	for _, tag := range p.config.Tags {
+		ui.Message(fmt.Sprintf("Tagging image %d with %s", image.ID, tag))
+		if err := tagImage(client, imageId, tag); err != nil {
+			return nil, false, false, err
+		}
+	}
+
+	log.Printf("Updated image %d, now named %s", image.ID, image.Name)
+	artifact = &digitalocean.Artifact{
+		SnapshotName: image.Name,
+		SnapshotId:   image.ID,
+		RegionNames:  regions,
+		Client:       client,
+		StateData:    map[string]interface{}{"generated_data": generatedData},
+	}
+
+	// The image is updated in place, so the input artifact must never be
+	// destroyed: it is the same image as the one we return.
+	return artifact, true, true, nil
+}
+
+func tagImage(client *godo.Client, imageId int, tag string) error {
+	// Creating a tag that already exists is a no-op
+	_, _, err := client.Tags.Create(context.TODO(), &godo.TagCreateRequest{Name: tag})
+	if err != nil {
+		return fmt.Errorf("Error creating tag %s: %s", tag, err)
+	}
+
+	_, err = client.Tags.TagResources(context.TODO(), tag, &godo.TagResourcesRequest{
+		Resources: []godo.Resource{
+			{ID: strconv.Itoa(imageId), Type: godo.ImageResourceType},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("Error tagging image %d with %s: %s", imageId, tag, err)
+	}
+
+	return nil
+}
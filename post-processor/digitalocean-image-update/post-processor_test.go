@@ -0,0 +1,92 @@
+package digitaloceanimageupdate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-digitalocean/builder/digitalocean"
+	"github.com/hashicorp/packer-plugin-digitalocean/internal/simulator"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packersdk.PostProcessor = new(PostProcessor)
+}
+
+func TestPostProcessor_Configure(t *testing.T) {
+	tt := []struct {
+		Name   string
+		Config map[string]interface{}
+		Valid  bool
+	}{
+		{Name: "Name", Config: map[string]interface{}{"api_token": "foo", "image_name": "golden"}, Valid: true},
+		{Name: "Tags", Config: map[string]interface{}{"api_token": "foo", "image_tags": []string{"blessed"}}, Valid: true},
+		{Name: "NothingToUpdate", Config: map[string]interface{}{"api_token": "foo"}},
+		{Name: "InvalidTag", Config: map[string]interface{}{"api_token": "foo", "image_tags": []string{"not valid"}}},
+		{Name: "MissingToken", Config: map[string]interface{}{"image_name": "golden"}},
+	}
+
+	t.Setenv("DIGITALOCEAN_API_TOKEN", "")
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			var p PostProcessor
+			err := p.Configure(tc.Config)
+			if tc.Valid && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !tc.Valid && err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestPostProcessor_PostProcess(t *testing.T) {
+	sim := simulator.New()
+	defer sim.Close()
+	image := sim.AddImage(godo.Image{Name: "packer-provisional", Type: "snapshot", Regions: []string{"nyc3"}})
+
+	var p PostProcessor
+	err := p.Configure(map[string]interface{}{
+		"api_token":         "foo",
+		"api_url":           sim.URL(),
+		"image_name":        "golden-{{ .Flavor }}",
+		"image_description": "Blessed image",
+		"image_tags":        []string{"blessed"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	source := &digitalocean.Artifact{
+		SnapshotName: image.Name,
+		SnapshotId:   image.ID,
+		RegionNames:  image.Regions,
+		StateData: map[string]interface{}{
+			"generated_data": map[string]interface{}{"Flavor": "web"},
+		},
+	}
+	artifact, keep, _, err := p.PostProcess(context.Background(), packersdk.TestUi(t), source)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !keep {
+		t.Fatal("the input artifact must be kept")
+	}
+	if artifact.Id() != source.Id() {
+		t.Fatalf("expected artifact ID %s, got %s", source.Id(), artifact.Id())
+	}
+
+	updated, _ := sim.Image(image.ID)
+	if updated.Name != "golden-web" {
+		t.Fatalf("expected image to be renamed, got %q", updated.Name)
+	}
+	if updated.Description != "Blessed image" {
+		t.Fatalf("expected description to be set, got %q", updated.Description)
+	}
+	if len(updated.Tags) != 1 || updated.Tags[0] != "blessed" {
+		t.Fatalf("expected image to be tagged, got %v", updated.Tags)
+	}
+}
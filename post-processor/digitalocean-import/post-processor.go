@@ -364,6 +364,10 @@ func distributeImageToRegions(client *godo.Client, imageId int, regions []string
 		}
 		log.Printf("Transferring image to %s", region)
 		action, _, err := client.ImageActions.Transfer(context.TODO(), imageId, transferRequest)
+		if digitalocean.IsAlreadyAvailable(err) {
+			log.Printf("Image is already available in %s", region)
+			continue
+		}
 		if err != nil {
 			return fmt.Errorf("Error transferring image: %s", err)
 		}
@@ -0,0 +1,286 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+package digitaloceanpromote
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-digitalocean/builder/digitalocean"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+const BuilderId = "packer.post-processor.digitalocean-promote"
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	APIToken string `mapstructure:"api_token"`
+	APIURL   string `mapstructure:"api_url"`
+
+	Alias       string `mapstructure:"alias"`
+	Mode        string `mapstructure:"mode"`
+	DemotedName string `mapstructure:"demoted_name"`
+
+	ctx interpolate.Context
+}
+
+// demotedData is what demoted_name is rendered with.
+type demotedData struct {
+	Alias     string
+	ID        int
+	Timestamp int64
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:         BuilderId,
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{"demoted_name"},
+		},
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	if p.config.APIToken == "" {
+		p.config.APIToken = os.Getenv("DIGITALOCEAN_API_TOKEN")
+	}
+
+	if p.config.APIURL == "" {
+		p.config.APIURL = os.Getenv("DIGITALOCEAN_API_URL")
+	}
+
+	if p.config.Mode == "" {
+		p.config.Mode = "name"
+	}
+
+	if p.config.DemotedName == "" {
+		// myimage-latest becomes myimage-1625745600
+		if strings.HasSuffix(p.config.Alias, "latest") {
+			p.config.DemotedName = strings.TrimSuffix(p.config.Alias, "latest") + "{{ .Timestamp }}"
+		} else {
+			p.config.DemotedName = "{{ .Alias }}-{{ .Timestamp }}"
+		}
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if p.config.APIToken == "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("api_token must be set"))
+	}
+
+	if p.config.Alias == "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("alias must be set"))
+	}
+
+	switch p.config.Mode {
+	case "name":
+	case "tag":
+		if err := digitalocean.ValidateTag(p.config.Alias); p.config.Alias != "" && err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("invalid alias: %s", err))
+		}
+	default:
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("mode must be name or tag, not %q", p.config.Mode))
+	}
+
+	if err = interpolate.Validate(p.config.DemotedName, &p.config.ctx); err != nil {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("Error parsing demoted_name template: %s", err))
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	packersdk.LogSecretFilter.Set(p.config.APIToken)
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifact packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
+	if artifact.BuilderId() != digitalocean.BuilderId {
+		return nil, false, false, fmt.Errorf(
+			"Unknown artifact type: %s\nCan only promote images created by the DigitalOcean builder or import post-processor.",
+			artifact.BuilderId())
+	}
+
+	regions, imageId, err := digitalocean.ParseArtifactId(artifact.Id())
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	client, err := digitalocean.NewClient(p.config.APIToken, p.config.APIURL)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Invalid API URL: %s", err)
+	}
+
+	image, _, err := client.Images.GetByID(context.TODO(), imageId)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Error retrieving image %d: %s", imageId, err)
+	}
+
+	previous, err := p.previousImages(client, imageId)
+	if err != nil {
+		return nil, false, false, err
+	}
+	// Render every demoted name up front, so that a bad one fails the
+	// promotion before any image has been touched
+	demoted := make([]string, len(previous))
+	for i, prev := range previous {
+		demoted[i], err = p.demotedName(prev)
+		if err != nil {
+			return nil, false, false, err
+		}
+		if p.config.Mode == "tag" {
+			if err := digitalocean.ValidateTag(demoted[i]); err != nil {
+				return nil, false, false, fmt.Errorf("Error demoting image %d: %s", prev.ID, err)
+			}
+		}
+	}
+	for i, prev := range previous {
+		ui.Message(fmt.Sprintf("Demoting image %d (%s) to %s", prev.ID, prev.Name, demoted[i]))
+		if err := p.demote(client, prev, demoted[i]); err != nil {
+			return nil, false, false, err
+		}
+	}
+
+	ui.Message(fmt.Sprintf("Promoting image %d (%s) to %s", image.ID, image.Name, p.config.Alias))
+	if p.config.Mode == "tag" {
+		if err := tagImage(client, image.ID, p.config.Alias); err != nil {
+			return nil, false, false, err
+		}
+	} else {
+		image, _, err = client.Images.Update(context.TODO(), image.ID, &godo.ImageUpdateRequest{Name: p.config.Alias})
+		if err != nil {
+			return nil, false, false, fmt.Errorf("Error renaming image %d: %s", imageId, err)
+		}
+	}
+
+	log.Printf("Promoted image %d to %s", image.ID, p.config.Alias)
+	artifact = &digitalocean.Artifact{
+		SnapshotName: image.Name,
+		SnapshotId:   image.ID,
+		RegionNames:  regions,
+		Client:       client,
+		StateData:    map[string]interface{}{"generated_data": artifact.State("generated_data")},
+	}
+
+	// The image is promoted in place, so the input artifact must never be
+	// destroyed: it is the same image as the one we return.
+	return artifact, true, true, nil
+}
+
+// previousImages returns the images currently holding the alias, other than
+// the one being promoted.
+func (p *PostProcessor) previousImages(client *godo.Client, imageId int) ([]godo.Image, error) {
+	opt := &godo.ListOptions{
+		Page:    1,
+		PerPage: 200,
+	}
+	var previous []godo.Image
+	for {
+		var images []godo.Image
+		var resp *godo.Response
+		var err error
+		if p.config.Mode == "tag" {
+			images, resp, err = client.Images.ListByTag(context.TODO(), p.config.Alias, opt)
+		} else {
+			images, resp, err = client.Images.ListUser(context.TODO(), opt)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Error listing images: %s", err)
+		}
+		for _, image := range images {
+			if image.ID == imageId {
+				continue
+			}
+			if p.config.Mode == "tag" || image.Name == p.config.Alias {
+				previous = append(previous, image)
+			}
+		}
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			break
+		}
+		opt.Page++
+	}
+	return previous, nil
+}
+
+func (p *PostProcessor) demotedName(image godo.Image) (string, error) {
+	timestamp := int64(image.ID)
+	if created, err := time.Parse(time.RFC3339, image.Created); err == nil {
+		timestamp = created.Unix()
+	}
+	p.config.ctx.Data = &demotedData{
+		Alias:     p.config.Alias,
+		ID:        image.ID,
+		Timestamp: timestamp,
+	}
+	name, err := interpolate.Render(p.config.DemotedName, &p.config.ctx)
+	if err != nil {
+		return "", fmt.Errorf("Error rendering demoted_name template: %s", err)
+	}
+	return name, nil
+}
+
+// demote takes the alias away from a previous image, renaming or tagging it
+// with its demoted name.
+func (p *PostProcessor) demote(client *godo.Client, image godo.Image, demoted string) error {
+	if p.config.Mode != "tag" {
+		_, _, err := client.Images.Update(context.TODO(), image.ID, &godo.ImageUpdateRequest{Name: demoted})
+		if err != nil {
+			return fmt.Errorf("Error renaming image %d: %s", image.ID, err)
+		}
+		return nil
+	}
+
+	_, err := client.Tags.UntagResources(context.TODO(), p.config.Alias, &godo.UntagResourcesRequest{
+		Resources: []godo.Resource{
+			{ID: strconv.Itoa(image.ID), Type: godo.ImageResourceType},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("Error untagging image %d: %s", image.ID, err)
+	}
+	return tagImage(client, image.ID, demoted)
+}
+
+func tagImage(client *godo.Client, imageId int, tag string) error {
+	// Creating a tag that already exists is a no-op
+	_, _, err := client.Tags.Create(context.TODO(), &godo.TagCreateRequest{Name: tag})
+	if err != nil {
+		return fmt.Errorf("Error creating tag %s: %s", tag, err)
+	}
+
+	_, err = client.Tags.TagResources(context.TODO(), tag, &godo.TagResourcesRequest{
+		Resources: []godo.Resource{
+			{ID: strconv.Itoa(imageId), Type: godo.ImageResourceType},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("Error tagging image %d with %s: %s", imageId, tag, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package digitaloceanpromote
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-digitalocean/builder/digitalocean"
+	"github.com/hashicorp/packer-plugin-digitalocean/internal/simulator"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packersdk.PostProcessor = new(PostProcessor)
+}
+
+func TestPostProcessor_Configure(t *testing.T) {
+	tt := []struct {
+		Name   string
+		Config map[string]interface{}
+		Valid  bool
+	}{
+		{Name: "Name", Config: map[string]interface{}{"api_token": "foo", "alias": "myimage-latest"}, Valid: true},
+		{Name: "Tag", Config: map[string]interface{}{"api_token": "foo", "alias": "latest", "mode": "tag"}, Valid: true},
+		{Name: "InvalidTag", Config: map[string]interface{}{"api_token": "foo", "alias": "my image", "mode": "tag"}},
+		{Name: "InvalidMode", Config: map[string]interface{}{"api_token": "foo", "alias": "latest", "mode": "label"}},
+		{Name: "MissingAlias", Config: map[string]interface{}{"api_token": "foo"}},
+		{Name: "MissingToken", Config: map[string]interface{}{"alias": "myimage-latest"}},
+	}
+
+	t.Setenv("DIGITALOCEAN_API_TOKEN", "")
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			var p PostProcessor
+			err := p.Configure(tc.Config)
+			if tc.Valid && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !tc.Valid && err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestPostProcessor_PostProcessName(t *testing.T) {
+	sim := simulator.New()
+	defer sim.Close()
+	previous := sim.AddImage(godo.Image{Name: "myimage-latest", Type: "snapshot", Created: "2021-07-08T12:00:00Z"})
+	image := sim.AddImage(godo.Image{Name: "packer-1625918400", Type: "snapshot", Regions: []string{"nyc3"}})
+
+	var p PostProcessor
+	err := p.Configure(map[string]interface{}{
+		"api_token": "foo",
+		"api_url":   sim.URL(),
+		"alias":     "myimage-latest",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	source := &digitalocean.Artifact{SnapshotName: image.Name, SnapshotId: image.ID, RegionNames: image.Regions}
+	artifact, keep, _, err := p.PostProcess(context.Background(), packersdk.TestUi(t), source)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !keep {
+		t.Fatal("the input artifact must be kept")
+	}
+	if artifact.Id() != source.Id() {
+		t.Fatalf("expected artifact ID %s, got %s", source.Id(), artifact.Id())
+	}
+
+	if got, _ := sim.Image(image.ID); got.Name != "myimage-latest" {
+		t.Errorf("expected the new image to be named myimage-latest, got %s", got.Name)
+	}
+	if got, _ := sim.Image(previous.ID); got.Name != "myimage-1625745600" {
+		t.Errorf("expected the previous image to be named myimage-1625745600, got %s", got.Name)
+	}
+}
+
+func TestPostProcessor_PostProcessTag(t *testing.T) {
+	sim := simulator.New()
+	defer sim.Close()
+	previous := sim.AddImage(godo.Image{Name: "myimage", Type: "snapshot", Tags: []string{"latest"}, Created: "2021-07-08T12:00:00Z"})
+	image := sim.AddImage(godo.Image{Name: "myimage", Type: "snapshot", Regions: []string{"nyc3"}})
+
+	var p PostProcessor
+	err := p.Configure(map[string]interface{}{
+		"api_token":    "foo",
+		"api_url":      sim.URL(),
+		"alias":        "latest",
+		"mode":         "tag",
+		"demoted_name": "released-{{ .Timestamp }}",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The tag has to exist to be taken away
+	client := sim.Client()
+	if _, _, err := client.Tags.Create(context.TODO(), &godo.TagCreateRequest{Name: "latest"}); err != nil {
+		t.Fatal(err)
+	}
+
+	source := &digitalocean.Artifact{SnapshotName: image.Name, SnapshotId: image.ID, RegionNames: image.Regions}
+	if _, _, _, err := p.PostProcess(context.Background(), packersdk.TestUi(t), source); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if got, _ := sim.Image(image.ID); len(got.Tags) != 1 || got.Tags[0] != "latest" {
+		t.Errorf("expected the new image to be tagged latest, got %v", got.Tags)
+	}
+	if got, _ := sim.Image(previous.ID); len(got.Tags) != 1 || got.Tags[0] != "released-1625745600" {
+		t.Errorf("expected the previous image to be tagged released-1625745600, got %v", got.Tags)
+	}
+}
+
+func TestPostProcessor_PostProcessTagInvalidDemotedName(t *testing.T) {
+	sim := simulator.New()
+	defer sim.Close()
+	first := sim.AddImage(godo.Image{Name: "myimage", Type: "snapshot", Tags: []string{"latest"}})
+	second := sim.AddImage(godo.Image{Name: "myimage", Type: "snapshot", Tags: []string{"latest"}})
+	image := sim.AddImage(godo.Image{Name: "myimage", Type: "snapshot", Regions: []string{"nyc3"}})
+
+	// Only one of the previous images gets an invalid tag
+	var p PostProcessor
+	err := p.Configure(map[string]interface{}{
+		"api_token":    "foo",
+		"api_url":      sim.URL(),
+		"alias":        "latest",
+		"mode":         "tag",
+		"demoted_name": fmt.Sprintf("{{ if eq .ID %d }}released {{ .ID }}{{ else }}released-{{ .ID }}{{ end }}", second.ID),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	client := sim.Client()
+	if _, _, err := client.Tags.Create(context.TODO(), &godo.TagCreateRequest{Name: "latest"}); err != nil {
+		t.Fatal(err)
+	}
+
+	source := &digitalocean.Artifact{SnapshotName: image.Name, SnapshotId: image.ID, RegionNames: image.Regions}
+	if _, _, _, err := p.PostProcess(context.Background(), packersdk.TestUi(t), source); err == nil {
+		t.Fatal("expected an error for the invalid demoted tag")
+	}
+
+	for _, prev := range []godo.Image{first, second} {
+		if got, _ := sim.Image(prev.ID); len(got.Tags) != 1 || got.Tags[0] != "latest" {
+			t.Errorf("expected image %d to still be tagged latest, got %v", prev.ID, got.Tags)
+		}
+	}
+	if got, _ := sim.Image(image.ID); len(got.Tags) != 0 {
+		t.Errorf("expected the new image not to be tagged, got %v", got.Tags)
+	}
+}
@@ -0,0 +1,142 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+package digitaloceanrollback
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-digitalocean/builder/digitalocean"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/shell-local/localexec"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+const BuilderId = "packer.post-processor.digitalocean-rollback"
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	APIToken string `mapstructure:"api_token"`
+	APIURL   string `mapstructure:"api_url"`
+
+	Commands []string `mapstructure:"commands"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:         BuilderId,
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	if p.config.APIToken == "" {
+		p.config.APIToken = os.Getenv("DIGITALOCEAN_API_TOKEN")
+	}
+
+	if p.config.APIURL == "" {
+		p.config.APIURL = os.Getenv("DIGITALOCEAN_API_URL")
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if p.config.APIToken == "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("api_token must be set"))
+	}
+
+	if len(p.config.Commands) == 0 {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("commands must be set"))
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	packersdk.LogSecretFilter.Set(p.config.APIToken)
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifact packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
+	if artifact.BuilderId() != digitalocean.BuilderId {
+		return nil, false, false, fmt.Errorf(
+			"Unknown artifact type: %s\nCan only roll back images created by the DigitalOcean builder or import post-processor.",
+			artifact.BuilderId())
+	}
+
+	regions, imageId, err := digitalocean.ParseArtifactId(artifact.Id())
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	client, err := digitalocean.NewClient(p.config.APIToken, p.config.APIURL)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Invalid API URL: %s", err)
+	}
+
+	image, _, err := client.Images.GetByID(context.TODO(), imageId)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Error retrieving image %d: %s", imageId, err)
+	}
+
+	env := append(os.Environ(),
+		"PACKER_BUILD_NAME="+p.config.PackerBuildName,
+		"DIGITALOCEAN_SNAPSHOT_ID="+strconv.Itoa(imageId),
+		"DIGITALOCEAN_SNAPSHOT_NAME="+image.Name,
+		"DIGITALOCEAN_SNAPSHOT_REGIONS="+strings.Join(regions, ","),
+	)
+
+	for _, command := range p.config.Commands {
+		ui.Message(fmt.Sprintf("Running verification command: %s", command))
+
+		var cmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+		} else {
+			cmd = exec.CommandContext(ctx, "/bin/sh", "-c", command)
+		}
+		cmd.Env = env
+		err := localexec.RunAndStream(cmd, ui, []string{p.config.APIToken})
+		if err == nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			// Interrupted, which says nothing about the image
+			return nil, false, false, ctx.Err()
+		}
+
+		ui.Error(fmt.Sprintf("Verification command %q failed, deleting image %d...", command, imageId))
+		// Deleting the image deletes its copies in every region
+		if _, deleteErr := client.Images.Delete(context.TODO(), imageId); deleteErr != nil {
+			return nil, false, false, fmt.Errorf(
+				"Verification command %q failed: %s\nError deleting image %d, please delete it manually: %s",
+				command, err, imageId, deleteErr)
+		}
+		log.Printf("Deleted image %d", imageId)
+		return nil, false, false, fmt.Errorf("Verification command %q failed, image %d was deleted: %s", command, imageId, err)
+	}
+
+	// The image is verified as is, so the input artifact is the output one
+	return artifact, true, true, nil
+}
@@ -0,0 +1,88 @@
+package digitaloceanrollback
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-digitalocean/builder/digitalocean"
+	"github.com/hashicorp/packer-plugin-digitalocean/internal/simulator"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packersdk.PostProcessor = new(PostProcessor)
+}
+
+func TestPostProcessor_Configure(t *testing.T) {
+	t.Setenv("DIGITALOCEAN_API_TOKEN", "")
+
+	var p PostProcessor
+	if err := p.Configure(map[string]interface{}{"api_token": "foo"}); err == nil {
+		t.Fatal("expected an error without commands")
+	}
+
+	p = PostProcessor{}
+	if err := p.Configure(map[string]interface{}{"commands": []string{"true"}}); err == nil {
+		t.Fatal("expected an error without api_token")
+	}
+}
+
+func TestPostProcessor_PostProcess(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the commands are shell commands")
+	}
+
+	cases := []struct {
+		name     string
+		commands []string
+		deleted  bool
+	}{
+		{"passing", []string{"true", `test "$DIGITALOCEAN_SNAPSHOT_NAME" = packer-test`}, false},
+		{"failing", []string{"true", "exit 3", "true"}, true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := simulator.New()
+			defer sim.Close()
+			image := sim.AddImage(godo.Image{Name: "packer-test", Type: "snapshot", Regions: []string{"nyc3", "sfo3"}})
+
+			var p PostProcessor
+			err := p.Configure(map[string]interface{}{
+				"api_token": "foo",
+				"api_url":   sim.URL(),
+				"commands":  tt.commands,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			source := &digitalocean.Artifact{
+				SnapshotName: image.Name,
+				SnapshotId:   image.ID,
+				RegionNames:  image.Regions,
+			}
+			artifact, keep, _, err := p.PostProcess(context.Background(), packersdk.TestUi(t), source)
+			_, exists := sim.Image(image.ID)
+			if tt.deleted {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				if exists {
+					t.Fatal("expected the image to be deleted")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !exists {
+				t.Fatal("the image must not be deleted")
+			}
+			if !keep || artifact != source {
+				t.Fatal("the input artifact must be returned")
+			}
+		})
+	}
+}
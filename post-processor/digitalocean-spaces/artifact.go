@@ -0,0 +1,44 @@
+package digitaloceanspaces
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Artifact struct {
+	// The name of the Space the files were uploaded to
+	SpaceName string
+
+	// The URLs of the uploaded objects
+	URLs []string
+
+	// StateData should store data such as GeneratedData
+	// to be shared with post-processors
+	StateData map[string]interface{}
+}
+
+func (*Artifact) BuilderId() string {
+	return BuilderId
+}
+
+func (*Artifact) Files() []string {
+	// The uploaded files are remote
+	return nil
+}
+
+func (a *Artifact) Id() string {
+	return a.SpaceName
+}
+
+func (a *Artifact) String() string {
+	return fmt.Sprintf("Files uploaded to Space '%s': %s", a.SpaceName, strings.Join(a.URLs, ", "))
+}
+
+func (a *Artifact) State(name string) interface{} {
+	return a.StateData[name]
+}
+
+func (a *Artifact) Destroy() error {
+	// Uploaded files are left in place
+	return nil
+}
@@ -0,0 +1,223 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+package digitaloceanspaces
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+const BuilderId = "packer.post-processor.digitalocean-spaces"
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	SpacesKey    string `mapstructure:"spaces_key"`
+	SpacesSecret string `mapstructure:"spaces_secret"`
+
+	SpacesRegion string   `mapstructure:"spaces_region"`
+	SpaceName    string   `mapstructure:"space_name"`
+	ObjectPrefix string   `mapstructure:"space_object_prefix"`
+	Files        []string `mapstructure:"files"`
+	SkipArtifact bool     `mapstructure:"skip_artifact_files"`
+	ACL          string   `mapstructure:"acl"`
+	ContentType  string   `mapstructure:"content_type"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:         BuilderId,
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{"space_object_prefix"},
+		},
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	if p.config.SpacesKey == "" {
+		p.config.SpacesKey = os.Getenv("DIGITALOCEAN_SPACES_ACCESS_KEY")
+	}
+
+	if p.config.SpacesSecret == "" {
+		p.config.SpacesSecret = os.Getenv("DIGITALOCEAN_SPACES_SECRET_KEY")
+	}
+
+	if p.config.ACL == "" {
+		p.config.ACL = "private"
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if err = interpolate.Validate(p.config.ObjectPrefix, &p.config.ctx); err != nil {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("Error parsing space_object_prefix template: %s", err))
+	}
+
+	requiredArgs := map[string]*string{
+		"spaces_key":    &p.config.SpacesKey,
+		"spaces_secret": &p.config.SpacesSecret,
+		"spaces_region": &p.config.SpacesRegion,
+		"space_name":    &p.config.SpaceName,
+	}
+	for key, ptr := range requiredArgs {
+		if *ptr == "" {
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("%s must be set", key))
+		}
+	}
+
+	// Spaces only supports these two canned ACLs
+	if p.config.ACL != "private" && p.config.ACL != "public-read" {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("acl must be one of private or public-read, got %q", p.config.ACL))
+	}
+
+	if p.config.SkipArtifact && len(p.config.Files) == 0 {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("files must be set when skip_artifact_files is true"))
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	packersdk.LogSecretFilter.Set(p.config.SpacesKey, p.config.SpacesSecret)
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifact packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
+	generatedData := artifact.State("generated_data")
+	if generatedData == nil {
+		// Make sure it's not a nil map so we can assign to it later.
+		generatedData = make(map[string]interface{})
+	}
+	p.config.ctx.Data = generatedData
+
+	prefix, err := interpolate.Render(p.config.ObjectPrefix, &p.config.ctx)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Error rendering space_object_prefix template: %s", err)
+	}
+
+	var files []string
+	if !p.config.SkipArtifact {
+		files = append(files, artifact.Files()...)
+	}
+	files = append(files, p.config.Files...)
+	if len(files) == 0 {
+		return nil, false, false, fmt.Errorf("No files to upload: the artifact has no files and none are configured")
+	}
+	keys, err := objectKeys(prefix, files)
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	spacesCreds := credentials.NewStaticCredentials(p.config.SpacesKey, p.config.SpacesSecret, "")
+	spacesEndpoint := fmt.Sprintf("https://%s.digitaloceanspaces.com", p.config.SpacesRegion)
+	sess, err := session.NewSession(&aws.Config{
+		Credentials: spacesCreds,
+		Endpoint:    aws.String(spacesEndpoint),
+		Region:      aws.String(p.config.SpacesRegion),
+	})
+	if err != nil {
+		return nil, false, false, err
+	}
+	uploader := s3manager.NewUploader(sess)
+
+	var urls []string
+	for i, source := range files {
+		key := keys[i]
+		ui.Message(fmt.Sprintf("Uploading %s to spaces://%s/%s", source, p.config.SpaceName, key))
+		if err := p.upload(uploader, source, key); err != nil {
+			return nil, false, false, err
+		}
+		urls = append(urls, fmt.Sprintf("https://%s.%s.digitaloceanspaces.com/%s", p.config.SpaceName, p.config.SpacesRegion, key))
+	}
+
+	log.Printf("Uploaded %d files to spaces://%s", len(urls), p.config.SpaceName)
+	return &Artifact{
+		SpaceName: p.config.SpaceName,
+		URLs:      urls,
+		StateData: map[string]interface{}{"generated_data": generatedData},
+	}, true, false, nil
+}
+
+func (p *PostProcessor) upload(uploader *s3manager.Uploader, source, key string) error {
+	file, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("Failed to open %s: %s", source, err)
+	}
+	defer file.Close()
+
+	_, err = uploader.Upload(&s3manager.UploadInput{
+		Body:        file,
+		Bucket:      aws.String(p.config.SpaceName),
+		Key:         aws.String(key),
+		ACL:         aws.String(p.config.ACL),
+		ContentType: aws.String(contentType(source, p.config.ContentType)),
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to upload %s: %s", source, err)
+	}
+
+	return nil
+}
+
+// objectKey returns the key a local file is uploaded to.
+func objectKey(prefix, source string) string {
+	return path.Join(prefix, filepath.Base(source))
+}
+
+// objectKeys returns the keys of the files, failing when two of them, with
+// the same base name, would be uploaded to the same key.
+func objectKeys(prefix string, files []string) ([]string, error) {
+	keys := make([]string, 0, len(files))
+	sources := make(map[string]string, len(files))
+	for _, source := range files {
+		key := objectKey(prefix, source)
+		if other, ok := sources[key]; ok {
+			return nil, fmt.Errorf("Files %s and %s would both be uploaded to %s", other, source, key)
+		}
+		sources[key] = source
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// contentType returns the override when set, and otherwise guesses the
+// content type from the file extension.
+func contentType(source, override string) string {
+	if override != "" {
+		return override
+	}
+	if t := mime.TypeByExtension(filepath.Ext(source)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
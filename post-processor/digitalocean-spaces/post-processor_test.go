@@ -0,0 +1,92 @@
+package digitaloceanspaces
+
+import (
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packersdk.PostProcessor = new(PostProcessor)
+	var _ packersdk.Artifact = new(Artifact)
+}
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"spaces_key":    "key",
+		"spaces_secret": "secret",
+		"spaces_region": "nyc3",
+		"space_name":    "images",
+	}
+}
+
+func TestPostProcessor_Configure(t *testing.T) {
+	var p PostProcessor
+	if err := p.Configure(testConfig()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if p.config.ACL != "private" {
+		t.Fatalf("expected acl to default to private, got %q", p.config.ACL)
+	}
+
+	c := testConfig()
+	c["acl"] = "authenticated-read"
+	p = PostProcessor{}
+	if err := p.Configure(c); err == nil {
+		t.Fatal("expected an error for an unsupported acl")
+	}
+
+	c = testConfig()
+	c["skip_artifact_files"] = true
+	p = PostProcessor{}
+	if err := p.Configure(c); err == nil {
+		t.Fatal("expected an error when there is nothing to upload")
+	}
+}
+
+func TestPostProcessor_ObjectKey(t *testing.T) {
+	tt := []struct {
+		Prefix, Source, Key string
+	}{
+		{"", "output/manifest.json", "manifest.json"},
+		{"catalog/ubuntu", "/tmp/SHA256SUMS", "catalog/ubuntu/SHA256SUMS"},
+		{"catalog/", "image.qcow2", "catalog/image.qcow2"},
+	}
+
+	for _, tc := range tt {
+		if key := objectKey(tc.Prefix, tc.Source); key != tc.Key {
+			t.Errorf("expected key %q, got %q", tc.Key, key)
+		}
+	}
+}
+
+func TestPostProcessor_ObjectKeys(t *testing.T) {
+	keys, err := objectKeys("catalog", []string{"output/image.qcow2", "output/SHA256SUMS"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(keys) != 2 || keys[0] != "catalog/image.qcow2" || keys[1] != "catalog/SHA256SUMS" {
+		t.Errorf("unexpected keys %q", keys)
+	}
+
+	// Files of the same name in different directories
+	if _, err := objectKeys("catalog", []string{"amd64/SHA256SUMS", "arm64/SHA256SUMS"}); err == nil {
+		t.Fatal("expected an error for files uploaded to the same key")
+	}
+}
+
+func TestPostProcessor_ContentType(t *testing.T) {
+	tt := []struct {
+		Source, Override, ContentType string
+	}{
+		{"manifest.json", "", "application/json"},
+		{"disk.img-part", "", "application/octet-stream"},
+		{"SHA256SUMS", "text/plain", "text/plain"},
+	}
+
+	for _, tc := range tt {
+		if ct := contentType(tc.Source, tc.Override); ct != tc.ContentType {
+			t.Errorf("expected content type %q for %s, got %q", tc.ContentType, tc.Source, ct)
+		}
+	}
+}
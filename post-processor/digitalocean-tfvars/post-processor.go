@@ -0,0 +1,183 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+package digitaloceantfvars
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/hashicorp/packer-plugin-digitalocean/builder/digitalocean"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const BuilderId = "packer.post-processor.digitalocean-tfvars"
+
+// Terraform variable names are identifiers
+var reVariablePrefix = regexp.MustCompile(`^([a-zA-Z_][a-zA-Z0-9_-]*)?$`)
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	APIToken string `mapstructure:"api_token"`
+	APIURL   string `mapstructure:"api_url"`
+
+	Output         string `mapstructure:"output"`
+	Format         string `mapstructure:"format"`
+	VariablePrefix string `mapstructure:"variable_prefix"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:         BuilderId,
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{},
+		},
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	if p.config.APIToken == "" {
+		p.config.APIToken = os.Getenv("DIGITALOCEAN_API_TOKEN")
+	}
+
+	if p.config.APIURL == "" {
+		p.config.APIURL = os.Getenv("DIGITALOCEAN_API_URL")
+	}
+
+	if p.config.Output == "" {
+		p.config.Output = "digitalocean.auto.tfvars"
+	}
+
+	if p.config.Format == "" {
+		p.config.Format = "hcl"
+		if filepath.Ext(p.config.Output) == ".json" {
+			p.config.Format = "json"
+		}
+	}
+
+	if p.config.VariablePrefix == "" {
+		p.config.VariablePrefix = "image_"
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if p.config.APIToken == "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("api_token must be set"))
+	}
+
+	if p.config.Format != "hcl" && p.config.Format != "json" {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("format must be one of hcl or json, got %q", p.config.Format))
+	}
+
+	if !reVariablePrefix.MatchString(p.config.VariablePrefix) {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("variable_prefix must start with a letter or an underscore and contain only letters, digits, underscores and dashes"))
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	packersdk.LogSecretFilter.Set(p.config.APIToken)
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifact packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
+	if artifact.BuilderId() != digitalocean.BuilderId {
+		return nil, false, false, fmt.Errorf(
+			"Unknown artifact type: %s\nCan only write variables for images created by the DigitalOcean builder or import post-processor.",
+			artifact.BuilderId())
+	}
+
+	regions, imageId, err := digitalocean.ParseArtifactId(artifact.Id())
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	client, err := digitalocean.NewClient(p.config.APIToken, p.config.APIURL)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Invalid API URL: %s", err)
+	}
+
+	image, _, err := client.Images.GetByID(context.TODO(), imageId)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Error retrieving image %d: %s", imageId, err)
+	}
+
+	// In the order they are written
+	prefix := p.config.VariablePrefix
+	names := []string{prefix + "id", prefix + "name", prefix + "regions", prefix + "min_disk_size"}
+	values := map[string]interface{}{
+		prefix + "id":            image.ID,
+		prefix + "name":          image.Name,
+		prefix + "regions":       regions,
+		prefix + "min_disk_size": image.MinDiskSize,
+	}
+
+	var data []byte
+	if p.config.Format == "json" {
+		data, err = json.MarshalIndent(values, "", "  ")
+		if err != nil {
+			return nil, false, false, err
+		}
+		data = append(data, '\n')
+	} else {
+		data = hclVariables(names, image.ID, image.Name, regions, image.MinDiskSize)
+	}
+
+	ui.Message(fmt.Sprintf("Writing Terraform variables to %s", p.config.Output))
+	if err := os.MkdirAll(filepath.Dir(p.config.Output), 0755); err != nil {
+		return nil, false, false, fmt.Errorf("Error creating directory for %s: %s", p.config.Output, err)
+	}
+	if err := ioutil.WriteFile(p.config.Output, data, 0644); err != nil {
+		return nil, false, false, fmt.Errorf("Error writing %s: %s", p.config.Output, err)
+	}
+
+	// Nothing about the image changed, so the input artifact is passed on.
+	return artifact, true, true, nil
+}
+
+// hclVariables renders the variables in the form of a .tfvars file, names
+// being the names of the id, name, regions and min_disk_size variables.
+func hclVariables(names []string, id int, name string, regions []string, minDiskSize int) []byte {
+	regionValues := make([]cty.Value, 0, len(regions))
+	for _, region := range regions {
+		regionValues = append(regionValues, cty.StringVal(region))
+	}
+	regionsValue := cty.ListValEmpty(cty.String)
+	if len(regionValues) > 0 {
+		regionsValue = cty.ListVal(regionValues)
+	}
+
+	f := hclwrite.NewEmptyFile()
+	body := f.Body()
+	body.SetAttributeValue(names[0], cty.NumberIntVal(int64(id)))
+	body.SetAttributeValue(names[1], cty.StringVal(name))
+	body.SetAttributeValue(names[2], regionsValue)
+	body.SetAttributeValue(names[3], cty.NumberIntVal(int64(minDiskSize)))
+	return hclwrite.Format(f.Bytes())
+}
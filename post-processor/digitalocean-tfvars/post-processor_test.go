@@ -0,0 +1,128 @@
+package digitaloceantfvars
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer-plugin-digitalocean/builder/digitalocean"
+	"github.com/hashicorp/packer-plugin-digitalocean/internal/simulator"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packersdk.PostProcessor = new(PostProcessor)
+}
+
+func TestPostProcessor_Configure(t *testing.T) {
+	tt := []struct {
+		Name   string
+		Config map[string]interface{}
+		Format string
+	}{
+		{Name: "Default", Config: map[string]interface{}{"api_token": "foo"}, Format: "hcl"},
+		{Name: "JSONExtension", Config: map[string]interface{}{"api_token": "foo", "output": "image.auto.tfvars.json"}, Format: "json"},
+		{Name: "ExplicitFormat", Config: map[string]interface{}{"api_token": "foo", "output": "image.vars", "format": "json"}, Format: "json"},
+		{Name: "InvalidFormat", Config: map[string]interface{}{"api_token": "foo", "format": "yaml"}},
+		{Name: "InvalidPrefix", Config: map[string]interface{}{"api_token": "foo", "variable_prefix": "1image"}},
+		{Name: "MissingToken", Config: map[string]interface{}{}},
+	}
+
+	t.Setenv("DIGITALOCEAN_API_TOKEN", "")
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			var p PostProcessor
+			err := p.Configure(tc.Config)
+			if tc.Format != "" && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if tc.Format == "" {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if p.config.Format != tc.Format {
+				t.Fatalf("expected format %s, got %s", tc.Format, p.config.Format)
+			}
+		})
+	}
+}
+
+func testPostProcess(t *testing.T, config map[string]interface{}) (string, int) {
+	sim := simulator.New()
+	t.Cleanup(sim.Close)
+	image := sim.AddImage(godo.Image{Name: "packer-1625918400", Type: "snapshot", Regions: []string{"nyc3", "ams3"}, MinDiskSize: 25})
+
+	config["api_token"] = "foo"
+	config["api_url"] = sim.URL()
+	var p PostProcessor
+	if err := p.Configure(config); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	source := &digitalocean.Artifact{SnapshotName: image.Name, SnapshotId: image.ID, RegionNames: image.Regions}
+	_, keep, _, err := p.PostProcess(context.Background(), packersdk.TestUi(t), source)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !keep {
+		t.Fatal("the input artifact must be kept")
+	}
+
+	data, err := ioutil.ReadFile(p.config.Output)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return string(data), image.ID
+}
+
+func TestPostProcessor_PostProcessHCL(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "vars", "image.auto.tfvars")
+	got, id := testPostProcess(t, map[string]interface{}{"output": output})
+
+	expected := fmt.Sprintf(`image_id            = %d
+image_name          = "packer-1625918400"
+image_regions       = ["nyc3", "ams3"]
+image_min_disk_size = 25
+`, id)
+	if got != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestPostProcessor_PostProcessJSON(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "image.auto.tfvars.json")
+	got, id := testPostProcess(t, map[string]interface{}{"output": output, "variable_prefix": "web_"})
+
+	var vars map[string]interface{}
+	if err := json.Unmarshal([]byte(got), &vars); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := map[string]interface{}{
+		"web_id":            float64(id),
+		"web_name":          "packer-1625918400",
+		"web_regions":       []interface{}{"nyc3", "ams3"},
+		"web_min_disk_size": float64(25),
+	}
+	if !reflect.DeepEqual(vars, expected) {
+		t.Fatalf("expected %v, got %v", expected, vars)
+	}
+}
+
+func TestPostProcessor_UnknownArtifact(t *testing.T) {
+	var p PostProcessor
+	if err := p.Configure(map[string]interface{}{"api_token": "foo"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	_, _, _, err := p.PostProcess(context.Background(), packersdk.TestUi(t), &packersdk.MockArtifact{})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}